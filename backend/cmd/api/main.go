@@ -32,6 +32,11 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// "holyhome migrate ..." inspects or upgrades the schema without starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrateCommand(cfg, os.Args[2:]))
+	}
+
 	// Validate configuration security
 	if err := validateConfig(cfg); err != nil {
 		log.Fatalf("Configuration validation failed: %v", err)
@@ -55,9 +60,16 @@ func main() {
 	}
 	log.Println("Admin bootstrap complete")
 
+	// Uploads are bounded by the attachment limit; leave room for the multipart envelope
+	bodyLimit := 4 << 20 // Fiber default
+	if attachmentLimit := int(cfg.Attachments.MaxSizeBytes) + 1<<20; attachmentLimit > bodyLimit {
+		bodyLimit = attachmentLimit
+	}
+
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
 		AppName:                 cfg.App.Name,
+		BodyLimit:               bodyLimit,
 		ErrorHandler:            customErrorHandler,
 		EnableTrustedProxyCheck: true,
 		TrustedProxies:          []string{"172.20.0.0/16", "10.0.0.0/8", "127.0.0.1"},
@@ -114,27 +126,61 @@ func main() {
 	})
 
 	// Initialize services with repositories
-	userService := services.NewUserService(repos.Users, repos.Groups, repos.Roles, repos.PasswordResetTokens, cfg)
+	userService := services.NewUserService(repos.Users, repos.Residencies, repos.Groups, repos.Roles, repos.PasswordResetTokens, cfg)
 	groupService := services.NewGroupService(repos.Groups, repos.Users, repos.Allocations)
 	eventService := services.NewEventService()
 	webPushService := services.NewWebPushService(repos.WebPushSubscriptions)
 	notificationPreferenceService := services.NewNotificationPreferenceService(repos.NotificationPreferences)
 	notificationService := services.NewNotificationService(repos.Notifications, eventService, webPushService, notificationPreferenceService, cfg)
-	billService := services.NewBillService(repos.Bills, repos.Consumptions, repos.Allocations, repos.Payments, repos.Users, repos.Groups, notificationService)
-	consumptionService := services.NewConsumptionService(repos.Consumptions, repos.Bills, repos.Users)
-	allocationService := services.NewAllocationService(repos.Users, repos.Groups, repos.Consumptions, repos.Allocations, repos.Bills)
-	loanService := services.NewLoanService(repos.Loans, repos.LoanPayments, repos.Users, repos.Groups, notificationService)
-	choreService := services.NewChoreService(repos.Chores, repos.ChoreAssignments, repos.Users, notificationService)
-	supplyService := services.NewSupplyService(repos.SupplySettings, repos.SupplyItems, repos.SupplyContributions, repos.Users, notificationService)
-	recurringBillService := services.NewRecurringBillService(repos.RecurringBillTemplates, repos.RecurringBillAllocations, repos.Bills, repos.Allocations, repos.Payments, repos.Users, cfg)
-	paymentService := services.NewPaymentService(repos.Payments, repos.Bills, recurringBillService)
-	exportService := services.NewExportService(repos.Bills, repos.Consumptions, repos.Loans, repos.LoanPayments, repos.Chores, repos.ChoreAssignments, repos.Users, repos.Groups)
-	backupService := services.NewBackupService(sqliteDB.DB, repos.Users, repos.Groups, repos.Bills, repos.Consumptions, repos.Allocations, repos.Payments, repos.Loans, repos.LoanPayments, repos.Chores, repos.ChoreAssignments, repos.ChoreSettings, repos.Notifications, repos.SupplySettings, repos.SupplyItems, repos.SupplyContributions, repos.RecurringBillTemplates, repos.RecurringBillAllocations, repos.PasskeyCredentials)
+	appSettingsService := services.NewAppSettingsService(repos.AppSettings)
+	currencyService := services.NewCurrencyService(repos.ExchangeRates, appSettingsService)
+	allocationService := services.NewAllocationService(repos.Users, repos.Residencies, repos.BillResidents, repos.Groups, repos.Consumptions, repos.Allocations, repos.Bills, repos.BillSplits, repos.BillItems, repos.BillTariffZones, repos.Meters)
+	creditService := services.NewCreditService(repos.CreditEntries, repos.Payments, repos.Users, allocationService)
+	billService := services.NewBillService(repos.Bills, repos.Consumptions, repos.Allocations, repos.Payments, repos.Users, repos.Groups, repos.BillSplits, repos.BillItems, repos.BillTariffZones, repos.BillTransitions, repos.BillVersions, repos.BankAccounts, repos.TxManager, notificationService, currencyService, allocationService, creditService, services.BillTransitionsFromConfig(cfg))
+	attachmentStore, err := services.NewBlobStore(cfg.Attachments.Dir)
+	if err != nil {
+		log.Fatalf("Failed to initialize attachment storage: %v", err)
+	}
+	attachmentService := services.NewAttachmentService(repos.Attachments, repos.Bills, repos.SupplyItemHistory, repos.Loans, repos.Consumptions, repos.TxManager, attachmentStore, cfg)
+	if removed, err := attachmentService.RemoveOrphanedFiles(context.Background()); err != nil {
+		log.Printf("Warning: Failed to clean up attachment files: %v", err)
+	} else if removed > 0 {
+		log.Printf("Removed %d orphaned attachment files", removed)
+	}
+	consumptionService := services.NewConsumptionService(repos.Consumptions, repos.ConsumptionAnomalies, attachmentService, repos.Bills, repos.Users, repos.Meters, repos.BillTariffZones, repos.TxManager)
+	meterService := services.NewMeterService(repos.Meters, repos.Consumptions, repos.Users, repos.Groups, repos.TxManager)
+	forecastService := services.NewForecastService(repos.UtilityTariffs, repos.Bills, repos.Consumptions, repos.Meters, allocationService, currencyService)
+	loanService := services.NewLoanService(repos.Loans, repos.LoanPayments, repos.Users, repos.Groups, repos.TxManager, notificationService, currencyService)
+	settleUpService := services.NewSettleUpService(repos.Loans, repos.LoanPayments, repos.SettlementTransfers, repos.Bills, repos.Allocations, repos.Payments, repos.Users, repos.Groups, repos.TxManager, currencyService, creditService)
+	choreService := services.NewChoreService(repos.Chores, repos.ChoreAssignments, repos.Users, repos.TxManager, notificationService)
+	supplyService := services.NewSupplyService(repos.SupplySettings, repos.SupplyItems, repos.SupplyContributions, repos.SupplyItemHistory, repos.Users, repos.TxManager, notificationService, currencyService)
+	recurringBillService := services.NewRecurringBillService(repos.RecurringBillTemplates, repos.RecurringBillAllocations, repos.Bills, repos.Allocations, repos.Users, creditService, currencyService, cfg)
+	paymentService := services.NewPaymentService(repos.Payments, repos.Bills, repos.TxManager, creditService, recurringBillService)
+	bankImportService := services.NewBankImportService(repos.BankImports, repos.BankTransactions, repos.Bills, repos.Loans, repos.LoanPayments, repos.Users, repos.TxManager, creditService, paymentService, loanService, currencyService)
+	bankAccountService := services.NewBankAccountService(repos.BankAccounts, repos.TxManager)
+	paymentRequestService := services.NewPaymentRequestService(repos.Bills, repos.BankAccounts, creditService)
+	invoiceImportService := services.NewInvoiceImportService()
+	ledgerService := services.NewLedgerService(repos.Users, repos.Bills, repos.Payments, repos.Loans, repos.LoanPayments, repos.SupplyContributions, repos.SupplyItemHistory, repos.LateFees, allocationService, currencyService)
+	exportService := services.NewExportService(repos.Bills, repos.Consumptions, repos.Loans, repos.LoanPayments, repos.Chores, repos.ChoreAssignments, repos.Users, repos.Groups, currencyService, ledgerService)
+	backupService := services.NewBackupService(sqliteDB.DB, repos.Users, repos.Residencies, repos.Groups, repos.Bills, repos.Consumptions, repos.Allocations, repos.Payments, repos.CreditEntries, repos.Loans, repos.LoanPayments, repos.SettlementTransfers, repos.BankAccounts, repos.BankImports, repos.BankTransactions, repos.Attachments, attachmentStore, repos.Chores, repos.ChoreAssignments, repos.ChoreSettings, repos.Notifications, repos.SupplySettings, repos.SupplyItems, repos.SupplyContributions, repos.SupplyItemHistory, repos.RecurringBillTemplates, repos.RecurringBillAllocations, repos.BillSplits, repos.BillItems, repos.BillTariffZones, repos.BillResidents, repos.Meters, repos.PasskeyCredentials, repos.ExchangeRates, repos.UtilityTariffs, repos.ConsumptionAnomalies, repos.BillTransitions, repos.BillDisputes, repos.BillVersions, repos.LateFeePolicies, repos.LateFees, repos.Budgets, repos.BudgetAlerts)
 	auditService := services.NewAuditService(repos.AuditLogs)
+
+	// Bill transitions are audited, and the paid bill of a recurring template generates the next one
+	billService.OnTransition(services.BillAuditHook(auditService))
+	billService.OnTransition(recurringBillService.OnBillTransition, services.BillTransitionPost, services.BillTransitionRequestPayment,
+		services.BillTransitionResolveDispute, services.BillTransitionClose)
+
 	permissionService := services.NewPermissionService(repos.Permissions)
 	roleService := services.NewRoleService(repos.Roles, repos.Users, repos.Permissions)
+	disputeService := services.NewDisputeService(repos.BillDisputes, repos.Bills, repos.Allocations, repos.Users, repos.TxManager, billService, allocationService, creditService, roleService, notificationService)
+	lateFeeService := services.NewLateFeeService(repos.LateFeePolicies, repos.LateFees, repos.Bills, repos.Loans, repos.LoanPayments, repos.Users, creditService, notificationService)
+	analyticsService := services.NewAnalyticsService(repos.Analytics, repos.Users, currencyService)
+	budgetService := services.NewBudgetService(repos.Budgets, repos.BudgetAlerts, repos.Bills, repos.Analytics, repos.Users, currencyService, notificationService)
+
+	// Posted bills count against the budgets straight away
+	billService.OnTransition(budgetService.OnBillTransition, services.BillTransitionPost, services.BillTransitionAmend)
+
 	approvalService := services.NewApprovalService(repos.ApprovalRequests)
-	appSettingsService := services.NewAppSettingsService(repos.AppSettings)
 	reminderService := services.NewReminderService(
 		repos.SentReminders,
 		repos.AppSettings,
@@ -145,6 +191,7 @@ func main() {
 		repos.Chores,
 		repos.SupplyItems,
 		notificationService,
+		currencyService,
 	)
 	schedulerService := services.NewSchedulerService(
 		repos.SentReminders,
@@ -155,7 +202,12 @@ func main() {
 		repos.ChoreAssignments,
 		repos.Chores,
 		repos.SupplyItems,
+		repos.Consumptions,
+		repos.ConsumptionAnomalies,
+		repos.Meters,
 		notificationService,
+		lateFeeService,
+		budgetService,
 	)
 
 	// Initialize default permissions and roles
@@ -173,14 +225,27 @@ func main() {
 	userHandler := handlers.NewUserHandler(userService, auditService, roleService, cfg)
 	groupHandler := handlers.NewGroupHandler(groupService, auditService)
 	billHandler := handlers.NewBillHandler(billService, consumptionService, allocationService, auditService, eventService)
+	disputeHandler := handlers.NewDisputeHandler(disputeService, auditService)
+	lateFeeHandler := handlers.NewLateFeeHandler(lateFeeService, auditService)
+	budgetHandler := handlers.NewBudgetHandler(budgetService, auditService)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
+	meterHandler := handlers.NewMeterHandler(meterService, auditService)
+	forecastHandler := handlers.NewForecastHandler(forecastService, auditService)
 	recurringBillHandler := handlers.NewRecurringBillHandler(recurringBillService, auditService)
 	loanHandler := handlers.NewLoanHandler(loanService, eventService, auditService)
+	settleUpHandler := handlers.NewSettleUpHandler(settleUpService, eventService, auditService)
+	bankAccountHandler := handlers.NewBankAccountHandler(bankAccountService, auditService)
+	paymentRequestHandler := handlers.NewPaymentRequestHandler(paymentRequestService)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, auditService)
+	invoiceHandler := handlers.NewInvoiceHandler(invoiceImportService)
+	bankImportHandler := handlers.NewBankImportHandler(bankImportService, eventService, auditService)
 	choreHandler := handlers.NewChoreHandler(choreService, approvalService, roleService, auditService, eventService)
 	supplyHandler := handlers.NewSupplyHandler(supplyService, auditService, eventService)
 	backupHandler := handlers.NewBackupHandler(backupService)
 	eventHandler := handlers.NewEventHandler(eventService)
 	wsHandler := handlers.NewWebSocketHandler(eventService, cfg)
 	exportHandler := handlers.NewExportHandler(exportService)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerService)
 	auditHandler := handlers.NewAuditHandler(auditService)
 	roleHandler := handlers.NewRoleHandler(roleService, permissionService, auditService, eventService, userService)
 	approvalHandler := handlers.NewApprovalHandler(approvalService)
@@ -188,6 +253,7 @@ func main() {
 	webPushHandler := handlers.NewWebPushHandler(webPushService)
 	notificationPreferenceHandler := handlers.NewNotificationPreferenceHandler(notificationPreferenceService)
 	appSettingsHandler := handlers.NewAppSettingsHandler(appSettingsService)
+	exchangeRateHandler := handlers.NewExchangeRateHandler(currencyService, auditService)
 	paymentHandler := handlers.NewPaymentHandler(paymentService, auditService)
 	reminderHandler := handlers.NewReminderHandler(reminderService)
 
@@ -232,12 +298,18 @@ func main() {
 	users.Get("/", middleware.AuthMiddleware(cfg), middleware.RequirePermission("users.read", getRoleService), userHandler.GetUsers)
 	users.Post("/", middleware.AuthMiddleware(cfg), middleware.RequirePermission("users.create", getRoleService), userHandler.CreateUser)
 	users.Get("/me", middleware.AuthMiddleware(cfg), userHandler.GetMe)
+	users.Get("/me/ledger", middleware.AuthMiddleware(cfg), ledgerHandler.GetMyLedger)
 	users.Get("/:id", middleware.AuthMiddleware(cfg), userHandler.GetUser)
 	users.Patch("/:id", middleware.AuthMiddleware(cfg), userHandler.UpdateUser)
 	users.Delete("/:id", middleware.AuthMiddleware(cfg), middleware.RequirePermission("users.delete", getRoleService), userHandler.DeleteUser)
 	users.Post("/change-password", middleware.AuthMiddleware(cfg), userHandler.ChangePassword)
 	users.Post("/:id/force-password-change", middleware.AuthMiddleware(cfg), middleware.RequirePermission("users.update", getRoleService), userHandler.ForcePasswordChange)
 	users.Post("/:id/generate-reset-link", middleware.AuthMiddleware(cfg), middleware.RequirePermission("users.update", getRoleService), userHandler.GeneratePasswordResetLink)
+	users.Get("/:id/ledger", middleware.AuthMiddleware(cfg), middleware.RequirePermission("users.read", getRoleService), ledgerHandler.GetUserLedger)
+	users.Get("/:id/residencies", middleware.AuthMiddleware(cfg), middleware.RequirePermission("users.read", getRoleService), userHandler.GetResidencies)
+	users.Post("/:id/residencies", middleware.AuthMiddleware(cfg), middleware.RequirePermission("users.update", getRoleService), userHandler.AddResidency)
+	users.Patch("/:id/residencies/:residencyId", middleware.AuthMiddleware(cfg), middleware.RequirePermission("users.update", getRoleService), userHandler.UpdateResidency)
+	users.Delete("/:id/residencies/:residencyId", middleware.AuthMiddleware(cfg), middleware.RequirePermission("users.update", getRoleService), userHandler.DeleteResidency)
 
 	// Group routes
 	groups := api.Group("/groups")
@@ -251,13 +323,33 @@ func main() {
 	bills := api.Group("/bills")
 	bills.Post("/", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.create", getRoleService), billHandler.CreateBill)
 	bills.Get("/", middleware.AuthMiddleware(cfg), billHandler.GetBills)
+	bills.Get("/forecast", middleware.AuthMiddleware(cfg), forecastHandler.GetForecast)
+	bills.Post("/parse-invoice", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.create", getRoleService), invoiceHandler.ParseInvoice)
 	bills.Get("/:id", middleware.AuthMiddleware(cfg), billHandler.GetBill)
 	bills.Post("/:id/post", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.post", getRoleService), billHandler.PostBill)
+	bills.Post("/:id/request-payment", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.post", getRoleService), billHandler.RequestBillPayment)
 	bills.Post("/:id/close", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.close", getRoleService), billHandler.CloseBill)
 	bills.Post("/:id/reopen", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.update", getRoleService), billHandler.ReopenBill)
 	bills.Delete("/:id", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.delete", getRoleService), billHandler.DeleteBill)
+	bills.Get("/:id/history", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.read", getRoleService), billHandler.GetBillHistory)
+	bills.Post("/:id/amend", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.update", getRoleService), billHandler.AmendBill)
+	bills.Get("/:id/versions", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.read", getRoleService), billHandler.GetBillVersions)
+	bills.Get("/:id/versions/diff", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.read", getRoleService), billHandler.GetBillVersionDiff)
+	bills.Post("/:id/disputes", middleware.AuthMiddleware(cfg), disputeHandler.OpenDispute)
+	bills.Get("/:id/disputes", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.read", getRoleService), disputeHandler.GetBillDisputes)
 	bills.Get("/:id/allocation", middleware.AuthMiddleware(cfg), billHandler.GetBillAllocation)
 	bills.Get("/:id/payment-status", middleware.AuthMiddleware(cfg), billHandler.GetBillPaymentStatus)
+	bills.Get("/:id/payment-requests", middleware.AuthMiddleware(cfg), paymentRequestHandler.GetBillPaymentRequests)
+	bills.Get("/:id/payment-requests/:subjectId/qr", middleware.AuthMiddleware(cfg), paymentRequestHandler.GetPaymentQR)
+	bills.Get("/:id/attachments", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.read", getRoleService), attachmentHandler.GetAttachments(services.AttachmentResourceBill))
+	bills.Post("/:id/attachments", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.update", getRoleService), attachmentHandler.UploadAttachment(services.AttachmentResourceBill))
+	bills.Get("/:id/attachments/:attachmentId", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.read", getRoleService), attachmentHandler.DownloadAttachment(services.AttachmentResourceBill))
+	bills.Delete("/:id/attachments/:attachmentId", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.update", getRoleService), attachmentHandler.DeleteAttachment(services.AttachmentResourceBill))
+
+	// Dispute routes
+	disputes := api.Group("/disputes")
+	disputes.Get("/", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.disputes.resolve", getRoleService), disputeHandler.GetDisputes)
+	disputes.Post("/:id/resolve", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.disputes.resolve", getRoleService), disputeHandler.ResolveDispute)
 
 	// Consumption routes
 	consumptions := api.Group("/consumptions")
@@ -265,6 +357,17 @@ func main() {
 	consumptions.Get("/", middleware.AuthMiddleware(cfg), billHandler.GetConsumptions)
 	consumptions.Delete("/:id", middleware.AuthMiddleware(cfg), middleware.RequirePermission("readings.delete", getRoleService), billHandler.DeleteConsumption)
 	consumptions.Post("/:id/mark-invalid", middleware.AuthMiddleware(cfg), billHandler.MarkConsumptionInvalid)
+	consumptions.Get("/:id/attachments/:attachmentId", middleware.AuthMiddleware(cfg), attachmentHandler.DownloadAttachment(services.AttachmentResourceConsumption))
+
+	// Meter routes
+	meters := api.Group("/meters")
+	meters.Get("/", middleware.AuthMiddleware(cfg), meterHandler.GetMeters)
+	meters.Get("/me", middleware.AuthMiddleware(cfg), meterHandler.GetMyMeters)
+	meters.Get("/:id", middleware.AuthMiddleware(cfg), meterHandler.GetMeter)
+	meters.Post("/", middleware.AuthMiddleware(cfg), middleware.RequirePermission("meters.manage", getRoleService), meterHandler.CreateMeter)
+	meters.Patch("/:id", middleware.AuthMiddleware(cfg), middleware.RequirePermission("meters.manage", getRoleService), meterHandler.UpdateMeter)
+	meters.Post("/:id/replace", middleware.AuthMiddleware(cfg), middleware.RequirePermission("meters.manage", getRoleService), meterHandler.ReplaceMeter)
+	meters.Delete("/:id", middleware.AuthMiddleware(cfg), middleware.RequirePermission("meters.manage", getRoleService), meterHandler.DeleteMeter)
 
 	// Recurring bill routes
 	recurringBills := api.Group("/recurring-bills")
@@ -279,18 +382,66 @@ func main() {
 	payments := api.Group("/payments")
 	payments.Post("/", middleware.AuthMiddleware(cfg), paymentHandler.RecordPayment)
 	payments.Get("/me", middleware.AuthMiddleware(cfg), paymentHandler.GetUserPayments)
+	payments.Get("/credit/me", middleware.AuthMiddleware(cfg), paymentHandler.GetUserCreditStatement)
 	payments.Get("/bill/:billId", middleware.AuthMiddleware(cfg), paymentHandler.GetBillPayments)
 
+	// Bank account routes
+	bankAccounts := api.Group("/bank-accounts")
+	bankAccounts.Get("/", middleware.AuthMiddleware(cfg), bankAccountHandler.GetBankAccounts)
+	bankAccounts.Get("/me", middleware.AuthMiddleware(cfg), bankAccountHandler.GetMyBankAccounts)
+	bankAccounts.Post("/", middleware.AuthMiddleware(cfg), bankAccountHandler.CreateBankAccount)
+	bankAccounts.Patch("/:id", middleware.AuthMiddleware(cfg), bankAccountHandler.UpdateBankAccount)
+	bankAccounts.Delete("/:id", middleware.AuthMiddleware(cfg), bankAccountHandler.DeleteBankAccount)
+
+	// Bank statement import routes
+	bankImports := api.Group("/bank-imports")
+	bankImports.Post("/", middleware.AuthMiddleware(cfg), middleware.RequirePermission("payments.import", getRoleService), bankImportHandler.ImportStatement)
+	bankImports.Get("/", middleware.AuthMiddleware(cfg), middleware.RequirePermission("payments.import", getRoleService), bankImportHandler.ListImports)
+	bankImports.Get("/queue", middleware.AuthMiddleware(cfg), middleware.RequirePermission("payments.import", getRoleService), bankImportHandler.GetReviewQueue)
+	bankImports.Get("/:id/transactions", middleware.AuthMiddleware(cfg), middleware.RequirePermission("payments.import", getRoleService), bankImportHandler.ListImportTransactions)
+	bankImports.Post("/transactions/:id/confirm", middleware.AuthMiddleware(cfg), middleware.RequirePermission("payments.import", getRoleService), bankImportHandler.ConfirmMatch)
+	bankImports.Post("/transactions/:id/ignore", middleware.AuthMiddleware(cfg), middleware.RequirePermission("payments.import", getRoleService), bankImportHandler.IgnoreTransaction)
+
 	// Loan routes
 	loans := api.Group("/loans")
 	loans.Post("/", middleware.AuthMiddleware(cfg), middleware.RequirePermission("loans.create", getRoleService), loanHandler.CreateLoan)
 	loans.Post("/compensate", middleware.AuthMiddleware(cfg), middleware.RequirePermission("loans.create", getRoleService), loanHandler.CompensateLoan)
 	loans.Get("/", middleware.AuthMiddleware(cfg), middleware.RequirePermission("loans.read", getRoleService), loanHandler.GetLoans)
+	loans.Get("/settle-up", middleware.AuthMiddleware(cfg), middleware.RequirePermission("loans.read", getRoleService), settleUpHandler.PreviewSettleUp)
+	loans.Post("/settle-up", middleware.AuthMiddleware(cfg), middleware.RequirePermission("loans.update", getRoleService), settleUpHandler.ApplySettleUp)
+	loans.Get("/settle-up/transfers", middleware.AuthMiddleware(cfg), middleware.RequirePermission("loans.read", getRoleService), settleUpHandler.GetTransfers)
 	loans.Get("/balances", middleware.AuthMiddleware(cfg), middleware.RequirePermission("loans.read", getRoleService), loanHandler.GetBalances)
 	loans.Get("/balances/me", middleware.AuthMiddleware(cfg), middleware.RequirePermission("loans.read", getRoleService), loanHandler.GetMyBalance)
 	loans.Get("/balances/user/:id", middleware.AuthMiddleware(cfg), middleware.RequirePermission("loans.read", getRoleService), loanHandler.GetUserBalance)
 	loans.Get("/:id/payments", middleware.AuthMiddleware(cfg), middleware.RequirePermission("loans.read", getRoleService), loanHandler.GetLoanPayments)
 	loans.Delete("/:id", middleware.AuthMiddleware(cfg), middleware.RequirePermission("loans.delete", getRoleService), loanHandler.DeleteLoan)
+	loans.Get("/:id/attachments", middleware.AuthMiddleware(cfg), middleware.RequirePermission("loans.read", getRoleService), attachmentHandler.GetAttachments(services.AttachmentResourceLoan))
+	loans.Post("/:id/attachments", middleware.AuthMiddleware(cfg), middleware.RequirePermission("loans.update", getRoleService), attachmentHandler.UploadAttachment(services.AttachmentResourceLoan))
+	loans.Get("/:id/attachments/:attachmentId", middleware.AuthMiddleware(cfg), middleware.RequirePermission("loans.read", getRoleService), attachmentHandler.DownloadAttachment(services.AttachmentResourceLoan))
+	loans.Delete("/:id/attachments/:attachmentId", middleware.AuthMiddleware(cfg), middleware.RequirePermission("loans.update", getRoleService), attachmentHandler.DeleteAttachment(services.AttachmentResourceLoan))
+
+	// Late fee routes
+	lateFees := api.Group("/late-fees")
+	lateFees.Get("/", middleware.AuthMiddleware(cfg), middleware.RequirePermission("late-fees.manage", getRoleService), lateFeeHandler.GetFees)
+	lateFees.Get("/policies", middleware.AuthMiddleware(cfg), middleware.RequirePermission("late-fees.manage", getRoleService), lateFeeHandler.GetPolicies)
+	lateFees.Put("/policies", middleware.AuthMiddleware(cfg), middleware.RequirePermission("late-fees.manage", getRoleService), lateFeeHandler.SetPolicy)
+	lateFees.Delete("/policies/:id", middleware.AuthMiddleware(cfg), middleware.RequirePermission("late-fees.manage", getRoleService), lateFeeHandler.DeletePolicy)
+	lateFees.Post("/:id/waive", middleware.AuthMiddleware(cfg), middleware.RequirePermission("late-fees.manage", getRoleService), lateFeeHandler.WaiveFee)
+
+	// Budget routes
+	budgets := api.Group("/budgets")
+	budgets.Get("/", middleware.AuthMiddleware(cfg), budgetHandler.GetBudgets)
+	budgets.Get("/report", middleware.AuthMiddleware(cfg), budgetHandler.GetReport)
+	budgets.Put("/", middleware.AuthMiddleware(cfg), middleware.RequirePermission("budgets.manage", getRoleService), budgetHandler.SetBudget)
+	budgets.Delete("/:id", middleware.AuthMiddleware(cfg), middleware.RequirePermission("budgets.manage", getRoleService), budgetHandler.DeleteBudget)
+
+	// Analytics routes
+	analytics := api.Group("/analytics")
+	analytics.Get("/costs", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.read", getRoleService), analyticsHandler.GetCosts)
+	analytics.Get("/per-person", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.read", getRoleService), analyticsHandler.GetPersonSpend)
+	analytics.Get("/year-over-year", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.read", getRoleService), analyticsHandler.GetYearOverYear)
+	analytics.Get("/unit-prices", middleware.AuthMiddleware(cfg), middleware.RequirePermission("bills.read", getRoleService), analyticsHandler.GetUnitPrices)
+	analytics.Get("/supplies", middleware.AuthMiddleware(cfg), middleware.RequirePermission("supplies.read", getRoleService), analyticsHandler.GetSupplySpend)
 
 	// Loan payment routes
 	loanPayments := api.Group("/loan-payments")
@@ -333,6 +484,12 @@ func main() {
 	supplies.Patch("/items/:id/quantity", middleware.AuthMiddleware(cfg), supplyHandler.SetQuantity)
 	supplies.Post("/items/:id/refund", middleware.AuthMiddleware(cfg), middleware.RequirePermission("supplies.update", getRoleService), supplyHandler.MarkAsRefunded)
 	supplies.Delete("/items/:id", middleware.AuthMiddleware(cfg), supplyHandler.DeleteItem)
+	// Restock receipts are attached to each restock in the item history
+	supplies.Get("/items/:id/restocks", middleware.AuthMiddleware(cfg), supplyHandler.GetRestocks)
+	supplies.Get("/restocks/:id/attachments", middleware.AuthMiddleware(cfg), middleware.RequirePermission("supplies.read", getRoleService), attachmentHandler.GetAttachments(services.AttachmentResourceSupplyRestock))
+	supplies.Post("/restocks/:id/attachments", middleware.AuthMiddleware(cfg), middleware.RequirePermission("supplies.update", getRoleService), attachmentHandler.UploadAttachment(services.AttachmentResourceSupplyRestock))
+	supplies.Get("/restocks/:id/attachments/:attachmentId", middleware.AuthMiddleware(cfg), middleware.RequirePermission("supplies.read", getRoleService), attachmentHandler.DownloadAttachment(services.AttachmentResourceSupplyRestock))
+	supplies.Delete("/restocks/:id/attachments/:attachmentId", middleware.AuthMiddleware(cfg), middleware.RequirePermission("supplies.update", getRoleService), attachmentHandler.DeleteAttachment(services.AttachmentResourceSupplyRestock))
 
 	// Contributions
 	supplies.Get("/contributions", middleware.AuthMiddleware(cfg), supplyHandler.GetContributions)
@@ -356,6 +513,8 @@ func main() {
 	exports.Get("/balances", middleware.AuthMiddleware(cfg), exportHandler.ExportBalances)
 	exports.Get("/chores", middleware.AuthMiddleware(cfg), exportHandler.ExportChores)
 	exports.Get("/consumptions", middleware.AuthMiddleware(cfg), exportHandler.ExportConsumptions)
+	exports.Get("/ledger", middleware.AuthMiddleware(cfg), exportHandler.ExportMyLedger)
+	exports.Get("/ledger/:userId", middleware.AuthMiddleware(cfg), middleware.RequirePermission("users.read", getRoleService), exportHandler.ExportUserLedger)
 
 	// Audit log routes
 	audit := api.Group("/audit")
@@ -403,6 +562,18 @@ func main() {
 	appSettings.Get("/languages", appSettingsHandler.GetSupportedLanguages) // Public - get supported languages
 	appSettings.Patch("/", middleware.AuthMiddleware(cfg), middleware.RequirePermission("settings.app.update", getRoleService), appSettingsHandler.UpdateSettings)
 
+	// Exchange rate routes (rates are maintained locally by admins)
+	exchangeRates := api.Group("/exchange-rates")
+	exchangeRates.Get("/", middleware.AuthMiddleware(cfg), exchangeRateHandler.GetExchangeRates)
+	exchangeRates.Post("/", middleware.AuthMiddleware(cfg), middleware.RequirePermission("settings.app.update", getRoleService), exchangeRateHandler.SetExchangeRate)
+	exchangeRates.Delete("/:id", middleware.AuthMiddleware(cfg), middleware.RequirePermission("settings.app.update", getRoleService), exchangeRateHandler.DeleteExchangeRate)
+
+	// Utility tariff routes
+	tariffs := api.Group("/tariffs")
+	tariffs.Get("/", middleware.AuthMiddleware(cfg), forecastHandler.GetTariffs)
+	tariffs.Post("/", middleware.AuthMiddleware(cfg), middleware.RequirePermission("settings.app.update", getRoleService), forecastHandler.SetTariff)
+	tariffs.Delete("/:id", middleware.AuthMiddleware(cfg), middleware.RequirePermission("settings.app.update", getRoleService), forecastHandler.DeleteTariff)
+
 	// Reminder routes
 	reminders := api.Group("/reminders")
 	reminders.Post("/debt/:userId", middleware.AuthMiddleware(cfg), middleware.RequirePermission("reminders.send", getRoleService), reminderHandler.SendDebtReminder)
@@ -489,6 +660,20 @@ func main() {
 		}
 	}()
 
+	// Start reading photo retention job (removes photos of readings on long closed bills)
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if removed, err := attachmentService.RemoveExpiredReadingPhotos(context.Background(), time.Now()); err != nil {
+				log.Printf("Error during reading photo cleanup: %v", err)
+			} else if removed > 0 {
+				log.Printf("Removed %d expired reading photos", removed)
+			}
+		}
+	}()
+
 	// Start reminder cleanup job (removes reminders older than 30 days)
 	go func() {
 		ticker := time.NewTicker(24 * time.Hour)
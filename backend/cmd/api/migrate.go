@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/config"
+	"github.com/sainaif/holy-home/internal/database"
+)
+
+const migrateUsage = `Usage: holyhome migrate <command>
+
+Commands:
+  status    List migrations and whether they have been applied
+  dry-run   Apply pending migrations in a transaction and roll it back
+  up        Apply pending migrations (also done automatically at startup)
+`
+
+// runMigrateCommand handles "holyhome migrate ..." and returns the process exit code
+func runMigrateCommand(cfg *config.Config, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	db, err := database.Open(cfg.SQLite.DatabasePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	fmt.Printf("Database: %s\n", cfg.SQLite.DatabasePath)
+
+	switch args[0] {
+	case "status":
+		statuses, err := db.MigrationStatus(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read migration status: %v\n", err)
+			return 1
+		}
+		pending := 0
+		for _, st := range statuses {
+			switch {
+			case st.Unknown:
+				fmt.Printf("  %04d  %-40s applied %s (not known to this build)\n", st.Version, "?", st.AppliedAt.Format(time.RFC3339))
+			case st.AppliedAt != nil:
+				fmt.Printf("  %04d  %-40s applied %s\n", st.Version, st.Name, st.AppliedAt.Format(time.RFC3339))
+			default:
+				pending++
+				fmt.Printf("  %04d  %-40s pending\n", st.Version, st.Name)
+			}
+		}
+		fmt.Printf("%d pending migration(s)\n", pending)
+
+	case "dry-run":
+		migrations, err := db.DryRunMigrations(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Dry run failed, nothing was changed: %v\n", err)
+			return 1
+		}
+		if len(migrations) == 0 {
+			fmt.Println("Schema is up to date")
+			return 0
+		}
+		for _, m := range migrations {
+			fmt.Printf("  %04d  %s  OK\n", m.Version, m.Name)
+		}
+		fmt.Printf("%d migration(s) would be applied; changes were rolled back\n", len(migrations))
+
+	case "up":
+		if err := db.Migrate(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Migration failed, nothing was changed: %v\n", err)
+			return 1
+		}
+		fmt.Println("Schema is up to date")
+
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	return 0
+}
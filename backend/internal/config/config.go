@@ -3,17 +3,22 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
-	App     AppConfig
-	JWT     JWTConfig
-	Admin   AdminConfig
-	Auth    AuthConfig
-	SQLite  SQLiteConfig
-	Logging LogConfig
-	VAPID   VAPIDConfig
+	App         AppConfig
+	JWT         JWTConfig
+	Admin       AdminConfig
+	Auth        AuthConfig
+	SQLite      SQLiteConfig
+	Attachments AttachmentsConfig
+	Bills       BillsConfig
+	Logging     LogConfig
+	VAPID       VAPIDConfig
 }
 
 type VAPIDConfig struct {
@@ -57,6 +62,18 @@ type SQLiteConfig struct {
 	DatabasePath string // Path to SQLite database file
 }
 
+type AttachmentsConfig struct {
+	Dir          string   // Blob store directory, defaults to "attachments" next to the database
+	MaxSizeBytes int64    // Largest accepted upload
+	AllowedTypes []string // Accepted content types, detected from the file contents
+	// Days a meter reading's photo is kept once the reading's bill is closed; 0 keeps photos forever
+	ReadingPhotoRetentionDays int
+}
+
+type BillsConfig struct {
+	AllowUnpaidClose bool // bills with unpaid shares can be closed without forcing it
+}
+
 type LogConfig struct {
 	Level  string
 	Format string
@@ -73,6 +90,18 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid JWT_REFRESH_TTL: %w", err)
 	}
 
+	maxAttachmentMB, err := strconv.Atoi(getEnv("ATTACHMENTS_MAX_SIZE_MB", "10"))
+	if err != nil || maxAttachmentMB <= 0 {
+		return nil, fmt.Errorf("invalid ATTACHMENTS_MAX_SIZE_MB: %q", os.Getenv("ATTACHMENTS_MAX_SIZE_MB"))
+	}
+
+	photoRetentionDays, err := strconv.Atoi(getEnv("READING_PHOTO_RETENTION_DAYS", "365"))
+	if err != nil || photoRetentionDays < 0 {
+		return nil, fmt.Errorf("invalid READING_PHOTO_RETENTION_DAYS: %q", os.Getenv("READING_PHOTO_RETENTION_DAYS"))
+	}
+
+	databasePath := getEnv("DATABASE_PATH", "./holyhome.db")
+
 	return &Config{
 		App: AppConfig{
 			Name:           getEnv("APP_NAME", "Holy Home"),
@@ -101,7 +130,16 @@ func Load() (*Config, error) {
 			RequireUsername:    getEnv("AUTH_REQUIRE_USERNAME", "false") == "true",
 		},
 		SQLite: SQLiteConfig{
-			DatabasePath: getEnv("DATABASE_PATH", "./holyhome.db"),
+			DatabasePath: databasePath,
+		},
+		Attachments: AttachmentsConfig{
+			Dir:                       getEnv("ATTACHMENTS_DIR", filepath.Join(filepath.Dir(databasePath), "attachments")),
+			MaxSizeBytes:              int64(maxAttachmentMB) << 20,
+			AllowedTypes:              splitList(getEnv("ATTACHMENTS_ALLOWED_TYPES", "application/pdf,image/jpeg,image/png,image/webp,image/gif")),
+			ReadingPhotoRetentionDays: photoRetentionDays,
+		},
+		Bills: BillsConfig{
+			AllowUnpaidClose: getEnv("BILLS_ALLOW_UNPAID_CLOSE", "false") == "true",
 		},
 		Logging: LogConfig{
 			Level:  getEnv("LOG_LEVEL", "info"),
@@ -120,3 +158,13 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,308 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationFilePattern matches migration files such as 0002_add_bill_splits.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.sql$`)
+
+// Migration is a numbered, embedded up-migration
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// MigrationStatus describes a migration and when it was applied (nil if pending).
+// Unknown marks versions recorded in the database that this build does not ship.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Unknown   bool
+}
+
+// legacyColumns were added by hand before versioned migrations existed.
+// Databases created before schema_migrations may be missing them, and
+// CREATE TABLE IF NOT EXISTS in the baseline migration will not add them.
+var legacyColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"app_settings", "reminder_rate_limit_per_hour", "INTEGER NOT NULL DEFAULT 1"},
+	// Multi-currency: existing records were all entered in PLN
+	{"app_settings", "base_currency", "TEXT NOT NULL DEFAULT 'PLN'"},
+	{"bills", "currency", "TEXT NOT NULL DEFAULT 'PLN'"},
+	{"recurring_bill_templates", "currency", "TEXT NOT NULL DEFAULT 'PLN'"},
+	{"loans", "currency", "TEXT NOT NULL DEFAULT 'PLN'"},
+	{"supply_items", "last_restock_currency", "TEXT"},
+}
+
+// LoadMigrations returns the embedded migrations ordered by version
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationsFS, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version: %s", entry.Name())
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, other, entry.Name())
+		}
+		seen[version] = entry.Name()
+
+		content, err := migrationsFS.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    match[2],
+			SQL:     string(content),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Migrate applies all pending migrations in a single transaction
+func (s *SQLiteDB) Migrate(ctx context.Context) error {
+	applied, err := s.applyPendingMigrations(ctx, false)
+	if err != nil {
+		return err
+	}
+	for _, m := range applied {
+		log.Printf("Migration: Applied %04d_%s", m.Version, m.Name)
+	}
+	return nil
+}
+
+// DryRunMigrations applies all pending migrations and rolls them back,
+// returning the migrations that would have been applied
+func (s *SQLiteDB) DryRunMigrations(ctx context.Context) ([]Migration, error) {
+	return s.applyPendingMigrations(ctx, true)
+}
+
+// MigrationStatus lists every known migration together with its applied time
+func (s *SQLiteDB) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(ctx, s.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = MigrationStatus{Version: m.Version, Name: m.Name}
+		if appliedAt, ok := applied[m.Version]; ok {
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+
+	for _, version := range unknownVersions(migrations, applied) {
+		appliedAt := applied[version]
+		statuses = append(statuses, MigrationStatus{Version: version, AppliedAt: &appliedAt, Unknown: true})
+	}
+
+	return statuses, nil
+}
+
+// applyPendingMigrations runs every pending migration in one transaction so an
+// upgrade either completes or leaves the database untouched
+func (s *SQLiteDB) applyPendingMigrations(ctx context.Context, dryRun bool) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin migration transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Databases created before versioned migrations have tables but no history
+	legacy, err := isLegacyDatabase(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TEXT NOT NULL
+		)
+	`); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	if legacy {
+		log.Println("Migration: Upgrading database created before versioned migrations")
+		for _, c := range legacyColumns {
+			if err := addColumnIfMissing(ctx, tx, c.table, c.column, c.definition); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	applied, err := appliedMigrations(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	// Refuse to run an older build against a newer schema
+	if unknown := unknownVersions(migrations, applied); len(unknown) > 0 {
+		return nil, fmt.Errorf("database has migrations this build does not know about: %v", unknown)
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+			return nil, fmt.Errorf("migration %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+			m.Version, m.Name, time.Now().UTC().Format(time.RFC3339),
+		); err != nil {
+			return nil, fmt.Errorf("failed to record migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		pending = append(pending, m)
+	}
+
+	if dryRun {
+		return pending, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit migrations: %w", err)
+	}
+	return pending, nil
+}
+
+// appliedMigrations returns applied versions with their timestamps (empty if the table does not exist yet)
+func appliedMigrations(ctx context.Context, q sqlx.QueryerContext) (map[int]time.Time, error) {
+	exists, err := tableExists(ctx, q, "schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]time.Time)
+	if !exists {
+		return applied, nil
+	}
+
+	var rows []struct {
+		Version   int    `db:"version"`
+		AppliedAt string `db:"applied_at"`
+	}
+	if err := sqlx.SelectContext(ctx, q, &rows, "SELECT version, applied_at FROM schema_migrations ORDER BY version"); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	for _, row := range rows {
+		appliedAt, _ := time.Parse(time.RFC3339, row.AppliedAt)
+		applied[row.Version] = appliedAt
+	}
+	return applied, nil
+}
+
+// isLegacyDatabase reports whether the schema predates the schema_migrations table
+func isLegacyDatabase(ctx context.Context, q sqlx.QueryerContext) (bool, error) {
+	hasHistory, err := tableExists(ctx, q, "schema_migrations")
+	if err != nil || hasHistory {
+		return false, err
+	}
+	return tableExists(ctx, q, "users")
+}
+
+func tableExists(ctx context.Context, q sqlx.QueryerContext, table string) (bool, error) {
+	var count int
+	if err := sqlx.GetContext(ctx, q, &count, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table); err != nil {
+		return false, fmt.Errorf("failed to check %s table: %w", table, err)
+	}
+	return count > 0, nil
+}
+
+// addColumnIfMissing adds a column to an existing table unless it is already there
+func addColumnIfMissing(ctx context.Context, tx *sqlx.Tx, table, column, definition string) error {
+	exists, err := tableExists(ctx, tx, table)
+	if err != nil || !exists {
+		// The baseline migration creates missing tables with all their columns
+		return err
+	}
+
+	var count int
+	err = tx.GetContext(ctx, &count, `
+		SELECT COUNT(*) FROM pragma_table_info(?)
+		WHERE name = ?
+	`, table, column)
+	if err != nil {
+		return fmt.Errorf("failed to check %s column: %w", table, err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	if err != nil {
+		return fmt.Errorf("failed to add %s column: %w", column, err)
+	}
+	log.Printf("Migration: Added %s column to %s", column, table)
+	return nil
+}
+
+// unknownVersions returns applied versions that have no embedded migration, in order
+func unknownVersions(migrations []Migration, applied map[int]time.Time) []int {
+	known := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+	}
+
+	var unknown []int
+	for version := range applied {
+		if !known[version] {
+			unknown = append(unknown, version)
+		}
+	}
+	sort.Ints(unknown)
+	return unknown
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestDB(t *testing.T) *SQLiteDB {
+	t.Helper()
+
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	assert.Equal(t, 1, migrations[0].Version)
+	assert.Equal(t, "initial_schema", migrations[0].Name)
+	for i := 1; i < len(migrations); i++ {
+		assert.Greater(t, migrations[i].Version, migrations[i-1].Version, "migrations must be ordered by version")
+	}
+}
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	require.NoError(t, db.Migrate(ctx))
+	// Running again must be a no-op
+	require.NoError(t, db.Migrate(ctx))
+
+	statuses, err := db.MigrationStatus(ctx)
+	require.NoError(t, err)
+	for _, st := range statuses {
+		assert.NotNil(t, st.AppliedAt, "migration %04d_%s should be applied", st.Version, st.Name)
+	}
+
+	exists, err := tableExists(ctx, db.DB, "users")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestDryRunMigrationsLeavesDatabaseUntouched(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	migrations, err := db.DryRunMigrations(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+
+	for _, table := range []string{"users", "schema_migrations"} {
+		exists, err := tableExists(ctx, db.DB, table)
+		require.NoError(t, err)
+		assert.False(t, exists, "%s should have been rolled back", table)
+	}
+
+	statuses, err := db.MigrationStatus(ctx)
+	require.NoError(t, err)
+	for _, st := range statuses {
+		assert.Nil(t, st.AppliedAt)
+	}
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	// A database created before versioned migrations: tables exist, no history,
+	// and a column that used to be added by hand is missing
+	migrations, err := LoadMigrations()
+	require.NoError(t, err)
+	_, err = db.DB.ExecContext(ctx, migrations[0].SQL)
+	require.NoError(t, err)
+	_, err = db.DB.ExecContext(ctx, "ALTER TABLE loans DROP COLUMN currency")
+	require.NoError(t, err)
+
+	require.NoError(t, db.Migrate(ctx))
+
+	var count int
+	require.NoError(t, db.DB.GetContext(ctx, &count, "SELECT COUNT(*) FROM pragma_table_info('loans') WHERE name = 'currency'"))
+	assert.Equal(t, 1, count)
+
+	statuses, err := db.MigrationStatus(ctx)
+	require.NoError(t, err)
+	assert.NotNil(t, statuses[0].AppliedAt)
+}
+
+func TestMigrateRefusesUnknownVersions(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	require.NoError(t, db.Migrate(ctx))
+	_, err := db.DB.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (9999, 'from_the_future', '2030-01-01T00:00:00Z')")
+	require.NoError(t, err)
+
+	assert.Error(t, db.Migrate(ctx))
+
+	statuses, err := db.MigrationStatus(ctx)
+	require.NoError(t, err)
+	last := statuses[len(statuses)-1]
+	assert.Equal(t, 9999, last.Version)
+	assert.True(t, last.Unknown)
+}
@@ -1,6 +1,6 @@
--- Holy Home SQLite Schema
--- Version: 1.5 (Bridge Release)
--- This schema migrates from MongoDB document store to SQLite relational database
+-- Migration 0001: initial schema
+-- Baseline of the Holy Home SQLite schema (formerly schema.sql, version 1.5 Bridge Release).
+-- Later schema changes go into new numbered migrations instead of editing this file.
 
 -- ============================================
 -- CORE ENTITIES
@@ -60,6 +60,7 @@ CREATE TABLE IF NOT EXISTS recurring_bill_templates (
     custom_type TEXT NOT NULL,
     frequency TEXT NOT NULL,
     amount TEXT NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'PLN',
     day_of_month INTEGER NOT NULL,
     start_date TEXT NOT NULL,
     notes TEXT,
@@ -81,6 +82,7 @@ CREATE TABLE IF NOT EXISTS bills (
     period_end TEXT NOT NULL,
     payment_deadline TEXT,
     total_amount_pln TEXT NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'PLN',
     total_units TEXT,
     notes TEXT,
     status TEXT NOT NULL DEFAULT 'draft',
@@ -162,6 +164,7 @@ CREATE TABLE IF NOT EXISTS loans (
     lender_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     borrower_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     amount_pln TEXT NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'PLN',
     note TEXT,
     due_date TEXT,
     status TEXT NOT NULL DEFAULT 'open',
@@ -257,6 +260,7 @@ CREATE TABLE IF NOT EXISTS supply_items (
     last_restocked_at TEXT,
     last_restocked_by_user_id TEXT REFERENCES users(id) ON DELETE SET NULL,
     last_restock_amount_pln TEXT,
+    last_restock_currency TEXT,
     needs_refund INTEGER NOT NULL DEFAULT 0,
     notes TEXT
 );
@@ -439,9 +443,26 @@ CREATE TABLE IF NOT EXISTS app_settings (
     default_language TEXT NOT NULL DEFAULT 'en',
     disable_auto_detect INTEGER NOT NULL DEFAULT 0,
     reminder_rate_limit_per_hour INTEGER NOT NULL DEFAULT 1,
+    base_currency TEXT NOT NULL DEFAULT 'PLN',
     updated_at TEXT NOT NULL DEFAULT (datetime('now'))
 );
 
+-- ============================================
+-- EXCHANGE RATES (maintained locally by admins)
+-- ============================================
+
+-- rate is the value of one unit of currency expressed in the household base currency
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    id TEXT PRIMARY KEY,
+    currency TEXT NOT NULL,
+    rate TEXT NOT NULL,
+    rate_date TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (datetime('now')),
+    UNIQUE(currency, rate_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_currency_date ON exchange_rates(currency, rate_date);
+
 -- ============================================
 -- SENT REMINDERS (for rate limiting & deduplication)
 -- ============================================
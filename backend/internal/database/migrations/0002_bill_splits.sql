@@ -0,0 +1,18 @@
+-- Migration 0002: per-bill split rules
+-- Custom split definitions for one-off bills (allocation_type 'custom').
+-- They are turned into rows of the allocations table when the bill is posted.
+
+CREATE TABLE IF NOT EXISTS bill_splits (
+    id TEXT PRIMARY KEY,
+    bill_id TEXT NOT NULL REFERENCES bills(id) ON DELETE CASCADE,
+    subject_type TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    allocation_type TEXT NOT NULL,
+    percentage REAL,
+    fraction_numerator INTEGER,
+    fraction_denominator INTEGER,
+    fixed_amount TEXT,
+    shares REAL
+);
+
+CREATE INDEX IF NOT EXISTS idx_bill_splits_bill ON bill_splits(bill_id);
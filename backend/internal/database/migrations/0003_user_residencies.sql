@@ -0,0 +1,13 @@
+-- Migration 0003: residency intervals
+-- Move-in and move-out dates per user, used to prorate shared bill costs.
+-- Users without any interval are treated as present whenever they are active.
+
+CREATE TABLE IF NOT EXISTS user_residencies (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    moved_in_at TEXT NOT NULL,
+    moved_out_at TEXT,
+    created_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_residencies_user ON user_residencies(user_id, moved_in_at);
@@ -0,0 +1,21 @@
+-- Migration 0004: itemized receipts
+-- Line items of bills with allocation_type 'itemized'. Each item is split equally among its
+-- participants; tax and discount rows have no participants and are spread over the item subtotals.
+
+CREATE TABLE IF NOT EXISTS bill_items (
+    id TEXT PRIMARY KEY,
+    bill_id TEXT NOT NULL REFERENCES bills(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL DEFAULT 'item',
+    description TEXT NOT NULL DEFAULT '',
+    amount TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_bill_items_bill ON bill_items(bill_id, position);
+
+CREATE TABLE IF NOT EXISTS bill_item_participants (
+    item_id TEXT NOT NULL REFERENCES bill_items(id) ON DELETE CASCADE,
+    subject_type TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    PRIMARY KEY (item_id, subject_type, subject_id)
+);
@@ -0,0 +1,18 @@
+-- Migration 0005: per-user bill credit
+-- Ledger of credit movements. An overpayment on a bill adds credit (positive amount),
+-- covering a later allocation uses it up (negative amount). A user's balance is the sum
+-- of their entries in one currency.
+
+CREATE TABLE IF NOT EXISTS credit_entries (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    bill_id TEXT REFERENCES bills(id) ON DELETE SET NULL,
+    payment_id TEXT REFERENCES payments(id) ON DELETE SET NULL,
+    kind TEXT NOT NULL,
+    amount TEXT NOT NULL,
+    currency TEXT NOT NULL,
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_entries_user ON credit_entries(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_credit_entries_bill ON credit_entries(bill_id);
@@ -0,0 +1,34 @@
+-- Migration 0006: bank statement import
+-- Uploaded statement files and the incoming transfers read from them. Pending transactions form
+-- the review queue; confirming a match records a bill payment or loan repayment and links it here.
+
+CREATE TABLE IF NOT EXISTS bank_imports (
+    id TEXT PRIMARY KEY,
+    file_name TEXT NOT NULL,
+    format TEXT NOT NULL,
+    imported_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    line_count INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS bank_transactions (
+    id TEXT PRIMARY KEY,
+    import_id TEXT NOT NULL REFERENCES bank_imports(id) ON DELETE CASCADE,
+    fingerprint TEXT NOT NULL UNIQUE,
+    booked_at TEXT NOT NULL,
+    amount TEXT NOT NULL,
+    currency TEXT NOT NULL,
+    counterparty_name TEXT,
+    counterparty_account TEXT,
+    title TEXT NOT NULL DEFAULT '',
+    reference TEXT,
+    status TEXT NOT NULL DEFAULT 'pending',
+    match_type TEXT,
+    payment_id TEXT REFERENCES payments(id) ON DELETE SET NULL,
+    loan_payment_id TEXT REFERENCES loan_payments(id) ON DELETE SET NULL,
+    reviewed_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_bank_transactions_import ON bank_transactions(import_id, booked_at);
+CREATE INDEX IF NOT EXISTS idx_bank_transactions_status ON bank_transactions(status, booked_at);
@@ -0,0 +1,17 @@
+-- Migration 0007: payment requests
+-- Bank accounts residents receive transfers on. A bill can name the account its shares are
+-- paid to; payment requests and transfer QR codes for each allocation are built from it.
+
+CREATE TABLE IF NOT EXISTS user_bank_accounts (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    label TEXT NOT NULL DEFAULT '',
+    holder_name TEXT NOT NULL,
+    account_number TEXT NOT NULL,
+    is_default INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_bank_accounts_user ON user_bank_accounts(user_id);
+
+ALTER TABLE bills ADD COLUMN recipient_account_id TEXT REFERENCES user_bank_accounts(id) ON DELETE SET NULL;
@@ -0,0 +1,34 @@
+-- Migration 0008: attachments
+-- Scanned invoices and receipts attached to bills, supply items (restock receipts) and loans.
+-- File contents live in the content-addressed blob store next to the database, keyed by
+-- their SHA-256; identical uploads share one blob. Rows go away with the record they belong to.
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id TEXT PRIMARY KEY,
+    resource_type TEXT NOT NULL,
+    resource_id TEXT NOT NULL,
+    file_name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    sha256 TEXT NOT NULL,
+    uploaded_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_resource ON attachments(resource_type, resource_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_sha256 ON attachments(sha256);
+
+CREATE TRIGGER IF NOT EXISTS trg_attachments_bill_deleted AFTER DELETE ON bills
+BEGIN
+    DELETE FROM attachments WHERE resource_type = 'bill' AND resource_id = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_attachments_loan_deleted AFTER DELETE ON loans
+BEGIN
+    DELETE FROM attachments WHERE resource_type = 'loan' AND resource_id = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_attachments_supply_item_deleted AFTER DELETE ON supply_items
+BEGIN
+    DELETE FROM attachments WHERE resource_type = 'supply_item' AND resource_id = OLD.id;
+END;
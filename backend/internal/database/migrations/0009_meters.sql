@@ -0,0 +1,36 @@
+-- Migration 0009: meter registry and tariff zones
+-- Meters belong to a user or group and are read in one tariff zone (e.g. day or night).
+-- A replaced meter keeps its final value and links to its successor through replaces_meter_id,
+-- so the usage between the last reading and the swap is not lost.
+-- Bills with tariff zones price the units of each zone separately.
+
+CREATE TABLE IF NOT EXISTS meters (
+    id TEXT PRIMARY KEY,
+    type TEXT NOT NULL,
+    unit TEXT NOT NULL,
+    subject_type TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    tariff_zone TEXT NOT NULL DEFAULT 'single',
+    serial_number TEXT NOT NULL DEFAULT '',
+    installed_at TEXT NOT NULL,
+    initial_value TEXT NOT NULL DEFAULT '0',
+    replaced_at TEXT,
+    final_value TEXT,
+    replaces_meter_id TEXT REFERENCES meters(id) ON DELETE SET NULL,
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_meters_subject ON meters(subject_type, subject_id);
+CREATE INDEX IF NOT EXISTS idx_meters_replaces ON meters(replaces_meter_id);
+
+ALTER TABLE consumptions ADD COLUMN meter_id TEXT REFERENCES meters(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_consumptions_meter ON consumptions(meter_id, recorded_at);
+
+CREATE TABLE IF NOT EXISTS bill_tariff_zones (
+    bill_id TEXT NOT NULL REFERENCES bills(id) ON DELETE CASCADE,
+    zone TEXT NOT NULL,
+    units TEXT NOT NULL,
+    amount TEXT NOT NULL,
+    PRIMARY KEY (bill_id, zone)
+);
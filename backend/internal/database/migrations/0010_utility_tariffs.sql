@@ -0,0 +1,16 @@
+-- Migration 0010: utility tariffs
+-- Unit prices and fixed monthly fees of a metered utility, valid from a given day until the next
+-- tariff for the same utility and zone. Used to forecast bills before the invoice arrives.
+-- custom_type is empty except for "inne" bills, where it names the utility (e.g. Woda).
+
+CREATE TABLE IF NOT EXISTS utility_tariffs (
+    id TEXT PRIMARY KEY,
+    bill_type TEXT NOT NULL,
+    custom_type TEXT NOT NULL DEFAULT '',
+    tariff_zone TEXT NOT NULL DEFAULT 'single',
+    unit_price TEXT NOT NULL,
+    fixed_fee_monthly TEXT NOT NULL DEFAULT '0',
+    valid_from TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    UNIQUE(bill_type, custom_type, tariff_zone, valid_from)
+);
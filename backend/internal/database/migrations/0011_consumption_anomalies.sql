@@ -0,0 +1,30 @@
+-- Migration 0011: consumption anomalies
+-- The scheduler checks every new reading against the subject's usual daily usage and flags
+-- spikes, meter values that go backwards or jump implausibly, and meters nobody has read for a while.
+-- Readings that existed before this migration are treated as already checked, so upgrading
+-- does not flag the whole history at once.
+
+CREATE TABLE IF NOT EXISTS consumption_anomalies (
+    id TEXT PRIMARY KEY,
+    consumption_id TEXT REFERENCES consumptions(id) ON DELETE CASCADE,
+    meter_id TEXT REFERENCES meters(id) ON DELETE CASCADE,
+    subject_type TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    daily_units TEXT,
+    expected_daily_units TEXT,
+    message TEXT NOT NULL,
+    detected_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_consumption_anomalies_consumption ON consumption_anomalies(consumption_id);
+CREATE INDEX IF NOT EXISTS idx_consumption_anomalies_meter ON consumption_anomalies(meter_id, kind, detected_at);
+
+ALTER TABLE consumptions ADD COLUMN anomaly_checked_at TEXT;
+
+UPDATE consumptions SET anomaly_checked_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now');
+
+-- Anomaly alerts are on by default, like the other notification categories
+UPDATE notification_preferences
+SET preferences = json_set(preferences, '$.consumption_anomaly', json('true'))
+WHERE json_extract(preferences, '$.consumption_anomaly') IS NULL;
@@ -0,0 +1,8 @@
+-- Migration 0012: reading photos
+-- Meter readings can carry a photo of the meter as evidence of the value typed in. Photos are
+-- attachments of the reading, stored in the blob store like invoices, and go away with the reading.
+
+CREATE TRIGGER IF NOT EXISTS trg_attachments_consumption_deleted AFTER DELETE ON consumptions
+BEGIN
+    DELETE FROM attachments WHERE resource_type = 'consumption' AND resource_id = OLD.id;
+END;
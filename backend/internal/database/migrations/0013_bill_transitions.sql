@@ -0,0 +1,18 @@
+-- Migration 0013: bill transitions
+-- Every change of a bill's status is recorded with who made it and why, so the history of a bill
+-- can be shown next to it. Bills also gain the awaiting_payment and disputed statuses; the status
+-- column is plain text, so only the history table is new.
+
+CREATE TABLE IF NOT EXISTS bill_transitions (
+    id TEXT PRIMARY KEY,
+    bill_id TEXT NOT NULL REFERENCES bills(id) ON DELETE CASCADE,
+    transition TEXT NOT NULL,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    actor_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT,
+    forced INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_bill_transitions_bill ON bill_transitions(bill_id, created_at);
@@ -0,0 +1,24 @@
+-- Migration 0014: bill disputes
+-- A resident who thinks their share of a posted bill is wrong opens a dispute with a reason and the
+-- amount they think is right. The bill is held in disputed status until every dispute is resolved;
+-- an accepted dispute changes the stored allocations of the bill.
+
+CREATE TABLE IF NOT EXISTS bill_disputes (
+    id TEXT PRIMARY KEY,
+    bill_id TEXT NOT NULL REFERENCES bills(id) ON DELETE CASCADE,
+    subject_type TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    opened_by TEXT NOT NULL REFERENCES users(id),
+    reason TEXT NOT NULL,
+    allocated_amount TEXT NOT NULL,
+    proposed_amount TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open',
+    resolved_amount TEXT,
+    resolution_note TEXT,
+    resolved_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TEXT,
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_bill_disputes_bill ON bill_disputes(bill_id, status);
+CREATE INDEX IF NOT EXISTS idx_bill_disputes_status ON bill_disputes(status, created_at);
@@ -0,0 +1,15 @@
+-- Migration 0015: bill versions
+-- Every time a bill is posted, the bill and its allocations are saved as a new version, so a
+-- bill amended after a corrected invoice keeps what everyone was charged before.
+
+CREATE TABLE IF NOT EXISTS bill_versions (
+    id TEXT PRIMARY KEY,
+    bill_id TEXT NOT NULL REFERENCES bills(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    snapshot TEXT NOT NULL,
+    allocations TEXT NOT NULL,
+    reason TEXT,
+    created_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TEXT NOT NULL,
+    UNIQUE(bill_id, version)
+);
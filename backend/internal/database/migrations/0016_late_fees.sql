@@ -0,0 +1,56 @@
+-- Migration 0016: late fees
+-- Late-fee policies are set per bill type (optionally per custom bill name) and per loan, with a
+-- default for loans without their own. Once the payment deadline and the grace days have passed,
+-- the scheduler accrues a flat fee and/or a percentage of what is still unpaid for every period,
+-- up to the cap. Each fee is an explicit record; a waived fee stays with who waived it and why.
+
+CREATE TABLE IF NOT EXISTS late_fee_policies (
+    id TEXT PRIMARY KEY,
+    resource_type TEXT NOT NULL,
+    bill_type TEXT NOT NULL DEFAULT '',
+    custom_type TEXT NOT NULL DEFAULT '',
+    loan_id TEXT NOT NULL DEFAULT '',
+    flat_fee TEXT NOT NULL DEFAULT '0.00',
+    percent_per_period TEXT NOT NULL DEFAULT '0',
+    period_days INTEGER NOT NULL DEFAULT 30,
+    grace_days INTEGER NOT NULL DEFAULT 0,
+    cap TEXT,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    UNIQUE(resource_type, bill_type, custom_type, loan_id)
+);
+
+CREATE TABLE IF NOT EXISTS late_fees (
+    id TEXT PRIMARY KEY,
+    policy_id TEXT REFERENCES late_fee_policies(id) ON DELETE SET NULL,
+    resource_type TEXT NOT NULL,
+    resource_id TEXT NOT NULL,
+    subject_type TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    beneficiary_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    kind TEXT NOT NULL,
+    period INTEGER NOT NULL DEFAULT 0,
+    amount TEXT NOT NULL,
+    currency TEXT NOT NULL,
+    accrued_at TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'accrued',
+    waived_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    waived_at TEXT,
+    waive_reason TEXT,
+    created_at TEXT NOT NULL,
+    UNIQUE(resource_type, resource_id, subject_type, subject_id, kind, period)
+);
+
+CREATE INDEX IF NOT EXISTS idx_late_fees_subject ON late_fees(subject_type, subject_id);
+CREATE INDEX IF NOT EXISTS idx_late_fees_beneficiary ON late_fees(beneficiary_id);
+
+CREATE TRIGGER IF NOT EXISTS trg_late_fees_bill_deleted AFTER DELETE ON bills
+BEGIN
+    DELETE FROM late_fees WHERE resource_type = 'bill' AND resource_id = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_late_fees_loan_deleted AFTER DELETE ON loans
+BEGIN
+    DELETE FROM late_fees WHERE resource_type = 'loan' AND resource_id = OLD.id;
+    DELETE FROM late_fee_policies WHERE resource_type = 'loan' AND loan_id = OLD.id;
+END;
@@ -0,0 +1,29 @@
+-- Migration 0017: budgets
+-- Monthly budgets per bill type (optionally per custom bill name) and per supply category, in the
+-- base currency. Actuals come from posted bills, spread over the months of their period, and from
+-- the cost of supply restocks. An alert is sent once per budget, month and threshold, when spending
+-- reaches that percentage of the budget.
+
+CREATE TABLE IF NOT EXISTS budgets (
+    id TEXT PRIMARY KEY,
+    kind TEXT NOT NULL,
+    category TEXT NOT NULL,
+    custom_type TEXT NOT NULL DEFAULT '',
+    monthly_amount TEXT NOT NULL,
+    alert_thresholds TEXT NOT NULL DEFAULT '[80,100]',
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    UNIQUE(kind, category, custom_type)
+);
+
+CREATE TABLE IF NOT EXISTS budget_alerts (
+    id TEXT PRIMARY KEY,
+    budget_id TEXT NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+    month TEXT NOT NULL,
+    threshold INTEGER NOT NULL,
+    actual TEXT NOT NULL,
+    sent_at TEXT NOT NULL,
+    UNIQUE(budget_id, month, threshold)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_alerts_month ON budget_alerts(month);
@@ -0,0 +1,20 @@
+-- Migration 0018: settle-up transfers
+-- Applying a settle-up records the transfers it asks flatmates to make, in the base currency,
+-- under one settlement ID. Loan payments created by the settle-up carry the same ID, as do the
+-- bill payments it moves between flatmates (in their reference), so each can be traced back.
+
+CREATE TABLE IF NOT EXISTS settlement_transfers (
+    id TEXT PRIMARY KEY,
+    settlement_id TEXT NOT NULL,
+    from_type TEXT NOT NULL,
+    from_id TEXT NOT NULL,
+    to_type TEXT NOT NULL,
+    to_id TEXT NOT NULL,
+    amount TEXT NOT NULL,
+    currency TEXT NOT NULL,
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_settlement_transfers_settlement ON settlement_transfers(settlement_id);
+
+ALTER TABLE loan_payments ADD COLUMN settlement_id TEXT;
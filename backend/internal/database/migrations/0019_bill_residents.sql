@@ -0,0 +1,16 @@
+-- Migration 0019: residents of posted bills
+-- When a bill is posted, the residents of its period are stored with the subject they paid with,
+-- their weight and the days they were present. Shares of a posted bill are calculated from this
+-- snapshot, so later changes to move-in and move-out dates, groups or weights do not rewrite them.
+-- Bills posted before this migration have no snapshot and are still calculated from current data.
+
+CREATE TABLE IF NOT EXISTS bill_residents (
+    bill_id TEXT NOT NULL REFERENCES bills(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    subject_type TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    weight REAL NOT NULL,
+    days_present INTEGER NOT NULL,
+    period_days INTEGER NOT NULL,
+    PRIMARY KEY (bill_id, user_id)
+);
@@ -0,0 +1,29 @@
+-- Migration 0020: receipts belong to restocks
+-- Receipts were attached to a supply item, so all its purchases shared them. They now belong to
+-- the restock entry in the supply history they were bought with. Existing receipts move to the
+-- latest restock of their item; those of items that were never restocked cannot be placed and are
+-- dropped, and their files are removed with the other orphaned blobs.
+
+UPDATE attachments
+SET resource_type = 'supply_restock',
+    resource_id = (
+        SELECT h.id FROM supply_item_history h
+        WHERE h.supply_item_id = attachments.resource_id AND h.action = 'restock'
+        ORDER BY h.created_at DESC, h.id DESC
+        LIMIT 1
+    )
+WHERE resource_type = 'supply_item'
+  AND EXISTS (
+        SELECT 1 FROM supply_item_history h
+        WHERE h.supply_item_id = attachments.resource_id AND h.action = 'restock'
+  );
+
+DELETE FROM attachments WHERE resource_type = 'supply_item';
+
+DROP TRIGGER IF EXISTS trg_attachments_supply_item_deleted;
+
+-- History entries also go away with their item through the foreign key, which fires this trigger
+CREATE TRIGGER IF NOT EXISTS trg_attachments_supply_restock_deleted AFTER DELETE ON supply_item_history
+BEGIN
+    DELETE FROM attachments WHERE resource_type = 'supply_restock' AND resource_id = OLD.id;
+END;
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sainaif/holy-home/internal/services"
+)
+
+type AnalyticsHandler struct {
+	analyticsService *services.AnalyticsService
+}
+
+func NewAnalyticsHandler(analyticsService *services.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{analyticsService: analyticsService}
+}
+
+// GetCosts returns the cost of posted bills per type and period
+// Query: from, to (YYYY-MM-DD, both inclusive), groupBy (month, quarter, year), billType
+func (h *AnalyticsHandler) GetCosts(c *fiber.Ctx) error {
+	query, err := analyticsQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	report, err := h.analyticsService.GetCosts(c.Context(), query)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// GetPersonSpend returns what each person paid towards bills and spent on supplies
+func (h *AnalyticsHandler) GetPersonSpend(c *fiber.Ctx) error {
+	query, err := analyticsQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	report, err := h.analyticsService.GetPersonSpend(c.Context(), query)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// GetYearOverYear compares the bill costs of a year (?year=, default the current one) with the year before
+func (h *AnalyticsHandler) GetYearOverYear(c *fiber.Ctx) error {
+	report, err := h.analyticsService.GetYearOverYear(c.Context(), c.QueryInt("year", time.Now().Year()), c.Query("billType"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// GetUnitPrices returns the unit price trend of metered utilities
+func (h *AnalyticsHandler) GetUnitPrices(c *fiber.Ctx) error {
+	query, err := analyticsQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	report, err := h.analyticsService.GetUnitPrices(c.Context(), query)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// GetSupplySpend returns what was spent on supplies per period, by category and buyer
+func (h *AnalyticsHandler) GetSupplySpend(c *fiber.Ctx) error {
+	query, err := analyticsQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	report, err := h.analyticsService.GetSupplySpend(c.Context(), query)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(report)
+}
+
+// analyticsQuery reads the date range and grouping of an analytics request. The range is given in
+// whole days of the household's time zone, so the day after "to" is the exclusive end.
+func analyticsQuery(c *fiber.Ctx) (services.AnalyticsQuery, error) {
+	query := services.AnalyticsQuery{
+		GroupBy:  c.Query("groupBy"),
+		BillType: c.Query("billType"),
+	}
+	if f := c.Query("from"); f != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", f, time.Local)
+		if err != nil {
+			return query, fmt.Errorf("invalid 'from' date: %q", f)
+		}
+		query.From = &parsed
+	}
+	if t := c.Query("to"); t != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", t, time.Local)
+		if err != nil {
+			return query, fmt.Errorf("invalid 'to' date: %q", t)
+		}
+		end := parsed.AddDate(0, 0, 1)
+		query.To = &end
+	}
+	return query, nil
+}
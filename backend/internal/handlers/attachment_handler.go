@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sainaif/holy-home/internal/middleware"
+	"github.com/sainaif/holy-home/internal/services"
+)
+
+// AttachmentHandler serves the attachments of bills, supply restocks, loans and readings. Each handler is
+// bound to one resource type and mounted under that resource's routes, which carry its permissions.
+type AttachmentHandler struct {
+	attachmentService *services.AttachmentService
+	auditService      *services.AuditService
+}
+
+func NewAttachmentHandler(attachmentService *services.AttachmentService, auditService *services.AuditService) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentService: attachmentService,
+		auditService:      auditService,
+	}
+}
+
+// GetAttachments lists the attachments of the record in the :id route parameter
+func (h *AttachmentHandler) GetAttachments(resourceType string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		attachments, err := h.attachmentService.ListAttachments(c.Context(), resourceType, c.Params("id"))
+		if err != nil {
+			return attachmentError(c, err)
+		}
+
+		return c.JSON(attachments)
+	}
+}
+
+// UploadAttachment attaches the uploaded "file" form field to the record in the :id route parameter
+func (h *AttachmentHandler) UploadAttachment(resourceType string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Unauthorized",
+			})
+		}
+		userEmail, err := middleware.GetUserEmail(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Unauthorized",
+			})
+		}
+
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "File is required",
+			})
+		}
+		if fileHeader.Size > h.attachmentService.MaxSize() {
+			return attachmentError(c, services.ErrAttachmentTooLarge)
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Failed to read file",
+			})
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(io.LimitReader(file, h.attachmentService.MaxSize()+1))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Failed to read file",
+			})
+		}
+		if len(data) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "File is empty",
+			})
+		}
+
+		resourceID := c.Params("id")
+		attachment, err := h.attachmentService.Upload(c.Context(), resourceType, resourceID, fileHeader.Filename, data, userID)
+		if err != nil {
+			h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "upload_attachment", "attachment", nil,
+				map[string]interface{}{"resource_type": resourceType, "resource_id": resourceID, "file_name": fileHeader.Filename, "error": err.Error()},
+				c.IP(), c.Get("User-Agent"), "failure")
+			return attachmentError(c, err)
+		}
+
+		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "upload_attachment", "attachment", &attachment.ID,
+			map[string]interface{}{
+				"resource_type": resourceType,
+				"resource_id":   resourceID,
+				"file_name":     attachment.FileName,
+				"size_bytes":    attachment.SizeBytes,
+			},
+			c.IP(), c.Get("User-Agent"), "success")
+
+		return c.Status(fiber.StatusCreated).JSON(attachment)
+	}
+}
+
+// DownloadAttachment sends the file of an attachment. PDFs and images open inline unless ?download=true.
+func (h *AttachmentHandler) DownloadAttachment(resourceType string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		attachment, data, err := h.attachmentService.Download(c.Context(), resourceType, c.Params("id"), c.Params("attachmentId"))
+		if err != nil {
+			return attachmentError(c, err)
+		}
+
+		disposition := "inline"
+		if c.QueryBool("download") {
+			disposition = "attachment"
+		}
+		c.Set("Content-Type", attachment.ContentType)
+		c.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+		c.Set("X-Content-Type-Options", "nosniff")
+		return c.Send(data)
+	}
+}
+
+// DeleteAttachment removes an attachment of the record in the :id route parameter
+func (h *AttachmentHandler) DeleteAttachment(resourceType string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := middleware.GetUserID(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Unauthorized",
+			})
+		}
+		userEmail, err := middleware.GetUserEmail(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Unauthorized",
+			})
+		}
+
+		resourceID := c.Params("id")
+		attachmentID := c.Params("attachmentId")
+		if err := h.attachmentService.DeleteAttachment(c.Context(), resourceType, resourceID, attachmentID); err != nil {
+			return attachmentError(c, err)
+		}
+
+		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "delete_attachment", "attachment", &attachmentID,
+			map[string]interface{}{"resource_type": resourceType, "resource_id": resourceID},
+			c.IP(), c.Get("User-Agent"), "success")
+
+		return c.JSON(fiber.Map{
+			"message": "Attachment deleted successfully",
+		})
+	}
+}
+
+func attachmentError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrAttachmentNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		status = fiber.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrAttachmentTypeNotAllowed):
+		status = fiber.StatusUnsupportedMediaType
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sainaif/holy-home/internal/middleware"
+	"github.com/sainaif/holy-home/internal/services"
+)
+
+type BankAccountHandler struct {
+	bankAccountService *services.BankAccountService
+	auditService       *services.AuditService
+}
+
+func NewBankAccountHandler(bankAccountService *services.BankAccountService, auditService *services.AuditService) *BankAccountHandler {
+	return &BankAccountHandler{
+		bankAccountService: bankAccountService,
+		auditService:       auditService,
+	}
+}
+
+// GetBankAccounts returns every resident's bank accounts
+func (h *BankAccountHandler) GetBankAccounts(c *fiber.Ctx) error {
+	accounts, err := h.bankAccountService.ListAccounts(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(accounts)
+}
+
+// GetMyBankAccounts returns the current user's bank accounts
+func (h *BankAccountHandler) GetMyBankAccounts(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	accounts, err := h.bankAccountService.ListUserAccounts(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(accounts)
+}
+
+// CreateBankAccount adds a bank account for the current user
+func (h *BankAccountHandler) CreateBankAccount(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req services.BankAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	account, err := h.bankAccountService.CreateAccount(c.Context(), userID, req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "create_bank_account", "bank_account", &account.ID,
+		map[string]interface{}{"label": account.Label, "is_default": account.IsDefault},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	return c.Status(fiber.StatusCreated).JSON(account)
+}
+
+// UpdateBankAccount changes one of the current user's bank accounts
+func (h *BankAccountHandler) UpdateBankAccount(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req services.BankAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	account, err := h.bankAccountService.UpdateAccount(c.Context(), userID, c.Params("id"), req)
+	if err != nil {
+		status := fiber.StatusBadRequest
+		if errors.Is(err, services.ErrBankAccountNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "update_bank_account", "bank_account", &account.ID,
+		map[string]interface{}{"label": account.Label, "is_default": account.IsDefault},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	return c.JSON(account)
+}
+
+// DeleteBankAccount removes one of the current user's bank accounts
+func (h *BankAccountHandler) DeleteBankAccount(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	accountID := c.Params("id")
+	if err := h.bankAccountService.DeleteAccount(c.Context(), userID, accountID); err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, services.ErrBankAccountNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "delete_bank_account", "bank_account", &accountID,
+		map[string]interface{}{},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	return c.JSON(fiber.Map{
+		"message": "Bank account deleted successfully",
+	})
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sainaif/holy-home/internal/middleware"
+	"github.com/sainaif/holy-home/internal/services"
+)
+
+// maxStatementSize limits uploaded bank statements
+const maxStatementSize = 5 << 20
+
+type BankImportHandler struct {
+	bankImportService *services.BankImportService
+	eventService      *services.EventService
+	auditService      *services.AuditService
+}
+
+func NewBankImportHandler(bankImportService *services.BankImportService, eventService *services.EventService, auditService *services.AuditService) *BankImportHandler {
+	return &BankImportHandler{
+		bankImportService: bankImportService,
+		eventService:      eventService,
+		auditService:      auditService,
+	}
+}
+
+// ImportStatement uploads a CSV, MT940 or CAMT.053 statement and queues its incoming transfers
+func (h *BankImportHandler) ImportStatement(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Statement file is required",
+		})
+	}
+	if fileHeader.Size > maxStatementSize {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Statement file is too large",
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read statement file",
+		})
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxStatementSize))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read statement file",
+		})
+	}
+
+	result, err := h.bankImportService.ImportStatement(c.Context(), fileHeader.Filename, data, c.FormValue("format"), userID)
+	if err != nil {
+		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "import_bank_statement", "bank_import", nil,
+			map[string]interface{}{"file_name": fileHeader.Filename, "error": err.Error()},
+			c.IP(), c.Get("User-Agent"), "failure")
+
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidStatement) {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "import_bank_statement", "bank_import", &result.Import.ID,
+		map[string]interface{}{
+			"file_name":        fileHeader.Filename,
+			"format":           result.Import.Format,
+			"imported":         result.Imported,
+			"duplicates":       result.Duplicates,
+			"skipped_outgoing": result.SkippedOutgoing,
+		},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	return c.Status(fiber.StatusCreated).JSON(result)
+}
+
+// ListImports returns uploaded statements
+func (h *BankImportHandler) ListImports(c *fiber.Ctx) error {
+	imports, err := h.bankImportService.ListImports(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(imports)
+}
+
+// ListImportTransactions returns the transactions read from one statement
+func (h *BankImportHandler) ListImportTransactions(c *fiber.Ctx) error {
+	txns, err := h.bankImportService.ListImportTransactions(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(txns)
+}
+
+// GetReviewQueue returns unmatched transactions with suggested bill shares and loans
+func (h *BankImportHandler) GetReviewQueue(c *fiber.Ctx) error {
+	queue, err := h.bankImportService.GetReviewQueue(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(queue)
+}
+
+// ConfirmMatch records a transaction as payment of the chosen bill share or loan
+func (h *BankImportHandler) ConfirmMatch(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req services.ConfirmBankMatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	txnID := c.Params("id")
+	txn, err := h.bankImportService.ConfirmMatch(c.Context(), txnID, req, userID)
+	if err != nil {
+		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "confirm_bank_match", "bank_transaction", &txnID,
+			map[string]interface{}{"type": req.Type, "error": err.Error()},
+			c.IP(), c.Get("User-Agent"), "failure")
+
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "confirm_bank_match", "bank_transaction", &txn.ID,
+		map[string]interface{}{
+			"type":            req.Type,
+			"bill_id":         req.BillID,
+			"user_id":         req.UserID,
+			"loan_id":         req.LoanID,
+			"amount":          txn.Amount,
+			"currency":        txn.Currency,
+			"payment_id":      txn.PaymentID,
+			"loan_payment_id": txn.LoanPaymentID,
+		},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	h.eventService.Broadcast(services.EventBalanceUpdated, map[string]interface{}{
+		"timestamp": time.Now(),
+	})
+
+	return c.JSON(txn)
+}
+
+// IgnoreTransaction removes a transaction that is not a household payment from the review queue
+func (h *BankImportHandler) IgnoreTransaction(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	txn, err := h.bankImportService.IgnoreTransaction(c.Context(), c.Params("id"), userID)
+	if err != nil {
+		status := fiber.StatusNotFound
+		if errors.Is(err, services.ErrBankTransactionNotPending) {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "ignore_bank_transaction", "bank_transaction", &txn.ID,
+		map[string]interface{}{"amount": txn.Amount, "currency": txn.Currency, "title": txn.Title},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	return c.JSON(txn)
+}
@@ -1,11 +1,17 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/sainaif/holy-home/internal/middleware"
+	"github.com/sainaif/holy-home/internal/models"
 	"github.com/sainaif/holy-home/internal/services"
+	"github.com/sainaif/holy-home/internal/utils"
 )
 
 type BillHandler struct {
@@ -58,10 +64,18 @@ func (h *BillHandler) CreateBill(c *fiber.Ctx) error {
 		})
 	}
 
-	auditDetails := map[string]interface{}{"type": bill.Type, "amount": req.TotalAmountPLN, "period_start": req.PeriodStart, "period_end": req.PeriodEnd}
+	// Itemized bills may leave the total to be computed from their receipt lines
+	totalAmount := utils.MoneyFromString(bill.TotalAmountPLN)
+	auditDetails := map[string]interface{}{"type": bill.Type, "amount": totalAmount, "currency": bill.Currency, "period_start": req.PeriodStart, "period_end": req.PeriodEnd}
 	if bill.CustomType != nil {
 		auditDetails["custom_type"] = *bill.CustomType
 	}
+	if len(bill.Splits) > 0 {
+		auditDetails["splits"] = bill.Splits
+	}
+	if len(bill.Items) > 0 {
+		auditDetails["items"] = len(bill.Items)
+	}
 	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "create_bill", "bill", &bill.ID,
 		auditDetails,
 		c.IP(), c.Get("User-Agent"), "success")
@@ -70,7 +84,8 @@ func (h *BillHandler) CreateBill(c *fiber.Ctx) error {
 	h.eventService.Broadcast(services.EventBillCreated, map[string]interface{}{
 		"billId":      bill.ID,
 		"type":        bill.Type,
-		"amount":      req.TotalAmountPLN,
+		"amount":      totalAmount,
+		"currency":    bill.Currency,
 		"createdBy":   userEmail,
 		"periodStart": req.PeriodStart,
 		"periodEnd":   req.PeriodEnd,
@@ -135,19 +150,103 @@ func (h *BillHandler) GetBill(c *fiber.Ctx) error {
 
 // PostBill changes status to posted (ADMIN only)
 func (h *BillHandler) PostBill(c *fiber.Ctx) error {
-	userID, err := middleware.GetUserID(c)
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
+	bill, err := h.transitionBill(c, services.BillTransitionPost, "", false)
+	if err != nil || bill == nil {
+		return err
+	}
+
+	userEmail, _ := middleware.GetUserEmail(c)
+
+	// Broadcast bill posted event to all users
+	billType := bill.Type
+	if bill.CustomType != nil && *bill.CustomType != "" {
+		billType = *bill.CustomType
+	}
+
+	h.eventService.Broadcast(services.EventBillPosted, map[string]interface{}{
+		"billId":    bill.ID,
+		"type":      billType,
+		"amount":    bill.TotalAmountPLN,
+		"postedBy":  userEmail,
+		"periodEnd": bill.PeriodEnd.Format("2006-01-02"),
+	})
+
+	return c.JSON(fiber.Map{
+		"message": "Bill posted successfully",
+	})
+}
+
+// RequestBillPayment asks the household to pay their shares of a posted bill (ADMIN only)
+func (h *BillHandler) RequestBillPayment(c *fiber.Ctx) error {
+	bill, err := h.transitionBill(c, services.BillTransitionRequestPayment, "", false)
+	if err != nil || bill == nil {
+		return err
+	}
+
+	return c.JSON(bill)
+}
+
+// CloseBill changes status to closed (ADMIN only). Force closes a bill whose shares are not all paid.
+func (h *BillHandler) CloseBill(c *fiber.Ctx) error {
+	var req struct {
+		Force  bool   `json:"force"`
+		Reason string `json:"reason"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	bill, err := h.transitionBill(c, services.BillTransitionClose, req.Reason, req.Force)
+	if err != nil || bill == nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Bill closed successfully",
+	})
+}
+
+// ReopenBill reopens a bill to a previous status (ADMIN only)
+func (h *BillHandler) ReopenBill(c *fiber.Ctx) error {
+	var req struct {
+		TargetStatus string `json:"targetStatus"`
+		Reason       string `json:"reason"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
 		})
 	}
-	userEmail, err := middleware.GetUserEmail(c)
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized",
+
+	var transition string
+	switch req.TargetStatus {
+	case services.BillStatusDraft:
+		transition = services.BillTransitionReopen
+	case services.BillStatusPosted:
+		transition = services.BillTransitionReopenPosted
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "target status must be 'draft' or 'posted'",
 		})
 	}
 
+	bill, err := h.transitionBill(c, transition, req.Reason, false)
+	if err != nil || bill == nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Bill reopened successfully",
+	})
+}
+
+// GetBillHistory returns the status changes of a bill and the transitions it can take next
+func (h *BillHandler) GetBillHistory(c *fiber.Ctx) error {
 	billID := c.Params("id")
 	if billID == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -155,7 +254,6 @@ func (h *BillHandler) PostBill(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get bill info for audit
 	bill, err := h.billService.GetBill(c.Context(), billID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -163,44 +261,22 @@ func (h *BillHandler) PostBill(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.billService.PostBill(c.Context(), billID); err != nil {
-		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "post_bill", "bill", &billID,
-			map[string]interface{}{"bill_type": bill.Type, "status": "draft"},
-			c.IP(), c.Get("User-Agent"), "failure")
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+	history, err := h.billService.GetBillHistory(c.Context(), billID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	postAuditDetails := map[string]interface{}{"bill_type": bill.Type, "old_status": "draft", "new_status": "posted"}
-	if bill.CustomType != nil {
-		postAuditDetails["custom_type"] = *bill.CustomType
-	}
-	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "post_bill", "bill", &billID,
-		postAuditDetails,
-		c.IP(), c.Get("User-Agent"), "success")
-
-	// Broadcast bill posted event to all users
-	billType := bill.Type
-	if bill.CustomType != nil && *bill.CustomType != "" {
-		billType = *bill.CustomType
-	}
-
-	h.eventService.Broadcast(services.EventBillPosted, map[string]interface{}{
-		"billId":    bill.ID,
-		"type":      billType,
-		"amount":    bill.TotalAmountPLN,
-		"postedBy":  userEmail,
-		"periodEnd": bill.PeriodEnd.Format("2006-01-02"),
-	})
-
 	return c.JSON(fiber.Map{
-		"message": "Bill posted successfully",
+		"status":               bill.Status,
+		"availableTransitions": h.billService.AvailableTransitions(bill),
+		"transitions":          history,
 	})
 }
 
-// CloseBill changes status to closed (ADMIN only)
-func (h *BillHandler) CloseBill(c *fiber.Ctx) error {
+// AmendBill corrects an open bill in place and stores it as a new version
+func (h *BillHandler) AmendBill(c *fiber.Ctx) error {
 	userID, err := middleware.GetUserID(c)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -214,80 +290,139 @@ func (h *BillHandler) CloseBill(c *fiber.Ctx) error {
 		})
 	}
 
-	billID := c.Params("id")
-	if billID == "" {
+	var req services.AmendBillRequest
+	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid bill ID",
+			"error": "Invalid request body",
 		})
 	}
 
-	// Get bill info for audit
+	billID := c.Params("id")
 	bill, err := h.billService.GetBill(c.Context(), billID)
-	if err != nil {
+	if err != nil || bill == nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Bill not found",
 		})
 	}
 
-	if err := h.billService.CloseBill(c.Context(), billID); err != nil {
-		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "close_bill", "bill", &billID,
-			map[string]interface{}{"bill_type": bill.Type, "status": "posted"},
+	version, err := h.billService.AmendBill(c.Context(), billID, req, services.BillActor{
+		UserID:    userID,
+		Email:     userEmail,
+		IPAddress: c.IP(),
+		UserAgent: c.Get("User-Agent"),
+	})
+	if err != nil {
+		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "amend_bill", "bill", &billID,
+			map[string]interface{}{"bill_type": bill.Type, "status": bill.Status, "reason": req.Reason},
 			c.IP(), c.Get("User-Agent"), "failure")
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+
+		status := fiber.StatusBadRequest
+		if errors.Is(err, services.ErrBillTransitionNotAllowed) || errors.Is(err, services.ErrDisputesOpen) {
+			status = fiber.StatusConflict
+		}
+		return c.Status(status).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	closeAuditDetails := map[string]interface{}{"bill_type": bill.Type, "old_status": "posted", "new_status": "closed"}
-	if bill.CustomType != nil {
-		closeAuditDetails["custom_type"] = *bill.CustomType
+	return c.JSON(version)
+}
+
+// GetBillVersions returns the posted versions of a bill
+func (h *BillHandler) GetBillVersions(c *fiber.Ctx) error {
+	versions, err := h.billService.GetBillVersions(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
-	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "close_bill", "bill", &billID,
-		closeAuditDetails,
-		c.IP(), c.Get("User-Agent"), "success")
 
-	return c.JSON(fiber.Map{
-		"message": "Bill closed successfully",
-	})
+	return c.JSON(versions)
 }
 
-// ReopenBill reopens a bill to a previous status (ADMIN only)
-func (h *BillHandler) ReopenBill(c *fiber.Ctx) error {
-	billID := c.Params("id")
-	if billID == "" {
+// GetBillVersionDiff compares the shares of two versions of a bill, the latest two unless from and to are given
+func (h *BillHandler) GetBillVersionDiff(c *fiber.Ctx) error {
+	from := c.QueryInt("from", 0)
+	to := c.QueryInt("to", 0)
+	if from < 0 || to < 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid bill ID",
+			"error": "Invalid version",
 		})
 	}
 
-	// Get user ID from context
+	diff, err := h.billService.DiffBillVersions(c.Context(), c.Params("id"), from, to)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(diff)
+}
+
+// transitionBill takes a bill through a transition on behalf of the current user. Successful
+// transitions are audited by the bill service; failures are audited here. On failure the error
+// response has already been written and the returned bill is nil.
+func (h *BillHandler) transitionBill(c *fiber.Ctx, transition, reason string, force bool) (*models.Bill, error) {
 	userID, err := middleware.GetUserID(c)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		return nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Unauthorized",
 		})
 	}
 
-	var req struct {
-		TargetStatus string `json:"targetStatus"`
-		Reason       string `json:"reason"`
+	billID := c.Params("id")
+	if billID == "" {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid bill ID",
+		})
 	}
 
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
+	// Get bill info for audit
+	bill, err := h.billService.GetBill(c.Context(), billID)
+	if err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Bill not found",
 		})
 	}
 
-	if err := h.billService.ReopenBill(c.Context(), billID, userID, req.TargetStatus, req.Reason); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+	updated, err := h.billService.TransitionBill(c.Context(), billID, services.BillTransitionRequest{
+		Transition: transition,
+		Reason:     reason,
+		Force:      force,
+		Actor: services.BillActor{
+			UserID:    userID,
+			Email:     userEmail,
+			IPAddress: c.IP(),
+			UserAgent: c.Get("User-Agent"),
+		},
+	})
+	if err != nil {
+		action := transition
+		if rule, ok := h.billService.TransitionRule(transition); ok {
+			action = rule.AuditAction
+		}
+		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, action, "bill", &billID,
+			map[string]interface{}{"bill_type": bill.Type, "status": bill.Status, "transition": transition, "force": force},
+			c.IP(), c.Get("User-Agent"), "failure")
+
+		status := fiber.StatusBadRequest
+		if errors.Is(err, services.ErrBillTransitionNotAllowed) || errors.Is(err, services.ErrBillUnpaid) ||
+			errors.Is(err, services.ErrDisputesOpen) {
+			status = fiber.StatusConflict
+		}
+		return nil, c.Status(status).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"message": "Bill reopened successfully",
-	})
+	return updated, nil
 }
 
 // CreateConsumption records a consumption reading
@@ -307,10 +442,10 @@ func (h *BillHandler) CreateConsumption(c *fiber.Ctx) error {
 		})
 	}
 
-	var req services.CreateConsumptionRequest
-	if err := c.BodyParser(&req); err != nil {
+	req, err := parseConsumptionRequest(c)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
+			"error": err.Error(),
 		})
 	}
 
@@ -337,14 +472,21 @@ func (h *BillHandler) CreateConsumption(c *fiber.Ctx) error {
 		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "create_reading", "consumption", nil,
 			map[string]interface{}{"bill_id": req.BillID, "bill_type": bill.Type, "meter_value": req.MeterValue},
 			c.IP(), c.Get("User-Agent"), "failure")
+		if errors.Is(err, services.ErrAttachmentTooLarge) || errors.Is(err, services.ErrAttachmentTypeNotAllowed) {
+			return attachmentError(c, err)
+		}
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
+	details := map[string]interface{}{"bill_id": req.BillID, "bill_type": bill.Type, "meter_value": req.MeterValue, "source": source}
+	if consumption.Photo != nil {
+		details["photo_attachment_id"] = consumption.Photo.ID
+		details["photo_sha256"] = consumption.Photo.SHA256
+	}
 	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "create_reading", "consumption", &consumption.ID,
-		map[string]interface{}{"bill_id": req.BillID, "bill_type": bill.Type, "meter_value": req.MeterValue, "source": source},
-		c.IP(), c.Get("User-Agent"), "success")
+		details, c.IP(), c.Get("User-Agent"), "success")
 
 	// Broadcast event to all users
 	h.eventService.Broadcast(services.EventConsumptionCreated, map[string]interface{}{
@@ -358,6 +500,39 @@ func (h *BillHandler) CreateConsumption(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(consumption)
 }
 
+// parseConsumptionRequest reads a reading from a JSON body, or from a multipart form whose "reading"
+// field holds the same JSON and whose optional "photo" field holds a photo of the meter
+func parseConsumptionRequest(c *fiber.Ctx) (services.CreateConsumptionRequest, error) {
+	var req services.CreateConsumptionRequest
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		if err := c.BodyParser(&req); err != nil {
+			return req, errors.New("Invalid request body")
+		}
+		return req, nil
+	}
+
+	if err := json.Unmarshal([]byte(c.FormValue("reading")), &req); err != nil {
+		return req, errors.New("Invalid reading")
+	}
+
+	fileHeader, err := c.FormFile("photo")
+	if err != nil {
+		return req, nil // the photo is optional
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return req, errors.New("Failed to read photo")
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return req, errors.New("Failed to read photo")
+	}
+	req.Photo = &services.ReadingPhoto{FileName: fileHeader.Filename, Data: data}
+	return req, nil
+}
+
 // GetConsumptions retrieves consumptions for a bill (or all consumptions if no billId)
 func (h *BillHandler) GetConsumptions(c *fiber.Ctx) error {
 	billIDStr := c.Query("billId")
@@ -470,12 +645,39 @@ func (h *BillHandler) MarkConsumptionInvalid(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.consumptionService.MarkConsumptionInvalid(c.Context(), consumptionID, userID); err != nil {
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	consumption, err := h.consumptionService.MarkConsumptionInvalid(c.Context(), consumptionID, userID)
+	if err != nil {
+		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "invalidate_reading", "consumption", &consumptionID,
+			map[string]interface{}{"error": err.Error()}, c.IP(), c.Get("User-Agent"), "failure")
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
+	// The audit entry points at the photo the reading was sent with, so the evidence can be looked up later
+	details := map[string]interface{}{
+		"bill_id":      consumption.BillID,
+		"subject_type": consumption.SubjectType,
+		"subject_id":   consumption.SubjectID,
+		"units":        consumption.Units,
+		"meter_value":  consumption.MeterValue,
+		"meter_id":     consumption.MeterID,
+		"recorded_at":  consumption.RecordedAt,
+	}
+	if consumption.Photo != nil {
+		details["photo_attachment_id"] = consumption.Photo.ID
+		details["photo_sha256"] = consumption.Photo.SHA256
+	}
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "invalidate_reading", "consumption", &consumption.ID,
+		details, c.IP(), c.Get("User-Agent"), "success")
+
 	return c.JSON(fiber.Map{"message": "Consumption marked as invalid"})
 }
 
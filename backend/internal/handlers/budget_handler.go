@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sainaif/holy-home/internal/middleware"
+	"github.com/sainaif/holy-home/internal/services"
+)
+
+type BudgetHandler struct {
+	budgetService *services.BudgetService
+	auditService  *services.AuditService
+}
+
+func NewBudgetHandler(budgetService *services.BudgetService, auditService *services.AuditService) *BudgetHandler {
+	return &BudgetHandler{
+		budgetService: budgetService,
+		auditService:  auditService,
+	}
+}
+
+// GetBudgets lists the monthly budgets
+func (h *BudgetHandler) GetBudgets(c *fiber.Ctx) error {
+	budgets, err := h.budgetService.GetBudgets(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(budgets)
+}
+
+// SetBudget creates or replaces the monthly budget of a bill type or supply category
+func (h *BudgetHandler) SetBudget(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req services.SetBudgetRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	budget, err := h.budgetService.SetBudget(c.Context(), req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "set_budget", "budget", &budget.ID,
+		map[string]interface{}{
+			"kind":             budget.Kind,
+			"category":         budget.Category,
+			"custom_type":      budget.CustomType,
+			"monthly_amount":   budget.MonthlyAmount,
+			"alert_thresholds": budget.AlertThresholds,
+		},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	return c.JSON(budget)
+}
+
+// DeleteBudget deletes a monthly budget
+func (h *BudgetHandler) DeleteBudget(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	id := c.Params("id")
+	if err := h.budgetService.DeleteBudget(c.Context(), id); err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, services.ErrBudgetNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "delete_budget", "budget", &id,
+		map[string]interface{}{}, c.IP(), c.Get("User-Agent"), "success")
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetReport compares the budgets with spending over the last N months (?months=, default 6)
+func (h *BudgetHandler) GetReport(c *fiber.Ctx) error {
+	report, err := h.budgetService.GetReport(c.Context(), c.QueryInt("months", 0), time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(report)
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sainaif/holy-home/internal/middleware"
+	"github.com/sainaif/holy-home/internal/services"
+)
+
+type DisputeHandler struct {
+	disputeService *services.DisputeService
+	auditService   *services.AuditService
+}
+
+func NewDisputeHandler(disputeService *services.DisputeService, auditService *services.AuditService) *DisputeHandler {
+	return &DisputeHandler{
+		disputeService: disputeService,
+		auditService:   auditService,
+	}
+}
+
+// OpenDispute disputes the current user's share of a bill
+func (h *DisputeHandler) OpenDispute(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req services.OpenDisputeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	billID := c.Params("id")
+	dispute, err := h.disputeService.OpenDispute(c.Context(), billID, req, h.actor(c, userID, userEmail))
+	if err != nil {
+		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "open_allocation_dispute", "bill", &billID,
+			map[string]interface{}{"proposed_amount": req.ProposedAmount.String(), "reason": req.Reason},
+			c.IP(), c.Get("User-Agent"), "failure")
+		return disputeError(c, err)
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "open_allocation_dispute", "bill", &billID,
+		map[string]interface{}{
+			"dispute_id":       dispute.ID,
+			"subject_type":     dispute.SubjectType,
+			"subject_id":       dispute.SubjectID,
+			"allocated_amount": dispute.AllocatedAmount,
+			"proposed_amount":  dispute.ProposedAmount,
+			"reason":           dispute.Reason,
+		},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	return c.Status(fiber.StatusCreated).JSON(dispute)
+}
+
+// ResolveDispute accepts or rejects a dispute, logging the allocations before and after
+func (h *DisputeHandler) ResolveDispute(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req services.ResolveDisputeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	disputeID := c.Params("id")
+	resolution, err := h.disputeService.ResolveDispute(c.Context(), disputeID, req, h.actor(c, userID, userEmail))
+	if err != nil {
+		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "resolve_allocation_dispute", "bill_dispute", &disputeID,
+			map[string]interface{}{"accept": req.Accept, "note": req.Note},
+			c.IP(), c.Get("User-Agent"), "failure")
+		return disputeError(c, err)
+	}
+
+	dispute := resolution.Dispute
+	details := map[string]interface{}{
+		"bill_id":          dispute.BillID,
+		"subject_type":     dispute.SubjectType,
+		"subject_id":       dispute.SubjectID,
+		"decision":         dispute.Status,
+		"allocated_amount": dispute.AllocatedAmount,
+		"proposed_amount":  dispute.ProposedAmount,
+	}
+	if dispute.ResolvedAmount != nil {
+		details["resolved_amount"] = *dispute.ResolvedAmount
+	}
+	if dispute.ResolutionNote != nil {
+		details["note"] = *dispute.ResolutionNote
+	}
+	if resolution.Before != nil {
+		details["before"] = resolution.Before
+		details["after"] = resolution.After
+	}
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "resolve_allocation_dispute", "bill_dispute", &disputeID,
+		details, c.IP(), c.Get("User-Agent"), "success")
+
+	return c.JSON(resolution)
+}
+
+// GetBillDisputes lists the disputes of a bill
+func (h *DisputeHandler) GetBillDisputes(c *fiber.Ctx) error {
+	disputes, err := h.disputeService.GetBillDisputes(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(disputes)
+}
+
+// GetDisputes lists disputes across bills, the open ones unless another status is asked for
+func (h *DisputeHandler) GetDisputes(c *fiber.Ctx) error {
+	disputes, err := h.disputeService.GetDisputes(c.Context(), c.Query("status", services.DisputeStatusOpen))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(disputes)
+}
+
+func (h *DisputeHandler) actor(c *fiber.Ctx, userID, userEmail string) services.BillActor {
+	return services.BillActor{
+		UserID:    userID,
+		Email:     userEmail,
+		IPAddress: c.IP(),
+		UserAgent: c.Get("User-Agent"),
+	}
+}
+
+func disputeError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, services.ErrDisputeNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrDisputeNotOpen), errors.Is(err, services.ErrDisputeAlreadyOpen),
+		errors.Is(err, services.ErrBillTransitionNotAllowed):
+		status = fiber.StatusConflict
+	case errors.Is(err, services.ErrNoBillShare):
+		status = fiber.StatusForbidden
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
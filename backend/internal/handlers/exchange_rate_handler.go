@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sainaif/holy-home/internal/middleware"
+	"github.com/sainaif/holy-home/internal/services"
+)
+
+type ExchangeRateHandler struct {
+	currencyService *services.CurrencyService
+	auditService    *services.AuditService
+}
+
+func NewExchangeRateHandler(currencyService *services.CurrencyService, auditService *services.AuditService) *ExchangeRateHandler {
+	return &ExchangeRateHandler{
+		currencyService: currencyService,
+		auditService:    auditService,
+	}
+}
+
+type SetExchangeRateRequest struct {
+	Currency string    `json:"currency"`
+	Rate     string    `json:"rate"`     // Base currency units per 1 unit of Currency
+	RateDate time.Time `json:"rateDate"` // Defaults to today
+}
+
+// GetExchangeRates lists all exchange rates together with the base currency
+func (h *ExchangeRateHandler) GetExchangeRates(c *fiber.Ctx) error {
+	baseCurrency, err := h.currencyService.BaseCurrency(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	rates, err := h.currencyService.ListRates(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"baseCurrency": baseCurrency,
+		"rates":        rates,
+	})
+}
+
+// SetExchangeRate creates or replaces the rate for a currency on a given day (ADMIN only)
+func (h *ExchangeRateHandler) SetExchangeRate(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req SetExchangeRateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	rateDate := req.RateDate
+	if rateDate.IsZero() {
+		rateDate = time.Now()
+	}
+
+	rate, err := h.currencyService.SetRate(c.Context(), req.Currency, req.Rate, rateDate)
+	if err != nil {
+		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "set_exchange_rate", "exchange_rate", nil,
+			map[string]interface{}{"currency": req.Currency, "rate": req.Rate, "error": err.Error()},
+			c.IP(), c.Get("User-Agent"), "failure")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "set_exchange_rate", "exchange_rate", &rate.ID,
+		map[string]interface{}{"currency": rate.Currency, "rate": rate.Rate, "rateDate": rate.RateDate.Format("2006-01-02")},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	return c.Status(fiber.StatusCreated).JSON(rate)
+}
+
+// DeleteExchangeRate deletes an exchange rate (ADMIN only)
+func (h *ExchangeRateHandler) DeleteExchangeRate(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	rateID := c.Params("id")
+	if rateID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid exchange rate ID",
+		})
+	}
+
+	if err := h.currencyService.DeleteRate(c.Context(), rateID); err != nil {
+		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "delete_exchange_rate", "exchange_rate", &rateID,
+			map[string]interface{}{"error": err.Error()},
+			c.IP(), c.Get("User-Agent"), "failure")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "delete_exchange_rate", "exchange_rate", &rateID,
+		map[string]interface{}{},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	return c.JSON(fiber.Map{
+		"message": "Exchange rate deleted successfully",
+	})
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 
+	"github.com/sainaif/holy-home/internal/middleware"
 	"github.com/sainaif/holy-home/internal/services"
 )
 
@@ -122,3 +123,34 @@ func (h *ExportHandler) ExportConsumptions(c *fiber.Ctx) error {
 	c.Set("Content-Disposition", "attachment; filename=consumptions.csv")
 	return c.Send(csv)
 }
+
+// ExportMyLedger exports the current user's account statement to CSV
+func (h *ExportHandler) ExportMyLedger(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+	return h.sendLedger(c, userID)
+}
+
+// ExportUserLedger exports the account statement of any user to CSV
+func (h *ExportHandler) ExportUserLedger(c *fiber.Ctx) error {
+	return h.sendLedger(c, c.Params("userId"))
+}
+
+func (h *ExportHandler) sendLedger(c *fiber.Ctx, userID string) error {
+	from, to, err := parseLedgerRange(c)
+	if err != nil {
+		return err
+	}
+
+	// Export CSV
+	csv, err := h.exportService.ExportLedgerCSV(c.Context(), userID, from, to)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	c.Set("Content-Type", "text/csv")
+	c.Set("Content-Disposition", "attachment; filename=ledger.csv")
+	return c.Send(csv)
+}
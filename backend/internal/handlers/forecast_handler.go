@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sainaif/holy-home/internal/middleware"
+	"github.com/sainaif/holy-home/internal/services"
+)
+
+type ForecastHandler struct {
+	forecastService *services.ForecastService
+	auditService    *services.AuditService
+}
+
+func NewForecastHandler(forecastService *services.ForecastService, auditService *services.AuditService) *ForecastHandler {
+	return &ForecastHandler{
+		forecastService: forecastService,
+		auditService:    auditService,
+	}
+}
+
+// GetForecast projects the current bill period of a metered utility.
+// The optional at parameter takes RFC3339 or a plain date, which means the end of that day.
+func (h *ForecastHandler) GetForecast(c *fiber.Ctx) error {
+	at := time.Now()
+	if atStr := c.Query("at"); atStr != "" {
+		parsed, err := time.Parse(time.RFC3339, atStr)
+		if err != nil {
+			day, derr := time.Parse("2006-01-02", atStr)
+			if derr != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid date, use RFC3339 or YYYY-MM-DD",
+				})
+			}
+			parsed = day.Add(24*time.Hour - time.Second)
+		}
+		at = parsed
+	}
+
+	forecast, err := h.forecastService.Forecast(c.Context(), c.Query("type"), c.Query("customType"), at)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(forecast)
+}
+
+// GetTariffs lists all utility tariffs
+func (h *ForecastHandler) GetTariffs(c *fiber.Ctx) error {
+	tariffs, err := h.forecastService.ListTariffs(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(tariffs)
+}
+
+// SetTariff creates or replaces the tariff of a utility zone from a given day (ADMIN only)
+func (h *ForecastHandler) SetTariff(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req services.SetTariffRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	tariff, err := h.forecastService.SetTariff(c.Context(), req)
+	if err != nil {
+		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "set_tariff", "utility_tariff", nil,
+			map[string]interface{}{"bill_type": req.BillType, "tariff_zone": req.TariffZone, "unit_price": req.UnitPrice, "error": err.Error()},
+			c.IP(), c.Get("User-Agent"), "failure")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "set_tariff", "utility_tariff", &tariff.ID,
+		map[string]interface{}{"bill_type": tariff.BillType, "custom_type": tariff.CustomType, "tariff_zone": tariff.TariffZone,
+			"unit_price": tariff.UnitPrice, "fixed_fee_monthly": tariff.FixedFeeMonthly, "valid_from": tariff.ValidFrom.Format("2006-01-02")},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	return c.Status(fiber.StatusCreated).JSON(tariff)
+}
+
+// DeleteTariff deletes a utility tariff (ADMIN only)
+func (h *ForecastHandler) DeleteTariff(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	tariffID := c.Params("id")
+	if err := h.forecastService.DeleteTariff(c.Context(), tariffID); err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, services.ErrTariffNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "delete_tariff", "utility_tariff", &tariffID,
+		map[string]interface{}{},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	return c.JSON(fiber.Map{
+		"message": "Tariff deleted successfully",
+	})
+}
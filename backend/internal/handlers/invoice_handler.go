@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sainaif/holy-home/internal/services"
+)
+
+// maxInvoiceSize limits uploaded invoices
+const maxInvoiceSize = 10 << 20
+
+type InvoiceHandler struct {
+	invoiceImportService *services.InvoiceImportService
+}
+
+func NewInvoiceHandler(invoiceImportService *services.InvoiceImportService) *InvoiceHandler {
+	return &InvoiceHandler{invoiceImportService: invoiceImportService}
+}
+
+// ParseInvoice reads an uploaded invoice and returns a bill draft for review.
+// Nothing is stored; the reviewed draft is submitted to the create bill endpoint.
+func (h *InvoiceHandler) ParseInvoice(c *fiber.Ctx) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invoice file is required",
+		})
+	}
+	if fileHeader.Size > maxInvoiceSize {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invoice file is too large",
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read invoice file",
+		})
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxInvoiceSize))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read invoice file",
+		})
+	}
+
+	draft, err := h.invoiceImportService.ParseInvoice(data)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidInvoice) {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(draft)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sainaif/holy-home/internal/middleware"
+	"github.com/sainaif/holy-home/internal/services"
+)
+
+type LateFeeHandler struct {
+	lateFeeService *services.LateFeeService
+	auditService   *services.AuditService
+}
+
+func NewLateFeeHandler(lateFeeService *services.LateFeeService, auditService *services.AuditService) *LateFeeHandler {
+	return &LateFeeHandler{
+		lateFeeService: lateFeeService,
+		auditService:   auditService,
+	}
+}
+
+// GetPolicies lists the late-fee policies
+func (h *LateFeeHandler) GetPolicies(c *fiber.Ctx) error {
+	policies, err := h.lateFeeService.GetPolicies(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(policies)
+}
+
+// SetPolicy creates or replaces the late-fee policy of a bill type or loan
+func (h *LateFeeHandler) SetPolicy(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req services.SetLateFeePolicyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	policy, err := h.lateFeeService.SetPolicy(c.Context(), req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	details := map[string]interface{}{
+		"resource_type":      policy.ResourceType,
+		"bill_type":          policy.BillType,
+		"custom_type":        policy.CustomType,
+		"loan_id":            policy.LoanID,
+		"flat_fee":           policy.FlatFee,
+		"percent_per_period": policy.PercentPerPeriod,
+		"period_days":        policy.PeriodDays,
+		"grace_days":         policy.GraceDays,
+	}
+	if policy.Cap != nil {
+		details["cap"] = *policy.Cap
+	}
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "set_late_fee_policy", "late_fee_policy", &policy.ID,
+		details, c.IP(), c.Get("User-Agent"), "success")
+
+	return c.JSON(policy)
+}
+
+// DeletePolicy deletes a late-fee policy
+func (h *LateFeeHandler) DeletePolicy(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	id := c.Params("id")
+	if err := h.lateFeeService.DeletePolicy(c.Context(), id); err != nil {
+		return lateFeeError(c, err)
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "delete_late_fee_policy", "late_fee_policy", &id,
+		map[string]interface{}{}, c.IP(), c.Get("User-Agent"), "success")
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetFees lists accrued and waived late fees, optionally of one bill or loan
+func (h *LateFeeHandler) GetFees(c *fiber.Ctx) error {
+	fees, err := h.lateFeeService.GetFees(c.Context(), c.Query("resourceType"), c.Query("resourceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fees)
+}
+
+// WaiveFee waives an accrued late fee
+func (h *LateFeeHandler) WaiveFee(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	feeID := c.Params("id")
+	fee, err := h.lateFeeService.WaiveFee(c.Context(), feeID, req.Reason, userID)
+	if err != nil {
+		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "waive_late_fee", "late_fee", &feeID,
+			map[string]interface{}{"reason": req.Reason},
+			c.IP(), c.Get("User-Agent"), "failure")
+		return lateFeeError(c, err)
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "waive_late_fee", "late_fee", &feeID,
+		map[string]interface{}{
+			"resource_type": fee.ResourceType,
+			"resource_id":   fee.ResourceID,
+			"subject_type":  fee.SubjectType,
+			"subject_id":    fee.SubjectID,
+			"amount":        fee.Amount,
+			"currency":      fee.Currency,
+			"reason":        req.Reason,
+		},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	return c.JSON(fee)
+}
+
+func lateFeeError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, services.ErrLateFeeNotFound), errors.Is(err, services.ErrLateFeePolicyNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, services.ErrLateFeeWaived):
+		status = fiber.StatusConflict
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sainaif/holy-home/internal/middleware"
+	"github.com/sainaif/holy-home/internal/services"
+)
+
+type LedgerHandler struct {
+	ledgerService *services.LedgerService
+}
+
+func NewLedgerHandler(ledgerService *services.LedgerService) *LedgerHandler {
+	return &LedgerHandler{ledgerService: ledgerService}
+}
+
+// GetMyLedger returns the current user's account statement
+func (h *LedgerHandler) GetMyLedger(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	return h.sendStatement(c, userID)
+}
+
+// GetUserLedger returns the account statement of any user
+func (h *LedgerHandler) GetUserLedger(c *fiber.Ctx) error {
+	return h.sendStatement(c, c.Params("id"))
+}
+
+func (h *LedgerHandler) sendStatement(c *fiber.Ctx, userID string) error {
+	from, to, err := parseLedgerRange(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	statement, err := h.ledgerService.GetStatement(c.Context(), userID, from, to)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(statement)
+}
+
+// parseLedgerRange reads the optional from/to dates (YYYY-MM-DD) of a statement.
+// Both days are included, so "to" is moved to the end of its day.
+func parseLedgerRange(c *fiber.Ctx) (*time.Time, *time.Time, error) {
+	var from, to *time.Time
+	if f := c.Query("from"); f != "" {
+		parsed, err := time.Parse("2006-01-02", f)
+		if err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid from date, use YYYY-MM-DD")
+		}
+		from = &parsed
+	}
+	if t := c.Query("to"); t != "" {
+		parsed, err := time.Parse("2006-01-02", t)
+		if err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid to date, use YYYY-MM-DD")
+		}
+		endOfDay := parsed.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		to = &endOfDay
+	}
+	if from != nil && to != nil && to.Before(*from) {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "to date must not be before from date")
+	}
+	return from, to, nil
+}
@@ -55,7 +55,7 @@ func (h *LoanHandler) CreateLoan(c *fiber.Ctx) error {
 	}
 
 	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "create_loan", "loan", &loan.ID,
-		map[string]interface{}{"lender_id": req.LenderID, "borrower_id": req.BorrowerID, "amount": req.AmountPLN},
+		map[string]interface{}{"lender_id": req.LenderID, "borrower_id": req.BorrowerID, "amount": req.AmountPLN, "currency": loan.Currency},
 		c.IP(), c.Get("User-Agent"), "success")
 
 	// Broadcast loan created event
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sainaif/holy-home/internal/middleware"
+	"github.com/sainaif/holy-home/internal/services"
+)
+
+type MeterHandler struct {
+	meterService *services.MeterService
+	auditService *services.AuditService
+}
+
+func NewMeterHandler(meterService *services.MeterService, auditService *services.AuditService) *MeterHandler {
+	return &MeterHandler{
+		meterService: meterService,
+		auditService: auditService,
+	}
+}
+
+// GetMeters returns all meters, optionally only those of one user or group
+func (h *MeterHandler) GetMeters(c *fiber.Ctx) error {
+	var subjectType, subjectID *string
+	if st := c.Query("subjectType"); st != "" {
+		subjectType = &st
+	}
+	if sid := c.Query("subjectId"); sid != "" {
+		subjectID = &sid
+	}
+
+	meters, err := h.meterService.ListMeters(c.Context(), subjectType, subjectID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(meters)
+}
+
+// GetMyMeters returns the meters of the current user or their group
+func (h *MeterHandler) GetMyMeters(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	meters, err := h.meterService.ListUserMeters(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(meters)
+}
+
+// GetMeter returns a single meter
+func (h *MeterHandler) GetMeter(c *fiber.Ctx) error {
+	meter, err := h.meterService.GetMeter(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(meterErrorStatus(err)).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(meter)
+}
+
+// CreateMeter registers a meter
+func (h *MeterHandler) CreateMeter(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req services.CreateMeterRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	meter, err := h.meterService.CreateMeter(c.Context(), req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "create_meter", "meter", &meter.ID,
+		map[string]interface{}{"type": meter.Type, "tariff_zone": meter.TariffZone, "serial_number": meter.SerialNumber,
+			"subject_type": meter.SubjectType, "subject_id": meter.SubjectID},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	return c.Status(fiber.StatusCreated).JSON(meter)
+}
+
+// UpdateMeter corrects the unit, tariff zone or serial number of a meter
+func (h *MeterHandler) UpdateMeter(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req services.UpdateMeterRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	meter, err := h.meterService.UpdateMeter(c.Context(), c.Params("id"), req)
+	if err != nil {
+		return c.Status(meterErrorStatus(err)).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "update_meter", "meter", &meter.ID,
+		map[string]interface{}{"unit": meter.Unit, "tariff_zone": meter.TariffZone, "serial_number": meter.SerialNumber},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	return c.JSON(meter)
+}
+
+// ReplaceMeter records a meter swap and returns the new meter
+func (h *MeterHandler) ReplaceMeter(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req services.ReplaceMeterRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	meterID := c.Params("id")
+	meter, err := h.meterService.ReplaceMeter(c.Context(), meterID, req)
+	if err != nil {
+		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "replace_meter", "meter", &meterID,
+			map[string]interface{}{"final_value": req.FinalValue, "error": err.Error()},
+			c.IP(), c.Get("User-Agent"), "failure")
+		return c.Status(meterErrorStatus(err)).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "replace_meter", "meter", &meterID,
+		map[string]interface{}{"final_value": req.FinalValue, "new_meter_id": meter.ID, "serial_number": meter.SerialNumber,
+			"initial_value": meter.InitialValue},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	return c.Status(fiber.StatusCreated).JSON(meter)
+}
+
+// DeleteMeter removes a meter that has no readings
+func (h *MeterHandler) DeleteMeter(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	meterID := c.Params("id")
+	if err := h.meterService.DeleteMeter(c.Context(), meterID); err != nil {
+		return c.Status(meterErrorStatus(err)).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "delete_meter", "meter", &meterID,
+		map[string]interface{}{},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	return c.JSON(fiber.Map{
+		"message": "Meter deleted successfully",
+	})
+}
+
+func meterErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrMeterNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, services.ErrMeterReplaced), errors.Is(err, services.ErrMeterInUse):
+		return fiber.StatusConflict
+	default:
+		return fiber.StatusBadRequest
+	}
+}
@@ -2,12 +2,12 @@ package handlers
 
 import (
 	"log"
-	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/sainaif/holy-home/internal/middleware"
 	"github.com/sainaif/holy-home/internal/models"
 	"github.com/sainaif/holy-home/internal/services"
+	"github.com/sainaif/holy-home/internal/utils"
 )
 
 type PaymentHandler struct {
@@ -58,7 +58,7 @@ func (h *PaymentHandler) RecordPayment(c *fiber.Ctx) error {
 	}
 
 	// Parse amount
-	amountFloat, err := strconv.ParseFloat(req.Amount, 64)
+	amount, err := utils.ParseMoney(req.Amount)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid amount format",
@@ -68,14 +68,14 @@ func (h *PaymentHandler) RecordPayment(c *fiber.Ctx) error {
 	// Record the payment
 	payment, err := h.paymentService.RecordPayment(c.Context(), services.RecordPaymentRequest{
 		BillID: req.BillID,
-		Amount: amountFloat,
+		Amount: amount,
 		Method: req.Method,
 	}, userID)
 
 	if err != nil {
 		log.Printf("Payment error for bill %s, user %s: %v", req.BillID, userID, err)
 		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "record_payment", "payment", nil,
-			map[string]interface{}{"bill_id": req.BillID, "amount": amountFloat},
+			map[string]interface{}{"bill_id": req.BillID, "amount": amount},
 			c.IP(), c.Get("User-Agent"), "failure")
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -83,7 +83,7 @@ func (h *PaymentHandler) RecordPayment(c *fiber.Ctx) error {
 	}
 
 	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "record_payment", "payment", &payment.ID,
-		map[string]interface{}{"bill_id": req.BillID, "amount": amountFloat},
+		map[string]interface{}{"bill_id": req.BillID, "amount": amount},
 		c.IP(), c.Get("User-Agent"), "success")
 
 	return c.Status(fiber.StatusCreated).JSON(payment)
@@ -134,3 +134,22 @@ func (h *PaymentHandler) GetUserPayments(c *fiber.Ctx) error {
 
 	return c.JSON(payments)
 }
+
+// GetUserCreditStatement returns the current user's bill credit with a running balance
+func (h *PaymentHandler) GetUserCreditStatement(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	statement, err := h.paymentService.GetUserCreditStatement(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch credit statement",
+		})
+	}
+
+	return c.JSON(statement)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sainaif/holy-home/internal/services"
+)
+
+// defaultQRSize is the QR code width in pixels when the request does not choose one
+const defaultQRSize = 300
+
+type PaymentRequestHandler struct {
+	paymentRequestService *services.PaymentRequestService
+}
+
+func NewPaymentRequestHandler(paymentRequestService *services.PaymentRequestService) *PaymentRequestHandler {
+	return &PaymentRequestHandler{paymentRequestService: paymentRequestService}
+}
+
+// GetBillPaymentRequests returns a transfer request for every unpaid allocation of a bill
+func (h *PaymentRequestHandler) GetBillPaymentRequests(c *fiber.Ctx) error {
+	requests, err := h.paymentRequestService.GetBillPaymentRequests(c.Context(), c.Params("id"))
+	if err != nil {
+		return paymentRequestError(c, err)
+	}
+
+	return c.JSON(requests)
+}
+
+// GetPaymentQR serves the transfer QR code of one allocation as PNG (default) or SVG
+func (h *PaymentRequestHandler) GetPaymentQR(c *fiber.Ctx) error {
+	size := defaultQRSize
+	if s := c.Query("size"); s != "" {
+		parsed, err := strconv.Atoi(s)
+		if err != nil || parsed < 64 || parsed > 2048 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "size must be between 64 and 2048",
+			})
+		}
+		size = parsed
+	}
+
+	req, err := h.paymentRequestService.GetPaymentRequest(c.Context(), c.Params("id"), c.Params("subjectId"))
+	if err != nil {
+		return paymentRequestError(c, err)
+	}
+
+	image, contentType, err := services.RenderPaymentQR(req.QRPayload, c.Query("format", services.PaymentQRFormatPNG), size)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set("Content-Type", contentType)
+	return c.Send(image)
+}
+
+func paymentRequestError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusNotFound
+	if errors.Is(err, services.ErrPaymentRequestUnavailable) {
+		status = fiber.StatusBadRequest
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
@@ -13,7 +13,8 @@ import (
 type RecurringBillTemplateRequest struct {
 	CustomType  string                           `json:"customType"`
 	Frequency   string                           `json:"frequency"`
-	Amount      string                           `json:"amount"` // Comes as string from JSON
+	Amount      string                           `json:"amount"`             // Comes as string from JSON
+	Currency    string                           `json:"currency,omitempty"` // Defaults to the base currency
 	DayOfMonth  int                              `json:"dayOfMonth"`
 	StartDate   time.Time                        `json:"startDate"` // Required
 	Allocations []models.RecurringBillAllocation `json:"allocations"`
@@ -59,6 +60,7 @@ func (h *RecurringBillHandler) CreateRecurringBillTemplate(c *fiber.Ctx) error {
 		CustomType:  req.CustomType,
 		Frequency:   req.Frequency,
 		Amount:      req.Amount,
+		Currency:    req.Currency,
 		DayOfMonth:  req.DayOfMonth,
 		StartDate:   req.StartDate,
 		Allocations: req.Allocations,
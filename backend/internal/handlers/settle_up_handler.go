@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sainaif/holy-home/internal/middleware"
+	"github.com/sainaif/holy-home/internal/services"
+)
+
+type SettleUpHandler struct {
+	settleUpService *services.SettleUpService
+	eventService    *services.EventService
+	auditService    *services.AuditService
+}
+
+func NewSettleUpHandler(settleUpService *services.SettleUpService, eventService *services.EventService, auditService *services.AuditService) *SettleUpHandler {
+	return &SettleUpHandler{
+		settleUpService: settleUpService,
+		eventService:    eventService,
+		auditService:    auditService,
+	}
+}
+
+// PreviewSettleUp returns the minimal transfers that would settle all open debts
+func (h *SettleUpHandler) PreviewSettleUp(c *fiber.Ctx) error {
+	plan, err := h.settleUpService.PreviewSettleUp(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(plan)
+}
+
+// GetTransfers returns the transfers recorded by settle-ups (?settlementId= for one settle-up)
+func (h *SettleUpHandler) GetTransfers(c *fiber.Ctx) error {
+	transfers, err := h.settleUpService.GetTransfers(c.Context(), c.Query("settlementId"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(transfers)
+}
+
+// ApplySettleUp records the settle-up as transfers, repaid loans and reassigned bill payments
+func (h *SettleUpHandler) ApplySettleUp(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	userEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	plan, err := h.settleUpService.ApplySettleUp(c.Context())
+	if err != nil {
+		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "settle_up", "loan", nil,
+			map[string]interface{}{"error": err.Error()},
+			c.IP(), c.Get("User-Agent"), "failure")
+
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, services.ErrNothingToSettle) {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "settle_up", "loan", &plan.SettlementID,
+		map[string]interface{}{
+			"transfers":     len(plan.Transfers),
+			"loans_settled": plan.LoansSettled,
+			"bills_settled": plan.BillsSettled,
+			"currency":      plan.Currency,
+		},
+		c.IP(), c.Get("User-Agent"), "success")
+
+	h.eventService.Broadcast(services.EventBalanceUpdated, map[string]interface{}{
+		"timestamp": time.Now(),
+	})
+
+	return c.JSON(plan)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/sainaif/holy-home/internal/middleware"
 	"github.com/sainaif/holy-home/internal/services"
+	"github.com/sainaif/holy-home/internal/utils"
 )
 
 type SupplyHandler struct {
@@ -39,8 +40,8 @@ func (h *SupplyHandler) GetSettings(c *fiber.Ctx) error {
 // UpdateSettings updates supply settings (ADMIN only)
 func (h *SupplyHandler) UpdateSettings(c *fiber.Ctx) error {
 	var req struct {
-		WeeklyContributionPLN float64 `json:"weeklyContributionPLN"`
-		ContributionDay       string  `json:"contributionDay"`
+		WeeklyContributionPLN utils.Money `json:"weeklyContributionPLN"`
+		ContributionDay       string      `json:"contributionDay"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -63,8 +64,8 @@ func (h *SupplyHandler) UpdateSettings(c *fiber.Ctx) error {
 // AdjustBudget manually adjusts budget (ADMIN only)
 func (h *SupplyHandler) AdjustBudget(c *fiber.Ctx) error {
 	var req struct {
-		Adjustment float64 `json:"adjustment"`
-		Notes      string  `json:"notes"`
+		Adjustment utils.Money `json:"adjustment"`
+		Notes      string      `json:"notes"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -254,9 +255,10 @@ func (h *SupplyHandler) RestockItem(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		QuantityToAdd int      `json:"quantityToAdd"`
-		AmountPLN     *float64 `json:"amountPLN"`
-		NeedsRefund   bool     `json:"needsRefund"`
+		QuantityToAdd int          `json:"quantityToAdd"`
+		AmountPLN     *utils.Money `json:"amountPLN"`
+		Currency      string       `json:"currency"` // Defaults to the base currency
+		NeedsRefund   bool         `json:"needsRefund"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -265,7 +267,8 @@ func (h *SupplyHandler) RestockItem(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.supplyService.RestockItem(c.Context(), itemID, userID, req.QuantityToAdd, req.AmountPLN, req.NeedsRefund); err != nil {
+	restock, err := h.supplyService.RestockItem(c.Context(), itemID, userID, req.QuantityToAdd, req.AmountPLN, req.Currency, req.NeedsRefund)
+	if err != nil {
 		h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "restock_supply_item", "supply", &itemID,
 			map[string]interface{}{"quantity": req.QuantityToAdd, "error": err.Error()},
 			c.IP(), c.Get("User-Agent"), "failure")
@@ -275,14 +278,27 @@ func (h *SupplyHandler) RestockItem(c *fiber.Ctx) error {
 	}
 
 	h.auditService.LogAction(c.Context(), userID, userEmail, userEmail, "restock_supply_item", "supply", &itemID,
-		map[string]interface{}{"quantity": req.QuantityToAdd, "amount": req.AmountPLN, "needs_refund": req.NeedsRefund},
+		map[string]interface{}{"quantity": req.QuantityToAdd, "amount": req.AmountPLN, "currency": req.Currency, "needs_refund": req.NeedsRefund},
 		c.IP(), c.Get("User-Agent"), "success")
 
 	return c.JSON(fiber.Map{
 		"message": "Item restocked successfully",
+		"restock": restock,
 	})
 }
 
+// GetRestocks returns the restocks of an item, whose receipts are attached to them
+func (h *SupplyHandler) GetRestocks(c *fiber.Ctx) error {
+	restocks, err := h.supplyService.GetRestocks(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(restocks)
+}
+
 // ConsumeItem reduces item quantity
 func (h *SupplyHandler) ConsumeItem(c *fiber.Ctx) error {
 	itemID := c.Params("id")
@@ -442,8 +458,8 @@ func (h *SupplyHandler) CreateContribution(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		AmountPLN float64 `json:"amountPLN"`
-		Notes     *string `json:"notes"`
+		AmountPLN utils.Money `json:"amountPLN"`
+		Notes     *string     `json:"notes"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
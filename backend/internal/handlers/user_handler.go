@@ -4,6 +4,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/sainaif/holy-home/internal/config"
 	"github.com/sainaif/holy-home/internal/middleware"
+	"github.com/sainaif/holy-home/internal/models"
 	"github.com/sainaif/holy-home/internal/services"
 )
 
@@ -342,3 +343,149 @@ func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
 		"message": "User deleted successfully",
 	})
 }
+
+// GetResidencies lists the move-in/move-out intervals of a user
+func (h *UserHandler) GetResidencies(c *fiber.Ctx) error {
+	userID := c.Params("id")
+	if userID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	residencies, err := h.userService.GetResidencies(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(residencies)
+}
+
+// AddResidency records when a user moved in (and optionally out) (ADMIN only)
+func (h *UserHandler) AddResidency(c *fiber.Ctx) error {
+	userID := c.Params("id")
+	if userID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	currentUserID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	currentEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		currentEmail = "unknown"
+	}
+
+	var req services.ResidencyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	residency, err := h.userService.AddResidency(c.Context(), userID, req)
+	if err != nil {
+		h.auditService.LogAction(c.Context(), currentUserID, currentEmail, "", "user.residency.add", "user", &userID, map[string]interface{}{"error": err.Error()}, c.IP(), c.Get("User-Agent"), "failure")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), currentUserID, currentEmail, "", "user.residency.add", "user", &userID, residencyAuditDetails(residency), c.IP(), c.Get("User-Agent"), "success")
+
+	return c.Status(fiber.StatusCreated).JSON(residency)
+}
+
+// UpdateResidency changes the dates of a residency interval, e.g. to record a move-out (ADMIN only)
+func (h *UserHandler) UpdateResidency(c *fiber.Ctx) error {
+	userID := c.Params("id")
+	residencyID := c.Params("residencyId")
+	if userID == "" || residencyID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid residency ID",
+		})
+	}
+
+	currentUserID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	currentEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		currentEmail = "unknown"
+	}
+
+	var req services.ResidencyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	residency, err := h.userService.UpdateResidency(c.Context(), userID, residencyID, req)
+	if err != nil {
+		h.auditService.LogAction(c.Context(), currentUserID, currentEmail, "", "user.residency.update", "user", &userID, map[string]interface{}{"residencyId": residencyID, "error": err.Error()}, c.IP(), c.Get("User-Agent"), "failure")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), currentUserID, currentEmail, "", "user.residency.update", "user", &userID, residencyAuditDetails(residency), c.IP(), c.Get("User-Agent"), "success")
+
+	return c.JSON(residency)
+}
+
+// DeleteResidency removes a residency interval (ADMIN only)
+func (h *UserHandler) DeleteResidency(c *fiber.Ctx) error {
+	userID := c.Params("id")
+	residencyID := c.Params("residencyId")
+	if userID == "" || residencyID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid residency ID",
+		})
+	}
+
+	currentUserID, err := middleware.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	currentEmail, err := middleware.GetUserEmail(c)
+	if err != nil {
+		currentEmail = "unknown"
+	}
+
+	if err := h.userService.DeleteResidency(c.Context(), userID, residencyID); err != nil {
+		h.auditService.LogAction(c.Context(), currentUserID, currentEmail, "", "user.residency.delete", "user", &userID, map[string]interface{}{"residencyId": residencyID, "error": err.Error()}, c.IP(), c.Get("User-Agent"), "failure")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	h.auditService.LogAction(c.Context(), currentUserID, currentEmail, "", "user.residency.delete", "user", &userID, map[string]interface{}{"residencyId": residencyID}, c.IP(), c.Get("User-Agent"), "success")
+
+	return c.JSON(fiber.Map{
+		"message": "Residency deleted successfully",
+	})
+}
+
+func residencyAuditDetails(residency *models.Residency) map[string]interface{} {
+	details := map[string]interface{}{
+		"residencyId": residency.ID,
+		"movedInAt":   residency.MovedInAt.Format("2006-01-02"),
+	}
+	if residency.MovedOutAt != nil {
+		details["movedOutAt"] = residency.MovedOutAt.Format("2006-01-02")
+	}
+	return details
+}
@@ -36,6 +36,16 @@ type PasskeyCredential struct {
 	BackupState    bool `db:"backup_state" json:"backupState"`
 }
 
+// Residency is a period during which a user lived in the household.
+// Both dates are whole days and inclusive; no MovedOutAt means the user still lives there.
+type Residency struct {
+	ID         string     `db:"id" json:"id"`
+	UserID     string     `db:"user_id" json:"userId"`
+	MovedInAt  time.Time  `db:"moved_in_at" json:"movedInAt"`
+	MovedOutAt *time.Time `db:"moved_out_at" json:"movedOutAt,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"createdAt"`
+}
+
 // Group represents a household group (e.g., couples)
 type Group struct {
 	ID        string    `db:"id" json:"id"`
@@ -46,22 +56,78 @@ type Group struct {
 
 // Bill represents a utility bill or shared expense
 type Bill struct {
-	ID                  string     `db:"id" json:"id"`
-	Type                string     `db:"type" json:"type"`                                // electricity, gas, internet, inne
-	CustomType          *string    `db:"custom_type" json:"customType,omitempty"`         // used when Type is "inne"
-	AllocationType      *string    `db:"allocation_type" json:"allocationType,omitempty"` // simple (like gas) or metered (like electricity) - only for "inne"
-	PeriodStart         time.Time  `db:"period_start" json:"periodStart"`
-	PeriodEnd           time.Time  `db:"period_end" json:"periodEnd"`
-	PaymentDeadline     *time.Time `db:"payment_deadline" json:"paymentDeadline,omitempty"` // optional deadline for payment
-	TotalAmountPLN      string     `db:"total_amount_pln" json:"totalAmountPLN"`            // Decimal as string
-	TotalUnits          string     `db:"total_units" json:"totalUnits,omitempty"`           // Decimal as string
-	Notes               *string    `db:"notes" json:"notes,omitempty"`
-	Status              string     `db:"status" json:"status"` // draft, posted, closed
-	ReopenedAt          *time.Time `db:"reopened_at" json:"reopenedAt,omitempty"`
-	ReopenReason        *string    `db:"reopen_reason" json:"reopenReason,omitempty"`
-	ReopenedBy          *string    `db:"reopened_by" json:"reopenedBy,omitempty"`
-	RecurringTemplateID *string    `db:"recurring_template_id" json:"recurringTemplateId,omitempty"` // link to recurring template if generated
-	CreatedAt           time.Time  `db:"created_at" json:"createdAt"`
+	ID                  string           `db:"id" json:"id"`
+	Type                string           `db:"type" json:"type"`                                // electricity, gas, internet, inne
+	CustomType          *string          `db:"custom_type" json:"customType,omitempty"`         // used when Type is "inne"
+	AllocationType      *string          `db:"allocation_type" json:"allocationType,omitempty"` // simple (like gas), metered (like electricity), custom (split rules) or itemized (receipt lines)
+	PeriodStart         time.Time        `db:"period_start" json:"periodStart"`
+	PeriodEnd           time.Time        `db:"period_end" json:"periodEnd"`
+	PaymentDeadline     *time.Time       `db:"payment_deadline" json:"paymentDeadline,omitempty"` // optional deadline for payment
+	TotalAmountPLN      string           `db:"total_amount_pln" json:"totalAmountPLN"`            // Decimal as string, in Currency
+	Currency            string           `db:"currency" json:"currency"`                          // ISO 4217 code, e.g. PLN, EUR
+	TotalUnits          string           `db:"total_units" json:"totalUnits,omitempty"`           // Decimal as string
+	Notes               *string          `db:"notes" json:"notes,omitempty"`
+	Status              string           `db:"status" json:"status"` // draft, posted, awaiting_payment, disputed, closed
+	ReopenedAt          *time.Time       `db:"reopened_at" json:"reopenedAt,omitempty"`
+	ReopenReason        *string          `db:"reopen_reason" json:"reopenReason,omitempty"`
+	ReopenedBy          *string          `db:"reopened_by" json:"reopenedBy,omitempty"`
+	RecurringTemplateID *string          `db:"recurring_template_id" json:"recurringTemplateId,omitempty"` // link to recurring template if generated
+	RecipientAccountID  *string          `db:"recipient_account_id" json:"recipientAccountId,omitempty"`   // bank account the shares are paid to
+	CreatedAt           time.Time        `db:"created_at" json:"createdAt"`
+	Splits              []BillSplit      `db:"-" json:"splits,omitempty"`      // Loaded separately, only for custom allocation
+	Items               []BillItem       `db:"-" json:"items,omitempty"`       // Loaded separately, only for itemized allocation
+	TariffZones         []BillTariffZone `db:"-" json:"tariffZones,omitempty"` // Loaded separately, only for metered allocation
+}
+
+// BillTransition records one change of a bill's status
+type BillTransition struct {
+	ID         string    `db:"id" json:"id"`
+	BillID     string    `db:"bill_id" json:"billId"`
+	Transition string    `db:"transition" json:"transition"` // post, request_payment, dispute, resolve_dispute, close, reopen, reopen_posted
+	FromStatus string    `db:"from_status" json:"fromStatus"`
+	ToStatus   string    `db:"to_status" json:"toStatus"`
+	ActorID    *string   `db:"actor_id" json:"actorId,omitempty"` // empty for transitions made by the system
+	Reason     *string   `db:"reason" json:"reason,omitempty"`
+	Forced     bool      `db:"forced" json:"forced"` // guards were overridden, e.g. closing a bill with unpaid shares
+	CreatedAt  time.Time `db:"created_at" json:"createdAt"`
+}
+
+// BillDispute is a resident's objection to their share of a posted bill
+type BillDispute struct {
+	ID              string     `db:"id" json:"id"`
+	BillID          string     `db:"bill_id" json:"billId"`
+	SubjectType     string     `db:"subject_type" json:"subjectType"` // "user" or "group"
+	SubjectID       string     `db:"subject_id" json:"subjectId"`
+	OpenedBy        string     `db:"opened_by" json:"openedBy"`
+	Reason          string     `db:"reason" json:"reason"`
+	AllocatedAmount string     `db:"allocated_amount" json:"allocatedAmount"`         // the share when the dispute was opened, decimal as string
+	ProposedAmount  string     `db:"proposed_amount" json:"proposedAmount"`           // decimal as string
+	Status          string     `db:"status" json:"status"`                            // open, accepted, rejected
+	ResolvedAmount  *string    `db:"resolved_amount" json:"resolvedAmount,omitempty"` // the share after an accepted dispute
+	ResolutionNote  *string    `db:"resolution_note" json:"resolutionNote,omitempty"`
+	ResolvedBy      *string    `db:"resolved_by" json:"resolvedBy,omitempty"`
+	ResolvedAt      *time.Time `db:"resolved_at" json:"resolvedAt,omitempty"`
+	CreatedAt       time.Time  `db:"created_at" json:"createdAt"`
+}
+
+// BillVersion is a bill and its allocations as they were when it was posted
+type BillVersion struct {
+	ID          string                  `db:"id" json:"id"`
+	BillID      string                  `db:"bill_id" json:"billId"`
+	Version     int                     `db:"version" json:"version"` // 1 for the first post, counting up with every amendment
+	Bill        Bill                    `db:"-" json:"bill"`
+	Allocations []BillVersionAllocation `db:"-" json:"allocations"`
+	Reason      *string                 `db:"reason" json:"reason,omitempty"` // why the bill was amended
+	CreatedBy   *string                 `db:"created_by" json:"createdBy,omitempty"`
+	CreatedAt   time.Time               `db:"created_at" json:"createdAt"`
+}
+
+// BillVersionAllocation is one share of a bill version
+type BillVersionAllocation struct {
+	SubjectType string `json:"subjectType"` // "user" or "group"
+	SubjectID   string `json:"subjectId"`
+	SubjectName string `json:"subjectName"`
+	Amount      string `json:"amount"` // decimal as string, in the bill currency
 }
 
 // RecurringBillTemplate represents a template for auto-generating bills
@@ -70,6 +136,7 @@ type RecurringBillTemplate struct {
 	CustomType      string                    `db:"custom_type" json:"customType"`  // name of the bill (e.g., "Netflix", "Rent")
 	Frequency       string                    `db:"frequency" json:"frequency"`     // monthly, quarterly, yearly
 	Amount          string                    `db:"amount" json:"amount"`           // fixed amount per period (decimal as string)
+	Currency        string                    `db:"currency" json:"currency"`       // currency of Amount and of generated bills
 	DayOfMonth      int                       `db:"day_of_month" json:"dayOfMonth"` // 1-31, day when bill is due
 	StartDate       time.Time                 `db:"start_date" json:"startDate"`    // required start date for first bill
 	Allocations     []RecurringBillAllocation `db:"-" json:"allocations"`           // Loaded separately
@@ -95,16 +162,107 @@ type RecurringBillAllocation struct {
 	FixedAmount    *string  `db:"fixed_amount" json:"fixedAmount,omitempty"`                 // fixed PLN amount (decimal as string)
 }
 
+// BillSplit is a custom split rule for a single bill.
+// Fixed amounts are taken first, percentages and fractions apply to what is left after them,
+// and the rest is divided by shares (or by household weight among subjects without a rule).
+type BillSplit struct {
+	ID             string   `db:"id" json:"id"`
+	BillID         string   `db:"bill_id" json:"billId,omitempty"`
+	SubjectType    string   `db:"subject_type" json:"subjectType"`                           // user or group
+	SubjectID      string   `db:"subject_id" json:"subjectId"`                               // user ID or group ID
+	AllocationType string   `db:"allocation_type" json:"allocationType"`                     // "fixed", "percentage", "fraction", "shares", "exclude"
+	Percentage     *float64 `db:"percentage" json:"percentage,omitempty"`                    // 0-100, for percentage type
+	FractionNum    *int     `db:"fraction_numerator" json:"fractionNumerator,omitempty"`     // numerator for fraction type
+	FractionDenom  *int     `db:"fraction_denominator" json:"fractionDenominator,omitempty"` // denominator for fraction type
+	FixedAmount    *string  `db:"fixed_amount" json:"fixedAmount,omitempty"`                 // exact amount in the bill currency (decimal as string)
+	Shares         *float64 `db:"shares" json:"shares,omitempty"`                            // number of shares, for shares type
+}
+
+// BillItem is one line of an itemized receipt.
+// Kind "item" is split equally among its participants; "tax" and "discount" lines have no
+// participants and are spread over everyone in proportion to their item subtotal.
+type BillItem struct {
+	ID           string                `db:"id" json:"id"`
+	BillID       string                `db:"bill_id" json:"billId,omitempty"`
+	Kind         string                `db:"kind" json:"kind"` // item, tax, discount
+	Description  string                `db:"description" json:"description"`
+	Amount       string                `db:"amount" json:"amount"` // Decimal as string, in the bill currency
+	Position     int                   `db:"position" json:"position"`
+	Participants []BillItemParticipant `db:"-" json:"participants,omitempty"` // Loaded separately
+}
+
+// BillItemParticipant is a user or group sharing a receipt line
+type BillItemParticipant struct {
+	SubjectType string `db:"subject_type" json:"subjectType"` // user or group
+	SubjectID   string `db:"subject_id" json:"subjectId"`
+}
+
+// BillResident is a resident of a bill period as stored when the bill was posted: the subject
+// they paid with, their weight and how many days of the period they lived in the household
+type BillResident struct {
+	BillID      string  `db:"bill_id" json:"billId"`
+	UserID      string  `db:"user_id" json:"userId"`
+	SubjectType string  `db:"subject_type" json:"subjectType"` // user or group
+	SubjectID   string  `db:"subject_id" json:"subjectId"`
+	Weight      float64 `db:"weight" json:"weight"`
+	DaysPresent int     `db:"days_present" json:"daysPresent"`
+	PeriodDays  int     `db:"period_days" json:"periodDays"`
+}
+
+// BillTariffZone is the part of a metered bill charged for one tariff zone (e.g. day or night).
+// The units of each zone are priced at the zone's amount; the rest of the bill is shared.
+type BillTariffZone struct {
+	BillID string `db:"bill_id" json:"billId,omitempty"`
+	Zone   string `db:"zone" json:"zone"`
+	Units  string `db:"units" json:"units"`   // Decimal as string
+	Amount string `db:"amount" json:"amount"` // Decimal as string, in the bill currency
+}
+
+// Meter is a physical meter of a user or group. Readings of a meter are compared with its
+// previous reading; the first reading of a replacement meter continues from the meter it replaced.
+type Meter struct {
+	ID              string     `db:"id" json:"id"`
+	Type            string     `db:"type" json:"type"`                  // electricity, gas, water, heat
+	Unit            string     `db:"unit" json:"unit"`                  // kWh, m3, GJ
+	SubjectType     string     `db:"subject_type" json:"subjectType"`   // "user" or "group"
+	SubjectID       string     `db:"subject_id" json:"subjectId"`       // user ID or group ID
+	TariffZone      string     `db:"tariff_zone" json:"tariffZone"`     // single, day, night, ...
+	SerialNumber    string     `db:"serial_number" json:"serialNumber"` // as printed on the meter
+	InstalledAt     time.Time  `db:"installed_at" json:"installedAt"`
+	InitialValue    string     `db:"initial_value" json:"initialValue"` // Decimal as string, value at installation
+	ReplacedAt      *time.Time `db:"replaced_at" json:"replacedAt,omitempty"`
+	FinalValue      *string    `db:"final_value" json:"finalValue,omitempty"` // Decimal as string, value at replacement
+	ReplacesMeterID *string    `db:"replaces_meter_id" json:"replacesMeterId,omitempty"`
+	CreatedAt       time.Time  `db:"created_at" json:"createdAt"`
+}
+
 // Consumption represents individual usage readings
 type Consumption struct {
-	ID          string    `db:"id" json:"id"`
-	BillID      string    `db:"bill_id" json:"billId"`
-	SubjectType string    `db:"subject_type" json:"subjectType"` // "user" or "group"
-	SubjectID   string    `db:"subject_id" json:"subjectId"`     // user ID or group ID
-	Units       string    `db:"units" json:"units"`              // Decimal as string
-	MeterValue  *string   `db:"meter_value" json:"meterValue,omitempty"`
-	RecordedAt  time.Time `db:"recorded_at" json:"recordedAt"`
-	Source      string    `db:"source" json:"source"` // user, admin
+	ID          string               `db:"id" json:"id"`
+	BillID      string               `db:"bill_id" json:"billId"`
+	SubjectType string               `db:"subject_type" json:"subjectType"` // "user" or "group"
+	SubjectID   string               `db:"subject_id" json:"subjectId"`     // user ID or group ID
+	Units       string               `db:"units" json:"units"`              // Decimal as string
+	MeterValue  *string              `db:"meter_value" json:"meterValue,omitempty"`
+	MeterID     *string              `db:"meter_id" json:"meterId,omitempty"` // registered meter the value was read from
+	RecordedAt  time.Time            `db:"recorded_at" json:"recordedAt"`
+	Source      string               `db:"source" json:"source"`         // user, admin, invalid, estimated
+	Anomalies   []ConsumptionAnomaly `db:"-" json:"anomalies,omitempty"` // Loaded separately, flags raised by the anomaly check
+	Photo       *Attachment          `db:"-" json:"photo,omitempty"`     // Loaded separately, photo of the meter sent with the reading
+}
+
+// ConsumptionAnomaly flags a reading that does not fit the subject's usual usage, or a meter nobody reads
+type ConsumptionAnomaly struct {
+	ID                 string    `db:"id" json:"id"`
+	ConsumptionID      *string   `db:"consumption_id" json:"consumptionId,omitempty"` // flagged reading, empty for stale meters
+	MeterID            *string   `db:"meter_id" json:"meterId,omitempty"`
+	SubjectType        string    `db:"subject_type" json:"subjectType"`
+	SubjectID          string    `db:"subject_id" json:"subjectId"`
+	Kind               string    `db:"kind" json:"kind"`                                         // spike, negative_delta, implausible_delta, stale_meter
+	DailyUnits         *string   `db:"daily_units" json:"dailyUnits,omitempty"`                  // usage per day since the previous reading, decimal as string
+	ExpectedDailyUnits *string   `db:"expected_daily_units" json:"expectedDailyUnits,omitempty"` // usual usage per day, decimal as string
+	Message            string    `db:"message" json:"message"`
+	DetectedAt         time.Time `db:"detected_at" json:"detectedAt"`
 }
 
 // Payment represents a payment towards a bill
@@ -118,12 +276,83 @@ type Payment struct {
 	Reference   *string   `db:"reference" json:"reference,omitempty"`
 }
 
+// CreditEntry is one movement of a user's bill credit.
+// Overpaying a bill adds credit, covering a later allocation with it uses credit up.
+type CreditEntry struct {
+	ID        string    `db:"id" json:"id"`
+	UserID    string    `db:"user_id" json:"userId"`
+	BillID    *string   `db:"bill_id" json:"billId,omitempty"`
+	PaymentID *string   `db:"payment_id" json:"paymentId,omitempty"`
+	Kind      string    `db:"kind" json:"kind"`     // overpayment, applied
+	Amount    string    `db:"amount" json:"amount"` // Decimal as string, positive adds credit
+	Currency  string    `db:"currency" json:"currency"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
+
+// BankAccount is a bank account a resident receives transfers on
+type BankAccount struct {
+	ID            string    `db:"id" json:"id"`
+	UserID        string    `db:"user_id" json:"userId"`
+	Label         string    `db:"label" json:"label"`
+	HolderName    string    `db:"holder_name" json:"holderName"`       // recipient name shown in transfers
+	AccountNumber string    `db:"account_number" json:"accountNumber"` // 26-digit Polish NRB
+	IsDefault     bool      `db:"is_default" json:"isDefault"`
+	CreatedAt     time.Time `db:"created_at" json:"createdAt"`
+}
+
+// BankImport is one uploaded bank statement file
+type BankImport struct {
+	ID         string    `db:"id" json:"id"`
+	FileName   string    `db:"file_name" json:"fileName"`
+	Format     string    `db:"format" json:"format"` // csv, mt940, camt053
+	ImportedBy *string   `db:"imported_by" json:"importedBy,omitempty"`
+	LineCount  int       `db:"line_count" json:"lineCount"` // transactions stored from the file
+	CreatedAt  time.Time `db:"created_at" json:"createdAt"`
+}
+
+// BankTransaction is an incoming transfer read from a bank statement.
+// Pending transactions wait in the review queue until an admin matches them
+// to a bill share or a loan, or ignores them.
+type BankTransaction struct {
+	ID                  string     `db:"id" json:"id"`
+	ImportID            string     `db:"import_id" json:"importId"`
+	Fingerprint         string     `db:"fingerprint" json:"fingerprint"` // identifies the line again in overlapping statements
+	BookedAt            time.Time  `db:"booked_at" json:"bookedAt"`
+	Amount              string     `db:"amount" json:"amount"` // Decimal as string, in Currency
+	Currency            string     `db:"currency" json:"currency"`
+	CounterpartyName    *string    `db:"counterparty_name" json:"counterpartyName,omitempty"`
+	CounterpartyAccount *string    `db:"counterparty_account" json:"counterpartyAccount,omitempty"`
+	Title               string     `db:"title" json:"title"`
+	Reference           *string    `db:"reference" json:"reference,omitempty"`  // bank or end-to-end reference
+	Status              string     `db:"status" json:"status"`                  // pending, matched, ignored
+	MatchType           *string    `db:"match_type" json:"matchType,omitempty"` // bill, loan
+	PaymentID           *string    `db:"payment_id" json:"paymentId,omitempty"`
+	LoanPaymentID       *string    `db:"loan_payment_id" json:"loanPaymentId,omitempty"`
+	ReviewedBy          *string    `db:"reviewed_by" json:"reviewedBy,omitempty"`
+	ReviewedAt          *time.Time `db:"reviewed_at" json:"reviewedAt,omitempty"`
+}
+
+// Attachment is a scanned invoice or receipt attached to a bill, supply item or loan, or a meter photo sent with a reading.
+// The file itself lives in the blob store under its SHA-256.
+type Attachment struct {
+	ID           string    `db:"id" json:"id"`
+	ResourceType string    `db:"resource_type" json:"resourceType"` // bill, supply_restock, loan, consumption
+	ResourceID   string    `db:"resource_id" json:"resourceId"`
+	FileName     string    `db:"file_name" json:"fileName"`
+	ContentType  string    `db:"content_type" json:"contentType"`
+	SizeBytes    int64     `db:"size_bytes" json:"sizeBytes"`
+	SHA256       string    `db:"sha256" json:"sha256"`
+	UploadedBy   *string   `db:"uploaded_by" json:"uploadedBy,omitempty"`
+	CreatedAt    time.Time `db:"created_at" json:"createdAt"`
+}
+
 // Loan represents money lent between users
 type Loan struct {
 	ID         string     `db:"id" json:"id"`
 	LenderID   string     `db:"lender_id" json:"lenderId"`
 	BorrowerID string     `db:"borrower_id" json:"borrowerId"`
-	AmountPLN  string     `db:"amount_pln" json:"amountPLN"` // Decimal as string
+	AmountPLN  string     `db:"amount_pln" json:"amountPLN"` // Decimal as string, in Currency
+	Currency   string     `db:"currency" json:"currency"`    // ISO 4217 code; repayments use the same currency
 	Note       *string    `db:"note" json:"note,omitempty"`
 	DueDate    *time.Time `db:"due_date" json:"dueDate,omitempty"`
 	Status     string     `db:"status" json:"status"` // open, partial, settled
@@ -132,11 +361,84 @@ type Loan struct {
 
 // LoanPayment represents a partial or full loan repayment
 type LoanPayment struct {
+	ID           string    `db:"id" json:"id"`
+	LoanID       string    `db:"loan_id" json:"loanId"`
+	AmountPLN    string    `db:"amount_pln" json:"amountPLN"` // Decimal as string
+	PaidAt       time.Time `db:"paid_at" json:"paidAt"`
+	Note         *string   `db:"note" json:"note,omitempty"`
+	SettlementID *string   `db:"settlement_id" json:"settlementId,omitempty"` // settle-up that repaid the loan
+}
+
+// SettlementTransfer is a transfer a settle-up asked one party (a user or a group) to make to another
+type SettlementTransfer struct {
+	ID           string    `db:"id" json:"id"`
+	SettlementID string    `db:"settlement_id" json:"settlementId"`
+	FromType     string    `db:"from_type" json:"fromType"` // user, group
+	FromID       string    `db:"from_id" json:"fromId"`
+	ToType       string    `db:"to_type" json:"toType"`
+	ToID         string    `db:"to_id" json:"toId"`
+	Amount       string    `db:"amount" json:"amount"`     // Decimal as string, in Currency
+	Currency     string    `db:"currency" json:"currency"` // household base currency at the time
+	CreatedAt    time.Time `db:"created_at" json:"createdAt"`
+}
+
+// LateFeePolicy sets the late fees of bills of one type or of loans
+type LateFeePolicy struct {
+	ID               string    `db:"id" json:"id"`
+	ResourceType     string    `db:"resource_type" json:"resourceType"`          // bill, loan
+	BillType         string    `db:"bill_type" json:"billType,omitempty"`        // bills only
+	CustomType       string    `db:"custom_type" json:"customType,omitempty"`    // "inne" bills only, empty for every bill of the type
+	LoanID           string    `db:"loan_id" json:"loanId,omitempty"`            // loans only, empty for the default loan policy
+	FlatFee          string    `db:"flat_fee" json:"flatFee"`                    // charged once after the grace days, decimal as string
+	PercentPerPeriod string    `db:"percent_per_period" json:"percentPerPeriod"` // percent of the unpaid amount charged every period, decimal as string
+	PeriodDays       int       `db:"period_days" json:"periodDays"`
+	GraceDays        int       `db:"grace_days" json:"graceDays"`
+	Cap              *string   `db:"cap" json:"cap,omitempty"` // most that fees can add up to per person and bill or loan
+	CreatedAt        time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt        time.Time `db:"updated_at" json:"updatedAt"`
+}
+
+// LateFee is a fee accrued on an overdue bill share or loan
+type LateFee struct {
+	ID            string     `db:"id" json:"id"`
+	PolicyID      *string    `db:"policy_id" json:"policyId,omitempty"`
+	ResourceType  string     `db:"resource_type" json:"resourceType"` // bill, loan
+	ResourceID    string     `db:"resource_id" json:"resourceId"`
+	SubjectType   string     `db:"subject_type" json:"subjectType"` // "user" or "group" that is charged
+	SubjectID     string     `db:"subject_id" json:"subjectId"`
+	BeneficiaryID *string    `db:"beneficiary_id" json:"beneficiaryId,omitempty"` // the lender of a loan
+	Kind          string     `db:"kind" json:"kind"`                              // flat, interest
+	Period        int        `db:"period" json:"period"`                          // 0 for the flat fee, 1, 2, ... for interest periods
+	Amount        string     `db:"amount" json:"amount"`                          // decimal as string, in Currency
+	Currency      string     `db:"currency" json:"currency"`
+	AccruedAt     time.Time  `db:"accrued_at" json:"accruedAt"`
+	Status        string     `db:"status" json:"status"` // accrued, waived
+	WaivedBy      *string    `db:"waived_by" json:"waivedBy,omitempty"`
+	WaivedAt      *time.Time `db:"waived_at" json:"waivedAt,omitempty"`
+	WaiveReason   *string    `db:"waive_reason" json:"waiveReason,omitempty"`
+	CreatedAt     time.Time  `db:"created_at" json:"createdAt"`
+}
+
+// Budget is the monthly spending limit of a bill type or supply category, in the base currency
+type Budget struct {
+	ID              string    `db:"id" json:"id"`
+	Kind            string    `db:"kind" json:"kind"`                        // bill, supply
+	Category        string    `db:"category" json:"category"`                // bill type, or supply category
+	CustomType      string    `db:"custom_type" json:"customType,omitempty"` // name of the bill for "inne" bills, empty for all of them
+	MonthlyAmount   string    `db:"monthly_amount" json:"monthlyAmount"`     // decimal as string
+	AlertThresholds []int     `db:"-" json:"alertThresholds"`                // percentages of the budget, e.g. 80 and 100
+	CreatedAt       time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt       time.Time `db:"updated_at" json:"updatedAt"`
+}
+
+// BudgetAlert records that spending in a month reached a threshold of a budget
+type BudgetAlert struct {
 	ID        string    `db:"id" json:"id"`
-	LoanID    string    `db:"loan_id" json:"loanId"`
-	AmountPLN string    `db:"amount_pln" json:"amountPLN"` // Decimal as string
-	PaidAt    time.Time `db:"paid_at" json:"paidAt"`
-	Note      *string   `db:"note" json:"note,omitempty"`
+	BudgetID  string    `db:"budget_id" json:"budgetId"`
+	Month     string    `db:"month" json:"month"` // YYYY-MM
+	Threshold int       `db:"threshold" json:"threshold"`
+	Actual    string    `db:"actual" json:"actual"` // spending when the alert was sent, decimal as string
+	SentAt    time.Time `db:"sent_at" json:"sentAt"`
 }
 
 // Chore represents a household task
@@ -211,9 +513,33 @@ type AppSettings struct {
 	DefaultLanguage          string    `db:"default_language" json:"defaultLanguage"`                      // Default locale code (e.g., "en", "pl")
 	DisableAutoDetect        bool      `db:"disable_auto_detect" json:"disableAutoDetect"`                 // If true, always use default language
 	ReminderRateLimitPerHour int       `db:"reminder_rate_limit_per_hour" json:"reminderRateLimitPerHour"` // Max reminders per user per hour (0 = unlimited)
+	BaseCurrency             string    `db:"base_currency" json:"baseCurrency"`                            // Currency balances and reports are converted to
 	UpdatedAt                time.Time `db:"updated_at" json:"updatedAt"`
 }
 
+// ExchangeRate is a locally maintained conversion rate valid from RateDate
+// until the next rate for the same currency
+type ExchangeRate struct {
+	ID        string    `db:"id" json:"id"`
+	Currency  string    `db:"currency" json:"currency"`  // ISO 4217 code, e.g. EUR
+	Rate      string    `db:"rate" json:"rate"`          // Base currency units per 1 unit of Currency, decimal as string
+	RateDate  time.Time `db:"rate_date" json:"rateDate"` // Day the rate applies from
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
+
+// UtilityTariff is the price of a metered utility in one tariff zone, valid from ValidFrom
+// until the next tariff for the same utility and zone
+type UtilityTariff struct {
+	ID              string    `db:"id" json:"id"`
+	BillType        string    `db:"bill_type" json:"billType"`                // electricity, gas, inne
+	CustomType      string    `db:"custom_type" json:"customType,omitempty"`  // name of the utility for "inne" bills, e.g. Woda
+	TariffZone      string    `db:"tariff_zone" json:"tariffZone"`            // single, day, night, ...
+	UnitPrice       string    `db:"unit_price" json:"unitPrice"`              // Base currency per unit, decimal as string
+	FixedFeeMonthly string    `db:"fixed_fee_monthly" json:"fixedFeeMonthly"` // Base currency per month, decimal as string
+	ValidFrom       time.Time `db:"valid_from" json:"validFrom"`              // Day the tariff applies from
+	CreatedAt       time.Time `db:"created_at" json:"createdAt"`
+}
+
 // SupplyItem represents a household supply with inventory tracking
 type SupplyItem struct {
 	ID                    string     `db:"id" json:"id"`
@@ -227,7 +553,8 @@ type SupplyItem struct {
 	AddedAt               time.Time  `db:"added_at" json:"addedAt"`
 	LastRestockedAt       *time.Time `db:"last_restocked_at" json:"lastRestockedAt,omitempty"`
 	LastRestockedByUserID *string    `db:"last_restocked_by_user_id" json:"lastRestockedByUserId,omitempty"`
-	LastRestockAmountPLN  *string    `db:"last_restock_amount_pln" json:"lastRestockAmountPLN,omitempty"` // decimal as string
+	LastRestockAmountPLN  *string    `db:"last_restock_amount_pln" json:"lastRestockAmountPLN,omitempty"` // decimal as string, in LastRestockCurrency
+	LastRestockCurrency   *string    `db:"last_restock_currency" json:"lastRestockCurrency,omitempty"`    // nil means the base currency
 	NeedsRefund           bool       `db:"needs_refund" json:"needsRefund"`                               // If last restock awaits reimbursement
 	Notes                 *string    `db:"notes" json:"notes,omitempty"`
 }
@@ -249,7 +576,7 @@ type SupplyItemHistory struct {
 	ID            string    `db:"id" json:"id"`
 	SupplyItemID  string    `db:"supply_item_id" json:"supplyItemId"`
 	UserID        string    `db:"user_id" json:"userId"`
-	Action        string    `db:"action" json:"action"`                // add, remove, restock, purchase, adjust
+	Action        string    `db:"action" json:"action"`                // add, remove, restock, purchase, adjust, refund
 	QuantityDelta int       `db:"quantity_delta" json:"quantityDelta"` // +/- amount changed
 	OldQuantity   int       `db:"old_quantity" json:"oldQuantity"`
 	NewQuantity   int       `db:"new_quantity" json:"newQuantity"`
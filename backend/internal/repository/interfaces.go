@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/utils"
 )
 
 // UserRepository handles user data operations
@@ -42,6 +43,16 @@ type PasskeyCredentialRepository interface {
 	List(ctx context.Context) ([]PasskeyCredentialWithUser, error) // for backups
 }
 
+// ResidencyRepository handles user move-in/move-out intervals
+type ResidencyRepository interface {
+	Create(ctx context.Context, residency *models.Residency) error
+	GetByID(ctx context.Context, id string) (*models.Residency, error)
+	Update(ctx context.Context, residency *models.Residency) error
+	Delete(ctx context.Context, id string) error
+	ListByUserID(ctx context.Context, userID string) ([]models.Residency, error)
+	List(ctx context.Context) ([]models.Residency, error)
+}
+
 // GroupRepository handles group operations
 type GroupRepository interface {
 	Create(ctx context.Context, group *models.Group) error
@@ -62,9 +73,35 @@ type BillRepository interface {
 	ListByType(ctx context.Context, billType string) ([]models.Bill, error)
 	ListByPeriod(ctx context.Context, start, end time.Time) ([]models.Bill, error)
 	ListFiltered(ctx context.Context, billType *string, from, to *time.Time) ([]models.Bill, error)
+	ListOverlapping(ctx context.Context, start, end time.Time, statuses []string) ([]models.Bill, error)
 	GetByRecurringTemplateID(ctx context.Context, templateID string) (*models.Bill, error)
 }
 
+// BillTransitionRepository handles the status history of bills
+type BillTransitionRepository interface {
+	Create(ctx context.Context, transition *models.BillTransition) error
+	ListByBillID(ctx context.Context, billID string) ([]models.BillTransition, error)
+	List(ctx context.Context) ([]models.BillTransition, error)
+}
+
+// BillDisputeRepository handles disputes of bill allocations
+type BillDisputeRepository interface {
+	Create(ctx context.Context, dispute *models.BillDispute) error
+	GetByID(ctx context.Context, id string) (*models.BillDispute, error)
+	Update(ctx context.Context, dispute *models.BillDispute) error
+	ListByBillID(ctx context.Context, billID string) ([]models.BillDispute, error)
+	ListByStatus(ctx context.Context, status string) ([]models.BillDispute, error)
+	List(ctx context.Context) ([]models.BillDispute, error)
+}
+
+// BillVersionRepository handles the posted versions of bills
+type BillVersionRepository interface {
+	Create(ctx context.Context, version *models.BillVersion) error
+	GetByVersion(ctx context.Context, billID string, version int) (*models.BillVersion, error)
+	ListByBillID(ctx context.Context, billID string) ([]models.BillVersion, error)
+	List(ctx context.Context) ([]models.BillVersion, error)
+}
+
 // RecurringBillTemplateRepository handles recurring bill template operations
 type RecurringBillTemplateRepository interface {
 	Create(ctx context.Context, template *models.RecurringBillTemplate) error
@@ -85,6 +122,49 @@ type RecurringBillAllocationRepository interface {
 	List(ctx context.Context) ([]models.RecurringBillAllocation, error)
 }
 
+// BillSplitRepository handles per-bill custom split rules
+type BillSplitRepository interface {
+	Create(ctx context.Context, billID string, split *models.BillSplit) error
+	GetByBillID(ctx context.Context, billID string) ([]models.BillSplit, error)
+	DeleteByBillID(ctx context.Context, billID string) error
+	List(ctx context.Context) ([]models.BillSplit, error)
+}
+
+// BillItemRepository handles receipt lines of itemized bills
+type BillItemRepository interface {
+	Create(ctx context.Context, billID string, item *models.BillItem) error
+	GetByBillID(ctx context.Context, billID string) ([]models.BillItem, error)
+	DeleteByBillID(ctx context.Context, billID string) error
+	List(ctx context.Context) ([]models.BillItem, error)
+}
+
+// BillTariffZoneRepository handles the tariff zones of metered bills
+type BillTariffZoneRepository interface {
+	Create(ctx context.Context, billID string, zone *models.BillTariffZone) error
+	GetByBillID(ctx context.Context, billID string) ([]models.BillTariffZone, error)
+	DeleteByBillID(ctx context.Context, billID string) error
+	List(ctx context.Context) ([]models.BillTariffZone, error)
+}
+
+// BillResidentRepository handles the residents stored for posted bills
+type BillResidentRepository interface {
+	Create(ctx context.Context, resident *models.BillResident) error
+	GetByBillID(ctx context.Context, billID string) ([]models.BillResident, error)
+	DeleteByBillID(ctx context.Context, billID string) error
+	List(ctx context.Context) ([]models.BillResident, error)
+}
+
+// MeterRepository handles the meter registry
+type MeterRepository interface {
+	Create(ctx context.Context, meter *models.Meter) error
+	GetByID(ctx context.Context, id string) (*models.Meter, error)
+	GetByReplacesMeterID(ctx context.Context, meterID string) (*models.Meter, error)
+	Update(ctx context.Context, meter *models.Meter) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]models.Meter, error)
+	ListBySubject(ctx context.Context, subjectType, subjectID string) ([]models.Meter, error)
+}
+
 // ConsumptionRepository handles consumption/meter reading operations
 type ConsumptionRepository interface {
 	Create(ctx context.Context, consumption *models.Consumption) error
@@ -94,8 +174,19 @@ type ConsumptionRepository interface {
 	List(ctx context.Context) ([]models.Consumption, error)
 	ListByBillID(ctx context.Context, billID string) ([]models.Consumption, error)
 	ListBySubject(ctx context.Context, subjectType, subjectID string) ([]models.Consumption, error)
+	ListByMeterID(ctx context.Context, meterID string) ([]models.Consumption, error)
 	ListFiltered(ctx context.Context, subjectType, subjectID *string, from, to *time.Time) ([]models.Consumption, error)
 	DeleteByBillID(ctx context.Context, billID string) error
+	ListUnchecked(ctx context.Context) ([]models.Consumption, error)
+	MarkChecked(ctx context.Context, id string, at time.Time) error
+}
+
+// ConsumptionAnomalyRepository handles flags raised by the reading anomaly check
+type ConsumptionAnomalyRepository interface {
+	Create(ctx context.Context, anomaly *models.ConsumptionAnomaly) error
+	ListByConsumptionID(ctx context.Context, consumptionID string) ([]models.ConsumptionAnomaly, error)
+	GetLatestByMeterID(ctx context.Context, meterID, kind string) (*models.ConsumptionAnomaly, error)
+	List(ctx context.Context) ([]models.ConsumptionAnomaly, error)
 }
 
 // AllocationRepository handles bill allocation operations
@@ -127,6 +218,54 @@ type PaymentRepository interface {
 	SumByBillID(ctx context.Context, billID string) (string, error) // Returns decimal string
 }
 
+// CreditEntryRepository handles the per-user bill credit ledger
+type CreditEntryRepository interface {
+	Create(ctx context.Context, entry *models.CreditEntry) error
+	ListByUserID(ctx context.Context, userID string) ([]models.CreditEntry, error)
+	ListByBillID(ctx context.Context, billID string) ([]models.CreditEntry, error)
+	DeleteAppliedByBillID(ctx context.Context, billID string) error
+	List(ctx context.Context) ([]models.CreditEntry, error)
+}
+
+// AttachmentRepository handles attachment metadata; file contents live in the blob store
+type AttachmentRepository interface {
+	Create(ctx context.Context, attachment *models.Attachment) error
+	GetByID(ctx context.Context, id string) (*models.Attachment, error)
+	Delete(ctx context.Context, id string) error
+	ListByResource(ctx context.Context, resourceType, resourceID string) ([]models.Attachment, error)
+	List(ctx context.Context) ([]models.Attachment, error)
+	CountBySHA256(ctx context.Context, sha256 string) (int, error)
+}
+
+// BankAccountRepository handles residents' bank accounts
+type BankAccountRepository interface {
+	Create(ctx context.Context, account *models.BankAccount) error
+	GetByID(ctx context.Context, id string) (*models.BankAccount, error)
+	Update(ctx context.Context, account *models.BankAccount) error
+	Delete(ctx context.Context, id string) error
+	ListByUserID(ctx context.Context, userID string) ([]models.BankAccount, error)
+	List(ctx context.Context) ([]models.BankAccount, error)
+	ClearDefault(ctx context.Context, userID string) error
+}
+
+// BankImportRepository handles uploaded bank statement files
+type BankImportRepository interface {
+	Create(ctx context.Context, bankImport *models.BankImport) error
+	GetByID(ctx context.Context, id string) (*models.BankImport, error)
+	List(ctx context.Context) ([]models.BankImport, error)
+}
+
+// BankTransactionRepository handles transactions read from bank statements
+type BankTransactionRepository interface {
+	Create(ctx context.Context, txn *models.BankTransaction) error
+	GetByID(ctx context.Context, id string) (*models.BankTransaction, error)
+	Update(ctx context.Context, txn *models.BankTransaction) error
+	ExistsByFingerprint(ctx context.Context, fingerprint string) (bool, error)
+	ListByImportID(ctx context.Context, importID string) ([]models.BankTransaction, error)
+	ListByStatus(ctx context.Context, status string) ([]models.BankTransaction, error)
+	List(ctx context.Context) ([]models.BankTransaction, error)
+}
+
 // LoanRepository handles loan operations
 type LoanRepository interface {
 	Create(ctx context.Context, loan *models.Loan) error
@@ -150,6 +289,13 @@ type LoanPaymentRepository interface {
 	SumByLoanID(ctx context.Context, loanID string) (string, error)
 }
 
+// SettlementTransferRepository handles transfers recorded by settle-ups
+type SettlementTransferRepository interface {
+	Create(ctx context.Context, transfer *models.SettlementTransfer) error
+	ListBySettlementID(ctx context.Context, settlementID string) ([]models.SettlementTransfer, error)
+	List(ctx context.Context) ([]models.SettlementTransfer, error)
+}
+
 // ChoreRepository handles chore operations
 type ChoreRepository interface {
 	Create(ctx context.Context, chore *models.Chore) error
@@ -212,8 +358,10 @@ type SupplyContributionRepository interface {
 // SupplyItemHistoryRepository handles supply item history operations
 type SupplyItemHistoryRepository interface {
 	Create(ctx context.Context, history *models.SupplyItemHistory) error
+	GetByID(ctx context.Context, id string) (*models.SupplyItemHistory, error)
 	ListBySupplyItemID(ctx context.Context, supplyItemID string) ([]models.SupplyItemHistory, error)
 	ListByUserID(ctx context.Context, userID string) ([]models.SupplyItemHistory, error)
+	List(ctx context.Context) ([]models.SupplyItemHistory, error)
 }
 
 // SessionRepository handles session operations
@@ -316,19 +464,138 @@ type SentReminderRepository interface {
 	List(ctx context.Context) ([]models.SentReminder, error)
 }
 
+// ExchangeRateRepository handles locally maintained currency exchange rates
+type ExchangeRateRepository interface {
+	Upsert(ctx context.Context, rate *models.ExchangeRate) error
+	GetByID(ctx context.Context, id string) (*models.ExchangeRate, error)
+	GetLatest(ctx context.Context, currency string, at time.Time) (*models.ExchangeRate, error)
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]models.ExchangeRate, error)
+}
+
+// UtilityTariffRepository handles configured unit prices of metered utilities
+type UtilityTariffRepository interface {
+	Upsert(ctx context.Context, tariff *models.UtilityTariff) error
+	GetByID(ctx context.Context, id string) (*models.UtilityTariff, error)
+	ListByUtility(ctx context.Context, billType, customType string) ([]models.UtilityTariff, error)
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]models.UtilityTariff, error)
+}
+
+// LateFeePolicyRepository handles late-fee policies
+type LateFeePolicyRepository interface {
+	Upsert(ctx context.Context, policy *models.LateFeePolicy) error
+	GetByID(ctx context.Context, id string) (*models.LateFeePolicy, error)
+	Find(ctx context.Context, resourceType, billType, customType, loanID string) (*models.LateFeePolicy, error)
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]models.LateFeePolicy, error)
+}
+
+// LateFeeRepository handles accrued late fees
+type LateFeeRepository interface {
+	Create(ctx context.Context, fee *models.LateFee) error
+	GetByID(ctx context.Context, id string) (*models.LateFee, error)
+	Update(ctx context.Context, fee *models.LateFee) error
+	ListByResource(ctx context.Context, resourceType, resourceID string) ([]models.LateFee, error)
+	ListBySubject(ctx context.Context, subjectType, subjectID string) ([]models.LateFee, error)
+	ListByBeneficiaryID(ctx context.Context, userID string) ([]models.LateFee, error)
+	List(ctx context.Context) ([]models.LateFee, error)
+}
+
+// BudgetRepository handles monthly budgets
+type BudgetRepository interface {
+	Upsert(ctx context.Context, budget *models.Budget) error
+	GetByID(ctx context.Context, id string) (*models.Budget, error)
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]models.Budget, error)
+}
+
+// BudgetAlertRepository handles sent budget alerts
+type BudgetAlertRepository interface {
+	Create(ctx context.Context, alert *models.BudgetAlert) error
+	ListByMonth(ctx context.Context, month string) ([]models.BudgetAlert, error)
+	List(ctx context.Context) ([]models.BudgetAlert, error)
+}
+
+// AnalyticsFilter narrows and groups analytics queries
+type AnalyticsFilter struct {
+	From     *time.Time     // inclusive
+	To       *time.Time     // exclusive
+	GroupBy  string         // month, quarter, year
+	BillType string         // empty for every bill type
+	Statuses []string       // bill statuses to include
+	Location *time.Location // time zone periods follow, the local one when nil
+}
+
+// BillCostAggregate is the cost of the bills of one type, currency and period
+type BillCostAggregate struct {
+	Period     string
+	BillType   string
+	CustomType string
+	Currency   string
+	Amount     utils.Money
+	Units      float64 // metered bills only
+	BillCount  int
+}
+
+// PaymentAggregate is what one user paid towards bills in one currency and period
+type PaymentAggregate struct {
+	Period   string
+	UserID   string
+	Currency string
+	Amount   utils.Money
+}
+
+// SupplySpendAggregate is what one user spent on one supply category in a period, in the base currency
+type SupplySpendAggregate struct {
+	Period    string
+	Category  string
+	UserID    string
+	Amount    utils.Money
+	Purchases int
+}
+
+// SupplySpendActions are the supply history actions that record money spent on supplies.
+// Budgets and analytics count the same entries.
+var SupplySpendActions = []string{"restock"}
+
+// AnalyticsRepository aggregates spending for reports
+type AnalyticsRepository interface {
+	BillCosts(ctx context.Context, filter AnalyticsFilter) ([]BillCostAggregate, error)
+	MeteredCosts(ctx context.Context, filter AnalyticsFilter) ([]BillCostAggregate, error)
+	BillPayments(ctx context.Context, filter AnalyticsFilter) ([]PaymentAggregate, error)
+	SupplySpend(ctx context.Context, filter AnalyticsFilter) ([]SupplySpendAggregate, error)
+}
+
 // Repositories aggregates all repository interfaces
 type Repositories struct {
 	Users                    UserRepository
 	PasskeyCredentials       PasskeyCredentialRepository
+	Residencies              ResidencyRepository
 	Groups                   GroupRepository
 	Bills                    BillRepository
+	BillTransitions          BillTransitionRepository
+	BillDisputes             BillDisputeRepository
+	BillVersions             BillVersionRepository
 	RecurringBillTemplates   RecurringBillTemplateRepository
 	RecurringBillAllocations RecurringBillAllocationRepository
+	BillSplits               BillSplitRepository
+	BillItems                BillItemRepository
+	BillTariffZones          BillTariffZoneRepository
+	BillResidents            BillResidentRepository
+	Meters                   MeterRepository
 	Consumptions             ConsumptionRepository
+	ConsumptionAnomalies     ConsumptionAnomalyRepository
 	Allocations              AllocationRepository
 	Payments                 PaymentRepository
+	CreditEntries            CreditEntryRepository
+	BankAccounts             BankAccountRepository
+	BankImports              BankImportRepository
+	BankTransactions         BankTransactionRepository
+	Attachments              AttachmentRepository
 	Loans                    LoanRepository
 	LoanPayments             LoanPaymentRepository
+	SettlementTransfers      SettlementTransferRepository
 	Chores                   ChoreRepository
 	ChoreAssignments         ChoreAssignmentRepository
 	ChoreSettings            ChoreSettingsRepository
@@ -347,4 +614,12 @@ type Repositories struct {
 	ApprovalRequests         ApprovalRequestRepository
 	AppSettings              AppSettingsRepository
 	SentReminders            SentReminderRepository
+	ExchangeRates            ExchangeRateRepository
+	UtilityTariffs           UtilityTariffRepository
+	LateFeePolicies          LateFeePolicyRepository
+	LateFees                 LateFeeRepository
+	Budgets                  BudgetRepository
+	BudgetAlerts             BudgetAlertRepository
+	Analytics                AnalyticsRepository
+	TxManager                TxManager
 }
@@ -32,14 +32,14 @@ func (r *AllocationRepository) Create(ctx context.Context, billID, subjectType,
 	id := uuid.New().String()
 
 	query := `INSERT INTO allocations (id, bill_id, subject_type, subject_id, allocated_pln) VALUES (?, ?, ?, ?, ?)`
-	_, err := r.db.ExecContext(ctx, query, id, billID, subjectType, subjectID, allocatedPLN)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, id, billID, subjectType, subjectID, allocatedPLN)
 	return err
 }
 
 // GetByBillID returns allocations for a bill
 func (r *AllocationRepository) GetByBillID(ctx context.Context, billID string) ([]repository.Allocation, error) {
 	var rows []AllocationRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM allocations WHERE bill_id = ?", billID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM allocations WHERE bill_id = ?", billID)
 	if err != nil {
 		return nil, err
 	}
@@ -59,20 +59,20 @@ func (r *AllocationRepository) GetByBillID(ctx context.Context, billID string) (
 
 // DeleteByBillID deletes all allocations for a bill
 func (r *AllocationRepository) DeleteByBillID(ctx context.Context, billID string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM allocations WHERE bill_id = ?", billID)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM allocations WHERE bill_id = ?", billID)
 	return err
 }
 
 // DeleteBySubjectID deletes all allocations for a subject (user or group)
 func (r *AllocationRepository) DeleteBySubjectID(ctx context.Context, subjectType, subjectID string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM allocations WHERE subject_type = ? AND subject_id = ?", subjectType, subjectID)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM allocations WHERE subject_type = ? AND subject_id = ?", subjectType, subjectID)
 	return err
 }
 
 // List returns all allocations
 func (r *AllocationRepository) List(ctx context.Context) ([]repository.Allocation, error) {
 	var rows []AllocationRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM allocations")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM allocations")
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,333 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+// AnalyticsRepository implements repository.AnalyticsRepository for SQLite
+type AnalyticsRepository struct {
+	db *sqlx.DB
+}
+
+// NewAnalyticsRepository creates a new SQLite analytics repository
+func NewAnalyticsRepository(db *sqlx.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+type billCostRow struct {
+	Period     string  `db:"period"`
+	BillType   string  `db:"bill_type"`
+	CustomType string  `db:"custom_type"`
+	Currency   string  `db:"currency"`
+	Grosze     int64   `db:"grosze"`
+	Units      float64 `db:"units"`
+	BillCount  int     `db:"bill_count"`
+}
+
+// BillCosts sums bills by period, type and currency. Bills count in the period they start in;
+// bill periods are calendar days stored as UTC midnight, so they are not moved to another zone.
+func (r *AnalyticsRepository) BillCosts(ctx context.Context, filter repository.AnalyticsFilter) ([]repository.BillCostAggregate, error) {
+	return r.billCosts(ctx, filter, false)
+}
+
+// MeteredCosts sums the amounts and units of bills with recorded units, by period, type and currency
+func (r *AnalyticsRepository) MeteredCosts(ctx context.Context, filter repository.AnalyticsFilter) ([]repository.BillCostAggregate, error) {
+	return r.billCosts(ctx, filter, true)
+}
+
+func (r *AnalyticsRepository) billCosts(ctx context.Context, filter repository.AnalyticsFilter, metered bool) ([]repository.BillCostAggregate, error) {
+	query := `
+		SELECT ` + periodExpr("period_start", filter.GroupBy) + ` AS period,
+			type AS bill_type,
+			COALESCE(custom_type, '') AS custom_type,
+			currency,
+			` + sumGrosze("total_amount_pln") + ` AS grosze,
+			COALESCE(SUM(CAST(total_units AS REAL)), 0) AS units,
+			COUNT(*) AS bill_count
+		FROM bills
+		WHERE 1=1`
+	args := []interface{}{}
+
+	query, args = whereRange(query, args, "period_start", calendarDays(filter))
+	if filter.BillType != "" {
+		query += " AND type = ?"
+		args = append(args, filter.BillType)
+	}
+	if len(filter.Statuses) > 0 {
+		query += " AND status IN (?" + strings.Repeat(", ?", len(filter.Statuses)-1) + ")"
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	if metered {
+		query += " AND CAST(total_units AS REAL) > 0"
+	}
+	query += " GROUP BY period, bill_type, custom_type, currency ORDER BY period, bill_type, custom_type, currency"
+
+	var rows []billCostRow
+	if err := conn(ctx, r.db).SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	result := make([]repository.BillCostAggregate, len(rows))
+	for i, row := range rows {
+		result[i] = repository.BillCostAggregate{
+			Period:     row.Period,
+			BillType:   row.BillType,
+			CustomType: row.CustomType,
+			Currency:   row.Currency,
+			Amount:     utils.Money(row.Grosze),
+			Units:      row.Units,
+			BillCount:  row.BillCount,
+		}
+	}
+	return result, nil
+}
+
+type paymentAggregateRow struct {
+	Period   string `db:"period"`
+	UserID   string `db:"user_id"`
+	Currency string `db:"currency"`
+	Grosze   int64  `db:"grosze"`
+}
+
+// BillPayments sums bill payments by period, payer and the currency of the bill. Payments count in the
+// period they were made in, in the report's time zone. Payments a settle-up moved between flatmates are
+// left out: they reassign a share, they are not spending.
+func (r *AnalyticsRepository) BillPayments(ctx context.Context, filter repository.AnalyticsFilter) ([]repository.PaymentAggregate, error) {
+	periods, args, err := r.periods(ctx, "payments", "paid_at", filter)
+	if err != nil || periods == "" {
+		return nil, err
+	}
+	query := periods + `
+		SELECT pr.period AS period,
+			p.payer_user_id AS user_id,
+			b.currency AS currency,
+			` + sumGrosze("p.amount_pln") + ` AS grosze
+		FROM payments p
+		JOIN bills b ON b.id = p.bill_id
+		JOIN periods pr ON p.paid_at >= pr.period_start AND p.paid_at < pr.period_end
+		WHERE COALESCE(p.method, '') != 'settle_up'`
+
+	query, args = whereRange(query, args, "p.paid_at", filter)
+	if filter.BillType != "" {
+		query += " AND b.type = ?"
+		args = append(args, filter.BillType)
+	}
+	query += " GROUP BY period, user_id, currency ORDER BY period, user_id, currency"
+
+	var rows []paymentAggregateRow
+	if err := conn(ctx, r.db).SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	result := make([]repository.PaymentAggregate, len(rows))
+	for i, row := range rows {
+		result[i] = repository.PaymentAggregate{
+			Period:   row.Period,
+			UserID:   row.UserID,
+			Currency: row.Currency,
+			Amount:   utils.Money(row.Grosze),
+		}
+	}
+	return result, nil
+}
+
+type supplySpendRow struct {
+	Period    string `db:"period"`
+	Category  string `db:"category"`
+	UserID    string `db:"user_id"`
+	Grosze    int64  `db:"grosze"`
+	Purchases int    `db:"purchases"`
+}
+
+// SupplySpend sums the money spent on supplies by period, category and buyer, in the report's time zone.
+// Only restocks recorded in the supply history count; those made before the history kept their cost are not known.
+func (r *AnalyticsRepository) SupplySpend(ctx context.Context, filter repository.AnalyticsFilter) ([]repository.SupplySpendAggregate, error) {
+	periods, args, err := r.periods(ctx, "supply_item_history", "created_at", filter)
+	if err != nil || periods == "" {
+		return nil, err
+	}
+	query := periods + `
+		SELECT pr.period AS period,
+			i.category AS category,
+			h.user_id AS user_id,
+			` + sumGrosze("h.cost_pln") + ` AS grosze,
+			COUNT(*) AS purchases
+		FROM supply_item_history h
+		JOIN supply_items i ON i.id = h.supply_item_id
+		JOIN periods pr ON h.created_at >= pr.period_start AND h.created_at < pr.period_end
+		WHERE h.action IN (?` + strings.Repeat(", ?", len(repository.SupplySpendActions)-1) + `) AND h.cost_pln IS NOT NULL`
+	for _, action := range repository.SupplySpendActions {
+		args = append(args, action)
+	}
+
+	query, args = whereRange(query, args, "h.created_at", filter)
+	query += " GROUP BY period, category, user_id ORDER BY period, category, user_id"
+
+	var rows []supplySpendRow
+	if err := conn(ctx, r.db).SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	result := make([]repository.SupplySpendAggregate, len(rows))
+	for i, row := range rows {
+		result[i] = repository.SupplySpendAggregate{
+			Period:    row.Period,
+			Category:  row.Category,
+			UserID:    row.UserID,
+			Amount:    utils.Money(row.Grosze),
+			Purchases: row.Purchases,
+		}
+	}
+	return result, nil
+}
+
+// periods returns a WITH clause declaring the table periods(period, period_start, period_end) of the
+// periods a report covers, with the UTC instants each one starts and ends at in the report's time zone,
+// and its arguments. Timestamps are stored as RFC3339 in UTC, so joining on the bounds buckets them by
+// local time. Without a full range the periods span the timestamps of the column; the clause is
+// empty when there are none.
+func (r *AnalyticsRepository) periods(ctx context.Context, table, column string, filter repository.AnalyticsFilter) (string, []interface{}, error) {
+	var from, to time.Time
+	if filter.From == nil || filter.To == nil {
+		var bounds struct {
+			First sql.NullString `db:"first"`
+			Last  sql.NullString `db:"last"`
+		}
+		query := "SELECT MIN(" + column + ") AS first, MAX(" + column + ") AS last FROM " + table
+		if err := conn(ctx, r.db).GetContext(ctx, &bounds, query); err != nil {
+			return "", nil, err
+		}
+		if !bounds.First.Valid {
+			return "", nil, nil
+		}
+		first, err := time.Parse(time.RFC3339, bounds.First.String)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid timestamp %q: %w", bounds.First.String, err)
+		}
+		last, err := time.Parse(time.RFC3339, bounds.Last.String)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid timestamp %q: %w", bounds.Last.String, err)
+		}
+		from, to = first, last.Add(time.Second)
+	}
+	if filter.From != nil {
+		from = *filter.From
+	}
+	if filter.To != nil {
+		to = *filter.To
+	}
+
+	var values []string
+	var args []interface{}
+	for start := periodStart(from, filter.GroupBy, location(filter)); start.Before(to); {
+		end := nextPeriod(start, filter.GroupBy)
+		values = append(values, "(?, ?, ?)")
+		args = append(args, periodLabel(start, filter.GroupBy), start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339))
+		start = end
+	}
+	if len(values) == 0 {
+		return "", nil, nil
+	}
+	return "WITH periods(period, period_start, period_end) AS (VALUES " + strings.Join(values, ", ") + ")", args, nil
+}
+
+// periodStart returns the start of the period t falls in, in a time zone
+func periodStart(t time.Time, groupBy string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch groupBy {
+	case "year":
+		return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, loc)
+	case "quarter":
+		return time.Date(t.Year(), (t.Month()-1)/3*3+1, 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	}
+}
+
+// nextPeriod returns the start of the period after the one starting at start
+func nextPeriod(start time.Time, groupBy string) time.Time {
+	switch groupBy {
+	case "year":
+		return start.AddDate(1, 0, 0)
+	case "quarter":
+		return start.AddDate(0, 3, 0)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+// periodLabel names the period starting at start like periodExpr does
+func periodLabel(start time.Time, groupBy string) string {
+	switch groupBy {
+	case "year":
+		return start.Format("2006")
+	case "quarter":
+		return fmt.Sprintf("%d-Q%d", start.Year(), (int(start.Month())+2)/3)
+	default:
+		return start.Format("2006-01")
+	}
+}
+
+// sumGrosze returns the SQL sum of a money column in whole grosze, which is exact, unlike
+// SUM(CAST(amount AS REAL)). Amounts are stored with two decimals, so rounding each one loses nothing.
+func sumGrosze(column string) string {
+	return "SUM(CAST(ROUND(CAST(" + column + " AS REAL) * 100) AS INTEGER))"
+}
+
+// periodExpr returns the SQL expression of the period a UTC timestamp column falls in:
+// YYYY-MM by month, YYYY-Qn by quarter, YYYY by year. Timestamps are stored as RFC3339 in UTC.
+func periodExpr(column, groupBy string) string {
+	switch groupBy {
+	case "year":
+		return "substr(" + column + ", 1, 4)"
+	case "quarter":
+		return "substr(" + column + ", 1, 4) || '-Q' || ((CAST(substr(" + column + ", 6, 2) AS INTEGER) + 2) / 3)"
+	default:
+		return "substr(" + column + ", 1, 7)"
+	}
+}
+
+// location returns the time zone the periods of a report follow
+func location(filter repository.AnalyticsFilter) *time.Location {
+	if filter.Location != nil {
+		return filter.Location
+	}
+	return time.Local
+}
+
+// calendarDays turns the range of a filter into the calendar days it covers in the report's time zone,
+// for columns of calendar days stored as UTC midnight
+func calendarDays(filter repository.AnalyticsFilter) repository.AnalyticsFilter {
+	day := func(t time.Time) *time.Time {
+		local := t.In(location(filter))
+		d := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+	days := filter
+	if filter.From != nil {
+		days.From = day(*filter.From)
+	}
+	if filter.To != nil {
+		days.To = day(*filter.To)
+	}
+	return days
+}
+
+func whereRange(query string, args []interface{}, column string, filter repository.AnalyticsFilter) (string, []interface{}) {
+	if filter.From != nil {
+		query += " AND " + column + " >= ?"
+		args = append(args, filter.From.UTC().Format(time.RFC3339))
+	}
+	if filter.To != nil {
+		query += " AND " + column + " < ?"
+		args = append(args, filter.To.UTC().Format(time.RFC3339))
+	}
+	return query, args
+}
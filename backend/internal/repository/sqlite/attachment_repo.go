@@ -0,0 +1,132 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sainaif/holy-home/internal/models"
+)
+
+// AttachmentRow represents an attachment row in SQLite
+type AttachmentRow struct {
+	ID           string  `db:"id"`
+	ResourceType string  `db:"resource_type"`
+	ResourceID   string  `db:"resource_id"`
+	FileName     string  `db:"file_name"`
+	ContentType  string  `db:"content_type"`
+	SizeBytes    int64   `db:"size_bytes"`
+	SHA256       string  `db:"sha256"`
+	UploadedBy   *string `db:"uploaded_by"`
+	CreatedAt    string  `db:"created_at"`
+}
+
+// AttachmentRepository implements repository.AttachmentRepository for SQLite
+type AttachmentRepository struct {
+	db *sqlx.DB
+}
+
+// NewAttachmentRepository creates a new SQLite attachment repository
+func NewAttachmentRepository(db *sqlx.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+// Create stores attachment metadata
+func (r *AttachmentRepository) Create(ctx context.Context, attachment *models.Attachment) error {
+	if attachment.ID == "" {
+		attachment.ID = uuid.New().String()
+	}
+	if attachment.CreatedAt.IsZero() {
+		attachment.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO attachments (id, resource_type, resource_id, file_name, content_type, size_bytes, sha256, uploaded_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		attachment.ID,
+		attachment.ResourceType,
+		attachment.ResourceID,
+		attachment.FileName,
+		attachment.ContentType,
+		attachment.SizeBytes,
+		attachment.SHA256,
+		attachment.UploadedBy,
+		attachment.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// GetByID retrieves an attachment by ID
+func (r *AttachmentRepository) GetByID(ctx context.Context, id string) (*models.Attachment, error) {
+	var row AttachmentRow
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM attachments WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToAttachment(&row), nil
+}
+
+// Delete deletes attachment metadata
+func (r *AttachmentRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM attachments WHERE id = ?", id)
+	return err
+}
+
+// ListByResource returns the attachments of a bill, supply item or loan, oldest first
+func (r *AttachmentRepository) ListByResource(ctx context.Context, resourceType, resourceID string) ([]models.Attachment, error) {
+	var rows []AttachmentRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM attachments WHERE resource_type = ? AND resource_id = ? ORDER BY created_at", resourceType, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToAttachments(rows), nil
+}
+
+// List returns all attachments
+func (r *AttachmentRepository) List(ctx context.Context) ([]models.Attachment, error) {
+	var rows []AttachmentRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM attachments ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToAttachments(rows), nil
+}
+
+// CountBySHA256 returns how many attachments share a stored file
+func (r *AttachmentRepository) CountBySHA256(ctx context.Context, sha256 string) (int, error) {
+	var count int
+	err := conn(ctx, r.db).GetContext(ctx, &count, "SELECT COUNT(*) FROM attachments WHERE sha256 = ?", sha256)
+	return count, err
+}
+
+func rowToAttachment(row *AttachmentRow) *models.Attachment {
+	attachment := &models.Attachment{
+		ID:           row.ID,
+		ResourceType: row.ResourceType,
+		ResourceID:   row.ResourceID,
+		FileName:     row.FileName,
+		ContentType:  row.ContentType,
+		SizeBytes:    row.SizeBytes,
+		SHA256:       row.SHA256,
+		UploadedBy:   row.UploadedBy,
+	}
+	attachment.CreatedAt, _ = time.Parse(time.RFC3339, row.CreatedAt)
+	return attachment
+}
+
+func rowsToAttachments(rows []AttachmentRow) []models.Attachment {
+	attachments := make([]models.Attachment, len(rows))
+	for i, row := range rows {
+		attachments[i] = *rowToAttachment(&row)
+	}
+	return attachments
+}
@@ -54,7 +54,7 @@ func (r *AuditLogRepository) Create(ctx context.Context, log *models.AuditLog) e
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		id,
 		log.UserID,
 		log.UserEmail,
@@ -74,7 +74,7 @@ func (r *AuditLogRepository) Create(ctx context.Context, log *models.AuditLog) e
 // List returns audit logs with pagination
 func (r *AuditLogRepository) List(ctx context.Context, limit, offset int) ([]models.AuditLog, error) {
 	var rows []AuditLogRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM audit_logs ORDER BY created_at DESC LIMIT ? OFFSET ?", limit, offset)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM audit_logs ORDER BY created_at DESC LIMIT ? OFFSET ?", limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -84,7 +84,7 @@ func (r *AuditLogRepository) List(ctx context.Context, limit, offset int) ([]mod
 // ListByUserID returns audit logs by user
 func (r *AuditLogRepository) ListByUserID(ctx context.Context, userID string, limit int) ([]models.AuditLog, error) {
 	var rows []AuditLogRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM audit_logs WHERE user_id = ? ORDER BY created_at DESC LIMIT ?", userID, limit)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM audit_logs WHERE user_id = ? ORDER BY created_at DESC LIMIT ?", userID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -94,7 +94,7 @@ func (r *AuditLogRepository) ListByUserID(ctx context.Context, userID string, li
 // ListByAction returns audit logs by action
 func (r *AuditLogRepository) ListByAction(ctx context.Context, action string, limit int) ([]models.AuditLog, error) {
 	var rows []AuditLogRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM audit_logs WHERE action = ? ORDER BY created_at DESC LIMIT ?", action, limit)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM audit_logs WHERE action = ? ORDER BY created_at DESC LIMIT ?", action, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -104,7 +104,7 @@ func (r *AuditLogRepository) ListByAction(ctx context.Context, action string, li
 // ListByResourceType returns audit logs by resource type
 func (r *AuditLogRepository) ListByResourceType(ctx context.Context, resourceType string, limit int) ([]models.AuditLog, error) {
 	var rows []AuditLogRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM audit_logs WHERE resource_type = ? ORDER BY created_at DESC LIMIT ?", resourceType, limit)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM audit_logs WHERE resource_type = ? ORDER BY created_at DESC LIMIT ?", resourceType, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -185,7 +185,7 @@ func (r *ApprovalRequestRepository) Create(ctx context.Context, request *models.
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		id,
 		request.UserID,
 		request.UserEmail,
@@ -203,7 +203,7 @@ func (r *ApprovalRequestRepository) Create(ctx context.Context, request *models.
 // GetByID retrieves an approval request by ID
 func (r *ApprovalRequestRepository) GetByID(ctx context.Context, id string) (*models.ApprovalRequest, error) {
 	var row ApprovalRequestRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM approval_requests WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM approval_requests WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -222,14 +222,14 @@ func (r *ApprovalRequestRepository) Update(ctx context.Context, request *models.
 	}
 
 	query := `UPDATE approval_requests SET status = ?, reviewed_by = ?, reviewed_at = ?, review_notes = ? WHERE id = ?`
-	_, err := r.db.ExecContext(ctx, query, request.Status, request.ReviewedBy, reviewedAt, request.ReviewNotes, request.ID)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, request.Status, request.ReviewedBy, reviewedAt, request.ReviewNotes, request.ID)
 	return err
 }
 
 // List returns all approval requests
 func (r *ApprovalRequestRepository) List(ctx context.Context) ([]models.ApprovalRequest, error) {
 	var rows []ApprovalRequestRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM approval_requests ORDER BY created_at DESC")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM approval_requests ORDER BY created_at DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -239,7 +239,7 @@ func (r *ApprovalRequestRepository) List(ctx context.Context) ([]models.Approval
 // ListPending returns pending approval requests
 func (r *ApprovalRequestRepository) ListPending(ctx context.Context) ([]models.ApprovalRequest, error) {
 	var rows []ApprovalRequestRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM approval_requests WHERE status = 'pending' ORDER BY created_at DESC")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM approval_requests WHERE status = 'pending' ORDER BY created_at DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -249,7 +249,7 @@ func (r *ApprovalRequestRepository) ListPending(ctx context.Context) ([]models.A
 // ListByUserID returns approval requests by user
 func (r *ApprovalRequestRepository) ListByUserID(ctx context.Context, userID string) ([]models.ApprovalRequest, error) {
 	var rows []ApprovalRequestRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM approval_requests WHERE user_id = ? ORDER BY created_at DESC", userID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM approval_requests WHERE user_id = ? ORDER BY created_at DESC", userID)
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +42,7 @@ func (r *PasswordResetTokenRepository) Create(ctx context.Context, token *models
 		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		id,
 		token.UserID,
 		token.TokenHash,
@@ -57,7 +57,7 @@ func (r *PasswordResetTokenRepository) Create(ctx context.Context, token *models
 // GetByTokenHash retrieves a token by its hash
 func (r *PasswordResetTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
 	var row PasswordResetTokenRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM password_reset_tokens WHERE token_hash = ?", tokenHash)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM password_reset_tokens WHERE token_hash = ?", tokenHash)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -70,20 +70,20 @@ func (r *PasswordResetTokenRepository) GetByTokenHash(ctx context.Context, token
 // MarkUsed marks a token as used
 func (r *PasswordResetTokenRepository) MarkUsed(ctx context.Context, id string) error {
 	now := time.Now().UTC().Format(time.RFC3339)
-	_, err := r.db.ExecContext(ctx, "UPDATE password_reset_tokens SET used = 1, used_at = ? WHERE id = ?", now, id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE password_reset_tokens SET used = 1, used_at = ? WHERE id = ?", now, id)
 	return err
 }
 
 // DeleteByUserID deletes all tokens for a user
 func (r *PasswordResetTokenRepository) DeleteByUserID(ctx context.Context, userID string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM password_reset_tokens WHERE user_id = ?", userID)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM password_reset_tokens WHERE user_id = ?", userID)
 	return err
 }
 
 // DeleteExpired deletes all expired tokens
 func (r *PasswordResetTokenRepository) DeleteExpired(ctx context.Context) error {
 	now := time.Now().UTC().Format(time.RFC3339)
-	_, err := r.db.ExecContext(ctx, "DELETE FROM password_reset_tokens WHERE expires_at < ?", now)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM password_reset_tokens WHERE expires_at < ?", now)
 	return err
 }
 
@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sainaif/holy-home/internal/models"
+)
+
+// BankAccountRow represents a resident's bank account row in SQLite
+type BankAccountRow struct {
+	ID            string `db:"id"`
+	UserID        string `db:"user_id"`
+	Label         string `db:"label"`
+	HolderName    string `db:"holder_name"`
+	AccountNumber string `db:"account_number"`
+	IsDefault     int    `db:"is_default"`
+	CreatedAt     string `db:"created_at"`
+}
+
+// BankAccountRepository implements repository.BankAccountRepository for SQLite
+type BankAccountRepository struct {
+	db *sqlx.DB
+}
+
+// NewBankAccountRepository creates a new SQLite bank account repository
+func NewBankAccountRepository(db *sqlx.DB) *BankAccountRepository {
+	return &BankAccountRepository{db: db}
+}
+
+// Create stores a bank account
+func (r *BankAccountRepository) Create(ctx context.Context, account *models.BankAccount) error {
+	if account.ID == "" {
+		account.ID = uuid.New().String()
+	}
+	if account.CreatedAt.IsZero() {
+		account.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO user_bank_accounts (id, user_id, label, holder_name, account_number, is_default, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		account.ID,
+		account.UserID,
+		account.Label,
+		account.HolderName,
+		account.AccountNumber,
+		boolToInt(account.IsDefault),
+		account.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// GetByID retrieves a bank account by ID
+func (r *BankAccountRepository) GetByID(ctx context.Context, id string) (*models.BankAccount, error) {
+	var row BankAccountRow
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM user_bank_accounts WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToBankAccount(&row), nil
+}
+
+// Update updates a bank account
+func (r *BankAccountRepository) Update(ctx context.Context, account *models.BankAccount) error {
+	query := `
+		UPDATE user_bank_accounts SET label = ?, holder_name = ?, account_number = ?, is_default = ?
+		WHERE id = ?
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		account.Label,
+		account.HolderName,
+		account.AccountNumber,
+		boolToInt(account.IsDefault),
+		account.ID,
+	)
+	return err
+}
+
+// Delete deletes a bank account
+func (r *BankAccountRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM user_bank_accounts WHERE id = ?", id)
+	return err
+}
+
+// ListByUserID returns a user's bank accounts, default first
+func (r *BankAccountRepository) ListByUserID(ctx context.Context, userID string) ([]models.BankAccount, error) {
+	var rows []BankAccountRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM user_bank_accounts WHERE user_id = ? ORDER BY is_default DESC, created_at", userID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBankAccounts(rows), nil
+}
+
+// List returns all bank accounts
+func (r *BankAccountRepository) List(ctx context.Context) ([]models.BankAccount, error) {
+	var rows []BankAccountRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM user_bank_accounts ORDER BY user_id, is_default DESC, created_at")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBankAccounts(rows), nil
+}
+
+// ClearDefault unmarks the user's default bank account
+func (r *BankAccountRepository) ClearDefault(ctx context.Context, userID string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE user_bank_accounts SET is_default = 0 WHERE user_id = ?", userID)
+	return err
+}
+
+func rowToBankAccount(row *BankAccountRow) *models.BankAccount {
+	account := &models.BankAccount{
+		ID:            row.ID,
+		UserID:        row.UserID,
+		Label:         row.Label,
+		HolderName:    row.HolderName,
+		AccountNumber: row.AccountNumber,
+		IsDefault:     intToBool(row.IsDefault),
+	}
+	account.CreatedAt, _ = time.Parse(time.RFC3339, row.CreatedAt)
+	return account
+}
+
+func rowsToBankAccounts(rows []BankAccountRow) []models.BankAccount {
+	accounts := make([]models.BankAccount, len(rows))
+	for i, row := range rows {
+		accounts[i] = *rowToBankAccount(&row)
+	}
+	return accounts
+}
@@ -0,0 +1,279 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sainaif/holy-home/internal/models"
+)
+
+// BankImportRow represents an uploaded statement row in SQLite
+type BankImportRow struct {
+	ID         string  `db:"id"`
+	FileName   string  `db:"file_name"`
+	Format     string  `db:"format"`
+	ImportedBy *string `db:"imported_by"`
+	LineCount  int     `db:"line_count"`
+	CreatedAt  string  `db:"created_at"`
+}
+
+// BankImportRepository implements repository.BankImportRepository for SQLite
+type BankImportRepository struct {
+	db *sqlx.DB
+}
+
+// NewBankImportRepository creates a new SQLite bank import repository
+func NewBankImportRepository(db *sqlx.DB) *BankImportRepository {
+	return &BankImportRepository{db: db}
+}
+
+// Create stores an uploaded statement
+func (r *BankImportRepository) Create(ctx context.Context, bankImport *models.BankImport) error {
+	if bankImport.ID == "" {
+		bankImport.ID = uuid.New().String()
+	}
+	if bankImport.CreatedAt.IsZero() {
+		bankImport.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO bank_imports (id, file_name, format, imported_by, line_count, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		bankImport.ID,
+		bankImport.FileName,
+		bankImport.Format,
+		bankImport.ImportedBy,
+		bankImport.LineCount,
+		bankImport.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// GetByID retrieves an uploaded statement by ID
+func (r *BankImportRepository) GetByID(ctx context.Context, id string) (*models.BankImport, error) {
+	var row BankImportRow
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM bank_imports WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToBankImport(&row), nil
+}
+
+// List returns all uploaded statements, newest first
+func (r *BankImportRepository) List(ctx context.Context) ([]models.BankImport, error) {
+	var rows []BankImportRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM bank_imports ORDER BY created_at DESC, rowid DESC")
+	if err != nil {
+		return nil, err
+	}
+
+	imports := make([]models.BankImport, len(rows))
+	for i, row := range rows {
+		imports[i] = *rowToBankImport(&row)
+	}
+	return imports, nil
+}
+
+func rowToBankImport(row *BankImportRow) *models.BankImport {
+	bankImport := &models.BankImport{
+		ID:         row.ID,
+		FileName:   row.FileName,
+		Format:     row.Format,
+		ImportedBy: row.ImportedBy,
+		LineCount:  row.LineCount,
+	}
+	bankImport.CreatedAt, _ = time.Parse(time.RFC3339, row.CreatedAt)
+	return bankImport
+}
+
+// BankTransactionRow represents a bank statement transaction row in SQLite
+type BankTransactionRow struct {
+	ID                  string  `db:"id"`
+	ImportID            string  `db:"import_id"`
+	Fingerprint         string  `db:"fingerprint"`
+	BookedAt            string  `db:"booked_at"`
+	Amount              string  `db:"amount"`
+	Currency            string  `db:"currency"`
+	CounterpartyName    *string `db:"counterparty_name"`
+	CounterpartyAccount *string `db:"counterparty_account"`
+	Title               string  `db:"title"`
+	Reference           *string `db:"reference"`
+	Status              string  `db:"status"`
+	MatchType           *string `db:"match_type"`
+	PaymentID           *string `db:"payment_id"`
+	LoanPaymentID       *string `db:"loan_payment_id"`
+	ReviewedBy          *string `db:"reviewed_by"`
+	ReviewedAt          *string `db:"reviewed_at"`
+}
+
+// BankTransactionRepository implements repository.BankTransactionRepository for SQLite
+type BankTransactionRepository struct {
+	db *sqlx.DB
+}
+
+// NewBankTransactionRepository creates a new SQLite bank transaction repository
+func NewBankTransactionRepository(db *sqlx.DB) *BankTransactionRepository {
+	return &BankTransactionRepository{db: db}
+}
+
+// Create stores a transaction read from a statement
+func (r *BankTransactionRepository) Create(ctx context.Context, txn *models.BankTransaction) error {
+	if txn.ID == "" {
+		txn.ID = uuid.New().String()
+	}
+	if txn.Status == "" {
+		txn.Status = "pending"
+	}
+
+	var reviewedAt *string
+	if txn.ReviewedAt != nil {
+		s := txn.ReviewedAt.UTC().Format(time.RFC3339)
+		reviewedAt = &s
+	}
+
+	query := `
+		INSERT INTO bank_transactions (id, import_id, fingerprint, booked_at, amount, currency, counterparty_name,
+			counterparty_account, title, reference, status, match_type, payment_id, loan_payment_id, reviewed_by, reviewed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		txn.ID,
+		txn.ImportID,
+		txn.Fingerprint,
+		txn.BookedAt.UTC().Format(time.RFC3339),
+		txn.Amount,
+		txn.Currency,
+		txn.CounterpartyName,
+		txn.CounterpartyAccount,
+		txn.Title,
+		txn.Reference,
+		txn.Status,
+		txn.MatchType,
+		txn.PaymentID,
+		txn.LoanPaymentID,
+		txn.ReviewedBy,
+		reviewedAt,
+	)
+	return err
+}
+
+// GetByID retrieves a transaction by ID
+func (r *BankTransactionRepository) GetByID(ctx context.Context, id string) (*models.BankTransaction, error) {
+	var row BankTransactionRow
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM bank_transactions WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToBankTransaction(&row), nil
+}
+
+// Update stores the review outcome of a transaction
+func (r *BankTransactionRepository) Update(ctx context.Context, txn *models.BankTransaction) error {
+	var reviewedAt *string
+	if txn.ReviewedAt != nil {
+		s := txn.ReviewedAt.UTC().Format(time.RFC3339)
+		reviewedAt = &s
+	}
+
+	query := `
+		UPDATE bank_transactions SET status = ?, match_type = ?, payment_id = ?, loan_payment_id = ?,
+			reviewed_by = ?, reviewed_at = ?
+		WHERE id = ?
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		txn.Status,
+		txn.MatchType,
+		txn.PaymentID,
+		txn.LoanPaymentID,
+		txn.ReviewedBy,
+		reviewedAt,
+		txn.ID,
+	)
+	return err
+}
+
+// ExistsByFingerprint reports whether a transaction was already imported from an earlier statement
+func (r *BankTransactionRepository) ExistsByFingerprint(ctx context.Context, fingerprint string) (bool, error) {
+	var count int
+	err := conn(ctx, r.db).GetContext(ctx, &count, "SELECT COUNT(*) FROM bank_transactions WHERE fingerprint = ?", fingerprint)
+	return count > 0, err
+}
+
+// ListByImportID returns the transactions read from one statement, in booking order
+func (r *BankTransactionRepository) ListByImportID(ctx context.Context, importID string) ([]models.BankTransaction, error) {
+	var rows []BankTransactionRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM bank_transactions WHERE import_id = ? ORDER BY booked_at, rowid", importID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBankTransactions(rows), nil
+}
+
+// ListByStatus returns transactions with the given review status, in booking order
+func (r *BankTransactionRepository) ListByStatus(ctx context.Context, status string) ([]models.BankTransaction, error) {
+	var rows []BankTransactionRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM bank_transactions WHERE status = ? ORDER BY booked_at, rowid", status)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBankTransactions(rows), nil
+}
+
+// List returns all imported transactions
+func (r *BankTransactionRepository) List(ctx context.Context) ([]models.BankTransaction, error) {
+	var rows []BankTransactionRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM bank_transactions ORDER BY booked_at, rowid")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBankTransactions(rows), nil
+}
+
+func rowToBankTransaction(row *BankTransactionRow) *models.BankTransaction {
+	txn := &models.BankTransaction{
+		ID:                  row.ID,
+		ImportID:            row.ImportID,
+		Fingerprint:         row.Fingerprint,
+		Amount:              row.Amount,
+		Currency:            row.Currency,
+		CounterpartyName:    row.CounterpartyName,
+		CounterpartyAccount: row.CounterpartyAccount,
+		Title:               row.Title,
+		Reference:           row.Reference,
+		Status:              row.Status,
+		MatchType:           row.MatchType,
+		PaymentID:           row.PaymentID,
+		LoanPaymentID:       row.LoanPaymentID,
+		ReviewedBy:          row.ReviewedBy,
+	}
+	txn.BookedAt, _ = time.Parse(time.RFC3339, row.BookedAt)
+	if row.ReviewedAt != nil {
+		t, _ := time.Parse(time.RFC3339, *row.ReviewedAt)
+		txn.ReviewedAt = &t
+	}
+	return txn
+}
+
+func rowsToBankTransactions(rows []BankTransactionRow) []models.BankTransaction {
+	txns := make([]models.BankTransaction, len(rows))
+	for i, row := range rows {
+		txns[i] = *rowToBankTransaction(&row)
+	}
+	return txns
+}
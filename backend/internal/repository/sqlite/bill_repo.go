@@ -3,6 +3,8 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,6 +22,7 @@ type BillRow struct {
 	PeriodEnd           string  `db:"period_end"`
 	PaymentDeadline     *string `db:"payment_deadline"`
 	TotalAmountPLN      string  `db:"total_amount_pln"`
+	Currency            string  `db:"currency"`
 	TotalUnits          *string `db:"total_units"`
 	Notes               *string `db:"notes"`
 	Status              string  `db:"status"`
@@ -27,6 +30,7 @@ type BillRow struct {
 	ReopenReason        *string `db:"reopen_reason"`
 	ReopenedBy          *string `db:"reopened_by"`
 	RecurringTemplateID *string `db:"recurring_template_id"`
+	RecipientAccountID  *string `db:"recipient_account_id"`
 	CreatedAt           string  `db:"created_at"`
 }
 
@@ -69,11 +73,12 @@ func (r *BillRepository) Create(ctx context.Context, bill *models.Bill) error {
 
 	query := `
 		INSERT INTO bills (id, type, custom_type, allocation_type, period_start, period_end, payment_deadline,
-			total_amount_pln, total_units, notes, status, reopened_at, reopen_reason, reopened_by, recurring_template_id, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			total_amount_pln, currency, total_units, notes, status, reopened_at, reopen_reason, reopened_by, recurring_template_id,
+			recipient_account_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		id,
 		bill.Type,
 		bill.CustomType,
@@ -82,6 +87,7 @@ func (r *BillRepository) Create(ctx context.Context, bill *models.Bill) error {
 		bill.PeriodEnd.UTC().Format(time.RFC3339),
 		paymentDeadline,
 		bill.TotalAmountPLN,
+		currencyOrDefault(bill.Currency),
 		totalUnits,
 		bill.Notes,
 		bill.Status,
@@ -89,6 +95,7 @@ func (r *BillRepository) Create(ctx context.Context, bill *models.Bill) error {
 		bill.ReopenReason,
 		bill.ReopenedBy,
 		bill.RecurringTemplateID,
+		bill.RecipientAccountID,
 		now,
 	)
 	return err
@@ -97,7 +104,7 @@ func (r *BillRepository) Create(ctx context.Context, bill *models.Bill) error {
 // GetByID retrieves a bill by ID
 func (r *BillRepository) GetByID(ctx context.Context, id string) (*models.Bill, error) {
 	var row BillRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM bills WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM bills WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -129,12 +136,12 @@ func (r *BillRepository) Update(ctx context.Context, bill *models.Bill) error {
 	query := `
 		UPDATE bills SET
 			type = ?, custom_type = ?, allocation_type = ?, period_start = ?, period_end = ?, payment_deadline = ?,
-			total_amount_pln = ?, total_units = ?, notes = ?, status = ?, reopened_at = ?, reopen_reason = ?,
-			reopened_by = ?, recurring_template_id = ?
+			total_amount_pln = ?, currency = ?, total_units = ?, notes = ?, status = ?, reopened_at = ?, reopen_reason = ?,
+			reopened_by = ?, recurring_template_id = ?, recipient_account_id = ?
 		WHERE id = ?
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		bill.Type,
 		bill.CustomType,
 		bill.AllocationType,
@@ -142,6 +149,7 @@ func (r *BillRepository) Update(ctx context.Context, bill *models.Bill) error {
 		bill.PeriodEnd.UTC().Format(time.RFC3339),
 		paymentDeadline,
 		bill.TotalAmountPLN,
+		currencyOrDefault(bill.Currency),
 		totalUnits,
 		bill.Notes,
 		bill.Status,
@@ -149,6 +157,7 @@ func (r *BillRepository) Update(ctx context.Context, bill *models.Bill) error {
 		bill.ReopenReason,
 		bill.ReopenedBy,
 		bill.RecurringTemplateID,
+		bill.RecipientAccountID,
 		bill.ID,
 	)
 	return err
@@ -156,14 +165,14 @@ func (r *BillRepository) Update(ctx context.Context, bill *models.Bill) error {
 
 // Delete deletes a bill
 func (r *BillRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM bills WHERE id = ?", id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM bills WHERE id = ?", id)
 	return err
 }
 
 // List returns all bills
 func (r *BillRepository) List(ctx context.Context) ([]models.Bill, error) {
 	var rows []BillRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM bills ORDER BY period_start DESC")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM bills ORDER BY period_start DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -173,7 +182,7 @@ func (r *BillRepository) List(ctx context.Context) ([]models.Bill, error) {
 // ListByStatus returns bills by status
 func (r *BillRepository) ListByStatus(ctx context.Context, status string) ([]models.Bill, error) {
 	var rows []BillRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM bills WHERE status = ? ORDER BY period_start DESC", status)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM bills WHERE status = ? ORDER BY period_start DESC", status)
 	if err != nil {
 		return nil, err
 	}
@@ -183,7 +192,7 @@ func (r *BillRepository) ListByStatus(ctx context.Context, status string) ([]mod
 // ListByType returns bills by type
 func (r *BillRepository) ListByType(ctx context.Context, billType string) ([]models.Bill, error) {
 	var rows []BillRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM bills WHERE type = ? ORDER BY period_start DESC", billType)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM bills WHERE type = ? ORDER BY period_start DESC", billType)
 	if err != nil {
 		return nil, err
 	}
@@ -193,7 +202,7 @@ func (r *BillRepository) ListByType(ctx context.Context, billType string) ([]mod
 // ListByPeriod returns bills within a period
 func (r *BillRepository) ListByPeriod(ctx context.Context, start, end time.Time) ([]models.Bill, error) {
 	var rows []BillRow
-	err := r.db.SelectContext(ctx, &rows,
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
 		"SELECT * FROM bills WHERE period_start >= ? AND period_end <= ? ORDER BY period_start DESC",
 		start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339))
 	if err != nil {
@@ -202,10 +211,29 @@ func (r *BillRepository) ListByPeriod(ctx context.Context, start, end time.Time)
 	return rowsToBills(rows), nil
 }
 
+// ListOverlapping returns the bills with one of the statuses whose period overlaps [start, end)
+func (r *BillRepository) ListOverlapping(ctx context.Context, start, end time.Time, statuses []string) ([]models.Bill, error) {
+	query := "SELECT * FROM bills WHERE period_end >= ? AND period_start < ?"
+	args := []interface{}{start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339)}
+	if len(statuses) > 0 {
+		query += " AND status IN (?" + strings.Repeat(", ?", len(statuses)-1) + ")"
+		for _, status := range statuses {
+			args = append(args, status)
+		}
+	}
+	query += " ORDER BY period_start DESC"
+
+	var rows []BillRow
+	if err := conn(ctx, r.db).SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	return rowsToBills(rows), nil
+}
+
 // GetByRecurringTemplateID retrieves a bill by recurring template ID
 func (r *BillRepository) GetByRecurringTemplateID(ctx context.Context, templateID string) (*models.Bill, error) {
 	var row BillRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM bills WHERE recurring_template_id = ?", templateID)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM bills WHERE recurring_template_id = ?", templateID)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -236,7 +264,7 @@ func (r *BillRepository) ListFiltered(ctx context.Context, billType *string, fro
 	query += " ORDER BY period_start DESC"
 
 	var rows []BillRow
-	err := r.db.SelectContext(ctx, &rows, query, args...)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -250,11 +278,13 @@ func rowToBill(row *BillRow) *models.Bill {
 		CustomType:          row.CustomType,
 		AllocationType:      row.AllocationType,
 		TotalAmountPLN:      row.TotalAmountPLN,
+		Currency:            row.Currency,
 		Notes:               row.Notes,
 		Status:              row.Status,
 		ReopenReason:        row.ReopenReason,
 		ReopenedBy:          row.ReopenedBy,
 		RecurringTemplateID: row.RecurringTemplateID,
+		RecipientAccountID:  row.RecipientAccountID,
 	}
 
 	bill.PeriodStart, _ = time.Parse(time.RFC3339, row.PeriodStart)
@@ -284,3 +314,711 @@ func rowsToBills(rows []BillRow) []models.Bill {
 	}
 	return bills
 }
+
+// BillSplitRow represents a bill split rule row in SQLite
+type BillSplitRow struct {
+	ID                  string   `db:"id"`
+	BillID              string   `db:"bill_id"`
+	SubjectType         string   `db:"subject_type"`
+	SubjectID           string   `db:"subject_id"`
+	AllocationType      string   `db:"allocation_type"`
+	Percentage          *float64 `db:"percentage"`
+	FractionNumerator   *int     `db:"fraction_numerator"`
+	FractionDenominator *int     `db:"fraction_denominator"`
+	FixedAmount         *string  `db:"fixed_amount"`
+	Shares              *float64 `db:"shares"`
+}
+
+// BillSplitRepository implements repository.BillSplitRepository for SQLite
+type BillSplitRepository struct {
+	db *sqlx.DB
+}
+
+// NewBillSplitRepository creates a new SQLite bill split repository
+func NewBillSplitRepository(db *sqlx.DB) *BillSplitRepository {
+	return &BillSplitRepository{db: db}
+}
+
+// Create creates a new split rule for a bill
+func (r *BillSplitRepository) Create(ctx context.Context, billID string, split *models.BillSplit) error {
+	if split.ID == "" {
+		split.ID = uuid.New().String()
+	}
+	split.BillID = billID
+
+	query := `
+		INSERT INTO bill_splits (id, bill_id, subject_type, subject_id, allocation_type,
+			percentage, fraction_numerator, fraction_denominator, fixed_amount, shares)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		split.ID,
+		billID,
+		split.SubjectType,
+		split.SubjectID,
+		split.AllocationType,
+		split.Percentage,
+		split.FractionNum,
+		split.FractionDenom,
+		split.FixedAmount,
+		split.Shares,
+	)
+	return err
+}
+
+// GetByBillID returns the split rules of a bill
+func (r *BillSplitRepository) GetByBillID(ctx context.Context, billID string) ([]models.BillSplit, error) {
+	var rows []BillSplitRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM bill_splits WHERE bill_id = ? ORDER BY rowid", billID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBillSplits(rows), nil
+}
+
+// DeleteByBillID deletes all split rules of a bill
+func (r *BillSplitRepository) DeleteByBillID(ctx context.Context, billID string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM bill_splits WHERE bill_id = ?", billID)
+	return err
+}
+
+// List returns all bill split rules
+func (r *BillSplitRepository) List(ctx context.Context) ([]models.BillSplit, error) {
+	var rows []BillSplitRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM bill_splits ORDER BY bill_id, rowid")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBillSplits(rows), nil
+}
+
+func rowsToBillSplits(rows []BillSplitRow) []models.BillSplit {
+	splits := make([]models.BillSplit, len(rows))
+	for i, row := range rows {
+		splits[i] = models.BillSplit{
+			ID:             row.ID,
+			BillID:         row.BillID,
+			SubjectType:    row.SubjectType,
+			SubjectID:      row.SubjectID,
+			AllocationType: row.AllocationType,
+			Percentage:     row.Percentage,
+			FractionNum:    row.FractionNumerator,
+			FractionDenom:  row.FractionDenominator,
+			FixedAmount:    row.FixedAmount,
+			Shares:         row.Shares,
+		}
+	}
+	return splits
+}
+
+// BillItemRow represents a receipt line row in SQLite
+type BillItemRow struct {
+	ID          string `db:"id"`
+	BillID      string `db:"bill_id"`
+	Kind        string `db:"kind"`
+	Description string `db:"description"`
+	Amount      string `db:"amount"`
+	Position    int    `db:"position"`
+}
+
+// BillItemParticipantRow represents a receipt line participant row in SQLite
+type BillItemParticipantRow struct {
+	ItemID      string `db:"item_id"`
+	SubjectType string `db:"subject_type"`
+	SubjectID   string `db:"subject_id"`
+}
+
+// BillItemRepository implements repository.BillItemRepository for SQLite
+type BillItemRepository struct {
+	db *sqlx.DB
+}
+
+// NewBillItemRepository creates a new SQLite bill item repository
+func NewBillItemRepository(db *sqlx.DB) *BillItemRepository {
+	return &BillItemRepository{db: db}
+}
+
+// Create creates a receipt line together with its participants
+func (r *BillItemRepository) Create(ctx context.Context, billID string, item *models.BillItem) error {
+	if item.ID == "" {
+		item.ID = uuid.New().String()
+	}
+	item.BillID = billID
+
+	_, err := conn(ctx, r.db).ExecContext(ctx,
+		`INSERT INTO bill_items (id, bill_id, kind, description, amount, position) VALUES (?, ?, ?, ?, ?, ?)`,
+		item.ID, billID, item.Kind, item.Description, item.Amount, item.Position)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range item.Participants {
+		_, err := conn(ctx, r.db).ExecContext(ctx,
+			`INSERT INTO bill_item_participants (item_id, subject_type, subject_id) VALUES (?, ?, ?)`,
+			item.ID, p.SubjectType, p.SubjectID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetByBillID returns the receipt lines of a bill in order, with their participants
+func (r *BillItemRepository) GetByBillID(ctx context.Context, billID string) ([]models.BillItem, error) {
+	var rows []BillItemRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM bill_items WHERE bill_id = ? ORDER BY position", billID)
+	if err != nil {
+		return nil, err
+	}
+
+	var participants []BillItemParticipantRow
+	err = conn(ctx, r.db).SelectContext(ctx, &participants, `
+		SELECT p.* FROM bill_item_participants p
+		JOIN bill_items i ON i.id = p.item_id
+		WHERE i.bill_id = ?
+		ORDER BY p.rowid
+	`, billID)
+	if err != nil {
+		return nil, err
+	}
+
+	return rowsToBillItems(rows, participants), nil
+}
+
+// DeleteByBillID deletes all receipt lines of a bill
+func (r *BillItemRepository) DeleteByBillID(ctx context.Context, billID string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx,
+		"DELETE FROM bill_item_participants WHERE item_id IN (SELECT id FROM bill_items WHERE bill_id = ?)", billID)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, "DELETE FROM bill_items WHERE bill_id = ?", billID)
+	return err
+}
+
+// List returns all receipt lines with their participants
+func (r *BillItemRepository) List(ctx context.Context) ([]models.BillItem, error) {
+	var rows []BillItemRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM bill_items ORDER BY bill_id, position")
+	if err != nil {
+		return nil, err
+	}
+
+	var participants []BillItemParticipantRow
+	err = conn(ctx, r.db).SelectContext(ctx, &participants, "SELECT * FROM bill_item_participants ORDER BY rowid")
+	if err != nil {
+		return nil, err
+	}
+
+	return rowsToBillItems(rows, participants), nil
+}
+
+func rowsToBillItems(rows []BillItemRow, participants []BillItemParticipantRow) []models.BillItem {
+	byItem := make(map[string][]models.BillItemParticipant)
+	for _, p := range participants {
+		byItem[p.ItemID] = append(byItem[p.ItemID], models.BillItemParticipant{
+			SubjectType: p.SubjectType,
+			SubjectID:   p.SubjectID,
+		})
+	}
+
+	items := make([]models.BillItem, len(rows))
+	for i, row := range rows {
+		items[i] = models.BillItem{
+			ID:           row.ID,
+			BillID:       row.BillID,
+			Kind:         row.Kind,
+			Description:  row.Description,
+			Amount:       row.Amount,
+			Position:     row.Position,
+			Participants: byItem[row.ID],
+		}
+	}
+	return items
+}
+
+// BillTariffZoneRow represents a bill tariff zone row in SQLite
+type BillTariffZoneRow struct {
+	BillID string `db:"bill_id"`
+	Zone   string `db:"zone"`
+	Units  string `db:"units"`
+	Amount string `db:"amount"`
+}
+
+// BillTariffZoneRepository implements repository.BillTariffZoneRepository for SQLite
+type BillTariffZoneRepository struct {
+	db *sqlx.DB
+}
+
+// NewBillTariffZoneRepository creates a new SQLite bill tariff zone repository
+func NewBillTariffZoneRepository(db *sqlx.DB) *BillTariffZoneRepository {
+	return &BillTariffZoneRepository{db: db}
+}
+
+// Create adds a tariff zone to a bill
+func (r *BillTariffZoneRepository) Create(ctx context.Context, billID string, zone *models.BillTariffZone) error {
+	zone.BillID = billID
+
+	_, err := conn(ctx, r.db).ExecContext(ctx,
+		`INSERT INTO bill_tariff_zones (bill_id, zone, units, amount) VALUES (?, ?, ?, ?)`,
+		billID, zone.Zone, zone.Units, zone.Amount)
+	return err
+}
+
+// GetByBillID returns the tariff zones of a bill in the order they were added
+func (r *BillTariffZoneRepository) GetByBillID(ctx context.Context, billID string) ([]models.BillTariffZone, error) {
+	var rows []BillTariffZoneRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM bill_tariff_zones WHERE bill_id = ? ORDER BY rowid", billID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBillTariffZones(rows), nil
+}
+
+// DeleteByBillID deletes all tariff zones of a bill
+func (r *BillTariffZoneRepository) DeleteByBillID(ctx context.Context, billID string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM bill_tariff_zones WHERE bill_id = ?", billID)
+	return err
+}
+
+// List returns all bill tariff zones
+func (r *BillTariffZoneRepository) List(ctx context.Context) ([]models.BillTariffZone, error) {
+	var rows []BillTariffZoneRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM bill_tariff_zones ORDER BY bill_id, rowid")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBillTariffZones(rows), nil
+}
+
+func rowsToBillTariffZones(rows []BillTariffZoneRow) []models.BillTariffZone {
+	zones := make([]models.BillTariffZone, len(rows))
+	for i, row := range rows {
+		zones[i] = models.BillTariffZone{
+			BillID: row.BillID,
+			Zone:   row.Zone,
+			Units:  row.Units,
+			Amount: row.Amount,
+		}
+	}
+	return zones
+}
+
+// BillResidentRepository implements repository.BillResidentRepository for SQLite
+type BillResidentRepository struct {
+	db *sqlx.DB
+}
+
+// NewBillResidentRepository creates a new SQLite bill resident repository
+func NewBillResidentRepository(db *sqlx.DB) *BillResidentRepository {
+	return &BillResidentRepository{db: db}
+}
+
+// Create stores a resident of a posted bill
+func (r *BillResidentRepository) Create(ctx context.Context, resident *models.BillResident) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx,
+		`INSERT INTO bill_residents (bill_id, user_id, subject_type, subject_id, weight, days_present, period_days)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		resident.BillID, resident.UserID, resident.SubjectType, resident.SubjectID,
+		resident.Weight, resident.DaysPresent, resident.PeriodDays)
+	return err
+}
+
+// GetByBillID returns the residents stored for a bill in the order they were added
+func (r *BillResidentRepository) GetByBillID(ctx context.Context, billID string) ([]models.BillResident, error) {
+	var residents []models.BillResident
+	err := conn(ctx, r.db).SelectContext(ctx, &residents, "SELECT * FROM bill_residents WHERE bill_id = ? ORDER BY rowid", billID)
+	if err != nil {
+		return nil, err
+	}
+	return residents, nil
+}
+
+// DeleteByBillID deletes the residents stored for a bill
+func (r *BillResidentRepository) DeleteByBillID(ctx context.Context, billID string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM bill_residents WHERE bill_id = ?", billID)
+	return err
+}
+
+// List returns the residents stored for all bills
+func (r *BillResidentRepository) List(ctx context.Context) ([]models.BillResident, error) {
+	var residents []models.BillResident
+	err := conn(ctx, r.db).SelectContext(ctx, &residents, "SELECT * FROM bill_residents ORDER BY bill_id, rowid")
+	if err != nil {
+		return nil, err
+	}
+	return residents, nil
+}
+
+// BillTransitionRow represents a bill transition row in SQLite
+type BillTransitionRow struct {
+	ID         string  `db:"id"`
+	BillID     string  `db:"bill_id"`
+	Transition string  `db:"transition"`
+	FromStatus string  `db:"from_status"`
+	ToStatus   string  `db:"to_status"`
+	ActorID    *string `db:"actor_id"`
+	Reason     *string `db:"reason"`
+	Forced     int     `db:"forced"`
+	CreatedAt  string  `db:"created_at"`
+}
+
+// BillTransitionRepository implements repository.BillTransitionRepository for SQLite
+type BillTransitionRepository struct {
+	db *sqlx.DB
+}
+
+// NewBillTransitionRepository creates a new SQLite bill transition repository
+func NewBillTransitionRepository(db *sqlx.DB) *BillTransitionRepository {
+	return &BillTransitionRepository{db: db}
+}
+
+// Create records a change of a bill's status
+func (r *BillTransitionRepository) Create(ctx context.Context, transition *models.BillTransition) error {
+	if transition.ID == "" {
+		transition.ID = uuid.New().String()
+	}
+	if transition.CreatedAt.IsZero() {
+		transition.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO bill_transitions (id, bill_id, transition, from_status, to_status, actor_id, reason, forced, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		transition.ID,
+		transition.BillID,
+		transition.Transition,
+		transition.FromStatus,
+		transition.ToStatus,
+		transition.ActorID,
+		transition.Reason,
+		boolToInt(transition.Forced),
+		transition.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// ListByBillID returns the status history of a bill, oldest first
+func (r *BillTransitionRepository) ListByBillID(ctx context.Context, billID string) ([]models.BillTransition, error) {
+	var rows []BillTransitionRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM bill_transitions WHERE bill_id = ? ORDER BY created_at, rowid", billID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBillTransitions(rows), nil
+}
+
+// List returns all bill transitions
+func (r *BillTransitionRepository) List(ctx context.Context) ([]models.BillTransition, error) {
+	var rows []BillTransitionRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM bill_transitions ORDER BY bill_id, created_at, rowid")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBillTransitions(rows), nil
+}
+
+func rowsToBillTransitions(rows []BillTransitionRow) []models.BillTransition {
+	transitions := make([]models.BillTransition, len(rows))
+	for i, row := range rows {
+		transitions[i] = models.BillTransition{
+			ID:         row.ID,
+			BillID:     row.BillID,
+			Transition: row.Transition,
+			FromStatus: row.FromStatus,
+			ToStatus:   row.ToStatus,
+			ActorID:    row.ActorID,
+			Reason:     row.Reason,
+			Forced:     intToBool(row.Forced),
+		}
+		transitions[i].CreatedAt, _ = time.Parse(time.RFC3339, row.CreatedAt)
+	}
+	return transitions
+}
+
+// BillDisputeRow represents a bill dispute row in SQLite
+type BillDisputeRow struct {
+	ID              string  `db:"id"`
+	BillID          string  `db:"bill_id"`
+	SubjectType     string  `db:"subject_type"`
+	SubjectID       string  `db:"subject_id"`
+	OpenedBy        string  `db:"opened_by"`
+	Reason          string  `db:"reason"`
+	AllocatedAmount string  `db:"allocated_amount"`
+	ProposedAmount  string  `db:"proposed_amount"`
+	Status          string  `db:"status"`
+	ResolvedAmount  *string `db:"resolved_amount"`
+	ResolutionNote  *string `db:"resolution_note"`
+	ResolvedBy      *string `db:"resolved_by"`
+	ResolvedAt      *string `db:"resolved_at"`
+	CreatedAt       string  `db:"created_at"`
+}
+
+// BillDisputeRepository implements repository.BillDisputeRepository for SQLite
+type BillDisputeRepository struct {
+	db *sqlx.DB
+}
+
+// NewBillDisputeRepository creates a new SQLite bill dispute repository
+func NewBillDisputeRepository(db *sqlx.DB) *BillDisputeRepository {
+	return &BillDisputeRepository{db: db}
+}
+
+// Create opens a new bill dispute
+func (r *BillDisputeRepository) Create(ctx context.Context, dispute *models.BillDispute) error {
+	if dispute.ID == "" {
+		dispute.ID = uuid.New().String()
+	}
+	if dispute.CreatedAt.IsZero() {
+		dispute.CreatedAt = time.Now()
+	}
+
+	var resolvedAt *string
+	if dispute.ResolvedAt != nil {
+		ra := dispute.ResolvedAt.UTC().Format(time.RFC3339)
+		resolvedAt = &ra
+	}
+
+	query := `
+		INSERT INTO bill_disputes (id, bill_id, subject_type, subject_id, opened_by, reason, allocated_amount, proposed_amount,
+			status, resolved_amount, resolution_note, resolved_by, resolved_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		dispute.ID,
+		dispute.BillID,
+		dispute.SubjectType,
+		dispute.SubjectID,
+		dispute.OpenedBy,
+		dispute.Reason,
+		dispute.AllocatedAmount,
+		dispute.ProposedAmount,
+		dispute.Status,
+		dispute.ResolvedAmount,
+		dispute.ResolutionNote,
+		dispute.ResolvedBy,
+		resolvedAt,
+		dispute.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// GetByID retrieves a bill dispute by ID
+func (r *BillDisputeRepository) GetByID(ctx context.Context, id string) (*models.BillDispute, error) {
+	var row BillDisputeRow
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM bill_disputes WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToBillDispute(&row), nil
+}
+
+// Update records the resolution of a bill dispute
+func (r *BillDisputeRepository) Update(ctx context.Context, dispute *models.BillDispute) error {
+	var resolvedAt *string
+	if dispute.ResolvedAt != nil {
+		ra := dispute.ResolvedAt.UTC().Format(time.RFC3339)
+		resolvedAt = &ra
+	}
+
+	query := `
+		UPDATE bill_disputes SET
+			status = ?, resolved_amount = ?, resolution_note = ?, resolved_by = ?, resolved_at = ?
+		WHERE id = ?
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		dispute.Status,
+		dispute.ResolvedAmount,
+		dispute.ResolutionNote,
+		dispute.ResolvedBy,
+		resolvedAt,
+		dispute.ID,
+	)
+	return err
+}
+
+// ListByBillID returns the disputes of a bill, oldest first
+func (r *BillDisputeRepository) ListByBillID(ctx context.Context, billID string) ([]models.BillDispute, error) {
+	var rows []BillDisputeRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM bill_disputes WHERE bill_id = ? ORDER BY created_at, rowid", billID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBillDisputes(rows), nil
+}
+
+// ListByStatus returns the disputes in a status, oldest first
+func (r *BillDisputeRepository) ListByStatus(ctx context.Context, status string) ([]models.BillDispute, error) {
+	var rows []BillDisputeRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM bill_disputes WHERE status = ? ORDER BY created_at, rowid", status)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBillDisputes(rows), nil
+}
+
+// List returns all bill disputes
+func (r *BillDisputeRepository) List(ctx context.Context) ([]models.BillDispute, error) {
+	var rows []BillDisputeRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM bill_disputes ORDER BY created_at, rowid")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBillDisputes(rows), nil
+}
+
+func rowToBillDispute(row *BillDisputeRow) *models.BillDispute {
+	dispute := &models.BillDispute{
+		ID:              row.ID,
+		BillID:          row.BillID,
+		SubjectType:     row.SubjectType,
+		SubjectID:       row.SubjectID,
+		OpenedBy:        row.OpenedBy,
+		Reason:          row.Reason,
+		AllocatedAmount: row.AllocatedAmount,
+		ProposedAmount:  row.ProposedAmount,
+		Status:          row.Status,
+		ResolvedAmount:  row.ResolvedAmount,
+		ResolutionNote:  row.ResolutionNote,
+		ResolvedBy:      row.ResolvedBy,
+	}
+	dispute.CreatedAt, _ = time.Parse(time.RFC3339, row.CreatedAt)
+	if row.ResolvedAt != nil {
+		resolvedAt, _ := time.Parse(time.RFC3339, *row.ResolvedAt)
+		dispute.ResolvedAt = &resolvedAt
+	}
+	return dispute
+}
+
+func rowsToBillDisputes(rows []BillDisputeRow) []models.BillDispute {
+	disputes := make([]models.BillDispute, len(rows))
+	for i, row := range rows {
+		disputes[i] = *rowToBillDispute(&row)
+	}
+	return disputes
+}
+
+// BillVersionRow represents a bill version row in SQLite
+type BillVersionRow struct {
+	ID          string  `db:"id"`
+	BillID      string  `db:"bill_id"`
+	Version     int     `db:"version"`
+	Snapshot    string  `db:"snapshot"`
+	Allocations string  `db:"allocations"`
+	Reason      *string `db:"reason"`
+	CreatedBy   *string `db:"created_by"`
+	CreatedAt   string  `db:"created_at"`
+}
+
+// BillVersionRepository implements repository.BillVersionRepository for SQLite
+type BillVersionRepository struct {
+	db *sqlx.DB
+}
+
+// NewBillVersionRepository creates a new SQLite bill version repository
+func NewBillVersionRepository(db *sqlx.DB) *BillVersionRepository {
+	return &BillVersionRepository{db: db}
+}
+
+// Create stores a posted version of a bill
+func (r *BillVersionRepository) Create(ctx context.Context, version *models.BillVersion) error {
+	if version.ID == "" {
+		version.ID = uuid.New().String()
+	}
+	if version.CreatedAt.IsZero() {
+		version.CreatedAt = time.Now()
+	}
+
+	snapshotJSON, err := json.Marshal(version.Bill)
+	if err != nil {
+		return err
+	}
+	allocationsJSON, err := json.Marshal(version.Allocations)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO bill_versions (id, bill_id, version, snapshot, allocations, reason, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = conn(ctx, r.db).ExecContext(ctx, query,
+		version.ID,
+		version.BillID,
+		version.Version,
+		string(snapshotJSON),
+		string(allocationsJSON),
+		version.Reason,
+		version.CreatedBy,
+		version.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// GetByVersion retrieves one version of a bill
+func (r *BillVersionRepository) GetByVersion(ctx context.Context, billID string, version int) (*models.BillVersion, error) {
+	var row BillVersionRow
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM bill_versions WHERE bill_id = ? AND version = ?", billID, version)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToBillVersion(&row), nil
+}
+
+// ListByBillID returns the versions of a bill, oldest first
+func (r *BillVersionRepository) ListByBillID(ctx context.Context, billID string) ([]models.BillVersion, error) {
+	var rows []BillVersionRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM bill_versions WHERE bill_id = ? ORDER BY version", billID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBillVersions(rows), nil
+}
+
+// List returns all bill versions
+func (r *BillVersionRepository) List(ctx context.Context) ([]models.BillVersion, error) {
+	var rows []BillVersionRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM bill_versions ORDER BY bill_id, version")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBillVersions(rows), nil
+}
+
+func rowToBillVersion(row *BillVersionRow) *models.BillVersion {
+	version := &models.BillVersion{
+		ID:        row.ID,
+		BillID:    row.BillID,
+		Version:   row.Version,
+		Reason:    row.Reason,
+		CreatedBy: row.CreatedBy,
+	}
+	json.Unmarshal([]byte(row.Snapshot), &version.Bill)
+	json.Unmarshal([]byte(row.Allocations), &version.Allocations)
+	version.CreatedAt, _ = time.Parse(time.RFC3339, row.CreatedAt)
+	return version
+}
+
+func rowsToBillVersions(rows []BillVersionRow) []models.BillVersion {
+	versions := make([]models.BillVersion, len(rows))
+	for i, row := range rows {
+		versions[i] = *rowToBillVersion(&row)
+	}
+	return versions
+}
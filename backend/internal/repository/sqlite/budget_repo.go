@@ -0,0 +1,213 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sainaif/holy-home/internal/models"
+)
+
+// BudgetRow represents a budget row in SQLite
+type BudgetRow struct {
+	ID              string `db:"id"`
+	Kind            string `db:"kind"`
+	Category        string `db:"category"`
+	CustomType      string `db:"custom_type"`
+	MonthlyAmount   string `db:"monthly_amount"`
+	AlertThresholds string `db:"alert_thresholds"` // JSON array
+	CreatedAt       string `db:"created_at"`
+	UpdatedAt       string `db:"updated_at"`
+}
+
+// BudgetRepository implements repository.BudgetRepository for SQLite
+type BudgetRepository struct {
+	db *sqlx.DB
+}
+
+// NewBudgetRepository creates a new SQLite budget repository
+func NewBudgetRepository(db *sqlx.DB) *BudgetRepository {
+	return &BudgetRepository{db: db}
+}
+
+// Upsert creates a budget or replaces the budget already set for the same category
+func (r *BudgetRepository) Upsert(ctx context.Context, budget *models.Budget) error {
+	if budget.ID == "" {
+		budget.ID = uuid.New().String()
+	}
+	now := time.Now()
+	if budget.CreatedAt.IsZero() {
+		budget.CreatedAt = now
+	}
+	budget.UpdatedAt = now
+
+	thresholdsJSON, err := json.Marshal(budget.AlertThresholds)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO budgets (id, kind, category, custom_type, monthly_amount, alert_thresholds, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(kind, category, custom_type) DO UPDATE SET
+			monthly_amount = excluded.monthly_amount,
+			alert_thresholds = excluded.alert_thresholds,
+			updated_at = excluded.updated_at
+	`
+
+	_, err = conn(ctx, r.db).ExecContext(ctx, query,
+		budget.ID,
+		budget.Kind,
+		budget.Category,
+		budget.CustomType,
+		budget.MonthlyAmount,
+		string(thresholdsJSON),
+		budget.CreatedAt.UTC().Format(time.RFC3339),
+		budget.UpdatedAt.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Pick up the existing ID and creation time when the budget replaced an earlier one
+	var row BudgetRow
+	err = conn(ctx, r.db).GetContext(ctx, &row,
+		"SELECT * FROM budgets WHERE kind = ? AND category = ? AND custom_type = ?",
+		budget.Kind, budget.Category, budget.CustomType)
+	if err != nil {
+		return err
+	}
+	*budget = *rowToBudget(&row)
+	return nil
+}
+
+// GetByID retrieves a budget by ID
+func (r *BudgetRepository) GetByID(ctx context.Context, id string) (*models.Budget, error) {
+	var row BudgetRow
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM budgets WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToBudget(&row), nil
+}
+
+// Delete deletes a budget and its alerts
+func (r *BudgetRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM budgets WHERE id = ?", id)
+	return err
+}
+
+// List returns all budgets, bill types first
+func (r *BudgetRepository) List(ctx context.Context) ([]models.Budget, error) {
+	var rows []BudgetRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM budgets ORDER BY kind, category, custom_type")
+	if err != nil {
+		return nil, err
+	}
+	budgets := make([]models.Budget, len(rows))
+	for i, row := range rows {
+		budgets[i] = *rowToBudget(&row)
+	}
+	return budgets, nil
+}
+
+func rowToBudget(row *BudgetRow) *models.Budget {
+	budget := &models.Budget{
+		ID:            row.ID,
+		Kind:          row.Kind,
+		Category:      row.Category,
+		CustomType:    row.CustomType,
+		MonthlyAmount: row.MonthlyAmount,
+	}
+	json.Unmarshal([]byte(row.AlertThresholds), &budget.AlertThresholds)
+	budget.CreatedAt, _ = time.Parse(time.RFC3339, row.CreatedAt)
+	budget.UpdatedAt, _ = time.Parse(time.RFC3339, row.UpdatedAt)
+	return budget
+}
+
+// BudgetAlertRow represents a budget alert row in SQLite
+type BudgetAlertRow struct {
+	ID        string `db:"id"`
+	BudgetID  string `db:"budget_id"`
+	Month     string `db:"month"`
+	Threshold int    `db:"threshold"`
+	Actual    string `db:"actual"`
+	SentAt    string `db:"sent_at"`
+}
+
+// BudgetAlertRepository implements repository.BudgetAlertRepository for SQLite
+type BudgetAlertRepository struct {
+	db *sqlx.DB
+}
+
+// NewBudgetAlertRepository creates a new SQLite budget alert repository
+func NewBudgetAlertRepository(db *sqlx.DB) *BudgetAlertRepository {
+	return &BudgetAlertRepository{db: db}
+}
+
+// Create records a sent budget alert
+func (r *BudgetAlertRepository) Create(ctx context.Context, alert *models.BudgetAlert) error {
+	if alert.ID == "" {
+		alert.ID = uuid.New().String()
+	}
+	if alert.SentAt.IsZero() {
+		alert.SentAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO budget_alerts (id, budget_id, month, threshold, actual, sent_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		alert.ID,
+		alert.BudgetID,
+		alert.Month,
+		alert.Threshold,
+		alert.Actual,
+		alert.SentAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// ListByMonth returns the alerts sent for a month (YYYY-MM)
+func (r *BudgetAlertRepository) ListByMonth(ctx context.Context, month string) ([]models.BudgetAlert, error) {
+	var rows []BudgetAlertRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM budget_alerts WHERE month = ? ORDER BY sent_at", month)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBudgetAlerts(rows), nil
+}
+
+// List returns all sent budget alerts
+func (r *BudgetAlertRepository) List(ctx context.Context) ([]models.BudgetAlert, error) {
+	var rows []BudgetAlertRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM budget_alerts ORDER BY month, sent_at")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToBudgetAlerts(rows), nil
+}
+
+func rowsToBudgetAlerts(rows []BudgetAlertRow) []models.BudgetAlert {
+	alerts := make([]models.BudgetAlert, len(rows))
+	for i, row := range rows {
+		alerts[i] = models.BudgetAlert{
+			ID:        row.ID,
+			BudgetID:  row.BudgetID,
+			Month:     row.Month,
+			Threshold: row.Threshold,
+			Actual:    row.Actual,
+		}
+		alerts[i].SentAt, _ = time.Parse(time.RFC3339, row.SentAt)
+	}
+	return alerts
+}
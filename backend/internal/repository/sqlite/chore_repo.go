@@ -45,7 +45,7 @@ func (r *ChoreRepository) Create(ctx context.Context, chore *models.Chore) error
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		chore.ID,
 		chore.Name,
 		chore.Description,
@@ -65,7 +65,7 @@ func (r *ChoreRepository) Create(ctx context.Context, chore *models.Chore) error
 // GetByID retrieves a chore by ID
 func (r *ChoreRepository) GetByID(ctx context.Context, id string) (*models.Chore, error) {
 	var row ChoreRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM chores WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM chores WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -84,7 +84,7 @@ func (r *ChoreRepository) Update(ctx context.Context, chore *models.Chore) error
 		WHERE id = ?
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		chore.Name,
 		chore.Description,
 		chore.Frequency,
@@ -102,14 +102,14 @@ func (r *ChoreRepository) Update(ctx context.Context, chore *models.Chore) error
 
 // Delete deletes a chore
 func (r *ChoreRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM chores WHERE id = ?", id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM chores WHERE id = ?", id)
 	return err
 }
 
 // List returns all chores
 func (r *ChoreRepository) List(ctx context.Context) ([]models.Chore, error) {
 	var rows []ChoreRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM chores ORDER BY name")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM chores ORDER BY name")
 	if err != nil {
 		return nil, err
 	}
@@ -119,7 +119,7 @@ func (r *ChoreRepository) List(ctx context.Context) ([]models.Chore, error) {
 // ListActive returns all active chores
 func (r *ChoreRepository) ListActive(ctx context.Context) ([]models.Chore, error) {
 	var rows []ChoreRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM chores WHERE is_active = 1 ORDER BY name")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM chores WHERE is_active = 1 ORDER BY name")
 	if err != nil {
 		return nil, err
 	}
@@ -187,7 +187,7 @@ func (r *ChoreAssignmentRepository) Create(ctx context.Context, assignment *mode
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		assignment.ID,
 		assignment.ChoreID,
 		assignment.AssigneeUserID,
@@ -203,7 +203,7 @@ func (r *ChoreAssignmentRepository) Create(ctx context.Context, assignment *mode
 // GetByID retrieves a chore assignment by ID
 func (r *ChoreAssignmentRepository) GetByID(ctx context.Context, id string) (*models.ChoreAssignment, error) {
 	var row ChoreAssignmentRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM chore_assignments WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM chore_assignments WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -227,7 +227,7 @@ func (r *ChoreAssignmentRepository) Update(ctx context.Context, assignment *mode
 		WHERE id = ?
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		assignment.ChoreID,
 		assignment.AssigneeUserID,
 		assignment.DueDate.UTC().Format(time.RFC3339),
@@ -242,14 +242,14 @@ func (r *ChoreAssignmentRepository) Update(ctx context.Context, assignment *mode
 
 // Delete deletes a chore assignment
 func (r *ChoreAssignmentRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM chore_assignments WHERE id = ?", id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM chore_assignments WHERE id = ?", id)
 	return err
 }
 
 // ListByChoreID returns assignments for a chore
 func (r *ChoreAssignmentRepository) ListByChoreID(ctx context.Context, choreID string) ([]models.ChoreAssignment, error) {
 	var rows []ChoreAssignmentRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM chore_assignments WHERE chore_id = ? ORDER BY due_date DESC", choreID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM chore_assignments WHERE chore_id = ? ORDER BY due_date DESC", choreID)
 	if err != nil {
 		return nil, err
 	}
@@ -259,7 +259,7 @@ func (r *ChoreAssignmentRepository) ListByChoreID(ctx context.Context, choreID s
 // ListByAssigneeID returns assignments for an assignee
 func (r *ChoreAssignmentRepository) ListByAssigneeID(ctx context.Context, assigneeID string) ([]models.ChoreAssignment, error) {
 	var rows []ChoreAssignmentRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM chore_assignments WHERE assignee_user_id = ? ORDER BY due_date DESC", assigneeID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM chore_assignments WHERE assignee_user_id = ? ORDER BY due_date DESC", assigneeID)
 	if err != nil {
 		return nil, err
 	}
@@ -269,7 +269,7 @@ func (r *ChoreAssignmentRepository) ListByAssigneeID(ctx context.Context, assign
 // ListByStatus returns assignments by status
 func (r *ChoreAssignmentRepository) ListByStatus(ctx context.Context, status string) ([]models.ChoreAssignment, error) {
 	var rows []ChoreAssignmentRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM chore_assignments WHERE status = ? ORDER BY due_date", status)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM chore_assignments WHERE status = ? ORDER BY due_date", status)
 	if err != nil {
 		return nil, err
 	}
@@ -279,7 +279,7 @@ func (r *ChoreAssignmentRepository) ListByStatus(ctx context.Context, status str
 // ListPendingByAssignee returns pending assignments for an assignee
 func (r *ChoreAssignmentRepository) ListPendingByAssignee(ctx context.Context, assigneeID string) ([]models.ChoreAssignment, error) {
 	var rows []ChoreAssignmentRow
-	err := r.db.SelectContext(ctx, &rows,
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
 		"SELECT * FROM chore_assignments WHERE assignee_user_id = ? AND status IN ('pending', 'in_progress') ORDER BY due_date",
 		assigneeID)
 	if err != nil {
@@ -291,7 +291,7 @@ func (r *ChoreAssignmentRepository) ListPendingByAssignee(ctx context.Context, a
 // GetLatestByChoreID returns the latest assignment for a chore
 func (r *ChoreAssignmentRepository) GetLatestByChoreID(ctx context.Context, choreID string) (*models.ChoreAssignment, error) {
 	var row ChoreAssignmentRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM chore_assignments WHERE chore_id = ? ORDER BY due_date DESC LIMIT 1", choreID)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM chore_assignments WHERE chore_id = ? ORDER BY due_date DESC LIMIT 1", choreID)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -304,7 +304,7 @@ func (r *ChoreAssignmentRepository) GetLatestByChoreID(ctx context.Context, chor
 // List returns all chore assignments
 func (r *ChoreAssignmentRepository) List(ctx context.Context) ([]models.ChoreAssignment, error) {
 	var rows []ChoreAssignmentRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM chore_assignments ORDER BY due_date DESC")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM chore_assignments ORDER BY due_date DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -328,7 +328,7 @@ func (r *ChoreAssignmentRepository) ListFiltered(ctx context.Context, assigneeID
 	query += " ORDER BY due_date DESC"
 
 	var rows []ChoreAssignmentRow
-	err := r.db.SelectContext(ctx, &rows, query, args...)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -384,7 +384,7 @@ func NewChoreSettingsRepository(db *sqlx.DB) *ChoreSettingsRepository {
 // Get retrieves the chore settings singleton
 func (r *ChoreSettingsRepository) Get(ctx context.Context) (*models.ChoreSettings, error) {
 	var row ChoreSettingsRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM chore_settings WHERE id = 'singleton'")
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM chore_settings WHERE id = 'singleton'")
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -411,7 +411,7 @@ func (r *ChoreSettingsRepository) Upsert(ctx context.Context, settings *models.C
 			updated_at = excluded.updated_at
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		settings.DefaultAssignmentMode,
 		boolToInt(settings.GlobalNotifications),
 		settings.DefaultReminderHours,
@@ -0,0 +1,129 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sainaif/holy-home/internal/models"
+)
+
+// ConsumptionAnomalyRow represents a consumption anomaly row in SQLite
+type ConsumptionAnomalyRow struct {
+	ID                 string  `db:"id"`
+	ConsumptionID      *string `db:"consumption_id"`
+	MeterID            *string `db:"meter_id"`
+	SubjectType        string  `db:"subject_type"`
+	SubjectID          string  `db:"subject_id"`
+	Kind               string  `db:"kind"`
+	DailyUnits         *string `db:"daily_units"`
+	ExpectedDailyUnits *string `db:"expected_daily_units"`
+	Message            string  `db:"message"`
+	DetectedAt         string  `db:"detected_at"`
+}
+
+// ConsumptionAnomalyRepository implements repository.ConsumptionAnomalyRepository for SQLite
+type ConsumptionAnomalyRepository struct {
+	db *sqlx.DB
+}
+
+// NewConsumptionAnomalyRepository creates a new SQLite consumption anomaly repository
+func NewConsumptionAnomalyRepository(db *sqlx.DB) *ConsumptionAnomalyRepository {
+	return &ConsumptionAnomalyRepository{db: db}
+}
+
+// Create creates a new consumption anomaly
+func (r *ConsumptionAnomalyRepository) Create(ctx context.Context, anomaly *models.ConsumptionAnomaly) error {
+	if anomaly.ID == "" {
+		anomaly.ID = uuid.New().String()
+	}
+	if anomaly.DetectedAt.IsZero() {
+		anomaly.DetectedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO consumption_anomalies (id, consumption_id, meter_id, subject_type, subject_id, kind,
+			daily_units, expected_daily_units, message, detected_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		anomaly.ID,
+		anomaly.ConsumptionID,
+		anomaly.MeterID,
+		anomaly.SubjectType,
+		anomaly.SubjectID,
+		anomaly.Kind,
+		anomaly.DailyUnits,
+		anomaly.ExpectedDailyUnits,
+		anomaly.Message,
+		anomaly.DetectedAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// ListByConsumptionID returns the flags raised for a reading
+func (r *ConsumptionAnomalyRepository) ListByConsumptionID(ctx context.Context, consumptionID string) ([]models.ConsumptionAnomaly, error) {
+	var rows []ConsumptionAnomalyRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM consumption_anomalies WHERE consumption_id = ? ORDER BY detected_at", consumptionID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToConsumptionAnomalies(rows), nil
+}
+
+// GetLatestByMeterID returns the most recent flag of a kind raised for a meter
+func (r *ConsumptionAnomalyRepository) GetLatestByMeterID(ctx context.Context, meterID, kind string) (*models.ConsumptionAnomaly, error) {
+	var row ConsumptionAnomalyRow
+	query := `
+		SELECT * FROM consumption_anomalies
+		WHERE meter_id = ? AND kind = ?
+		ORDER BY detected_at DESC
+		LIMIT 1
+	`
+	err := conn(ctx, r.db).GetContext(ctx, &row, query, meterID, kind)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToConsumptionAnomaly(&row), nil
+}
+
+// List returns all consumption anomalies, newest first
+func (r *ConsumptionAnomalyRepository) List(ctx context.Context) ([]models.ConsumptionAnomaly, error) {
+	var rows []ConsumptionAnomalyRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM consumption_anomalies ORDER BY detected_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToConsumptionAnomalies(rows), nil
+}
+
+func rowToConsumptionAnomaly(row *ConsumptionAnomalyRow) *models.ConsumptionAnomaly {
+	anomaly := &models.ConsumptionAnomaly{
+		ID:                 row.ID,
+		ConsumptionID:      row.ConsumptionID,
+		MeterID:            row.MeterID,
+		SubjectType:        row.SubjectType,
+		SubjectID:          row.SubjectID,
+		Kind:               row.Kind,
+		DailyUnits:         row.DailyUnits,
+		ExpectedDailyUnits: row.ExpectedDailyUnits,
+		Message:            row.Message,
+	}
+	anomaly.DetectedAt, _ = time.Parse(time.RFC3339, row.DetectedAt)
+	return anomaly
+}
+
+func rowsToConsumptionAnomalies(rows []ConsumptionAnomalyRow) []models.ConsumptionAnomaly {
+	anomalies := make([]models.ConsumptionAnomaly, len(rows))
+	for i, row := range rows {
+		anomalies[i] = *rowToConsumptionAnomaly(&row)
+	}
+	return anomalies
+}
@@ -18,8 +18,11 @@ type ConsumptionRow struct {
 	SubjectID   string  `db:"subject_id"`
 	Units       string  `db:"units"`
 	MeterValue  *string `db:"meter_value"`
+	MeterID     *string `db:"meter_id"`
 	RecordedAt  string  `db:"recorded_at"`
 	Source      string  `db:"source"`
+	// Set once the scheduler has checked the reading for anomalies
+	AnomalyCheckedAt *string `db:"anomaly_checked_at"`
 }
 
 // ConsumptionRepository implements repository.ConsumptionRepository for SQLite
@@ -34,20 +37,23 @@ func NewConsumptionRepository(db *sqlx.DB) *ConsumptionRepository {
 
 // Create creates a new consumption
 func (r *ConsumptionRepository) Create(ctx context.Context, consumption *models.Consumption) error {
-	id := uuid.New().String()
+	if consumption.ID == "" {
+		consumption.ID = uuid.New().String()
+	}
 
 	query := `
-		INSERT INTO consumptions (id, bill_id, subject_type, subject_id, units, meter_value, recorded_at, source)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO consumptions (id, bill_id, subject_type, subject_id, units, meter_value, meter_id, recorded_at, source)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
-		id,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		consumption.ID,
 		consumption.BillID,
 		consumption.SubjectType,
 		consumption.SubjectID,
 		consumption.Units,
 		consumption.MeterValue,
+		consumption.MeterID,
 		consumption.RecordedAt.UTC().Format(time.RFC3339),
 		consumption.Source,
 	)
@@ -57,7 +63,7 @@ func (r *ConsumptionRepository) Create(ctx context.Context, consumption *models.
 // GetByID retrieves a consumption by ID
 func (r *ConsumptionRepository) GetByID(ctx context.Context, id string) (*models.Consumption, error) {
 	var row ConsumptionRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM consumptions WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM consumptions WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -71,16 +77,17 @@ func (r *ConsumptionRepository) GetByID(ctx context.Context, id string) (*models
 func (r *ConsumptionRepository) Update(ctx context.Context, consumption *models.Consumption) error {
 	query := `
 		UPDATE consumptions SET
-			bill_id = ?, subject_type = ?, subject_id = ?, units = ?, meter_value = ?, recorded_at = ?, source = ?
+			bill_id = ?, subject_type = ?, subject_id = ?, units = ?, meter_value = ?, meter_id = ?, recorded_at = ?, source = ?
 		WHERE id = ?
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		consumption.BillID,
 		consumption.SubjectType,
 		consumption.SubjectID,
 		consumption.Units,
 		consumption.MeterValue,
+		consumption.MeterID,
 		consumption.RecordedAt.UTC().Format(time.RFC3339),
 		consumption.Source,
 		consumption.ID,
@@ -90,14 +97,14 @@ func (r *ConsumptionRepository) Update(ctx context.Context, consumption *models.
 
 // Delete deletes a consumption
 func (r *ConsumptionRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM consumptions WHERE id = ?", id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM consumptions WHERE id = ?", id)
 	return err
 }
 
 // List returns all consumptions
 func (r *ConsumptionRepository) List(ctx context.Context) ([]models.Consumption, error) {
 	var rows []ConsumptionRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM consumptions ORDER BY recorded_at DESC")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM consumptions ORDER BY recorded_at DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -107,7 +114,7 @@ func (r *ConsumptionRepository) List(ctx context.Context) ([]models.Consumption,
 // ListByBillID returns consumptions for a bill
 func (r *ConsumptionRepository) ListByBillID(ctx context.Context, billID string) ([]models.Consumption, error) {
 	var rows []ConsumptionRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM consumptions WHERE bill_id = ?", billID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM consumptions WHERE bill_id = ?", billID)
 	if err != nil {
 		return nil, err
 	}
@@ -117,7 +124,7 @@ func (r *ConsumptionRepository) ListByBillID(ctx context.Context, billID string)
 // ListBySubject returns consumptions for a subject
 func (r *ConsumptionRepository) ListBySubject(ctx context.Context, subjectType, subjectID string) ([]models.Consumption, error) {
 	var rows []ConsumptionRow
-	err := r.db.SelectContext(ctx, &rows,
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
 		"SELECT * FROM consumptions WHERE subject_type = ? AND subject_id = ? ORDER BY recorded_at DESC",
 		subjectType, subjectID)
 	if err != nil {
@@ -126,6 +133,17 @@ func (r *ConsumptionRepository) ListBySubject(ctx context.Context, subjectType,
 	return rowsToConsumptions(rows), nil
 }
 
+// ListByMeterID returns the readings of a meter
+func (r *ConsumptionRepository) ListByMeterID(ctx context.Context, meterID string) ([]models.Consumption, error) {
+	var rows []ConsumptionRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM consumptions WHERE meter_id = ? ORDER BY recorded_at DESC", meterID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToConsumptions(rows), nil
+}
+
 // ListFiltered returns consumptions with optional filters
 func (r *ConsumptionRepository) ListFiltered(ctx context.Context, subjectType, subjectID *string, from, to *time.Time) ([]models.Consumption, error) {
 	query := "SELECT * FROM consumptions WHERE 1=1"
@@ -151,7 +169,7 @@ func (r *ConsumptionRepository) ListFiltered(ctx context.Context, subjectType, s
 	query += " ORDER BY recorded_at DESC"
 
 	var rows []ConsumptionRow
-	err := r.db.SelectContext(ctx, &rows, query, args...)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -160,7 +178,25 @@ func (r *ConsumptionRepository) ListFiltered(ctx context.Context, subjectType, s
 
 // DeleteByBillID deletes all consumptions for a bill
 func (r *ConsumptionRepository) DeleteByBillID(ctx context.Context, billID string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM consumptions WHERE bill_id = ?", billID)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM consumptions WHERE bill_id = ?", billID)
+	return err
+}
+
+// ListUnchecked returns the readings the anomaly check has not looked at yet, oldest first
+func (r *ConsumptionRepository) ListUnchecked(ctx context.Context) ([]models.Consumption, error) {
+	var rows []ConsumptionRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM consumptions WHERE anomaly_checked_at IS NULL ORDER BY recorded_at ASC")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToConsumptions(rows), nil
+}
+
+// MarkChecked records that the anomaly check has looked at a reading
+func (r *ConsumptionRepository) MarkChecked(ctx context.Context, id string, at time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE consumptions SET anomaly_checked_at = ? WHERE id = ?",
+		at.UTC().Format(time.RFC3339), id)
 	return err
 }
 
@@ -172,6 +208,7 @@ func rowToConsumption(row *ConsumptionRow) *models.Consumption {
 		SubjectID:   row.SubjectID,
 		Units:       row.Units,
 		MeterValue:  row.MeterValue,
+		MeterID:     row.MeterID,
 		Source:      row.Source,
 	}
 
@@ -0,0 +1,120 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sainaif/holy-home/internal/models"
+)
+
+// CreditEntryRow represents a credit ledger row in SQLite
+type CreditEntryRow struct {
+	ID        string  `db:"id"`
+	UserID    string  `db:"user_id"`
+	BillID    *string `db:"bill_id"`
+	PaymentID *string `db:"payment_id"`
+	Kind      string  `db:"kind"`
+	Amount    string  `db:"amount"`
+	Currency  string  `db:"currency"`
+	CreatedAt string  `db:"created_at"`
+}
+
+// CreditEntryRepository implements repository.CreditEntryRepository for SQLite
+type CreditEntryRepository struct {
+	db *sqlx.DB
+}
+
+// NewCreditEntryRepository creates a new SQLite credit ledger repository
+func NewCreditEntryRepository(db *sqlx.DB) *CreditEntryRepository {
+	return &CreditEntryRepository{db: db}
+}
+
+// Create appends an entry to the credit ledger
+func (r *CreditEntryRepository) Create(ctx context.Context, entry *models.CreditEntry) error {
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO credit_entries (id, user_id, bill_id, payment_id, kind, amount, currency, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		entry.ID,
+		entry.UserID,
+		entry.BillID,
+		entry.PaymentID,
+		entry.Kind,
+		entry.Amount,
+		entry.Currency,
+		entry.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// ListByUserID returns a user's credit entries, oldest first
+func (r *CreditEntryRepository) ListByUserID(ctx context.Context, userID string) ([]models.CreditEntry, error) {
+	var rows []CreditEntryRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM credit_entries WHERE user_id = ? ORDER BY created_at, rowid", userID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToCreditEntries(rows), nil
+}
+
+// ListByBillID returns credit earned on or applied to a bill
+func (r *CreditEntryRepository) ListByBillID(ctx context.Context, billID string) ([]models.CreditEntry, error) {
+	var rows []CreditEntryRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM credit_entries WHERE bill_id = ? ORDER BY created_at, rowid", billID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToCreditEntries(rows), nil
+}
+
+// DeleteAppliedByBillID releases the credit applied to a bill back to its users
+func (r *CreditEntryRepository) DeleteAppliedByBillID(ctx context.Context, billID string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx,
+		"DELETE FROM credit_entries WHERE bill_id = ? AND kind = 'applied'", billID)
+	return err
+}
+
+// List returns all credit entries
+func (r *CreditEntryRepository) List(ctx context.Context) ([]models.CreditEntry, error) {
+	var rows []CreditEntryRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM credit_entries ORDER BY created_at, rowid")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToCreditEntries(rows), nil
+}
+
+func rowToCreditEntry(row *CreditEntryRow) *models.CreditEntry {
+	entry := &models.CreditEntry{
+		ID:        row.ID,
+		UserID:    row.UserID,
+		BillID:    row.BillID,
+		PaymentID: row.PaymentID,
+		Kind:      row.Kind,
+		Amount:    row.Amount,
+		Currency:  row.Currency,
+	}
+	entry.CreatedAt, _ = time.Parse(time.RFC3339, row.CreatedAt)
+	return entry
+}
+
+func rowsToCreditEntries(rows []CreditEntryRow) []models.CreditEntry {
+	entries := make([]models.CreditEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = *rowToCreditEntry(&row)
+	}
+	return entries
+}
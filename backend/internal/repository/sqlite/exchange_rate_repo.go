@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sainaif/holy-home/internal/models"
+)
+
+// ExchangeRateRow represents an exchange rate row in SQLite
+type ExchangeRateRow struct {
+	ID        string `db:"id"`
+	Currency  string `db:"currency"`
+	Rate      string `db:"rate"`
+	RateDate  string `db:"rate_date"`
+	CreatedAt string `db:"created_at"`
+}
+
+// ExchangeRateRepository implements repository.ExchangeRateRepository for SQLite
+type ExchangeRateRepository struct {
+	db *sqlx.DB
+}
+
+// NewExchangeRateRepository creates a new SQLite exchange rate repository
+func NewExchangeRateRepository(db *sqlx.DB) *ExchangeRateRepository {
+	return &ExchangeRateRepository{db: db}
+}
+
+// Upsert creates a rate or replaces the rate already set for the same currency and day
+func (r *ExchangeRateRepository) Upsert(ctx context.Context, rate *models.ExchangeRate) error {
+	if rate.ID == "" {
+		rate.ID = uuid.New().String()
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	query := `
+		INSERT INTO exchange_rates (id, currency, rate, rate_date, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(currency, rate_date) DO UPDATE SET
+			rate = excluded.rate,
+			created_at = excluded.created_at
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		rate.ID,
+		rate.Currency,
+		rate.Rate,
+		rate.RateDate.UTC().Format(time.RFC3339),
+		now,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Pick up the existing ID when the rate replaced an earlier one
+	return conn(ctx, r.db).GetContext(ctx, &rate.ID,
+		"SELECT id FROM exchange_rates WHERE currency = ? AND rate_date = ?",
+		rate.Currency, rate.RateDate.UTC().Format(time.RFC3339))
+}
+
+// GetByID retrieves an exchange rate by ID
+func (r *ExchangeRateRepository) GetByID(ctx context.Context, id string) (*models.ExchangeRate, error) {
+	var row ExchangeRateRow
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM exchange_rates WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToExchangeRate(&row), nil
+}
+
+// GetLatest returns the most recent rate for a currency that applies at the given time
+func (r *ExchangeRateRepository) GetLatest(ctx context.Context, currency string, at time.Time) (*models.ExchangeRate, error) {
+	var row ExchangeRateRow
+	query := `
+		SELECT * FROM exchange_rates
+		WHERE currency = ? AND rate_date <= ?
+		ORDER BY rate_date DESC
+		LIMIT 1
+	`
+	err := conn(ctx, r.db).GetContext(ctx, &row, query, currency, at.UTC().Format(time.RFC3339))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToExchangeRate(&row), nil
+}
+
+// Delete deletes an exchange rate
+func (r *ExchangeRateRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM exchange_rates WHERE id = ?", id)
+	return err
+}
+
+// List returns all exchange rates grouped by currency, newest first
+func (r *ExchangeRateRepository) List(ctx context.Context) ([]models.ExchangeRate, error) {
+	var rows []ExchangeRateRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM exchange_rates ORDER BY currency, rate_date DESC")
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make([]models.ExchangeRate, len(rows))
+	for i, row := range rows {
+		rates[i] = *rowToExchangeRate(&row)
+	}
+	return rates, nil
+}
+
+func rowToExchangeRate(row *ExchangeRateRow) *models.ExchangeRate {
+	rate := &models.ExchangeRate{
+		ID:       row.ID,
+		Currency: row.Currency,
+		Rate:     row.Rate,
+	}
+	rate.RateDate, _ = time.Parse(time.RFC3339, row.RateDate)
+	rate.CreatedAt, _ = time.Parse(time.RFC3339, row.CreatedAt)
+	return rate
+}
@@ -34,14 +34,14 @@ func (r *GroupRepository) Create(ctx context.Context, group *models.Group) error
 	now := time.Now().UTC().Format(time.RFC3339)
 
 	query := `INSERT INTO groups (id, name, weight, created_at) VALUES (?, ?, ?, ?)`
-	_, err := r.db.ExecContext(ctx, query, id, group.Name, group.Weight, now)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, id, group.Name, group.Weight, now)
 	return err
 }
 
 // GetByID retrieves a group by ID
 func (r *GroupRepository) GetByID(ctx context.Context, id string) (*models.Group, error) {
 	var row GroupRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM groups WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM groups WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -54,20 +54,20 @@ func (r *GroupRepository) GetByID(ctx context.Context, id string) (*models.Group
 // Update updates an existing group
 func (r *GroupRepository) Update(ctx context.Context, group *models.Group) error {
 	query := `UPDATE groups SET name = ?, weight = ? WHERE id = ?`
-	_, err := r.db.ExecContext(ctx, query, group.Name, group.Weight, group.ID)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, group.Name, group.Weight, group.ID)
 	return err
 }
 
 // Delete deletes a group
 func (r *GroupRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM groups WHERE id = ?", id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM groups WHERE id = ?", id)
 	return err
 }
 
 // List returns all groups
 func (r *GroupRepository) List(ctx context.Context) ([]models.Group, error) {
 	var rows []GroupRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM groups ORDER BY name")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM groups ORDER BY name")
 	if err != nil {
 		return nil, err
 	}
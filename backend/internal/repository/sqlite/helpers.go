@@ -52,3 +52,11 @@ func PtrString(s *string) string {
 	}
 	return *s
 }
+
+// currencyOrDefault returns the currency code, falling back to the column default (PLN)
+func currencyOrDefault(currency string) string {
+	if currency == "" {
+		return "PLN"
+	}
+	return currency
+}
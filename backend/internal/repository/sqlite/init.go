@@ -10,15 +10,31 @@ func NewRepositories(db *sqlx.DB) *repository.Repositories {
 	return &repository.Repositories{
 		Users:                    NewUserRepository(db),
 		PasskeyCredentials:       NewPasskeyCredentialRepository(db),
+		Residencies:              NewResidencyRepository(db),
 		Groups:                   NewGroupRepository(db),
 		Bills:                    NewBillRepository(db),
+		BillTransitions:          NewBillTransitionRepository(db),
+		BillDisputes:             NewBillDisputeRepository(db),
+		BillVersions:             NewBillVersionRepository(db),
 		RecurringBillTemplates:   NewRecurringBillTemplateRepository(db),
 		RecurringBillAllocations: NewRecurringBillAllocationRepository(db),
+		BillSplits:               NewBillSplitRepository(db),
+		BillItems:                NewBillItemRepository(db),
+		BillTariffZones:          NewBillTariffZoneRepository(db),
+		BillResidents:            NewBillResidentRepository(db),
+		Meters:                   NewMeterRepository(db),
 		Consumptions:             NewConsumptionRepository(db),
+		ConsumptionAnomalies:     NewConsumptionAnomalyRepository(db),
 		Allocations:              NewAllocationRepository(db),
 		Payments:                 NewPaymentRepository(db),
+		CreditEntries:            NewCreditEntryRepository(db),
+		BankAccounts:             NewBankAccountRepository(db),
+		BankImports:              NewBankImportRepository(db),
+		BankTransactions:         NewBankTransactionRepository(db),
+		Attachments:              NewAttachmentRepository(db),
 		Loans:                    NewLoanRepository(db),
 		LoanPayments:             NewLoanPaymentRepository(db),
+		SettlementTransfers:      NewSettlementTransferRepository(db),
 		Chores:                   NewChoreRepository(db),
 		ChoreAssignments:         NewChoreAssignmentRepository(db),
 		ChoreSettings:            NewChoreSettingsRepository(db),
@@ -37,5 +53,13 @@ func NewRepositories(db *sqlx.DB) *repository.Repositories {
 		ApprovalRequests:         NewApprovalRequestRepository(db),
 		AppSettings:              NewAppSettingsRepository(db),
 		SentReminders:            NewSentReminderRepository(db),
+		ExchangeRates:            NewExchangeRateRepository(db),
+		UtilityTariffs:           NewUtilityTariffRepository(db),
+		LateFeePolicies:          NewLateFeePolicyRepository(db),
+		LateFees:                 NewLateFeeRepository(db),
+		Budgets:                  NewBudgetRepository(db),
+		BudgetAlerts:             NewBudgetAlertRepository(db),
+		Analytics:                NewAnalyticsRepository(db),
+		TxManager:                NewTxManager(db),
 	}
 }
@@ -0,0 +1,341 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sainaif/holy-home/internal/models"
+)
+
+// LateFeePolicyRow represents a late-fee policy row in SQLite
+type LateFeePolicyRow struct {
+	ID               string  `db:"id"`
+	ResourceType     string  `db:"resource_type"`
+	BillType         string  `db:"bill_type"`
+	CustomType       string  `db:"custom_type"`
+	LoanID           string  `db:"loan_id"`
+	FlatFee          string  `db:"flat_fee"`
+	PercentPerPeriod string  `db:"percent_per_period"`
+	PeriodDays       int     `db:"period_days"`
+	GraceDays        int     `db:"grace_days"`
+	Cap              *string `db:"cap"`
+	CreatedAt        string  `db:"created_at"`
+	UpdatedAt        string  `db:"updated_at"`
+}
+
+// LateFeePolicyRepository implements repository.LateFeePolicyRepository for SQLite
+type LateFeePolicyRepository struct {
+	db *sqlx.DB
+}
+
+// NewLateFeePolicyRepository creates a new SQLite late-fee policy repository
+func NewLateFeePolicyRepository(db *sqlx.DB) *LateFeePolicyRepository {
+	return &LateFeePolicyRepository{db: db}
+}
+
+// Upsert creates a policy or replaces the policy already set for the same bill type or loan
+func (r *LateFeePolicyRepository) Upsert(ctx context.Context, policy *models.LateFeePolicy) error {
+	if policy.ID == "" {
+		policy.ID = uuid.New().String()
+	}
+	now := time.Now()
+	if policy.CreatedAt.IsZero() {
+		policy.CreatedAt = now
+	}
+	policy.UpdatedAt = now
+
+	query := `
+		INSERT INTO late_fee_policies (id, resource_type, bill_type, custom_type, loan_id, flat_fee, percent_per_period,
+			period_days, grace_days, cap, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(resource_type, bill_type, custom_type, loan_id) DO UPDATE SET
+			flat_fee = excluded.flat_fee,
+			percent_per_period = excluded.percent_per_period,
+			period_days = excluded.period_days,
+			grace_days = excluded.grace_days,
+			cap = excluded.cap,
+			updated_at = excluded.updated_at
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		policy.ID,
+		policy.ResourceType,
+		policy.BillType,
+		policy.CustomType,
+		policy.LoanID,
+		policy.FlatFee,
+		policy.PercentPerPeriod,
+		policy.PeriodDays,
+		policy.GraceDays,
+		policy.Cap,
+		policy.CreatedAt.UTC().Format(time.RFC3339),
+		policy.UpdatedAt.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Pick up the existing ID and creation time when the policy replaced an earlier one
+	var row LateFeePolicyRow
+	err = conn(ctx, r.db).GetContext(ctx, &row,
+		"SELECT * FROM late_fee_policies WHERE resource_type = ? AND bill_type = ? AND custom_type = ? AND loan_id = ?",
+		policy.ResourceType, policy.BillType, policy.CustomType, policy.LoanID)
+	if err != nil {
+		return err
+	}
+	*policy = *rowToLateFeePolicy(&row)
+	return nil
+}
+
+// GetByID retrieves a late-fee policy by ID
+func (r *LateFeePolicyRepository) GetByID(ctx context.Context, id string) (*models.LateFeePolicy, error) {
+	var row LateFeePolicyRow
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM late_fee_policies WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToLateFeePolicy(&row), nil
+}
+
+// Find retrieves the policy set for exactly this bill type or loan
+func (r *LateFeePolicyRepository) Find(ctx context.Context, resourceType, billType, customType, loanID string) (*models.LateFeePolicy, error) {
+	var row LateFeePolicyRow
+	err := conn(ctx, r.db).GetContext(ctx, &row,
+		"SELECT * FROM late_fee_policies WHERE resource_type = ? AND bill_type = ? AND custom_type = ? AND loan_id = ?",
+		resourceType, billType, customType, loanID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToLateFeePolicy(&row), nil
+}
+
+// Delete deletes a late-fee policy
+func (r *LateFeePolicyRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM late_fee_policies WHERE id = ?", id)
+	return err
+}
+
+// List returns all late-fee policies, bill types first
+func (r *LateFeePolicyRepository) List(ctx context.Context) ([]models.LateFeePolicy, error) {
+	var rows []LateFeePolicyRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM late_fee_policies ORDER BY resource_type, bill_type, custom_type, loan_id")
+	if err != nil {
+		return nil, err
+	}
+	policies := make([]models.LateFeePolicy, len(rows))
+	for i, row := range rows {
+		policies[i] = *rowToLateFeePolicy(&row)
+	}
+	return policies, nil
+}
+
+func rowToLateFeePolicy(row *LateFeePolicyRow) *models.LateFeePolicy {
+	policy := &models.LateFeePolicy{
+		ID:               row.ID,
+		ResourceType:     row.ResourceType,
+		BillType:         row.BillType,
+		CustomType:       row.CustomType,
+		LoanID:           row.LoanID,
+		FlatFee:          row.FlatFee,
+		PercentPerPeriod: row.PercentPerPeriod,
+		PeriodDays:       row.PeriodDays,
+		GraceDays:        row.GraceDays,
+		Cap:              row.Cap,
+	}
+	policy.CreatedAt, _ = time.Parse(time.RFC3339, row.CreatedAt)
+	policy.UpdatedAt, _ = time.Parse(time.RFC3339, row.UpdatedAt)
+	return policy
+}
+
+// LateFeeRow represents a late fee row in SQLite
+type LateFeeRow struct {
+	ID            string  `db:"id"`
+	PolicyID      *string `db:"policy_id"`
+	ResourceType  string  `db:"resource_type"`
+	ResourceID    string  `db:"resource_id"`
+	SubjectType   string  `db:"subject_type"`
+	SubjectID     string  `db:"subject_id"`
+	BeneficiaryID *string `db:"beneficiary_id"`
+	Kind          string  `db:"kind"`
+	Period        int     `db:"period"`
+	Amount        string  `db:"amount"`
+	Currency      string  `db:"currency"`
+	AccruedAt     string  `db:"accrued_at"`
+	Status        string  `db:"status"`
+	WaivedBy      *string `db:"waived_by"`
+	WaivedAt      *string `db:"waived_at"`
+	WaiveReason   *string `db:"waive_reason"`
+	CreatedAt     string  `db:"created_at"`
+}
+
+// LateFeeRepository implements repository.LateFeeRepository for SQLite
+type LateFeeRepository struct {
+	db *sqlx.DB
+}
+
+// NewLateFeeRepository creates a new SQLite late fee repository
+func NewLateFeeRepository(db *sqlx.DB) *LateFeeRepository {
+	return &LateFeeRepository{db: db}
+}
+
+// Create records an accrued late fee
+func (r *LateFeeRepository) Create(ctx context.Context, fee *models.LateFee) error {
+	if fee.ID == "" {
+		fee.ID = uuid.New().String()
+	}
+	if fee.CreatedAt.IsZero() {
+		fee.CreatedAt = time.Now()
+	}
+	if fee.Status == "" {
+		fee.Status = "accrued"
+	}
+
+	query := `
+		INSERT INTO late_fees (id, policy_id, resource_type, resource_id, subject_type, subject_id, beneficiary_id, kind,
+			period, amount, currency, accrued_at, status, waived_by, waived_at, waive_reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	var waivedAt *string
+	if fee.WaivedAt != nil {
+		wa := fee.WaivedAt.UTC().Format(time.RFC3339)
+		waivedAt = &wa
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		fee.ID,
+		fee.PolicyID,
+		fee.ResourceType,
+		fee.ResourceID,
+		fee.SubjectType,
+		fee.SubjectID,
+		fee.BeneficiaryID,
+		fee.Kind,
+		fee.Period,
+		fee.Amount,
+		fee.Currency,
+		fee.AccruedAt.UTC().Format(time.RFC3339),
+		fee.Status,
+		fee.WaivedBy,
+		waivedAt,
+		fee.WaiveReason,
+		fee.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// GetByID retrieves a late fee by ID
+func (r *LateFeeRepository) GetByID(ctx context.Context, id string) (*models.LateFee, error) {
+	var row LateFeeRow
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM late_fees WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToLateFee(&row), nil
+}
+
+// Update records a waiver of a late fee
+func (r *LateFeeRepository) Update(ctx context.Context, fee *models.LateFee) error {
+	var waivedAt *string
+	if fee.WaivedAt != nil {
+		wa := fee.WaivedAt.UTC().Format(time.RFC3339)
+		waivedAt = &wa
+	}
+
+	query := `
+		UPDATE late_fees SET status = ?, waived_by = ?, waived_at = ?, waive_reason = ?
+		WHERE id = ?
+	`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, fee.Status, fee.WaivedBy, waivedAt, fee.WaiveReason, fee.ID)
+	return err
+}
+
+// ListByResource returns the fees of a bill or loan, oldest first
+func (r *LateFeeRepository) ListByResource(ctx context.Context, resourceType, resourceID string) ([]models.LateFee, error) {
+	var rows []LateFeeRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM late_fees WHERE resource_type = ? AND resource_id = ? ORDER BY accrued_at, period", resourceType, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToLateFees(rows), nil
+}
+
+// ListBySubject returns the fees charged to a user or group, oldest first
+func (r *LateFeeRepository) ListBySubject(ctx context.Context, subjectType, subjectID string) ([]models.LateFee, error) {
+	var rows []LateFeeRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM late_fees WHERE subject_type = ? AND subject_id = ? ORDER BY accrued_at, period", subjectType, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToLateFees(rows), nil
+}
+
+// ListByBeneficiaryID returns the fees owed to a lender, oldest first
+func (r *LateFeeRepository) ListByBeneficiaryID(ctx context.Context, userID string) ([]models.LateFee, error) {
+	var rows []LateFeeRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM late_fees WHERE beneficiary_id = ? ORDER BY accrued_at, period", userID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToLateFees(rows), nil
+}
+
+// List returns all late fees, newest first
+func (r *LateFeeRepository) List(ctx context.Context) ([]models.LateFee, error) {
+	var rows []LateFeeRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM late_fees ORDER BY accrued_at DESC, period DESC")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToLateFees(rows), nil
+}
+
+func rowToLateFee(row *LateFeeRow) *models.LateFee {
+	fee := &models.LateFee{
+		ID:            row.ID,
+		PolicyID:      row.PolicyID,
+		ResourceType:  row.ResourceType,
+		ResourceID:    row.ResourceID,
+		SubjectType:   row.SubjectType,
+		SubjectID:     row.SubjectID,
+		BeneficiaryID: row.BeneficiaryID,
+		Kind:          row.Kind,
+		Period:        row.Period,
+		Amount:        row.Amount,
+		Currency:      row.Currency,
+		Status:        row.Status,
+		WaivedBy:      row.WaivedBy,
+		WaiveReason:   row.WaiveReason,
+	}
+	fee.AccruedAt, _ = time.Parse(time.RFC3339, row.AccruedAt)
+	fee.CreatedAt, _ = time.Parse(time.RFC3339, row.CreatedAt)
+	if row.WaivedAt != nil {
+		waivedAt, _ := time.Parse(time.RFC3339, *row.WaivedAt)
+		fee.WaivedAt = &waivedAt
+	}
+	return fee
+}
+
+func rowsToLateFees(rows []LateFeeRow) []models.LateFee {
+	fees := make([]models.LateFee, len(rows))
+	for i, row := range rows {
+		fees[i] = *rowToLateFee(&row)
+	}
+	return fees
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/utils"
 )
 
 // LoanRow represents a loan row in SQLite
@@ -16,6 +17,7 @@ type LoanRow struct {
 	LenderID   string  `db:"lender_id"`
 	BorrowerID string  `db:"borrower_id"`
 	AmountPLN  string  `db:"amount_pln"`
+	Currency   string  `db:"currency"`
 	Note       *string `db:"note"`
 	DueDate    *string `db:"due_date"`
 	Status     string  `db:"status"`
@@ -34,7 +36,9 @@ func NewLoanRepository(db *sqlx.DB) *LoanRepository {
 
 // Create creates a new loan
 func (r *LoanRepository) Create(ctx context.Context, loan *models.Loan) error {
-	id := uuid.New().String()
+	if loan.ID == "" {
+		loan.ID = uuid.New().String()
+	}
 	now := time.Now().UTC().Format(time.RFC3339)
 
 	var dueDate *string
@@ -44,15 +48,16 @@ func (r *LoanRepository) Create(ctx context.Context, loan *models.Loan) error {
 	}
 
 	query := `
-		INSERT INTO loans (id, lender_id, borrower_id, amount_pln, note, due_date, status, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO loans (id, lender_id, borrower_id, amount_pln, currency, note, due_date, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
-		id,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		loan.ID,
 		loan.LenderID,
 		loan.BorrowerID,
 		loan.AmountPLN,
+		currencyOrDefault(loan.Currency),
 		loan.Note,
 		dueDate,
 		loan.Status,
@@ -64,7 +69,7 @@ func (r *LoanRepository) Create(ctx context.Context, loan *models.Loan) error {
 // GetByID retrieves a loan by ID
 func (r *LoanRepository) GetByID(ctx context.Context, id string) (*models.Loan, error) {
 	var row LoanRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM loans WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM loans WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -84,14 +89,15 @@ func (r *LoanRepository) Update(ctx context.Context, loan *models.Loan) error {
 
 	query := `
 		UPDATE loans SET
-			lender_id = ?, borrower_id = ?, amount_pln = ?, note = ?, due_date = ?, status = ?
+			lender_id = ?, borrower_id = ?, amount_pln = ?, currency = ?, note = ?, due_date = ?, status = ?
 		WHERE id = ?
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		loan.LenderID,
 		loan.BorrowerID,
 		loan.AmountPLN,
+		currencyOrDefault(loan.Currency),
 		loan.Note,
 		dueDate,
 		loan.Status,
@@ -102,14 +108,14 @@ func (r *LoanRepository) Update(ctx context.Context, loan *models.Loan) error {
 
 // Delete deletes a loan
 func (r *LoanRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM loans WHERE id = ?", id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM loans WHERE id = ?", id)
 	return err
 }
 
 // List returns all loans
 func (r *LoanRepository) List(ctx context.Context) ([]models.Loan, error) {
 	var rows []LoanRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM loans ORDER BY created_at DESC")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM loans ORDER BY created_at DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -119,7 +125,7 @@ func (r *LoanRepository) List(ctx context.Context) ([]models.Loan, error) {
 // ListByLenderID returns loans by lender
 func (r *LoanRepository) ListByLenderID(ctx context.Context, lenderID string) ([]models.Loan, error) {
 	var rows []LoanRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM loans WHERE lender_id = ? ORDER BY created_at DESC", lenderID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM loans WHERE lender_id = ? ORDER BY created_at DESC", lenderID)
 	if err != nil {
 		return nil, err
 	}
@@ -129,7 +135,7 @@ func (r *LoanRepository) ListByLenderID(ctx context.Context, lenderID string) ([
 // ListByBorrowerID returns loans by borrower
 func (r *LoanRepository) ListByBorrowerID(ctx context.Context, borrowerID string) ([]models.Loan, error) {
 	var rows []LoanRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM loans WHERE borrower_id = ? ORDER BY created_at DESC", borrowerID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM loans WHERE borrower_id = ? ORDER BY created_at DESC", borrowerID)
 	if err != nil {
 		return nil, err
 	}
@@ -139,7 +145,7 @@ func (r *LoanRepository) ListByBorrowerID(ctx context.Context, borrowerID string
 // ListByStatus returns loans by status
 func (r *LoanRepository) ListByStatus(ctx context.Context, status string) ([]models.Loan, error) {
 	var rows []LoanRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM loans WHERE status = ? ORDER BY created_at DESC", status)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM loans WHERE status = ? ORDER BY created_at DESC", status)
 	if err != nil {
 		return nil, err
 	}
@@ -155,7 +161,7 @@ func (r *LoanRepository) ListOpenBetweenUsers(ctx context.Context, lenderID, bor
 		AND lender_id = ? AND borrower_id = ?
 		ORDER BY created_at DESC
 	`
-	err := r.db.SelectContext(ctx, &rows, query, lenderID, borrowerID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, query, lenderID, borrowerID)
 	if err != nil {
 		return nil, err
 	}
@@ -168,6 +174,7 @@ func rowToLoan(row *LoanRow) *models.Loan {
 		LenderID:   row.LenderID,
 		BorrowerID: row.BorrowerID,
 		AmountPLN:  row.AmountPLN,
+		Currency:   row.Currency,
 		Note:       row.Note,
 		Status:     row.Status,
 	}
@@ -192,11 +199,12 @@ func rowsToLoans(rows []LoanRow) []models.Loan {
 
 // LoanPaymentRow represents a loan payment row in SQLite
 type LoanPaymentRow struct {
-	ID        string  `db:"id"`
-	LoanID    string  `db:"loan_id"`
-	AmountPLN string  `db:"amount_pln"`
-	PaidAt    string  `db:"paid_at"`
-	Note      *string `db:"note"`
+	ID           string  `db:"id"`
+	LoanID       string  `db:"loan_id"`
+	AmountPLN    string  `db:"amount_pln"`
+	PaidAt       string  `db:"paid_at"`
+	Note         *string `db:"note"`
+	SettlementID *string `db:"settlement_id"`
 }
 
 // LoanPaymentRepository implements repository.LoanPaymentRepository for SQLite
@@ -213,13 +221,14 @@ func NewLoanPaymentRepository(db *sqlx.DB) *LoanPaymentRepository {
 func (r *LoanPaymentRepository) Create(ctx context.Context, payment *models.LoanPayment) error {
 	id := uuid.New().String()
 
-	query := `INSERT INTO loan_payments (id, loan_id, amount_pln, paid_at, note) VALUES (?, ?, ?, ?, ?)`
-	_, err := r.db.ExecContext(ctx, query,
+	query := `INSERT INTO loan_payments (id, loan_id, amount_pln, paid_at, note, settlement_id) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		id,
 		payment.LoanID,
 		payment.AmountPLN,
 		payment.PaidAt.UTC().Format(time.RFC3339),
 		payment.Note,
+		payment.SettlementID,
 	)
 	return err
 }
@@ -227,7 +236,7 @@ func (r *LoanPaymentRepository) Create(ctx context.Context, payment *models.Loan
 // GetByID retrieves a loan payment by ID
 func (r *LoanPaymentRepository) GetByID(ctx context.Context, id string) (*models.LoanPayment, error) {
 	var row LoanPaymentRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM loan_payments WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM loan_payments WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -239,14 +248,14 @@ func (r *LoanPaymentRepository) GetByID(ctx context.Context, id string) (*models
 
 // Delete deletes a loan payment
 func (r *LoanPaymentRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM loan_payments WHERE id = ?", id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM loan_payments WHERE id = ?", id)
 	return err
 }
 
 // List returns all loan payments
 func (r *LoanPaymentRepository) List(ctx context.Context) ([]models.LoanPayment, error) {
 	var rows []LoanPaymentRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM loan_payments ORDER BY paid_at DESC")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM loan_payments ORDER BY paid_at DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -256,7 +265,7 @@ func (r *LoanPaymentRepository) List(ctx context.Context) ([]models.LoanPayment,
 // ListByLoanID returns payments for a loan
 func (r *LoanPaymentRepository) ListByLoanID(ctx context.Context, loanID string) ([]models.LoanPayment, error) {
 	var rows []LoanPaymentRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM loan_payments WHERE loan_id = ? ORDER BY paid_at DESC", loanID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM loan_payments WHERE loan_id = ? ORDER BY paid_at DESC", loanID)
 	if err != nil {
 		return nil, err
 	}
@@ -265,23 +274,21 @@ func (r *LoanPaymentRepository) ListByLoanID(ctx context.Context, loanID string)
 
 // SumByLoanID returns the sum of payments for a loan
 func (r *LoanPaymentRepository) SumByLoanID(ctx context.Context, loanID string) (string, error) {
-	var sum sql.NullString
-	err := r.db.GetContext(ctx, &sum, "SELECT COALESCE(SUM(CAST(amount_pln AS REAL)), 0) FROM loan_payments WHERE loan_id = ?", loanID)
+	var amounts []utils.Money
+	err := conn(ctx, r.db).SelectContext(ctx, &amounts, "SELECT amount_pln FROM loan_payments WHERE loan_id = ?", loanID)
 	if err != nil {
 		return "0", err
 	}
-	if !sum.Valid || sum.String == "" {
-		return "0", nil
-	}
-	return sum.String, nil
+	return utils.SumMoney(amounts...).String(), nil
 }
 
 func rowToLoanPayment(row *LoanPaymentRow) *models.LoanPayment {
 	payment := &models.LoanPayment{
-		ID:        row.ID,
-		LoanID:    row.LoanID,
-		AmountPLN: row.AmountPLN,
-		Note:      row.Note,
+		ID:           row.ID,
+		LoanID:       row.LoanID,
+		AmountPLN:    row.AmountPLN,
+		Note:         row.Note,
+		SettlementID: row.SettlementID,
 	}
 
 	payment.PaidAt, _ = time.Parse(time.RFC3339, row.PaidAt)
@@ -0,0 +1,194 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sainaif/holy-home/internal/models"
+)
+
+// MeterRow represents a meter row in SQLite
+type MeterRow struct {
+	ID              string  `db:"id"`
+	Type            string  `db:"type"`
+	Unit            string  `db:"unit"`
+	SubjectType     string  `db:"subject_type"`
+	SubjectID       string  `db:"subject_id"`
+	TariffZone      string  `db:"tariff_zone"`
+	SerialNumber    string  `db:"serial_number"`
+	InstalledAt     string  `db:"installed_at"`
+	InitialValue    string  `db:"initial_value"`
+	ReplacedAt      *string `db:"replaced_at"`
+	FinalValue      *string `db:"final_value"`
+	ReplacesMeterID *string `db:"replaces_meter_id"`
+	CreatedAt       string  `db:"created_at"`
+}
+
+// MeterRepository implements repository.MeterRepository for SQLite
+type MeterRepository struct {
+	db *sqlx.DB
+}
+
+// NewMeterRepository creates a new SQLite meter repository
+func NewMeterRepository(db *sqlx.DB) *MeterRepository {
+	return &MeterRepository{db: db}
+}
+
+// Create registers a new meter
+func (r *MeterRepository) Create(ctx context.Context, meter *models.Meter) error {
+	if meter.ID == "" {
+		meter.ID = uuid.New().String()
+	}
+	if meter.CreatedAt.IsZero() {
+		meter.CreatedAt = time.Now()
+	}
+
+	var replacedAt *string
+	if meter.ReplacedAt != nil {
+		ra := meter.ReplacedAt.UTC().Format(time.RFC3339)
+		replacedAt = &ra
+	}
+
+	query := `
+		INSERT INTO meters (id, type, unit, subject_type, subject_id, tariff_zone, serial_number, installed_at,
+			initial_value, replaced_at, final_value, replaces_meter_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		meter.ID,
+		meter.Type,
+		meter.Unit,
+		meter.SubjectType,
+		meter.SubjectID,
+		meter.TariffZone,
+		meter.SerialNumber,
+		meter.InstalledAt.UTC().Format(time.RFC3339),
+		meter.InitialValue,
+		replacedAt,
+		meter.FinalValue,
+		meter.ReplacesMeterID,
+		meter.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// GetByID retrieves a meter by ID
+func (r *MeterRepository) GetByID(ctx context.Context, id string) (*models.Meter, error) {
+	var row MeterRow
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM meters WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToMeter(&row), nil
+}
+
+// GetByReplacesMeterID returns the meter installed in place of the given one
+func (r *MeterRepository) GetByReplacesMeterID(ctx context.Context, meterID string) (*models.Meter, error) {
+	var row MeterRow
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM meters WHERE replaces_meter_id = ?", meterID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToMeter(&row), nil
+}
+
+// Update updates an existing meter
+func (r *MeterRepository) Update(ctx context.Context, meter *models.Meter) error {
+	var replacedAt *string
+	if meter.ReplacedAt != nil {
+		ra := meter.ReplacedAt.UTC().Format(time.RFC3339)
+		replacedAt = &ra
+	}
+
+	query := `
+		UPDATE meters SET
+			type = ?, unit = ?, subject_type = ?, subject_id = ?, tariff_zone = ?, serial_number = ?, installed_at = ?,
+			initial_value = ?, replaced_at = ?, final_value = ?, replaces_meter_id = ?
+		WHERE id = ?
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		meter.Type,
+		meter.Unit,
+		meter.SubjectType,
+		meter.SubjectID,
+		meter.TariffZone,
+		meter.SerialNumber,
+		meter.InstalledAt.UTC().Format(time.RFC3339),
+		meter.InitialValue,
+		replacedAt,
+		meter.FinalValue,
+		meter.ReplacesMeterID,
+		meter.ID,
+	)
+	return err
+}
+
+// Delete deletes a meter
+func (r *MeterRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM meters WHERE id = ?", id)
+	return err
+}
+
+// List returns all meters
+func (r *MeterRepository) List(ctx context.Context) ([]models.Meter, error) {
+	var rows []MeterRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM meters ORDER BY subject_type, subject_id, installed_at")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToMeters(rows), nil
+}
+
+// ListBySubject returns the meters of a user or group, including replaced ones
+func (r *MeterRepository) ListBySubject(ctx context.Context, subjectType, subjectID string) ([]models.Meter, error) {
+	var rows []MeterRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM meters WHERE subject_type = ? AND subject_id = ? ORDER BY installed_at", subjectType, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToMeters(rows), nil
+}
+
+func rowToMeter(row *MeterRow) *models.Meter {
+	meter := &models.Meter{
+		ID:              row.ID,
+		Type:            row.Type,
+		Unit:            row.Unit,
+		SubjectType:     row.SubjectType,
+		SubjectID:       row.SubjectID,
+		TariffZone:      row.TariffZone,
+		SerialNumber:    row.SerialNumber,
+		InitialValue:    row.InitialValue,
+		FinalValue:      row.FinalValue,
+		ReplacesMeterID: row.ReplacesMeterID,
+	}
+
+	meter.InstalledAt, _ = time.Parse(time.RFC3339, row.InstalledAt)
+	meter.CreatedAt, _ = time.Parse(time.RFC3339, row.CreatedAt)
+	if row.ReplacedAt != nil {
+		t, _ := time.Parse(time.RFC3339, *row.ReplacedAt)
+		meter.ReplacedAt = &t
+	}
+
+	return meter
+}
+
+func rowsToMeters(rows []MeterRow) []models.Meter {
+	meters := make([]models.Meter, len(rows))
+	for i, row := range rows {
+		meters[i] = *rowToMeter(&row)
+	}
+	return meters
+}
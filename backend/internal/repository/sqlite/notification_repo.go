@@ -50,7 +50,7 @@ func (r *NotificationRepository) Create(ctx context.Context, notification *model
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		id,
 		notification.Channel,
 		notification.TemplateID,
@@ -68,7 +68,7 @@ func (r *NotificationRepository) Create(ctx context.Context, notification *model
 // GetByID retrieves a notification by ID
 func (r *NotificationRepository) GetByID(ctx context.Context, id string) (*models.Notification, error) {
 	var row NotificationRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM notifications WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM notifications WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -92,7 +92,7 @@ func (r *NotificationRepository) Update(ctx context.Context, notification *model
 		WHERE id = ?
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		notification.Channel,
 		notification.TemplateID,
 		notification.ScheduledFor.UTC().Format(time.RFC3339),
@@ -109,14 +109,14 @@ func (r *NotificationRepository) Update(ctx context.Context, notification *model
 
 // Delete deletes a notification
 func (r *NotificationRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM notifications WHERE id = ?", id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM notifications WHERE id = ?", id)
 	return err
 }
 
 // List returns all notifications
 func (r *NotificationRepository) List(ctx context.Context) ([]models.Notification, error) {
 	var rows []NotificationRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM notifications ORDER BY scheduled_for DESC")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM notifications ORDER BY scheduled_for DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -126,7 +126,7 @@ func (r *NotificationRepository) List(ctx context.Context) ([]models.Notificatio
 // ListByUserID returns notifications for a user
 func (r *NotificationRepository) ListByUserID(ctx context.Context, userID string, limit int) ([]models.Notification, error) {
 	var rows []NotificationRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM notifications WHERE user_id = ? ORDER BY scheduled_for DESC LIMIT ?", userID, limit)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM notifications WHERE user_id = ? ORDER BY scheduled_for DESC LIMIT ?", userID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -136,7 +136,7 @@ func (r *NotificationRepository) ListByUserID(ctx context.Context, userID string
 // ListUnreadByUserID returns unread notifications for a user
 func (r *NotificationRepository) ListUnreadByUserID(ctx context.Context, userID string) ([]models.Notification, error) {
 	var rows []NotificationRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM notifications WHERE user_id = ? AND read = 0 ORDER BY scheduled_for DESC", userID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM notifications WHERE user_id = ? AND read = 0 ORDER BY scheduled_for DESC", userID)
 	if err != nil {
 		return nil, err
 	}
@@ -145,13 +145,13 @@ func (r *NotificationRepository) ListUnreadByUserID(ctx context.Context, userID
 
 // MarkAsRead marks a notification as read
 func (r *NotificationRepository) MarkAsRead(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "UPDATE notifications SET read = 1 WHERE id = ?", id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE notifications SET read = 1 WHERE id = ?", id)
 	return err
 }
 
 // MarkAllAsReadForUser marks all notifications as read for a user
 func (r *NotificationRepository) MarkAllAsReadForUser(ctx context.Context, userID string) error {
-	_, err := r.db.ExecContext(ctx, "UPDATE notifications SET read = 1 WHERE user_id = ?", userID)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE notifications SET read = 1 WHERE user_id = ?", userID)
 	return err
 }
 
@@ -206,7 +206,7 @@ func NewNotificationPreferenceRepository(db *sqlx.DB) *NotificationPreferenceRep
 // GetByUserID retrieves notification preferences for a user
 func (r *NotificationPreferenceRepository) GetByUserID(ctx context.Context, userID string) (*models.NotificationPreference, error) {
 	var row NotificationPreferenceRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM notification_preferences WHERE user_id = ?", userID)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM notification_preferences WHERE user_id = ?", userID)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -232,7 +232,7 @@ func (r *NotificationPreferenceRepository) Upsert(ctx context.Context, pref *mod
 			updated_at = excluded.updated_at
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		id,
 		pref.UserID,
 		string(prefsJSON),
@@ -288,14 +288,14 @@ func (r *WebPushSubscriptionRepository) Create(ctx context.Context, sub *models.
 			auth = excluded.auth
 	`
 
-	_, err := r.db.ExecContext(ctx, query, id, sub.UserID, sub.Endpoint, sub.ExpirationTime, sub.P256dh, sub.Auth)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, id, sub.UserID, sub.Endpoint, sub.ExpirationTime, sub.P256dh, sub.Auth)
 	return err
 }
 
 // GetByEndpoint retrieves a subscription by endpoint
 func (r *WebPushSubscriptionRepository) GetByEndpoint(ctx context.Context, endpoint string) (*models.WebPushSubscription, error) {
 	var row WebPushSubscriptionRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM web_push_subscriptions WHERE endpoint = ?", endpoint)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM web_push_subscriptions WHERE endpoint = ?", endpoint)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -314,14 +314,14 @@ func (r *WebPushSubscriptionRepository) GetByEndpoint(ctx context.Context, endpo
 
 // Delete deletes a subscription by endpoint, scoped to user for security
 func (r *WebPushSubscriptionRepository) Delete(ctx context.Context, userID, endpoint string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM web_push_subscriptions WHERE user_id = ? AND endpoint = ?", userID, endpoint)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM web_push_subscriptions WHERE user_id = ? AND endpoint = ?", userID, endpoint)
 	return err
 }
 
 // ListByUserID returns subscriptions for a user
 func (r *WebPushSubscriptionRepository) ListByUserID(ctx context.Context, userID string) ([]models.WebPushSubscription, error) {
 	var rows []WebPushSubscriptionRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM web_push_subscriptions WHERE user_id = ?", userID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM web_push_subscriptions WHERE user_id = ?", userID)
 	if err != nil {
 		return nil, err
 	}
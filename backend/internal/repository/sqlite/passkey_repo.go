@@ -44,7 +44,7 @@ func (r *PasskeyCredentialRepository) Create(ctx context.Context, userID string,
 		INSERT INTO passkey_credentials (id, user_id, public_key, attestation_type, aaguid, sign_count, name, backup_eligible, backup_state, created_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		credIDHex,
 		userID,
 		cred.PublicKey,
@@ -62,7 +62,7 @@ func (r *PasskeyCredentialRepository) Create(ctx context.Context, userID string,
 // GetByUserID retrieves all passkey credentials for a user
 func (r *PasskeyCredentialRepository) GetByUserID(ctx context.Context, userID string) ([]models.PasskeyCredential, error) {
 	var rows []PasskeyCredentialRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM passkey_credentials WHERE user_id = ?", userID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM passkey_credentials WHERE user_id = ?", userID)
 	if err != nil {
 		return nil, err
 	}
@@ -73,7 +73,7 @@ func (r *PasskeyCredentialRepository) GetByUserID(ctx context.Context, userID st
 func (r *PasskeyCredentialRepository) GetByCredentialID(ctx context.Context, credID []byte) (*models.PasskeyCredential, string, error) {
 	credIDHex := bytesToHex(credID)
 	var row PasskeyCredentialRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM passkey_credentials WHERE id = ?", credIDHex)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM passkey_credentials WHERE id = ?", credIDHex)
 	if err == sql.ErrNoRows {
 		return nil, "", nil
 	}
@@ -86,14 +86,14 @@ func (r *PasskeyCredentialRepository) GetByCredentialID(ctx context.Context, cre
 // UpdateSignCount updates the sign count for a credential
 func (r *PasskeyCredentialRepository) UpdateSignCount(ctx context.Context, credID []byte, signCount uint32) error {
 	credIDHex := bytesToHex(credID)
-	_, err := r.db.ExecContext(ctx, "UPDATE passkey_credentials SET sign_count = ? WHERE id = ?", signCount, credIDHex)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE passkey_credentials SET sign_count = ? WHERE id = ?", signCount, credIDHex)
 	return err
 }
 
 // UpdateLastUsed updates the last used timestamp for a credential
 func (r *PasskeyCredentialRepository) UpdateLastUsed(ctx context.Context, credID []byte, lastUsedAt time.Time) error {
 	credIDHex := bytesToHex(credID)
-	_, err := r.db.ExecContext(ctx, "UPDATE passkey_credentials SET last_used_at = ? WHERE id = ?",
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE passkey_credentials SET last_used_at = ? WHERE id = ?",
 		lastUsedAt.UTC().Format(time.RFC3339), credIDHex)
 	return err
 }
@@ -101,20 +101,20 @@ func (r *PasskeyCredentialRepository) UpdateLastUsed(ctx context.Context, credID
 // Delete deletes a passkey credential
 func (r *PasskeyCredentialRepository) Delete(ctx context.Context, userID string, credID []byte) error {
 	credIDHex := bytesToHex(credID)
-	_, err := r.db.ExecContext(ctx, "DELETE FROM passkey_credentials WHERE user_id = ? AND id = ?", userID, credIDHex)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM passkey_credentials WHERE user_id = ? AND id = ?", userID, credIDHex)
 	return err
 }
 
 // DeleteAllForUser deletes all passkey credentials for a user
 func (r *PasskeyCredentialRepository) DeleteAllForUser(ctx context.Context, userID string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM passkey_credentials WHERE user_id = ?", userID)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM passkey_credentials WHERE user_id = ?", userID)
 	return err
 }
 
 // List returns all passkey credentials with their user IDs (for backups)
 func (r *PasskeyCredentialRepository) List(ctx context.Context) ([]repository.PasskeyCredentialWithUser, error) {
 	var rows []PasskeyCredentialRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM passkey_credentials")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM passkey_credentials")
 	if err != nil {
 		return nil, err
 	}
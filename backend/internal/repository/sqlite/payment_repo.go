@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/utils"
 )
 
 // PaymentRow represents a payment row in SQLite
@@ -33,15 +34,17 @@ func NewPaymentRepository(db *sqlx.DB) *PaymentRepository {
 
 // Create creates a new payment
 func (r *PaymentRepository) Create(ctx context.Context, payment *models.Payment) error {
-	id := uuid.New().String()
+	if payment.ID == "" {
+		payment.ID = uuid.New().String()
+	}
 
 	query := `
 		INSERT INTO payments (id, bill_id, payer_user_id, amount_pln, paid_at, method, reference)
 		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
-		id,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		payment.ID,
 		payment.BillID,
 		payment.PayerUserID,
 		payment.AmountPLN,
@@ -55,7 +58,7 @@ func (r *PaymentRepository) Create(ctx context.Context, payment *models.Payment)
 // GetByID retrieves a payment by ID
 func (r *PaymentRepository) GetByID(ctx context.Context, id string) (*models.Payment, error) {
 	var row PaymentRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM payments WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM payments WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -67,14 +70,14 @@ func (r *PaymentRepository) GetByID(ctx context.Context, id string) (*models.Pay
 
 // Delete deletes a payment
 func (r *PaymentRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM payments WHERE id = ?", id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM payments WHERE id = ?", id)
 	return err
 }
 
 // List returns all payments
 func (r *PaymentRepository) List(ctx context.Context) ([]models.Payment, error) {
 	var rows []PaymentRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM payments ORDER BY paid_at DESC")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM payments ORDER BY paid_at DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -84,7 +87,7 @@ func (r *PaymentRepository) List(ctx context.Context) ([]models.Payment, error)
 // ListByBillID returns payments for a bill
 func (r *PaymentRepository) ListByBillID(ctx context.Context, billID string) ([]models.Payment, error) {
 	var rows []PaymentRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM payments WHERE bill_id = ? ORDER BY paid_at DESC", billID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM payments WHERE bill_id = ? ORDER BY paid_at DESC", billID)
 	if err != nil {
 		return nil, err
 	}
@@ -94,7 +97,7 @@ func (r *PaymentRepository) ListByBillID(ctx context.Context, billID string) ([]
 // ListByPayerID returns payments by a payer
 func (r *PaymentRepository) ListByPayerID(ctx context.Context, payerID string) ([]models.Payment, error) {
 	var rows []PaymentRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM payments WHERE payer_user_id = ? ORDER BY paid_at DESC", payerID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM payments WHERE payer_user_id = ? ORDER BY paid_at DESC", payerID)
 	if err != nil {
 		return nil, err
 	}
@@ -103,15 +106,13 @@ func (r *PaymentRepository) ListByPayerID(ctx context.Context, payerID string) (
 
 // SumByBillID returns the sum of payments for a bill
 func (r *PaymentRepository) SumByBillID(ctx context.Context, billID string) (string, error) {
-	var sum sql.NullString
-	err := r.db.GetContext(ctx, &sum, "SELECT COALESCE(SUM(CAST(amount_pln AS REAL)), 0) FROM payments WHERE bill_id = ?", billID)
+	// Sum in Go with utils.Money - SUM(CAST(amount_pln AS REAL)) accumulates float error
+	var amounts []utils.Money
+	err := conn(ctx, r.db).SelectContext(ctx, &amounts, "SELECT amount_pln FROM payments WHERE bill_id = ?", billID)
 	if err != nil {
 		return "0", err
 	}
-	if !sum.Valid || sum.String == "" {
-		return "0", nil
-	}
-	return sum.String, nil
+	return utils.SumMoney(amounts...).String(), nil
 }
 
 func rowToPayment(row *PaymentRow) *models.Payment {
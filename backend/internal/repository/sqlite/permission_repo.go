@@ -34,14 +34,14 @@ func (r *PermissionRepository) Create(ctx context.Context, permission *models.Pe
 	id := uuid.New().String()
 
 	query := `INSERT INTO permissions (id, name, description, category) VALUES (?, ?, ?, ?)`
-	_, err := r.db.ExecContext(ctx, query, id, permission.Name, permission.Description, permission.Category)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, id, permission.Name, permission.Description, permission.Category)
 	return err
 }
 
 // GetByName retrieves a permission by name
 func (r *PermissionRepository) GetByName(ctx context.Context, name string) (*models.Permission, error) {
 	var row PermissionRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM permissions WHERE name = ?", name)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM permissions WHERE name = ?", name)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -54,7 +54,7 @@ func (r *PermissionRepository) GetByName(ctx context.Context, name string) (*mod
 // List returns all permissions
 func (r *PermissionRepository) List(ctx context.Context) ([]models.Permission, error) {
 	var rows []PermissionRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM permissions ORDER BY category, name")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM permissions ORDER BY category, name")
 	if err != nil {
 		return nil, err
 	}
@@ -64,7 +64,7 @@ func (r *PermissionRepository) List(ctx context.Context) ([]models.Permission, e
 // ListByCategory returns permissions by category
 func (r *PermissionRepository) ListByCategory(ctx context.Context, category string) ([]models.Permission, error) {
 	var rows []PermissionRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM permissions WHERE category = ? ORDER BY name", category)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM permissions WHERE category = ? ORDER BY name", category)
 	if err != nil {
 		return nil, err
 	}
@@ -117,14 +117,14 @@ func (r *RoleRepository) Create(ctx context.Context, role *models.Role) error {
 	permsJSON, _ := json.Marshal(role.Permissions)
 
 	query := `INSERT INTO roles (id, name, display_name, is_system, permissions, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
-	_, err := r.db.ExecContext(ctx, query, id, role.Name, role.DisplayName, boolToInt(role.IsSystem), string(permsJSON), now, now)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, id, role.Name, role.DisplayName, boolToInt(role.IsSystem), string(permsJSON), now, now)
 	return err
 }
 
 // GetByID retrieves a role by ID
 func (r *RoleRepository) GetByID(ctx context.Context, id string) (*models.Role, error) {
 	var row RoleRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM roles WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM roles WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -137,7 +137,7 @@ func (r *RoleRepository) GetByID(ctx context.Context, id string) (*models.Role,
 // GetByName retrieves a role by name
 func (r *RoleRepository) GetByName(ctx context.Context, name string) (*models.Role, error) {
 	var row RoleRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM roles WHERE name = ?", name)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM roles WHERE name = ?", name)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -153,20 +153,20 @@ func (r *RoleRepository) Update(ctx context.Context, role *models.Role) error {
 	permsJSON, _ := json.Marshal(role.Permissions)
 
 	query := `UPDATE roles SET name = ?, display_name = ?, is_system = ?, permissions = ?, updated_at = ? WHERE id = ?`
-	_, err := r.db.ExecContext(ctx, query, role.Name, role.DisplayName, boolToInt(role.IsSystem), string(permsJSON), now, role.ID)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, role.Name, role.DisplayName, boolToInt(role.IsSystem), string(permsJSON), now, role.ID)
 	return err
 }
 
 // Delete deletes a role
 func (r *RoleRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM roles WHERE id = ?", id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM roles WHERE id = ?", id)
 	return err
 }
 
 // List returns all roles
 func (r *RoleRepository) List(ctx context.Context) ([]models.Role, error) {
 	var rows []RoleRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM roles ORDER BY name")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM roles ORDER BY name")
 	if err != nil {
 		return nil, err
 	}
@@ -16,6 +16,7 @@ type RecurringBillTemplateRow struct {
 	CustomType      string  `db:"custom_type"`
 	Frequency       string  `db:"frequency"`
 	Amount          string  `db:"amount"`
+	Currency        string  `db:"currency"`
 	DayOfMonth      int     `db:"day_of_month"`
 	StartDate       string  `db:"start_date"`
 	Notes           *string `db:"notes"`
@@ -54,16 +55,17 @@ func (r *RecurringBillTemplateRepository) Create(ctx context.Context, template *
 	}
 
 	query := `
-		INSERT INTO recurring_bill_templates (id, custom_type, frequency, amount, day_of_month, start_date, notes,
+		INSERT INTO recurring_bill_templates (id, custom_type, frequency, amount, currency, day_of_month, start_date, notes,
 			is_active, current_bill_id, next_due_date, last_generated_at, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		id,
 		template.CustomType,
 		template.Frequency,
 		template.Amount,
+		currencyOrDefault(template.Currency),
 		template.DayOfMonth,
 		template.StartDate.UTC().Format(time.RFC3339),
 		template.Notes,
@@ -80,7 +82,7 @@ func (r *RecurringBillTemplateRepository) Create(ctx context.Context, template *
 // GetByID retrieves a recurring bill template by ID
 func (r *RecurringBillTemplateRepository) GetByID(ctx context.Context, id string) (*models.RecurringBillTemplate, error) {
 	var row RecurringBillTemplateRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM recurring_bill_templates WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM recurring_bill_templates WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -102,15 +104,16 @@ func (r *RecurringBillTemplateRepository) Update(ctx context.Context, template *
 
 	query := `
 		UPDATE recurring_bill_templates SET
-			custom_type = ?, frequency = ?, amount = ?, day_of_month = ?, start_date = ?, notes = ?,
+			custom_type = ?, frequency = ?, amount = ?, currency = ?, day_of_month = ?, start_date = ?, notes = ?,
 			is_active = ?, current_bill_id = ?, next_due_date = ?, last_generated_at = ?, updated_at = ?
 		WHERE id = ?
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		template.CustomType,
 		template.Frequency,
 		template.Amount,
+		currencyOrDefault(template.Currency),
 		template.DayOfMonth,
 		template.StartDate.UTC().Format(time.RFC3339),
 		template.Notes,
@@ -126,14 +129,14 @@ func (r *RecurringBillTemplateRepository) Update(ctx context.Context, template *
 
 // Delete deletes a recurring bill template
 func (r *RecurringBillTemplateRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM recurring_bill_templates WHERE id = ?", id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM recurring_bill_templates WHERE id = ?", id)
 	return err
 }
 
 // List returns all recurring bill templates
 func (r *RecurringBillTemplateRepository) List(ctx context.Context) ([]models.RecurringBillTemplate, error) {
 	var rows []RecurringBillTemplateRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM recurring_bill_templates ORDER BY custom_type")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM recurring_bill_templates ORDER BY custom_type")
 	if err != nil {
 		return nil, err
 	}
@@ -143,7 +146,7 @@ func (r *RecurringBillTemplateRepository) List(ctx context.Context) ([]models.Re
 // ListActive returns all active recurring bill templates
 func (r *RecurringBillTemplateRepository) ListActive(ctx context.Context) ([]models.RecurringBillTemplate, error) {
 	var rows []RecurringBillTemplateRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM recurring_bill_templates WHERE is_active = 1 ORDER BY custom_type")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM recurring_bill_templates WHERE is_active = 1 ORDER BY custom_type")
 	if err != nil {
 		return nil, err
 	}
@@ -153,7 +156,7 @@ func (r *RecurringBillTemplateRepository) ListActive(ctx context.Context) ([]mod
 // ListDueBefore returns templates due before the given date
 func (r *RecurringBillTemplateRepository) ListDueBefore(ctx context.Context, date time.Time) ([]models.RecurringBillTemplate, error) {
 	var rows []RecurringBillTemplateRow
-	err := r.db.SelectContext(ctx, &rows,
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
 		"SELECT * FROM recurring_bill_templates WHERE is_active = 1 AND next_due_date <= ? ORDER BY next_due_date",
 		date.UTC().Format(time.RFC3339))
 	if err != nil {
@@ -168,6 +171,7 @@ func rowToRecurringBillTemplate(row *RecurringBillTemplateRow) *models.Recurring
 		CustomType:    row.CustomType,
 		Frequency:     row.Frequency,
 		Amount:        row.Amount,
+		Currency:      row.Currency,
 		DayOfMonth:    row.DayOfMonth,
 		Notes:         row.Notes,
 		IsActive:      intToBool(row.IsActive),
@@ -228,7 +232,7 @@ func (r *RecurringBillAllocationRepository) Create(ctx context.Context, template
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		id,
 		templateID,
 		alloc.SubjectType,
@@ -245,7 +249,7 @@ func (r *RecurringBillAllocationRepository) Create(ctx context.Context, template
 // GetByTemplateID returns allocations for a template
 func (r *RecurringBillAllocationRepository) GetByTemplateID(ctx context.Context, templateID string) ([]models.RecurringBillAllocation, error) {
 	var rows []RecurringBillAllocationRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM recurring_bill_allocations WHERE template_id = ?", templateID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM recurring_bill_allocations WHERE template_id = ?", templateID)
 	if err != nil {
 		return nil, err
 	}
@@ -254,7 +258,7 @@ func (r *RecurringBillAllocationRepository) GetByTemplateID(ctx context.Context,
 
 // DeleteByTemplateID deletes all allocations for a template
 func (r *RecurringBillAllocationRepository) DeleteByTemplateID(ctx context.Context, templateID string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM recurring_bill_allocations WHERE template_id = ?", templateID)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM recurring_bill_allocations WHERE template_id = ?", templateID)
 	return err
 }
 
@@ -278,7 +282,7 @@ func (r *RecurringBillAllocationRepository) ReplaceForTemplate(ctx context.Conte
 // List returns all recurring bill allocations
 func (r *RecurringBillAllocationRepository) List(ctx context.Context) ([]models.RecurringBillAllocation, error) {
 	var rows []RecurringBillAllocationRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM recurring_bill_allocations")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM recurring_bill_allocations")
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sainaif/holy-home/internal/models"
+)
+
+// ResidencyRow represents a user residency row in SQLite
+type ResidencyRow struct {
+	ID         string  `db:"id"`
+	UserID     string  `db:"user_id"`
+	MovedInAt  string  `db:"moved_in_at"`
+	MovedOutAt *string `db:"moved_out_at"`
+	CreatedAt  string  `db:"created_at"`
+}
+
+// ResidencyRepository implements repository.ResidencyRepository for SQLite
+type ResidencyRepository struct {
+	db *sqlx.DB
+}
+
+// NewResidencyRepository creates a new SQLite residency repository
+func NewResidencyRepository(db *sqlx.DB) *ResidencyRepository {
+	return &ResidencyRepository{db: db}
+}
+
+// Create creates a new residency interval
+func (r *ResidencyRepository) Create(ctx context.Context, residency *models.Residency) error {
+	if residency.ID == "" {
+		residency.ID = uuid.New().String()
+	}
+	if residency.CreatedAt.IsZero() {
+		residency.CreatedAt = time.Now()
+	}
+
+	var movedOutAt *string
+	if residency.MovedOutAt != nil {
+		s := residency.MovedOutAt.UTC().Format(time.RFC3339)
+		movedOutAt = &s
+	}
+
+	query := `
+		INSERT INTO user_residencies (id, user_id, moved_in_at, moved_out_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		residency.ID,
+		residency.UserID,
+		residency.MovedInAt.UTC().Format(time.RFC3339),
+		movedOutAt,
+		residency.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// GetByID retrieves a residency interval by ID
+func (r *ResidencyRepository) GetByID(ctx context.Context, id string) (*models.Residency, error) {
+	var row ResidencyRow
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM user_residencies WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToResidency(&row), nil
+}
+
+// Update changes the dates of a residency interval
+func (r *ResidencyRepository) Update(ctx context.Context, residency *models.Residency) error {
+	var movedOutAt *string
+	if residency.MovedOutAt != nil {
+		s := residency.MovedOutAt.UTC().Format(time.RFC3339)
+		movedOutAt = &s
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE user_residencies SET moved_in_at = ?, moved_out_at = ? WHERE id = ?",
+		residency.MovedInAt.UTC().Format(time.RFC3339), movedOutAt, residency.ID)
+	return err
+}
+
+// Delete deletes a residency interval
+func (r *ResidencyRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM user_residencies WHERE id = ?", id)
+	return err
+}
+
+// ListByUserID returns a user's residency intervals, oldest first
+func (r *ResidencyRepository) ListByUserID(ctx context.Context, userID string) ([]models.Residency, error) {
+	var rows []ResidencyRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM user_residencies WHERE user_id = ? ORDER BY moved_in_at", userID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToResidencies(rows), nil
+}
+
+// List returns all residency intervals
+func (r *ResidencyRepository) List(ctx context.Context) ([]models.Residency, error) {
+	var rows []ResidencyRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM user_residencies ORDER BY user_id, moved_in_at")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToResidencies(rows), nil
+}
+
+func rowToResidency(row *ResidencyRow) *models.Residency {
+	residency := &models.Residency{
+		ID:     row.ID,
+		UserID: row.UserID,
+	}
+	residency.MovedInAt, _ = time.Parse(time.RFC3339, row.MovedInAt)
+	residency.CreatedAt, _ = time.Parse(time.RFC3339, row.CreatedAt)
+	if row.MovedOutAt != nil {
+		t, _ := time.Parse(time.RFC3339, *row.MovedOutAt)
+		residency.MovedOutAt = &t
+	}
+	return residency
+}
+
+func rowsToResidencies(rows []ResidencyRow) []models.Residency {
+	residencies := make([]models.Residency, len(rows))
+	for i, row := range rows {
+		residencies[i] = *rowToResidency(&row)
+	}
+	return residencies
+}
@@ -41,7 +41,7 @@ func (r *SentReminderRepository) Create(ctx context.Context, reminder *models.Se
 		INSERT INTO sent_reminders (id, user_id, resource_type, resource_id, reminder_type, sent_at)
 		VALUES (?, ?, ?, ?, ?, ?)
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		reminder.ID,
 		reminder.UserID,
 		reminder.ResourceType,
@@ -59,7 +59,7 @@ func (r *SentReminderRepository) Exists(ctx context.Context, userID, resourceTyp
 		SELECT COUNT(*) FROM sent_reminders
 		WHERE user_id = ? AND resource_type = ? AND resource_id = ? AND reminder_type = ?
 	`
-	err := r.db.GetContext(ctx, &count, query, userID, resourceType, resourceID, reminderType)
+	err := conn(ctx, r.db).GetContext(ctx, &count, query, userID, resourceType, resourceID, reminderType)
 	if err != nil {
 		return false, err
 	}
@@ -73,7 +73,7 @@ func (r *SentReminderRepository) CountRecentByUserID(ctx context.Context, userID
 		SELECT COUNT(*) FROM sent_reminders
 		WHERE user_id = ? AND sent_at >= ?
 	`
-	err := r.db.GetContext(ctx, &count, query, userID, since.UTC().Format(time.RFC3339))
+	err := conn(ctx, r.db).GetContext(ctx, &count, query, userID, since.UTC().Format(time.RFC3339))
 	if err != nil {
 		return 0, err
 	}
@@ -82,14 +82,14 @@ func (r *SentReminderRepository) CountRecentByUserID(ctx context.Context, userID
 
 // DeleteOlderThan deletes reminders sent before the given time
 func (r *SentReminderRepository) DeleteOlderThan(ctx context.Context, before time.Time) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM sent_reminders WHERE sent_at < ?", before.UTC().Format(time.RFC3339))
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM sent_reminders WHERE sent_at < ?", before.UTC().Format(time.RFC3339))
 	return err
 }
 
 // List returns all sent reminders
 func (r *SentReminderRepository) List(ctx context.Context) ([]models.SentReminder, error) {
 	var rows []SentReminderRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM sent_reminders ORDER BY sent_at DESC")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM sent_reminders ORDER BY sent_at DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -99,7 +99,7 @@ func (r *SentReminderRepository) List(ctx context.Context) ([]models.SentReminde
 // GetByID retrieves a sent reminder by ID
 func (r *SentReminderRepository) GetByID(ctx context.Context, id string) (*models.SentReminder, error) {
 	var row SentReminderRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM sent_reminders WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM sent_reminders WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
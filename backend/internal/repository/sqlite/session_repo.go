@@ -42,7 +42,7 @@ func (r *SessionRepository) Create(ctx context.Context, session *models.Session)
 		INSERT INTO sessions (id, user_id, refresh_token, name, ip_address, user_agent, created_at, last_used_at, expires_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		id,
 		session.UserID,
 		session.RefreshToken,
@@ -59,7 +59,7 @@ func (r *SessionRepository) Create(ctx context.Context, session *models.Session)
 // GetByID retrieves a session by ID
 func (r *SessionRepository) GetByID(ctx context.Context, id string) (*models.Session, error) {
 	var row SessionRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM sessions WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM sessions WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -72,7 +72,7 @@ func (r *SessionRepository) GetByID(ctx context.Context, id string) (*models.Ses
 // GetByRefreshToken retrieves a session by refresh token hash
 func (r *SessionRepository) GetByRefreshToken(ctx context.Context, tokenHash string) (*models.Session, error) {
 	var row SessionRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM sessions WHERE refresh_token = ?", tokenHash)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM sessions WHERE refresh_token = ?", tokenHash)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -92,7 +92,7 @@ func (r *SessionRepository) Update(ctx context.Context, session *models.Session)
 			expires_at = ?
 		WHERE id = ?
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		session.RefreshToken,
 		session.Name,
 		session.LastUsedAt.UTC().Format(time.RFC3339),
@@ -104,27 +104,27 @@ func (r *SessionRepository) Update(ctx context.Context, session *models.Session)
 
 // Delete deletes a session
 func (r *SessionRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM sessions WHERE id = ?", id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM sessions WHERE id = ?", id)
 	return err
 }
 
 // DeleteByUserID deletes all sessions for a user
 func (r *SessionRepository) DeleteByUserID(ctx context.Context, userID string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userID)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM sessions WHERE user_id = ?", userID)
 	return err
 }
 
 // DeleteExpired deletes all expired sessions
 func (r *SessionRepository) DeleteExpired(ctx context.Context) error {
 	now := time.Now().UTC().Format(time.RFC3339)
-	_, err := r.db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at < ?", now)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM sessions WHERE expires_at < ?", now)
 	return err
 }
 
 // ListByUserID returns all sessions for a user
 func (r *SessionRepository) ListByUserID(ctx context.Context, userID string) ([]models.Session, error) {
 	var rows []SessionRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM sessions WHERE user_id = ? ORDER BY last_used_at DESC", userID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM sessions WHERE user_id = ? ORDER BY last_used_at DESC", userID)
 	if err != nil {
 		return nil, err
 	}
@@ -16,6 +16,7 @@ type AppSettingsRow struct {
 	DefaultLanguage          string `db:"default_language"`
 	DisableAutoDetect        int    `db:"disable_auto_detect"`
 	ReminderRateLimitPerHour int    `db:"reminder_rate_limit_per_hour"`
+	BaseCurrency             string `db:"base_currency"`
 	UpdatedAt                string `db:"updated_at"`
 }
 
@@ -32,7 +33,7 @@ func NewAppSettingsRepository(db *sqlx.DB) *AppSettingsRepository {
 // Get retrieves the app settings singleton
 func (r *AppSettingsRepository) Get(ctx context.Context) (*models.AppSettings, error) {
 	var row AppSettingsRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM app_settings WHERE id = 'singleton'")
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM app_settings WHERE id = 'singleton'")
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -47,21 +48,23 @@ func (r *AppSettingsRepository) Upsert(ctx context.Context, settings *models.App
 	now := time.Now().UTC().Format(time.RFC3339)
 
 	query := `
-		INSERT INTO app_settings (id, app_name, default_language, disable_auto_detect, reminder_rate_limit_per_hour, updated_at)
-		VALUES ('singleton', ?, ?, ?, ?, ?)
+		INSERT INTO app_settings (id, app_name, default_language, disable_auto_detect, reminder_rate_limit_per_hour, base_currency, updated_at)
+		VALUES ('singleton', ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			app_name = excluded.app_name,
 			default_language = excluded.default_language,
 			disable_auto_detect = excluded.disable_auto_detect,
 			reminder_rate_limit_per_hour = excluded.reminder_rate_limit_per_hour,
+			base_currency = excluded.base_currency,
 			updated_at = excluded.updated_at
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		settings.AppName,
 		settings.DefaultLanguage,
 		boolToInt(settings.DisableAutoDetect),
 		settings.ReminderRateLimitPerHour,
+		currencyOrDefault(settings.BaseCurrency),
 		now,
 	)
 	return err
@@ -74,6 +77,7 @@ func rowToAppSettings(row *AppSettingsRow) *models.AppSettings {
 		DefaultLanguage:          row.DefaultLanguage,
 		DisableAutoDetect:        intToBool(row.DisableAutoDetect),
 		ReminderRateLimitPerHour: row.ReminderRateLimitPerHour,
+		BaseCurrency:             row.BaseCurrency,
 	}
 	settings.UpdatedAt, _ = time.Parse(time.RFC3339, row.UpdatedAt)
 	return settings
@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sainaif/holy-home/internal/models"
+)
+
+// SettlementTransferRow represents a settle-up transfer row in SQLite
+type SettlementTransferRow struct {
+	ID           string `db:"id"`
+	SettlementID string `db:"settlement_id"`
+	FromType     string `db:"from_type"`
+	FromID       string `db:"from_id"`
+	ToType       string `db:"to_type"`
+	ToID         string `db:"to_id"`
+	Amount       string `db:"amount"`
+	Currency     string `db:"currency"`
+	CreatedAt    string `db:"created_at"`
+}
+
+// SettlementTransferRepository implements repository.SettlementTransferRepository for SQLite
+type SettlementTransferRepository struct {
+	db *sqlx.DB
+}
+
+// NewSettlementTransferRepository creates a new SQLite settlement transfer repository
+func NewSettlementTransferRepository(db *sqlx.DB) *SettlementTransferRepository {
+	return &SettlementTransferRepository{db: db}
+}
+
+// Create records a transfer of a settle-up
+func (r *SettlementTransferRepository) Create(ctx context.Context, transfer *models.SettlementTransfer) error {
+	if transfer.ID == "" {
+		transfer.ID = uuid.New().String()
+	}
+	if transfer.CreatedAt.IsZero() {
+		transfer.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO settlement_transfers (id, settlement_id, from_type, from_id, to_type, to_id, amount, currency, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		transfer.ID,
+		transfer.SettlementID,
+		transfer.FromType,
+		transfer.FromID,
+		transfer.ToType,
+		transfer.ToID,
+		transfer.Amount,
+		transfer.Currency,
+		transfer.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// ListBySettlementID returns the transfers of one settle-up
+func (r *SettlementTransferRepository) ListBySettlementID(ctx context.Context, settlementID string) ([]models.SettlementTransfer, error) {
+	var rows []SettlementTransferRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM settlement_transfers WHERE settlement_id = ? ORDER BY rowid", settlementID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToSettlementTransfers(rows), nil
+}
+
+// List returns all settle-up transfers, newest first
+func (r *SettlementTransferRepository) List(ctx context.Context) ([]models.SettlementTransfer, error) {
+	var rows []SettlementTransferRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM settlement_transfers ORDER BY created_at DESC, rowid")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToSettlementTransfers(rows), nil
+}
+
+func rowsToSettlementTransfers(rows []SettlementTransferRow) []models.SettlementTransfer {
+	transfers := make([]models.SettlementTransfer, len(rows))
+	for i, row := range rows {
+		transfers[i] = models.SettlementTransfer{
+			ID:           row.ID,
+			SettlementID: row.SettlementID,
+			FromType:     row.FromType,
+			FromID:       row.FromID,
+			ToType:       row.ToType,
+			ToID:         row.ToID,
+			Amount:       row.Amount,
+			Currency:     row.Currency,
+		}
+		transfers[i].CreatedAt, _ = time.Parse(time.RFC3339, row.CreatedAt)
+	}
+	return transfers
+}
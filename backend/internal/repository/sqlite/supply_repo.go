@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/utils"
 )
 
 // SupplySettingsRow represents supply settings row in SQLite
@@ -35,7 +36,7 @@ func NewSupplySettingsRepository(db *sqlx.DB) *SupplySettingsRepository {
 // Get retrieves the supply settings singleton
 func (r *SupplySettingsRepository) Get(ctx context.Context) (*models.SupplySettings, error) {
 	var row SupplySettingsRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM supply_settings WHERE id = 'singleton'")
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM supply_settings WHERE id = 'singleton'")
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -62,7 +63,7 @@ func (r *SupplySettingsRepository) Upsert(ctx context.Context, settings *models.
 			updated_at = excluded.updated_at
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		settings.WeeklyContributionPLN,
 		settings.ContributionDay,
 		settings.CurrentBudgetPLN,
@@ -102,6 +103,7 @@ type SupplyItemRow struct {
 	LastRestockedAt       *string `db:"last_restocked_at"`
 	LastRestockedByUserID *string `db:"last_restocked_by_user_id"`
 	LastRestockAmountPLN  *string `db:"last_restock_amount_pln"`
+	LastRestockCurrency   *string `db:"last_restock_currency"`
 	NeedsRefund           int     `db:"needs_refund"`
 	Notes                 *string `db:"notes"`
 }
@@ -118,12 +120,14 @@ func NewSupplyItemRepository(db *sqlx.DB) *SupplyItemRepository {
 
 // Create creates a new supply item
 func (r *SupplyItemRepository) Create(ctx context.Context, item *models.SupplyItem) error {
-	id := uuid.New().String()
+	if item.ID == "" {
+		item.ID = uuid.New().String()
+	}
 
 	query := `
 		INSERT INTO supply_items (id, name, category, current_quantity, min_quantity, unit, priority,
-			added_by_user_id, added_at, last_restocked_at, last_restocked_by_user_id, last_restock_amount_pln, needs_refund, notes)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			added_by_user_id, added_at, last_restocked_at, last_restocked_by_user_id, last_restock_amount_pln, last_restock_currency, needs_refund, notes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	var lastRestockedAt *string
@@ -132,8 +136,8 @@ func (r *SupplyItemRepository) Create(ctx context.Context, item *models.SupplyIt
 		lastRestockedAt = &lra
 	}
 
-	_, err := r.db.ExecContext(ctx, query,
-		id,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		item.ID,
 		item.Name,
 		item.Category,
 		item.CurrentQuantity,
@@ -145,6 +149,7 @@ func (r *SupplyItemRepository) Create(ctx context.Context, item *models.SupplyIt
 		lastRestockedAt,
 		item.LastRestockedByUserID,
 		item.LastRestockAmountPLN,
+		item.LastRestockCurrency,
 		boolToInt(item.NeedsRefund),
 		item.Notes,
 	)
@@ -154,7 +159,7 @@ func (r *SupplyItemRepository) Create(ctx context.Context, item *models.SupplyIt
 // GetByID retrieves a supply item by ID
 func (r *SupplyItemRepository) GetByID(ctx context.Context, id string) (*models.SupplyItem, error) {
 	var row SupplyItemRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM supply_items WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM supply_items WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -175,11 +180,11 @@ func (r *SupplyItemRepository) Update(ctx context.Context, item *models.SupplyIt
 	query := `
 		UPDATE supply_items SET
 			name = ?, category = ?, current_quantity = ?, min_quantity = ?, unit = ?, priority = ?,
-			last_restocked_at = ?, last_restocked_by_user_id = ?, last_restock_amount_pln = ?, needs_refund = ?, notes = ?
+			last_restocked_at = ?, last_restocked_by_user_id = ?, last_restock_amount_pln = ?, last_restock_currency = ?, needs_refund = ?, notes = ?
 		WHERE id = ?
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		item.Name,
 		item.Category,
 		item.CurrentQuantity,
@@ -189,6 +194,7 @@ func (r *SupplyItemRepository) Update(ctx context.Context, item *models.SupplyIt
 		lastRestockedAt,
 		item.LastRestockedByUserID,
 		item.LastRestockAmountPLN,
+		item.LastRestockCurrency,
 		boolToInt(item.NeedsRefund),
 		item.Notes,
 		item.ID,
@@ -198,14 +204,14 @@ func (r *SupplyItemRepository) Update(ctx context.Context, item *models.SupplyIt
 
 // Delete deletes a supply item
 func (r *SupplyItemRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM supply_items WHERE id = ?", id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM supply_items WHERE id = ?", id)
 	return err
 }
 
 // List returns all supply items
 func (r *SupplyItemRepository) List(ctx context.Context) ([]models.SupplyItem, error) {
 	var rows []SupplyItemRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM supply_items ORDER BY name")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM supply_items ORDER BY name")
 	if err != nil {
 		return nil, err
 	}
@@ -215,7 +221,7 @@ func (r *SupplyItemRepository) List(ctx context.Context) ([]models.SupplyItem, e
 // ListByCategory returns supply items by category
 func (r *SupplyItemRepository) ListByCategory(ctx context.Context, category string) ([]models.SupplyItem, error) {
 	var rows []SupplyItemRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM supply_items WHERE category = ? ORDER BY name", category)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM supply_items WHERE category = ? ORDER BY name", category)
 	if err != nil {
 		return nil, err
 	}
@@ -225,7 +231,7 @@ func (r *SupplyItemRepository) ListByCategory(ctx context.Context, category stri
 // ListLowStock returns items with low stock
 func (r *SupplyItemRepository) ListLowStock(ctx context.Context) ([]models.SupplyItem, error) {
 	var rows []SupplyItemRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM supply_items WHERE current_quantity <= min_quantity ORDER BY priority DESC, name")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM supply_items WHERE current_quantity <= min_quantity ORDER BY priority DESC, name")
 	if err != nil {
 		return nil, err
 	}
@@ -244,6 +250,7 @@ func rowToSupplyItem(row *SupplyItemRow) *models.SupplyItem {
 		AddedByUserID:         row.AddedByUserID,
 		LastRestockedByUserID: row.LastRestockedByUserID,
 		LastRestockAmountPLN:  row.LastRestockAmountPLN,
+		LastRestockCurrency:   row.LastRestockCurrency,
 		NeedsRefund:           intToBool(row.NeedsRefund),
 		Notes:                 row.Notes,
 	}
@@ -297,7 +304,7 @@ func (r *SupplyContributionRepository) Create(ctx context.Context, contribution
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		id,
 		contribution.UserID,
 		contribution.AmountPLN,
@@ -313,7 +320,7 @@ func (r *SupplyContributionRepository) Create(ctx context.Context, contribution
 // GetByID retrieves a supply contribution by ID
 func (r *SupplyContributionRepository) GetByID(ctx context.Context, id string) (*models.SupplyContribution, error) {
 	var row SupplyContributionRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM supply_contributions WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM supply_contributions WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -325,14 +332,14 @@ func (r *SupplyContributionRepository) GetByID(ctx context.Context, id string) (
 
 // Delete deletes a supply contribution
 func (r *SupplyContributionRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM supply_contributions WHERE id = ?", id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM supply_contributions WHERE id = ?", id)
 	return err
 }
 
 // List returns all supply contributions
 func (r *SupplyContributionRepository) List(ctx context.Context) ([]models.SupplyContribution, error) {
 	var rows []SupplyContributionRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM supply_contributions ORDER BY period_start DESC")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM supply_contributions ORDER BY period_start DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -342,7 +349,7 @@ func (r *SupplyContributionRepository) List(ctx context.Context) ([]models.Suppl
 // ListByUserID returns contributions by user
 func (r *SupplyContributionRepository) ListByUserID(ctx context.Context, userID string) ([]models.SupplyContribution, error) {
 	var rows []SupplyContributionRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM supply_contributions WHERE user_id = ? ORDER BY period_start DESC", userID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM supply_contributions WHERE user_id = ? ORDER BY period_start DESC", userID)
 	if err != nil {
 		return nil, err
 	}
@@ -352,7 +359,7 @@ func (r *SupplyContributionRepository) ListByUserID(ctx context.Context, userID
 // ListByPeriod returns contributions within a period
 func (r *SupplyContributionRepository) ListByPeriod(ctx context.Context, start, end time.Time) ([]models.SupplyContribution, error) {
 	var rows []SupplyContributionRow
-	err := r.db.SelectContext(ctx, &rows,
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
 		"SELECT * FROM supply_contributions WHERE period_start >= ? AND period_end <= ? ORDER BY period_start DESC",
 		start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339))
 	if err != nil {
@@ -363,15 +370,12 @@ func (r *SupplyContributionRepository) ListByPeriod(ctx context.Context, start,
 
 // SumByUserID returns total contributions by user
 func (r *SupplyContributionRepository) SumByUserID(ctx context.Context, userID string) (string, error) {
-	var sum sql.NullString
-	err := r.db.GetContext(ctx, &sum, "SELECT COALESCE(SUM(CAST(amount_pln AS REAL)), 0) FROM supply_contributions WHERE user_id = ?", userID)
+	var amounts []utils.Money
+	err := conn(ctx, r.db).SelectContext(ctx, &amounts, "SELECT amount_pln FROM supply_contributions WHERE user_id = ?", userID)
 	if err != nil {
 		return "0", err
 	}
-	if !sum.Valid || sum.String == "" {
-		return "0", nil
-	}
-	return sum.String, nil
+	return utils.SumMoney(amounts...).String(), nil
 }
 
 func rowToSupplyContribution(row *SupplyContributionRow) *models.SupplyContribution {
@@ -422,16 +426,20 @@ func NewSupplyItemHistoryRepository(db *sqlx.DB) *SupplyItemHistoryRepository {
 
 // Create creates a new supply item history entry
 func (r *SupplyItemHistoryRepository) Create(ctx context.Context, history *models.SupplyItemHistory) error {
-	id := uuid.New().String()
-	now := time.Now().UTC().Format(time.RFC3339)
+	if history.ID == "" {
+		history.ID = uuid.New().String()
+	}
+	if history.CreatedAt.IsZero() {
+		history.CreatedAt = time.Now()
+	}
 
 	query := `
 		INSERT INTO supply_item_history (id, supply_item_id, user_id, action, quantity_delta, old_quantity, new_quantity, cost_pln, notes, created_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
-		id,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		history.ID,
 		history.SupplyItemID,
 		history.UserID,
 		history.Action,
@@ -440,15 +448,38 @@ func (r *SupplyItemHistoryRepository) Create(ctx context.Context, history *model
 		history.NewQuantity,
 		history.CostPLN,
 		history.Notes,
-		now,
+		history.CreatedAt.UTC().Format(time.RFC3339),
 	)
 	return err
 }
 
+// GetByID retrieves a supply item history entry by ID
+func (r *SupplyItemHistoryRepository) GetByID(ctx context.Context, id string) (*models.SupplyItemHistory, error) {
+	var row SupplyItemHistoryRow
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM supply_item_history WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToSupplyItemHistory(&row), nil
+}
+
+// List returns the history of all supply items
+func (r *SupplyItemHistoryRepository) List(ctx context.Context) ([]models.SupplyItemHistory, error) {
+	var rows []SupplyItemHistoryRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM supply_item_history ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToSupplyItemHistories(rows), nil
+}
+
 // ListBySupplyItemID returns history for a supply item
 func (r *SupplyItemHistoryRepository) ListBySupplyItemID(ctx context.Context, supplyItemID string) ([]models.SupplyItemHistory, error) {
 	var rows []SupplyItemHistoryRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM supply_item_history WHERE supply_item_id = ? ORDER BY created_at DESC", supplyItemID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM supply_item_history WHERE supply_item_id = ? ORDER BY created_at DESC", supplyItemID)
 	if err != nil {
 		return nil, err
 	}
@@ -458,7 +489,7 @@ func (r *SupplyItemHistoryRepository) ListBySupplyItemID(ctx context.Context, su
 // ListByUserID returns history by user
 func (r *SupplyItemHistoryRepository) ListByUserID(ctx context.Context, userID string) ([]models.SupplyItemHistory, error) {
 	var rows []SupplyItemHistoryRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM supply_item_history WHERE user_id = ? ORDER BY created_at DESC", userID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM supply_item_history WHERE user_id = ? ORDER BY created_at DESC", userID)
 	if err != nil {
 		return nil, err
 	}
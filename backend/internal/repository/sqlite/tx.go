@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type txKey struct{}
+
+// afterCommitKey carries the callbacks waiting for the outermost transaction to commit
+type afterCommitKey struct{}
+
+// querier is the subset of sqlx shared by *sqlx.DB and *sqlx.Tx that repositories use
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// conn returns the transaction carried by ctx, or db when the call is not part of one.
+// The pool is limited to a single connection, so a repository that bypassed an open
+// transaction would block until it finished.
+func conn(ctx context.Context, db *sqlx.DB) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// TxManager implements repository.TxManager on top of a SQLite connection
+type TxManager struct {
+	db *sqlx.DB
+}
+
+func NewTxManager(db *sqlx.DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+// WithinTx runs fn in a transaction, joining the one already carried by ctx if there is any
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	var afterCommit []func(ctx context.Context)
+	txCtx := context.WithValue(context.WithValue(ctx, txKey{}, tx), afterCommitKey{}, &afterCommit)
+	if err := fn(txCtx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Printf("[TX] Rollback failed: %v", rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Callbacks get the caller's context, so their own writes start a new transaction
+	for _, callback := range afterCommit {
+		callback(ctx)
+	}
+	return nil
+}
+
+// AfterCommit runs fn once the outermost transaction carried by ctx is committed, or right away
+// when ctx carries none
+func (m *TxManager) AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if afterCommit, ok := ctx.Value(afterCommitKey{}).(*[]func(ctx context.Context)); ok {
+		*afterCommit = append(*afterCommit, fn)
+		return
+	}
+	fn(ctx)
+}
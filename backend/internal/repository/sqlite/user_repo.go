@@ -56,7 +56,7 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		id,
 		strings.ToLower(user.Email),
 		username,
@@ -76,7 +76,7 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 // GetByID retrieves a user by ID
 func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
 	var row UserRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM users WHERE id = ?", id)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM users WHERE id = ?", id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -89,7 +89,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User,
 // GetByEmail retrieves a user by email
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	var row UserRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM users WHERE LOWER(email) = LOWER(?)", email)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM users WHERE LOWER(email) = LOWER(?)", email)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -102,7 +102,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 // GetByUsername retrieves a user by username
 func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	var row UserRow
-	err := r.db.GetContext(ctx, &row, "SELECT * FROM users WHERE LOWER(username) = LOWER(?)", username)
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM users WHERE LOWER(username) = LOWER(?)", username)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -115,7 +115,7 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*m
 // GetByEmailOrUsername retrieves a user by email or username
 func (r *UserRepository) GetByEmailOrUsername(ctx context.Context, identifier string) (*models.User, error) {
 	var row UserRow
-	err := r.db.GetContext(ctx, &row,
+	err := conn(ctx, r.db).GetContext(ctx, &row,
 		"SELECT * FROM users WHERE LOWER(email) = LOWER(?) OR LOWER(username) = LOWER(?)",
 		identifier, identifier)
 	if err == sql.ErrNoRows {
@@ -153,7 +153,7 @@ func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 		WHERE id = ?
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
 		strings.ToLower(user.Email),
 		username,
 		user.Name,
@@ -171,14 +171,14 @@ func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 
 // Delete deletes a user
 func (r *UserRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE id = ?", id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM users WHERE id = ?", id)
 	return err
 }
 
 // List returns all users
 func (r *UserRepository) List(ctx context.Context) ([]models.User, error) {
 	var rows []UserRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM users ORDER BY created_at DESC")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM users ORDER BY created_at DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -188,7 +188,7 @@ func (r *UserRepository) List(ctx context.Context) ([]models.User, error) {
 // ListActive returns all active users
 func (r *UserRepository) ListActive(ctx context.Context) ([]models.User, error) {
 	var rows []UserRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM users WHERE is_active = 1 ORDER BY name")
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM users WHERE is_active = 1 ORDER BY name")
 	if err != nil {
 		return nil, err
 	}
@@ -198,7 +198,7 @@ func (r *UserRepository) ListActive(ctx context.Context) ([]models.User, error)
 // ListByGroupID returns users in a specific group
 func (r *UserRepository) ListByGroupID(ctx context.Context, groupID string) ([]models.User, error) {
 	var rows []UserRow
-	err := r.db.SelectContext(ctx, &rows, "SELECT * FROM users WHERE group_id = ?", groupID)
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, "SELECT * FROM users WHERE group_id = ?", groupID)
 	if err != nil {
 		return nil, err
 	}
@@ -207,13 +207,13 @@ func (r *UserRepository) ListByGroupID(ctx context.Context, groupID string) ([]m
 
 // UpdatePassword updates a user's password
 func (r *UserRepository) UpdatePassword(ctx context.Context, id, passwordHash string) error {
-	_, err := r.db.ExecContext(ctx, "UPDATE users SET password_hash = ? WHERE id = ?", passwordHash, id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE users SET password_hash = ? WHERE id = ?", passwordHash, id)
 	return err
 }
 
 // SetMustChangePassword sets the must_change_password flag
 func (r *UserRepository) SetMustChangePassword(ctx context.Context, id string, must bool) error {
-	_, err := r.db.ExecContext(ctx, "UPDATE users SET must_change_password = ? WHERE id = ?", boolToInt(must), id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE users SET must_change_password = ? WHERE id = ?", boolToInt(must), id)
 	return err
 }
 
@@ -223,7 +223,7 @@ func (r *UserRepository) UpdateTOTPSecret(ctx context.Context, id, secret string
 	if secret != "" {
 		totpSecret = &secret
 	}
-	_, err := r.db.ExecContext(ctx, "UPDATE users SET totp_secret = ? WHERE id = ?", totpSecret, id)
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE users SET totp_secret = ? WHERE id = ?", totpSecret, id)
 	return err
 }
 
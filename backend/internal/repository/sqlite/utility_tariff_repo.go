@@ -0,0 +1,137 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sainaif/holy-home/internal/models"
+)
+
+// UtilityTariffRow represents a utility tariff row in SQLite
+type UtilityTariffRow struct {
+	ID              string `db:"id"`
+	BillType        string `db:"bill_type"`
+	CustomType      string `db:"custom_type"`
+	TariffZone      string `db:"tariff_zone"`
+	UnitPrice       string `db:"unit_price"`
+	FixedFeeMonthly string `db:"fixed_fee_monthly"`
+	ValidFrom       string `db:"valid_from"`
+	CreatedAt       string `db:"created_at"`
+}
+
+// UtilityTariffRepository implements repository.UtilityTariffRepository for SQLite
+type UtilityTariffRepository struct {
+	db *sqlx.DB
+}
+
+// NewUtilityTariffRepository creates a new SQLite utility tariff repository
+func NewUtilityTariffRepository(db *sqlx.DB) *UtilityTariffRepository {
+	return &UtilityTariffRepository{db: db}
+}
+
+// Upsert creates a tariff or replaces the tariff already set for the same utility, zone and day
+func (r *UtilityTariffRepository) Upsert(ctx context.Context, tariff *models.UtilityTariff) error {
+	if tariff.ID == "" {
+		tariff.ID = uuid.New().String()
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	validFrom := tariff.ValidFrom.UTC().Format(time.RFC3339)
+
+	query := `
+		INSERT INTO utility_tariffs (id, bill_type, custom_type, tariff_zone, unit_price, fixed_fee_monthly, valid_from, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(bill_type, custom_type, tariff_zone, valid_from) DO UPDATE SET
+			unit_price = excluded.unit_price,
+			fixed_fee_monthly = excluded.fixed_fee_monthly,
+			created_at = excluded.created_at
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		tariff.ID,
+		tariff.BillType,
+		tariff.CustomType,
+		tariff.TariffZone,
+		tariff.UnitPrice,
+		tariff.FixedFeeMonthly,
+		validFrom,
+		now,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Pick up the existing ID when the tariff replaced an earlier one
+	return conn(ctx, r.db).GetContext(ctx, &tariff.ID,
+		"SELECT id FROM utility_tariffs WHERE bill_type = ? AND custom_type = ? AND tariff_zone = ? AND valid_from = ?",
+		tariff.BillType, tariff.CustomType, tariff.TariffZone, validFrom)
+}
+
+// GetByID retrieves a utility tariff by ID
+func (r *UtilityTariffRepository) GetByID(ctx context.Context, id string) (*models.UtilityTariff, error) {
+	var row UtilityTariffRow
+	err := conn(ctx, r.db).GetContext(ctx, &row, "SELECT * FROM utility_tariffs WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowToUtilityTariff(&row), nil
+}
+
+// ListByUtility returns the tariffs of one utility in all zones, newest first
+func (r *UtilityTariffRepository) ListByUtility(ctx context.Context, billType, customType string) ([]models.UtilityTariff, error) {
+	var rows []UtilityTariffRow
+	query := `
+		SELECT * FROM utility_tariffs
+		WHERE bill_type = ? AND custom_type = ?
+		ORDER BY valid_from DESC, tariff_zone
+	`
+	err := conn(ctx, r.db).SelectContext(ctx, &rows, query, billType, customType)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToUtilityTariffs(rows), nil
+}
+
+// Delete deletes a utility tariff
+func (r *UtilityTariffRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM utility_tariffs WHERE id = ?", id)
+	return err
+}
+
+// List returns all utility tariffs grouped by utility, newest first
+func (r *UtilityTariffRepository) List(ctx context.Context) ([]models.UtilityTariff, error) {
+	var rows []UtilityTariffRow
+	err := conn(ctx, r.db).SelectContext(ctx, &rows,
+		"SELECT * FROM utility_tariffs ORDER BY bill_type, custom_type, valid_from DESC, tariff_zone")
+	if err != nil {
+		return nil, err
+	}
+	return rowsToUtilityTariffs(rows), nil
+}
+
+func rowToUtilityTariff(row *UtilityTariffRow) *models.UtilityTariff {
+	tariff := &models.UtilityTariff{
+		ID:              row.ID,
+		BillType:        row.BillType,
+		CustomType:      row.CustomType,
+		TariffZone:      row.TariffZone,
+		UnitPrice:       row.UnitPrice,
+		FixedFeeMonthly: row.FixedFeeMonthly,
+	}
+	tariff.ValidFrom, _ = time.Parse(time.RFC3339, row.ValidFrom)
+	tariff.CreatedAt, _ = time.Parse(time.RFC3339, row.CreatedAt)
+	return tariff
+}
+
+func rowsToUtilityTariffs(rows []UtilityTariffRow) []models.UtilityTariff {
+	tariffs := make([]models.UtilityTariff, len(rows))
+	for i, row := range rows {
+		tariffs[i] = *rowToUtilityTariff(&row)
+	}
+	return tariffs
+}
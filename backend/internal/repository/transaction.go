@@ -0,0 +1,14 @@
+package repository
+
+import "context"
+
+// TxManager runs a unit of work inside a database transaction.
+// Repository calls made with the context passed to fn join the transaction;
+// it is committed when fn returns nil and rolled back otherwise.
+// Calling WithinTx again with that context reuses the outer transaction.
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+	// AfterCommit runs fn once the outermost transaction carried by ctx is committed, or right away
+	// when ctx carries none. It is dropped if the transaction is rolled back.
+	AfterCommit(ctx context.Context, fn func(ctx context.Context))
+}
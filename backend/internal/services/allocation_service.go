@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/sainaif/holy-home/internal/models"
 	"github.com/sainaif/holy-home/internal/repository"
@@ -11,218 +13,417 @@ import (
 )
 
 type AllocationService struct {
-	users        repository.UserRepository
-	groups       repository.GroupRepository
-	consumptions repository.ConsumptionRepository
-	allocations  repository.AllocationRepository
-	bills        repository.BillRepository
+	users           repository.UserRepository
+	residencies     repository.ResidencyRepository
+	billResidents   repository.BillResidentRepository
+	groups          repository.GroupRepository
+	consumptions    repository.ConsumptionRepository
+	allocations     repository.AllocationRepository
+	bills           repository.BillRepository
+	billSplits      repository.BillSplitRepository
+	billItems       repository.BillItemRepository
+	billTariffZones repository.BillTariffZoneRepository
+	meters          repository.MeterRepository
 }
 
 func NewAllocationService(
 	users repository.UserRepository,
+	residencies repository.ResidencyRepository,
+	billResidents repository.BillResidentRepository,
 	groups repository.GroupRepository,
 	consumptions repository.ConsumptionRepository,
 	allocations repository.AllocationRepository,
 	bills repository.BillRepository,
+	billSplits repository.BillSplitRepository,
+	billItems repository.BillItemRepository,
+	billTariffZones repository.BillTariffZoneRepository,
+	meters repository.MeterRepository,
 ) *AllocationService {
 	return &AllocationService{
-		users:        users,
-		groups:       groups,
-		consumptions: consumptions,
-		allocations:  allocations,
-		bills:        bills,
+		users:           users,
+		residencies:     residencies,
+		billResidents:   billResidents,
+		groups:          groups,
+		consumptions:    consumptions,
+		allocations:     allocations,
+		bills:           bills,
+		billSplits:      billSplits,
+		billItems:       billItems,
+		billTariffZones: billTariffZones,
+		meters:          meters,
 	}
 }
 
 // AllocationBreakdown represents cost breakdown per user/group
 type AllocationBreakdown struct {
-	SubjectID   string  `json:"subjectId"`
-	SubjectType string  `json:"subjectType"` // "user" or "group"
-	SubjectName string  `json:"subjectName"`
-	Weight      float64 `json:"weight"`
-	Amount      float64 `json:"amount"`
+	SubjectID   string      `json:"subjectId"`
+	SubjectType string      `json:"subjectType"` // "user" or "group"
+	SubjectName string      `json:"subjectName"`
+	Weight      float64     `json:"weight"`
+	Amount      utils.Money `json:"amount"`
 	// For metered allocation (electricity)
-	PersonalAmount *float64 `json:"personalAmount,omitempty"`
-	SharedAmount   *float64 `json:"sharedAmount,omitempty"`
-	Units          *float64 `json:"units,omitempty"`
+	PersonalAmount *utils.Money `json:"personalAmount,omitempty"`
+	SharedAmount   *utils.Money `json:"sharedAmount,omitempty"`
+	Units          *float64     `json:"units,omitempty"`
+	EstimatedUnits *float64     `json:"estimatedUnits,omitempty"` // part of Units estimated for readings that are missing
+	Zones          []ZoneUsage  `json:"zones,omitempty"`          // set for bills with tariff zones
+	// For itemized allocation: the subject's lines before tax and discounts
+	ItemsSubtotal *utils.Money `json:"itemsSubtotal,omitempty"`
+	// Set when someone in this subject lived in the household for only part of the bill period
+	Proration []ResidentPresence `json:"proration,omitempty"`
 }
 
-// CalculateSimpleAllocation divides total cost by weights
-func (s *AllocationService) CalculateSimpleAllocation(ctx context.Context, billID string, totalAmount float64) ([]AllocationBreakdown, error) {
-	// Get all active users
-	users, err := s.users.ListActive(ctx)
+// ZoneUsage is a subject's consumption and personal cost in one tariff zone
+type ZoneUsage struct {
+	Zone   string      `json:"zone"`
+	Units  float64     `json:"units"`
+	Amount utils.Money `json:"amount"`
+}
+
+// ResidentPresence shows how much of the bill period one resident lived in the household.
+// The resident's weight in shared costs is multiplied by Factor.
+type ResidentPresence struct {
+	UserID      string  `json:"userId"`
+	UserName    string  `json:"userName"`
+	DaysPresent int     `json:"daysPresent"`
+	PeriodDays  int     `json:"periodDays"`
+	Factor      float64 `json:"factor"`
+}
+
+// allocationSubject is a user or a whole group that receives one allocation.
+// Users in a group are merged into a single subject whose weight is the sum of its members.
+type allocationSubject struct {
+	id          string
+	subjectType string
+	name        string
+	weight      float64 // reported weight (first member's weight for groups)
+	totalWeight float64 // weight used for splitting shared costs, prorated by days present
+	presence    []ResidentPresence
+}
+
+// proration returns the presence of the subject's residents, or nil when all of them were there the whole period
+func (a allocationSubject) proration() []ResidentPresence {
+	for _, p := range a.presence {
+		if p.DaysPresent < p.PeriodDays {
+			return a.presence
+		}
+	}
+	return nil
+}
+
+// buildAllocationSubjects groups the residents of a bill period into allocation subjects
+func (s *AllocationService) buildAllocationSubjects(ctx context.Context, periodStart, periodEnd time.Time) ([]allocationSubject, error) {
+	residents, err := s.periodResidents(ctx, periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+	return s.groupResidents(ctx, residents)
+}
+
+// billSubjects returns the allocation subjects of a bill: those of the residents stored when it was
+// posted, or of the current residents of its period for drafts and bills posted without a snapshot
+func (s *AllocationService) billSubjects(ctx context.Context, bill *models.Bill) ([]allocationSubject, error) {
+	if bill.Status != BillStatusDraft {
+		residents, err := s.billResidents.GetByBillID(ctx, bill.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get bill residents: %w", err)
+		}
+		if len(residents) > 0 {
+			return s.groupResidents(ctx, residents)
+		}
+	}
+	return s.buildAllocationSubjects(ctx, bill.PeriodStart, bill.PeriodEnd)
+}
+
+// StoreResidents stores the current residents of a bill period with the bill, replacing any stored
+// by an earlier post, so the shares of the posted bill no longer follow changes to residencies
+func (s *AllocationService) StoreResidents(ctx context.Context, bill *models.Bill) error {
+	residents, err := s.periodResidents(ctx, bill.PeriodStart, bill.PeriodEnd)
+	if err != nil {
+		return err
+	}
+
+	if err := s.billResidents.DeleteByBillID(ctx, bill.ID); err != nil {
+		return fmt.Errorf("failed to delete bill residents: %w", err)
+	}
+	for i := range residents {
+		residents[i].BillID = bill.ID
+		if err := s.billResidents.Create(ctx, &residents[i]); err != nil {
+			return fmt.Errorf("failed to store bill resident: %w", err)
+		}
+	}
+	return nil
+}
+
+// periodResidents lists the residents of a bill period in name order with the subject they pay with,
+// their weight and their days present. Users with residency intervals take part for the days they
+// lived in the household during the period; users without any interval take part for the whole
+// period while they are active.
+func (s *AllocationService) periodResidents(ctx context.Context, periodStart, periodEnd time.Time) ([]models.BillResident, error) {
+	users, err := s.users.List(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get users: %w", err)
 	}
+	sort.SliceStable(users, func(i, j int) bool { return users[i].Name < users[j].Name })
 
-	if len(users) == 0 {
-		return nil, errors.New("no active users found")
+	residencies, err := s.residencies.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get residencies: %w", err)
+	}
+	userResidencies := make(map[string][]models.Residency)
+	for _, r := range residencies {
+		userResidencies[r.UserID] = append(userResidencies[r.UserID], r)
 	}
 
-	// Get all groups
 	groups, err := s.groups.List(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get groups: %w", err)
 	}
-
-	// Build group weight map
 	groupWeights := make(map[string]float64)
 	for _, g := range groups {
 		groupWeights[g.ID] = g.Weight
 	}
 
-	// Calculate weights for each user
-	userWeights := make(map[string]float64)
-	totalWeight := 0.0
-
+	periodDays := daysInPeriod(periodStart, periodEnd)
+	var residents []models.BillResident
 	for _, u := range users {
-		weight := 1.0 // default weight
+		daysPresent := periodDays
+		if intervals, ok := userResidencies[u.ID]; ok {
+			daysPresent = daysPresentInPeriod(intervals, periodStart, periodEnd)
+		} else if !u.IsActive {
+			continue
+		}
+		if daysPresent == 0 {
+			continue
+		}
+
+		resident := models.BillResident{
+			UserID:      u.ID,
+			SubjectType: "user",
+			SubjectID:   u.ID,
+			Weight:      1.0, // default weight
+			DaysPresent: daysPresent,
+			PeriodDays:  periodDays,
+		}
 		if u.GroupID != nil {
+			resident.SubjectType = "group"
+			resident.SubjectID = *u.GroupID
 			if gw, ok := groupWeights[*u.GroupID]; ok {
-				weight = gw
+				resident.Weight = gw
 			}
 		}
-		userWeights[u.ID] = weight
-		totalWeight += weight
+		residents = append(residents, resident)
+	}
+
+	if len(residents) == 0 {
+		return nil, errors.New("no residents found for the bill period")
+	}
+	return residents, nil
+}
+
+// groupResidents merges residents into allocation subjects in a stable order:
+// groups first (in order of first member), then individual users
+func (s *AllocationService) groupResidents(ctx context.Context, residents []models.BillResident) ([]allocationSubject, error) {
+	users, err := s.users.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	userNames := make(map[string]string)
+	for _, u := range users {
+		userNames[u.ID] = u.Name
+	}
+
+	groups, err := s.groups.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+	groupNames := make(map[string]string)
+	for _, g := range groups {
+		groupNames[g.ID] = g.Name
+	}
+
+	var groupSubjects, individualSubjects []allocationSubject
+	groupIndex := make(map[string]int)
+	totalWeight := 0.0
+
+	for _, r := range residents {
+		p := ResidentPresence{
+			UserID:      r.UserID,
+			UserName:    userNames[r.UserID],
+			DaysPresent: r.DaysPresent,
+			PeriodDays:  r.PeriodDays,
+			Factor:      float64(r.DaysPresent) / float64(r.PeriodDays),
+		}
+		proratedWeight := r.Weight * p.Factor
+		totalWeight += proratedWeight
+
+		if r.SubjectType != "group" {
+			individualSubjects = append(individualSubjects, allocationSubject{
+				id:          r.SubjectID,
+				subjectType: "user",
+				name:        p.UserName,
+				weight:      r.Weight,
+				totalWeight: proratedWeight,
+				presence:    []ResidentPresence{p},
+			})
+			continue
+		}
+
+		// User is in a group - aggregate to group
+		if idx, ok := groupIndex[r.SubjectID]; ok {
+			groupSubjects[idx].totalWeight += proratedWeight
+			groupSubjects[idx].presence = append(groupSubjects[idx].presence, p)
+			continue
+		}
+		groupIndex[r.SubjectID] = len(groupSubjects)
+		groupSubjects = append(groupSubjects, allocationSubject{
+			id:          r.SubjectID,
+			subjectType: "group",
+			name:        groupNames[r.SubjectID],
+			weight:      r.Weight,
+			totalWeight: proratedWeight,
+			presence:    []ResidentPresence{p},
+		})
 	}
 
 	if totalWeight == 0 {
 		return nil, errors.New("total weight is zero")
 	}
 
-	// Group users by group or show individually
-	groupAllocations := make(map[string]struct {
-		groupID   string
-		groupName string
-		weight    float64
-		amount    float64
-	})
-	individualAllocations := []AllocationBreakdown{}
+	return append(groupSubjects, individualSubjects...), nil
+}
 
-	for _, u := range users {
-		weight := userWeights[u.ID]
-		amount := (weight / totalWeight) * totalAmount
+// truncateToDay drops the time of day so periods and residencies are compared in whole days
+func truncateToDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
 
-		if u.GroupID != nil {
-			// User is in a group - aggregate to group
-			if existing, ok := groupAllocations[*u.GroupID]; ok {
-				existing.amount += amount
-				groupAllocations[*u.GroupID] = existing
-			} else {
-				// Find group name
-				groupName := ""
-				for _, g := range groups {
-					if g.ID == *u.GroupID {
-						groupName = g.Name
-						break
-					}
-				}
-				groupAllocations[*u.GroupID] = struct {
-					groupID   string
-					groupName string
-					weight    float64
-					amount    float64
-				}{
-					groupID:   *u.GroupID,
-					groupName: groupName,
-					weight:    weight,
-					amount:    amount,
-				}
+// daysInPeriod counts the days of a bill period, both ends included
+func daysInPeriod(start, end time.Time) int {
+	days := int(truncateToDay(end).Sub(truncateToDay(start)).Hours()/24) + 1
+	if days < 1 {
+		return 1
+	}
+	return days
+}
+
+// daysPresentInPeriod counts the days of the period covered by at least one residency interval
+func daysPresentInPeriod(residencies []models.Residency, start, end time.Time) int {
+	periodStart := truncateToDay(start)
+	periodDays := daysInPeriod(start, end)
+
+	present := 0
+	for i := 0; i < periodDays; i++ {
+		day := periodStart.AddDate(0, 0, i)
+		for _, r := range residencies {
+			if day.Before(truncateToDay(r.MovedInAt)) {
+				continue
 			}
-		} else {
-			// User is not in a group - show individually with Name
-			individualAllocations = append(individualAllocations, AllocationBreakdown{
-				SubjectID:   u.ID,
-				SubjectType: "user",
-				SubjectName: u.Name,
-				Weight:      weight,
-				Amount:      utils.RoundToTwoDecimals(amount),
-			})
+			if r.MovedOutAt != nil && day.After(truncateToDay(*r.MovedOutAt)) {
+				continue
+			}
+			present++
+			break
 		}
 	}
+	return present
+}
 
-	// Build final breakdown
-	breakdown := make([]AllocationBreakdown, 0, len(groupAllocations)+len(individualAllocations))
+// CalculateSimpleAllocation divides the bill total by weights, prorated by days present.
+// Shares are rounded with the largest remainder method so they always add up to the total.
+func (s *AllocationService) CalculateSimpleAllocation(ctx context.Context, bill *models.Bill) ([]AllocationBreakdown, error) {
+	totalAmount := utils.MoneyFromString(bill.TotalAmountPLN)
 
-	// Add group allocations
-	for _, ga := range groupAllocations {
-		breakdown = append(breakdown, AllocationBreakdown{
-			SubjectID:   ga.groupID,
-			SubjectType: "group",
-			SubjectName: ga.groupName,
-			Weight:      ga.weight,
-			Amount:      utils.RoundToTwoDecimals(ga.amount),
-		})
+	subjects, err := s.billSubjects(ctx, bill)
+	if err != nil {
+		return nil, err
 	}
 
-	// Add individual allocations
-	breakdown = append(breakdown, individualAllocations...)
+	weights := make([]float64, len(subjects))
+	for i, subject := range subjects {
+		weights[i] = subject.totalWeight
+	}
+	shares := totalAmount.Allocate(weights)
+
+	breakdown := make([]AllocationBreakdown, len(subjects))
+	for i, subject := range subjects {
+		breakdown[i] = AllocationBreakdown{
+			SubjectID:   subject.id,
+			SubjectType: subject.subjectType,
+			SubjectName: subject.name,
+			Weight:      subject.weight,
+			Amount:      shares[i],
+			Proration:   subject.proration(),
+		}
+	}
 
 	return breakdown, nil
 }
 
-// CalculateMeteredAllocation calculates based on meter readings + shared common area
-func (s *AllocationService) CalculateMeteredAllocation(ctx context.Context, billID string, totalAmount float64, totalUnits *float64) ([]AllocationBreakdown, error) {
-	if totalUnits == nil || *totalUnits == 0 {
-		return nil, errors.New("totalUnits is required for metered allocation")
+// CalculateMeteredAllocation calculates based on meter readings + shared common area.
+// The personal pool is split by units consumed and the shared pool by weight prorated by days present;
+// both use remainder distribution so the amounts add up to the bill total exactly.
+// Bills with tariff zones have a personal pool per zone, priced at the zone's amount and split by the
+// readings of meters in that zone; whatever the zones do not cover (e.g. fixed charges) is shared.
+func (s *AllocationService) CalculateMeteredAllocation(ctx context.Context, bill *models.Bill) ([]AllocationBreakdown, error) {
+	totalAmount := utils.MoneyFromString(bill.TotalAmountPLN)
+
+	pools, zoned, err := s.meteredPools(ctx, bill)
+	if err != nil {
+		return nil, err
+	}
+	for _, pool := range pools {
+		if pool.units == 0 {
+			return nil, errors.New("totalUnits is required for metered allocation")
+		}
 	}
 
 	// Get all consumptions for this bill
-	consumptions, err := s.consumptions.ListByBillID(ctx, billID)
+	consumptions, err := s.consumptions.ListByBillID(ctx, bill.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get consumptions: %w", err)
 	}
 
-	// Get all active users
-	users, err := s.users.ListActive(ctx)
+	subjects, err := s.billSubjects(ctx, bill)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get users: %w", err)
-	}
-
-	if len(users) == 0 {
-		return nil, errors.New("no active users found")
+		return nil, err
 	}
 
-	// Get all groups
-	groups, err := s.groups.List(ctx)
+	// Subjects who have not read their meters yet are charged an estimate of their usage
+	estimates, err := s.estimateMissingReadings(ctx, bill, consumptions, subjects, pools, zoned)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get groups: %w", err)
+		return nil, err
 	}
+	consumptions = append(consumptions, estimates...)
 
-	// Build group weight map
-	groupWeights := make(map[string]float64)
-	for _, g := range groups {
-		groupWeights[g.ID] = g.Weight
+	// Calculate consumed units from readings (aggregated by zone and subject)
+	zoneUnits := make([]map[string]float64, len(pools))
+	for i := range zoneUnits {
+		zoneUnits[i] = make(map[string]float64)
 	}
+	estimatedUnits := make(map[string]float64)
 
-	// Calculate user weights
-	userWeights := make(map[string]float64)
-	totalWeight := 0.0
+	for _, c := range consumptions {
+		if c.Source == "invalid" {
+			continue
+		}
 
-	for _, u := range users {
-		weight := 1.0 // default weight
-		if u.GroupID != nil {
-			if gw, ok := groupWeights[*u.GroupID]; ok {
-				weight = gw
+		zoneIndex := 0
+		if zoned {
+			zoneIndex, err = s.readingZone(ctx, c, pools)
+			if err != nil {
+				return nil, err
+			}
+			if zoneIndex < 0 {
+				continue // not read from a meter in one of the bill's zones
 			}
 		}
-		userWeights[u.ID] = weight
-		totalWeight += weight
-	}
-
-	if totalWeight == 0 {
-		return nil, errors.New("total weight is zero")
-	}
-
-	// Calculate total consumed units from readings (aggregated by subject)
-	subjectUnits := make(map[string]float64)
-	totalConsumedUnits := 0.0
 
-	for _, c := range consumptions {
 		units := utils.DecimalStringToFloat(c.Units)
 
 		if units <= 0 && c.MeterValue != nil {
-			derivedUnits, derr := s.deriveUnitsFromMeter(ctx, c)
+			derivedUnits, derr := meterReadingUnits(ctx, s.consumptions, s.meters, c)
 			switch {
 			case derr == nil:
 				units = derivedUnits
@@ -237,127 +438,544 @@ func (s *AllocationService) CalculateMeteredAllocation(ctx context.Context, bill
 			continue
 		}
 
-		subjectUnits[c.SubjectID] += units // Aggregate units per subject (group or user)
-		totalConsumedUnits += units
+		zoneUnits[zoneIndex][c.SubjectID] += units // Aggregate units per subject (group or user)
+		if c.Source == "estimated" {
+			estimatedUnits[c.SubjectID] += units
+		}
+	}
+
+	// Only readings of subjects that take part in the split count towards the personal pools,
+	// otherwise their share of the bill would not be charged to anyone
+	personalAmounts := make([]utils.Money, len(subjects))
+	subjectUnits := make([]float64, len(subjects))
+	zoneUsage := make([][]ZoneUsage, len(subjects))
+	sharedPool := totalAmount
+
+	for z, pool := range pools {
+		unitWeights := make([]float64, len(subjects))
+		totalConsumedUnits := 0.0
+		for i, subject := range subjects {
+			unitWeights[i] = zoneUnits[z][subject.id]
+			totalConsumedUnits += unitWeights[i]
+		}
+
+		// Calculate the personal pool of the zone
+		personalPoolRatio := totalConsumedUnits / pool.units
+		if personalPoolRatio > 1.0 {
+			personalPoolRatio = 1.0 // cap at 100%
+		}
+
+		personalPool := pool.amount.MulFloat(personalPoolRatio)
+		sharedPool = sharedPool.Sub(personalPool)
+
+		personalShares := make([]utils.Money, len(subjects))
+		if totalConsumedUnits > 0 {
+			personalShares = personalPool.Allocate(unitWeights)
+		}
+
+		for i := range subjects {
+			personalAmounts[i] = personalAmounts[i].Add(personalShares[i])
+			subjectUnits[i] += unitWeights[i]
+			if zoned {
+				zoneUsage[i] = append(zoneUsage[i], ZoneUsage{
+					Zone:   pool.zone,
+					Units:  utils.RoundToThreeDecimals(unitWeights[i]),
+					Amount: personalShares[i],
+				})
+			}
+		}
 	}
 
-	// Calculate personal and shared pools
-	personalPoolRatio := totalConsumedUnits / *totalUnits
-	if personalPoolRatio > 1.0 {
-		personalPoolRatio = 1.0 // cap at 100%
+	sharedWeights := make([]float64, len(subjects))
+	for i, subject := range subjects {
+		sharedWeights[i] = subject.totalWeight
+	}
+	sharedShares := sharedPool.Allocate(sharedWeights)
+
+	breakdown := make([]AllocationBreakdown, len(subjects))
+	for i, subject := range subjects {
+		personalAmount := personalAmounts[i]
+		sharedAmount := sharedShares[i]
+		breakdown[i] = AllocationBreakdown{
+			SubjectID:      subject.id,
+			SubjectType:    subject.subjectType,
+			SubjectName:    subject.name,
+			Weight:         subject.weight,
+			Amount:         personalAmount.Add(sharedAmount),
+			PersonalAmount: &personalAmount,
+			SharedAmount:   &sharedAmount,
+			Units:          floatPtr(utils.RoundToThreeDecimals(subjectUnits[i])),
+			Zones:          zoneUsage[i],
+			Proration:      subject.proration(),
+		}
+		if estimated := estimatedUnits[subject.id]; estimated > 0 {
+			breakdown[i].EstimatedUnits = floatPtr(utils.RoundToThreeDecimals(estimated))
+		}
 	}
-	sharedPoolRatio := 1.0 - personalPoolRatio
 
-	personalPool := totalAmount * personalPoolRatio
-	sharedPool := totalAmount * sharedPoolRatio
+	return breakdown, nil
+}
+
+// meteredPool is the part of a metered bill priced by consumption
+type meteredPool struct {
+	zone   string
+	amount utils.Money
+	units  float64
+}
 
-	// Calculate rate per unit for personal usage
-	ratePerUnit := 0.0
-	if totalConsumedUnits > 0 {
-		ratePerUnit = personalPool / totalConsumedUnits
+// meteredPools returns the pools a metered bill is priced in: one per tariff zone, or the whole bill
+// as a single pool when it has no zones
+func (s *AllocationService) meteredPools(ctx context.Context, bill *models.Bill) ([]meteredPool, bool, error) {
+	tariffZones, err := s.billTariffZones.GetByBillID(ctx, bill.ID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get tariff zones: %w", err)
+	}
+	if len(tariffZones) == 0 {
+		return []meteredPool{{amount: utils.MoneyFromString(bill.TotalAmountPLN), units: utils.DecimalStringToFloat(bill.TotalUnits)}}, false, nil
 	}
 
-	// Group users by group or show individually
-	groupAllocations := make(map[string]struct {
-		groupID        string
-		groupName      string
-		weight         float64
-		personalAmount float64
-		sharedAmount   float64
-		units          float64
-	})
-	individualAllocations := []AllocationBreakdown{}
+	pools := make([]meteredPool, len(tariffZones))
+	for i, zone := range tariffZones {
+		pools[i] = meteredPool{
+			zone:   zone.Zone,
+			amount: utils.MoneyFromString(zone.Amount),
+			units:  utils.DecimalStringToFloat(zone.Units),
+		}
+	}
+	return pools, true, nil
+}
 
-	for _, u := range users {
-		weight := userWeights[u.ID]
+// readingZone returns the pool of the tariff zone a reading was taken in, or -1 when the reading
+// has no meter or its meter's zone is not on the bill
+func (s *AllocationService) readingZone(ctx context.Context, c models.Consumption, pools []meteredPool) (int, error) {
+	if c.MeterID == nil {
+		return -1, nil
+	}
+	meter, err := s.meters.GetByID(ctx, *c.MeterID)
+	if err != nil {
+		return -1, fmt.Errorf("failed to fetch meter: %w", err)
+	}
+	if meter == nil {
+		return -1, nil
+	}
+	for i, pool := range pools {
+		if pool.zone == meter.TariffZone {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
 
-		// Determine subject ID for consumption lookup
-		var subjectID string
-		if u.GroupID != nil {
-			subjectID = *u.GroupID
-		} else {
-			subjectID = u.ID
+// CalculateCustomAllocation applies the split rules stored for a bill
+func (s *AllocationService) CalculateCustomAllocation(ctx context.Context, bill *models.Bill) ([]AllocationBreakdown, error) {
+	splits, err := s.billSplits.GetByBillID(ctx, bill.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get split rules: %w", err)
+	}
+	if len(splits) == 0 {
+		return nil, errors.New("custom allocation requires split rules")
+	}
+
+	subjects, err := s.billSubjects(ctx, bill)
+	if err != nil {
+		return nil, err
+	}
+
+	return calculateCustomSplit(utils.MoneyFromString(bill.TotalAmountPLN), splits, subjects)
+}
+
+// ValidateBillSplits checks split rules against the residents of the bill period before they are stored
+func (s *AllocationService) ValidateBillSplits(ctx context.Context, totalAmount utils.Money, periodStart, periodEnd time.Time, splits []models.BillSplit) error {
+	subjects, err := s.buildAllocationSubjects(ctx, periodStart, periodEnd)
+	if err != nil {
+		return err
+	}
+
+	_, err = calculateCustomSplit(totalAmount, splits, subjects)
+	return err
+}
+
+// validateBillSplits checks that every rule is complete and within range
+func validateBillSplits(splits []models.BillSplit) error {
+	if len(splits) == 0 {
+		return errors.New("at least one split rule is required")
+	}
+
+	for i, split := range splits {
+		if split.SubjectType != "user" && split.SubjectType != "group" {
+			return fmt.Errorf("split %d: subject type must be 'user' or 'group'", i+1)
 		}
+		if split.SubjectID == "" {
+			return fmt.Errorf("split %d: subject ID is required", i+1)
+		}
+
+		switch split.AllocationType {
+		case "fixed":
+			if split.FixedAmount == nil {
+				return fmt.Errorf("split %d: fixed amount is required for fixed type", i+1)
+			}
+			amount, err := utils.ParseMoney(*split.FixedAmount)
+			if err != nil {
+				return fmt.Errorf("split %d: invalid fixed amount %q", i+1, *split.FixedAmount)
+			}
+			if amount.IsNegative() {
+				return fmt.Errorf("split %d: fixed amount cannot be negative", i+1)
+			}
+		case "percentage":
+			if split.Percentage == nil {
+				return fmt.Errorf("split %d: percentage is required for percentage type", i+1)
+			}
+			if *split.Percentage <= 0 || *split.Percentage > 100 {
+				return fmt.Errorf("split %d: percentage must be between 0 and 100", i+1)
+			}
+		case "fraction":
+			if split.FractionNum == nil || split.FractionDenom == nil {
+				return fmt.Errorf("split %d: fraction numerator and denominator are required for fraction type", i+1)
+			}
+			if *split.FractionNum <= 0 || *split.FractionDenom <= 0 {
+				return fmt.Errorf("split %d: fraction values must be positive", i+1)
+			}
+			if *split.FractionNum > *split.FractionDenom {
+				return fmt.Errorf("split %d: fraction numerator cannot be greater than denominator", i+1)
+			}
+		case "shares":
+			if split.Shares == nil || *split.Shares <= 0 {
+				return fmt.Errorf("split %d: a positive number of shares is required for shares type", i+1)
+			}
+		case "exclude":
+		default:
+			return fmt.Errorf("split %d: invalid allocation type '%s'", i+1, split.AllocationType)
+		}
+	}
+
+	return nil
+}
 
-		// Personal amount based on consumption
-		units := subjectUnits[subjectID]
-		personalAmount := units * ratePerUnit
+// calculateCustomSplit turns split rules into exact amounts per subject.
+// Fixed amounts are taken first and percentages/fractions apply to what is left after them.
+// The rest goes to subjects with a shares rule, or - when there are none - to every subject
+// without a rule by household weight. Excluded subjects pay nothing.
+func calculateCustomSplit(total utils.Money, splits []models.BillSplit, subjects []allocationSubject) ([]AllocationBreakdown, error) {
+	if err := validateBillSplits(splits); err != nil {
+		return nil, err
+	}
 
-		// Shared amount based on weight
-		sharedAmount := (weight / totalWeight) * sharedPool
+	subjectIndex := make(map[string]int, len(subjects))
+	for i, subject := range subjects {
+		subjectIndex[subject.subjectType+":"+subject.id] = i
+	}
 
-		if u.GroupID != nil {
-			// User is in a group - aggregate to group
-			if existing, ok := groupAllocations[*u.GroupID]; ok {
-				existing.sharedAmount += sharedAmount
-				// Personal amount is already aggregated at the group level from consumption
-				groupAllocations[*u.GroupID] = existing
-			} else {
-				// Find group name
-				groupName := ""
-				for _, g := range groups {
-					if g.ID == *u.GroupID {
-						groupName = g.Name
-						break
-					}
+	// Rules are matched to allocation subjects, so users in a group must be addressed through their group
+	rules := make([]*models.BillSplit, len(subjects))
+	for i := range splits {
+		split := &splits[i]
+		idx, ok := subjectIndex[split.SubjectType+":"+split.SubjectID]
+		if !ok {
+			return nil, fmt.Errorf("split %d: %s %s is not an active allocation subject (users in a group are split as their group)",
+				i+1, split.SubjectType, split.SubjectID)
+		}
+		if rules[idx] != nil {
+			return nil, fmt.Errorf("split %d: %s %s already has a split rule", i+1, split.SubjectType, split.SubjectID)
+		}
+		rules[idx] = split
+	}
+
+	amounts := make([]utils.Money, len(subjects))
+	participates := make([]bool, len(subjects))
+
+	// Fixed amounts
+	remaining := total
+	for i, rule := range rules {
+		if rule == nil || rule.AllocationType != "fixed" {
+			continue
+		}
+		amounts[i] = utils.MoneyFromString(*rule.FixedAmount)
+		participates[i] = true
+		remaining = remaining.Sub(amounts[i])
+	}
+	if remaining.IsNegative() {
+		return nil, fmt.Errorf("fixed amounts exceed the bill total by %s", remaining.Neg())
+	}
+
+	// Percentages and fractions of the remaining amount; the extra weight collects what they leave over
+	weights := make([]float64, len(subjects)+1)
+	proportional := 0.0
+	for i, rule := range rules {
+		if rule == nil {
+			continue
+		}
+		switch rule.AllocationType {
+		case "percentage":
+			weights[i] = *rule.Percentage / 100.0
+		case "fraction":
+			weights[i] = float64(*rule.FractionNum) / float64(*rule.FractionDenom)
+		default:
+			continue
+		}
+		proportional += weights[i]
+		participates[i] = true
+	}
+	if proportional > 1.001 {
+		return nil, fmt.Errorf("percentages and fractions must not exceed 100%% (currently %.2f%%)", proportional*100)
+	}
+
+	rest := remaining
+	if proportional > 0 {
+		if proportional < 0.999 {
+			weights[len(subjects)] = 1 - proportional
+		}
+		shares := remaining.Allocate(weights)
+		for i := range subjects {
+			if weights[i] > 0 {
+				amounts[i] = shares[i]
+			}
+		}
+		rest = shares[len(subjects)]
+	}
+
+	// Shares, or household weight for subjects without a rule
+	restWeights := make([]float64, len(subjects))
+	hasShares := false
+	for i, rule := range rules {
+		if rule != nil && rule.AllocationType == "shares" {
+			restWeights[i] = *rule.Shares
+			participates[i] = true
+			hasShares = true
+		}
+	}
+	if !hasShares && rest.IsPositive() {
+		for i, subject := range subjects {
+			if rules[i] == nil {
+				restWeights[i] = subject.totalWeight
+				participates[i] = true
+			}
+		}
+	}
+
+	if rest.IsPositive() {
+		restWeight := 0.0
+		for _, w := range restWeights {
+			restWeight += w
+		}
+		if restWeight == 0 {
+			return nil, fmt.Errorf("split rules leave %s unassigned", rest)
+		}
+		for i, share := range rest.Allocate(restWeights) {
+			amounts[i] = amounts[i].Add(share)
+		}
+	}
+
+	breakdown := make([]AllocationBreakdown, 0, len(subjects))
+	for i, subject := range subjects {
+		if !participates[i] {
+			continue
+		}
+		breakdown = append(breakdown, AllocationBreakdown{
+			SubjectID:   subject.id,
+			SubjectType: subject.subjectType,
+			SubjectName: subject.name,
+			Weight:      subject.weight,
+			Amount:      amounts[i],
+			Proration:   subject.proration(),
+		})
+	}
+
+	return breakdown, nil
+}
+
+// CalculateItemizedAllocation adds up each subject's receipt lines of a bill
+func (s *AllocationService) CalculateItemizedAllocation(ctx context.Context, bill *models.Bill) ([]AllocationBreakdown, error) {
+	items, err := s.billItems.GetByBillID(ctx, bill.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receipt items: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, errors.New("itemized allocation requires receipt items")
+	}
+
+	subjects, err := s.billSubjects(ctx, bill)
+	if err != nil {
+		return nil, err
+	}
+
+	return calculateItemizedSplit(utils.MoneyFromString(bill.TotalAmountPLN), items, subjects)
+}
+
+// ValidateBillItems checks receipt lines against the residents of the bill period before they are stored
+func (s *AllocationService) ValidateBillItems(ctx context.Context, totalAmount utils.Money, periodStart, periodEnd time.Time, items []models.BillItem) error {
+	subjects, err := s.buildAllocationSubjects(ctx, periodStart, periodEnd)
+	if err != nil {
+		return err
+	}
+
+	_, err = calculateItemizedSplit(totalAmount, items, subjects)
+	return err
+}
+
+// ItemsTotal returns what a receipt adds up to: items plus tax minus discounts
+func ItemsTotal(items []models.BillItem) (utils.Money, error) {
+	var total utils.Money
+	for i, item := range items {
+		amount, err := utils.ParseMoney(item.Amount)
+		if err != nil {
+			return 0, fmt.Errorf("item %d: invalid amount %q", i+1, item.Amount)
+		}
+		if item.Kind == "discount" {
+			amount = amount.Neg()
+		}
+		total = total.Add(amount)
+	}
+	return total, nil
+}
+
+// validateBillItems checks that every receipt line is complete
+func validateBillItems(items []models.BillItem) error {
+	if len(items) == 0 {
+		return errors.New("at least one receipt item is required")
+	}
+
+	for i, item := range items {
+		amount, err := utils.ParseMoney(item.Amount)
+		if err != nil {
+			return fmt.Errorf("item %d: invalid amount %q", i+1, item.Amount)
+		}
+		if amount.IsNegative() {
+			return fmt.Errorf("item %d: amount cannot be negative", i+1)
+		}
+
+		switch item.Kind {
+		case "item":
+			if len(item.Participants) == 0 {
+				return fmt.Errorf("item %d: at least one participant is required", i+1)
+			}
+			seen := make(map[string]bool)
+			for _, p := range item.Participants {
+				if p.SubjectType != "user" && p.SubjectType != "group" {
+					return fmt.Errorf("item %d: subject type must be 'user' or 'group'", i+1)
 				}
-				groupAllocations[*u.GroupID] = struct {
-					groupID        string
-					groupName      string
-					weight         float64
-					personalAmount float64
-					sharedAmount   float64
-					units          float64
-				}{
-					groupID:        *u.GroupID,
-					groupName:      groupName,
-					weight:         weight,
-					personalAmount: personalAmount, // From group's total consumption
-					sharedAmount:   sharedAmount,
-					units:          units,
+				key := p.SubjectType + ":" + p.SubjectID
+				if seen[key] {
+					return fmt.Errorf("item %d: %s %s is listed twice", i+1, p.SubjectType, p.SubjectID)
 				}
+				seen[key] = true
 			}
-		} else {
-			// User is not in a group - show individually
-			totalUserAmount := personalAmount + sharedAmount
-
-			individualAllocations = append(individualAllocations, AllocationBreakdown{
-				SubjectID:      u.ID,
-				SubjectType:    "user",
-				SubjectName:    u.Name,
-				Weight:         weight,
-				Amount:         utils.RoundToTwoDecimals(totalUserAmount),
-				PersonalAmount: floatPtr(utils.RoundToTwoDecimals(personalAmount)),
-				SharedAmount:   floatPtr(utils.RoundToTwoDecimals(sharedAmount)),
-				Units:          floatPtr(utils.RoundToThreeDecimals(units)),
-			})
+		case "tax", "discount":
+			if len(item.Participants) > 0 {
+				return fmt.Errorf("item %d: %s lines are spread over everyone and take no participants", i+1, item.Kind)
+			}
+		default:
+			return fmt.Errorf("item %d: invalid kind '%s'", i+1, item.Kind)
+		}
+	}
+
+	return nil
+}
+
+// calculateItemizedSplit splits each receipt line equally among its participants and
+// spreads tax and discounts in proportion to everyone's item subtotal.
+// Each participant entry is one share, so a user in a group adds a share to the group,
+// and naming the group itself counts once.
+func calculateItemizedSplit(total utils.Money, items []models.BillItem, subjects []allocationSubject) ([]AllocationBreakdown, error) {
+	if err := validateBillItems(items); err != nil {
+		return nil, err
+	}
+
+	subjectIndex := make(map[string]int, len(subjects))
+	for i, subject := range subjects {
+		subjectIndex[subject.subjectType+":"+subject.id] = i
+		// Group members are charged through their group
+		for _, member := range subject.presence {
+			subjectIndex["user:"+member.UserID] = i
+		}
+	}
+
+	subtotals := make([]utils.Money, len(subjects))
+	participates := make([]bool, len(subjects))
+	var itemsTotal, adjustment utils.Money
+
+	for i, item := range items {
+		amount := utils.MoneyFromString(item.Amount)
+		switch item.Kind {
+		case "tax":
+			adjustment = adjustment.Add(amount)
+			continue
+		case "discount":
+			adjustment = adjustment.Sub(amount)
+			continue
 		}
+
+		weights := make([]float64, len(subjects))
+		for _, p := range item.Participants {
+			idx, ok := subjectIndex[p.SubjectType+":"+p.SubjectID]
+			if !ok {
+				return nil, fmt.Errorf("item %d: %s %s did not live in the household during the bill period", i+1, p.SubjectType, p.SubjectID)
+			}
+			weights[idx]++
+			participates[idx] = true
+		}
+		for j, share := range amount.Allocate(weights) {
+			subtotals[j] = subtotals[j].Add(share)
+		}
+		itemsTotal = itemsTotal.Add(amount)
+	}
+
+	if sum := itemsTotal.Add(adjustment); sum != total {
+		return nil, fmt.Errorf("receipt items add up to %s, not the bill total %s", sum, total)
+	}
+	if !adjustment.IsZero() && itemsTotal.IsZero() {
+		return nil, errors.New("tax or discount cannot be spread over a receipt without item amounts")
 	}
 
-	// Build final breakdown
-	breakdown := make([]AllocationBreakdown, 0, len(groupAllocations)+len(individualAllocations))
+	amounts := subtotals
+	if !adjustment.IsZero() {
+		weights := make([]float64, len(subjects))
+		for i, subtotal := range subtotals {
+			weights[i] = float64(subtotal.Grosze())
+		}
+		amounts = make([]utils.Money, len(subjects))
+		for i, share := range adjustment.Allocate(weights) {
+			amounts[i] = subtotals[i].Add(share)
+		}
+	}
 
-	// Add group allocations
-	for _, ga := range groupAllocations {
-		totalAmount := ga.personalAmount + ga.sharedAmount
+	breakdown := make([]AllocationBreakdown, 0, len(subjects))
+	for i, subject := range subjects {
+		if !participates[i] {
+			continue
+		}
+		subtotal := subtotals[i]
 		breakdown = append(breakdown, AllocationBreakdown{
-			SubjectID:      ga.groupID,
-			SubjectType:    "group",
-			SubjectName:    ga.groupName,
-			Weight:         ga.weight,
-			Amount:         utils.RoundToTwoDecimals(totalAmount),
-			PersonalAmount: floatPtr(utils.RoundToTwoDecimals(ga.personalAmount)),
-			SharedAmount:   floatPtr(utils.RoundToTwoDecimals(ga.sharedAmount)),
-			Units:          floatPtr(utils.RoundToThreeDecimals(ga.units)),
+			SubjectID:     subject.id,
+			SubjectType:   subject.subjectType,
+			SubjectName:   subject.name,
+			Weight:        subject.weight,
+			Amount:        amounts[i],
+			ItemsSubtotal: &subtotal,
 		})
 	}
 
-	// Add individual allocations
-	breakdown = append(breakdown, individualAllocations...)
-
 	return breakdown, nil
 }
 
+// CalculateAllocation computes the split of a bill according to its allocation type
+func (s *AllocationService) CalculateAllocation(ctx context.Context, bill *models.Bill) ([]AllocationBreakdown, error) {
+	allocationType := "simple" // default
+	if bill.AllocationType != nil {
+		allocationType = *bill.AllocationType
+	}
+
+	switch allocationType {
+	case "custom":
+		return s.CalculateCustomAllocation(ctx, bill)
+	case "itemized":
+		return s.CalculateItemizedAllocation(ctx, bill)
+	case "metered":
+		return s.CalculateMeteredAllocation(ctx, bill)
+	default:
+		return s.CalculateSimpleAllocation(ctx, bill)
+	}
+}
+
 // GetAllocationBreakdown returns allocation breakdown for a bill
 func (s *AllocationService) GetAllocationBreakdown(ctx context.Context, billID string) ([]AllocationBreakdown, error) {
 	// First, check if allocations already exist in the database
@@ -366,12 +984,27 @@ func (s *AllocationService) GetAllocationBreakdown(ctx context.Context, billID s
 		return nil, fmt.Errorf("failed to query allocations: %w", err)
 	}
 
-	// If allocations exist, return them
+	// If allocations exist, return them with the weights and presence stored when the bill was posted
 	if len(storedAllocations) > 0 {
+		residents, err := s.billResidents.GetByBillID(ctx, billID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get bill residents: %w", err)
+		}
+		subjects := make(map[string]allocationSubject)
+		if len(residents) > 0 {
+			grouped, err := s.groupResidents(ctx, residents)
+			if err != nil {
+				return nil, err
+			}
+			for _, subject := range grouped {
+				subjects[subject.subjectType+":"+subject.id] = subject
+			}
+		}
+
 		breakdown := make([]AllocationBreakdown, 0, len(storedAllocations))
 
 		for _, alloc := range storedAllocations {
-			amount := utils.DecimalStringToFloat(alloc.AllocatedPLN)
+			amount := utils.MoneyFromString(alloc.AllocatedPLN)
 
 			// Get subject name
 			var subjectName string
@@ -387,13 +1020,18 @@ func (s *AllocationService) GetAllocationBreakdown(ctx context.Context, billID s
 				}
 			}
 
-			breakdown = append(breakdown, AllocationBreakdown{
+			entry := AllocationBreakdown{
 				SubjectID:   alloc.SubjectID,
 				SubjectType: alloc.SubjectType,
 				SubjectName: subjectName,
-				Weight:      1.0, // Not applicable for stored allocations
-				Amount:      utils.RoundToTwoDecimals(amount),
-			})
+				Weight:      1.0, // Not known for allocations stored without residents
+				Amount:      amount,
+			}
+			if subject, ok := subjects[alloc.SubjectType+":"+alloc.SubjectID]; ok {
+				entry.Weight = subject.weight
+				entry.Proration = subject.proration()
+			}
+			breakdown = append(breakdown, entry)
 		}
 
 		return breakdown, nil
@@ -402,74 +1040,13 @@ func (s *AllocationService) GetAllocationBreakdown(ctx context.Context, billID s
 	// If no allocations exist, calculate them on-the-fly (for draft bills)
 	// Get the bill
 	bill, err := s.bills.GetByID(ctx, billID)
-	if err != nil {
+	if err != nil || bill == nil {
 		return nil, errors.New("bill not found")
 	}
 
-	// Get total amount
-	totalAmount := utils.DecimalStringToFloat(bill.TotalAmountPLN)
-
-	// Determine allocation type
-	allocationType := "simple" // default
-	if bill.AllocationType != nil {
-		allocationType = *bill.AllocationType
-	}
-
-	// Calculate allocation based on type
-	if allocationType == "metered" {
-		var totalUnits *float64
-		if bill.TotalUnits != "" {
-			units := utils.DecimalStringToFloat(bill.TotalUnits)
-			totalUnits = &units
-		}
-		return s.CalculateMeteredAllocation(ctx, billID, totalAmount, totalUnits)
-	}
-
-	return s.CalculateSimpleAllocation(ctx, billID, totalAmount)
+	return s.CalculateAllocation(ctx, bill)
 }
 
 func floatPtr(f float64) *float64 {
 	return &f
 }
-
-// deriveUnitsFromMeter calculates usage delta when only meter readings were stored (legacy data)
-func (s *AllocationService) deriveUnitsFromMeter(ctx context.Context, consumption models.Consumption) (float64, error) {
-	if consumption.MeterValue == nil {
-		return 0, nil
-	}
-
-	currentValue := utils.DecimalStringToFloat(*consumption.MeterValue)
-
-	// Get all consumptions for this subject to find the most recent one before this reading
-	consumptions, err := s.consumptions.ListBySubject(ctx, consumption.SubjectType, consumption.SubjectID)
-	if err != nil {
-		return 0, fmt.Errorf("failed to fetch consumptions: %w", err)
-	}
-
-	var previous *models.Consumption
-	for i := range consumptions {
-		c := &consumptions[i]
-		if c.MeterValue == nil {
-			continue
-		}
-		if !c.RecordedAt.Before(consumption.RecordedAt) {
-			continue
-		}
-		if previous == nil || c.RecordedAt.After(previous.RecordedAt) {
-			previous = c
-		}
-	}
-
-	if previous == nil || previous.MeterValue == nil {
-		return 0, ErrNoPreviousReading
-	}
-
-	prevValue := utils.DecimalStringToFloat(*previous.MeterValue)
-
-	units := currentValue - prevValue
-	if units < 0 {
-		return 0, errors.New("meter reading cannot be lower than previous reading")
-	}
-
-	return units, nil
-}
@@ -1,12 +1,15 @@
 package services
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/sainaif/holy-home/internal/models"
 	"github.com/sainaif/holy-home/internal/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAllocationBreakdown_RoundingPrecision(t *testing.T) {
@@ -245,3 +248,369 @@ func TestGroupAggregationLogic(t *testing.T) {
 		assert.Equal(t, 3, individualCount, "Should have 3 individual users")
 	})
 }
+
+func TestCalculateCustomSplit(t *testing.T) {
+	// A couple sharing one group (weight 2) and three individual flatmates
+	subjects := []allocationSubject{
+		{id: "couple", subjectType: "group", name: "Couple", weight: 1, totalWeight: 2},
+		{id: "a", subjectType: "user", name: "A", weight: 1, totalWeight: 1},
+		{id: "b", subjectType: "user", name: "B", weight: 1, totalWeight: 1},
+		{id: "c", subjectType: "user", name: "C", weight: 1, totalWeight: 1},
+	}
+	user := func(id, allocationType string) models.BillSplit {
+		return models.BillSplit{SubjectType: "user", SubjectID: id, AllocationType: allocationType}
+	}
+	excludeCouple := models.BillSplit{SubjectType: "group", SubjectID: "couple", AllocationType: "exclude"}
+	fixed := func(id, amount string) models.BillSplit {
+		split := user(id, "fixed")
+		split.FixedAmount = &amount
+		return split
+	}
+	percentage := func(id string, p float64) models.BillSplit {
+		split := user(id, "percentage")
+		split.Percentage = &p
+		return split
+	}
+	fraction := func(id string, num, denom int) models.BillSplit {
+		split := user(id, "fraction")
+		split.FractionNum = &num
+		split.FractionDenom = &denom
+		return split
+	}
+	shares := func(id string, n float64) models.BillSplit {
+		split := user(id, "shares")
+		split.Shares = &n
+		return split
+	}
+
+	tests := []struct {
+		name    string
+		splits  []models.BillSplit
+		want    map[string]utils.Money
+		wantErr string
+	}{
+		{
+			name:   "Excluded person pays nothing, the rest split by weight",
+			splits: []models.BillSplit{user("c", "exclude")},
+			want:   map[string]utils.Money{"couple": 5000, "a": 2500, "b": 2500},
+		},
+		{
+			name:   "Fixed amount first, the rest split by weight",
+			splits: []models.BillSplit{fixed("a", "40.00")},
+			want:   map[string]utils.Money{"couple": 3000, "a": 4000, "b": 1500, "c": 1500},
+		},
+		{
+			name:   "Only people with shares take part",
+			splits: []models.BillSplit{shares("a", 2), shares("b", 1)},
+			want:   map[string]utils.Money{"a": 6667, "b": 3333},
+		},
+		{
+			name:   "Percentage applies to what is left after fixed amounts",
+			splits: []models.BillSplit{fixed("a", "20.00"), percentage("b", 50), excludeCouple},
+			want:   map[string]utils.Money{"a": 2000, "b": 4000, "c": 4000},
+		},
+		{
+			name:   "Fractions covering the whole bill",
+			splits: []models.BillSplit{fraction("a", 1, 3), fraction("b", 1, 3), fraction("c", 1, 3)},
+			want:   map[string]utils.Money{"a": 3334, "b": 3333, "c": 3333},
+		},
+		{
+			name:    "Fixed amounts above the total",
+			splits:  []models.BillSplit{fixed("a", "60.00"), fixed("b", "50.00")},
+			wantErr: "fixed amounts exceed the bill total by 10.00",
+		},
+		{
+			name:    "Percentages above 100%",
+			splits:  []models.BillSplit{percentage("a", 60), percentage("b", 50)},
+			wantErr: "must not exceed 100%",
+		},
+		{
+			name:    "Nobody left to pay the rest",
+			splits:  []models.BillSplit{fixed("a", "10.00"), user("b", "exclude"), user("c", "exclude"), excludeCouple},
+			wantErr: "split rules leave 90.00 unassigned",
+		},
+		{
+			name:    "Users in a group are split as their group",
+			splits:  []models.BillSplit{user("couple-member", "exclude")},
+			wantErr: "not an active allocation subject",
+		},
+		{
+			name:    "One rule per person",
+			splits:  []models.BillSplit{user("a", "exclude"), shares("a", 1)},
+			wantErr: "already has a split rule",
+		},
+		{
+			name:    "Unknown rule type",
+			splits:  []models.BillSplit{user("a", "half")},
+			wantErr: "invalid allocation type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakdown, err := calculateCustomSplit(10000, tt.splits, subjects)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			got := make(map[string]utils.Money)
+			var sum utils.Money
+			for _, entry := range breakdown {
+				got[entry.SubjectID] = entry.Amount
+				sum = sum.Add(entry.Amount)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, utils.Money(10000), sum, "split must add up to the bill total")
+		})
+	}
+}
+
+func TestDaysPresentInPeriod(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, time.November, d, 0, 0, 0, 0, time.UTC) }
+	dayPtr := func(d int) *time.Time { t := day(d); return &t }
+
+	tests := []struct {
+		name        string
+		residencies []models.Residency
+		want        int
+	}{
+		{"Lived there the whole month", []models.Residency{{MovedInAt: day(1).AddDate(-1, 0, 0)}}, 30},
+		{"Moved out on the 10th", []models.Residency{{MovedInAt: day(1).AddDate(-1, 0, 0), MovedOutAt: dayPtr(10)}}, 10},
+		{"Moved in on the 21st", []models.Residency{{MovedInAt: day(21)}}, 10},
+		{"Left and came back", []models.Residency{
+			{MovedInAt: day(1).AddDate(0, -1, 0), MovedOutAt: dayPtr(5)},
+			{MovedInAt: day(26)},
+		}, 10},
+		{"Moved in after the period", []models.Residency{{MovedInAt: day(1).AddDate(0, 1, 0)}}, 0},
+		{"Time of day is ignored", []models.Residency{{MovedInAt: day(30).Add(23 * time.Hour)}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, 30, daysInPeriod(day(1), day(30)))
+			assert.Equal(t, tt.want, daysPresentInPeriod(tt.residencies, day(1), day(30)))
+		})
+	}
+}
+
+func TestSimpleAllocationProratesByResidency(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	_, allocationService := newTestBillService(repos)
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+	carol := createTestUser(t, repos, "Carol")
+
+	periodStart := time.Date(2025, time.November, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := time.Date(2025, time.November, 30, 0, 0, 0, 0, time.UTC)
+
+	// Bob moved out on the 10th and was deactivated, Carol only moves in next month
+	movedOut := time.Date(2025, time.November, 10, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, repos.Residencies.Create(ctx, &models.Residency{
+		UserID: bob.ID, MovedInAt: periodStart.AddDate(-1, 0, 0), MovedOutAt: &movedOut,
+	}))
+	bob.IsActive = false
+	require.NoError(t, repos.Users.Update(ctx, bob))
+	require.NoError(t, repos.Residencies.Create(ctx, &models.Residency{
+		UserID: carol.ID, MovedInAt: periodEnd.AddDate(0, 0, 1),
+	}))
+
+	bill := &models.Bill{
+		Type:           "gas",
+		PeriodStart:    periodStart,
+		PeriodEnd:      periodEnd,
+		TotalAmountPLN: "100.00",
+		Status:         "draft",
+	}
+	require.NoError(t, repos.Bills.Create(ctx, bill))
+
+	breakdown, err := allocationService.CalculateSimpleAllocation(ctx, bill)
+	require.NoError(t, err)
+	require.Len(t, breakdown, 2)
+
+	byID := make(map[string]AllocationBreakdown)
+	for _, entry := range breakdown {
+		byID[entry.SubjectID] = entry
+	}
+
+	// Alice weighs 1 and Bob 10/30, so Alice pays three quarters
+	assert.Equal(t, utils.Money(7500), byID[alice.ID].Amount)
+	assert.Nil(t, byID[alice.ID].Proration, "full-period residents are not prorated")
+	assert.Equal(t, utils.Money(2500), byID[bob.ID].Amount)
+	require.Len(t, byID[bob.ID].Proration, 1)
+	assert.Equal(t, 10, byID[bob.ID].Proration[0].DaysPresent)
+	assert.Equal(t, 30, byID[bob.ID].Proration[0].PeriodDays)
+}
+
+func TestPostedBillKeepsResidents(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	billService, allocationService := newTestBillService(repos)
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+
+	periodStart := time.Date(2025, time.November, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := time.Date(2025, time.November, 30, 0, 0, 0, 0, time.UTC)
+	movedOut := time.Date(2025, time.November, 10, 0, 0, 0, 0, time.UTC)
+	residency := &models.Residency{UserID: bob.ID, MovedInAt: periodStart.AddDate(-1, 0, 0), MovedOutAt: &movedOut}
+	require.NoError(t, repos.Residencies.Create(ctx, residency))
+
+	simple, err := billService.CreateBill(ctx, CreateBillRequest{
+		Type: "gas", PeriodStart: periodStart, PeriodEnd: periodEnd, TotalAmountPLN: utils.NewMoney(100, 0),
+	}, alice.ID)
+	require.NoError(t, err)
+	fixedAmount := "40.00"
+	custom, err := billService.CreateBill(ctx, CreateBillRequest{
+		Type: "inne", CustomType: stringPtr("Cleaning"), PeriodStart: periodStart, PeriodEnd: periodEnd,
+		TotalAmountPLN: utils.NewMoney(100, 0),
+		Splits: []models.BillSplit{
+			{SubjectType: "user", SubjectID: alice.ID, AllocationType: "fixed", FixedAmount: &fixedAmount},
+		},
+	}, alice.ID)
+	require.NoError(t, err)
+	require.NoError(t, billService.PostBill(ctx, simple.ID))
+	require.NoError(t, billService.PostBill(ctx, custom.ID))
+
+	// Bob's move-out is corrected after the bills were posted
+	residency.MovedOutAt = nil
+	require.NoError(t, repos.Residencies.Update(ctx, residency))
+
+	shares := func(billID string) map[string]AllocationBreakdown {
+		breakdown, err := allocationService.GetAllocationBreakdown(ctx, billID)
+		require.NoError(t, err)
+		byID := make(map[string]AllocationBreakdown)
+		for _, entry := range breakdown {
+			byID[entry.SubjectID] = entry
+		}
+		return byID
+	}
+
+	// The simple bill is still split by the presence stored when it was posted
+	byID := shares(simple.ID)
+	assert.Equal(t, utils.Money(7500), byID[alice.ID].Amount)
+	assert.Equal(t, utils.Money(2500), byID[bob.ID].Amount)
+	require.Len(t, byID[bob.ID].Proration, 1)
+	assert.Equal(t, 10, byID[bob.ID].Proration[0].DaysPresent)
+
+	// Stored allocations come with the same presence
+	byID = shares(custom.ID)
+	assert.Equal(t, utils.Money(6000), byID[bob.ID].Amount)
+	require.Len(t, byID[bob.ID].Proration, 1)
+	assert.Equal(t, 10, byID[bob.ID].Proration[0].DaysPresent)
+	assert.Nil(t, byID[alice.ID].Proration)
+
+	// A new draft follows the corrected dates
+	draft := &models.Bill{Type: "gas", PeriodStart: periodStart, PeriodEnd: periodEnd, TotalAmountPLN: "100.00", Status: "draft"}
+	require.NoError(t, repos.Bills.Create(ctx, draft))
+	byID = shares(draft.ID)
+	assert.Equal(t, utils.Money(5000), byID[bob.ID].Amount)
+	assert.Nil(t, byID[bob.ID].Proration)
+}
+
+func TestCalculateItemizedSplit(t *testing.T) {
+	subjects := []allocationSubject{
+		{id: "couple", subjectType: "group", name: "Couple", weight: 1, totalWeight: 2,
+			presence: []ResidentPresence{{UserID: "m1"}, {UserID: "m2"}}},
+		{id: "a", subjectType: "user", name: "A", weight: 1, totalWeight: 1, presence: []ResidentPresence{{UserID: "a"}}},
+		{id: "b", subjectType: "user", name: "B", weight: 1, totalWeight: 1, presence: []ResidentPresence{{UserID: "b"}}},
+	}
+	users := func(ids ...string) []models.BillItemParticipant {
+		participants := make([]models.BillItemParticipant, len(ids))
+		for i, id := range ids {
+			participants[i] = models.BillItemParticipant{SubjectType: "user", SubjectID: id}
+		}
+		return participants
+	}
+
+	tests := []struct {
+		name    string
+		total   utils.Money
+		items   []models.BillItem
+		want    map[string]utils.Money
+		wantErr string
+	}{
+		{
+			name:  "Lines are summed per person and tax spread by subtotal",
+			total: 5500,
+			items: []models.BillItem{
+				{Kind: "item", Description: "Pizza", Amount: "30.00", Participants: users("a", "b", "m1")},
+				{Kind: "item", Description: "Wine", Amount: "20.00", Participants: users("a")},
+				{Kind: "tax", Description: "Service", Amount: "5.00"},
+			},
+			want: map[string]utils.Money{"couple": 1100, "a": 3300, "b": 1100},
+		},
+		{
+			name:  "Discount lowers everyone's share",
+			total: 900,
+			items: []models.BillItem{
+				{Kind: "item", Amount: "10.00", Participants: users("a", "b")},
+				{Kind: "discount", Amount: "1.00"},
+			},
+			want: map[string]utils.Money{"a": 450, "b": 450},
+		},
+		{
+			name:  "Both group members take a share each",
+			total: 900,
+			items: []models.BillItem{
+				{Kind: "item", Amount: "9.00", Participants: users("m1", "m2", "b")},
+			},
+			want: map[string]utils.Money{"couple": 600, "b": 300},
+		},
+		{
+			name:    "Lines must add up to the total",
+			total:   1000,
+			items:   []models.BillItem{{Kind: "item", Amount: "9.99", Participants: users("a")}},
+			wantErr: "receipt items add up to 9.99, not the bill total 10.00",
+		},
+		{
+			name:    "Participants must be residents",
+			total:   1000,
+			items:   []models.BillItem{{Kind: "item", Amount: "10.00", Participants: users("stranger")}},
+			wantErr: "did not live in the household",
+		},
+		{
+			name:    "Items need participants",
+			total:   1000,
+			items:   []models.BillItem{{Kind: "item", Amount: "10.00"}},
+			wantErr: "at least one participant is required",
+		},
+		{
+			name:  "Tax is spread over everyone",
+			total: 1100,
+			items: []models.BillItem{
+				{Kind: "item", Amount: "10.00", Participants: users("a")},
+				{Kind: "tax", Amount: "1.00", Participants: users("a")},
+			},
+			wantErr: "take no participants",
+		},
+		{
+			name:    "Unknown kind",
+			total:   1000,
+			items:   []models.BillItem{{Kind: "tip", Amount: "10.00"}},
+			wantErr: "invalid kind",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakdown, err := calculateItemizedSplit(tt.total, tt.items, subjects)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			got := make(map[string]utils.Money)
+			for _, entry := range breakdown {
+				got[entry.SubjectID] = entry.Amount
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
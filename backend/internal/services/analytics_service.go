@@ -0,0 +1,505 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+// Analytics groupings
+const (
+	AnalyticsGroupByMonth   = "month"
+	AnalyticsGroupByQuarter = "quarter"
+	AnalyticsGroupByYear    = "year"
+)
+
+type AnalyticsService struct {
+	analytics       repository.AnalyticsRepository
+	users           repository.UserRepository
+	currencyService *CurrencyService
+	location        *time.Location // periods follow the household's time zone, the server's local one
+}
+
+func NewAnalyticsService(analytics repository.AnalyticsRepository, users repository.UserRepository, currencyService *CurrencyService) *AnalyticsService {
+	return &AnalyticsService{
+		analytics:       analytics,
+		users:           users,
+		currencyService: currencyService,
+		location:        time.Local,
+	}
+}
+
+// AnalyticsQuery limits a report to a date range and sets how it is grouped
+type AnalyticsQuery struct {
+	From     *time.Time // inclusive
+	To       *time.Time // exclusive
+	GroupBy  string     // month (default), quarter, year
+	BillType string     // empty for every bill type
+}
+
+// CostLine is what the bills of one type cost in a period
+type CostLine struct {
+	BillType   string      `json:"billType"`
+	CustomType string      `json:"customType,omitempty"`
+	Amount     utils.Money `json:"amount"`
+	Bills      int         `json:"bills"`
+}
+
+// CostPeriod is the cost of bills in one period
+type CostPeriod struct {
+	Period string      `json:"period"` // YYYY-MM, YYYY-Qn or YYYY
+	Total  utils.Money `json:"total"`
+	Lines  []CostLine  `json:"lines"`
+}
+
+// CostReport is the cost of posted bills per type over time. Bills count in the period they start in.
+type CostReport struct {
+	Currency string       `json:"currency"` // base currency of all amounts
+	GroupBy  string       `json:"groupBy"`
+	Total    utils.Money  `json:"total"`
+	Periods  []CostPeriod `json:"periods"`
+}
+
+// GetCosts returns the cost of posted bills per type and period
+func (s *AnalyticsService) GetCosts(ctx context.Context, query AnalyticsQuery) (*CostReport, error) {
+	filter, err := s.filter(query)
+	if err != nil {
+		return nil, err
+	}
+	aggregates, err := s.analytics.BillCosts(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate bill costs: %w", err)
+	}
+	baseCurrency, err := s.currencyService.BaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CostReport{Currency: baseCurrency, GroupBy: filter.GroupBy, Periods: []CostPeriod{}}
+	index := make(map[string]int)
+	for _, aggregate := range aggregates {
+		amount, err := s.convert(ctx, aggregate.Amount, aggregate.Currency, aggregate.Period)
+		if err != nil {
+			return nil, err
+		}
+		if len(report.Periods) == 0 || report.Periods[len(report.Periods)-1].Period != aggregate.Period {
+			report.Periods = append(report.Periods, CostPeriod{Period: aggregate.Period, Lines: []CostLine{}})
+		}
+		period := &report.Periods[len(report.Periods)-1]
+
+		// Bills of one type in other currencies are merged into one line
+		key := aggregate.Period + "|" + aggregate.BillType + "|" + aggregate.CustomType
+		if i, ok := index[key]; ok {
+			period.Lines[i].Amount = period.Lines[i].Amount.Add(amount)
+			period.Lines[i].Bills += aggregate.BillCount
+		} else {
+			index[key] = len(period.Lines)
+			period.Lines = append(period.Lines, CostLine{
+				BillType:   aggregate.BillType,
+				CustomType: aggregate.CustomType,
+				Amount:     amount,
+				Bills:      aggregate.BillCount,
+			})
+		}
+		period.Total = period.Total.Add(amount)
+		report.Total = report.Total.Add(amount)
+	}
+	return report, nil
+}
+
+// PersonSpend is what one person paid towards bills and spent on supplies
+type PersonSpend struct {
+	UserID          string      `json:"userId"`
+	UserName        string      `json:"userName"`
+	BillPayments    utils.Money `json:"billPayments"`
+	SupplyPurchases utils.Money `json:"supplyPurchases"`
+	Total           utils.Money `json:"total"`
+}
+
+// PersonSpendPeriod is what everyone spent in one period
+type PersonSpendPeriod struct {
+	Period string        `json:"period"`
+	People []PersonSpend `json:"people"`
+}
+
+// PersonSpendReport is what each person spent over time
+type PersonSpendReport struct {
+	Currency string              `json:"currency"`
+	GroupBy  string              `json:"groupBy"`
+	Periods  []PersonSpendPeriod `json:"periods"`
+	Totals   []PersonSpend       `json:"totals"` // over the whole range, highest first
+}
+
+// GetPersonSpend returns what each person paid towards bills and spent on supplies, per period.
+// The bill type filter applies to bill payments only.
+func (s *AnalyticsService) GetPersonSpend(ctx context.Context, query AnalyticsQuery) (*PersonSpendReport, error) {
+	filter, err := s.filter(query)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := s.analytics.BillPayments(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate payments: %w", err)
+	}
+	supplies, err := s.analytics.SupplySpend(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate supply spending: %w", err)
+	}
+	baseCurrency, err := s.currencyService.BaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byPeriod := make(map[string]map[string]*PersonSpend)
+	totals := make(map[string]*PersonSpend)
+	spend := func(period, userID string) (*PersonSpend, *PersonSpend) {
+		if byPeriod[period] == nil {
+			byPeriod[period] = make(map[string]*PersonSpend)
+		}
+		if byPeriod[period][userID] == nil {
+			byPeriod[period][userID] = &PersonSpend{UserID: userID}
+		}
+		if totals[userID] == nil {
+			totals[userID] = &PersonSpend{UserID: userID}
+		}
+		return byPeriod[period][userID], totals[userID]
+	}
+
+	for _, payment := range payments {
+		amount, err := s.convert(ctx, payment.Amount, payment.Currency, payment.Period)
+		if err != nil {
+			return nil, err
+		}
+		inPeriod, total := spend(payment.Period, payment.UserID)
+		inPeriod.BillPayments = inPeriod.BillPayments.Add(amount)
+		total.BillPayments = total.BillPayments.Add(amount)
+	}
+	for _, supply := range supplies {
+		inPeriod, total := spend(supply.Period, supply.UserID)
+		inPeriod.SupplyPurchases = inPeriod.SupplyPurchases.Add(supply.Amount)
+		total.SupplyPurchases = total.SupplyPurchases.Add(supply.Amount)
+	}
+
+	names, err := s.userNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collect := func(people map[string]*PersonSpend) []PersonSpend {
+		result := make([]PersonSpend, 0, len(people))
+		for _, person := range people {
+			person.UserName = names[person.UserID]
+			person.Total = person.BillPayments.Add(person.SupplyPurchases)
+			result = append(result, *person)
+		}
+		sort.Slice(result, func(i, j int) bool {
+			if result[i].Total != result[j].Total {
+				return result[i].Total > result[j].Total
+			}
+			return result[i].UserName < result[j].UserName
+		})
+		return result
+	}
+
+	report := &PersonSpendReport{Currency: baseCurrency, GroupBy: filter.GroupBy, Periods: []PersonSpendPeriod{}}
+	for _, period := range sortedKeys(byPeriod) {
+		report.Periods = append(report.Periods, PersonSpendPeriod{Period: period, People: collect(byPeriod[period])})
+	}
+	report.Totals = collect(totals)
+	return report, nil
+}
+
+// YearOverYearMonth compares one month with the same month a year earlier
+type YearOverYearMonth struct {
+	Month         int         `json:"month"` // 1-12
+	Current       utils.Money `json:"current"`
+	Previous      utils.Money `json:"previous"`
+	Change        utils.Money `json:"change"`
+	ChangePercent *float64    `json:"changePercent,omitempty"` // nil when the previous year had no cost
+}
+
+// YearOverYearReport compares the bill costs of a year with the year before, month by month
+type YearOverYearReport struct {
+	Year          int                 `json:"year"`
+	PreviousYear  int                 `json:"previousYear"`
+	BillType      string              `json:"billType,omitempty"`
+	Currency      string              `json:"currency"`
+	Months        []YearOverYearMonth `json:"months"`
+	Current       utils.Money         `json:"current"`
+	Previous      utils.Money         `json:"previous"`
+	Change        utils.Money         `json:"change"`
+	ChangePercent *float64            `json:"changePercent,omitempty"`
+}
+
+// GetYearOverYear compares the cost of posted bills in a year with the year before
+func (s *AnalyticsService) GetYearOverYear(ctx context.Context, year int, billType string) (*YearOverYearReport, error) {
+	if year < 2000 || year > 9999 {
+		return nil, errors.New("invalid year")
+	}
+	from := time.Date(year-1, 1, 1, 0, 0, 0, 0, s.location)
+	to := time.Date(year+1, 1, 1, 0, 0, 0, 0, s.location)
+	costs, err := s.GetCosts(ctx, AnalyticsQuery{From: &from, To: &to, GroupBy: AnalyticsGroupByMonth, BillType: billType})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &YearOverYearReport{
+		Year:         year,
+		PreviousYear: year - 1,
+		BillType:     billType,
+		Currency:     costs.Currency,
+		Months:       make([]YearOverYearMonth, 12),
+	}
+	for i := range report.Months {
+		report.Months[i].Month = i + 1
+	}
+	for _, period := range costs.Periods {
+		periodYear, _ := strconv.Atoi(period.Period[:4])
+		month, _ := strconv.Atoi(period.Period[5:7])
+		if periodYear == year {
+			report.Months[month-1].Current = period.Total
+		} else {
+			report.Months[month-1].Previous = period.Total
+		}
+	}
+	for i := range report.Months {
+		m := &report.Months[i]
+		m.Change = m.Current.Sub(m.Previous)
+		m.ChangePercent = changePercent(m.Current, m.Previous)
+		report.Current = report.Current.Add(m.Current)
+		report.Previous = report.Previous.Add(m.Previous)
+	}
+	report.Change = report.Current.Sub(report.Previous)
+	report.ChangePercent = changePercent(report.Current, report.Previous)
+	return report, nil
+}
+
+// UnitPricePoint is the average price of a metered utility in a period
+type UnitPricePoint struct {
+	Period     string      `json:"period"`
+	BillType   string      `json:"billType"`
+	CustomType string      `json:"customType,omitempty"`
+	Units      float64     `json:"units"`
+	Amount     utils.Money `json:"amount"`
+	UnitPrice  float64     `json:"unitPrice"` // base currency per unit, fixed fees included
+}
+
+// UnitPriceReport is the trend of what a unit of each metered utility cost
+type UnitPriceReport struct {
+	Currency string           `json:"currency"`
+	GroupBy  string           `json:"groupBy"`
+	Points   []UnitPricePoint `json:"points"`
+}
+
+// GetUnitPrices returns the average unit price of metered utilities per period, from the
+// posted bills that recorded units
+func (s *AnalyticsService) GetUnitPrices(ctx context.Context, query AnalyticsQuery) (*UnitPriceReport, error) {
+	filter, err := s.filter(query)
+	if err != nil {
+		return nil, err
+	}
+	aggregates, err := s.analytics.MeteredCosts(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate metered costs: %w", err)
+	}
+	baseCurrency, err := s.currencyService.BaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &UnitPriceReport{Currency: baseCurrency, GroupBy: filter.GroupBy, Points: []UnitPricePoint{}}
+	index := make(map[string]int)
+	for _, aggregate := range aggregates {
+		amount, err := s.convert(ctx, aggregate.Amount, aggregate.Currency, aggregate.Period)
+		if err != nil {
+			return nil, err
+		}
+		key := aggregate.Period + "|" + aggregate.BillType + "|" + aggregate.CustomType
+		if i, ok := index[key]; ok {
+			report.Points[i].Amount = report.Points[i].Amount.Add(amount)
+			report.Points[i].Units += aggregate.Units
+			continue
+		}
+		index[key] = len(report.Points)
+		report.Points = append(report.Points, UnitPricePoint{
+			Period:     aggregate.Period,
+			BillType:   aggregate.BillType,
+			CustomType: aggregate.CustomType,
+			Units:      aggregate.Units,
+			Amount:     amount,
+		})
+	}
+	for i := range report.Points {
+		point := &report.Points[i]
+		point.UnitPrice = math.Round(point.Amount.Float64()/point.Units*10000) / 10000
+	}
+	return report, nil
+}
+
+// SupplySpendLine is what was spent on one supply category in a period
+type SupplySpendLine struct {
+	Category  string      `json:"category"`
+	Amount    utils.Money `json:"amount"`
+	Purchases int         `json:"purchases"`
+}
+
+// SupplyBuyer is what one person spent on supplies in a period
+type SupplyBuyer struct {
+	UserID   string      `json:"userId"`
+	UserName string      `json:"userName"`
+	Amount   utils.Money `json:"amount"`
+}
+
+// SupplySpendPeriod is the supply spending of one period
+type SupplySpendPeriod struct {
+	Period     string            `json:"period"`
+	Total      utils.Money       `json:"total"`
+	Categories []SupplySpendLine `json:"categories"`
+	Buyers     []SupplyBuyer     `json:"buyers"`
+}
+
+// SupplySpendReport is the supply spending over time, from every recorded restock
+type SupplySpendReport struct {
+	Currency string              `json:"currency"`
+	GroupBy  string              `json:"groupBy"`
+	Total    utils.Money         `json:"total"`
+	Periods  []SupplySpendPeriod `json:"periods"`
+}
+
+// GetSupplySpend returns what was spent on supplies per period, by category and buyer
+func (s *AnalyticsService) GetSupplySpend(ctx context.Context, query AnalyticsQuery) (*SupplySpendReport, error) {
+	filter, err := s.filter(query)
+	if err != nil {
+		return nil, err
+	}
+	aggregates, err := s.analytics.SupplySpend(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate supply spending: %w", err)
+	}
+	baseCurrency, err := s.currencyService.BaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names, err := s.userNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SupplySpendReport{Currency: baseCurrency, GroupBy: filter.GroupBy, Periods: []SupplySpendPeriod{}}
+	for _, aggregate := range aggregates {
+		if len(report.Periods) == 0 || report.Periods[len(report.Periods)-1].Period != aggregate.Period {
+			report.Periods = append(report.Periods, SupplySpendPeriod{Period: aggregate.Period, Categories: []SupplySpendLine{}, Buyers: []SupplyBuyer{}})
+		}
+		period := &report.Periods[len(report.Periods)-1]
+		period.Total = period.Total.Add(aggregate.Amount)
+		report.Total = report.Total.Add(aggregate.Amount)
+
+		if n := len(period.Categories); n > 0 && period.Categories[n-1].Category == aggregate.Category {
+			period.Categories[n-1].Amount = period.Categories[n-1].Amount.Add(aggregate.Amount)
+			period.Categories[n-1].Purchases += aggregate.Purchases
+		} else {
+			period.Categories = append(period.Categories, SupplySpendLine{Category: aggregate.Category, Amount: aggregate.Amount, Purchases: aggregate.Purchases})
+		}
+
+		found := false
+		for i := range period.Buyers {
+			if period.Buyers[i].UserID == aggregate.UserID {
+				period.Buyers[i].Amount = period.Buyers[i].Amount.Add(aggregate.Amount)
+				found = true
+				break
+			}
+		}
+		if !found {
+			period.Buyers = append(period.Buyers, SupplyBuyer{UserID: aggregate.UserID, UserName: names[aggregate.UserID], Amount: aggregate.Amount})
+		}
+	}
+	return report, nil
+}
+
+// filter validates a query and turns it into a repository filter of posted bills
+func (s *AnalyticsService) filter(query AnalyticsQuery) (repository.AnalyticsFilter, error) {
+	filter := repository.AnalyticsFilter{
+		From:     query.From,
+		To:       query.To,
+		GroupBy:  query.GroupBy,
+		BillType: strings.ToLower(strings.TrimSpace(query.BillType)),
+		Statuses: IssuedBillStatuses,
+		Location: s.location,
+	}
+	switch filter.GroupBy {
+	case "":
+		filter.GroupBy = AnalyticsGroupByMonth
+	case AnalyticsGroupByMonth, AnalyticsGroupByQuarter, AnalyticsGroupByYear:
+	default:
+		return filter, errors.New("groupBy must be 'month', 'quarter' or 'year'")
+	}
+	switch filter.BillType {
+	case "", "electricity", "gas", "internet", "inne":
+	default:
+		return filter, fmt.Errorf("invalid bill type: %q", query.BillType)
+	}
+	if filter.From != nil && filter.To != nil && !filter.To.After(*filter.From) {
+		return filter, errors.New("'to' must be after 'from'")
+	}
+	return filter, nil
+}
+
+// convert converts an amount of a period to the base currency at the rate of the period's first day
+func (s *AnalyticsService) convert(ctx context.Context, amount utils.Money, currency, period string) (utils.Money, error) {
+	converted, err := s.currencyService.Convert(ctx, amount, currency, periodStart(period))
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert %s costs of %s: %w", currency, period, err)
+	}
+	return converted, nil
+}
+
+func (s *AnalyticsService) userNames(ctx context.Context) (map[string]string, error) {
+	users, err := s.users.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	names := make(map[string]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.Name
+	}
+	return names, nil
+}
+
+// periodStart returns the first day of a YYYY-MM, YYYY-Qn or YYYY period
+func periodStart(period string) time.Time {
+	year, _ := strconv.Atoi(period[:min(4, len(period))])
+	month := 1
+	switch {
+	case len(period) == 7 && period[5] == 'Q':
+		quarter, _ := strconv.Atoi(period[6:])
+		month = (quarter-1)*3 + 1
+	case len(period) == 7:
+		month, _ = strconv.Atoi(period[5:])
+	}
+	return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// changePercent returns how much current changed from previous in percent, or nil without a previous amount
+func changePercent(current, previous utils.Money) *float64 {
+	if previous.IsZero() {
+		return nil
+	}
+	percent := math.Round(float64(current.Sub(previous).Grosze())*10000/float64(previous.Grosze())) / 100
+	return &percent
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalytics(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	billService, paymentService, _ := newTestCreditServices(repos)
+	currencyService := NewCurrencyService(repos.ExchangeRates, NewAppSettingsService(repos.AppSettings))
+	supplyService := NewSupplyService(repos.SupplySettings, repos.SupplyItems, repos.SupplyContributions, repos.SupplyItemHistory,
+		repos.Users, repos.TxManager, nil, currencyService)
+	analyticsService := NewAnalyticsService(repos.Analytics, repos.Users, currencyService)
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	createBill := func(billType string, start, end time.Time, amount utils.Money, units float64, post bool) string {
+		req := CreateBillRequest{Type: billType, PeriodStart: start, PeriodEnd: end, TotalAmountPLN: amount}
+		if units > 0 {
+			req.TotalUnits = &units
+		}
+		bill, err := billService.CreateBill(ctx, req, alice.ID)
+		require.NoError(t, err)
+		if post {
+			require.NoError(t, billService.PostBill(ctx, bill.ID))
+		}
+		return bill.ID
+	}
+
+	createBill("electricity", day(2025, 1, 1), day(2025, 1, 31), utils.NewMoney(200, 0), 400, true)
+	createBill("electricity", day(2026, 1, 1), day(2026, 1, 31), utils.NewMoney(300, 0), 500, true)
+	internetID := createBill("internet", day(2026, 1, 5), day(2026, 2, 4), utils.NewMoney(90, 0), 0, true)
+	createBill("gas", day(2026, 2, 1), day(2026, 2, 28), utils.NewMoney(100, 0), 0, false) // drafts do not count
+
+	_, err := paymentService.RecordPayment(ctx, RecordPaymentRequest{BillID: internetID, Amount: utils.NewMoney(30, 0)}, bob.ID)
+	require.NoError(t, err)
+	item, err := supplyService.CreateItem(ctx, alice.ID, "Soap", "toiletries", 0, 1, "pcs", 2, nil)
+	require.NoError(t, err)
+	for _, cost := range []utils.Money{utils.NewMoney(8, 0), utils.NewMoney(4, 50)} {
+		cost := cost
+		_, err := supplyService.RestockItem(ctx, item.ID, bob.ID, 1, &cost, "", false)
+		require.NoError(t, err)
+	}
+
+	// Monthly cost per bill type within a range
+	from := day(2026, 1, 1)
+	costs, err := analyticsService.GetCosts(ctx, AnalyticsQuery{From: &from})
+	require.NoError(t, err)
+	require.Len(t, costs.Periods, 1)
+	assert.Equal(t, "2026-01", costs.Periods[0].Period)
+	assert.Equal(t, []CostLine{
+		{BillType: "electricity", Amount: utils.NewMoney(300, 0), Bills: 1},
+		{BillType: "internet", Amount: utils.NewMoney(90, 0), Bills: 1},
+	}, costs.Periods[0].Lines)
+	assert.Equal(t, "390.00", costs.Total.String())
+
+	costs, err = analyticsService.GetCosts(ctx, AnalyticsQuery{GroupBy: AnalyticsGroupByQuarter})
+	require.NoError(t, err)
+	var periods []string
+	for _, period := range costs.Periods {
+		periods = append(periods, period.Period+" "+period.Total.String())
+	}
+	assert.Equal(t, []string{"2025-Q1 200.00", "2026-Q1 390.00"}, periods)
+
+	_, err = analyticsService.GetCosts(ctx, AnalyticsQuery{GroupBy: "week"})
+	assert.Error(t, err)
+
+	// Year over year
+	yoy, err := analyticsService.GetYearOverYear(ctx, 2026, "electricity")
+	require.NoError(t, err)
+	january := yoy.Months[0]
+	assert.Equal(t, "300.00", january.Current.String())
+	assert.Equal(t, "200.00", january.Previous.String())
+	assert.Equal(t, "100.00", january.Change.String())
+	require.NotNil(t, january.ChangePercent)
+	assert.Equal(t, 50.0, *january.ChangePercent)
+	assert.Nil(t, yoy.Months[1].ChangePercent)
+
+	// Unit price trend of metered bills
+	prices, err := analyticsService.GetUnitPrices(ctx, AnalyticsQuery{})
+	require.NoError(t, err)
+	require.Len(t, prices.Points, 2)
+	assert.Equal(t, 0.5, prices.Points[0].UnitPrice)
+	assert.Equal(t, 0.6, prices.Points[1].UnitPrice)
+
+	// Supplies come from every restock, not only the latest one of each item
+	supplies, err := analyticsService.GetSupplySpend(ctx, AnalyticsQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, "12.50", supplies.Total.String())
+	require.Len(t, supplies.Periods, 1)
+	assert.Equal(t, []SupplySpendLine{{Category: "toiletries", Amount: utils.NewMoney(12, 50), Purchases: 2}}, supplies.Periods[0].Categories)
+
+	people, err := analyticsService.GetPersonSpend(ctx, AnalyticsQuery{})
+	require.NoError(t, err)
+	require.Len(t, people.Totals, 1)
+	assert.Equal(t, "Bob", people.Totals[0].UserName)
+	assert.Equal(t, "30.00", people.Totals[0].BillPayments.String())
+	assert.Equal(t, "12.50", people.Totals[0].SupplyPurchases.String())
+	assert.Equal(t, "42.50", people.Totals[0].Total.String())
+
+	// Payments count in the month of the household's time zone, bills in the month their period starts
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	require.NoError(t, err)
+	analyticsService.location = warsaw
+	paidAt := time.Date(2026, 2, 28, 23, 30, 0, 0, time.UTC)
+	_, err = paymentService.RecordPayment(ctx, RecordPaymentRequest{BillID: internetID, Amount: utils.NewMoney(10, 0), PaidAt: &paidAt}, alice.ID)
+	require.NoError(t, err)
+	march, april := time.Date(2026, 3, 1, 0, 0, 0, 0, warsaw), time.Date(2026, 4, 1, 0, 0, 0, 0, warsaw)
+	people, err = analyticsService.GetPersonSpend(ctx, AnalyticsQuery{From: &march, To: &april})
+	require.NoError(t, err)
+	require.Len(t, people.Periods, 1)
+	assert.Equal(t, "2026-03", people.Periods[0].Period)
+	assert.Equal(t, "10.00", people.Periods[0].People[0].BillPayments.String())
+	costs, err = analyticsService.GetCosts(ctx, AnalyticsQuery{From: &from})
+	require.NoError(t, err)
+	assert.Equal(t, "2026-01", costs.Periods[0].Period)
+	assert.Equal(t, "390.00", costs.Total.String())
+}
@@ -34,6 +34,7 @@ func (s *AppSettingsService) GetSettings(ctx context.Context) (*models.AppSettin
 			DefaultLanguage:          "en",
 			DisableAutoDetect:        false,
 			ReminderRateLimitPerHour: 1,
+			BaseCurrency:             DefaultCurrency,
 			UpdatedAt:                time.Now(),
 		}
 
@@ -66,6 +67,7 @@ type UpdateSettingsInput struct {
 	DefaultLanguage          *string `json:"defaultLanguage"`
 	DisableAutoDetect        *bool   `json:"disableAutoDetect"`
 	ReminderRateLimitPerHour *int    `json:"reminderRateLimitPerHour"`
+	BaseCurrency             *string `json:"baseCurrency"`
 }
 
 // UpdateSettings updates app settings (ADMIN only - enforced at handler)
@@ -101,6 +103,16 @@ func (s *AppSettingsService) UpdateSettings(ctx context.Context, input UpdateSet
 		settings.ReminderRateLimitPerHour = *input.ReminderRateLimitPerHour
 	}
 
+	if input.BaseCurrency != nil {
+		// Exchange rates are expressed in the base currency, so they need to be
+		// re-entered by an admin after the base currency changes
+		baseCurrency, err := NormalizeCurrency(*input.BaseCurrency)
+		if err != nil {
+			return err
+		}
+		settings.BaseCurrency = baseCurrency
+	}
+
 	settings.UpdatedAt = time.Now()
 
 	if err := s.appSettings.Upsert(ctx, settings); err != nil {
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sainaif/holy-home/internal/config"
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+)
+
+// Records attachments can belong to
+const (
+	AttachmentResourceBill          = "bill"
+	AttachmentResourceSupplyRestock = "supply_restock" // receipt of one restock in the supply history
+	AttachmentResourceLoan          = "loan"
+	AttachmentResourceConsumption   = "consumption" // meter photo sent with a reading
+)
+
+// maxAttachmentNameLength limits stored file names, in characters
+const maxAttachmentNameLength = 255
+
+var (
+	// ErrAttachmentNotFound is returned when the attachment or the record it belongs to does not exist
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	// ErrAttachmentTooLarge is returned for files above the configured size limit
+	ErrAttachmentTooLarge = errors.New("attachment is too large")
+	// ErrAttachmentTypeNotAllowed is returned for files whose detected type is not accepted
+	ErrAttachmentTypeNotAllowed = errors.New("attachment type not allowed")
+)
+
+type AttachmentService struct {
+	attachments   repository.AttachmentRepository
+	bills         repository.BillRepository
+	supplyHistory repository.SupplyItemHistoryRepository
+	loans         repository.LoanRepository
+	consumptions  repository.ConsumptionRepository
+	txManager     repository.TxManager
+	store         *BlobStore
+	maxSize       int64
+	allowedTypes  []string
+	photoRetain   int // days reading photos are kept after their bill is closed, 0 keeps them
+
+	// mu keeps a blob from being removed while another upload of the same file is recorded.
+	// It is only taken inside a transaction, so the database connection is always acquired first
+	// and an upload joined to a caller's transaction cannot deadlock with a removal.
+	mu sync.Mutex
+}
+
+func NewAttachmentService(
+	attachments repository.AttachmentRepository,
+	bills repository.BillRepository,
+	supplyHistory repository.SupplyItemHistoryRepository,
+	loans repository.LoanRepository,
+	consumptions repository.ConsumptionRepository,
+	txManager repository.TxManager,
+	store *BlobStore,
+	cfg *config.Config,
+) *AttachmentService {
+	return &AttachmentService{
+		attachments:   attachments,
+		bills:         bills,
+		supplyHistory: supplyHistory,
+		loans:         loans,
+		consumptions:  consumptions,
+		txManager:     txManager,
+		store:         store,
+		maxSize:       cfg.Attachments.MaxSizeBytes,
+		allowedTypes:  cfg.Attachments.AllowedTypes,
+		photoRetain:   cfg.Attachments.ReadingPhotoRetentionDays,
+	}
+}
+
+// MaxSize returns the largest accepted upload in bytes
+func (s *AttachmentService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Upload stores a file and attaches it to a bill, supply item, loan or meter reading.
+// The content type is detected from the file contents, not taken from the client.
+func (s *AttachmentService) Upload(ctx context.Context, resourceType, resourceID, fileName string, data []byte, uploadedBy string) (*models.Attachment, error) {
+	if err := s.checkResource(ctx, resourceType, resourceID); err != nil {
+		return nil, err
+	}
+	contentType, err := s.checkFile(resourceType, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachment *models.Attachment
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		hash, err := s.store.Put(data)
+		if err != nil {
+			return fmt.Errorf("failed to store attachment: %w", err)
+		}
+
+		attachment = &models.Attachment{
+			ID:           uuid.New().String(),
+			ResourceType: resourceType,
+			ResourceID:   resourceID,
+			FileName:     cleanAttachmentName(fileName),
+			ContentType:  contentType,
+			SizeBytes:    int64(len(data)),
+			SHA256:       hash,
+			UploadedBy:   &uploadedBy,
+			CreatedAt:    time.Now(),
+		}
+		if err := s.attachments.Create(ctx, attachment); err != nil {
+			s.removeUnusedBlob(ctx, hash)
+			return fmt.Errorf("failed to create attachment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// ListAttachments returns the attachments of a record
+func (s *AttachmentService) ListAttachments(ctx context.Context, resourceType, resourceID string) ([]models.Attachment, error) {
+	if err := s.checkResource(ctx, resourceType, resourceID); err != nil {
+		return nil, err
+	}
+	return s.attachments.ListByResource(ctx, resourceType, resourceID)
+}
+
+// ReadingPhoto returns the latest photo sent with a meter reading, or nil when it has none
+func (s *AttachmentService) ReadingPhoto(ctx context.Context, consumptionID string) (*models.Attachment, error) {
+	photos, err := s.attachments.ListByResource(ctx, AttachmentResourceConsumption, consumptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reading photo: %w", err)
+	}
+	if len(photos) == 0 {
+		return nil, nil
+	}
+	return &photos[len(photos)-1], nil
+}
+
+// Download returns an attachment of a record with its file contents
+func (s *AttachmentService) Download(ctx context.Context, resourceType, resourceID, attachmentID string) (*models.Attachment, []byte, error) {
+	attachment, err := s.getAttachment(ctx, resourceType, resourceID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := s.store.Get(attachment.SHA256)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read attachment file: %w", err)
+	}
+	return attachment, data, nil
+}
+
+// DeleteAttachment removes an attachment of a record. The file is removed once no attachment uses it.
+func (s *AttachmentService) DeleteAttachment(ctx context.Context, resourceType, resourceID, attachmentID string) error {
+	attachment, err := s.getAttachment(ctx, resourceType, resourceID, attachmentID)
+	if err != nil {
+		return err
+	}
+
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if err := s.attachments.Delete(ctx, attachment.ID); err != nil {
+			return fmt.Errorf("failed to delete attachment: %w", err)
+		}
+		s.removeUnusedBlob(ctx, attachment.SHA256)
+		return nil
+	})
+}
+
+// RemoveOrphanedFiles deletes stored files no attachment refers to anymore, such as the
+// files of deleted bills, loans and supply items. Returns how many files were removed.
+func (s *AttachmentService) RemoveOrphanedFiles(ctx context.Context) (int, error) {
+	removed := 0
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		hashes, err := s.store.List()
+		if err != nil {
+			return fmt.Errorf("failed to list attachment files: %w", err)
+		}
+
+		for _, hash := range hashes {
+			count, err := s.attachments.CountBySHA256(ctx, hash)
+			if err != nil {
+				return fmt.Errorf("failed to count attachments: %w", err)
+			}
+			if count > 0 {
+				continue
+			}
+			if err := s.store.Delete(hash); err != nil {
+				return fmt.Errorf("failed to delete attachment file: %w", err)
+			}
+			removed++
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// RemoveExpiredReadingPhotos deletes the photos of meter readings on bills closed for longer than the
+// retention period allows, counted from the upload. Photos of readings marked invalid are kept as the
+// evidence for the invalidation. Returns how many photos were removed.
+func (s *AttachmentService) RemoveExpiredReadingPhotos(ctx context.Context, now time.Time) (int, error) {
+	if s.photoRetain <= 0 {
+		return 0, nil
+	}
+	cutoff := now.AddDate(0, 0, -s.photoRetain)
+
+	attachments, err := s.attachments.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list attachments: %w", err)
+	}
+
+	removed := 0
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		for _, attachment := range attachments {
+			if attachment.ResourceType != AttachmentResourceConsumption || !attachment.CreatedAt.Before(cutoff) {
+				continue
+			}
+			consumption, err := s.consumptions.GetByID(ctx, attachment.ResourceID)
+			if err != nil {
+				return fmt.Errorf("failed to get consumption: %w", err)
+			}
+			if consumption == nil || consumption.Source == "invalid" {
+				continue
+			}
+			bill, err := s.bills.GetByID(ctx, consumption.BillID)
+			if err != nil {
+				return fmt.Errorf("failed to get bill: %w", err)
+			}
+			if bill == nil || bill.Status != "closed" {
+				continue
+			}
+
+			if err := s.attachments.Delete(ctx, attachment.ID); err != nil {
+				return fmt.Errorf("failed to delete attachment: %w", err)
+			}
+			s.removeUnusedBlob(ctx, attachment.SHA256)
+			removed++
+		}
+		return nil
+	})
+	return removed, err
+}
+
+func (s *AttachmentService) getAttachment(ctx context.Context, resourceType, resourceID, attachmentID string) (*models.Attachment, error) {
+	attachment, err := s.attachments.GetByID(ctx, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	// Attachments are only reachable through the record they belong to, so the
+	// permission checked for that record's route applies
+	if attachment == nil || attachment.ResourceType != resourceType || attachment.ResourceID != resourceID {
+		return nil, ErrAttachmentNotFound
+	}
+	return attachment, nil
+}
+
+// checkResource verifies the record an attachment belongs to exists
+func (s *AttachmentService) checkResource(ctx context.Context, resourceType, resourceID string) error {
+	var exists bool
+	switch resourceType {
+	case AttachmentResourceBill:
+		bill, err := s.bills.GetByID(ctx, resourceID)
+		if err != nil {
+			return err
+		}
+		exists = bill != nil
+	case AttachmentResourceSupplyRestock:
+		// Only restocks are purchases with a receipt; consumption entries have none
+		entry, err := s.supplyHistory.GetByID(ctx, resourceID)
+		if err != nil {
+			return err
+		}
+		exists = entry != nil && entry.Action == "restock"
+	case AttachmentResourceLoan:
+		loan, err := s.loans.GetByID(ctx, resourceID)
+		if err != nil {
+			return err
+		}
+		exists = loan != nil
+	case AttachmentResourceConsumption:
+		consumption, err := s.consumptions.GetByID(ctx, resourceID)
+		if err != nil {
+			return err
+		}
+		exists = consumption != nil
+	default:
+		return fmt.Errorf("unsupported attachment resource type: %s", resourceType)
+	}
+
+	if !exists {
+		return fmt.Errorf("%w: %s %s does not exist", ErrAttachmentNotFound, strings.ReplaceAll(resourceType, "_", " "), resourceID)
+	}
+	return nil
+}
+
+// checkFile verifies an upload fits the size limit and has an accepted type, and returns the type.
+// Readings only take photos.
+func (s *AttachmentService) checkFile(resourceType string, data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", errors.New("attachment is empty")
+	}
+	if int64(len(data)) > s.maxSize {
+		return "", fmt.Errorf("%w: limit is %d MB", ErrAttachmentTooLarge, s.maxSize>>20)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if !s.typeAllowed(contentType) || (resourceType == AttachmentResourceConsumption && !strings.HasPrefix(contentType, "image/")) {
+		return "", fmt.Errorf("%w: %s", ErrAttachmentTypeNotAllowed, contentType)
+	}
+	return contentType, nil
+}
+
+func (s *AttachmentService) typeAllowed(contentType string) bool {
+	for _, allowed := range s.allowedTypes {
+		if strings.EqualFold(allowed, contentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeUnusedBlob deletes a stored file once no attachment refers to it.
+// Failures only leave an orphaned file behind, so they are logged.
+func (s *AttachmentService) removeUnusedBlob(ctx context.Context, hash string) {
+	count, err := s.attachments.CountBySHA256(ctx, hash)
+	if err != nil {
+		log.Printf("[ATTACHMENTS] Failed to count attachments for %s: %v", hash, err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+	if err := s.store.Delete(hash); err != nil {
+		log.Printf("[ATTACHMENTS] Failed to delete file %s: %v", hash, err)
+	}
+}
+
+// cleanAttachmentName keeps the base name of an uploaded file
+func cleanAttachmentName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return truncateRunes(name, maxAttachmentNameLength)
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sainaif/holy-home/internal/config"
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachments(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	billService, _, _ := newTestCreditServices(repos)
+
+	store, err := NewBlobStore(t.TempDir())
+	require.NoError(t, err)
+	attachmentService := NewAttachmentService(repos.Attachments, repos.Bills, repos.SupplyItemHistory, repos.Loans, repos.Consumptions, repos.TxManager, store, &config.Config{
+		Attachments: config.AttachmentsConfig{
+			MaxSizeBytes: 1 << 10,
+			AllowedTypes: []string{"application/pdf", "image/png"},
+		},
+	})
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+
+	bill, err := billService.CreateBill(ctx, CreateBillRequest{
+		Type:           "electricity",
+		PeriodStart:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:      time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		TotalAmountPLN: utils.NewMoney(100, 0),
+	}, alice.ID)
+	require.NoError(t, err)
+	loan := &models.Loan{ID: uuid.New().String(), LenderID: alice.ID, BorrowerID: bob.ID, AmountPLN: "50.00", Currency: DefaultCurrency, Status: "open"}
+	require.NoError(t, repos.Loans.Create(ctx, loan))
+
+	invoice := []byte("%PDF-1.4\n1 0 obj << /Type /Catalog >> endobj\n%%EOF\n")
+
+	attachment, err := attachmentService.Upload(ctx, AttachmentResourceBill, bill.ID, `C:\scans\faktura.pdf`, invoice, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "faktura.pdf", attachment.FileName)
+	assert.Equal(t, "application/pdf", attachment.ContentType)
+	assert.Equal(t, int64(len(invoice)), attachment.SizeBytes)
+
+	// The same file attached elsewhere shares the stored blob
+	loanAttachment, err := attachmentService.Upload(ctx, AttachmentResourceLoan, loan.ID, "receipt.pdf", invoice, bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, attachment.SHA256, loanAttachment.SHA256)
+	hashes, err := store.List()
+	require.NoError(t, err)
+	assert.Len(t, hashes, 1)
+
+	_, err = attachmentService.Upload(ctx, AttachmentResourceBill, bill.ID, "notes.txt", []byte("plain text"), alice.ID)
+	assert.ErrorIs(t, err, ErrAttachmentTypeNotAllowed, "the type is detected from the contents")
+	_, err = attachmentService.Upload(ctx, AttachmentResourceBill, bill.ID, "big.pdf", append(invoice, make([]byte, 1<<10)...), alice.ID)
+	assert.ErrorIs(t, err, ErrAttachmentTooLarge)
+	_, err = attachmentService.Upload(ctx, AttachmentResourceSupplyRestock, "missing", "receipt.pdf", invoice, alice.ID)
+	assert.ErrorIs(t, err, ErrAttachmentNotFound)
+
+	// Each restock keeps its own receipt, and the receipts go away with the item
+	supplyService := NewSupplyService(repos.SupplySettings, repos.SupplyItems, repos.SupplyContributions, repos.SupplyItemHistory,
+		repos.Users, repos.TxManager, nil, NewCurrencyService(repos.ExchangeRates, NewAppSettingsService(repos.AppSettings)))
+	item, err := supplyService.CreateItem(ctx, alice.ID, "Coffee", "groceries", 0, 1, "pcs", 1, nil)
+	require.NoError(t, err)
+	cost := utils.NewMoney(30, 0)
+	first, err := supplyService.RestockItem(ctx, item.ID, bob.ID, 1, &cost, "", false)
+	require.NoError(t, err)
+	second, err := supplyService.RestockItem(ctx, item.ID, alice.ID, 2, &cost, "", false)
+	require.NoError(t, err)
+	_, err = attachmentService.Upload(ctx, AttachmentResourceSupplyRestock, first.ID, "coffee.pdf", invoice, bob.ID)
+	require.NoError(t, err)
+	_, err = attachmentService.Upload(ctx, AttachmentResourceSupplyRestock, second.ID, "coffee.pdf", invoice, alice.ID)
+	require.NoError(t, err)
+	restocks, err := supplyService.GetRestocks(ctx, item.ID)
+	require.NoError(t, err)
+	require.Len(t, restocks, 2)
+	for _, restock := range restocks {
+		receipts, err := attachmentService.ListAttachments(ctx, AttachmentResourceSupplyRestock, restock.ID)
+		require.NoError(t, err)
+		require.Len(t, receipts, 1)
+		assert.Equal(t, restock.UserID, *receipts[0].UploadedBy)
+	}
+	require.NoError(t, supplyService.DeleteItem(ctx, item.ID))
+	remaining, err := repos.Attachments.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, remaining, 2, "only the bill and loan attachments are left")
+
+	_, data, err := attachmentService.Download(ctx, AttachmentResourceBill, bill.ID, attachment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, invoice, data)
+	_, _, err = attachmentService.Download(ctx, AttachmentResourceLoan, loan.ID, attachment.ID)
+	assert.ErrorIs(t, err, ErrAttachmentNotFound, "attachments are only reachable through their own record")
+
+	// The blob stays while the loan still uses it
+	require.NoError(t, attachmentService.DeleteAttachment(ctx, AttachmentResourceBill, bill.ID, attachment.ID))
+	_, data, err = attachmentService.Download(ctx, AttachmentResourceLoan, loan.ID, loanAttachment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, invoice, data)
+
+	// Deleting the record drops its attachments; the orphaned file is cleaned up afterwards
+	require.NoError(t, repos.Loans.Delete(ctx, loan.ID))
+	attachments, err := repos.Attachments.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, attachments)
+
+	removed, err := attachmentService.RemoveOrphanedFiles(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	_, err = store.Get(attachment.SHA256)
+	assert.ErrorIs(t, err, ErrBlobNotFound)
+}
+
+func TestReadingPhotos(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+
+	store, err := NewBlobStore(t.TempDir())
+	require.NoError(t, err)
+	attachmentService := NewAttachmentService(repos.Attachments, repos.Bills, repos.SupplyItemHistory, repos.Loans, repos.Consumptions, repos.TxManager, store, &config.Config{
+		Attachments: config.AttachmentsConfig{
+			MaxSizeBytes:              1 << 10,
+			AllowedTypes:              []string{"application/pdf", "image/png"},
+			ReadingPhotoRetentionDays: 30,
+		},
+	})
+	consumptionService := NewConsumptionService(repos.Consumptions, repos.ConsumptionAnomalies, attachmentService, repos.Bills, repos.Users,
+		repos.Meters, repos.BillTariffZones, repos.TxManager)
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+	bill := &models.Bill{Type: "electricity", PeriodStart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), PeriodEnd: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		TotalAmountPLN: "100.00", Currency: "PLN", Status: "draft"}
+	require.NoError(t, repos.Bills.Create(ctx, bill))
+
+	photo := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+	read := func(user *models.User, file []byte) (*models.Consumption, error) {
+		return consumptionService.CreateConsumption(ctx, CreateConsumptionRequest{
+			BillID: bill.ID, UserID: user.ID, Units: 50, RecordedAt: time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC),
+			Photo: &ReadingPhoto{FileName: "meter.png", Data: file},
+		}, "user")
+	}
+
+	aliceReading, err := read(alice, photo)
+	require.NoError(t, err)
+	require.NotNil(t, aliceReading.Photo)
+	assert.Equal(t, "image/png", aliceReading.Photo.ContentType)
+	bobReading, err := read(bob, photo)
+	require.NoError(t, err)
+
+	// Readings only take photos, and a rejected photo records nothing
+	_, err = read(bob, []byte("%PDF-1.4\n%%EOF\n"))
+	assert.ErrorIs(t, err, ErrAttachmentTypeNotAllowed)
+	readings, err := consumptionService.GetConsumptions(ctx, &bill.ID)
+	require.NoError(t, err)
+	require.Len(t, readings, 2)
+	for _, reading := range readings {
+		require.NotNil(t, reading.Photo)
+		assert.Equal(t, aliceReading.Photo.SHA256, reading.Photo.SHA256)
+	}
+
+	// The invalidated reading comes back with its photo for the audit log
+	invalidated, err := consumptionService.MarkConsumptionInvalid(ctx, aliceReading.ID, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "invalid", invalidated.Source)
+	assert.Equal(t, aliceReading.Photo.ID, invalidated.Photo.ID)
+
+	// Photos are kept while the bill is open and for the retention period after it is closed;
+	// the photo of the invalidated reading stays as evidence
+	removed, err := attachmentService.RemoveExpiredReadingPhotos(ctx, time.Now().AddDate(0, 0, 31))
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+
+	bill.Status = "closed"
+	require.NoError(t, repos.Bills.Update(ctx, bill))
+	removed, err = attachmentService.RemoveExpiredReadingPhotos(ctx, time.Now().AddDate(0, 0, 29))
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+	removed, err = attachmentService.RemoveExpiredReadingPhotos(ctx, time.Now().AddDate(0, 0, 31))
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	bobPhoto, err := attachmentService.ReadingPhoto(ctx, bobReading.ID)
+	require.NoError(t, err)
+	assert.Nil(t, bobPhoto)
+	_, data, err := attachmentService.Download(ctx, AttachmentResourceConsumption, aliceReading.ID, aliceReading.Photo.ID)
+	require.NoError(t, err)
+	assert.Equal(t, photo, data)
+
+	// Deleting a reading drops its photo
+	require.NoError(t, repos.Consumptions.Delete(ctx, aliceReading.ID))
+	attachments, err := repos.Attachments.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, attachments)
+}
+
+func newTestAttachmentService(t *testing.T, repos *repository.Repositories) *AttachmentService {
+	store, err := NewBlobStore(t.TempDir())
+	require.NoError(t, err)
+	return NewAttachmentService(repos.Attachments, repos.Bills, repos.SupplyItemHistory, repos.Loans, repos.Consumptions, repos.TxManager, store, &config.Config{
+		Attachments: config.AttachmentsConfig{MaxSizeBytes: 1 << 20, AllowedTypes: []string{"image/jpeg", "image/png"}},
+	})
+}
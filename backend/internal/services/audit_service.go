@@ -44,6 +44,7 @@ func (s *AuditService) LogAction(
 		Action:       action,
 		ResourceType: resourceType,
 		ResourceID:   resourceID,
+		Details:      details,
 		DetailsJSON:  detailsJSON,
 		IPAddress:    ipAddress,
 		UserAgent:    userAgent,
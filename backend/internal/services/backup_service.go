@@ -16,13 +16,21 @@ import (
 type BackupService struct {
 	db                       *sqlx.DB
 	users                    repository.UserRepository
+	residencies              repository.ResidencyRepository
 	groups                   repository.GroupRepository
 	bills                    repository.BillRepository
 	consumptions             repository.ConsumptionRepository
 	allocations              repository.AllocationRepository
 	payments                 repository.PaymentRepository
+	creditEntries            repository.CreditEntryRepository
 	loans                    repository.LoanRepository
 	loanPayments             repository.LoanPaymentRepository
+	settlementTransfers      repository.SettlementTransferRepository
+	bankAccounts             repository.BankAccountRepository
+	bankImports              repository.BankImportRepository
+	bankTransactions         repository.BankTransactionRepository
+	attachments              repository.AttachmentRepository
+	attachmentStore          *BlobStore
 	chores                   repository.ChoreRepository
 	choreAssignments         repository.ChoreAssignmentRepository
 	choreSettings            repository.ChoreSettingsRepository
@@ -30,21 +38,45 @@ type BackupService struct {
 	supplySettings           repository.SupplySettingsRepository
 	supplyItems              repository.SupplyItemRepository
 	supplyContributions      repository.SupplyContributionRepository
+	supplyItemHistory        repository.SupplyItemHistoryRepository
 	recurringBillTemplates   repository.RecurringBillTemplateRepository
 	recurringBillAllocations repository.RecurringBillAllocationRepository
+	billSplits               repository.BillSplitRepository
+	billItems                repository.BillItemRepository
+	billTariffZones          repository.BillTariffZoneRepository
+	billResidents            repository.BillResidentRepository
+	meters                   repository.MeterRepository
 	passkeyCredentials       repository.PasskeyCredentialRepository
+	exchangeRates            repository.ExchangeRateRepository
+	utilityTariffs           repository.UtilityTariffRepository
+	consumptionAnomalies     repository.ConsumptionAnomalyRepository
+	billTransitions          repository.BillTransitionRepository
+	billDisputes             repository.BillDisputeRepository
+	billVersions             repository.BillVersionRepository
+	lateFeePolicies          repository.LateFeePolicyRepository
+	lateFees                 repository.LateFeeRepository
+	budgets                  repository.BudgetRepository
+	budgetAlerts             repository.BudgetAlertRepository
 }
 
 func NewBackupService(
 	db *sqlx.DB,
 	users repository.UserRepository,
+	residencies repository.ResidencyRepository,
 	groups repository.GroupRepository,
 	bills repository.BillRepository,
 	consumptions repository.ConsumptionRepository,
 	allocations repository.AllocationRepository,
 	payments repository.PaymentRepository,
+	creditEntries repository.CreditEntryRepository,
 	loans repository.LoanRepository,
 	loanPayments repository.LoanPaymentRepository,
+	settlementTransfers repository.SettlementTransferRepository,
+	bankAccounts repository.BankAccountRepository,
+	bankImports repository.BankImportRepository,
+	bankTransactions repository.BankTransactionRepository,
+	attachments repository.AttachmentRepository,
+	attachmentStore *BlobStore,
 	chores repository.ChoreRepository,
 	choreAssignments repository.ChoreAssignmentRepository,
 	choreSettings repository.ChoreSettingsRepository,
@@ -52,20 +84,44 @@ func NewBackupService(
 	supplySettings repository.SupplySettingsRepository,
 	supplyItems repository.SupplyItemRepository,
 	supplyContributions repository.SupplyContributionRepository,
+	supplyItemHistory repository.SupplyItemHistoryRepository,
 	recurringBillTemplates repository.RecurringBillTemplateRepository,
 	recurringBillAllocations repository.RecurringBillAllocationRepository,
+	billSplits repository.BillSplitRepository,
+	billItems repository.BillItemRepository,
+	billTariffZones repository.BillTariffZoneRepository,
+	billResidents repository.BillResidentRepository,
+	meters repository.MeterRepository,
 	passkeyCredentials repository.PasskeyCredentialRepository,
+	exchangeRates repository.ExchangeRateRepository,
+	utilityTariffs repository.UtilityTariffRepository,
+	consumptionAnomalies repository.ConsumptionAnomalyRepository,
+	billTransitions repository.BillTransitionRepository,
+	billDisputes repository.BillDisputeRepository,
+	billVersions repository.BillVersionRepository,
+	lateFeePolicies repository.LateFeePolicyRepository,
+	lateFees repository.LateFeeRepository,
+	budgets repository.BudgetRepository,
+	budgetAlerts repository.BudgetAlertRepository,
 ) *BackupService {
 	return &BackupService{
 		db:                       db,
 		users:                    users,
+		residencies:              residencies,
 		groups:                   groups,
 		bills:                    bills,
 		consumptions:             consumptions,
 		allocations:              allocations,
 		payments:                 payments,
+		creditEntries:            creditEntries,
 		loans:                    loans,
 		loanPayments:             loanPayments,
+		settlementTransfers:      settlementTransfers,
+		bankAccounts:             bankAccounts,
+		bankImports:              bankImports,
+		bankTransactions:         bankTransactions,
+		attachments:              attachments,
+		attachmentStore:          attachmentStore,
 		chores:                   chores,
 		choreAssignments:         choreAssignments,
 		choreSettings:            choreSettings,
@@ -73,9 +129,25 @@ func NewBackupService(
 		supplySettings:           supplySettings,
 		supplyItems:              supplyItems,
 		supplyContributions:      supplyContributions,
+		supplyItemHistory:        supplyItemHistory,
 		recurringBillTemplates:   recurringBillTemplates,
 		recurringBillAllocations: recurringBillAllocations,
+		billSplits:               billSplits,
+		billItems:                billItems,
+		billTariffZones:          billTariffZones,
+		billResidents:            billResidents,
+		meters:                   meters,
 		passkeyCredentials:       passkeyCredentials,
+		exchangeRates:            exchangeRates,
+		utilityTariffs:           utilityTariffs,
+		consumptionAnomalies:     consumptionAnomalies,
+		billTransitions:          billTransitions,
+		billDisputes:             billDisputes,
+		billVersions:             billVersions,
+		lateFeePolicies:          lateFeePolicies,
+		lateFees:                 lateFees,
+		budgets:                  budgets,
+		budgetAlerts:             budgetAlerts,
 	}
 }
 
@@ -111,6 +183,12 @@ type BackupPasskeyCredential struct {
 	BackupState     bool      `json:"backupState"`
 }
 
+// BackupAttachment is an attachment together with its file contents (base64 in JSON)
+type BackupAttachment struct {
+	models.Attachment
+	Data []byte `json:"data"`
+}
+
 // ImportResult contains information about the import operation
 type ImportResult struct {
 	UsersWithResetPasswords []string `json:"usersWithResetPasswords"` // Email addresses of users who got default passwords
@@ -123,13 +201,20 @@ type BackupData struct {
 	ExportedAt               time.Time                        `json:"exportedAt"`
 	Users                    []BackupUser                     `json:"users"`
 	PasskeyCredentials       []BackupPasskeyCredential        `json:"passkeyCredentials"`
+	Residencies              []models.Residency               `json:"residencies"`
 	Groups                   []models.Group                   `json:"groups"`
 	Bills                    []models.Bill                    `json:"bills"`
 	Consumptions             []models.Consumption             `json:"consumptions"`
 	Allocations              []repository.Allocation          `json:"allocations"`
 	Payments                 []models.Payment                 `json:"payments"`
+	CreditEntries            []models.CreditEntry             `json:"creditEntries"`
 	Loans                    []models.Loan                    `json:"loans"`
 	LoanPayments             []models.LoanPayment             `json:"loanPayments"`
+	SettlementTransfers      []models.SettlementTransfer      `json:"settlementTransfers"`
+	BankAccounts             []models.BankAccount             `json:"bankAccounts"`
+	BankImports              []models.BankImport              `json:"bankImports"`
+	BankTransactions         []models.BankTransaction         `json:"bankTransactions"`
+	Attachments              []BackupAttachment               `json:"attachments"`
 	Chores                   []models.Chore                   `json:"chores"`
 	ChoreAssignments         []models.ChoreAssignment         `json:"choreAssignments"`
 	ChoreSettings            *models.ChoreSettings            `json:"choreSettings,omitempty"`
@@ -137,8 +222,24 @@ type BackupData struct {
 	SupplySettings           *models.SupplySettings           `json:"supplySettings,omitempty"`
 	SupplyItems              []models.SupplyItem              `json:"supplyItems"`
 	SupplyContributions      []models.SupplyContribution      `json:"supplyContributions"`
+	SupplyItemHistory        []models.SupplyItemHistory       `json:"supplyItemHistory"`
 	RecurringBillTemplates   []models.RecurringBillTemplate   `json:"recurringBillTemplates"`
 	RecurringBillAllocations []models.RecurringBillAllocation `json:"recurringBillAllocations"`
+	BillSplits               []models.BillSplit               `json:"billSplits"`
+	BillItems                []models.BillItem                `json:"billItems"`
+	BillTariffZones          []models.BillTariffZone          `json:"billTariffZones"`
+	BillResidents            []models.BillResident            `json:"billResidents"`
+	Meters                   []models.Meter                   `json:"meters"`
+	ExchangeRates            []models.ExchangeRate            `json:"exchangeRates"`
+	UtilityTariffs           []models.UtilityTariff           `json:"utilityTariffs"`
+	ConsumptionAnomalies     []models.ConsumptionAnomaly      `json:"consumptionAnomalies"`
+	BillTransitions          []models.BillTransition          `json:"billTransitions"`
+	BillDisputes             []models.BillDispute             `json:"billDisputes"`
+	BillVersions             []models.BillVersion             `json:"billVersions"`
+	LateFeePolicies          []models.LateFeePolicy           `json:"lateFeePolicies"`
+	LateFees                 []models.LateFee                 `json:"lateFees"`
+	Budgets                  []models.Budget                  `json:"budgets"`
+	BudgetAlerts             []models.BudgetAlert             `json:"budgetAlerts"`
 }
 
 // ExportAll exports all data from all collections
@@ -192,6 +293,13 @@ func (s *BackupService) ExportAll(ctx context.Context) (*BackupData, error) {
 		}
 	}
 
+	// Export residency intervals
+	residencies, err := s.residencies.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch residencies: %w", err)
+	}
+	backup.Residencies = residencies
+
 	// Export groups
 	groups, err := s.groups.List(ctx)
 	if err != nil {
@@ -206,6 +314,41 @@ func (s *BackupService) ExportAll(ctx context.Context) (*BackupData, error) {
 	}
 	backup.Bills = bills
 
+	// Export bill split rules
+	billSplits, err := s.billSplits.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bill splits: %w", err)
+	}
+	backup.BillSplits = billSplits
+
+	// Export receipt lines (with participants)
+	billItems, err := s.billItems.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bill items: %w", err)
+	}
+	backup.BillItems = billItems
+
+	// Export bill tariff zones
+	billTariffZones, err := s.billTariffZones.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bill tariff zones: %w", err)
+	}
+	backup.BillTariffZones = billTariffZones
+
+	// Export the residents stored with posted bills
+	billResidents, err := s.billResidents.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bill residents: %w", err)
+	}
+	backup.BillResidents = billResidents
+
+	// Export meters
+	meters, err := s.meters.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch meters: %w", err)
+	}
+	backup.Meters = meters
+
 	// Export consumptions
 	consumptions, err := s.consumptions.List(ctx)
 	if err != nil {
@@ -220,6 +363,13 @@ func (s *BackupService) ExportAll(ctx context.Context) (*BackupData, error) {
 	}
 	backup.Payments = payments
 
+	// Export credit ledger
+	creditEntries, err := s.creditEntries.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch credit entries: %w", err)
+	}
+	backup.CreditEntries = creditEntries
+
 	// Export loans
 	loans, err := s.loans.List(ctx)
 	if err != nil {
@@ -234,6 +384,47 @@ func (s *BackupService) ExportAll(ctx context.Context) (*BackupData, error) {
 	}
 	backup.LoanPayments = loanPayments
 
+	// Export settle-up transfers
+	settlementTransfers, err := s.settlementTransfers.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch settlement transfers: %w", err)
+	}
+	backup.SettlementTransfers = settlementTransfers
+
+	// Export bank accounts
+	bankAccounts, err := s.bankAccounts.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bank accounts: %w", err)
+	}
+	backup.BankAccounts = bankAccounts
+
+	// Export bank statement imports
+	bankImports, err := s.bankImports.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bank imports: %w", err)
+	}
+	backup.BankImports = bankImports
+
+	bankTransactions, err := s.bankTransactions.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bank transactions: %w", err)
+	}
+	backup.BankTransactions = bankTransactions
+
+	// Export attachments with their files
+	attachments, err := s.attachments.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch attachments: %w", err)
+	}
+	backup.Attachments = make([]BackupAttachment, len(attachments))
+	for i, a := range attachments {
+		data, err := s.attachmentStore.Get(a.SHA256)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attachment file %s: %w", a.ID, err)
+		}
+		backup.Attachments[i] = BackupAttachment{Attachment: a, Data: data}
+	}
+
 	// Export chores
 	chores, err := s.chores.List(ctx)
 	if err != nil {
@@ -281,6 +472,13 @@ func (s *BackupService) ExportAll(ctx context.Context) (*BackupData, error) {
 	}
 	backup.SupplyContributions = supplyContributions
 
+	// Export supply item history (restocks, refunds)
+	supplyItemHistory, err := s.supplyItemHistory.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch supply item history: %w", err)
+	}
+	backup.SupplyItemHistory = supplyItemHistory
+
 	// Export allocations
 	allocations, err := s.allocations.List(ctx)
 	if err != nil {
@@ -302,6 +500,74 @@ func (s *BackupService) ExportAll(ctx context.Context) (*BackupData, error) {
 	}
 	backup.RecurringBillAllocations = recurringBillAllocations
 
+	// Export exchange rates
+	exchangeRates, err := s.exchangeRates.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch exchange rates: %w", err)
+	}
+	backup.ExchangeRates = exchangeRates
+
+	// Export utility tariffs
+	utilityTariffs, err := s.utilityTariffs.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch utility tariffs: %w", err)
+	}
+	backup.UtilityTariffs = utilityTariffs
+
+	// Export consumption anomalies
+	consumptionAnomalies, err := s.consumptionAnomalies.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch consumption anomalies: %w", err)
+	}
+	backup.ConsumptionAnomalies = consumptionAnomalies
+
+	// Export bill status history
+	billTransitions, err := s.billTransitions.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bill transitions: %w", err)
+	}
+	backup.BillTransitions = billTransitions
+
+	// Export allocation disputes
+	billDisputes, err := s.billDisputes.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bill disputes: %w", err)
+	}
+	backup.BillDisputes = billDisputes
+
+	// Export posted bill versions
+	billVersions, err := s.billVersions.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bill versions: %w", err)
+	}
+	backup.BillVersions = billVersions
+
+	// Export late-fee policies and the fees they accrued
+	lateFeePolicies, err := s.lateFeePolicies.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch late fee policies: %w", err)
+	}
+	backup.LateFeePolicies = lateFeePolicies
+
+	lateFees, err := s.lateFees.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch late fees: %w", err)
+	}
+	backup.LateFees = lateFees
+
+	// Export budgets and the alerts already sent for them
+	budgets, err := s.budgets.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch budgets: %w", err)
+	}
+	backup.Budgets = budgets
+
+	budgetAlerts, err := s.budgetAlerts.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch budget alerts: %w", err)
+	}
+	backup.BudgetAlerts = budgetAlerts
+
 	return backup, nil
 }
 
@@ -356,10 +622,27 @@ func (s *BackupService) ImportJSON(ctx context.Context, jsonData []byte) (*Impor
 
 	// Delete existing data in reverse dependency order
 	tablesToClear := []string{
+		"attachments",
+		"bank_transactions",
+		"bank_imports",
+		"late_fees",
+		"late_fee_policies",
 		"loan_payments",
+		"settlement_transfers",
+		"credit_entries",
 		"payments",
+		"consumption_anomalies",
 		"consumptions",
+		"meters",
 		"allocations",
+		"bill_splits",
+		"bill_tariff_zones",
+		"bill_residents",
+		"bill_item_participants",
+		"bill_items",
+		"bill_transitions",
+		"bill_disputes",
+		"bill_versions",
 		"chore_assignments",
 		"supply_contributions",
 		"supply_item_history",
@@ -374,9 +657,15 @@ func (s *BackupService) ImportJSON(ctx context.Context, jsonData []byte) (*Impor
 		"chores",
 		"chore_settings",
 		"supply_settings",
+		"exchange_rates",
+		"utility_tariffs",
+		"budget_alerts",
+		"budgets",
 		"sessions",
 		"password_reset_tokens",
 		"passkey_credentials",
+		"user_bank_accounts",
+		"user_residencies",
 		"users",
 		"groups",
 	}
@@ -441,6 +730,24 @@ func (s *BackupService) ImportJSON(ctx context.Context, jsonData []byte) (*Impor
 		}
 	}
 
+	// Import residency intervals
+	for _, residency := range backup.Residencies {
+		var movedOutAt *string
+		if residency.MovedOutAt != nil {
+			mo := residency.MovedOutAt.UTC().Format(time.RFC3339)
+			movedOutAt = &mo
+		}
+
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO user_residencies (id, user_id, moved_in_at, moved_out_at, created_at)
+			VALUES (?, ?, ?, ?, ?)`,
+			residency.ID, residency.UserID, residency.MovedInAt.UTC().Format(time.RFC3339), movedOutAt,
+			residency.CreatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import residency %s: %w", residency.ID, err)
+		}
+	}
+
 	// Import passkey credentials
 	for _, pc := range backup.PasskeyCredentials {
 		var lastUsedAt *string
@@ -468,6 +775,23 @@ func (s *BackupService) ImportJSON(ctx context.Context, jsonData []byte) (*Impor
 		}
 	}
 
+	// Import bank accounts
+	for _, account := range backup.BankAccounts {
+		isDefault := 0
+		if account.IsDefault {
+			isDefault = 1
+		}
+
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO user_bank_accounts (id, user_id, label, holder_name, account_number, is_default, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			account.ID, account.UserID, account.Label, account.HolderName, account.AccountNumber, isDefault,
+			account.CreatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import bank account %s: %w", account.ID, err)
+		}
+	}
+
 	// Import bills
 	for _, bill := range backup.Bills {
 		var paymentDeadline, reopenedAt, totalUnits *string
@@ -485,30 +809,116 @@ func (s *BackupService) ImportJSON(ctx context.Context, jsonData []byte) (*Impor
 
 		_, err := tx.ExecContext(ctx,
 			`INSERT INTO bills (id, type, custom_type, allocation_type, period_start, period_end, payment_deadline,
-				total_amount_pln, total_units, notes, status, reopened_at, reopen_reason, reopened_by, recurring_template_id, created_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				total_amount_pln, currency, total_units, notes, status, reopened_at, reopen_reason, reopened_by, recurring_template_id,
+				recipient_account_id, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 			bill.ID, bill.Type, bill.CustomType, bill.AllocationType,
 			bill.PeriodStart.UTC().Format(time.RFC3339), bill.PeriodEnd.UTC().Format(time.RFC3339),
-			paymentDeadline, bill.TotalAmountPLN, totalUnits, bill.Notes, bill.Status,
-			reopenedAt, bill.ReopenReason, bill.ReopenedBy, bill.RecurringTemplateID,
+			paymentDeadline, bill.TotalAmountPLN, backupCurrency(bill.Currency), totalUnits, bill.Notes, bill.Status,
+			reopenedAt, bill.ReopenReason, bill.ReopenedBy, bill.RecurringTemplateID, bill.RecipientAccountID,
 			bill.CreatedAt.UTC().Format(time.RFC3339))
 		if err != nil {
 			return nil, fmt.Errorf("failed to import bill %s: %w", bill.ID, err)
 		}
 	}
 
-	// Import consumptions
-	for _, consumption := range backup.Consumptions {
+	// Import bill status history
+	for _, transition := range backup.BillTransitions {
 		_, err := tx.ExecContext(ctx,
-			`INSERT INTO consumptions (id, bill_id, subject_type, subject_id, units, meter_value, recorded_at, source)
+			`INSERT INTO bill_transitions (id, bill_id, transition, from_status, to_status, actor_id, reason, forced, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			transition.ID, transition.BillID, transition.Transition, transition.FromStatus, transition.ToStatus,
+			transition.ActorID, transition.Reason, transition.Forced, transition.CreatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import bill transition %s: %w", transition.ID, err)
+		}
+	}
+
+	// Import allocation disputes
+	for _, dispute := range backup.BillDisputes {
+		var resolvedAt *string
+		if dispute.ResolvedAt != nil {
+			ra := dispute.ResolvedAt.UTC().Format(time.RFC3339)
+			resolvedAt = &ra
+		}
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO bill_disputes (id, bill_id, subject_type, subject_id, opened_by, reason, allocated_amount, proposed_amount,
+				status, resolved_amount, resolution_note, resolved_by, resolved_at, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			dispute.ID, dispute.BillID, dispute.SubjectType, dispute.SubjectID, dispute.OpenedBy, dispute.Reason,
+			dispute.AllocatedAmount, dispute.ProposedAmount, dispute.Status, dispute.ResolvedAmount, dispute.ResolutionNote,
+			dispute.ResolvedBy, resolvedAt, dispute.CreatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import bill dispute %s: %w", dispute.ID, err)
+		}
+	}
+
+	// Import posted bill versions
+	for _, version := range backup.BillVersions {
+		snapshotJSON, err := json.Marshal(version.Bill)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode bill version %s: %w", version.ID, err)
+		}
+		allocationsJSON, err := json.Marshal(version.Allocations)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode bill version %s: %w", version.ID, err)
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO bill_versions (id, bill_id, version, snapshot, allocations, reason, created_by, created_at)
 			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			version.ID, version.BillID, version.Version, string(snapshotJSON), string(allocationsJSON),
+			version.Reason, version.CreatedBy, version.CreatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import bill version %s: %w", version.ID, err)
+		}
+	}
+
+	// Import meters
+	for _, meter := range backup.Meters {
+		var replacedAt *string
+		if meter.ReplacedAt != nil {
+			ra := meter.ReplacedAt.UTC().Format(time.RFC3339)
+			replacedAt = &ra
+		}
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO meters (id, type, unit, subject_type, subject_id, tariff_zone, serial_number, installed_at,
+				initial_value, replaced_at, final_value, replaces_meter_id, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			meter.ID, meter.Type, meter.Unit, meter.SubjectType, meter.SubjectID, meter.TariffZone, meter.SerialNumber,
+			meter.InstalledAt.UTC().Format(time.RFC3339), meter.InitialValue, replacedAt, meter.FinalValue,
+			meter.ReplacesMeterID, meter.CreatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import meter %s: %w", meter.ID, err)
+		}
+	}
+
+	// Import consumptions. Restored readings count as checked for anomalies, their flags are imported below.
+	checkedAt := time.Now().UTC().Format(time.RFC3339)
+	for _, consumption := range backup.Consumptions {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO consumptions (id, bill_id, subject_type, subject_id, units, meter_value, meter_id, recorded_at, source, anomaly_checked_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 			consumption.ID, consumption.BillID, consumption.SubjectType, consumption.SubjectID,
-			consumption.Units, consumption.MeterValue, consumption.RecordedAt.UTC().Format(time.RFC3339), consumption.Source)
+			consumption.Units, consumption.MeterValue, consumption.MeterID, consumption.RecordedAt.UTC().Format(time.RFC3339), consumption.Source,
+			checkedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to import consumption %s: %w", consumption.ID, err)
 		}
 	}
 
+	// Import consumption anomalies
+	for _, anomaly := range backup.ConsumptionAnomalies {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO consumption_anomalies (id, consumption_id, meter_id, subject_type, subject_id, kind,
+				daily_units, expected_daily_units, message, detected_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			anomaly.ID, anomaly.ConsumptionID, anomaly.MeterID, anomaly.SubjectType, anomaly.SubjectID, anomaly.Kind,
+			anomaly.DailyUnits, anomaly.ExpectedDailyUnits, anomaly.Message, anomaly.DetectedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import consumption anomaly %s: %w", anomaly.ID, err)
+		}
+	}
+
 	// Import payments
 	for _, payment := range backup.Payments {
 		_, err := tx.ExecContext(ctx,
@@ -521,6 +931,18 @@ func (s *BackupService) ImportJSON(ctx context.Context, jsonData []byte) (*Impor
 		}
 	}
 
+	// Import credit ledger
+	for _, entry := range backup.CreditEntries {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO credit_entries (id, user_id, bill_id, payment_id, kind, amount, currency, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			entry.ID, entry.UserID, entry.BillID, entry.PaymentID, entry.Kind, entry.Amount, backupCurrency(entry.Currency),
+			entry.CreatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import credit entry %s: %w", entry.ID, err)
+		}
+	}
+
 	// Import loans
 	for _, loan := range backup.Loans {
 		var dueDate *string
@@ -530,9 +952,9 @@ func (s *BackupService) ImportJSON(ctx context.Context, jsonData []byte) (*Impor
 		}
 
 		_, err := tx.ExecContext(ctx,
-			`INSERT INTO loans (id, lender_id, borrower_id, amount_pln, note, due_date, status, created_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-			loan.ID, loan.LenderID, loan.BorrowerID, loan.AmountPLN, loan.Note, dueDate, loan.Status,
+			`INSERT INTO loans (id, lender_id, borrower_id, amount_pln, currency, note, due_date, status, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			loan.ID, loan.LenderID, loan.BorrowerID, loan.AmountPLN, backupCurrency(loan.Currency), loan.Note, dueDate, loan.Status,
 			loan.CreatedAt.UTC().Format(time.RFC3339))
 		if err != nil {
 			return nil, fmt.Errorf("failed to import loan %s: %w", loan.ID, err)
@@ -542,14 +964,116 @@ func (s *BackupService) ImportJSON(ctx context.Context, jsonData []byte) (*Impor
 	// Import loan payments
 	for _, lp := range backup.LoanPayments {
 		_, err := tx.ExecContext(ctx,
-			`INSERT INTO loan_payments (id, loan_id, amount_pln, paid_at, note)
-			VALUES (?, ?, ?, ?, ?)`,
-			lp.ID, lp.LoanID, lp.AmountPLN, lp.PaidAt.UTC().Format(time.RFC3339), lp.Note)
+			`INSERT INTO loan_payments (id, loan_id, amount_pln, paid_at, note, settlement_id)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			lp.ID, lp.LoanID, lp.AmountPLN, lp.PaidAt.UTC().Format(time.RFC3339), lp.Note, lp.SettlementID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to import loan payment %s: %w", lp.ID, err)
 		}
 	}
 
+	// Import settle-up transfers
+	for _, transfer := range backup.SettlementTransfers {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO settlement_transfers (id, settlement_id, from_type, from_id, to_type, to_id, amount, currency, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			transfer.ID, transfer.SettlementID, transfer.FromType, transfer.FromID, transfer.ToType, transfer.ToID,
+			transfer.Amount, transfer.Currency, transfer.CreatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import settlement transfer %s: %w", transfer.ID, err)
+		}
+	}
+
+	// Import late-fee policies
+	for _, policy := range backup.LateFeePolicies {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO late_fee_policies (id, resource_type, bill_type, custom_type, loan_id, flat_fee, percent_per_period,
+				period_days, grace_days, cap, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			policy.ID, policy.ResourceType, policy.BillType, policy.CustomType, policy.LoanID, policy.FlatFee,
+			policy.PercentPerPeriod, policy.PeriodDays, policy.GraceDays, policy.Cap,
+			policy.CreatedAt.UTC().Format(time.RFC3339), policy.UpdatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import late fee policy %s: %w", policy.ID, err)
+		}
+	}
+
+	// Import late fees
+	for _, fee := range backup.LateFees {
+		var waivedAt *string
+		if fee.WaivedAt != nil {
+			wa := fee.WaivedAt.UTC().Format(time.RFC3339)
+			waivedAt = &wa
+		}
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO late_fees (id, policy_id, resource_type, resource_id, subject_type, subject_id, beneficiary_id, kind,
+				period, amount, currency, accrued_at, status, waived_by, waived_at, waive_reason, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			fee.ID, fee.PolicyID, fee.ResourceType, fee.ResourceID, fee.SubjectType, fee.SubjectID, fee.BeneficiaryID,
+			fee.Kind, fee.Period, fee.Amount, backupCurrency(fee.Currency), fee.AccruedAt.UTC().Format(time.RFC3339),
+			fee.Status, fee.WaivedBy, waivedAt, fee.WaiveReason, fee.CreatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import late fee %s: %w", fee.ID, err)
+		}
+	}
+
+	// Import budgets
+	for _, budget := range backup.Budgets {
+		thresholdsJSON, err := json.Marshal(budget.AlertThresholds)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode budget %s: %w", budget.ID, err)
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO budgets (id, kind, category, custom_type, monthly_amount, alert_thresholds, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			budget.ID, budget.Kind, budget.Category, budget.CustomType, budget.MonthlyAmount, string(thresholdsJSON),
+			budget.CreatedAt.UTC().Format(time.RFC3339), budget.UpdatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import budget %s: %w", budget.ID, err)
+		}
+	}
+
+	// Import sent budget alerts
+	for _, alert := range backup.BudgetAlerts {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO budget_alerts (id, budget_id, month, threshold, actual, sent_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			alert.ID, alert.BudgetID, alert.Month, alert.Threshold, alert.Actual, alert.SentAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import budget alert %s: %w", alert.ID, err)
+		}
+	}
+
+	// Import bank statement imports
+	for _, bi := range backup.BankImports {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO bank_imports (id, file_name, format, imported_by, line_count, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			bi.ID, bi.FileName, bi.Format, bi.ImportedBy, bi.LineCount, bi.CreatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import bank import %s: %w", bi.ID, err)
+		}
+	}
+
+	for _, bt := range backup.BankTransactions {
+		var reviewedAt *string
+		if bt.ReviewedAt != nil {
+			ra := bt.ReviewedAt.UTC().Format(time.RFC3339)
+			reviewedAt = &ra
+		}
+
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO bank_transactions (id, import_id, fingerprint, booked_at, amount, currency, counterparty_name,
+				counterparty_account, title, reference, status, match_type, payment_id, loan_payment_id, reviewed_by, reviewed_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			bt.ID, bt.ImportID, bt.Fingerprint, bt.BookedAt.UTC().Format(time.RFC3339), bt.Amount, backupCurrency(bt.Currency),
+			bt.CounterpartyName, bt.CounterpartyAccount, bt.Title, bt.Reference, bt.Status, bt.MatchType,
+			bt.PaymentID, bt.LoanPaymentID, bt.ReviewedBy, reviewedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import bank transaction %s: %w", bt.ID, err)
+		}
+	}
+
 	// Import chores
 	for _, chore := range backup.Chores {
 		isActive := 0
@@ -676,11 +1200,11 @@ func (s *BackupService) ImportJSON(ctx context.Context, jsonData []byte) (*Impor
 		}
 
 		_, err := tx.ExecContext(ctx,
-			`INSERT INTO supply_items (id, name, category, current_quantity, min_quantity, unit, priority, added_by_user_id, added_at, last_restocked_at, last_restocked_by_user_id, last_restock_amount_pln, needs_refund, notes)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			`INSERT INTO supply_items (id, name, category, current_quantity, min_quantity, unit, priority, added_by_user_id, added_at, last_restocked_at, last_restocked_by_user_id, last_restock_amount_pln, last_restock_currency, needs_refund, notes)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 			item.ID, item.Name, item.Category, item.CurrentQuantity, item.MinQuantity,
 			item.Unit, item.Priority, item.AddedByUserID, item.AddedAt.UTC().Format(time.RFC3339),
-			lastRestockedAt, lastRestockedByUserID, lastRestockAmountPLN, needsRefund, item.Notes)
+			lastRestockedAt, lastRestockedByUserID, lastRestockAmountPLN, item.LastRestockCurrency, needsRefund, item.Notes)
 		if err != nil {
 			return nil, fmt.Errorf("failed to import supply item %s: %w", item.ID, err)
 		}
@@ -699,6 +1223,18 @@ func (s *BackupService) ImportJSON(ctx context.Context, jsonData []byte) (*Impor
 		}
 	}
 
+	// Import supply item history
+	for _, h := range backup.SupplyItemHistory {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO supply_item_history (id, supply_item_id, user_id, action, quantity_delta, old_quantity, new_quantity, cost_pln, notes, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			h.ID, h.SupplyItemID, h.UserID, h.Action, h.QuantityDelta, h.OldQuantity, h.NewQuantity, h.CostPLN, h.Notes,
+			h.CreatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import supply item history %s: %w", h.ID, err)
+		}
+	}
+
 	// Import allocations (bill cost splits)
 	for _, alloc := range backup.Allocations {
 		_, err := tx.ExecContext(ctx,
@@ -710,6 +1246,59 @@ func (s *BackupService) ImportJSON(ctx context.Context, jsonData []byte) (*Impor
 		}
 	}
 
+	// Import bill split rules
+	for _, split := range backup.BillSplits {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO bill_splits (id, bill_id, subject_type, subject_id, allocation_type, percentage, fraction_numerator, fraction_denominator, fixed_amount, shares)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			split.ID, split.BillID, split.SubjectType, split.SubjectID, split.AllocationType,
+			split.Percentage, split.FractionNum, split.FractionDenom, split.FixedAmount, split.Shares)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import bill split %s: %w", split.ID, err)
+		}
+	}
+
+	// Import receipt lines and their participants
+	for _, item := range backup.BillItems {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO bill_items (id, bill_id, kind, description, amount, position)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			item.ID, item.BillID, item.Kind, item.Description, item.Amount, item.Position)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import bill item %s: %w", item.ID, err)
+		}
+		for _, p := range item.Participants {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO bill_item_participants (item_id, subject_type, subject_id) VALUES (?, ?, ?)`,
+				item.ID, p.SubjectType, p.SubjectID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to import participant of bill item %s: %w", item.ID, err)
+			}
+		}
+	}
+
+	// Import bill tariff zones
+	for _, zone := range backup.BillTariffZones {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO bill_tariff_zones (bill_id, zone, units, amount) VALUES (?, ?, ?, ?)`,
+			zone.BillID, zone.Zone, zone.Units, zone.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import tariff zone %s of bill %s: %w", zone.Zone, zone.BillID, err)
+		}
+	}
+
+	// Import the residents stored with posted bills
+	for _, resident := range backup.BillResidents {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO bill_residents (bill_id, user_id, subject_type, subject_id, weight, days_present, period_days)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			resident.BillID, resident.UserID, resident.SubjectType, resident.SubjectID,
+			resident.Weight, resident.DaysPresent, resident.PeriodDays)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import resident %s of bill %s: %w", resident.UserID, resident.BillID, err)
+		}
+	}
+
 	// Import recurring bill templates
 	for _, template := range backup.RecurringBillTemplates {
 		isActive := 0
@@ -723,9 +1312,9 @@ func (s *BackupService) ImportJSON(ctx context.Context, jsonData []byte) (*Impor
 		}
 
 		_, err := tx.ExecContext(ctx,
-			`INSERT INTO recurring_bill_templates (id, custom_type, frequency, amount, day_of_month, start_date, notes, is_active, current_bill_id, next_due_date, last_generated_at, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			template.ID, template.CustomType, template.Frequency, template.Amount, template.DayOfMonth,
+			`INSERT INTO recurring_bill_templates (id, custom_type, frequency, amount, currency, day_of_month, start_date, notes, is_active, current_bill_id, next_due_date, last_generated_at, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			template.ID, template.CustomType, template.Frequency, template.Amount, backupCurrency(template.Currency), template.DayOfMonth,
 			template.StartDate.UTC().Format(time.RFC3339), template.Notes, isActive, template.CurrentBillID,
 			template.NextDueDate.UTC().Format(time.RFC3339), lastGeneratedAt,
 			template.CreatedAt.UTC().Format(time.RFC3339), template.UpdatedAt.UTC().Format(time.RFC3339))
@@ -746,6 +1335,51 @@ func (s *BackupService) ImportJSON(ctx context.Context, jsonData []byte) (*Impor
 		}
 	}
 
+	// Import exchange rates
+	for _, rate := range backup.ExchangeRates {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO exchange_rates (id, currency, rate, rate_date, created_at)
+			VALUES (?, ?, ?, ?, ?)`,
+			rate.ID, rate.Currency, rate.Rate, rate.RateDate.UTC().Format(time.RFC3339),
+			rate.CreatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import exchange rate %s: %w", rate.ID, err)
+		}
+	}
+
+	// Import utility tariffs
+	for _, tariff := range backup.UtilityTariffs {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO utility_tariffs (id, bill_type, custom_type, tariff_zone, unit_price, fixed_fee_monthly, valid_from, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			tariff.ID, tariff.BillType, tariff.CustomType, tariff.TariffZone, tariff.UnitPrice, tariff.FixedFeeMonthly,
+			tariff.ValidFrom.UTC().Format(time.RFC3339), tariff.CreatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import utility tariff %s: %w", tariff.ID, err)
+		}
+	}
+
+	// Import attachments. Files of attachments that were replaced stay in the blob
+	// store until the orphaned files are cleaned up at the next startup.
+	for _, a := range backup.Attachments {
+		hash, err := s.attachmentStore.Put(a.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store attachment file %s: %w", a.ID, err)
+		}
+		if hash != a.SHA256 {
+			return nil, fmt.Errorf("attachment %s file does not match its checksum", a.ID)
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO attachments (id, resource_type, resource_id, file_name, content_type, size_bytes, sha256, uploaded_by, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			a.ID, a.ResourceType, a.ResourceID, a.FileName, a.ContentType, len(a.Data), hash, a.UploadedBy,
+			a.CreatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import attachment %s: %w", a.ID, err)
+		}
+	}
+
 	// Commit the transaction
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
@@ -761,3 +1395,12 @@ func (s *BackupService) ImportJSON(ctx context.Context, jsonData []byte) (*Impor
 
 	return result, nil
 }
+
+// backupCurrency returns the currency of an imported record; backups made
+// before multi-currency support have no currency and were all in PLN
+func backupCurrency(currency string) string {
+	if currency == "" {
+		return DefaultCurrency
+	}
+	return currency
+}
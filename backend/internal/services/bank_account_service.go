@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+)
+
+var (
+	// ErrInvalidAccountNumber is returned for account numbers that are not a valid Polish NRB
+	ErrInvalidAccountNumber = errors.New("invalid bank account number")
+	// ErrBankAccountNotFound is returned when the account does not exist or belongs to someone else
+	ErrBankAccountNotFound = errors.New("bank account not found")
+)
+
+type BankAccountService struct {
+	accounts  repository.BankAccountRepository
+	txManager repository.TxManager
+}
+
+func NewBankAccountService(accounts repository.BankAccountRepository, txManager repository.TxManager) *BankAccountService {
+	return &BankAccountService{accounts: accounts, txManager: txManager}
+}
+
+// BankAccountRequest creates or updates a bank account
+type BankAccountRequest struct {
+	Label         string `json:"label"`
+	HolderName    string `json:"holderName"`
+	AccountNumber string `json:"accountNumber"` // NRB, with or without the PL prefix and spaces
+	IsDefault     bool   `json:"isDefault"`
+}
+
+// NormalizeNRB validates a Polish account number (NRB) and returns its 26 digits.
+// Spaces, dashes and a leading PL country code are accepted; the IBAN check digits must match.
+func NormalizeNRB(s string) (string, error) {
+	nrb := normalizeAccountNumber(s)
+	nrb = strings.TrimPrefix(nrb, "PL")
+	if len(nrb) != 26 || !isASCIIDigits(nrb) {
+		return "", ErrInvalidAccountNumber
+	}
+
+	// IBAN check: the BBAN followed by the country code (P=25, L=21) and check digits is 1 mod 97
+	n, ok := new(big.Int).SetString(nrb[2:]+"2521"+nrb[:2], 10)
+	if !ok || new(big.Int).Mod(n, big.NewInt(97)).Int64() != 1 {
+		return "", ErrInvalidAccountNumber
+	}
+	return nrb, nil
+}
+
+// ListAccounts returns every resident's bank accounts, for choosing where a bill is paid to
+func (s *BankAccountService) ListAccounts(ctx context.Context) ([]models.BankAccount, error) {
+	return s.accounts.List(ctx)
+}
+
+// ListUserAccounts returns a user's bank accounts, default first
+func (s *BankAccountService) ListUserAccounts(ctx context.Context, userID string) ([]models.BankAccount, error) {
+	return s.accounts.ListByUserID(ctx, userID)
+}
+
+// CreateAccount adds a bank account for the user. The first account becomes the default.
+func (s *BankAccountService) CreateAccount(ctx context.Context, userID string, req BankAccountRequest) (*models.BankAccount, error) {
+	account := &models.BankAccount{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		IsDefault: req.IsDefault,
+		CreatedAt: time.Now(),
+	}
+	if err := applyBankAccountRequest(account, req); err != nil {
+		return nil, err
+	}
+
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		existing, err := s.accounts.ListByUserID(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get bank accounts: %w", err)
+		}
+		if len(existing) == 0 {
+			account.IsDefault = true
+		}
+		if account.IsDefault {
+			if err := s.accounts.ClearDefault(ctx, userID); err != nil {
+				return fmt.Errorf("failed to update default bank account: %w", err)
+			}
+		}
+		if err := s.accounts.Create(ctx, account); err != nil {
+			return fmt.Errorf("failed to create bank account: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// UpdateAccount changes one of the user's bank accounts
+func (s *BankAccountService) UpdateAccount(ctx context.Context, userID, accountID string, req BankAccountRequest) (*models.BankAccount, error) {
+	var account *models.BankAccount
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		account, err = s.ownAccount(ctx, userID, accountID)
+		if err != nil {
+			return err
+		}
+		if err := applyBankAccountRequest(account, req); err != nil {
+			return err
+		}
+
+		// The default can be moved to another account but not removed
+		if req.IsDefault && !account.IsDefault {
+			if err := s.accounts.ClearDefault(ctx, userID); err != nil {
+				return fmt.Errorf("failed to update default bank account: %w", err)
+			}
+			account.IsDefault = true
+		}
+		if err := s.accounts.Update(ctx, account); err != nil {
+			return fmt.Errorf("failed to update bank account: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// DeleteAccount removes one of the user's bank accounts. Bills paid to it lose their
+// recipient; another account becomes the default if the default one was removed.
+func (s *BankAccountService) DeleteAccount(ctx context.Context, userID, accountID string) error {
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		account, err := s.ownAccount(ctx, userID, accountID)
+		if err != nil {
+			return err
+		}
+		if err := s.accounts.Delete(ctx, account.ID); err != nil {
+			return fmt.Errorf("failed to delete bank account: %w", err)
+		}
+		if !account.IsDefault {
+			return nil
+		}
+
+		remaining, err := s.accounts.ListByUserID(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get bank accounts: %w", err)
+		}
+		if len(remaining) > 0 {
+			remaining[0].IsDefault = true
+			if err := s.accounts.Update(ctx, &remaining[0]); err != nil {
+				return fmt.Errorf("failed to update default bank account: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BankAccountService) ownAccount(ctx context.Context, userID, accountID string) (*models.BankAccount, error) {
+	account, err := s.accounts.GetByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.UserID != userID {
+		return nil, ErrBankAccountNotFound
+	}
+	return account, nil
+}
+
+func applyBankAccountRequest(account *models.BankAccount, req BankAccountRequest) error {
+	holderName := strings.TrimSpace(req.HolderName)
+	if holderName == "" {
+		return errors.New("holder name is required")
+	}
+	nrb, err := NormalizeNRB(req.AccountNumber)
+	if err != nil {
+		return err
+	}
+
+	account.Label = strings.TrimSpace(req.Label)
+	account.HolderName = holderName
+	account.AccountNumber = nrb
+	return nil
+}
@@ -0,0 +1,605 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+// Bank transaction review states
+const (
+	BankTransactionPending = "pending"
+	BankTransactionMatched = "matched"
+	BankTransactionIgnored = "ignored"
+)
+
+// BankTransferPaymentMethod marks bill payments recorded from an imported bank statement
+const BankTransferPaymentMethod = "bank_transfer"
+
+const (
+	// bankMatchMinScore is the score a bill share or loan needs to be suggested
+	bankMatchMinScore = 40
+	// bankMatchMaxSuggestions limits the suggestions shown per transaction
+	bankMatchMaxSuggestions = 5
+	// bankMatchGraceDays is how long after a deadline a transfer still counts as on time
+	bankMatchGraceDays = 14
+)
+
+// ErrBankTransactionNotPending is returned when reviewing a transaction that was already matched or ignored
+var ErrBankTransactionNotPending = errors.New("bank transaction was already reviewed")
+
+type BankImportService struct {
+	imports         repository.BankImportRepository
+	transactions    repository.BankTransactionRepository
+	bills           repository.BillRepository
+	loans           repository.LoanRepository
+	loanPayments    repository.LoanPaymentRepository
+	users           repository.UserRepository
+	txManager       repository.TxManager
+	creditService   *CreditService
+	paymentService  *PaymentService
+	loanService     *LoanService
+	currencyService *CurrencyService
+}
+
+func NewBankImportService(
+	imports repository.BankImportRepository,
+	transactions repository.BankTransactionRepository,
+	bills repository.BillRepository,
+	loans repository.LoanRepository,
+	loanPayments repository.LoanPaymentRepository,
+	users repository.UserRepository,
+	txManager repository.TxManager,
+	creditService *CreditService,
+	paymentService *PaymentService,
+	loanService *LoanService,
+	currencyService *CurrencyService,
+) *BankImportService {
+	return &BankImportService{
+		imports:         imports,
+		transactions:    transactions,
+		bills:           bills,
+		loans:           loans,
+		loanPayments:    loanPayments,
+		users:           users,
+		txManager:       txManager,
+		creditService:   creditService,
+		paymentService:  paymentService,
+		loanService:     loanService,
+		currencyService: currencyService,
+	}
+}
+
+// BankImportResult summarizes an uploaded statement
+type BankImportResult struct {
+	Import          models.BankImport `json:"import"`
+	Imported        int               `json:"imported"`        // new incoming transfers queued for review
+	Duplicates      int               `json:"duplicates"`      // already imported from an earlier statement
+	SkippedOutgoing int               `json:"skippedOutgoing"` // outgoing transfers are not payments to the household
+}
+
+// BankMatchSuggestion is a bill share or loan a bank transfer probably pays
+type BankMatchSuggestion struct {
+	Type      string      `json:"type"` // bill, loan
+	BillID    string      `json:"billId,omitempty"`
+	LoanID    string      `json:"loanId,omitempty"`
+	UserID    string      `json:"userId"` // payer recorded when the match is confirmed
+	UserName  string      `json:"userName"`
+	Label     string      `json:"label"`
+	Remaining utils.Money `json:"remaining"`
+	Currency  string      `json:"currency"`
+	Score     int         `json:"score"`
+	Reasons   []string    `json:"reasons"` // amount, partial_amount, reference, payer, date
+}
+
+// BankTransactionReview is a pending transaction with its suggested matches, best first
+type BankTransactionReview struct {
+	models.BankTransaction
+	Suggestions []BankMatchSuggestion `json:"suggestions"`
+}
+
+// ConfirmBankMatchRequest selects what a bank transaction pays
+type ConfirmBankMatchRequest struct {
+	Type   string `json:"type"`             // bill, loan
+	BillID string `json:"billId,omitempty"` // for bill matches
+	UserID string `json:"userId,omitempty"` // payer of the bill share
+	LoanID string `json:"loanId,omitempty"` // for loan matches
+}
+
+// bankMatchCandidate is an open bill share or loan a transfer can be matched against
+type bankMatchCandidate struct {
+	suggestion BankMatchSuggestion
+	since      time.Time  // the bill or loan cannot be paid before this
+	deadline   *time.Time // payment deadline or loan due date
+	refs       []string   // identifiers that may appear in the transfer title
+	payerName  string
+}
+
+// ImportStatement parses an uploaded statement and queues its incoming transfers for review.
+// Transfers already imported from an earlier, overlapping statement are skipped.
+func (s *BankImportService) ImportStatement(ctx context.Context, fileName string, data []byte, format, userID string) (*BankImportResult, error) {
+	lines, format, err := ParseBankStatement(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	baseCurrency, err := s.currencyService.BaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BankImportResult{
+		Import: models.BankImport{
+			ID:         uuid.New().String(),
+			FileName:   fileName,
+			Format:     format,
+			ImportedBy: &userID,
+			CreatedAt:  time.Now(),
+		},
+	}
+
+	var txns []models.BankTransaction
+	occurrences := make(map[string]int)
+	for _, line := range lines {
+		if !line.Amount.IsPositive() {
+			result.SkippedOutgoing++
+			continue
+		}
+
+		currency := line.Currency
+		if currency == "" {
+			currency = baseCurrency
+		}
+		currency, err = NormalizeCurrency(currency)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+		}
+
+		key := bankLineKey(line, currency)
+		occurrences[key]++
+		fingerprint := bankLineFingerprint(key, occurrences[key])
+
+		exists, err := s.transactions.ExistsByFingerprint(ctx, fingerprint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for duplicates: %w", err)
+		}
+		if exists {
+			result.Duplicates++
+			continue
+		}
+
+		txns = append(txns, models.BankTransaction{
+			ID:                  uuid.New().String(),
+			ImportID:            result.Import.ID,
+			Fingerprint:         fingerprint,
+			BookedAt:            line.BookedAt,
+			Amount:              line.Amount.String(),
+			Currency:            currency,
+			CounterpartyName:    optionalString(line.CounterpartyName),
+			CounterpartyAccount: optionalString(line.CounterpartyAccount),
+			Title:               line.Title,
+			Reference:           optionalString(line.Reference),
+			Status:              BankTransactionPending,
+		})
+	}
+
+	result.Import.LineCount = len(txns)
+	result.Imported = len(txns)
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.imports.Create(ctx, &result.Import); err != nil {
+			return fmt.Errorf("failed to store bank import: %w", err)
+		}
+		for i := range txns {
+			if err := s.transactions.Create(ctx, &txns[i]); err != nil {
+				return fmt.Errorf("failed to store bank transaction: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[BANK-IMPORT] %s (%s): %d queued, %d duplicates, %d outgoing skipped",
+		fileName, format, result.Imported, result.Duplicates, result.SkippedOutgoing)
+	return result, nil
+}
+
+// bankLineKey identifies a transfer by what the bank reports about it
+func bankLineKey(line StatementLine, currency string) string {
+	return strings.Join([]string{
+		line.BookedAt.Format("2006-01-02"),
+		line.Amount.String(),
+		currency,
+		line.Reference,
+		line.CounterpartyAccount,
+		line.Title,
+	}, "|")
+}
+
+// bankLineFingerprint hashes a line key with its position among identical lines of the same
+// statement, so two equal transfers on one day are both kept while re-imports are still recognized
+func bankLineFingerprint(key string, occurrence int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", key, occurrence)))
+	return hex.EncodeToString(sum[:])
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// ListImports returns uploaded statements, newest first
+func (s *BankImportService) ListImports(ctx context.Context) ([]models.BankImport, error) {
+	return s.imports.List(ctx)
+}
+
+// ListImportTransactions returns the transactions stored from one statement
+func (s *BankImportService) ListImportTransactions(ctx context.Context, importID string) ([]models.BankTransaction, error) {
+	bankImport, err := s.imports.GetByID(ctx, importID)
+	if err != nil {
+		return nil, err
+	}
+	if bankImport == nil {
+		return nil, fmt.Errorf("bank import not found")
+	}
+	return s.transactions.ListByImportID(ctx, importID)
+}
+
+// GetReviewQueue returns the transactions waiting for review with suggested matches
+func (s *BankImportService) GetReviewQueue(ctx context.Context) ([]BankTransactionReview, error) {
+	pending, err := s.transactions.ListByStatus(ctx, BankTransactionPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending transactions: %w", err)
+	}
+
+	candidates, err := s.matchCandidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	queue := make([]BankTransactionReview, len(pending))
+	for i, txn := range pending {
+		queue[i] = BankTransactionReview{
+			BankTransaction: txn,
+			Suggestions:     suggestBankMatches(&txn, candidates),
+		}
+	}
+	return queue, nil
+}
+
+// matchCandidates collects every unpaid share of a posted bill and every open loan
+func (s *BankImportService) matchCandidates(ctx context.Context) ([]bankMatchCandidate, error) {
+	users, err := s.users.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users: %w", err)
+	}
+	names := make(map[string]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Name
+	}
+
+	var candidates []bankMatchCandidate
+
+	bills, err := listBillsByStatus(ctx, s.bills, OpenBillStatuses...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bills: %w", err)
+	}
+	for _, bill := range bills {
+		shares, err := s.creditService.GetOpenShares(ctx, bill.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, share := range shares {
+			// Any member of a group may pay the group's share
+			for _, userID := range share.PayerIDs {
+				candidates = append(candidates, bankMatchCandidate{
+					suggestion: BankMatchSuggestion{
+						Type:      "bill",
+						BillID:    bill.ID,
+						UserID:    userID,
+						UserName:  names[userID],
+						Label:     bankMatchBillLabel(&bill),
+						Remaining: share.Remaining,
+						Currency:  bill.Currency,
+					},
+					since:     bill.CreatedAt,
+					deadline:  bill.PaymentDeadline,
+					refs:      []string{bill.ID},
+					payerName: names[userID],
+				})
+			}
+		}
+	}
+
+	for _, status := range []string{"open", "partial"} {
+		loans, err := s.loans.ListByStatus(ctx, status)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch loans: %w", err)
+		}
+		for _, loan := range loans {
+			paid, err := s.loanPayments.SumByLoanID(ctx, loan.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to sum loan payments: %w", err)
+			}
+			remaining := utils.MoneyFromString(loan.AmountPLN).Sub(utils.MoneyFromString(paid))
+			if !remaining.IsPositive() {
+				continue
+			}
+
+			label := fmt.Sprintf("Pożyczka od %s", names[loan.LenderID])
+			if loan.Note != nil && *loan.Note != "" {
+				label += ": " + *loan.Note
+			}
+			candidates = append(candidates, bankMatchCandidate{
+				suggestion: BankMatchSuggestion{
+					Type:      "loan",
+					LoanID:    loan.ID,
+					UserID:    loan.BorrowerID,
+					UserName:  names[loan.BorrowerID],
+					Label:     label,
+					Remaining: remaining,
+					Currency:  loan.Currency,
+				},
+				since:     loan.CreatedAt,
+				deadline:  loan.DueDate,
+				refs:      []string{loan.ID},
+				payerName: names[loan.BorrowerID],
+			})
+		}
+	}
+
+	return candidates, nil
+}
+
+func bankMatchBillLabel(bill *models.Bill) string {
+	name := bill.Type
+	if bill.CustomType != nil && *bill.CustomType != "" {
+		name = *bill.CustomType
+	}
+	return fmt.Sprintf("%s %s – %s", name, bill.PeriodStart.Format("2006-01-02"), bill.PeriodEnd.Format("2006-01-02"))
+}
+
+// suggestBankMatches scores every candidate against a transfer and returns the likely ones, best first
+func suggestBankMatches(txn *models.BankTransaction, candidates []bankMatchCandidate) []BankMatchSuggestion {
+	suggestions := []BankMatchSuggestion{}
+	for _, c := range candidates {
+		score, reasons := scoreBankMatch(txn, &c)
+		if score < bankMatchMinScore {
+			continue
+		}
+		suggestion := c.suggestion
+		suggestion.Score = score
+		suggestion.Reasons = reasons
+		suggestions = append(suggestions, suggestion)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+	if len(suggestions) > bankMatchMaxSuggestions {
+		suggestions = suggestions[:bankMatchMaxSuggestions]
+	}
+	return suggestions
+}
+
+// scoreBankMatch rates how well a transfer fits a candidate by amount, reference, payer and date.
+// Candidates in another currency never match.
+func scoreBankMatch(txn *models.BankTransaction, c *bankMatchCandidate) (int, []string) {
+	if txn.Currency != c.suggestion.Currency {
+		return 0, nil
+	}
+
+	score := 0
+	var reasons []string
+
+	amount := utils.MoneyFromString(txn.Amount)
+	switch {
+	case amount == c.suggestion.Remaining:
+		score += 50
+		reasons = append(reasons, "amount")
+	case amount < c.suggestion.Remaining:
+		score += 10
+		reasons = append(reasons, "partial_amount")
+	}
+
+	text := foldForMatching(txn.Title)
+	if txn.Reference != nil {
+		text += " " + foldForMatching(*txn.Reference)
+	}
+	for _, ref := range c.refs {
+		if referenceMentioned(text, ref) {
+			score += 40
+			reasons = append(reasons, "reference")
+			break
+		}
+	}
+
+	counterparty := text
+	if txn.CounterpartyName != nil {
+		counterparty = foldForMatching(*txn.CounterpartyName) + " " + text
+	}
+	if nameMentioned(counterparty, c.payerName) {
+		score += 30
+		reasons = append(reasons, "payer")
+	}
+
+	switch {
+	case txn.BookedAt.Before(c.since.AddDate(0, 0, -1)):
+		score -= 20
+	case c.deadline == nil || !txn.BookedAt.After(c.deadline.AddDate(0, 0, bankMatchGraceDays)):
+		score += 10
+		reasons = append(reasons, "date")
+	}
+
+	return score, reasons
+}
+
+// referenceMentioned reports whether a transfer title contains an ID, in full or as the
+// first block of the UUID that people usually copy into the title
+func referenceMentioned(text, id string) bool {
+	id = strings.ToLower(id)
+	if id == "" {
+		return false
+	}
+	if strings.Contains(text, id) {
+		return true
+	}
+	short, _, _ := strings.Cut(id, "-")
+	return len(short) >= 8 && strings.Contains(text, short)
+}
+
+// nameMentioned reports whether every word of a person's name appears in the text
+func nameMentioned(text, name string) bool {
+	words := strings.Fields(foldForMatching(name))
+	if len(words) == 0 {
+		return false
+	}
+	textWords := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == ',' || r == '.' || r == '/' }) {
+		textWords[w] = true
+	}
+	for _, w := range words {
+		if !textWords[w] {
+			return false
+		}
+	}
+	return true
+}
+
+// polishFolding strips Polish diacritics, which banks often drop from names and titles
+var polishFolding = strings.NewReplacer("ą", "a", "ć", "c", "ę", "e", "ł", "l", "ń", "n", "ó", "o", "ś", "s", "ź", "z", "ż", "z")
+
+func foldForMatching(s string) string {
+	return polishFolding.Replace(strings.ToLower(s))
+}
+
+// ConfirmMatch records a pending transfer as a payment of the chosen bill share or loan
+// and marks it matched. The payment and the review outcome are stored together.
+func (s *BankImportService) ConfirmMatch(ctx context.Context, txnID string, req ConfirmBankMatchRequest, reviewerID string) (*models.BankTransaction, error) {
+	var txn *models.BankTransaction
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		txn, err = s.pendingTransaction(ctx, txnID)
+		if err != nil {
+			return err
+		}
+		amount := utils.MoneyFromString(txn.Amount)
+
+		switch req.Type {
+		case "bill":
+			if req.BillID == "" || req.UserID == "" {
+				return errors.New("billId and userId are required for bill matches")
+			}
+			bill, err := s.bills.GetByID(ctx, req.BillID)
+			if err != nil || bill == nil {
+				return fmt.Errorf("bill not found")
+			}
+			if bill.Currency != txn.Currency {
+				return fmt.Errorf("transfer currency %s does not match bill currency %s", txn.Currency, bill.Currency)
+			}
+
+			method := BankTransferPaymentMethod
+			payment, err := s.paymentService.RecordPayment(ctx, RecordPaymentRequest{
+				BillID:    bill.ID,
+				Amount:    amount,
+				Method:    &method,
+				Reference: txn.Reference,
+				PaidAt:    &txn.BookedAt,
+			}, req.UserID)
+			if err != nil {
+				return err
+			}
+			txn.PaymentID = &payment.ID
+
+		case "loan":
+			if req.LoanID == "" {
+				return errors.New("loanId is required for loan matches")
+			}
+			loan, err := s.loans.GetByID(ctx, req.LoanID)
+			if err != nil || loan == nil {
+				return fmt.Errorf("loan not found")
+			}
+			if loan.Currency != txn.Currency {
+				return fmt.Errorf("transfer currency %s does not match loan currency %s", txn.Currency, loan.Currency)
+			}
+
+			note := "Przelew: " + txn.Title
+			payment, err := s.loanService.CreateLoanPayment(ctx, CreateLoanPaymentRequest{
+				LoanID:    loan.ID,
+				AmountPLN: amount,
+				PaidAt:    txn.BookedAt,
+				Note:      &note,
+			})
+			if err != nil {
+				return err
+			}
+			txn.LoanPaymentID = &payment.ID
+
+		default:
+			return fmt.Errorf("invalid match type: %s", req.Type)
+		}
+
+		now := time.Now()
+		matchType := req.Type
+		txn.Status = BankTransactionMatched
+		txn.MatchType = &matchType
+		txn.ReviewedBy = &reviewerID
+		txn.ReviewedAt = &now
+		if err := s.transactions.Update(ctx, txn); err != nil {
+			return fmt.Errorf("failed to update bank transaction: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[BANK-IMPORT] Transaction %s matched to %s by %s", txn.ID, req.Type, reviewerID)
+	return txn, nil
+}
+
+// IgnoreTransaction takes a transfer that is not a household payment out of the review queue
+func (s *BankImportService) IgnoreTransaction(ctx context.Context, txnID, reviewerID string) (*models.BankTransaction, error) {
+	txn, err := s.pendingTransaction(ctx, txnID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	txn.Status = BankTransactionIgnored
+	txn.ReviewedBy = &reviewerID
+	txn.ReviewedAt = &now
+	if err := s.transactions.Update(ctx, txn); err != nil {
+		return nil, fmt.Errorf("failed to update bank transaction: %w", err)
+	}
+	return txn, nil
+}
+
+func (s *BankImportService) pendingTransaction(ctx context.Context, txnID string) (*models.BankTransaction, error) {
+	txn, err := s.transactions.GetByID(ctx, txnID)
+	if err != nil {
+		return nil, err
+	}
+	if txn == nil {
+		return nil, fmt.Errorf("bank transaction not found")
+	}
+	if txn.Status != BankTransactionPending {
+		return nil, ErrBankTransactionNotPending
+	}
+	return txn, nil
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCSVStatement(t *testing.T) {
+	data := []byte("\xef\xbb\xbfRachunek;PL61109010140000071219812874\n" +
+		"\n" +
+		"Data księgowania;Nadawca / Odbiorca;Rachunek kontrahenta;Tytuł;Kwota;Waluta\n" +
+		"05.03.2024;Jan Kowalski;61 1090 1014 0000 0712 1981 2874;Czynsz marzec;\"1 250,50\";PLN\n" +
+		"06.03.2024;Sklep;;Zakupy;-45,99;PLN\n")
+
+	lines, format, err := ParseBankStatement(data, "")
+	require.NoError(t, err)
+	assert.Equal(t, StatementFormatCSV, format)
+	require.Len(t, lines, 2)
+
+	assert.Equal(t, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), lines[0].BookedAt)
+	assert.Equal(t, utils.Money(125050), lines[0].Amount)
+	assert.Equal(t, "PLN", lines[0].Currency)
+	assert.Equal(t, "Jan Kowalski", lines[0].CounterpartyName)
+	assert.Equal(t, "61109010140000071219812874", lines[0].CounterpartyAccount)
+	assert.Equal(t, "Czynsz marzec", lines[0].Title)
+	assert.Equal(t, utils.Money(-4599), lines[1].Amount)
+}
+
+func TestParseMT940Statement(t *testing.T) {
+	data := []byte(":20:STMT\r\n" +
+		":25:/PL61109010140000071219812874\r\n" +
+		":28C:1/1\r\n" +
+		":60F:C240301PLN1000,00\r\n" +
+		":61:2403050305CN150,00NTRFNONREF//BANKREF1\r\n" +
+		":86:020~00TRAN~20Czynsz marzec~32Jan Kowalski~38PL27114020040000300201355387\r\n" +
+		":61:240306DN20,00NTRFNONREF\r\n" +
+		":86:Oplata za karte\r\n" +
+		":62F:C240306PLN1130,00\r\n")
+
+	lines, format, err := ParseBankStatement(data, "")
+	require.NoError(t, err)
+	assert.Equal(t, StatementFormatMT940, format)
+	require.Len(t, lines, 2)
+
+	assert.Equal(t, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), lines[0].BookedAt)
+	assert.Equal(t, utils.Money(15000), lines[0].Amount)
+	assert.Equal(t, "PLN", lines[0].Currency)
+	assert.Equal(t, "Czynsz marzec", lines[0].Title)
+	assert.Equal(t, "Jan Kowalski", lines[0].CounterpartyName)
+	assert.Equal(t, "PL27114020040000300201355387", lines[0].CounterpartyAccount)
+	assert.Equal(t, "BANKREF1", lines[0].Reference)
+
+	assert.Equal(t, utils.Money(-2000), lines[1].Amount)
+	assert.Equal(t, "Oplata za karte", lines[1].Title)
+}
+
+func TestParseCAMT053Statement(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Ntry>
+        <Amt Ccy="PLN">99.90</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2024-03-07</Dt></BookgDt>
+        <NtryDtls>
+          <TxDtls>
+            <Refs><EndToEndId>E2E-1</EndToEndId></Refs>
+            <RltdPties>
+              <Dbtr><Nm>Anna Nowak</Nm></Dbtr>
+              <DbtrAcct><Id><IBAN>PL27114020040000300201355387</IBAN></Id></DbtrAcct>
+            </RltdPties>
+            <RmtInf><Ustrd>Internet luty</Ustrd></RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="PLN">10.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>PDNG</Sts>
+        <BookgDt><Dt>2024-03-08</Dt></BookgDt>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`)
+
+	lines, format, err := ParseBankStatement(data, "")
+	require.NoError(t, err)
+	assert.Equal(t, StatementFormatCAMT053, format)
+	require.Len(t, lines, 1, "pending entries are not booked yet")
+
+	assert.Equal(t, time.Date(2024, 3, 7, 0, 0, 0, 0, time.UTC), lines[0].BookedAt)
+	assert.Equal(t, utils.Money(9990), lines[0].Amount)
+	assert.Equal(t, "Anna Nowak", lines[0].CounterpartyName)
+	assert.Equal(t, "PL27114020040000300201355387", lines[0].CounterpartyAccount)
+	assert.Equal(t, "Internet luty", lines[0].Title)
+	assert.Equal(t, "E2E-1", lines[0].Reference)
+}
+
+func TestParseBankStatementRejectsUnknownFormat(t *testing.T) {
+	_, _, err := ParseBankStatement([]byte("hello"), "")
+	assert.ErrorIs(t, err, ErrInvalidStatement)
+}
+
+func newTestBankImportService(repos *repository.Repositories) (*BankImportService, *BillService) {
+	billService, paymentService, _ := newTestCreditServices(repos)
+	currencyService := NewCurrencyService(repos.ExchangeRates, NewAppSettingsService(repos.AppSettings))
+	allocationService := NewAllocationService(repos.Users, repos.Residencies, repos.BillResidents, repos.Groups, repos.Consumptions, repos.Allocations,
+		repos.Bills, repos.BillSplits, repos.BillItems, repos.BillTariffZones, repos.Meters)
+	creditService := NewCreditService(repos.CreditEntries, repos.Payments, repos.Users, allocationService)
+	loanService := NewLoanService(repos.Loans, repos.LoanPayments, repos.Users, repos.Groups, repos.TxManager,
+		newTestNotificationService(repos), currencyService)
+	return NewBankImportService(repos.BankImports, repos.BankTransactions, repos.Bills, repos.Loans, repos.LoanPayments,
+		repos.Users, repos.TxManager, creditService, paymentService, loanService, currencyService), billService
+}
+
+func TestBankImportMatchesBillPayment(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	bankImportService, billService := newTestBankImportService(repos)
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+
+	bill, err := billService.CreateBill(ctx, CreateBillRequest{
+		Type:           "internet",
+		PeriodStart:    time.Now().AddDate(0, -1, 0),
+		PeriodEnd:      time.Now(),
+		TotalAmountPLN: utils.NewMoney(100, 0),
+	}, alice.ID)
+	require.NoError(t, err)
+	require.NoError(t, billService.PostBill(ctx, bill.ID))
+
+	today := time.Now().Format("2006-01-02")
+	statement := []byte(fmt.Sprintf("Data;Nadawca;Tytuł;Kwota;Waluta\n"+
+		"%s;Bob;Internet %s;50,00;PLN\n"+
+		"%s;Sklep;Zakupy;-20,00;PLN\n"+
+		"%s;Nieznany;Zwrot;12,34;PLN\n", today, bill.ID[:8], today, today))
+
+	result, err := bankImportService.ImportStatement(ctx, "wyciag.csv", statement, "", alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 1, result.SkippedOutgoing)
+
+	// Overlapping statements do not queue the same transfers twice
+	result, err = bankImportService.ImportStatement(ctx, "wyciag.csv", statement, "", alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Imported)
+	assert.Equal(t, 2, result.Duplicates)
+
+	queue, err := bankImportService.GetReviewQueue(ctx)
+	require.NoError(t, err)
+	require.Len(t, queue, 2)
+	require.NotEmpty(t, queue[0].Suggestions)
+	best := queue[0].Suggestions[0]
+	assert.Equal(t, "bill", best.Type)
+	assert.Equal(t, bill.ID, best.BillID)
+	assert.Equal(t, bob.ID, best.UserID)
+	assert.ElementsMatch(t, []string{"amount", "reference", "payer", "date"}, best.Reasons)
+	assert.Empty(t, queue[1].Suggestions, "an unrelated transfer stays unmatched")
+
+	txn, err := bankImportService.ConfirmMatch(ctx, queue[0].ID, ConfirmBankMatchRequest{
+		Type: best.Type, BillID: best.BillID, UserID: best.UserID,
+	}, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, BankTransactionMatched, txn.Status)
+	require.NotNil(t, txn.PaymentID)
+
+	payment, err := repos.Payments.GetByID(ctx, *txn.PaymentID)
+	require.NoError(t, err)
+	assert.Equal(t, bob.ID, payment.PayerUserID)
+	assert.Equal(t, BankTransferPaymentMethod, *payment.Method)
+
+	coverage, err := bankImportService.creditService.GetBillCoverage(ctx, bill.ID)
+	require.NoError(t, err)
+	assert.Equal(t, utils.NewMoney(50, 0), coverage.Paid[bob.ID])
+
+	_, err = bankImportService.ConfirmMatch(ctx, queue[0].ID, ConfirmBankMatchRequest{
+		Type: best.Type, BillID: best.BillID, UserID: best.UserID,
+	}, alice.ID)
+	assert.ErrorIs(t, err, ErrBankTransactionNotPending)
+
+	queue, err = bankImportService.GetReviewQueue(ctx)
+	require.NoError(t, err)
+	assert.Len(t, queue, 1)
+}
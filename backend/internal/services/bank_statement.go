@@ -0,0 +1,561 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+// Bank statement formats accepted by the import
+const (
+	StatementFormatCSV     = "csv"
+	StatementFormatMT940   = "mt940"
+	StatementFormatCAMT053 = "camt053"
+)
+
+// ErrInvalidStatement is returned when an uploaded bank statement cannot be read
+var ErrInvalidStatement = errors.New("invalid bank statement")
+
+// StatementLine is one booked transaction read from a bank statement
+type StatementLine struct {
+	BookedAt            time.Time
+	Amount              utils.Money // positive for incoming transfers, negative for outgoing
+	Currency            string      // empty when the statement does not say
+	CounterpartyName    string
+	CounterpartyAccount string
+	Title               string
+	Reference           string
+}
+
+// DetectStatementFormat guesses the format of a statement from its content
+func DetectStatementFormat(data []byte) string {
+	head := string(bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	if strings.HasPrefix(head, "<") && (strings.Contains(head, "camt.053") || strings.Contains(head, "BkToCstmrStmt")) {
+		return StatementFormatCAMT053
+	}
+	if strings.Contains(head, ":61:") || (strings.Contains(head, ":20:") && strings.Contains(head, ":60F:")) {
+		return StatementFormatMT940
+	}
+	return StatementFormatCSV
+}
+
+// ParseBankStatement reads the transactions of a statement. An empty format is detected
+// from the content. Returns the lines and the format that was used.
+func ParseBankStatement(data []byte, format string) ([]StatementLine, string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if format == "" {
+		format = DetectStatementFormat(data)
+	}
+
+	var lines []StatementLine
+	var err error
+	switch format {
+	case StatementFormatCSV:
+		lines, err = parseCSVStatement(data)
+	case StatementFormatMT940:
+		lines, err = parseMT940Statement(data)
+	case StatementFormatCAMT053:
+		lines, err = parseCAMT053Statement(data)
+	default:
+		return nil, format, fmt.Errorf("%w: unsupported format %q", ErrInvalidStatement, format)
+	}
+	if err != nil {
+		return nil, format, err
+	}
+	if len(lines) == 0 {
+		return nil, format, fmt.Errorf("%w: no transactions found", ErrInvalidStatement)
+	}
+	return lines, format, nil
+}
+
+// csvColumnAliases maps normalized header names used by banks to statement fields.
+// Earlier aliases win when a file has more than one matching column.
+var csvColumnAliases = map[string][]string{
+	"date":      {"data księgowania", "data ksiegowania", "booking date", "data operacji", "data transakcji", "data", "date"},
+	"amount":    {"kwota", "kwota operacji", "kwota transakcji", "amount"},
+	"currency":  {"waluta", "waluta operacji", "currency"},
+	"title":     {"tytuł", "tytul", "tytuł operacji", "opis operacji", "opis", "title", "description"},
+	"name":      {"nadawca", "nadawca / odbiorca", "nadawca/odbiorca", "kontrahent", "dane kontrahenta", "counterparty", "name"},
+	"account":   {"rachunek nadawcy", "rachunek kontrahenta", "numer rachunku", "nr rachunku", "rachunek", "account"},
+	"reference": {"numer referencyjny", "referencja", "id transakcji", "reference"},
+}
+
+// parseCSVStatement reads a CSV export. Leading lines before the header row (account
+// summaries some banks put on top) are skipped; the header needs a date and an amount column.
+func parseCSVStatement(data []byte) ([]StatementLine, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if bytes.Contains(data, []byte(";")) {
+		reader.Comma = ';'
+	}
+
+	var columns map[string]int
+	var lines []StatementLine
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+		}
+
+		if columns == nil {
+			columns = csvHeaderColumns(record)
+			continue
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		if field("date") == "" {
+			continue // blank line or closing balance summary
+		}
+
+		bookedAt, err := parseStatementDate(field("date"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidStatement, row, err)
+		}
+		amount, currency, err := parseStatementAmount(field("amount"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidStatement, row, err)
+		}
+		if c := field("currency"); c != "" {
+			currency = c
+		}
+
+		lines = append(lines, StatementLine{
+			BookedAt:            bookedAt,
+			Amount:              amount,
+			Currency:            strings.ToUpper(currency),
+			CounterpartyName:    field("name"),
+			CounterpartyAccount: normalizeAccountNumber(field("account")),
+			Title:               field("title"),
+			Reference:           field("reference"),
+		})
+	}
+
+	if columns == nil {
+		return nil, fmt.Errorf("%w: no header row with date and amount columns", ErrInvalidStatement)
+	}
+	return lines, nil
+}
+
+// csvHeaderColumns returns the column index of each known field, or nil when the
+// record is not a header row
+func csvHeaderColumns(record []string) map[string]int {
+	names := make(map[string]int)
+	for i, cell := range record {
+		name := strings.ToLower(strings.TrimSpace(cell))
+		name = strings.Trim(name, "#:")
+		if _, ok := names[name]; !ok {
+			names[name] = i
+		}
+	}
+
+	columns := make(map[string]int)
+	for field, aliases := range csvColumnAliases {
+		for _, alias := range aliases {
+			if i, ok := names[alias]; ok {
+				columns[field] = i
+				break
+			}
+		}
+	}
+
+	_, hasDate := columns["date"]
+	_, hasAmount := columns["amount"]
+	if !hasDate || !hasAmount {
+		return nil
+	}
+	return columns
+}
+
+var statementDateLayouts = []string{
+	"2006-01-02",
+	"02.01.2006",
+	"02-01-2006",
+	"2006/01/02",
+	"02/01/2006",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+}
+
+// parseStatementDate parses the date formats used in bank exports
+func parseStatementDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range statementDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// parseStatementAmount parses amounts such as "-1 234,56", "1,234.56" or "150,00 PLN",
+// returning the currency when it is written next to the amount
+func parseStatementAmount(s string) (utils.Money, string, error) {
+	s = strings.NewReplacer("\u00a0", "", " ", "", "'", "").Replace(strings.TrimSpace(s))
+
+	currency := strings.TrimLeftFunc(s, func(r rune) bool { return !unicode.IsLetter(r) })
+	s = strings.TrimSuffix(s, currency)
+	currency = strings.TrimSpace(currency)
+
+	// Whichever separator comes last is the decimal one; the other groups thousands
+	if dot, comma := strings.LastIndex(s, "."), strings.LastIndex(s, ","); dot >= 0 && comma >= 0 {
+		if dot > comma {
+			s = strings.ReplaceAll(s, ",", "")
+		} else {
+			s = strings.ReplaceAll(s, ".", "")
+		}
+	}
+
+	amount, err := utils.ParseMoney(s)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, currency, nil
+}
+
+// normalizeAccountNumber strips spaces from an account number and upper-cases IBAN country codes
+func normalizeAccountNumber(s string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "\u00a0", "", "-", "").Replace(s))
+}
+
+// parseMT940Statement reads an MT940 (SWIFT) statement. Each :61: field is one transaction,
+// described by the :86: field after it; Polish and German banks split :86: into numbered
+// subfields (~20 title, ~32 name, ~38 account), other banks put free text there.
+func parseMT940Statement(data []byte) ([]StatementLine, error) {
+	text := strings.ReplaceAll(strings.ReplaceAll(string(data), "\r\n", "\n"), "\r", "\n")
+
+	type field struct{ tag, value string }
+	var fields []field
+	for _, line := range strings.Split(text, "\n") {
+		if len(line) > 3 && line[0] == ':' {
+			if end := strings.Index(line[1:], ":"); end > 0 && end <= 4 {
+				fields = append(fields, field{tag: line[1 : end+1], value: line[end+2:]})
+				continue
+			}
+		}
+		if len(fields) > 0 && line != "-" {
+			fields[len(fields)-1].value += "\n" + line
+		}
+	}
+
+	var lines []StatementLine
+	currency := ""
+	for _, f := range fields {
+		switch f.tag {
+		case "60F", "60M":
+			// Opening balance: D/C mark, YYMMDD, currency, amount
+			if len(f.value) >= 10 {
+				currency = f.value[7:10]
+			}
+		case "61":
+			line, err := parseMT940Entry(f.value)
+			if err != nil {
+				return nil, err
+			}
+			line.Currency = currency
+			lines = append(lines, line)
+		case "86":
+			if len(lines) > 0 {
+				applyMT940Details(&lines[len(lines)-1], f.value)
+			}
+		}
+	}
+	return lines, nil
+}
+
+// parseMT940Entry parses a :61: statement line:
+// value date YYMMDD, optional entry date MMDD, mark (C, D, RC, RD), optional funds code,
+// amount with a decimal comma, transaction type, customer reference and //bank reference
+func parseMT940Entry(value string) (StatementLine, error) {
+	first, _, _ := strings.Cut(value, "\n")
+	s := strings.TrimSpace(first)
+	invalid := fmt.Errorf("%w: invalid :61: line %q", ErrInvalidStatement, first)
+
+	if len(s) < 6 {
+		return StatementLine{}, invalid
+	}
+	valueDate, err := time.Parse("060102", s[:6])
+	if err != nil {
+		return StatementLine{}, invalid
+	}
+	s = s[6:]
+
+	bookedAt := valueDate
+	if len(s) >= 4 && isASCIIDigits(s[:4]) {
+		if entry, err := time.Parse("0102", s[:4]); err == nil {
+			bookedAt = time.Date(valueDate.Year(), entry.Month(), entry.Day(), 0, 0, 0, 0, time.UTC)
+			// Entry and value dates may fall on different sides of New Year
+			if bookedAt.Sub(valueDate) > 180*24*time.Hour {
+				bookedAt = bookedAt.AddDate(-1, 0, 0)
+			} else if valueDate.Sub(bookedAt) > 180*24*time.Hour {
+				bookedAt = bookedAt.AddDate(1, 0, 0)
+			}
+		}
+		s = s[4:]
+	}
+
+	negative := false
+	switch {
+	case strings.HasPrefix(s, "RC"):
+		negative, s = true, s[2:]
+	case strings.HasPrefix(s, "RD"):
+		s = s[2:]
+	case strings.HasPrefix(s, "C"):
+		s = s[1:]
+	case strings.HasPrefix(s, "D"):
+		negative, s = true, s[1:]
+	default:
+		return StatementLine{}, invalid
+	}
+
+	// Optional third character of the currency code ("funds code")
+	if len(s) > 0 && unicode.IsLetter(rune(s[0])) {
+		s = s[1:]
+	}
+
+	end := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) && r != ',' })
+	if end <= 0 {
+		return StatementLine{}, invalid
+	}
+	amount, err := utils.ParseMoney(s[:end])
+	if err != nil {
+		return StatementLine{}, invalid
+	}
+	if negative {
+		amount = amount.Neg()
+	}
+	s = s[end:]
+
+	// Transaction type: N, F or S followed by three characters
+	if len(s) >= 4 {
+		s = s[4:]
+	}
+	customerRef, bankRef, _ := strings.Cut(s, "//")
+	reference := strings.TrimSpace(customerRef)
+	if reference == "" || strings.EqualFold(reference, "NONREF") {
+		reference = strings.TrimSpace(bankRef)
+	}
+
+	return StatementLine{
+		BookedAt:  bookedAt,
+		Amount:    amount,
+		Reference: reference,
+	}, nil
+}
+
+// applyMT940Details fills the title and counterparty of a line from its :86: field
+func applyMT940Details(line *StatementLine, value string) {
+	text := strings.ReplaceAll(value, "\n", "")
+
+	// Structured: three digit transaction code followed by a separator and numbered subfields
+	if len(text) > 4 && isASCIIDigits(text[:3]) && strings.ContainsRune("~?^", rune(text[3])) {
+		var title, name strings.Builder
+		for _, part := range strings.Split(text[4:], string(text[3])) {
+			if len(part) < 2 {
+				continue
+			}
+			code, content := part[:2], part[2:]
+			switch {
+			case code >= "20" && code <= "29", code >= "60" && code <= "63":
+				title.WriteString(content)
+			case code == "32" || code == "33":
+				name.WriteString(content)
+			case code == "38" || (code == "31" && line.CounterpartyAccount == ""):
+				line.CounterpartyAccount = normalizeAccountNumber(content)
+			}
+		}
+		line.Title = strings.TrimSpace(title.String())
+		line.CounterpartyName = strings.TrimSpace(name.String())
+		return
+	}
+
+	line.Title = strings.TrimSpace(text)
+}
+
+func isASCIIDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// camtDocument is the part of an ISO 20022 camt.053 statement the import reads.
+// Element names are matched without namespaces so every camt.053 version is accepted.
+type camtDocument struct {
+	Statements []struct {
+		Entries []camtEntry `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtEntry struct {
+	Amount      camtAmount `xml:"Amt"`
+	CreditDebit string     `xml:"CdtDbtInd"`
+	Reversal    bool       `xml:"RvslInd"`
+	Status      struct {
+		Value string `xml:",chardata"` // camt.053.001.02
+		Code  string `xml:"Cd"`        // camt.053.001.08 and later
+	} `xml:"Sts"`
+	BookingDate    camtDate        `xml:"BookgDt"`
+	ValueDate      camtDate        `xml:"ValDt"`
+	ServicerRef    string          `xml:"AcctSvcrRef"`
+	AdditionalInfo string          `xml:"AddtlNtryInf"`
+	Details        []camtTxDetails `xml:"NtryDtls>TxDtls"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"` // camt.053.001.08 and later
+}
+
+type camtTxDetails struct {
+	EndToEndID     string     `xml:"Refs>EndToEndId"`
+	ServicerRef    string     `xml:"Refs>AcctSvcrRef"`
+	Amount         camtAmount `xml:"Amt"`
+	TxAmount       camtAmount `xml:"AmtDtls>TxAmt>Amt"`
+	Debtor         camtParty  `xml:"RltdPties>Dbtr"`
+	DebtorIBAN     string     `xml:"RltdPties>DbtrAcct>Id>IBAN"`
+	Creditor       camtParty  `xml:"RltdPties>Cdtr"`
+	CreditorIBAN   string     `xml:"RltdPties>CdtrAcct>Id>IBAN"`
+	Unstructured   []string   `xml:"RmtInf>Ustrd"`
+	StructuredRefs []string   `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	AdditionalInfo string     `xml:"AddtlTxInf"`
+}
+
+// parseCAMT053Statement reads an ISO 20022 camt.053 XML statement. Only booked entries are
+// returned; an entry with several transaction details (a batch) gives one line per detail.
+func parseCAMT053Statement(data []byte) ([]StatementLine, error) {
+	var doc camtDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+	}
+
+	var lines []StatementLine
+	for _, stmt := range doc.Statements {
+		for _, entry := range stmt.Entries {
+			status := strings.TrimSpace(entry.Status.Code)
+			if status == "" {
+				status = strings.TrimSpace(entry.Status.Value)
+			}
+			if status != "" && status != "BOOK" {
+				continue
+			}
+
+			bookedAt, err := entry.BookingDate.parse()
+			if err != nil {
+				if bookedAt, err = entry.ValueDate.parse(); err != nil {
+					return nil, fmt.Errorf("%w: entry %s has no booking date", ErrInvalidStatement, entry.ServicerRef)
+				}
+			}
+
+			incoming := entry.CreditDebit == "CRDT"
+			if entry.Reversal {
+				incoming = !incoming
+			}
+
+			details := entry.Details
+			if len(details) == 0 {
+				details = []camtTxDetails{{}}
+			}
+			for _, d := range details {
+				amt := d.Amount
+				if amt.Value == "" {
+					amt = d.TxAmount
+				}
+				if amt.Value == "" || len(entry.Details) <= 1 {
+					amt = entry.Amount
+				}
+				amount, err := utils.ParseMoney(amt.Value)
+				if err != nil {
+					return nil, fmt.Errorf("%w: invalid amount %q", ErrInvalidStatement, amt.Value)
+				}
+				if !incoming {
+					amount = amount.Neg()
+				}
+
+				// The counterparty is whoever is on the other side of the transfer
+				party, account := d.Debtor, d.DebtorIBAN
+				if !incoming {
+					party, account = d.Creditor, d.CreditorIBAN
+				}
+				name := party.Name
+				if name == "" {
+					name = party.PartyName
+				}
+
+				title := strings.TrimSpace(strings.Join(d.Unstructured, " "))
+				if title == "" {
+					title = strings.TrimSpace(strings.Join(d.StructuredRefs, " "))
+				}
+				if title == "" {
+					title = strings.TrimSpace(d.AdditionalInfo)
+				}
+				if title == "" {
+					title = strings.TrimSpace(entry.AdditionalInfo)
+				}
+
+				reference := d.EndToEndID
+				if reference == "" || reference == "NOTPROVIDED" {
+					reference = d.ServicerRef
+				}
+				if reference == "" {
+					reference = entry.ServicerRef
+				}
+
+				lines = append(lines, StatementLine{
+					BookedAt:            bookedAt,
+					Amount:              amount,
+					Currency:            strings.ToUpper(amt.Currency),
+					CounterpartyName:    strings.TrimSpace(name),
+					CounterpartyAccount: normalizeAccountNumber(account),
+					Title:               title,
+					Reference:           strings.TrimSpace(reference),
+				})
+			}
+		}
+	}
+	return lines, nil
+}
+
+func (d camtDate) parse() (time.Time, error) {
+	if d.Date != "" {
+		return time.Parse("2006-01-02", strings.TrimSpace(d.Date))
+	}
+	if d.DateTime != "" {
+		return parseStatementDate(strings.TrimSpace(d.DateTime))
+	}
+	return time.Time{}, errors.New("missing date")
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,7 +22,18 @@ type BillService struct {
 	payments            repository.PaymentRepository
 	users               repository.UserRepository
 	groups              repository.GroupRepository
+	billSplits          repository.BillSplitRepository
+	billItems           repository.BillItemRepository
+	billTariffZones     repository.BillTariffZoneRepository
+	billTransitions     repository.BillTransitionRepository
+	billVersions        repository.BillVersionRepository
+	bankAccounts        repository.BankAccountRepository
+	txManager           repository.TxManager
 	notificationService *NotificationService
+	currencyService     *CurrencyService
+	allocationService   *AllocationService
+	creditService       *CreditService
+	stateMachine        *BillStateMachine
 }
 
 func NewBillService(
@@ -30,29 +43,63 @@ func NewBillService(
 	payments repository.PaymentRepository,
 	users repository.UserRepository,
 	groups repository.GroupRepository,
+	billSplits repository.BillSplitRepository,
+	billItems repository.BillItemRepository,
+	billTariffZones repository.BillTariffZoneRepository,
+	billTransitions repository.BillTransitionRepository,
+	billVersions repository.BillVersionRepository,
+	bankAccounts repository.BankAccountRepository,
+	txManager repository.TxManager,
 	notificationService *NotificationService,
+	currencyService *CurrencyService,
+	allocationService *AllocationService,
+	creditService *CreditService,
+	transitions []BillTransitionRule, // the bill lifecycle, DefaultBillTransitions when nil
 ) *BillService {
-	return &BillService{
+	if transitions == nil {
+		transitions = DefaultBillTransitions()
+	}
+	s := &BillService{
 		bills:               bills,
 		consumptions:        consumptions,
 		allocations:         allocations,
 		payments:            payments,
 		users:               users,
 		groups:              groups,
+		billSplits:          billSplits,
+		billItems:           billItems,
+		billTariffZones:     billTariffZones,
+		billTransitions:     billTransitions,
+		billVersions:        billVersions,
+		bankAccounts:        bankAccounts,
+		txManager:           txManager,
 		notificationService: notificationService,
+		currencyService:     currencyService,
+		allocationService:   allocationService,
+		creditService:       creditService,
+		stateMachine:        NewBillStateMachine(transitions),
 	}
+	s.stateMachine.OnTransition(s.notifyTransition, BillTransitionRequestPayment, BillTransitionResolveDispute)
+	return s
 }
 
 type CreateBillRequest struct {
-	Type            string     `json:"type"`                     // electricity, gas, internet, inne
-	CustomType      *string    `json:"customType,omitempty"`     // required when type is "inne"
-	AllocationType  *string    `json:"allocationType,omitempty"` // "simple" or "metered", required when type is "inne"
-	PeriodStart     time.Time  `json:"periodStart"`
-	PeriodEnd       time.Time  `json:"periodEnd"`
-	PaymentDeadline *time.Time `json:"paymentDeadline,omitempty"` // optional payment deadline
-	TotalAmountPLN  float64    `json:"totalAmountPLN"`
-	TotalUnits      *float64   `json:"totalUnits,omitempty"`
-	Notes           *string    `json:"notes,omitempty"`
+	Type            string             `json:"type"`                     // electricity, gas, internet, inne
+	CustomType      *string            `json:"customType,omitempty"`     // required when type is "inne"
+	AllocationType  *string            `json:"allocationType,omitempty"` // "simple" or "metered", required when type is "inne" without splits
+	PeriodStart     time.Time          `json:"periodStart"`
+	PeriodEnd       time.Time          `json:"periodEnd"`
+	PaymentDeadline *time.Time         `json:"paymentDeadline,omitempty"` // optional payment deadline
+	TotalAmountPLN  utils.Money        `json:"totalAmountPLN"`            // amount in Currency, defaults to the receipt total for itemized bills
+	Currency        string             `json:"currency,omitempty"`        // defaults to the household base currency
+	TotalUnits      *float64           `json:"totalUnits,omitempty"`
+	Notes           *string            `json:"notes,omitempty"`
+	Splits          []models.BillSplit `json:"splits,omitempty"` // custom split rules, switch the bill to "custom" allocation
+	Items           []models.BillItem  `json:"items,omitempty"`  // receipt lines, switch the bill to "itemized" allocation
+	// TariffZones price the units of each tariff zone of a metered bill separately
+	TariffZones []models.BillTariffZone `json:"tariffZones,omitempty"`
+	// RecipientAccountID is the bank account shares are paid to, used for payment requests
+	RecipientAccountID *string `json:"recipientAccountId,omitempty"`
 }
 
 // CreateBill creates a new bill in the database
@@ -73,14 +120,40 @@ func (s *BillService) CreateBill(ctx context.Context, req CreateBillRequest, cre
 		return nil, errors.New("customType should only be provided when type is 'inne'")
 	}
 
+	hasSplits := len(req.Splits) > 0
+	hasItems := len(req.Items) > 0
+
+	// Split rules and receipt lines take precedence over the default allocation of any bill type
+	if hasSplits && hasItems {
+		return nil, errors.New("a bill can have either split rules or receipt items, not both")
+	}
+	if hasSplits && req.AllocationType != nil && *req.AllocationType != "custom" {
+		return nil, errors.New("allocationType must be 'custom' when split rules are provided")
+	}
+	if !hasSplits && req.AllocationType != nil && *req.AllocationType == "custom" {
+		return nil, errors.New("split rules are required for custom allocation")
+	}
+	if hasItems && req.AllocationType != nil && *req.AllocationType != "itemized" {
+		return nil, errors.New("allocationType must be 'itemized' when receipt items are provided")
+	}
+	if !hasItems && req.AllocationType != nil && *req.AllocationType == "itemized" {
+		return nil, errors.New("receipt items are required for itemized allocation")
+	}
+
 	// Validate allocationType for "inne" type
-	if req.Type == "inne" && (req.AllocationType == nil || (*req.AllocationType != "simple" && *req.AllocationType != "metered")) {
+	if req.Type == "inne" && !hasSplits && !hasItems && (req.AllocationType == nil || (*req.AllocationType != "simple" && *req.AllocationType != "metered")) {
 		return nil, errors.New("allocationType must be 'simple' or 'metered' when type is 'inne'")
 	}
 
 	// Set default allocation types for standard bill types
 	allocationType := req.AllocationType
-	if req.Type == "gas" || req.Type == "internet" {
+	if hasSplits {
+		customType := "custom"
+		allocationType = &customType
+	} else if hasItems {
+		itemizedType := "itemized"
+		allocationType = &itemizedType
+	} else if req.Type == "gas" || req.Type == "internet" {
 		simpleType := "simple"
 		allocationType = &simpleType
 	} else if req.Type == "electricity" {
@@ -92,20 +165,83 @@ func (s *BillService) CreateBill(ctx context.Context, req CreateBillRequest, cre
 		return nil, errors.New("period end must be after period start")
 	}
 
-	amountStr := utils.FloatToDecimalString(req.TotalAmountPLN)
+	if len(req.TariffZones) > 0 {
+		if allocationType == nil || *allocationType != "metered" {
+			return nil, errors.New("tariff zones require metered allocation")
+		}
+		zonesUnits, err := validateTariffZones(req.TariffZones, req.TotalAmountPLN)
+		if err != nil {
+			return nil, err
+		}
+		// The bill's units are those of its zones
+		if req.TotalUnits == nil {
+			req.TotalUnits = &zonesUnits
+		} else if utils.RoundToThreeDecimals(*req.TotalUnits) != utils.RoundToThreeDecimals(zonesUnits) {
+			return nil, errors.New("totalUnits must equal the sum of the tariff zone units")
+		}
+	}
+
+	// Receipt lines without an explicit total are taken at face value
+	for i := range req.Items {
+		if req.Items[i].Kind == "" {
+			req.Items[i].Kind = "item"
+		}
+	}
+	if hasItems && req.TotalAmountPLN.IsZero() {
+		itemsTotal, err := ItemsTotal(req.Items)
+		if err != nil {
+			return nil, err
+		}
+		req.TotalAmountPLN = itemsTotal
+	}
+
+	if req.TotalAmountPLN.IsNegative() {
+		return nil, errors.New("total amount cannot be negative")
+	}
+
+	currency, err := s.currencyService.ResolveCurrency(ctx, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	// Foreign currency bills need a rate so they can be reported in the base currency
+	if err := s.currencyService.EnsureConvertible(ctx, currency, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if hasSplits {
+		if err := s.allocationService.ValidateBillSplits(ctx, req.TotalAmountPLN, req.PeriodStart, req.PeriodEnd, req.Splits); err != nil {
+			return nil, err
+		}
+	}
+	if hasItems {
+		if err := s.allocationService.ValidateBillItems(ctx, req.TotalAmountPLN, req.PeriodStart, req.PeriodEnd, req.Items); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.RecipientAccountID != nil {
+		account, err := s.bankAccounts.GetByID(ctx, *req.RecipientAccountID)
+		if err != nil || account == nil {
+			return nil, errors.New("recipient bank account not found")
+		}
+	}
+
+	amountStr := req.TotalAmountPLN.String()
 
 	bill := models.Bill{
-		ID:              uuid.New().String(),
-		Type:            req.Type,
-		CustomType:      req.CustomType,
-		AllocationType:  allocationType,
-		PeriodStart:     req.PeriodStart,
-		PeriodEnd:       req.PeriodEnd,
-		PaymentDeadline: req.PaymentDeadline,
-		TotalAmountPLN:  amountStr,
-		Notes:           req.Notes,
-		Status:          "draft",
-		CreatedAt:       time.Now(),
+		ID:                 uuid.New().String(),
+		Type:               req.Type,
+		CustomType:         req.CustomType,
+		AllocationType:     allocationType,
+		PeriodStart:        req.PeriodStart,
+		PeriodEnd:          req.PeriodEnd,
+		PaymentDeadline:    req.PaymentDeadline,
+		TotalAmountPLN:     amountStr,
+		Currency:           currency,
+		Notes:              req.Notes,
+		Status:             "draft",
+		RecipientAccountID: req.RecipientAccountID,
+		CreatedAt:          time.Now(),
 	}
 
 	if req.TotalUnits != nil {
@@ -113,12 +249,42 @@ func (s *BillService) CreateBill(ctx context.Context, req CreateBillRequest, cre
 		bill.TotalUnits = unitsStr
 	}
 
-	if err := s.bills.Create(ctx, &bill); err != nil {
-		return nil, fmt.Errorf("failed to create bill: %w", err)
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.bills.Create(ctx, &bill); err != nil {
+			return fmt.Errorf("failed to create bill: %w", err)
+		}
+		for i := range req.Splits {
+			split := req.Splits[i]
+			split.ID = ""
+			if err := s.billSplits.Create(ctx, bill.ID, &split); err != nil {
+				return fmt.Errorf("failed to create split rule: %w", err)
+			}
+			bill.Splits = append(bill.Splits, split)
+		}
+		for i := range req.Items {
+			item := req.Items[i]
+			item.ID = ""
+			item.Position = i
+			if err := s.billItems.Create(ctx, bill.ID, &item); err != nil {
+				return fmt.Errorf("failed to create receipt item: %w", err)
+			}
+			bill.Items = append(bill.Items, item)
+		}
+		for i := range req.TariffZones {
+			zone := req.TariffZones[i]
+			if err := s.billTariffZones.Create(ctx, bill.ID, &zone); err != nil {
+				return fmt.Errorf("failed to create tariff zone: %w", err)
+			}
+			bill.TariffZones = append(bill.TariffZones, zone)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	log.Printf("[BILL] Created: type=%s, amount=%s PLN, period=%s to %s (ID: %s, created by: %s)",
-		bill.Type, amountStr, req.PeriodStart.Format("2006-01-02"), req.PeriodEnd.Format("2006-01-02"), bill.ID, creatorID)
+	log.Printf("[BILL] Created: type=%s, amount=%s %s, period=%s to %s (ID: %s, created by: %s)",
+		bill.Type, amountStr, currency, req.PeriodStart.Format("2006-01-02"), req.PeriodEnd.Format("2006-01-02"), bill.ID, creatorID)
 
 	// Create a notification for all users except the creator
 	users, err := s.users.ListActive(ctx)
@@ -185,85 +351,332 @@ func (s *BillService) GetBill(ctx context.Context, billID string) (*models.Bill,
 	if err != nil {
 		return nil, errors.New("bill not found")
 	}
+	if bill == nil || bill.AllocationType == nil {
+		return bill, nil
+	}
+
+	switch *bill.AllocationType {
+	case "custom":
+		splits, err := s.billSplits.GetByBillID(ctx, billID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get split rules: %w", err)
+		}
+		bill.Splits = splits
+	case "itemized":
+		items, err := s.billItems.GetByBillID(ctx, billID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get receipt items: %w", err)
+		}
+		bill.Items = items
+	case "metered":
+		zones, err := s.billTariffZones.GetByBillID(ctx, billID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tariff zones: %w", err)
+		}
+		bill.TariffZones = zones
+	}
 	return bill, nil
 }
 
-// PostBill marks bill as posted (freezes allocations)
-func (s *BillService) PostBill(ctx context.Context, billID string) error {
-	err := s.updateBillStatus(ctx, billID, "draft", "posted")
-	if err == nil {
-		log.Printf("[BILL] Posted: ID=%s (status changed from draft to posted)", billID)
+// validateTariffZones checks the zones of a metered bill, normalizes their names and amounts,
+// and returns their total units. The zones cannot cost more than the bill.
+func validateTariffZones(zones []models.BillTariffZone, total utils.Money) (float64, error) {
+	seen := make(map[string]bool)
+	var zonesAmount utils.Money
+	zonesUnits := 0.0
+	for i := range zones {
+		zone := &zones[i]
+		zone.Zone = normalizeTariffZone(zone.Zone)
+		if seen[zone.Zone] {
+			return 0, fmt.Errorf("tariff zone %q is listed twice", zone.Zone)
+		}
+		seen[zone.Zone] = true
+
+		units, err := strconv.ParseFloat(strings.TrimSpace(zone.Units), 64)
+		if err != nil || units <= 0 {
+			return 0, fmt.Errorf("tariff zone %q must have positive units", zone.Zone)
+		}
+		amount, err := utils.ParseMoney(zone.Amount)
+		if err != nil || amount.IsNegative() {
+			return 0, fmt.Errorf("tariff zone %q has an invalid amount", zone.Zone)
+		}
+
+		zone.Units = utils.FloatToDecimalString(units)
+		zone.Amount = amount.String()
+		zonesUnits += units
+		zonesAmount = zonesAmount.Add(amount)
 	}
+	if total.Sub(zonesAmount).IsNegative() {
+		return 0, errors.New("tariff zones cannot cost more than the bill total")
+	}
+	return zonesUnits, nil
+}
+
+// OnTransition registers a hook that runs after the named bill transitions, or after every one when none are named
+func (s *BillService) OnTransition(hook BillTransitionHook, transitions ...string) {
+	s.stateMachine.OnTransition(hook, transitions...)
+}
+
+// GuardTransition registers a check that has to pass before any of the named bill transitions is made
+func (s *BillService) GuardTransition(guard BillTransitionGuard, transitions ...string) {
+	s.stateMachine.Guard(guard, transitions...)
+}
+
+// TransitionRule returns the declaration of a bill transition
+func (s *BillService) TransitionRule(name string) (BillTransitionRule, bool) {
+	return s.stateMachine.Rule(name)
+}
+
+// AvailableTransitions returns the transitions a bill can take from its current status
+func (s *BillService) AvailableTransitions(bill *models.Bill) []string {
+	return s.stateMachine.Available(bill.Status)
+}
+
+// TransitionBill moves a bill along its lifecycle. The transition has to be allowed from the bill's
+// current status and pass its guards. It is recorded in the bill's history, and the registered hooks
+// run once it is committed, after the caller's transaction when ctx carries one.
+func (s *BillService) TransitionBill(ctx context.Context, billID string, req BillTransitionRequest) (*models.Bill, error) {
+	rule, ok := s.stateMachine.Rule(req.Transition)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown transition %q", ErrBillTransitionNotAllowed, req.Transition)
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if rule.RequireReason && reason == "" {
+		return nil, ErrBillTransitionReason
+	}
+
+	var bill *models.Bill
+	var from string
+	forced := false
+	estimated := 0
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		bill, err = s.bills.GetByID(ctx, billID)
+		if err != nil || bill == nil {
+			return errors.New("bill not found")
+		}
+		from = bill.Status
+		if !rule.allowedFrom(from) {
+			return fmt.Errorf("%w: %s is not possible for a bill in %s status", ErrBillTransitionNotAllowed, rule.Name, from)
+		}
+
+		if err := s.stateMachine.checkGuards(ctx, bill, rule); err != nil {
+			return err
+		}
+
+		if rule.RequirePaid {
+			shares, err := s.creditService.GetOpenShares(ctx, billID)
+			if err != nil {
+				return err
+			}
+			if len(shares) > 0 {
+				if !req.Force {
+					return fmt.Errorf("%w: %d shares are still unpaid", ErrBillUnpaid, len(shares))
+				}
+				forced = true
+			}
+		}
+
+		bill.Status = rule.To
+		if rule.Name == BillTransitionReopen || rule.Name == BillTransitionReopenPosted {
+			now := time.Now()
+			bill.ReopenedAt = &now
+			bill.ReopenReason = &reason
+			bill.ReopenedBy = &req.Actor.UserID
+		}
+		if err := s.bills.Update(ctx, bill); err != nil {
+			return fmt.Errorf("failed to update bill status: %w", err)
+		}
+
+		if rule.Name == BillTransitionPost {
+			if estimated, err = s.freezeBill(ctx, bill); err != nil {
+				return err
+			}
+			if _, err := s.snapshotBill(ctx, billID, reason, req.Actor.UserID); err != nil {
+				return err
+			}
+		}
+
+		record := &models.BillTransition{
+			BillID:     billID,
+			Transition: rule.Name,
+			FromStatus: from,
+			ToStatus:   rule.To,
+			Forced:     forced,
+		}
+		if req.Actor.UserID != "" {
+			record.ActorID = &req.Actor.UserID
+		}
+		if reason != "" {
+			record.Reason = &reason
+		}
+		if err := s.billTransitions.Create(ctx, record); err != nil {
+			return fmt.Errorf("failed to record bill transition: %w", err)
+		}
+
+		// When the transition is part of a larger unit of work, the hooks wait until all of it is committed
+		event := BillTransitionEvent{Bill: bill, Rule: rule, From: from, Reason: reason, Forced: forced, Actor: req.Actor}
+		s.txManager.AfterCommit(ctx, func(ctx context.Context) {
+			log.Printf("[BILL] %s: ID=%s (status changed from %s to %s)", rule.Name, billID, from, rule.To)
+			if forced {
+				log.Printf("[BILL] Bill %s closed with unpaid shares by user %s", billID, req.Actor.UserID)
+			}
+			if estimated > 0 {
+				log.Printf("[BILL] Estimated %d missing readings for bill %s", estimated, billID)
+			}
+			s.stateMachine.runHooks(ctx, event)
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bill, nil
+}
+
+// GetBillHistory returns the status changes of a bill, oldest first
+func (s *BillService) GetBillHistory(ctx context.Context, billID string) ([]models.BillTransition, error) {
+	bill, err := s.bills.GetByID(ctx, billID)
+	if err != nil || bill == nil {
+		return nil, errors.New("bill not found")
+	}
+	return s.billTransitions.ListByBillID(ctx, billID)
+}
+
+// PostBill marks bill as posted (freezes allocations) and covers them with available credit
+func (s *BillService) PostBill(ctx context.Context, billID string) error {
+	_, err := s.TransitionBill(ctx, billID, BillTransitionRequest{Transition: BillTransitionPost})
 	return err
 }
 
-// CloseBill marks bill as closed (no more changes)
+// CloseBill marks a fully paid bill as closed (no more changes)
 func (s *BillService) CloseBill(ctx context.Context, billID string) error {
-	err := s.updateBillStatus(ctx, billID, "posted", "closed")
-	if err == nil {
-		log.Printf("[BILL] Closed: ID=%s (status changed from posted to closed)", billID)
-	}
+	_, err := s.TransitionBill(ctx, billID, BillTransitionRequest{Transition: BillTransitionClose})
 	return err
 }
 
 // ReopenBill reverts a bill back to draft or posted status
 func (s *BillService) ReopenBill(ctx context.Context, billID string, userID string, targetStatus, reason string) error {
-	// Validate target status
-	if targetStatus != "draft" && targetStatus != "posted" {
+	req := BillTransitionRequest{Reason: reason, Actor: BillActor{UserID: userID}}
+	switch targetStatus {
+	case BillStatusDraft:
+		req.Transition = BillTransitionReopen
+	case BillStatusPosted:
+		req.Transition = BillTransitionReopenPosted
+	default:
 		return errors.New("target status must be 'draft' or 'posted'")
 	}
+	_, err := s.TransitionBill(ctx, billID, req)
+	return err
+}
 
-	if reason == "" {
-		return errors.New("reopen reason is required")
+// freezeBill stores what a bill's allocations are based on when it is posted: the residents of its
+// period, the result of its split rules or receipt lines and estimates of missing readings.
+// Available credit is then applied to it. Returns the number of estimated readings.
+func (s *BillService) freezeBill(ctx context.Context, bill *models.Bill) (int, error) {
+	if err := s.allocationService.StoreResidents(ctx, bill); err != nil {
+		return 0, err
 	}
-
-	// Get current bill
-	bill, err := s.GetBill(ctx, billID)
-	if err != nil {
-		return err
+	if err := s.storeRuleAllocations(ctx, bill.ID); err != nil {
+		return 0, err
 	}
-
-	// Validate state transition
-	if bill.Status == "draft" {
-		return errors.New("bill is already in draft status")
+	estimated, err := s.allocationService.StoreEstimatedReadings(ctx, bill)
+	if err != nil {
+		return 0, err
 	}
+	return estimated, s.creditService.ApplyCredit(ctx, bill)
+}
 
-	if bill.Status == "posted" && targetStatus == "posted" {
-		return errors.New("bill is already in posted status")
+// notifyTransition tells the household that a bill awaits payment or had its dispute resolved.
+// Disputes are announced to their reviewers by the dispute service.
+func (s *BillService) notifyTransition(ctx context.Context, event BillTransitionEvent) error {
+	label := billLabel(event.Bill)
+	var recipients []string
+	var title, body string
+
+	switch event.Rule.Name {
+	case BillTransitionRequestPayment:
+		// Only those who still have something to pay
+		shares, err := s.creditService.GetOpenShares(ctx, event.Bill.ID)
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		for _, share := range shares {
+			for _, payerID := range share.PayerIDs {
+				if !seen[payerID] {
+					seen[payerID] = true
+					recipients = append(recipients, payerID)
+				}
+			}
+		}
+		title = "Rachunek do zapłaty"
+		body = fmt.Sprintf("Rachunek %s czeka na zapłatę", label)
+		if event.Bill.PaymentDeadline != nil {
+			body += fmt.Sprintf(" do %s", event.Bill.PaymentDeadline.Format("2006-01-02"))
+		}
+	case BillTransitionResolveDispute:
+		users, err := s.users.ListActive(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get active users: %w", err)
+		}
+		for _, user := range users {
+			recipients = append(recipients, user.ID)
+		}
+		title = "Spór rozstrzygnięty"
+		body = fmt.Sprintf("Spór dotyczący rachunku %s został rozstrzygnięty", label)
+	default:
+		return nil
 	}
 
-	now := time.Now()
-
-	// Update bill status and reopen metadata
-	bill.Status = targetStatus
-	bill.ReopenedAt = &now
-	bill.ReopenReason = &reason
-	bill.ReopenedBy = &userID
-
-	if err := s.bills.Update(ctx, bill); err != nil {
-		return fmt.Errorf("failed to reopen bill: %w", err)
+	for _, userID := range recipients {
+		if userID == event.Actor.UserID {
+			continue
+		}
+		now := time.Now()
+		notification := &models.Notification{
+			UserID:       &userID,
+			Channel:      "app",
+			TemplateID:   "bill",
+			ScheduledFor: now,
+			SentAt:       &now,
+			Status:       "sent",
+			Title:        title,
+			Body:         body,
+		}
+		if err := s.notificationService.CreateNotification(ctx, notification); err != nil {
+			log.Printf("[BILL] Failed to notify user %s about bill %s: %v", userID, event.Bill.ID, err)
+		}
 	}
-
-	log.Printf("[BILL] Reopened: ID=%s (from %s to %s, by user %s, reason: %q)", billID, bill.Status, targetStatus, userID, reason)
-
 	return nil
 }
 
-func (s *BillService) updateBillStatus(ctx context.Context, billID string, fromStatus, toStatus string) error {
+// storeRuleAllocations writes the result of a bill's split rules or receipt lines into the
+// allocations table, replacing whatever was stored by an earlier post
+func (s *BillService) storeRuleAllocations(ctx context.Context, billID string) error {
 	bill, err := s.bills.GetByID(ctx, billID)
-	if err != nil {
-		return fmt.Errorf("bill not found or not in %s status", fromStatus)
+	if err != nil || bill == nil {
+		return errors.New("bill not found")
+	}
+	if bill.AllocationType == nil || (*bill.AllocationType != "custom" && *bill.AllocationType != "itemized") {
+		return nil
 	}
 
-	if bill.Status != fromStatus {
-		return fmt.Errorf("bill not found or not in %s status", fromStatus)
+	breakdown, err := s.allocationService.CalculateAllocation(ctx, bill)
+	if err != nil {
+		return err
 	}
 
-	bill.Status = toStatus
-	if err := s.bills.Update(ctx, bill); err != nil {
-		return fmt.Errorf("failed to update bill status: %w", err)
+	if err := s.allocations.DeleteByBillID(ctx, billID); err != nil {
+		return fmt.Errorf("failed to delete allocations: %w", err)
+	}
+	for _, entry := range breakdown {
+		if err := s.allocations.Create(ctx, billID, entry.SubjectType, entry.SubjectID, entry.Amount.String()); err != nil {
+			return fmt.Errorf("failed to create allocation: %w", err)
+		}
 	}
 
+	log.Printf("[BILL] Stored %d %s allocations for bill %s", len(breakdown), *bill.AllocationType, billID)
 	return nil
 }
 
@@ -285,6 +698,26 @@ func (s *BillService) DeleteBill(ctx context.Context, billID string) error {
 		return fmt.Errorf("failed to delete allocations: %w", err)
 	}
 
+	// Delete split rules
+	if err := s.billSplits.DeleteByBillID(ctx, billID); err != nil {
+		return fmt.Errorf("failed to delete split rules: %w", err)
+	}
+
+	// Delete receipt lines
+	if err := s.billItems.DeleteByBillID(ctx, billID); err != nil {
+		return fmt.Errorf("failed to delete receipt items: %w", err)
+	}
+
+	// Delete tariff zones
+	if err := s.billTariffZones.DeleteByBillID(ctx, billID); err != nil {
+		return fmt.Errorf("failed to delete tariff zones: %w", err)
+	}
+
+	// Give credit applied to this bill back to its users
+	if err := s.creditService.ReleaseCredit(ctx, billID); err != nil {
+		return err
+	}
+
 	// Note: payments are not deleted as they represent actual money transactions
 	// They could be kept for audit purposes or handled separately
 
@@ -300,13 +733,15 @@ func (s *BillService) DeleteBill(ctx context.Context, billID string) error {
 
 // PaymentStatusEntry represents payment status for a user/group
 type PaymentStatusEntry struct {
-	SubjectID    string `json:"subjectId"`
-	SubjectType  string `json:"subjectType"` // "user" or "group"
-	SubjectName  string `json:"subjectName"`
-	AllocatedPLN string `json:"allocatedPLN"`
-	PaidPLN      string `json:"paidPLN"`
-	RemainingPLN string `json:"remainingPLN"`
-	IsPaid       bool   `json:"isPaid"`
+	SubjectID        string `json:"subjectId"`
+	SubjectType      string `json:"subjectType"` // "user" or "group"
+	SubjectName      string `json:"subjectName"`
+	AllocatedPLN     string `json:"allocatedPLN"`
+	PaidPLN          string `json:"paidPLN"` // includes credit applied, excludes overpayments moved to credit
+	CreditAppliedPLN string `json:"creditAppliedPLN"`
+	OverpaidPLN      string `json:"overpaidPLN"` // moved to the subject's credit for later bills
+	RemainingPLN     string `json:"remainingPLN"`
+	IsPaid           bool   `json:"isPaid"`
 }
 
 // GetBillPaymentStatus returns detailed payment status showing who paid and who hasn't
@@ -317,50 +752,30 @@ func (s *BillService) GetBillPaymentStatus(ctx context.Context, billID string) (
 		return nil, err
 	}
 
-	// Get all payments for this bill
-	payments, err := s.payments.ListByBillID(ctx, billID)
+	// Payments and credit per user
+	coverage, err := s.creditService.GetBillCoverage(ctx, billID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Build payment map by payer
-	paymentMap := make(map[string]float64)
-	for _, payment := range payments {
-		paidFloat := utils.DecimalStringToFloat(payment.AmountPLN)
-		paymentMap[payment.PayerUserID] += paidFloat
-	}
-
 	// Build status entries
 	var statusEntries []PaymentStatusEntry
 	for _, alloc := range allocations {
 		var subjectName string
+		var memberIDs []string
 
-		// Get subject name
+		// Get subject name and the users paying for it
 		if alloc.SubjectType == "user" {
 			user, err := s.users.GetByID(ctx, alloc.SubjectID)
-			if err == nil {
+			if err == nil && user != nil {
 				subjectName = user.Name
 			} else {
 				subjectName = "Unknown User"
 			}
-
-			// Get paid amount for this user
-			paidFloat := paymentMap[alloc.SubjectID]
-			allocFloat := utils.DecimalStringToFloat(alloc.AllocatedPLN)
-			remainingFloat := allocFloat - paidFloat
-
-			statusEntries = append(statusEntries, PaymentStatusEntry{
-				SubjectID:    alloc.SubjectID,
-				SubjectType:  alloc.SubjectType,
-				SubjectName:  subjectName,
-				AllocatedPLN: alloc.AllocatedPLN,
-				PaidPLN:      utils.FloatToDecimalString(paidFloat),
-				RemainingPLN: utils.FloatToDecimalString(remainingFloat),
-				IsPaid:       paidFloat >= allocFloat,
-			})
+			memberIDs = []string{alloc.SubjectID}
 		} else if alloc.SubjectType == "group" {
 			group, err := s.groups.GetByID(ctx, alloc.SubjectID)
-			if err == nil {
+			if err == nil && group != nil {
 				subjectName = group.Name
 			} else {
 				subjectName = "Unknown Group"
@@ -371,26 +786,35 @@ func (s *BillService) GetBillPaymentStatus(ctx context.Context, billID string) (
 			if err != nil {
 				continue
 			}
-
-			// Calculate total paid by all group members
-			totalPaidFloat := 0.0
 			for _, user := range groupUsers {
-				totalPaidFloat += paymentMap[user.ID]
+				memberIDs = append(memberIDs, user.ID)
 			}
+		} else {
+			continue
+		}
 
-			allocFloat := utils.DecimalStringToFloat(alloc.AllocatedPLN)
-			remainingFloat := allocFloat - totalPaidFloat
-
-			statusEntries = append(statusEntries, PaymentStatusEntry{
-				SubjectID:    alloc.SubjectID,
-				SubjectType:  alloc.SubjectType,
-				SubjectName:  subjectName,
-				AllocatedPLN: alloc.AllocatedPLN,
-				PaidPLN:      utils.FloatToDecimalString(totalPaidFloat),
-				RemainingPLN: utils.FloatToDecimalString(remainingFloat),
-				IsPaid:       totalPaidFloat >= allocFloat,
-			})
+		// Sum what the subject's users covered
+		var paid, creditApplied, overpaid utils.Money
+		for _, userID := range memberIDs {
+			paid = paid.Add(coverage.Paid[userID])
+			creditApplied = creditApplied.Add(coverage.CreditApplied[userID])
+			overpaid = overpaid.Add(coverage.Overpaid[userID])
 		}
+
+		allocated := utils.MoneyFromString(alloc.AllocatedPLN)
+		remaining := allocated.Sub(paid)
+
+		statusEntries = append(statusEntries, PaymentStatusEntry{
+			SubjectID:        alloc.SubjectID,
+			SubjectType:      alloc.SubjectType,
+			SubjectName:      subjectName,
+			AllocatedPLN:     alloc.AllocatedPLN,
+			PaidPLN:          paid.String(),
+			CreditAppliedPLN: creditApplied.String(),
+			OverpaidPLN:      overpaid.String(),
+			RemainingPLN:     remaining.String(),
+			IsPaid:           paid >= allocated,
+		})
 	}
 
 	return statusEntries, nil
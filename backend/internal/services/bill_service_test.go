@@ -1,9 +1,15 @@
 package services
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestBillTypeValidation tests bill type validation logic
@@ -204,3 +210,164 @@ func TestBillStatusValidation(t *testing.T) {
 func stringPtr(s string) *string {
 	return &s
 }
+
+// newTestBillService wires a bill service against the test database
+func newTestBillService(repos *repository.Repositories) (*BillService, *AllocationService) {
+	currencyService := NewCurrencyService(repos.ExchangeRates, NewAppSettingsService(repos.AppSettings))
+	allocationService := NewAllocationService(repos.Users, repos.Residencies, repos.BillResidents, repos.Groups, repos.Consumptions, repos.Allocations,
+		repos.Bills, repos.BillSplits, repos.BillItems, repos.BillTariffZones, repos.Meters)
+	creditService := NewCreditService(repos.CreditEntries, repos.Payments, repos.Users, allocationService)
+	billService := NewBillService(repos.Bills, repos.Consumptions, repos.Allocations, repos.Payments, repos.Users,
+		repos.Groups, repos.BillSplits, repos.BillItems, repos.BillTariffZones, repos.BillTransitions, repos.BillVersions, repos.BankAccounts, repos.TxManager, newTestNotificationService(repos), currencyService,
+		allocationService, creditService, nil)
+	return billService, allocationService
+}
+
+func TestPostBillStoresCustomSplit(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	billService, allocationService := newTestBillService(repos)
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+	carol := createTestUser(t, repos, "Carol")
+
+	// Pizza order: Alice had the expensive one, Carol was not there
+	fixedAmount := "45.00"
+	bill, err := billService.CreateBill(ctx, CreateBillRequest{
+		Type:           "inne",
+		CustomType:     stringPtr("Pizza"),
+		PeriodStart:    time.Now(),
+		PeriodEnd:      time.Now(),
+		TotalAmountPLN: utils.NewMoney(100, 0),
+		Splits: []models.BillSplit{
+			{SubjectType: "user", SubjectID: alice.ID, AllocationType: "fixed", FixedAmount: &fixedAmount},
+			{SubjectType: "user", SubjectID: carol.ID, AllocationType: "exclude"},
+		},
+	}, alice.ID)
+	require.NoError(t, err)
+	require.NotNil(t, bill.AllocationType)
+	assert.Equal(t, "custom", *bill.AllocationType)
+	assert.Len(t, bill.Splits, 2)
+
+	stored, err := billService.GetBill(ctx, bill.ID)
+	require.NoError(t, err)
+	assert.Len(t, stored.Splits, 2)
+
+	allocations, err := repos.Allocations.GetByBillID(ctx, bill.ID)
+	require.NoError(t, err)
+	assert.Empty(t, allocations, "draft bills are split on the fly")
+
+	require.NoError(t, billService.PostBill(ctx, bill.ID))
+
+	allocations, err = repos.Allocations.GetByBillID(ctx, bill.ID)
+	require.NoError(t, err)
+	got := make(map[string]string)
+	for _, alloc := range allocations {
+		got[alloc.SubjectID] = alloc.AllocatedPLN
+	}
+	assert.Equal(t, map[string]string{alice.ID: "45.00", bob.ID: "55.00"}, got)
+
+	breakdown, err := allocationService.GetAllocationBreakdown(ctx, bill.ID)
+	require.NoError(t, err)
+	assert.Len(t, breakdown, 2)
+}
+
+func TestCreateBillRejectsInvalidSplits(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	billService, _ := newTestBillService(repos)
+	alice := createTestUser(t, repos, "Alice")
+
+	tests := []struct {
+		name    string
+		req     CreateBillRequest
+		wantErr string
+	}{
+		{
+			name:    "Custom allocation without rules",
+			req:     CreateBillRequest{Type: "gas", AllocationType: stringPtr("custom")},
+			wantErr: "split rules are required",
+		},
+		{
+			name: "Rules with another allocation type",
+			req: CreateBillRequest{Type: "inne", CustomType: stringPtr("Pizza"), AllocationType: stringPtr("simple"),
+				Splits: []models.BillSplit{{SubjectType: "user", SubjectID: alice.ID, AllocationType: "exclude"}}},
+			wantErr: "allocationType must be 'custom'",
+		},
+		{
+			name: "Everybody excluded",
+			req: CreateBillRequest{Type: "inne", CustomType: stringPtr("Pizza"), TotalAmountPLN: utils.NewMoney(10, 0),
+				Splits: []models.BillSplit{{SubjectType: "user", SubjectID: alice.ID, AllocationType: "exclude"}}},
+			wantErr: "unassigned",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := billService.CreateBill(ctx, tt.req, alice.ID)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	bills, err := repos.Bills.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, bills)
+}
+
+func TestItemizedBillPaymentStatus(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	billService, _ := newTestBillService(repos)
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+
+	participants := func(users ...*models.User) []models.BillItemParticipant {
+		result := make([]models.BillItemParticipant, len(users))
+		for i, user := range users {
+			result[i] = models.BillItemParticipant{SubjectType: "user", SubjectID: user.ID}
+		}
+		return result
+	}
+
+	// No total given: it is taken from the receipt
+	bill, err := billService.CreateBill(ctx, CreateBillRequest{
+		Type:        "inne",
+		CustomType:  stringPtr("Zakupy"),
+		PeriodStart: time.Now(),
+		PeriodEnd:   time.Now(),
+		Items: []models.BillItem{
+			{Description: "Detergent", Amount: "20.00", Participants: participants(alice, bob)},
+			{Description: "Beer", Amount: "30.00", Participants: participants(bob)},
+			{Kind: "discount", Description: "Coupon", Amount: "5.00"},
+		},
+	}, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "45.00", bill.TotalAmountPLN)
+	assert.Equal(t, "itemized", *bill.AllocationType)
+
+	stored, err := billService.GetBill(ctx, bill.ID)
+	require.NoError(t, err)
+	require.Len(t, stored.Items, 3)
+	assert.Len(t, stored.Items[0].Participants, 2)
+
+	require.NoError(t, billService.PostBill(ctx, bill.ID))
+	require.NoError(t, repos.Payments.Create(ctx, &models.Payment{
+		BillID: bill.ID, PayerUserID: alice.ID, AmountPLN: "9.00", PaidAt: time.Now(),
+	}))
+
+	status, err := billService.GetBillPaymentStatus(ctx, bill.ID)
+	require.NoError(t, err)
+	bySubject := make(map[string]PaymentStatusEntry)
+	for _, entry := range status {
+		bySubject[entry.SubjectID] = entry
+	}
+
+	// Subtotals 10 and 40, the 5.00 coupon is split 1.00 / 4.00
+	assert.Equal(t, "9.00", bySubject[alice.ID].AllocatedPLN)
+	assert.True(t, bySubject[alice.ID].IsPaid)
+	assert.Equal(t, "36.00", bySubject[bob.ID].AllocatedPLN)
+	assert.Equal(t, "36.00", bySubject[bob.ID].RemainingPLN)
+}
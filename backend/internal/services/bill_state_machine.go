@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/sainaif/holy-home/internal/config"
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+)
+
+// Bill statuses
+const (
+	BillStatusDraft           = "draft"            // allocations can still change
+	BillStatusPosted          = "posted"           // allocations are frozen
+	BillStatusAwaitingPayment = "awaiting_payment" // shares were requested from the household
+	BillStatusDisputed        = "disputed"         // someone questioned the bill, it cannot be closed
+	BillStatusClosed          = "closed"           // no more changes
+)
+
+// Bill transitions
+const (
+	BillTransitionPost           = "post"
+	BillTransitionRequestPayment = "request_payment"
+	BillTransitionDispute        = "dispute"
+	BillTransitionResolveDispute = "resolve_dispute"
+	BillTransitionClose          = "close"
+	BillTransitionReopen         = "reopen"        // back to draft
+	BillTransitionReopenPosted   = "reopen_posted" // a closed bill back to posted
+	BillTransitionAmend          = "amend"         // an open bill corrected in place, recorded in its history only
+)
+
+// OpenBillStatuses are the statuses of bills that were posted and are not closed yet
+var OpenBillStatuses = []string{BillStatusPosted, BillStatusAwaitingPayment, BillStatusDisputed}
+
+// IssuedBillStatuses are the statuses of bills that were posted, closed ones included
+var IssuedBillStatuses = []string{BillStatusPosted, BillStatusAwaitingPayment, BillStatusDisputed, BillStatusClosed}
+
+var (
+	ErrBillTransitionNotAllowed = errors.New("transition not allowed")
+	ErrBillTransitionReason     = errors.New("a reason is required for this transition")
+	ErrBillUnpaid               = errors.New("bill is not fully paid")
+)
+
+// BillTransitionRule declares one allowed change of a bill's status
+type BillTransitionRule struct {
+	Name          string
+	From          []string
+	To            string
+	RequireReason bool   // the transition has to be explained, e.g. a dispute or a reopen
+	RequirePaid   bool   // every share has to be covered, unless the transition is forced
+	AuditAction   string // action written to the audit log
+}
+
+// allowedFrom reports whether the transition can be taken from a status
+func (r BillTransitionRule) allowedFrom(status string) bool {
+	for _, from := range r.From {
+		if from == status {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultBillTransitions is the bill lifecycle:
+//
+//	draft -> posted -> awaiting_payment -> closed
+//
+// Bills can only be closed once every share is paid, unless the close is forced. Open bills can be disputed, which holds them until
+// the dispute is resolved, and any bill that left draft can be reopened with a reason.
+func DefaultBillTransitions() []BillTransitionRule {
+	return []BillTransitionRule{
+		{
+			Name:        BillTransitionPost,
+			From:        []string{BillStatusDraft},
+			To:          BillStatusPosted,
+			AuditAction: "post_bill",
+		},
+		{
+			Name:        BillTransitionRequestPayment,
+			From:        []string{BillStatusPosted},
+			To:          BillStatusAwaitingPayment,
+			AuditAction: "request_bill_payment",
+		},
+		{
+			Name:          BillTransitionDispute,
+			From:          []string{BillStatusPosted, BillStatusAwaitingPayment},
+			To:            BillStatusDisputed,
+			RequireReason: true,
+			AuditAction:   "dispute_bill",
+		},
+		{
+			Name:        BillTransitionResolveDispute,
+			From:        []string{BillStatusDisputed},
+			To:          BillStatusAwaitingPayment,
+			AuditAction: "resolve_bill_dispute",
+		},
+		{
+			Name:        BillTransitionClose,
+			From:        []string{BillStatusPosted, BillStatusAwaitingPayment},
+			To:          BillStatusClosed,
+			RequirePaid: true,
+			AuditAction: "close_bill",
+		},
+		{
+			Name:          BillTransitionReopen,
+			From:          []string{BillStatusPosted, BillStatusAwaitingPayment, BillStatusDisputed, BillStatusClosed},
+			To:            BillStatusDraft,
+			RequireReason: true,
+			AuditAction:   "reopen_bill",
+		},
+		{
+			Name:          BillTransitionReopenPosted,
+			From:          []string{BillStatusClosed},
+			To:            BillStatusPosted,
+			RequireReason: true,
+			AuditAction:   "reopen_bill",
+		},
+	}
+}
+
+// UnpaidBillCloseTransition is a close that does not check the shares are paid.
+// It replaces the default close when added to the rule set.
+func UnpaidBillCloseTransition() BillTransitionRule {
+	return BillTransitionRule{
+		Name:        BillTransitionClose,
+		From:        []string{BillStatusPosted, BillStatusAwaitingPayment},
+		To:          BillStatusClosed,
+		AuditAction: "close_bill",
+	}
+}
+
+// BillTransitionsFromConfig returns the bill lifecycle configured for the household
+func BillTransitionsFromConfig(cfg *config.Config) []BillTransitionRule {
+	rules := DefaultBillTransitions()
+	if cfg.Bills.AllowUnpaidClose {
+		rules = append(rules, UnpaidBillCloseTransition())
+	}
+	return rules
+}
+
+// BillActor is who asked for a transition. Transitions made by the system have no user.
+type BillActor struct {
+	UserID    string
+	Email     string
+	IPAddress string
+	UserAgent string
+}
+
+// BillTransitionRequest asks for a bill to take a transition
+type BillTransitionRequest struct {
+	Transition string
+	Reason     string
+	Force      bool // close the bill even though shares are unpaid
+	Actor      BillActor
+}
+
+// BillTransitionEvent describes a committed transition to the hooks
+type BillTransitionEvent struct {
+	Bill   *models.Bill // the bill after the transition
+	Rule   BillTransitionRule
+	From   string
+	Reason string
+	Forced bool // a guard was overridden
+	Actor  BillActor
+}
+
+// BillTransitionHook runs after a transition is committed. Its error is logged and does not undo the transition.
+type BillTransitionHook func(ctx context.Context, event BillTransitionEvent) error
+
+// BillTransitionGuard can veto a transition before it is made. It runs within the transition's database transaction.
+type BillTransitionGuard func(ctx context.Context, bill *models.Bill, rule BillTransitionRule) error
+
+type billTransitionGuard struct {
+	transitions map[string]bool // empty for guards of every transition
+	guard       BillTransitionGuard
+}
+
+type billTransitionHook struct {
+	transitions map[string]bool // empty for hooks of every transition
+	hook        BillTransitionHook
+}
+
+// BillStateMachine holds the allowed bill transitions and the hooks that follow them
+type BillStateMachine struct {
+	rules  map[string]BillTransitionRule
+	order  []string
+	guards []billTransitionGuard
+	hooks  []billTransitionHook
+}
+
+// NewBillStateMachine declares the rules in order; a rule named like an earlier one replaces it
+func NewBillStateMachine(rules []BillTransitionRule) *BillStateMachine {
+	m := &BillStateMachine{rules: make(map[string]BillTransitionRule, len(rules))}
+	for _, rule := range rules {
+		if _, ok := m.rules[rule.Name]; !ok {
+			m.order = append(m.order, rule.Name)
+		}
+		m.rules[rule.Name] = rule
+	}
+	return m
+}
+
+// Rule returns the declaration of a transition
+func (m *BillStateMachine) Rule(name string) (BillTransitionRule, bool) {
+	rule, ok := m.rules[name]
+	return rule, ok
+}
+
+// Available returns the transitions that can be taken from a status, in the order they were declared
+func (m *BillStateMachine) Available(status string) []string {
+	available := []string{}
+	for _, name := range m.order {
+		if m.rules[name].allowedFrom(status) {
+			available = append(available, name)
+		}
+	}
+	return available
+}
+
+// OnTransition registers a hook for the named transitions, or for every transition when none are named
+func (m *BillStateMachine) OnTransition(hook BillTransitionHook, transitions ...string) {
+	entry := billTransitionHook{transitions: make(map[string]bool, len(transitions)), hook: hook}
+	for _, name := range transitions {
+		entry.transitions[name] = true
+	}
+	m.hooks = append(m.hooks, entry)
+}
+
+// Guard registers a check that has to pass before the named transitions, or every transition when none are named
+func (m *BillStateMachine) Guard(guard BillTransitionGuard, transitions ...string) {
+	entry := billTransitionGuard{transitions: make(map[string]bool, len(transitions)), guard: guard}
+	for _, name := range transitions {
+		entry.transitions[name] = true
+	}
+	m.guards = append(m.guards, entry)
+}
+
+// checkGuards runs the guards registered for a transition and returns the first veto
+func (m *BillStateMachine) checkGuards(ctx context.Context, bill *models.Bill, rule BillTransitionRule) error {
+	for _, entry := range m.guards {
+		if len(entry.transitions) > 0 && !entry.transitions[rule.Name] {
+			continue
+		}
+		if err := entry.guard(ctx, bill, rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runHooks runs the hooks registered for a committed transition in the order they were registered
+func (m *BillStateMachine) runHooks(ctx context.Context, event BillTransitionEvent) {
+	for _, entry := range m.hooks {
+		if len(entry.transitions) > 0 && !entry.transitions[event.Rule.Name] {
+			continue
+		}
+		if err := entry.hook(ctx, event); err != nil {
+			log.Printf("[BILL] Hook after %s failed for bill %s: %v", event.Rule.Name, event.Bill.ID, err)
+		}
+	}
+}
+
+// BillAuditHook writes committed bill transitions to the audit log
+func BillAuditHook(auditService *AuditService) BillTransitionHook {
+	return func(ctx context.Context, event BillTransitionEvent) error {
+		details := map[string]interface{}{
+			"bill_type":  event.Bill.Type,
+			"transition": event.Rule.Name,
+			"old_status": event.From,
+			"new_status": event.Bill.Status,
+		}
+		if event.Bill.CustomType != nil {
+			details["custom_type"] = *event.Bill.CustomType
+		}
+		if event.Reason != "" {
+			details["reason"] = event.Reason
+		}
+		if event.Forced {
+			details["forced"] = true
+		}
+
+		userID, email := event.Actor.UserID, event.Actor.Email
+		if userID == "" {
+			userID, email = "system", "system"
+		}
+		return auditService.LogAction(ctx, userID, email, email, event.Rule.AuditAction, "bill", &event.Bill.ID,
+			details, event.Actor.IPAddress, event.Actor.UserAgent, "success")
+	}
+}
+
+// isIssuedBill reports whether a bill was posted, i.e. its allocations are final and its shares can be paid
+func isIssuedBill(status string) bool {
+	for _, issued := range IssuedBillStatuses {
+		if status == issued {
+			return true
+		}
+	}
+	return false
+}
+
+// listBillsByStatus returns the bills in any of the statuses
+func listBillsByStatus(ctx context.Context, bills repository.BillRepository, statuses ...string) ([]models.Bill, error) {
+	var result []models.Bill
+	for _, status := range statuses {
+		byStatus, err := bills.ListByStatus(ctx, status)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, byStatus...)
+	}
+	return result, nil
+}
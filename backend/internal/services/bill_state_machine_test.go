@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/config"
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBillLifecycle(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	billService, paymentService, _ := newTestCreditServices(repos)
+	billService.OnTransition(BillAuditHook(NewAuditService(repos.AuditLogs)))
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+	actor := BillActor{UserID: alice.ID, Email: alice.Email}
+	transition := func(billID, name, reason string, force bool) error {
+		_, err := billService.TransitionBill(ctx, billID, BillTransitionRequest{Transition: name, Reason: reason, Force: force, Actor: actor})
+		return err
+	}
+
+	bill, err := billService.CreateBill(ctx, CreateBillRequest{
+		Type:           "internet",
+		PeriodStart:    time.Now().AddDate(0, -1, 0),
+		PeriodEnd:      time.Now(),
+		TotalAmountPLN: utils.NewMoney(100, 0),
+	}, alice.ID)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, transition(bill.ID, BillTransitionClose, "", false), ErrBillTransitionNotAllowed)
+	require.NoError(t, transition(bill.ID, BillTransitionPost, "", false))
+	assert.Equal(t, []string{BillTransitionRequestPayment, BillTransitionDispute, BillTransitionClose, BillTransitionReopen},
+		billService.AvailableTransitions(&models.Bill{Status: BillStatusPosted}))
+
+	// Only Bob still owes his share when payment is requested
+	_, err = paymentService.RecordPayment(ctx, RecordPaymentRequest{BillID: bill.ID, Amount: utils.NewMoney(50, 0)}, alice.ID)
+	require.NoError(t, err)
+	require.NoError(t, transition(bill.ID, BillTransitionRequestPayment, "", false))
+	paymentRequests := func(userID string) int {
+		notifications, err := repos.Notifications.ListByUserID(ctx, userID, 10)
+		require.NoError(t, err)
+		count := 0
+		for _, notification := range notifications {
+			if notification.Title == "Rachunek do zapłaty" {
+				count++
+			}
+		}
+		return count
+	}
+	assert.Equal(t, 1, paymentRequests(bob.ID))
+	assert.Equal(t, 0, paymentRequests(alice.ID))
+
+	// A dispute needs a reason and holds the bill from closing, even when forced
+	assert.ErrorIs(t, transition(bill.ID, BillTransitionDispute, " ", false), ErrBillTransitionReason)
+	require.NoError(t, transition(bill.ID, BillTransitionDispute, "Router was off for a week", false))
+	assert.ErrorIs(t, transition(bill.ID, BillTransitionClose, "", true), ErrBillTransitionNotAllowed)
+	require.NoError(t, transition(bill.ID, BillTransitionResolveDispute, "", false))
+
+	// Unpaid shares block closing unless forced
+	assert.ErrorIs(t, transition(bill.ID, BillTransitionClose, "", false), ErrBillUnpaid)
+	require.NoError(t, transition(bill.ID, BillTransitionClose, "Bob paid in cash", true))
+
+	require.NoError(t, billService.ReopenBill(ctx, bill.ID, alice.ID, BillStatusDraft, "Wrong amount"))
+	stored, err := billService.GetBill(ctx, bill.ID)
+	require.NoError(t, err)
+	assert.Equal(t, BillStatusDraft, stored.Status)
+	require.NotNil(t, stored.ReopenReason)
+	assert.Equal(t, "Wrong amount", *stored.ReopenReason)
+
+	history, err := billService.GetBillHistory(ctx, bill.ID)
+	require.NoError(t, err)
+	var steps []string
+	for _, record := range history {
+		steps = append(steps, record.FromStatus+" -> "+record.ToStatus)
+	}
+	assert.Equal(t, []string{
+		"draft -> posted",
+		"posted -> awaiting_payment",
+		"awaiting_payment -> disputed",
+		"disputed -> awaiting_payment",
+		"awaiting_payment -> closed",
+		"closed -> draft",
+	}, steps)
+	assert.True(t, history[4].Forced)
+	assert.Equal(t, "Bob paid in cash", *history[4].Reason)
+	assert.False(t, history[0].Forced)
+
+	audits, err := repos.AuditLogs.ListByAction(ctx, "close_bill", 10)
+	require.NoError(t, err)
+	require.Len(t, audits, 1)
+	assert.Equal(t, true, audits[0].Details["forced"])
+}
+
+func TestUnpaidCloseCanBeAllowed(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	billService, _, _ := newTestCreditServicesWithTransitions(repos, BillTransitionsFromConfig(&config.Config{
+		Bills: config.BillsConfig{AllowUnpaidClose: true},
+	}))
+
+	alice := createTestUser(t, repos, "Alice")
+	createTestUser(t, repos, "Bob")
+	bill, err := billService.CreateBill(ctx, CreateBillRequest{
+		Type:           "internet",
+		PeriodStart:    time.Now().AddDate(0, -1, 0),
+		PeriodEnd:      time.Now(),
+		TotalAmountPLN: utils.NewMoney(100, 0),
+	}, alice.ID)
+	require.NoError(t, err)
+	require.NoError(t, billService.PostBill(ctx, bill.ID))
+
+	closed, err := billService.TransitionBill(ctx, bill.ID, BillTransitionRequest{Transition: BillTransitionClose})
+	require.NoError(t, err)
+	assert.Equal(t, BillStatusClosed, closed.Status)
+	history, err := billService.GetBillHistory(ctx, bill.ID)
+	require.NoError(t, err)
+	assert.False(t, history[len(history)-1].Forced)
+}
+
+func TestBillHooksWaitForOuterTransaction(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	billService, _ := newTestBillService(repos)
+	var posted []string
+	billService.OnTransition(func(ctx context.Context, event BillTransitionEvent) error {
+		posted = append(posted, event.Bill.ID)
+		return nil
+	}, BillTransitionPost)
+
+	alice := createTestUser(t, repos, "Alice")
+	bill, err := billService.CreateBill(ctx, CreateBillRequest{
+		Type:           "internet",
+		PeriodStart:    time.Now().AddDate(0, -1, 0),
+		PeriodEnd:      time.Now(),
+		TotalAmountPLN: utils.NewMoney(100, 0),
+	}, alice.ID)
+	require.NoError(t, err)
+	post := func(ctx context.Context) error {
+		_, err := billService.TransitionBill(ctx, bill.ID, BillTransitionRequest{Transition: BillTransitionPost})
+		return err
+	}
+
+	// A rolled back transition never reaches the hooks
+	err = repos.TxManager.WithinTx(ctx, func(ctx context.Context) error {
+		require.NoError(t, post(ctx))
+		return errInjected
+	})
+	require.ErrorIs(t, err, errInjected)
+	assert.Empty(t, posted)
+
+	err = repos.TxManager.WithinTx(ctx, func(ctx context.Context) error {
+		require.NoError(t, post(ctx))
+		assert.Empty(t, posted)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{bill.ID}, posted)
+}
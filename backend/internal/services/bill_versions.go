@@ -0,0 +1,387 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+var ErrBillVersionNotFound = errors.New("bill version not found")
+
+// AmendBillRequest corrects a posted bill, e.g. after a corrected invoice arrives. Fields left out stay as they are.
+type AmendBillRequest struct {
+	Reason          string       `json:"reason"`
+	TotalAmountPLN  *utils.Money `json:"totalAmountPLN,omitempty"` // amount in the bill currency
+	TotalUnits      *float64     `json:"totalUnits,omitempty"`
+	PeriodStart     *time.Time   `json:"periodStart,omitempty"`
+	PeriodEnd       *time.Time   `json:"periodEnd,omitempty"`
+	PaymentDeadline *time.Time   `json:"paymentDeadline,omitempty"`
+	Notes           *string      `json:"notes,omitempty"`
+	// TariffZones replace the zones of a metered bill
+	TariffZones []models.BillTariffZone `json:"tariffZones,omitempty"`
+}
+
+// BillVersionDiff compares the allocations of two versions of a bill
+type BillVersionDiff struct {
+	BillID      string                 `json:"billId"`
+	From        int                    `json:"from"`
+	To          int                    `json:"to"`
+	FromTotal   utils.Money            `json:"fromTotal"`
+	ToTotal     utils.Money            `json:"toTotal"`
+	Currency    string                 `json:"currency"`
+	Allocations []BillVersionDiffEntry `json:"allocations"`
+}
+
+// BillVersionDiffEntry is how one share changed between two versions
+type BillVersionDiffEntry struct {
+	SubjectType string      `json:"subjectType"` // "user" or "group"
+	SubjectID   string      `json:"subjectId"`
+	SubjectName string      `json:"subjectName"`
+	Before      utils.Money `json:"before"`
+	After       utils.Money `json:"after"`
+	Change      utils.Money `json:"change"`
+	Paid        utils.Money `json:"paid"`    // covered so far, payments and credit
+	Balance     utils.Money `json:"balance"` // still owed on the later version, negative when paid too much
+}
+
+// GetBillVersions returns the posted versions of a bill, oldest first
+func (s *BillService) GetBillVersions(ctx context.Context, billID string) ([]models.BillVersion, error) {
+	bill, err := s.bills.GetByID(ctx, billID)
+	if err != nil || bill == nil {
+		return nil, errors.New("bill not found")
+	}
+	return s.billVersions.ListByBillID(ctx, billID)
+}
+
+// AmendBill corrects an open bill in place and stores the result as a new version. The bill keeps its
+// status, payments stay on it and count towards the new shares; what was paid above a lowered share
+// moves to the payer's credit. Closed bills have to be reopened first. Returns the new version.
+func (s *BillService) AmendBill(ctx context.Context, billID string, req AmendBillRequest, actor BillActor) (*models.BillVersion, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, ErrBillTransitionReason
+	}
+
+	// Amendments are not a change of status, but are recorded and followed by hooks like one
+	rule := BillTransitionRule{
+		Name:          BillTransitionAmend,
+		From:          OpenBillStatuses,
+		RequireReason: true,
+		AuditAction:   "amend_bill",
+	}
+
+	var version *models.BillVersion
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		bill, err := s.GetBill(ctx, billID)
+		if err != nil || bill == nil {
+			return errors.New("bill not found")
+		}
+		if !rule.allowedFrom(bill.Status) {
+			return fmt.Errorf("%w: only open bills can be amended", ErrBillTransitionNotAllowed)
+		}
+		rule.To = bill.Status
+		previousTotal := utils.MoneyFromString(bill.TotalAmountPLN)
+		previousStart, previousEnd := bill.PeriodStart, bill.PeriodEnd
+
+		if err := s.applyAmendment(ctx, bill, req); err != nil {
+			return err
+		}
+		if err := s.bills.Update(ctx, bill); err != nil {
+			return fmt.Errorf("failed to update bill: %w", err)
+		}
+		if req.TariffZones != nil {
+			if err := s.billTariffZones.DeleteByBillID(ctx, billID); err != nil {
+				return fmt.Errorf("failed to delete tariff zones: %w", err)
+			}
+			for i := range req.TariffZones {
+				zone := req.TariffZones[i]
+				if err := s.billTariffZones.Create(ctx, billID, &zone); err != nil {
+					return fmt.Errorf("failed to create tariff zone: %w", err)
+				}
+			}
+		}
+
+		// The residents stored on post only change with the period, along with the readings to estimate
+		if !bill.PeriodStart.Equal(previousStart) || !bill.PeriodEnd.Equal(previousEnd) {
+			if err := s.allocationService.StoreResidents(ctx, bill); err != nil {
+				return err
+			}
+			if _, err := s.allocationService.StoreEstimatedReadings(ctx, bill); err != nil {
+				return err
+			}
+		}
+		if err := s.rescaleStoredAllocations(ctx, bill, previousTotal); err != nil {
+			return err
+		}
+		if err := s.storeRuleAllocations(ctx, billID); err != nil {
+			return err
+		}
+		if err := s.creditService.RecordShareOverpayments(ctx, bill); err != nil {
+			return err
+		}
+		if err := s.creditService.ApplyCredit(ctx, bill); err != nil {
+			return err
+		}
+
+		if version, err = s.snapshotBill(ctx, billID, reason, actor.UserID); err != nil {
+			return err
+		}
+		record := &models.BillTransition{
+			BillID:     billID,
+			Transition: BillTransitionAmend,
+			FromStatus: bill.Status,
+			ToStatus:   bill.Status,
+			Reason:     &reason,
+		}
+		if actor.UserID != "" {
+			record.ActorID = &actor.UserID
+		}
+		if err := s.billTransitions.Create(ctx, record); err != nil {
+			return fmt.Errorf("failed to record bill transition: %w", err)
+		}
+
+		event := BillTransitionEvent{Bill: bill, Rule: rule, From: bill.Status, Reason: reason, Actor: actor}
+		s.txManager.AfterCommit(ctx, func(ctx context.Context) {
+			s.stateMachine.runHooks(ctx, event)
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[BILL] Amended: ID=%s, version %d, amount=%s %s (by user %s)",
+		billID, version.Version, version.Bill.TotalAmountPLN, version.Bill.Currency, actor.UserID)
+	return version, nil
+}
+
+// applyAmendment validates the corrections of a bill and applies them to it
+func (s *BillService) applyAmendment(ctx context.Context, bill *models.Bill, req AmendBillRequest) error {
+	total := utils.MoneyFromString(bill.TotalAmountPLN)
+	if req.TotalAmountPLN != nil {
+		if req.TotalAmountPLN.IsNegative() {
+			return errors.New("total amount cannot be negative")
+		}
+		total = *req.TotalAmountPLN
+		bill.TotalAmountPLN = total.String()
+	}
+	if req.PeriodStart != nil {
+		bill.PeriodStart = *req.PeriodStart
+	}
+	if req.PeriodEnd != nil {
+		bill.PeriodEnd = *req.PeriodEnd
+	}
+	if bill.PeriodEnd.Before(bill.PeriodStart) {
+		return errors.New("period end must be after period start")
+	}
+	if req.PaymentDeadline != nil {
+		bill.PaymentDeadline = req.PaymentDeadline
+	}
+	if req.Notes != nil {
+		bill.Notes = req.Notes
+	}
+
+	units := req.TotalUnits
+	zones := bill.TariffZones
+	if req.TariffZones != nil {
+		zones = req.TariffZones
+	}
+	if len(zones) > 0 {
+		if bill.AllocationType == nil || *bill.AllocationType != "metered" {
+			return errors.New("tariff zones require metered allocation")
+		}
+		zonesUnits, err := validateTariffZones(zones, total)
+		if err != nil {
+			return err
+		}
+		if units == nil {
+			units = &zonesUnits
+		} else if utils.RoundToThreeDecimals(*units) != utils.RoundToThreeDecimals(zonesUnits) {
+			return errors.New("totalUnits must equal the sum of the tariff zone units")
+		}
+	}
+	if units != nil {
+		bill.TotalUnits = utils.FloatToDecimalString(*units)
+	}
+
+	if bill.AllocationType != nil {
+		switch *bill.AllocationType {
+		case "custom":
+			return s.allocationService.ValidateBillSplits(ctx, total, bill.PeriodStart, bill.PeriodEnd, bill.Splits)
+		case "itemized":
+			return s.allocationService.ValidateBillItems(ctx, total, bill.PeriodStart, bill.PeriodEnd, bill.Items)
+		}
+	}
+	return nil
+}
+
+// rescaleStoredAllocations keeps allocations stored for a bill, e.g. by its recurring template or an
+// accepted dispute, in proportion to each other when its total changes. Split rules and receipt
+// lines are calculated again instead.
+func (s *BillService) rescaleStoredAllocations(ctx context.Context, bill *models.Bill, previousTotal utils.Money) error {
+	total := utils.MoneyFromString(bill.TotalAmountPLN)
+	if total == previousTotal || (bill.AllocationType != nil && (*bill.AllocationType == "custom" || *bill.AllocationType == "itemized")) {
+		return nil
+	}
+	stored, err := s.allocations.GetByBillID(ctx, bill.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get allocations: %w", err)
+	}
+	if len(stored) == 0 {
+		return nil
+	}
+
+	weights := make([]float64, len(stored))
+	for i, alloc := range stored {
+		weights[i] = utils.MoneyFromString(alloc.AllocatedPLN).Float64()
+	}
+	amounts := total.Allocate(weights)
+
+	if err := s.allocations.DeleteByBillID(ctx, bill.ID); err != nil {
+		return fmt.Errorf("failed to delete allocations: %w", err)
+	}
+	for i, alloc := range stored {
+		if err := s.allocations.Create(ctx, bill.ID, alloc.SubjectType, alloc.SubjectID, amounts[i].String()); err != nil {
+			return fmt.Errorf("failed to create allocation: %w", err)
+		}
+	}
+	return nil
+}
+
+// snapshotBill stores the bill and its allocations as its next version
+func (s *BillService) snapshotBill(ctx context.Context, billID, reason, actorID string) (*models.BillVersion, error) {
+	bill, err := s.GetBill(ctx, billID)
+	if err != nil || bill == nil {
+		return nil, errors.New("bill not found")
+	}
+	breakdown, err := s.allocationService.GetAllocationBreakdown(ctx, billID)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.billVersions.ListByBillID(ctx, billID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bill versions: %w", err)
+	}
+
+	version := &models.BillVersion{
+		BillID:  billID,
+		Version: 1,
+		Bill:    *bill,
+	}
+	if len(versions) > 0 {
+		version.Version = versions[len(versions)-1].Version + 1
+	}
+	for _, entry := range breakdown {
+		version.Allocations = append(version.Allocations, models.BillVersionAllocation{
+			SubjectType: entry.SubjectType,
+			SubjectID:   entry.SubjectID,
+			SubjectName: entry.SubjectName,
+			Amount:      entry.Amount.String(),
+		})
+	}
+	if reason != "" {
+		version.Reason = &reason
+	}
+	if actorID != "" {
+		version.CreatedBy = &actorID
+	}
+	if err := s.billVersions.Create(ctx, version); err != nil {
+		return nil, fmt.Errorf("failed to store bill version: %w", err)
+	}
+	return version, nil
+}
+
+// DiffBillVersions compares the shares of two versions of a bill, by default the latest with the one
+// before it. Each share shows what was paid so far and what is still owed on the later version.
+func (s *BillService) DiffBillVersions(ctx context.Context, billID string, from, to int) (*BillVersionDiff, error) {
+	versions, err := s.GetBillVersions(ctx, billID)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: the bill was never posted", ErrBillVersionNotFound)
+	}
+	if to == 0 {
+		to = versions[len(versions)-1].Version
+	}
+	if from == 0 {
+		from = to - 1
+	}
+	var before, after *models.BillVersion
+	for i := range versions {
+		switch versions[i].Version {
+		case from:
+			before = &versions[i]
+		case to:
+			after = &versions[i]
+		}
+	}
+	if before == nil {
+		return nil, fmt.Errorf("%w: %d", ErrBillVersionNotFound, from)
+	}
+	if after == nil {
+		return nil, fmt.Errorf("%w: %d", ErrBillVersionNotFound, to)
+	}
+
+	coverage, err := s.creditService.GetBillCoverage(ctx, billID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &BillVersionDiff{
+		BillID:      billID,
+		From:        from,
+		To:          to,
+		FromTotal:   utils.MoneyFromString(before.Bill.TotalAmountPLN),
+		ToTotal:     utils.MoneyFromString(after.Bill.TotalAmountPLN),
+		Currency:    after.Bill.Currency,
+		Allocations: []BillVersionDiffEntry{},
+	}
+	index := make(map[string]int)
+	add := func(alloc models.BillVersionAllocation) *BillVersionDiffEntry {
+		key := alloc.SubjectType + ":" + alloc.SubjectID
+		if i, ok := index[key]; ok {
+			return &diff.Allocations[i]
+		}
+		index[key] = len(diff.Allocations)
+		diff.Allocations = append(diff.Allocations, BillVersionDiffEntry{
+			SubjectType: alloc.SubjectType,
+			SubjectID:   alloc.SubjectID,
+			SubjectName: alloc.SubjectName,
+		})
+		return &diff.Allocations[len(diff.Allocations)-1]
+	}
+	for _, alloc := range after.Allocations {
+		add(alloc).After = utils.MoneyFromString(alloc.Amount)
+	}
+	for _, alloc := range before.Allocations {
+		add(alloc).Before = utils.MoneyFromString(alloc.Amount)
+	}
+
+	for i := range diff.Allocations {
+		entry := &diff.Allocations[i]
+		payers := []string{entry.SubjectID}
+		if entry.SubjectType == "group" {
+			members, err := s.users.ListByGroupID(ctx, entry.SubjectID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get group members: %w", err)
+			}
+			payers = payers[:0]
+			for _, m := range members {
+				payers = append(payers, m.ID)
+			}
+		}
+		for _, userID := range payers {
+			entry.Paid = entry.Paid.Add(coverage.Paid[userID])
+		}
+		entry.Change = entry.After.Sub(entry.Before)
+		entry.Balance = entry.After.Sub(entry.Paid)
+	}
+	return diff, nil
+}
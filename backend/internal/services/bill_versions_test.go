@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmendBillVersions(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	billService, paymentService, _ := newTestCreditServices(repos)
+	var amended []string
+	billService.OnTransition(func(ctx context.Context, event BillTransitionEvent) error {
+		amended = append(amended, event.Reason)
+		return nil
+	}, BillTransitionAmend)
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+	carol := createTestUser(t, repos, "Carol")
+	actor := BillActor{UserID: alice.ID, Email: alice.Email}
+
+	bill, err := billService.CreateBill(ctx, CreateBillRequest{
+		Type:           "internet",
+		PeriodStart:    time.Now().AddDate(0, -1, 0),
+		PeriodEnd:      time.Now(),
+		TotalAmountPLN: utils.NewMoney(90, 0),
+	}, alice.ID)
+	require.NoError(t, err)
+
+	// Drafts have no versions to amend
+	_, err = billService.AmendBill(ctx, bill.ID, AmendBillRequest{Reason: "Typo"}, actor)
+	assert.ErrorIs(t, err, ErrBillTransitionNotAllowed)
+
+	require.NoError(t, billService.PostBill(ctx, bill.ID))
+	_, err = paymentService.RecordPayment(ctx, RecordPaymentRequest{BillID: bill.ID, Amount: utils.NewMoney(30, 0)}, bob.ID)
+	require.NoError(t, err)
+	_, err = paymentService.RecordPayment(ctx, RecordPaymentRequest{BillID: bill.ID, Amount: utils.NewMoney(10, 0)}, alice.ID)
+	require.NoError(t, err)
+
+	// Shares stored after posting, e.g. by an accepted dispute, keep their proportions
+	require.NoError(t, repos.Allocations.DeleteByBillID(ctx, bill.ID))
+	require.NoError(t, repos.Allocations.Create(ctx, bill.ID, "user", alice.ID, "40.00"))
+	require.NoError(t, repos.Allocations.Create(ctx, bill.ID, "user", bob.ID, "30.00"))
+	require.NoError(t, repos.Allocations.Create(ctx, bill.ID, "user", carol.ID, "20.00"))
+
+	_, err = billService.TransitionBill(ctx, bill.ID, BillTransitionRequest{Transition: BillTransitionRequestPayment, Actor: actor})
+	require.NoError(t, err)
+
+	amount := utils.NewMoney(45, 0)
+	_, err = billService.AmendBill(ctx, bill.ID, AmendBillRequest{TotalAmountPLN: &amount}, actor)
+	assert.ErrorIs(t, err, ErrBillTransitionReason)
+	version, err := billService.AmendBill(ctx, bill.ID, AmendBillRequest{Reason: "Corrected invoice", TotalAmountPLN: &amount}, actor)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version.Version)
+	assert.Equal(t, "45.00", version.Bill.TotalAmountPLN)
+	assert.Equal(t, BillStatusAwaitingPayment, version.Bill.Status, "amending keeps the status")
+
+	versions, err := billService.GetBillVersions(ctx, bill.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, "90.00", versions[0].Bill.TotalAmountPLN)
+	assert.Len(t, versions[0].Allocations, 3)
+	assert.Nil(t, versions[0].Reason)
+	assert.Equal(t, "Corrected invoice", *versions[1].Reason)
+
+	diff, err := billService.DiffBillVersions(ctx, bill.ID, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, diff.From)
+	assert.Equal(t, 2, diff.To)
+	type row struct{ before, after, change, paid, balance string }
+	rows := make(map[string]row)
+	for _, entry := range diff.Allocations {
+		rows[entry.SubjectID] = row{entry.Before.String(), entry.After.String(), entry.Change.String(), entry.Paid.String(), entry.Balance.String()}
+	}
+	assert.Equal(t, map[string]row{
+		alice.ID: {"30.00", "20.00", "-10.00", "10.00", "10.00"},
+		bob.ID:   {"30.00", "15.00", "-15.00", "15.00", "0.00"},
+		carol.ID: {"30.00", "10.00", "-20.00", "0.00", "10.00"},
+	}, rows)
+
+	// Bob paid 15 zł more than his new share, which moved to his credit
+	credits, err := repos.CreditEntries.ListByUserID(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Len(t, credits, 1)
+	assert.Equal(t, CreditKindOverpayment, credits[0].Kind)
+	assert.Equal(t, "15.00", credits[0].Amount)
+	assert.Equal(t, []string{"Corrected invoice"}, amended, "hooks such as budget alerts follow an amendment")
+
+	_, err = billService.DiffBillVersions(ctx, bill.ID, 1, 3)
+	assert.ErrorIs(t, err, ErrBillVersionNotFound)
+
+	history, err := billService.GetBillHistory(ctx, bill.ID)
+	require.NoError(t, err)
+	var steps []string
+	for _, record := range history {
+		steps = append(steps, record.Transition)
+	}
+	assert.Equal(t, []string{BillTransitionPost, BillTransitionRequestPayment, BillTransitionAmend}, steps)
+	assert.Equal(t, BillStatusAwaitingPayment, history[2].FromStatus)
+	assert.Equal(t, BillStatusAwaitingPayment, history[2].ToStatus)
+	assert.Equal(t, "Corrected invoice", *history[2].Reason)
+
+	// Closed bills have to be reopened before they are corrected
+	_, err = billService.TransitionBill(ctx, bill.ID, BillTransitionRequest{Transition: BillTransitionClose, Force: true, Reason: "Carol pays in cash", Actor: actor})
+	require.NoError(t, err)
+	_, err = billService.AmendBill(ctx, bill.ID, AmendBillRequest{Reason: "Another correction", TotalAmountPLN: &amount}, actor)
+	assert.ErrorIs(t, err, ErrBillTransitionNotAllowed)
+}
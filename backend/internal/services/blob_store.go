@@ -0,0 +1,117 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrBlobNotFound is returned when no file is stored under a hash
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore keeps files on disk addressed by the hex SHA-256 of their contents,
+// fanned out by the first two hex digits (dir/ab/abcdef...). Identical files are stored once.
+type BlobStore struct {
+	dir string
+}
+
+// NewBlobStore opens the blob store in dir, creating the directory if needed
+func NewBlobStore(dir string) (*BlobStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create blob store directory: %w", err)
+	}
+	return &BlobStore{dir: dir}, nil
+}
+
+// Put stores data and returns its hash. Storing a file that already exists is a no-op.
+func (s *BlobStore) Put(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	path := s.path(hash)
+
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return "", fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated blob under its hash
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to store blob: %w", err)
+	}
+	return hash, nil
+}
+
+// Get reads the file stored under hash
+func (s *BlobStore) Get(hash string) ([]byte, error) {
+	if !isBlobHash(hash) {
+		return nil, ErrBlobNotFound
+	}
+	data, err := os.ReadFile(s.path(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return data, err
+}
+
+// Delete removes the file stored under hash. Missing files are not an error.
+func (s *BlobStore) Delete(hash string) error {
+	if !isBlobHash(hash) {
+		return nil
+	}
+	err := os.Remove(s.path(hash))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List returns the hashes of all stored files
+func (s *BlobStore) List() ([]string, error) {
+	var hashes []string
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isBlobHash(d.Name()) {
+			hashes = append(hashes, d.Name())
+		}
+		return nil
+	})
+	return hashes, err
+}
+
+func (s *BlobStore) path(hash string) string {
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+// isBlobHash accepts only lowercase hex SHA-256 digests, so hashes can never escape the store directory
+func isBlobHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for _, r := range hash {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,456 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+// Budget kinds
+const (
+	BudgetKindBill   = "bill"
+	BudgetKindSupply = "supply"
+)
+
+const (
+	defaultBudgetReportMonths = 6
+	maxBudgetReportMonths     = 36
+)
+
+// defaultBudgetThresholds are the alert thresholds of budgets that do not set any
+var defaultBudgetThresholds = []int{80, 100}
+
+var ErrBudgetNotFound = errors.New("budget not found")
+
+type BudgetService struct {
+	budgets             repository.BudgetRepository
+	budgetAlerts        repository.BudgetAlertRepository
+	bills               repository.BillRepository
+	analytics           repository.AnalyticsRepository
+	users               repository.UserRepository
+	currencyService     *CurrencyService
+	notificationService *NotificationService
+	location            *time.Location // months follow the household's time zone, the server's local one
+}
+
+func NewBudgetService(
+	budgets repository.BudgetRepository,
+	budgetAlerts repository.BudgetAlertRepository,
+	bills repository.BillRepository,
+	analytics repository.AnalyticsRepository,
+	users repository.UserRepository,
+	currencyService *CurrencyService,
+	notificationService *NotificationService,
+) *BudgetService {
+	return &BudgetService{
+		budgets:             budgets,
+		budgetAlerts:        budgetAlerts,
+		bills:               bills,
+		analytics:           analytics,
+		users:               users,
+		currencyService:     currencyService,
+		notificationService: notificationService,
+		location:            time.Local,
+	}
+}
+
+// SetBudgetRequest sets the monthly budget of a bill type or supply category
+type SetBudgetRequest struct {
+	Kind            string      `json:"kind"`                 // bill, supply
+	Category        string      `json:"category"`             // bill type, or supply category
+	CustomType      string      `json:"customType,omitempty"` // "inne" bills only, empty for all of them
+	MonthlyAmount   utils.Money `json:"monthlyAmount"`        // in the base currency
+	AlertThresholds []int       `json:"alertThresholds,omitempty"`
+}
+
+// SetBudget creates the monthly budget of a category, or replaces the one it has
+func (s *BudgetService) SetBudget(ctx context.Context, req SetBudgetRequest) (*models.Budget, error) {
+	budget := &models.Budget{
+		Kind:     req.Kind,
+		Category: strings.ToLower(strings.TrimSpace(req.Category)),
+	}
+
+	customType := strings.TrimSpace(req.CustomType)
+	switch req.Kind {
+	case BudgetKindBill:
+		switch budget.Category {
+		case "electricity", "gas", "internet":
+			if customType != "" {
+				return nil, errors.New("customType should only be provided when type is 'inne'")
+			}
+		case "inne":
+		default:
+			return nil, fmt.Errorf("invalid bill type: %q", req.Category)
+		}
+		budget.CustomType = customType
+	case BudgetKindSupply:
+		switch budget.Category {
+		case "groceries", "cleaning", "toiletries", "other":
+		default:
+			return nil, fmt.Errorf("invalid supply category: %q", req.Category)
+		}
+		if customType != "" {
+			return nil, errors.New("customType is only used by bill budgets")
+		}
+	default:
+		return nil, errors.New("kind must be 'bill' or 'supply'")
+	}
+
+	if !req.MonthlyAmount.IsPositive() {
+		return nil, errors.New("monthly amount must be positive")
+	}
+	budget.MonthlyAmount = req.MonthlyAmount.String()
+
+	thresholds := req.AlertThresholds
+	if len(thresholds) == 0 {
+		thresholds = defaultBudgetThresholds
+	}
+	seen := make(map[int]bool)
+	for _, threshold := range thresholds {
+		if threshold <= 0 || threshold > 1000 {
+			return nil, errors.New("alert thresholds must be between 1 and 1000 percent")
+		}
+		if !seen[threshold] {
+			seen[threshold] = true
+			budget.AlertThresholds = append(budget.AlertThresholds, threshold)
+		}
+	}
+	sort.Ints(budget.AlertThresholds)
+
+	if err := s.budgets.Upsert(ctx, budget); err != nil {
+		return nil, fmt.Errorf("failed to save budget: %w", err)
+	}
+	return budget, nil
+}
+
+// GetBudgets returns all budgets
+func (s *BudgetService) GetBudgets(ctx context.Context) ([]models.Budget, error) {
+	return s.budgets.List(ctx)
+}
+
+// DeleteBudget deletes a budget together with its sent alerts
+func (s *BudgetService) DeleteBudget(ctx context.Context, id string) error {
+	budget, err := s.budgets.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get budget: %w", err)
+	}
+	if budget == nil {
+		return ErrBudgetNotFound
+	}
+	return s.budgets.Delete(ctx, id)
+}
+
+// BudgetLine is one budget's plan and spending in a month
+type BudgetLine struct {
+	BudgetID    string      `json:"budgetId"`
+	Kind        string      `json:"kind"`
+	Category    string      `json:"category"`
+	CustomType  string      `json:"customType,omitempty"`
+	Budgeted    utils.Money `json:"budgeted"`
+	Actual      utils.Money `json:"actual"`
+	Remaining   utils.Money `json:"remaining"`   // negative when the budget was exceeded
+	PercentUsed float64     `json:"percentUsed"` // actual as a percentage of budgeted
+}
+
+// BudgetMonth is the budget-vs-actual of one month
+type BudgetMonth struct {
+	Month    string       `json:"month"` // YYYY-MM
+	Lines    []BudgetLine `json:"lines"`
+	Budgeted utils.Money  `json:"budgeted"`
+	Actual   utils.Money  `json:"actual"`
+}
+
+// BudgetReport compares budgets with spending over the last months, oldest first
+type BudgetReport struct {
+	Currency string        `json:"currency"` // base currency of all amounts
+	Months   []BudgetMonth `json:"months"`
+}
+
+// GetReport returns the budget-vs-actual of the last months, the current one included
+func (s *BudgetService) GetReport(ctx context.Context, months int, now time.Time) (*BudgetReport, error) {
+	if months == 0 {
+		months = defaultBudgetReportMonths
+	}
+	if months < 0 || months > maxBudgetReportMonths {
+		return nil, fmt.Errorf("months must be between 1 and %d", maxBudgetReportMonths)
+	}
+
+	baseCurrency, err := s.currencyService.BaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	budgets, err := s.budgets.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list budgets: %w", err)
+	}
+
+	current := s.monthOf(now)
+	from := current.AddDate(0, 1-months, 0)
+	actuals, err := s.spending(ctx, budgets, from, current.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BudgetReport{
+		Currency: baseCurrency,
+		Months:   make([]BudgetMonth, 0, months),
+	}
+	for month := from; !month.After(current); month = month.AddDate(0, 1, 0) {
+		key := month.Format("2006-01")
+		entry := BudgetMonth{Month: key, Lines: make([]BudgetLine, 0, len(budgets))}
+		for _, budget := range budgets {
+			line := budgetLine(&budget, actuals[budget.ID][key])
+			entry.Lines = append(entry.Lines, line)
+			entry.Budgeted = entry.Budgeted.Add(line.Budgeted)
+			entry.Actual = entry.Actual.Add(line.Actual)
+		}
+		report.Months = append(report.Months, entry)
+	}
+	return report, nil
+}
+
+// CheckAlerts notifies the household when spending in the current or previous month reaches a
+// threshold of a budget. The previous month is included because bills often arrive after their
+// period ends. Every threshold is alerted once per month. Returns the number of alerts sent.
+func (s *BudgetService) CheckAlerts(ctx context.Context, now time.Time) (int, error) {
+	budgets, err := s.budgets.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list budgets: %w", err)
+	}
+	if len(budgets) == 0 {
+		return 0, nil
+	}
+
+	current := s.monthOf(now)
+	previous := current.AddDate(0, -1, 0)
+	actuals, err := s.spending(ctx, budgets, previous, current.AddDate(0, 1, 0))
+	if err != nil {
+		return 0, err
+	}
+
+	baseCurrency, err := s.currencyService.BaseCurrency(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, month := range []time.Time{previous, current} {
+		key := month.Format("2006-01")
+		alerted, err := s.budgetAlerts.ListByMonth(ctx, key)
+		if err != nil {
+			return sent, fmt.Errorf("failed to get budget alerts: %w", err)
+		}
+		done := make(map[string]bool, len(alerted))
+		for _, alert := range alerted {
+			done[fmt.Sprintf("%s:%d", alert.BudgetID, alert.Threshold)] = true
+		}
+
+		for _, budget := range budgets {
+			line := budgetLine(&budget, actuals[budget.ID][key])
+			reached := 0
+			for _, threshold := range budget.AlertThresholds {
+				if line.PercentUsed < float64(threshold) || done[fmt.Sprintf("%s:%d", budget.ID, threshold)] {
+					continue
+				}
+				if err := s.budgetAlerts.Create(ctx, &models.BudgetAlert{
+					BudgetID:  budget.ID,
+					Month:     key,
+					Threshold: threshold,
+					Actual:    line.Actual.String(),
+				}); err != nil {
+					return sent, fmt.Errorf("failed to record budget alert: %w", err)
+				}
+				reached = threshold
+			}
+			// Thresholds crossed together are announced once, by the highest of them
+			if reached > 0 {
+				s.notifyBudget(ctx, &budget, line, key, reached, baseCurrency)
+				sent++
+			}
+		}
+	}
+
+	if sent > 0 {
+		log.Printf("[BUDGET] Sent %d budget alerts", sent)
+	}
+	return sent, nil
+}
+
+// OnBillTransition checks the budgets as soon as a bill is posted
+func (s *BudgetService) OnBillTransition(ctx context.Context, event BillTransitionEvent) error {
+	_, err := s.CheckAlerts(ctx, time.Now())
+	return err
+}
+
+// monthOf returns the start of the month t falls in, in the household's time zone
+func (s *BudgetService) monthOf(t time.Time) time.Time {
+	t = t.In(s.location)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, s.location)
+}
+
+// spending returns what was spent in every month in [from, to) on each budget, by budget ID and month.
+// from and to are starts of months in the household's time zone.
+func (s *BudgetService) spending(ctx context.Context, budgets []models.Budget, from, to time.Time) (map[string]map[string]utils.Money, error) {
+	result := make(map[string]map[string]utils.Money, len(budgets))
+	add := func(budgetID, month string, amount utils.Money) {
+		if result[budgetID] == nil {
+			result[budgetID] = make(map[string]utils.Money)
+		}
+		result[budgetID][month] = result[budgetID][month].Add(amount)
+	}
+
+	// Posted bills, spread over the months of their period by days. Bill periods are calendar days
+	// stored as UTC midnight, so the months are compared as calendar days too.
+	firstDay := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	endDay := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+	bills, err := s.bills.ListOverlapping(ctx, firstDay, endDay, IssuedBillStatuses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list posted bills: %w", err)
+	}
+	for _, bill := range bills {
+		var matched []string
+		for _, budget := range budgets {
+			if budget.Kind == BudgetKindBill && billInBudget(&bill, &budget) {
+				matched = append(matched, budget.ID)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		amount, err := s.currencyService.Convert(ctx, utils.MoneyFromString(bill.TotalAmountPLN), bill.Currency, bill.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert bill %s: %w", bill.ID, err)
+		}
+		for month, part := range spreadOverMonths(amount, bill.PeriodStart, bill.PeriodEnd) {
+			for _, budgetID := range matched {
+				add(budgetID, month, part)
+			}
+		}
+	}
+
+	// Supply restocks, summed by month and category like in analytics; their cost is kept in the base currency
+	spend, err := s.analytics.SupplySpend(ctx, repository.AnalyticsFilter{
+		From:     &from,
+		To:       &to,
+		GroupBy:  AnalyticsGroupByMonth,
+		Location: s.location,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supply spending: %w", err)
+	}
+	for _, entry := range spend {
+		for _, budget := range budgets {
+			if budget.Kind == BudgetKindSupply && budget.Category == entry.Category {
+				add(budget.ID, entry.Period, entry.Amount)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// billInBudget tells whether a bill counts against a bill budget. A budget for "inne" without a
+// custom type covers every "inne" bill.
+func billInBudget(bill *models.Bill, budget *models.Budget) bool {
+	if bill.Type != budget.Category {
+		return false
+	}
+	if budget.CustomType == "" {
+		return true
+	}
+	return bill.CustomType != nil && strings.EqualFold(strings.TrimSpace(*bill.CustomType), budget.CustomType)
+}
+
+// spreadOverMonths divides an amount over the months of a period in proportion to its days in each
+func spreadOverMonths(amount utils.Money, start, end time.Time) map[string]utils.Money {
+	first := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	last := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	if last.Before(first) {
+		last = first
+	}
+
+	var months []string
+	var weights []float64
+	for day := first; !day.After(last); {
+		monthEnd := time.Date(day.Year(), day.Month()+1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+		if monthEnd.After(last) {
+			monthEnd = last
+		}
+		months = append(months, day.Format("2006-01"))
+		weights = append(weights, monthEnd.Sub(day).Hours()/24+1)
+		day = monthEnd.AddDate(0, 0, 1)
+	}
+
+	result := make(map[string]utils.Money, len(months))
+	for i, part := range amount.Allocate(weights) {
+		result[months[i]] = part
+	}
+	return result
+}
+
+func budgetLine(budget *models.Budget, actual utils.Money) BudgetLine {
+	budgeted := utils.MoneyFromString(budget.MonthlyAmount)
+	line := BudgetLine{
+		BudgetID:   budget.ID,
+		Kind:       budget.Kind,
+		Category:   budget.Category,
+		CustomType: budget.CustomType,
+		Budgeted:   budgeted,
+		Actual:     actual,
+		Remaining:  budgeted.Sub(actual),
+	}
+	if budgeted.IsPositive() {
+		line.PercentUsed = float64(actual.Grosze()) * 100 / float64(budgeted.Grosze())
+	}
+	return line
+}
+
+// notifyBudget tells every active resident that spending reached a threshold of a budget
+func (s *BudgetService) notifyBudget(ctx context.Context, budget *models.Budget, line BudgetLine, month string, threshold int, currency string) {
+	if s.notificationService == nil {
+		return
+	}
+	users, err := s.users.ListActive(ctx)
+	if err != nil {
+		log.Printf("[BUDGET] Failed to list users: %v", err)
+		return
+	}
+
+	name := budget.Category
+	if budget.Kind == BudgetKindBill {
+		var customType *string
+		if budget.CustomType != "" {
+			customType = &budget.CustomType
+		}
+		name = getBillTypeName(budget.Category, customType)
+	}
+	title := "Budżet prawie wyczerpany"
+	if threshold >= 100 {
+		title = "Budżet przekroczony"
+	}
+	body := fmt.Sprintf("%s: wydano %s z %s (%.0f%%) w %s", name, FormatAmount(line.Actual, currency),
+		FormatAmount(line.Budgeted, currency), line.PercentUsed, month)
+
+	for _, user := range users {
+		userID := user.ID
+		now := time.Now()
+		_ = s.notificationService.CreateNotification(ctx, &models.Notification{
+			UserID:       &userID,
+			Channel:      "app",
+			TemplateID:   budget.Kind,
+			ScheduledFor: now,
+			SentAt:       &now,
+			Status:       "sent",
+			Title:        title,
+			Body:         body,
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetAlertsAndReport(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	billService, _, _ := newTestCreditServices(repos)
+	currencyService := NewCurrencyService(repos.ExchangeRates, NewAppSettingsService(repos.AppSettings))
+	notificationService := newTestNotificationService(repos)
+	supplyService := NewSupplyService(repos.SupplySettings, repos.SupplyItems, repos.SupplyContributions, repos.SupplyItemHistory,
+		repos.Users, repos.TxManager, notificationService, currencyService)
+	budgetService := NewBudgetService(repos.Budgets, repos.BudgetAlerts, repos.Bills, repos.Analytics, repos.Users,
+		currencyService, notificationService)
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+	now := time.Now()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	lastMonth := thisMonth.AddDate(0, -1, 0)
+
+	_, err := budgetService.SetBudget(ctx, SetBudgetRequest{Kind: BudgetKindBill, Category: "water", MonthlyAmount: utils.NewMoney(100, 0)})
+	assert.Error(t, err)
+	_, err = budgetService.SetBudget(ctx, SetBudgetRequest{Kind: BudgetKindSupply, Category: "groceries", CustomType: "Bread", MonthlyAmount: utils.NewMoney(100, 0)})
+	assert.Error(t, err)
+	_, err = budgetService.SetBudget(ctx, SetBudgetRequest{Kind: BudgetKindBill, Category: "internet"})
+	assert.Error(t, err)
+
+	internet, err := budgetService.SetBudget(ctx, SetBudgetRequest{Kind: BudgetKindBill, Category: "internet", MonthlyAmount: utils.NewMoney(80, 0)})
+	require.NoError(t, err)
+	assert.Equal(t, []int{80, 100}, internet.AlertThresholds)
+	replaced, err := budgetService.SetBudget(ctx, SetBudgetRequest{Kind: BudgetKindBill, Category: "internet", MonthlyAmount: utils.NewMoney(100, 0)})
+	require.NoError(t, err)
+	assert.Equal(t, internet.ID, replaced.ID)
+	_, err = budgetService.SetBudget(ctx, SetBudgetRequest{Kind: BudgetKindSupply, Category: "groceries", MonthlyAmount: utils.NewMoney(50, 0), AlertThresholds: []int{100, 50, 50}})
+	require.NoError(t, err)
+
+	// Last month's internet bill uses 90% of its budget
+	bill, err := billService.CreateBill(ctx, CreateBillRequest{
+		Type:           "internet",
+		PeriodStart:    lastMonth,
+		PeriodEnd:      thisMonth.AddDate(0, 0, -1),
+		TotalAmountPLN: utils.NewMoney(90, 0),
+	}, alice.ID)
+	require.NoError(t, err)
+
+	// Drafts do not count
+	sent, err := budgetService.CheckAlerts(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	require.NoError(t, billService.PostBill(ctx, bill.ID))
+	sent, err = budgetService.CheckAlerts(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	// Groceries bought this month cross the 50% threshold
+	item, err := supplyService.CreateItem(ctx, alice.ID, "Coffee", "groceries", 0, 1, "pcs", 3, nil)
+	require.NoError(t, err)
+	cost := utils.NewMoney(30, 0)
+	_, err = supplyService.RestockItem(ctx, item.ID, bob.ID, 2, &cost, "", true)
+	require.NoError(t, err)
+
+	sent, err = budgetService.CheckAlerts(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	sent, err = budgetService.CheckAlerts(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	notifications, err := repos.Notifications.ListByUserID(ctx, bob.ID, 20)
+	require.NoError(t, err)
+	var titles []string
+	for _, notification := range notifications {
+		if notification.Title == "Budżet prawie wyczerpany" || notification.Title == "Budżet przekroczony" {
+			titles = append(titles, notification.Title)
+		}
+	}
+	assert.Len(t, titles, 2)
+
+	report, err := budgetService.GetReport(ctx, 2, now)
+	require.NoError(t, err)
+	require.Len(t, report.Months, 2)
+	assert.Equal(t, lastMonth.Format("2006-01"), report.Months[0].Month)
+	type row struct{ budgeted, actual, remaining string }
+	rows := func(month BudgetMonth) map[string]row {
+		result := make(map[string]row)
+		for _, line := range month.Lines {
+			result[line.Category] = row{line.Budgeted.String(), line.Actual.String(), line.Remaining.String()}
+		}
+		return result
+	}
+	assert.Equal(t, map[string]row{
+		"internet":  {"100.00", "90.00", "10.00"},
+		"groceries": {"50.00", "0.00", "50.00"},
+	}, rows(report.Months[0]))
+	assert.Equal(t, map[string]row{
+		"internet":  {"100.00", "0.00", "100.00"},
+		"groceries": {"50.00", "30.00", "20.00"},
+	}, rows(report.Months[1]))
+	assert.Equal(t, "150.00", report.Months[1].Budgeted.String())
+	assert.Equal(t, "30.00", report.Months[1].Actual.String())
+
+	_, err = budgetService.GetReport(ctx, 37, now)
+	assert.Error(t, err)
+
+	// Purchases count in the month of the household's time zone
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	require.NoError(t, err)
+	budgetService.location = warsaw
+	lateCost := "10.00"
+	require.NoError(t, repos.SupplyItemHistory.Create(ctx, &models.SupplyItemHistory{
+		SupplyItemID: item.ID, UserID: bob.ID, Action: "restock", QuantityDelta: 1, NewQuantity: 1,
+		CostPLN: &lateCost, CreatedAt: time.Date(2026, 2, 28, 23, 30, 0, 0, time.UTC),
+	}))
+	report, err = budgetService.GetReport(ctx, 2, time.Date(2026, 3, 15, 12, 0, 0, 0, warsaw))
+	require.NoError(t, err)
+	assert.Equal(t, "0.00", rows(report.Months[0])["groceries"].actual)
+	assert.Equal(t, "2026-03", report.Months[1].Month)
+	assert.Equal(t, "10.00", rows(report.Months[1])["groceries"].actual)
+}
+
+func TestSpreadOverMonths(t *testing.T) {
+	// 16 days of January and 14 of February
+	parts := spreadOverMonths(utils.NewMoney(300, 0),
+		time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC), time.Date(2026, 2, 14, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, map[string]utils.Money{"2026-01": utils.NewMoney(160, 0), "2026-02": utils.NewMoney(140, 0)}, parts)
+
+	parts = spreadOverMonths(utils.NewMoney(10, 0),
+		time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, map[string]utils.Money{"2026-03": utils.NewMoney(10, 0)}, parts)
+}
@@ -16,6 +16,7 @@ type ChoreService struct {
 	chores              repository.ChoreRepository
 	choreAssignments    repository.ChoreAssignmentRepository
 	users               repository.UserRepository
+	txManager           repository.TxManager
 	notificationService *NotificationService
 }
 
@@ -23,12 +24,14 @@ func NewChoreService(
 	chores repository.ChoreRepository,
 	choreAssignments repository.ChoreAssignmentRepository,
 	users repository.UserRepository,
+	txManager repository.TxManager,
 	notificationService *NotificationService,
 ) *ChoreService {
 	return &ChoreService{
 		chores:              chores,
 		choreAssignments:    choreAssignments,
 		users:               users,
+		txManager:           txManager,
 		notificationService: notificationService,
 	}
 }
@@ -564,24 +567,33 @@ func (s *ChoreService) GetUserLeaderboard(ctx context.Context) ([]UserStats, err
 
 // DeleteChore deletes a chore and all its assignments
 func (s *ChoreService) DeleteChore(ctx context.Context, choreID string) error {
-	// Delete all assignments for this chore
-	assignments, err := s.choreAssignments.ListByChoreID(ctx, choreID)
-	if err != nil {
-		return fmt.Errorf("failed to list chore assignments: %w", err)
-	}
+	var deletedAssignments int
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Delete all assignments for this chore
+		assignments, err := s.choreAssignments.ListByChoreID(ctx, choreID)
+		if err != nil {
+			return fmt.Errorf("failed to list chore assignments: %w", err)
+		}
 
-	for _, assignment := range assignments {
-		if err := s.choreAssignments.Delete(ctx, assignment.ID); err != nil {
-			return fmt.Errorf("failed to delete chore assignment: %w", err)
+		for _, assignment := range assignments {
+			if err := s.choreAssignments.Delete(ctx, assignment.ID); err != nil {
+				return fmt.Errorf("failed to delete chore assignment: %w", err)
+			}
 		}
-	}
 
-	// Delete the chore
-	if err := s.chores.Delete(ctx, choreID); err != nil {
-		return fmt.Errorf("failed to delete chore: %w", err)
+		// Delete the chore
+		if err := s.chores.Delete(ctx, choreID); err != nil {
+			return fmt.Errorf("failed to delete chore: %w", err)
+		}
+
+		deletedAssignments = len(assignments)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	log.Printf("[CHORE] Deleted: ID=%s (including %d assignments)", choreID, len(assignments))
+	log.Printf("[CHORE] Deleted: ID=%s (including %d assignments)", choreID, deletedAssignments)
 
 	return nil
 }
@@ -15,36 +15,60 @@ import (
 var ErrNoPreviousReading = errors.New("no previous meter reading found")
 
 type ConsumptionService struct {
-	consumptions repository.ConsumptionRepository
-	bills        repository.BillRepository
-	users        repository.UserRepository
+	consumptions         repository.ConsumptionRepository
+	consumptionAnomalies repository.ConsumptionAnomalyRepository
+	attachmentService    *AttachmentService
+	bills                repository.BillRepository
+	users                repository.UserRepository
+	meters               repository.MeterRepository
+	billTariffZones      repository.BillTariffZoneRepository
+	txManager            repository.TxManager
 }
 
 func NewConsumptionService(
 	consumptions repository.ConsumptionRepository,
+	consumptionAnomalies repository.ConsumptionAnomalyRepository,
+	attachmentService *AttachmentService,
 	bills repository.BillRepository,
 	users repository.UserRepository,
+	meters repository.MeterRepository,
+	billTariffZones repository.BillTariffZoneRepository,
+	txManager repository.TxManager,
 ) *ConsumptionService {
 	return &ConsumptionService{
-		consumptions: consumptions,
-		bills:        bills,
-		users:        users,
+		consumptions:         consumptions,
+		consumptionAnomalies: consumptionAnomalies,
+		attachmentService:    attachmentService,
+		bills:                bills,
+		users:                users,
+		meters:               meters,
+		billTariffZones:      billTariffZones,
+		txManager:            txManager,
 	}
 }
 
 type CreateConsumptionRequest struct {
 	BillID     string    `json:"billId"`
 	UserID     string    `json:"userId"`
+	MeterID    *string   `json:"meterId,omitempty"` // registered meter the value was read from
 	Units      float64   `json:"units"`
 	MeterValue *float64  `json:"meterValue,omitempty"`
 	RecordedAt time.Time `json:"recordedAt"`
+	// Photo of the meter showing the value, uploaded along with the reading
+	Photo *ReadingPhoto `json:"-"`
+}
+
+// ReadingPhoto is a photo file sent with a reading
+type ReadingPhoto struct {
+	FileName string
+	Data     []byte
 }
 
 // CreateConsumption records a consumption reading
 func (s *ConsumptionService) CreateConsumption(ctx context.Context, req CreateConsumptionRequest, source string) (*models.Consumption, error) {
 	// Verify bill exists
 	bill, err := s.bills.GetByID(ctx, req.BillID)
-	if err != nil {
+	if err != nil || bill == nil {
 		return nil, errors.New("bill not found")
 	}
 
@@ -53,6 +77,13 @@ func (s *ConsumptionService) CreateConsumption(ctx context.Context, req CreateCo
 		return nil, errors.New("cannot add consumption to closed bill")
 	}
 
+	// Reject an unusable photo before anything is recorded
+	if req.Photo != nil {
+		if _, err := s.attachmentService.checkFile(AttachmentResourceConsumption, req.Photo.Data); err != nil {
+			return nil, err
+		}
+	}
+
 	// Verify user exists and get their group
 	user, err := s.users.GetByID(ctx, req.UserID)
 	if err != nil {
@@ -67,32 +98,29 @@ func (s *ConsumptionService) CreateConsumption(ctx context.Context, req CreateCo
 		subjectID = *user.GroupID
 	}
 
-	unitsValue := req.Units
-
-	if unitsValue <= 0 {
-		if req.MeterValue == nil {
-			return nil, errors.New("units must be greater than zero when no meter reading is provided")
-		}
-
-		computedUnits, err := s.calculateUnitsFromMeter(ctx, subjectID, subjectType, *req.MeterValue, req.RecordedAt)
-		switch {
-		case err == nil:
-			unitsValue = computedUnits
-		case errors.Is(err, ErrNoPreviousReading):
-			unitsValue = *req.MeterValue
-		default:
+	// Readings of a registered meter belong to the meter's subject
+	if req.MeterID != nil {
+		meter, err := s.checkMeter(ctx, bill, *req.MeterID, req.RecordedAt)
+		if err != nil {
 			return nil, err
 		}
+		if source != "admin" && (meter.SubjectType != subjectType || meter.SubjectID != subjectID) {
+			return nil, errors.New("you can only record readings of your own meters")
+		}
+		subjectType = meter.SubjectType
+		subjectID = meter.SubjectID
 	}
 
-	unitsDec := utils.FloatToDecimalString(unitsValue)
+	if req.Units <= 0 && req.MeterValue == nil {
+		return nil, errors.New("units must be greater than zero when no meter reading is provided")
+	}
 
 	consumption := &models.Consumption{
 		ID:          uuid.New().String(),
 		BillID:      req.BillID,
 		SubjectType: subjectType,
 		SubjectID:   subjectID,
-		Units:       unitsDec,
+		MeterID:     req.MeterID,
 		RecordedAt:  req.RecordedAt,
 		Source:      source,
 	}
@@ -102,17 +130,95 @@ func (s *ConsumptionService) CreateConsumption(ctx context.Context, req CreateCo
 		consumption.MeterValue = &meterDec
 	}
 
-	if err := s.consumptions.Create(ctx, consumption); err != nil {
-		return nil, fmt.Errorf("failed to create consumption: %w", err)
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// The real reading replaces what was estimated for the subject while it was missing
+		replaced, err := s.removeEstimates(ctx, req.BillID, subjectType, subjectID, req.MeterID)
+		if err != nil {
+			return err
+		}
+
+		// Units are worked out once the estimates are gone, so they continue from a real reading
+		unitsValue := req.Units
+		if unitsValue <= 0 {
+			computedUnits, err := meterReadingUnits(ctx, s.consumptions, s.meters, *consumption)
+			switch {
+			case err == nil:
+				unitsValue = computedUnits
+			case errors.Is(err, ErrNoPreviousReading):
+				unitsValue = *req.MeterValue
+			default:
+				return err
+			}
+		}
+		consumption.Units = utils.FloatToDecimalString(unitsValue)
+
+		if err := s.consumptions.Create(ctx, consumption); err != nil {
+			return fmt.Errorf("failed to create consumption: %w", err)
+		}
+
+		// Readings that continued from a replaced estimate continue from the real reading now
+		for _, estimate := range replaced {
+			if estimate.MeterID == nil {
+				continue
+			}
+			after := estimate.RecordedAt
+			if consumption.MeterID != nil && *consumption.MeterID == *estimate.MeterID && consumption.RecordedAt.After(after) {
+				after = consumption.RecordedAt
+			}
+			if err := rederiveNextReading(ctx, s.consumptions, s.meters, s.bills, *estimate.MeterID, after); err != nil {
+				return err
+			}
+		}
+
+		// The photo is recorded last; if the transaction still fails, its file is left for the orphan cleanup
+		if req.Photo != nil {
+			photo, err := s.attachmentService.Upload(ctx, AttachmentResourceConsumption, consumption.ID, req.Photo.FileName, req.Photo.Data, req.UserID)
+			if err != nil {
+				return err
+			}
+			consumption.Photo = photo
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return consumption, nil
 }
 
+// removeEstimates deletes the estimated readings of a subject on a bill that a new reading replaces:
+// those of the same meter and those not tied to a meter, or all of them for a reading without a meter
+func (s *ConsumptionService) removeEstimates(ctx context.Context, billID, subjectType, subjectID string, meterID *string) ([]models.Consumption, error) {
+	readings, err := s.consumptions.ListByBillID(ctx, billID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consumptions: %w", err)
+	}
+
+	var removed []models.Consumption
+	for _, c := range readings {
+		if c.Source != "estimated" || c.SubjectType != subjectType || c.SubjectID != subjectID {
+			continue
+		}
+		if meterID != nil && c.MeterID != nil && *c.MeterID != *meterID {
+			continue
+		}
+		if err := s.consumptions.Delete(ctx, c.ID); err != nil {
+			return nil, fmt.Errorf("failed to remove estimated reading: %w", err)
+		}
+		removed = append(removed, c)
+	}
+	return removed, nil
+}
+
 // GetConsumptions retrieves consumptions for a bill, or all consumptions if billID is nil
 func (s *ConsumptionService) GetConsumptions(ctx context.Context, billID *string) ([]models.Consumption, error) {
 	if billID != nil {
-		return s.consumptions.ListByBillID(ctx, *billID)
+		consumptions, err := s.consumptions.ListByBillID(ctx, *billID)
+		if err != nil {
+			return nil, err
+		}
+		return s.withDetails(ctx, consumptions)
 	}
 	// For listing all consumptions, we'd need a List method - for now return empty
 	return []models.Consumption{}, nil
@@ -152,9 +258,25 @@ func (s *ConsumptionService) GetUserConsumptions(ctx context.Context, userID str
 			}
 			filtered = append(filtered, c)
 		}
-		return filtered, nil
+		consumptions = filtered
 	}
 
+	return s.withDetails(ctx, consumptions)
+}
+
+// withDetails attaches the flags raised by the anomaly check and the meter photo to each reading
+func (s *ConsumptionService) withDetails(ctx context.Context, consumptions []models.Consumption) ([]models.Consumption, error) {
+	for i := range consumptions {
+		anomalies, err := s.consumptionAnomalies.ListByConsumptionID(ctx, consumptions[i].ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get anomalies: %w", err)
+		}
+		consumptions[i].Anomalies = anomalies
+
+		if consumptions[i].Photo, err = s.attachmentService.ReadingPhoto(ctx, consumptions[i].ID); err != nil {
+			return nil, err
+		}
+	}
 	return consumptions, nil
 }
 
@@ -171,18 +293,19 @@ func (s *ConsumptionService) DeleteConsumption(ctx context.Context, consumptionI
 	return s.consumptions.Delete(ctx, consumptionID)
 }
 
-// MarkConsumptionInvalid marks a consumption as invalid (user or their group must own it)
-func (s *ConsumptionService) MarkConsumptionInvalid(ctx context.Context, consumptionID, userID string) error {
+// MarkConsumptionInvalid marks a consumption as invalid (user or their group must own it).
+// Returns the reading with its photo, the evidence the invalidation is recorded against.
+func (s *ConsumptionService) MarkConsumptionInvalid(ctx context.Context, consumptionID, userID string) (*models.Consumption, error) {
 	// Find the consumption
 	consumption, err := s.consumptions.GetByID(ctx, consumptionID)
 	if err != nil || consumption == nil {
-		return errors.New("consumption not found")
+		return nil, errors.New("consumption not found")
 	}
 
 	// Get user to check their group
 	user, err := s.users.GetByID(ctx, userID)
 	if err != nil {
-		return fmt.Errorf("user not found: %w", err)
+		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
 	// Verify ownership - either direct user ownership or group ownership
@@ -194,46 +317,56 @@ func (s *ConsumptionService) MarkConsumptionInvalid(ctx context.Context, consump
 	}
 
 	if !isOwner {
-		return errors.New("you can only mark your own readings as invalid")
+		return nil, errors.New("you can only mark your own readings as invalid")
 	}
 
 	// Update source to indicate it's invalid
 	consumption.Source = "invalid"
-	return s.consumptions.Update(ctx, consumption)
+	if err := s.consumptions.Update(ctx, consumption); err != nil {
+		return nil, err
+	}
+
+	if consumption.Photo, err = s.attachmentService.ReadingPhoto(ctx, consumption.ID); err != nil {
+		return nil, err
+	}
+	return consumption, nil
 }
 
-// calculateUnitsFromMeter derives consumption units based on the previous meter reading
-func (s *ConsumptionService) calculateUnitsFromMeter(ctx context.Context, subjectID string, subjectType string, currentMeter float64, recordedAt time.Time) (float64, error) {
-	// Get all consumptions for this subject and find the most recent one before recordedAt
-	consumptions, err := s.consumptions.ListBySubject(ctx, subjectType, subjectID)
+// checkMeter verifies that a meter can be read for a bill at the given time
+func (s *ConsumptionService) checkMeter(ctx context.Context, bill *models.Bill, meterID string, recordedAt time.Time) (*models.Meter, error) {
+	meter, err := s.meters.GetByID(ctx, meterID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to fetch consumptions: %w", err)
+		return nil, fmt.Errorf("failed to fetch meter: %w", err)
 	}
-
-	var previous *models.Consumption
-	for i := range consumptions {
-		c := &consumptions[i]
-		if c.MeterValue == nil {
-			continue
-		}
-		if !c.RecordedAt.Before(recordedAt) {
-			continue
-		}
-		if previous == nil || c.RecordedAt.After(previous.RecordedAt) {
-			previous = c
-		}
+	if meter == nil {
+		return nil, ErrMeterNotFound
 	}
-
-	if previous == nil || previous.MeterValue == nil {
-		return 0, ErrNoPreviousReading
+	if meter.ReplacedAt != nil && recordedAt.After(*meter.ReplacedAt) {
+		return nil, ErrMeterReplaced
 	}
-
-	prevValue := utils.DecimalStringToFloat(*previous.MeterValue)
-
-	units := currentMeter - prevValue
-	if units < 0 {
-		return 0, errors.New("meter reading cannot be lower than previous reading")
+	if recordedAt.Before(meter.InstalledAt) {
+		return nil, errors.New("reading cannot be older than the meter")
+	}
+	if (bill.Type == "electricity" || bill.Type == "gas") && meter.Type != bill.Type {
+		return nil, fmt.Errorf("%s meters cannot be read for %s bills", meter.Type, bill.Type)
 	}
 
-	return units, nil
+	// Bills with tariff zones only price the zones they list
+	zones, err := s.billTariffZones.GetByBillID(ctx, bill.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tariff zones: %w", err)
+	}
+	if len(zones) > 0 {
+		found := false
+		for _, zone := range zones {
+			if zone.Zone == meter.TariffZone {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("bill has no tariff zone %q", meter.TariffZone)
+		}
+	}
+	return meter, nil
 }
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+// Credit entry kinds
+const (
+	CreditKindOverpayment = "overpayment"
+	CreditKindApplied     = "applied"
+)
+
+type CreditService struct {
+	credits           repository.CreditEntryRepository
+	payments          repository.PaymentRepository
+	users             repository.UserRepository
+	allocationService *AllocationService
+}
+
+func NewCreditService(
+	credits repository.CreditEntryRepository,
+	payments repository.PaymentRepository,
+	users repository.UserRepository,
+	allocationService *AllocationService,
+) *CreditService {
+	return &CreditService{
+		credits:           credits,
+		payments:          payments,
+		users:             users,
+		allocationService: allocationService,
+	}
+}
+
+// BillCoverage is what each user put towards one bill, keyed by user ID
+type BillCoverage struct {
+	Paid          map[string]utils.Money // payments minus overpayments moved to credit, plus credit applied
+	CreditApplied map[string]utils.Money // part of Paid that came from earlier credit
+	Overpaid      map[string]utils.Money // payments moved to the user's credit
+}
+
+// Total returns the amount covered on the bill by everyone
+func (c *BillCoverage) Total() utils.Money {
+	var total utils.Money
+	for _, paid := range c.Paid {
+		total = total.Add(paid)
+	}
+	return total
+}
+
+// billCoverage nets a bill's payments against its credit entries. Credit entries record the
+// change of the user's credit, so an overpayment (positive) is taken off the bill and applied
+// credit (negative) counts towards it.
+func billCoverage(payments []models.Payment, credits []models.CreditEntry) *BillCoverage {
+	coverage := &BillCoverage{
+		Paid:          make(map[string]utils.Money),
+		CreditApplied: make(map[string]utils.Money),
+		Overpaid:      make(map[string]utils.Money),
+	}
+	for _, payment := range payments {
+		coverage.Paid[payment.PayerUserID] = coverage.Paid[payment.PayerUserID].Add(utils.MoneyFromString(payment.AmountPLN))
+	}
+	for _, entry := range credits {
+		amount := utils.MoneyFromString(entry.Amount)
+		coverage.Paid[entry.UserID] = coverage.Paid[entry.UserID].Sub(amount)
+		switch entry.Kind {
+		case CreditKindApplied:
+			coverage.CreditApplied[entry.UserID] = coverage.CreditApplied[entry.UserID].Sub(amount)
+		case CreditKindOverpayment:
+			coverage.Overpaid[entry.UserID] = coverage.Overpaid[entry.UserID].Add(amount)
+		}
+	}
+	return coverage
+}
+
+// GetBillCoverage returns how much each user has covered of a bill, credit included
+func (s *CreditService) GetBillCoverage(ctx context.Context, billID string) (*BillCoverage, error) {
+	payments, err := s.payments.ListByBillID(ctx, billID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payments: %w", err)
+	}
+	credits, err := s.credits.ListByBillID(ctx, billID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credit entries: %w", err)
+	}
+	return billCoverage(payments, credits), nil
+}
+
+// OpenBillShare is an allocation of a bill that is not fully paid yet
+type OpenBillShare struct {
+	SubjectID   string
+	SubjectType string // "user" or "group"
+	SubjectName string
+	PayerIDs    []string // users whose payments count towards the share
+	Allocated   utils.Money
+	Remaining   utils.Money
+}
+
+// GetOpenShares returns the allocations of a bill that still have something left to pay
+func (s *CreditService) GetOpenShares(ctx context.Context, billID string) ([]OpenBillShare, error) {
+	breakdown, err := s.allocationService.GetAllocationBreakdown(ctx, billID)
+	if err != nil {
+		return nil, err
+	}
+	coverage, err := s.GetBillCoverage(ctx, billID)
+	if err != nil {
+		return nil, err
+	}
+
+	var shares []OpenBillShare
+	for _, entry := range breakdown {
+		payers := []string{entry.SubjectID}
+		if entry.SubjectType == "group" {
+			members, err := s.users.ListByGroupID(ctx, entry.SubjectID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get group members: %w", err)
+			}
+			payers = payers[:0]
+			for _, m := range members {
+				payers = append(payers, m.ID)
+			}
+		}
+
+		remaining := entry.Amount
+		for _, userID := range payers {
+			remaining = remaining.Sub(coverage.Paid[userID])
+		}
+		if !remaining.IsPositive() {
+			continue
+		}
+
+		shares = append(shares, OpenBillShare{
+			SubjectID:   entry.SubjectID,
+			SubjectType: entry.SubjectType,
+			SubjectName: entry.SubjectName,
+			PayerIDs:    payers,
+			Allocated:   entry.Amount,
+			Remaining:   remaining,
+		})
+	}
+	return shares, nil
+}
+
+// RecordOverpayment moves the part of a just recorded payment that exceeds the bill total
+// to the payer's credit. Returns nil when the payment did not overpay the bill.
+func (s *CreditService) RecordOverpayment(ctx context.Context, bill *models.Bill, payment *models.Payment) (*models.CreditEntry, error) {
+	coverage, err := s.GetBillCoverage(ctx, bill.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	excess := coverage.Total().Sub(utils.MoneyFromString(bill.TotalAmountPLN))
+	if !excess.IsPositive() {
+		return nil, nil
+	}
+	excess = utils.MinMoney(excess, utils.MoneyFromString(payment.AmountPLN))
+
+	entry := &models.CreditEntry{
+		UserID:    payment.PayerUserID,
+		BillID:    &bill.ID,
+		PaymentID: &payment.ID,
+		Kind:      CreditKindOverpayment,
+		Amount:    excess.String(),
+		Currency:  bill.Currency,
+	}
+	if err := s.credits.Create(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to record credit: %w", err)
+	}
+
+	log.Printf("[CREDIT] Overpayment of %s %s on bill %s credited to user %s", excess, bill.Currency, bill.ID, payment.PayerUserID)
+	return entry, nil
+}
+
+// GetBalances returns a user's available credit per currency
+func (s *CreditService) GetBalances(ctx context.Context, userID string) (map[string]utils.Money, error) {
+	entries, err := s.credits.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credit entries: %w", err)
+	}
+
+	balances := make(map[string]utils.Money)
+	for _, entry := range entries {
+		balances[entry.Currency] = balances[entry.Currency].Add(utils.MoneyFromString(entry.Amount))
+	}
+	return balances, nil
+}
+
+// ApplyCredit covers a bill's allocations with credit its users built up on earlier bills.
+// Credit applied to the bill before is released first, so calling it again after the
+// allocations changed gives the same result as applying to the new allocations once.
+func (s *CreditService) ApplyCredit(ctx context.Context, bill *models.Bill) error {
+	if err := s.ReleaseCredit(ctx, bill.ID); err != nil {
+		return err
+	}
+
+	breakdown, err := s.allocationService.GetAllocationBreakdown(ctx, bill.ID)
+	if err != nil {
+		return err
+	}
+	coverage, err := s.GetBillCoverage(ctx, bill.ID)
+	if err != nil {
+		return err
+	}
+
+	var applied utils.Money
+	for _, entry := range breakdown {
+		members, err := s.shareMembers(ctx, entry)
+		if err != nil {
+			return err
+		}
+
+		owed := entry.Amount
+		for _, userID := range members {
+			owed = owed.Sub(coverage.Paid[userID])
+		}
+
+		for _, userID := range members {
+			if !owed.IsPositive() {
+				break
+			}
+			balances, err := s.GetBalances(ctx, userID)
+			if err != nil {
+				return err
+			}
+			available := balances[bill.Currency]
+			if !available.IsPositive() {
+				continue
+			}
+
+			use := utils.MinMoney(available, owed)
+			if err := s.credits.Create(ctx, &models.CreditEntry{
+				UserID:   userID,
+				BillID:   &bill.ID,
+				Kind:     CreditKindApplied,
+				Amount:   use.Neg().String(),
+				Currency: bill.Currency,
+			}); err != nil {
+				return fmt.Errorf("failed to apply credit: %w", err)
+			}
+			owed = owed.Sub(use)
+			applied = applied.Add(use)
+		}
+	}
+
+	if applied.IsPositive() {
+		log.Printf("[CREDIT] Applied %s %s of credit to bill %s", applied, bill.Currency, bill.ID)
+	}
+	return nil
+}
+
+// RecordShareOverpayments moves what users paid on a bill above their shares to their credit, e.g. after
+// an amendment lowered the shares. Payments of users left without a share move in full. Credit applied
+// to the bill is released first; ApplyCredit covers what is still owed afterwards.
+func (s *CreditService) RecordShareOverpayments(ctx context.Context, bill *models.Bill) error {
+	if err := s.ReleaseCredit(ctx, bill.ID); err != nil {
+		return err
+	}
+
+	breakdown, err := s.allocationService.GetAllocationBreakdown(ctx, bill.ID)
+	if err != nil {
+		return err
+	}
+	coverage, err := s.GetBillCoverage(ctx, bill.ID)
+	if err != nil {
+		return err
+	}
+
+	excess := make(map[string]utils.Money)
+	for userID, paid := range coverage.Paid {
+		excess[userID] = paid
+	}
+	for _, entry := range breakdown {
+		members, err := s.shareMembers(ctx, entry)
+		if err != nil {
+			return err
+		}
+		// The share takes what its members paid in turn; what none of its shares take is overpaid
+		owed := entry.Amount
+		for _, userID := range members {
+			take := utils.MinMoney(owed, excess[userID])
+			if !take.IsPositive() {
+				continue
+			}
+			excess[userID] = excess[userID].Sub(take)
+			owed = owed.Sub(take)
+		}
+	}
+
+	userIDs := make([]string, 0, len(excess))
+	for userID := range excess {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Strings(userIDs)
+	for _, userID := range userIDs {
+		amount := excess[userID]
+		if !amount.IsPositive() {
+			continue
+		}
+		if err := s.credits.Create(ctx, &models.CreditEntry{
+			UserID:   userID,
+			BillID:   &bill.ID,
+			Kind:     CreditKindOverpayment,
+			Amount:   amount.String(),
+			Currency: bill.Currency,
+		}); err != nil {
+			return fmt.Errorf("failed to record credit: %w", err)
+		}
+		log.Printf("[CREDIT] Overpayment of %s %s on bill %s credited to user %s", amount, bill.Currency, bill.ID, userID)
+	}
+	return nil
+}
+
+// shareMembers returns the users whose payments count towards a share, sorted by ID
+func (s *CreditService) shareMembers(ctx context.Context, entry AllocationBreakdown) ([]string, error) {
+	if entry.SubjectType != "group" {
+		return []string{entry.SubjectID}, nil
+	}
+	groupUsers, err := s.users.ListByGroupID(ctx, entry.SubjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group members: %w", err)
+	}
+	members := make([]string, len(groupUsers))
+	for i, user := range groupUsers {
+		members[i] = user.ID
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// ReleaseCredit gives the credit applied to a bill back to its users
+func (s *CreditService) ReleaseCredit(ctx context.Context, billID string) error {
+	if err := s.credits.DeleteAppliedByBillID(ctx, billID); err != nil {
+		return fmt.Errorf("failed to release applied credit: %w", err)
+	}
+	return nil
+}
+
+// CreditStatementEntry is a ledger entry with the balance it left in its currency
+type CreditStatementEntry struct {
+	models.CreditEntry
+	Balance utils.Money `json:"balance"`
+}
+
+// CreditStatement lists a user's credit movements with a running balance
+type CreditStatement struct {
+	UserID   string                 `json:"userId"`
+	Balances map[string]utils.Money `json:"balances"` // available credit per currency
+	Entries  []CreditStatementEntry `json:"entries"`  // oldest first
+}
+
+// GetStatement returns a user's credit statement
+func (s *CreditService) GetStatement(ctx context.Context, userID string) (*CreditStatement, error) {
+	entries, err := s.credits.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credit entries: %w", err)
+	}
+
+	statement := &CreditStatement{
+		UserID:   userID,
+		Balances: make(map[string]utils.Money),
+		Entries:  make([]CreditStatementEntry, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		balance := statement.Balances[entry.Currency].Add(utils.MoneyFromString(entry.Amount))
+		statement.Balances[entry.Currency] = balance
+		statement.Entries = append(statement.Entries, CreditStatementEntry{
+			CreditEntry: entry,
+			Balance:     balance,
+		})
+	}
+	return statement, nil
+}
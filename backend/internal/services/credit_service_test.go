@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/config"
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBillCoverage(t *testing.T) {
+	payments := []models.Payment{
+		{PayerUserID: "alice", AmountPLN: "50.00"},
+		{PayerUserID: "bob", AmountPLN: "70.00"},
+		{PayerUserID: "bob", AmountPLN: "-10.00"}, // settle-up correction
+	}
+	credits := []models.CreditEntry{
+		{UserID: "bob", Kind: CreditKindOverpayment, Amount: "10.00"},
+		{UserID: "carol", Kind: CreditKindApplied, Amount: "-30.00"},
+	}
+
+	coverage := billCoverage(payments, credits)
+	assert.Equal(t, utils.Money(5000), coverage.Paid["alice"])
+	assert.Equal(t, utils.Money(5000), coverage.Paid["bob"])
+	assert.Equal(t, utils.Money(1000), coverage.Overpaid["bob"])
+	assert.Equal(t, utils.Money(3000), coverage.Paid["carol"])
+	assert.Equal(t, utils.Money(3000), coverage.CreditApplied["carol"])
+	assert.Equal(t, utils.Money(13000), coverage.Total())
+}
+
+// newTestCreditServices wires the services taking part in recording payments and applying credit
+func newTestCreditServices(repos *repository.Repositories) (*BillService, *PaymentService, *RecurringBillService) {
+	return newTestCreditServicesWithTransitions(repos, nil)
+}
+
+func newTestCreditServicesWithTransitions(repos *repository.Repositories, transitions []BillTransitionRule) (*BillService, *PaymentService, *RecurringBillService) {
+	currencyService := NewCurrencyService(repos.ExchangeRates, NewAppSettingsService(repos.AppSettings))
+	allocationService := NewAllocationService(repos.Users, repos.Residencies, repos.BillResidents, repos.Groups, repos.Consumptions, repos.Allocations,
+		repos.Bills, repos.BillSplits, repos.BillItems, repos.BillTariffZones, repos.Meters)
+	creditService := NewCreditService(repos.CreditEntries, repos.Payments, repos.Users, allocationService)
+	billService := NewBillService(repos.Bills, repos.Consumptions, repos.Allocations, repos.Payments, repos.Users,
+		repos.Groups, repos.BillSplits, repos.BillItems, repos.BillTariffZones, repos.BillTransitions, repos.BillVersions, repos.BankAccounts, repos.TxManager, newTestNotificationService(repos), currencyService,
+		allocationService, creditService, transitions)
+	recurringBillService := NewRecurringBillService(repos.RecurringBillTemplates, repos.RecurringBillAllocations, repos.Bills,
+		repos.Allocations, repos.Users, creditService, currencyService, &config.Config{})
+	paymentService := NewPaymentService(repos.Payments, repos.Bills, repos.TxManager, creditService, recurringBillService)
+	return billService, paymentService, recurringBillService
+}
+
+func TestOverpaymentCarriesForward(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	billService, paymentService, recurringBillService := newTestCreditServices(repos)
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+
+	// 100.00 split evenly; Alice pays 50.00 and Bob 70.00, so 20.00 becomes Bob's credit
+	bill, err := billService.CreateBill(ctx, CreateBillRequest{
+		Type:           "internet",
+		PeriodStart:    time.Now().AddDate(0, -1, 0),
+		PeriodEnd:      time.Now(),
+		TotalAmountPLN: utils.NewMoney(100, 0),
+	}, alice.ID)
+	require.NoError(t, err)
+	require.NoError(t, billService.PostBill(ctx, bill.ID))
+
+	_, err = paymentService.RecordPayment(ctx, RecordPaymentRequest{BillID: bill.ID, Amount: utils.NewMoney(50, 0)}, alice.ID)
+	require.NoError(t, err)
+	_, err = paymentService.RecordPayment(ctx, RecordPaymentRequest{BillID: bill.ID, Amount: utils.NewMoney(70, 0)}, bob.ID)
+	require.NoError(t, err)
+
+	statement, err := paymentService.GetUserCreditStatement(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Len(t, statement.Entries, 1)
+	assert.Equal(t, CreditKindOverpayment, statement.Entries[0].Kind)
+	assert.Equal(t, utils.Money(2000), statement.Balances[bill.Currency])
+
+	// The next recurring bill uses the credit as soon as it is generated
+	fixed := "30.00"
+	template := &models.RecurringBillTemplate{
+		CustomType: "Netflix",
+		Frequency:  "monthly",
+		Amount:     "60.00",
+		DayOfMonth: 10,
+		StartDate:  time.Now(),
+		Allocations: []models.RecurringBillAllocation{
+			{SubjectType: "user", SubjectID: alice.ID, AllocationType: "fixed", FixedAmount: &fixed},
+			{SubjectType: "user", SubjectID: bob.ID, AllocationType: "fixed", FixedAmount: &fixed},
+		},
+	}
+	require.NoError(t, recurringBillService.CreateTemplate(ctx, template))
+	generated, err := repos.RecurringBillTemplates.GetByID(ctx, template.ID)
+	require.NoError(t, err)
+	require.NotNil(t, generated.CurrentBillID)
+
+	// Posting re-applies the credit instead of using it twice
+	require.NoError(t, billService.PostBill(ctx, *generated.CurrentBillID))
+
+	status, err := billService.GetBillPaymentStatus(ctx, *generated.CurrentBillID)
+	require.NoError(t, err)
+	require.Len(t, status, 2)
+	for _, entry := range status {
+		if entry.SubjectID == bob.ID {
+			assert.Equal(t, "20.00", entry.CreditAppliedPLN)
+			assert.Equal(t, "10.00", entry.RemainingPLN)
+		} else {
+			assert.Equal(t, "0.00", entry.CreditAppliedPLN)
+			assert.Equal(t, "30.00", entry.RemainingPLN)
+		}
+	}
+
+	statement, err = paymentService.GetUserCreditStatement(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Len(t, statement.Entries, 2)
+	assert.Equal(t, utils.Money(2000), statement.Entries[0].Balance)
+	assert.Equal(t, utils.Money(0), statement.Entries[1].Balance)
+	assert.True(t, statement.Balances[bill.Currency].IsZero())
+
+	// Deleting the bill gives the credit back
+	require.NoError(t, billService.DeleteBill(ctx, *generated.CurrentBillID))
+	statement, err = paymentService.GetUserCreditStatement(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, utils.Money(2000), statement.Balances[bill.Currency])
+}
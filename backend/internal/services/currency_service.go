@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+// DefaultCurrency is used when the household has not chosen a base currency
+const DefaultCurrency = "PLN"
+
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// ErrExchangeRateNotFound is returned when no rate is known for a currency at a given date
+var ErrExchangeRateNotFound = errors.New("exchange rate not found")
+
+type CurrencyService struct {
+	exchangeRates      repository.ExchangeRateRepository
+	appSettingsService *AppSettingsService
+}
+
+func NewCurrencyService(exchangeRates repository.ExchangeRateRepository, appSettingsService *AppSettingsService) *CurrencyService {
+	return &CurrencyService{
+		exchangeRates:      exchangeRates,
+		appSettingsService: appSettingsService,
+	}
+}
+
+// NormalizeCurrency upper-cases a currency code and checks it looks like ISO 4217 (e.g. "eur" -> "EUR")
+func NormalizeCurrency(code string) (string, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(code))
+	if !currencyCodePattern.MatchString(normalized) {
+		return "", fmt.Errorf("invalid currency code: %q", code)
+	}
+	return normalized, nil
+}
+
+// FormatAmount formats an amount for notifications, e.g. "12.30 zł" or "12.30 EUR"
+func FormatAmount(amount utils.Money, currency string) string {
+	if currency == "" || currency == "PLN" {
+		return amount.String() + " zł"
+	}
+	return amount.String() + " " + currency
+}
+
+// BaseCurrency returns the household base currency used for balances and reports
+func (s *CurrencyService) BaseCurrency(ctx context.Context) (string, error) {
+	settings, err := s.appSettingsService.GetSettings(ctx)
+	if err != nil {
+		return "", err
+	}
+	if settings.BaseCurrency == "" {
+		return DefaultCurrency, nil
+	}
+	return settings.BaseCurrency, nil
+}
+
+// ResolveCurrency normalizes a currency code from a request, defaulting to the base currency when empty
+func (s *CurrencyService) ResolveCurrency(ctx context.Context, code string) (string, error) {
+	if strings.TrimSpace(code) == "" {
+		return s.BaseCurrency(ctx)
+	}
+	return NormalizeCurrency(code)
+}
+
+// Convert converts an amount to the base currency using the latest rate on or before the given time
+func (s *CurrencyService) Convert(ctx context.Context, amount utils.Money, currency string, at time.Time) (utils.Money, error) {
+	base, err := s.BaseCurrency(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if currency == "" || currency == base {
+		return amount, nil
+	}
+
+	rate, err := s.exchangeRates.GetLatest(ctx, currency, at)
+	if err != nil {
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+	if rate == nil {
+		return 0, fmt.Errorf("%w: %s to %s on %s", ErrExchangeRateNotFound, currency, base, at.Format("2006-01-02"))
+	}
+
+	converted, err := amount.MulDecimal(rate.Rate)
+	if err != nil {
+		return 0, fmt.Errorf("invalid exchange rate for %s: %w", currency, err)
+	}
+	return converted, nil
+}
+
+// EnsureConvertible checks that amounts in the currency can be converted to the base currency at the given time
+func (s *CurrencyService) EnsureConvertible(ctx context.Context, currency string, at time.Time) error {
+	_, err := s.Convert(ctx, 0, currency, at)
+	return err
+}
+
+// ListRates returns all exchange rates
+func (s *CurrencyService) ListRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	rates, err := s.exchangeRates.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return rates, nil
+}
+
+// SetRate records how much one unit of currency is worth in the base currency from the given day on.
+// Setting a rate for a day that already has one replaces it.
+func (s *CurrencyService) SetRate(ctx context.Context, currency, rate string, rateDate time.Time) (*models.ExchangeRate, error) {
+	code, err := NormalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := s.BaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if code == base {
+		return nil, errors.New("cannot set an exchange rate for the base currency")
+	}
+
+	// Rates are kept as exact decimal strings so conversions never go through float64
+	normalizedRate := strings.Replace(strings.TrimSpace(rate), ",", ".", 1)
+	value, ok := new(big.Rat).SetString(normalizedRate)
+	if !ok || strings.ContainsAny(normalizedRate, "/eE") {
+		return nil, errors.New("invalid exchange rate")
+	}
+	if value.Sign() <= 0 {
+		return nil, errors.New("exchange rate must be positive")
+	}
+
+	exchangeRate := &models.ExchangeRate{
+		Currency: code,
+		Rate:     normalizedRate,
+		RateDate: time.Date(rateDate.Year(), rateDate.Month(), rateDate.Day(), 0, 0, 0, 0, time.UTC),
+	}
+
+	if err := s.exchangeRates.Upsert(ctx, exchangeRate); err != nil {
+		return nil, fmt.Errorf("failed to save exchange rate: %w", err)
+	}
+
+	exchangeRate.CreatedAt = time.Now()
+	return exchangeRate, nil
+}
+
+// DeleteRate deletes an exchange rate
+func (s *CurrencyService) DeleteRate(ctx context.Context, id string) error {
+	rate, err := s.exchangeRates.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if rate == nil {
+		return errors.New("exchange rate not found")
+	}
+
+	if err := s.exchangeRates.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete exchange rate: %w", err)
+	}
+	return nil
+}
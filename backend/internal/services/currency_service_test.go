@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeCurrency(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"Upper case", "EUR", "EUR", false},
+		{"Lower case", "eur", "EUR", false},
+		{"Surrounding spaces", " pln ", "PLN", false},
+		{"Too short", "EU", "", true},
+		{"Too long", "EURO", "", true},
+		{"Digits", "E1R", "", true},
+		{"Empty", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeCurrency(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "12.30 zł", FormatAmount(utils.NewMoney(12, 30), "PLN"))
+	assert.Equal(t, "12.30 zł", FormatAmount(utils.NewMoney(12, 30), ""))
+	assert.Equal(t, "12.30 EUR", FormatAmount(utils.NewMoney(12, 30), "EUR"))
+}
@@ -0,0 +1,486 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+// Dispute statuses
+const (
+	DisputeStatusOpen     = "open"
+	DisputeStatusAccepted = "accepted"
+	DisputeStatusRejected = "rejected"
+)
+
+// DisputeReviewPermission lets a user resolve disputes and be notified of new ones
+const DisputeReviewPermission = "bills.disputes.resolve"
+
+var (
+	ErrDisputeNotFound    = errors.New("dispute not found")
+	ErrDisputeNotOpen     = errors.New("dispute is already resolved")
+	ErrDisputeAlreadyOpen = errors.New("this share is already disputed")
+	ErrDisputesOpen       = errors.New("bill has open disputes")
+	ErrNoBillShare        = errors.New("you have no share in this bill")
+)
+
+type DisputeService struct {
+	disputes            repository.BillDisputeRepository
+	bills               repository.BillRepository
+	allocations         repository.AllocationRepository
+	users               repository.UserRepository
+	txManager           repository.TxManager
+	billService         *BillService
+	allocationService   *AllocationService
+	creditService       *CreditService
+	roleService         *RoleService
+	notificationService *NotificationService
+}
+
+func NewDisputeService(
+	disputes repository.BillDisputeRepository,
+	bills repository.BillRepository,
+	allocations repository.AllocationRepository,
+	users repository.UserRepository,
+	txManager repository.TxManager,
+	billService *BillService,
+	allocationService *AllocationService,
+	creditService *CreditService,
+	roleService *RoleService,
+	notificationService *NotificationService,
+) *DisputeService {
+	s := &DisputeService{
+		disputes:            disputes,
+		bills:               bills,
+		allocations:         allocations,
+		users:               users,
+		txManager:           txManager,
+		billService:         billService,
+		allocationService:   allocationService,
+		creditService:       creditService,
+		roleService:         roleService,
+		notificationService: notificationService,
+	}
+	// A disputed bill stays on hold until all disputes of its shares are resolved
+	billService.GuardTransition(s.checkNoOpenDisputes, BillTransitionResolveDispute, BillTransitionReopen)
+	billService.OnTransition(s.notifyBillDisputed, BillTransitionDispute)
+	return s
+}
+
+type OpenDisputeRequest struct {
+	Reason         string      `json:"reason"`
+	ProposedAmount utils.Money `json:"proposedAmount"` // the share the user thinks is right, in the bill currency
+}
+
+type ResolveDisputeRequest struct {
+	Accept bool         `json:"accept"`
+	Amount *utils.Money `json:"amount,omitempty"` // share to settle on when accepting, defaults to the proposed amount
+	Note   string       `json:"note"`
+}
+
+// DisputeResolution is a resolved dispute with the allocations it changed
+type DisputeResolution struct {
+	Dispute *models.BillDispute `json:"dispute"`
+	Before  map[string]string   `json:"before,omitempty"` // allocated amount by subject ID before the change
+	After   map[string]string   `json:"after,omitempty"`  // allocated amount by subject ID after the change
+}
+
+// OpenDispute objects to the share of a posted bill the user pays, alone or with their group.
+// The first open dispute moves the bill to disputed status, which holds it from closing.
+func (s *DisputeService) OpenDispute(ctx context.Context, billID string, req OpenDisputeRequest, actor BillActor) (*models.BillDispute, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+	if req.ProposedAmount.IsNegative() {
+		return nil, errors.New("proposed amount cannot be negative")
+	}
+
+	var dispute *models.BillDispute
+	var bill *models.Bill
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		bill, err = s.bills.GetByID(ctx, billID)
+		if err != nil || bill == nil {
+			return errors.New("bill not found")
+		}
+		if bill.Status != BillStatusPosted && bill.Status != BillStatusAwaitingPayment && bill.Status != BillStatusDisputed {
+			return fmt.Errorf("%w: only posted bills that are not closed can be disputed", ErrBillTransitionNotAllowed)
+		}
+
+		breakdown, err := s.allocationService.GetAllocationBreakdown(ctx, billID)
+		if err != nil {
+			return err
+		}
+		share, err := s.userShare(ctx, breakdown, actor.UserID)
+		if err != nil {
+			return err
+		}
+		var total utils.Money
+		for _, entry := range breakdown {
+			total = total.Add(entry.Amount)
+		}
+		if req.ProposedAmount > total {
+			return errors.New("proposed amount cannot exceed the bill total")
+		}
+		if req.ProposedAmount == share.Amount {
+			return errors.New("proposed amount is the same as the current share")
+		}
+
+		existing, err := s.disputes.ListByBillID(ctx, billID)
+		if err != nil {
+			return fmt.Errorf("failed to get disputes: %w", err)
+		}
+		for _, d := range existing {
+			if d.Status == DisputeStatusOpen && d.SubjectType == share.SubjectType && d.SubjectID == share.SubjectID {
+				return ErrDisputeAlreadyOpen
+			}
+		}
+
+		dispute = &models.BillDispute{
+			BillID:          billID,
+			SubjectType:     share.SubjectType,
+			SubjectID:       share.SubjectID,
+			OpenedBy:        actor.UserID,
+			Reason:          reason,
+			AllocatedAmount: share.Amount.String(),
+			ProposedAmount:  req.ProposedAmount.String(),
+			Status:          DisputeStatusOpen,
+		}
+		if err := s.disputes.Create(ctx, dispute); err != nil {
+			return fmt.Errorf("failed to create dispute: %w", err)
+		}
+
+		if bill.Status == BillStatusDisputed {
+			return nil
+		}
+		_, err = s.billService.TransitionBill(ctx, billID, BillTransitionRequest{
+			Transition: BillTransitionDispute,
+			Reason:     reason,
+			Actor:      actor,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[BILL] Dispute opened: bill=%s, subject=%s %s, share %s, proposed %s (ID: %s, by user %s)",
+		billID, dispute.SubjectType, dispute.SubjectID, dispute.AllocatedAmount, dispute.ProposedAmount, dispute.ID, actor.UserID)
+
+	s.notifyReviewers(ctx, bill, dispute, actor.UserID)
+	return dispute, nil
+}
+
+// ResolveDispute accepts or rejects an open dispute. Accepting it sets the disputed share to the
+// agreed amount and spreads the difference over the other shares in proportion to them, so the
+// bill total stays the same. Once the bill's last dispute is resolved it awaits payment again.
+func (s *DisputeService) ResolveDispute(ctx context.Context, disputeID string, req ResolveDisputeRequest, actor BillActor) (*DisputeResolution, error) {
+	note := strings.TrimSpace(req.Note)
+	if !req.Accept && note == "" {
+		return nil, errors.New("a note is required to reject a dispute")
+	}
+
+	resolution := &DisputeResolution{}
+	var bill *models.Bill
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		dispute, err := s.disputes.GetByID(ctx, disputeID)
+		if err != nil {
+			return fmt.Errorf("failed to get dispute: %w", err)
+		}
+		if dispute == nil {
+			return ErrDisputeNotFound
+		}
+		if dispute.Status != DisputeStatusOpen {
+			return ErrDisputeNotOpen
+		}
+		bill, err = s.bills.GetByID(ctx, dispute.BillID)
+		if err != nil || bill == nil {
+			return errors.New("bill not found")
+		}
+
+		now := time.Now()
+		dispute.Status = DisputeStatusRejected
+		dispute.ResolvedBy = &actor.UserID
+		dispute.ResolvedAt = &now
+		if note != "" {
+			dispute.ResolutionNote = &note
+		}
+
+		if req.Accept {
+			amount := utils.MoneyFromString(dispute.ProposedAmount)
+			if req.Amount != nil {
+				amount = *req.Amount
+			}
+			before, after, err := s.adjustShare(ctx, bill, dispute, amount)
+			if err != nil {
+				return err
+			}
+			resolution.Before, resolution.After = before, after
+			resolved := amount.String()
+			dispute.Status = DisputeStatusAccepted
+			dispute.ResolvedAmount = &resolved
+		}
+
+		if err := s.disputes.Update(ctx, dispute); err != nil {
+			return fmt.Errorf("failed to update dispute: %w", err)
+		}
+		resolution.Dispute = dispute
+
+		open, err := s.openDisputes(ctx, bill.ID)
+		if err != nil {
+			return err
+		}
+		if len(open) > 0 || bill.Status != BillStatusDisputed {
+			return nil
+		}
+		bill, err = s.billService.TransitionBill(ctx, bill.ID, BillTransitionRequest{
+			Transition: BillTransitionResolveDispute,
+			Reason:     note,
+			Actor:      actor,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dispute := resolution.Dispute
+	log.Printf("[BILL] Dispute %s: ID=%s, bill=%s, subject=%s %s (by user %s)",
+		dispute.Status, dispute.ID, dispute.BillID, dispute.SubjectType, dispute.SubjectID, actor.UserID)
+
+	s.notifyResolution(ctx, bill, dispute)
+	return resolution, nil
+}
+
+// GetBillDisputes returns the disputes of a bill, oldest first
+func (s *DisputeService) GetBillDisputes(ctx context.Context, billID string) ([]models.BillDispute, error) {
+	bill, err := s.bills.GetByID(ctx, billID)
+	if err != nil || bill == nil {
+		return nil, errors.New("bill not found")
+	}
+	return s.disputes.ListByBillID(ctx, billID)
+}
+
+// GetDisputes returns the disputes in a status across all bills, oldest first
+func (s *DisputeService) GetDisputes(ctx context.Context, status string) ([]models.BillDispute, error) {
+	switch status {
+	case DisputeStatusOpen, DisputeStatusAccepted, DisputeStatusRejected:
+	default:
+		return nil, fmt.Errorf("invalid dispute status: %s", status)
+	}
+	return s.disputes.ListByStatus(ctx, status)
+}
+
+// userShare finds the allocation a user pays: their own, or their group's
+func (s *DisputeService) userShare(ctx context.Context, breakdown []AllocationBreakdown, userID string) (*AllocationBreakdown, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, errors.New("user not found")
+	}
+	for i, entry := range breakdown {
+		if entry.SubjectType == "user" && entry.SubjectID == user.ID {
+			return &breakdown[i], nil
+		}
+	}
+	if user.GroupID != nil {
+		for i, entry := range breakdown {
+			if entry.SubjectType == "group" && entry.SubjectID == *user.GroupID {
+				return &breakdown[i], nil
+			}
+		}
+	}
+	return nil, ErrNoBillShare
+}
+
+// adjustShare stores the bill's allocations with the disputed share set to amount and covers them with
+// credit again. Returns the allocated amounts by subject before and after the change.
+func (s *DisputeService) adjustShare(ctx context.Context, bill *models.Bill, dispute *models.BillDispute, amount utils.Money) (map[string]string, map[string]string, error) {
+	if amount.IsNegative() {
+		return nil, nil, errors.New("amount cannot be negative")
+	}
+
+	breakdown, err := s.allocationService.GetAllocationBreakdown(ctx, bill.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	adjusted, err := redistributeShare(breakdown, dispute.SubjectType, dispute.SubjectID, amount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	before := make(map[string]string, len(breakdown))
+	for _, entry := range breakdown {
+		before[entry.SubjectID] = entry.Amount.String()
+	}
+	after := make(map[string]string, len(adjusted))
+	for _, entry := range adjusted {
+		after[entry.SubjectID] = entry.Amount.String()
+	}
+
+	if err := s.allocations.DeleteByBillID(ctx, bill.ID); err != nil {
+		return nil, nil, fmt.Errorf("failed to delete allocations: %w", err)
+	}
+	for _, entry := range adjusted {
+		if err := s.allocations.Create(ctx, bill.ID, entry.SubjectType, entry.SubjectID, entry.Amount.String()); err != nil {
+			return nil, nil, fmt.Errorf("failed to create allocation: %w", err)
+		}
+	}
+	if err := s.creditService.ApplyCredit(ctx, bill); err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// redistributeShare sets one subject's share of a bill and spreads the difference over the other shares
+// in proportion to them, so the shares still add up to the same total
+func redistributeShare(breakdown []AllocationBreakdown, subjectType, subjectID string, amount utils.Money) ([]AllocationBreakdown, error) {
+	adjusted := make([]AllocationBreakdown, len(breakdown))
+	copy(adjusted, breakdown)
+
+	disputed := -1
+	var others []int
+	var weights []float64
+	var total utils.Money
+	for i, entry := range adjusted {
+		total = total.Add(entry.Amount)
+		if entry.SubjectType == subjectType && entry.SubjectID == subjectID {
+			disputed = i
+			continue
+		}
+		others = append(others, i)
+		weights = append(weights, entry.Amount.Float64())
+	}
+	if disputed < 0 {
+		return nil, errors.New("the disputed share is no longer allocated on this bill")
+	}
+	if amount > total {
+		return nil, errors.New("amount cannot exceed the bill total")
+	}
+
+	difference := adjusted[disputed].Amount.Sub(amount)
+	if difference.IsZero() {
+		return adjusted, nil
+	}
+	if len(others) == 0 {
+		return nil, errors.New("nobody else shares the bill to take over the difference")
+	}
+
+	adjusted[disputed].Amount = amount
+	for i, part := range difference.Allocate(weights) {
+		adjusted[others[i]].Amount = adjusted[others[i]].Amount.Add(part)
+	}
+	return adjusted, nil
+}
+
+// openDisputes returns the disputes of a bill that are not resolved yet
+func (s *DisputeService) openDisputes(ctx context.Context, billID string) ([]models.BillDispute, error) {
+	disputes, err := s.disputes.ListByBillID(ctx, billID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disputes: %w", err)
+	}
+	var open []models.BillDispute
+	for _, d := range disputes {
+		if d.Status == DisputeStatusOpen {
+			open = append(open, d)
+		}
+	}
+	return open, nil
+}
+
+// checkNoOpenDisputes keeps a bill on hold while disputes of its shares are open
+func (s *DisputeService) checkNoOpenDisputes(ctx context.Context, bill *models.Bill, rule BillTransitionRule) error {
+	open, err := s.openDisputes(ctx, bill.ID)
+	if err != nil {
+		return err
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("%w: %d left to resolve", ErrDisputesOpen, len(open))
+	}
+	return nil
+}
+
+// notifyBillDisputed tells reviewers about a bill disputed as a whole. Disputes of a share are
+// announced with their details when they are opened.
+func (s *DisputeService) notifyBillDisputed(ctx context.Context, event BillTransitionEvent) error {
+	open, err := s.openDisputes(ctx, event.Bill.ID)
+	if err != nil {
+		return err
+	}
+	if len(open) > 0 {
+		return nil
+	}
+	s.sendToReviewers(ctx, event.Actor.UserID, "Rachunek zakwestionowany",
+		fmt.Sprintf("Rachunek %s został zakwestionowany: %s", billLabel(event.Bill), event.Reason))
+	return nil
+}
+
+// notifyReviewers tells reviewers about a newly opened dispute
+func (s *DisputeService) notifyReviewers(ctx context.Context, bill *models.Bill, dispute *models.BillDispute, actorID string) {
+	name := "Ktoś"
+	if user, err := s.users.GetByID(ctx, dispute.OpenedBy); err == nil && user != nil {
+		name = user.Name
+	}
+	s.sendToReviewers(ctx, actorID, "Rachunek zakwestionowany",
+		fmt.Sprintf("%s kwestionuje swój udział w rachunku %s: %s %s zamiast %s %s. Powód: %s",
+			name, billLabel(bill), dispute.ProposedAmount, bill.Currency, dispute.AllocatedAmount, bill.Currency, dispute.Reason))
+}
+
+// sendToReviewers notifies every active user allowed to resolve disputes, except the one who caused it
+func (s *DisputeService) sendToReviewers(ctx context.Context, actorID, title, body string) {
+	users, err := s.users.ListActive(ctx)
+	if err != nil {
+		log.Printf("[BILL] Failed to get active users: %v", err)
+		return
+	}
+	for _, user := range users {
+		if user.ID == actorID {
+			continue
+		}
+		allowed, err := s.roleService.HasPermission(ctx, user.Role, DisputeReviewPermission)
+		if err != nil || !allowed {
+			continue
+		}
+		s.notify(ctx, user.ID, title, body)
+	}
+}
+
+// notifyResolution tells the user who opened a dispute how it was resolved
+func (s *DisputeService) notifyResolution(ctx context.Context, bill *models.Bill, dispute *models.BillDispute) {
+	if dispute.ResolvedBy != nil && *dispute.ResolvedBy == dispute.OpenedBy {
+		return
+	}
+	body := fmt.Sprintf("Twój spór dotyczący rachunku %s został odrzucony", billLabel(bill))
+	if dispute.Status == DisputeStatusAccepted {
+		body = fmt.Sprintf("Twój spór dotyczący rachunku %s został przyjęty, nowy udział: %s %s",
+			billLabel(bill), *dispute.ResolvedAmount, bill.Currency)
+	}
+	if dispute.ResolutionNote != nil {
+		body += ": " + *dispute.ResolutionNote
+	}
+	s.notify(ctx, dispute.OpenedBy, "Spór rozpatrzony", body)
+}
+
+func (s *DisputeService) notify(ctx context.Context, userID, title, body string) {
+	now := time.Now()
+	notification := &models.Notification{
+		UserID:       &userID,
+		Channel:      "app",
+		TemplateID:   "bill",
+		ScheduledFor: now,
+		SentAt:       &now,
+		Status:       "sent",
+		Title:        title,
+		Body:         body,
+	}
+	if err := s.notificationService.CreateNotification(ctx, notification); err != nil {
+		log.Printf("[BILL] Failed to notify user %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllocationDispute(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	currencyService := NewCurrencyService(repos.ExchangeRates, NewAppSettingsService(repos.AppSettings))
+	allocationService := NewAllocationService(repos.Users, repos.Residencies, repos.BillResidents, repos.Groups, repos.Consumptions, repos.Allocations,
+		repos.Bills, repos.BillSplits, repos.BillItems, repos.BillTariffZones, repos.Meters)
+	creditService := NewCreditService(repos.CreditEntries, repos.Payments, repos.Users, allocationService)
+	notificationService := newTestNotificationService(repos)
+	billService := NewBillService(repos.Bills, repos.Consumptions, repos.Allocations, repos.Payments, repos.Users,
+		repos.Groups, repos.BillSplits, repos.BillItems, repos.BillTariffZones, repos.BillTransitions, repos.BillVersions, repos.BankAccounts, repos.TxManager,
+		notificationService, currencyService, allocationService, creditService, nil)
+	disputeService := NewDisputeService(repos.BillDisputes, repos.Bills, repos.Allocations, repos.Users, repos.TxManager, billService,
+		allocationService, creditService, NewRoleService(repos.Roles, repos.Users, repos.Permissions), notificationService)
+
+	// Alice reviews disputes as an admin
+	alice := createTestUser(t, repos, "Alice")
+	alice.Role = "ADMIN"
+	require.NoError(t, repos.Users.Update(ctx, alice))
+	bob := createTestUser(t, repos, "Bob")
+	carol := createTestUser(t, repos, "Carol")
+	bobActor := BillActor{UserID: bob.ID, Email: bob.Email}
+	aliceActor := BillActor{UserID: alice.ID, Email: alice.Email}
+
+	bill, err := billService.CreateBill(ctx, CreateBillRequest{
+		Type:           "internet",
+		PeriodStart:    time.Now().AddDate(0, -1, 0),
+		PeriodEnd:      time.Now(),
+		TotalAmountPLN: utils.NewMoney(90, 0),
+	}, alice.ID)
+	require.NoError(t, err)
+
+	// Only posted bills can be disputed
+	_, err = disputeService.OpenDispute(ctx, bill.ID, OpenDisputeRequest{Reason: "I was away", ProposedAmount: utils.NewMoney(20, 0)}, bobActor)
+	assert.ErrorIs(t, err, ErrBillTransitionNotAllowed)
+	require.NoError(t, billService.PostBill(ctx, bill.ID))
+
+	_, err = disputeService.OpenDispute(ctx, bill.ID, OpenDisputeRequest{Reason: " ", ProposedAmount: utils.NewMoney(20, 0)}, bobActor)
+	assert.Error(t, err)
+	_, err = disputeService.OpenDispute(ctx, bill.ID, OpenDisputeRequest{Reason: "Too much", ProposedAmount: utils.NewMoney(91, 0)}, bobActor)
+	assert.Error(t, err)
+
+	dispute, err := disputeService.OpenDispute(ctx, bill.ID, OpenDisputeRequest{Reason: "I was away for ten days", ProposedAmount: utils.NewMoney(20, 0)}, bobActor)
+	require.NoError(t, err)
+	assert.Equal(t, "30.00", dispute.AllocatedAmount)
+	_, err = disputeService.OpenDispute(ctx, bill.ID, OpenDisputeRequest{Reason: "Still away", ProposedAmount: utils.NewMoney(10, 0)}, bobActor)
+	assert.ErrorIs(t, err, ErrDisputeAlreadyOpen)
+
+	// The bill is held until the dispute is resolved
+	stored, err := billService.GetBill(ctx, bill.ID)
+	require.NoError(t, err)
+	assert.Equal(t, BillStatusDisputed, stored.Status)
+	assert.ErrorIs(t, billService.CloseBill(ctx, bill.ID), ErrBillTransitionNotAllowed)
+	_, err = billService.TransitionBill(ctx, bill.ID, BillTransitionRequest{Transition: BillTransitionResolveDispute, Actor: aliceActor})
+	assert.ErrorIs(t, err, ErrDisputesOpen)
+
+	// Reviewers are told about it, other residents are not
+	titled := func(userID, title string) int {
+		notifications, err := repos.Notifications.ListByUserID(ctx, userID, 20)
+		require.NoError(t, err)
+		count := 0
+		for _, notification := range notifications {
+			if notification.Title == title {
+				count++
+			}
+		}
+		return count
+	}
+	assert.Equal(t, 1, titled(alice.ID, "Rachunek zakwestionowany"))
+	assert.Equal(t, 0, titled(carol.ID, "Rachunek zakwestionowany"))
+
+	// Accepting moves the difference onto the other shares
+	resolution, err := disputeService.ResolveDispute(ctx, dispute.ID, ResolveDisputeRequest{Accept: true, Note: "Confirmed"}, aliceActor)
+	require.NoError(t, err)
+	assert.Equal(t, DisputeStatusAccepted, resolution.Dispute.Status)
+	assert.Equal(t, map[string]string{alice.ID: "30.00", bob.ID: "30.00", carol.ID: "30.00"}, resolution.Before)
+	assert.Equal(t, map[string]string{alice.ID: "35.00", bob.ID: "20.00", carol.ID: "35.00"}, resolution.After)
+
+	breakdown, err := allocationService.GetAllocationBreakdown(ctx, bill.ID)
+	require.NoError(t, err)
+	shares := make(map[string]string)
+	for _, entry := range breakdown {
+		shares[entry.SubjectID] = entry.Amount.String()
+	}
+	assert.Equal(t, resolution.After, shares)
+
+	stored, err = billService.GetBill(ctx, bill.ID)
+	require.NoError(t, err)
+	assert.Equal(t, BillStatusAwaitingPayment, stored.Status)
+	assert.Equal(t, 1, titled(bob.ID, "Spór rozpatrzony"))
+
+	_, err = disputeService.ResolveDispute(ctx, dispute.ID, ResolveDisputeRequest{Accept: false, Note: "Changed my mind"}, aliceActor)
+	assert.ErrorIs(t, err, ErrDisputeNotOpen)
+
+	// A rejected dispute leaves the allocations as they are
+	dispute, err = disputeService.OpenDispute(ctx, bill.ID, OpenDisputeRequest{Reason: "Bob's discount should be shared", ProposedAmount: utils.NewMoney(30, 0)}, BillActor{UserID: carol.ID})
+	require.NoError(t, err)
+	_, err = disputeService.ResolveDispute(ctx, dispute.ID, ResolveDisputeRequest{Accept: false}, aliceActor)
+	assert.Error(t, err)
+	resolution, err = disputeService.ResolveDispute(ctx, dispute.ID, ResolveDisputeRequest{Accept: false, Note: "Agreed at the house meeting"}, aliceActor)
+	require.NoError(t, err)
+	assert.Equal(t, DisputeStatusRejected, resolution.Dispute.Status)
+	assert.Nil(t, resolution.After)
+
+	open, err := disputeService.GetDisputes(ctx, DisputeStatusOpen)
+	require.NoError(t, err)
+	assert.Empty(t, open)
+	history, err := disputeService.GetBillDisputes(ctx, bill.ID)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
+
+func TestRedistributeShare(t *testing.T) {
+	breakdown := []AllocationBreakdown{
+		{SubjectType: "user", SubjectID: "a", Amount: utils.NewMoney(50, 0)},
+		{SubjectType: "user", SubjectID: "b", Amount: utils.NewMoney(30, 0)},
+		{SubjectType: "group", SubjectID: "c", Amount: utils.NewMoney(20, 0)},
+	}
+
+	// Paying more lowers the others in proportion to their shares
+	adjusted, err := redistributeShare(breakdown, "user", "a", utils.NewMoney(60, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "60.00", adjusted[0].Amount.String())
+	assert.Equal(t, "24.00", adjusted[1].Amount.String())
+	assert.Equal(t, "16.00", adjusted[2].Amount.String())
+	assert.Equal(t, "50.00", breakdown[0].Amount.String())
+
+	_, err = redistributeShare(breakdown, "user", "c", utils.NewMoney(10, 0))
+	assert.Error(t, err)
+	_, err = redistributeShare(breakdown, "user", "a", utils.NewMoney(101, 0))
+	assert.Error(t, err)
+}
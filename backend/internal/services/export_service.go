@@ -21,6 +21,8 @@ type ExportService struct {
 	choreAssignments repository.ChoreAssignmentRepository
 	users            repository.UserRepository
 	groups           repository.GroupRepository
+	currencyService  *CurrencyService
+	ledgerService    *LedgerService
 }
 
 func NewExportService(
@@ -32,6 +34,8 @@ func NewExportService(
 	choreAssignments repository.ChoreAssignmentRepository,
 	users repository.UserRepository,
 	groups repository.GroupRepository,
+	currencyService *CurrencyService,
+	ledgerService *LedgerService,
 ) *ExportService {
 	return &ExportService{
 		bills:            bills,
@@ -42,6 +46,8 @@ func NewExportService(
 		choreAssignments: choreAssignments,
 		users:            users,
 		groups:           groups,
+		currencyService:  currencyService,
+		ledgerService:    ledgerService,
 	}
 }
 
@@ -53,19 +59,26 @@ func (s *ExportService) ExportBillsCSV(ctx context.Context, billType *string, fr
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 
+	baseCurrency, err := s.currencyService.BaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create CSV buffer
 	var buf bytes.Buffer
 	writer := csv.NewWriter(&buf)
 
 	// Write header
-	header := []string{"Bill ID", "Type", "Period Start", "Period End", "Total Amount (PLN)", "Total Units", "Status", "Notes"}
+	header := []string{"Bill ID", "Type", "Period Start", "Period End", "Total Amount", "Currency",
+		fmt.Sprintf("Total Amount (%s)", baseCurrency), "Total Units", "Status", "Notes"}
 	if err := writer.Write(header); err != nil {
 		return nil, fmt.Errorf("failed to write CSV header: %w", err)
 	}
 
 	// Write bill rows
 	for _, bill := range bills {
-		amount := utils.DecimalStringToFloat(bill.TotalAmountPLN)
+		amount := utils.MoneyFromString(bill.TotalAmountPLN)
+		baseAmount := s.convertForExport(ctx, amount, bill.Currency, bill.CreatedAt)
 
 		var units float64
 		if bill.TotalUnits != "" {
@@ -82,7 +95,9 @@ func (s *ExportService) ExportBillsCSV(ctx context.Context, billType *string, fr
 			bill.Type,
 			bill.PeriodStart.Format("2006-01-02"),
 			bill.PeriodEnd.Format("2006-01-02"),
-			fmt.Sprintf("%.2f", amount),
+			amount.String(),
+			bill.Currency,
+			baseAmount,
 			fmt.Sprintf("%.3f", units),
 			bill.Status,
 			notes,
@@ -123,23 +138,29 @@ func (s *ExportService) ExportBalancesCSV(ctx context.Context) ([]byte, error) {
 	}
 
 	// Get payments for each loan
-	loanPaymentsMap := make(map[string]float64)
+	loanPaymentsMap := make(map[string]utils.Money)
 	for _, loan := range loans {
 		payments, err := s.loanPayments.ListByLoanID(ctx, loan.ID)
 		if err == nil {
 			for _, payment := range payments {
-				amount := utils.DecimalStringToFloat(payment.AmountPLN)
-				loanPaymentsMap[loan.ID] += amount
+				loanPaymentsMap[loan.ID] += utils.MoneyFromString(payment.AmountPLN)
 			}
 		}
 	}
 
+	baseCurrency, err := s.currencyService.BaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
 	// Create CSV buffer
 	var buf bytes.Buffer
 	writer := csv.NewWriter(&buf)
 
 	// Write header
-	header := []string{"Loan ID", "Lender", "Borrower", "Original Amount (PLN)", "Paid Amount (PLN)", "Remaining (PLN)", "Status", "Created Date"}
+	header := []string{"Loan ID", "Lender", "Borrower", "Original Amount", "Paid Amount", "Remaining", "Currency",
+		fmt.Sprintf("Remaining (%s)", baseCurrency), "Status", "Created Date"}
 	if err := writer.Write(header); err != nil {
 		return nil, fmt.Errorf("failed to write CSV header: %w", err)
 	}
@@ -149,17 +170,21 @@ func (s *ExportService) ExportBalancesCSV(ctx context.Context) ([]byte, error) {
 		lenderEmail := userMap[loan.LenderID]
 		borrowerEmail := userMap[loan.BorrowerID]
 
-		originalAmount := utils.DecimalStringToFloat(loan.AmountPLN)
+		originalAmount := utils.MoneyFromString(loan.AmountPLN)
 		paidAmount := loanPaymentsMap[loan.ID]
-		remaining := originalAmount - paidAmount
+		remaining := originalAmount.Sub(paidAmount)
+		// Same conversion as LoanService.GetBalances, so the export matches the balances view
+		remainingBase := s.convertForExport(ctx, remaining, loan.Currency, now)
 
 		row := []string{
 			loan.ID,
 			lenderEmail,
 			borrowerEmail,
-			fmt.Sprintf("%.2f", originalAmount),
-			fmt.Sprintf("%.2f", paidAmount),
-			fmt.Sprintf("%.2f", remaining),
+			originalAmount.String(),
+			paidAmount.String(),
+			remaining.String(),
+			loan.Currency,
+			remainingBase,
 			loan.Status,
 			loan.CreatedAt.Format("2006-01-02"),
 		}
@@ -345,6 +370,79 @@ func (s *ExportService) ExportConsumptionsCSV(ctx context.Context, subjectID *st
 	return buf.Bytes(), nil
 }
 
+// ExportLedgerCSV exports a user's account statement with its running balance
+func (s *ExportService) ExportLedgerCSV(ctx context.Context, userID string, from *time.Time, to *time.Time) ([]byte, error) {
+	statement, err := s.ledgerService.GetStatement(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create CSV buffer
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	// Write header
+	header := []string{"Date", "Kind", "Description", "Reference ID", "Amount", "Currency",
+		fmt.Sprintf("Debit (%s)", statement.Currency), fmt.Sprintf("Credit (%s)", statement.Currency),
+		fmt.Sprintf("Balance (%s)", statement.Currency)}
+	if err := writer.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	// Carry the balance of everything before the first row
+	if from != nil {
+		row := []string{from.Format("2006-01-02"), "opening_balance", "Saldo początkowe", "", "", "", "", "",
+			statement.OpeningBalance.String()}
+		if err := writer.Write(row); err != nil {
+			return nil, fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	// Write entry rows
+	for _, entry := range statement.Entries {
+		debit, credit := "", ""
+		if !entry.Debit.IsZero() {
+			debit = entry.Debit.String()
+		}
+		if !entry.Credit.IsZero() {
+			credit = entry.Credit.String()
+		}
+
+		row := []string{
+			entry.Date.Format("2006-01-02 15:04"),
+			entry.Kind,
+			entry.Description,
+			entry.ReferenceID,
+			entry.Amount.String(),
+			entry.Currency,
+			debit,
+			credit,
+			entry.Balance.String(),
+		}
+
+		if err := writer.Write(row); err != nil {
+			return nil, fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("CSV writer error: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// convertForExport converts an amount to the base currency for a CSV cell.
+// The cell is left empty when no exchange rate is available instead of failing the whole export.
+func (s *ExportService) convertForExport(ctx context.Context, amount utils.Money, currency string, at time.Time) string {
+	converted, err := s.currencyService.Convert(ctx, amount, currency, at)
+	if err != nil {
+		return ""
+	}
+	return converted.String()
+}
+
 type billInfo struct {
 	Type        string
 	PeriodStart time.Time
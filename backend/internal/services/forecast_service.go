@@ -0,0 +1,734 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+// Rate sources reported with a forecast
+const (
+	RateSourceConfigured = "configured" // from the household's utility tariffs
+	RateSourceLearned    = "learned"    // fitted to earlier bills of the utility
+	RateSourceNone       = "none"       // no tariff and no earlier bills, costs are unknown
+)
+
+// forecastHistoryBills is how many of the latest settled bills usage and rates are learned from
+const forecastHistoryBills = 12
+
+var (
+	ErrForecastUnsupported = errors.New("forecasts are only available for metered utilities")
+	ErrTariffNotFound      = errors.New("tariff not found")
+)
+
+type ForecastService struct {
+	tariffs           repository.UtilityTariffRepository
+	bills             repository.BillRepository
+	consumptions      repository.ConsumptionRepository
+	meters            repository.MeterRepository
+	allocationService *AllocationService
+	currencyService   *CurrencyService
+}
+
+func NewForecastService(
+	tariffs repository.UtilityTariffRepository,
+	bills repository.BillRepository,
+	consumptions repository.ConsumptionRepository,
+	meters repository.MeterRepository,
+	allocationService *AllocationService,
+	currencyService *CurrencyService,
+) *ForecastService {
+	return &ForecastService{
+		tariffs:           tariffs,
+		bills:             bills,
+		consumptions:      consumptions,
+		meters:            meters,
+		allocationService: allocationService,
+		currencyService:   currencyService,
+	}
+}
+
+type SetTariffRequest struct {
+	BillType        string    `json:"billType"`
+	CustomType      string    `json:"customType,omitempty"`      // required for "inne" bills
+	TariffZone      string    `json:"tariffZone,omitempty"`      // defaults to single
+	UnitPrice       string    `json:"unitPrice"`                 // base currency per unit
+	FixedFeeMonthly string    `json:"fixedFeeMonthly,omitempty"` // base currency per month, defaults to 0
+	ValidFrom       time.Time `json:"validFrom"`                 // defaults to today
+}
+
+// BillForecast is the expected cost of a utility's current bill period, projected from the readings so far
+type BillForecast struct {
+	BillType        string            `json:"billType"`
+	CustomType      string            `json:"customType,omitempty"`
+	PeriodStart     time.Time         `json:"periodStart"`
+	PeriodEnd       time.Time         `json:"periodEnd"`
+	AsOf            time.Time         `json:"asOf"`
+	DaysElapsed     int               `json:"daysElapsed"`
+	PeriodDays      int               `json:"periodDays"`
+	Currency        string            `json:"currency"`
+	RateSource      string            `json:"rateSource"` // configured, learned or none
+	UnitPrices      []ZonePrice       `json:"unitPrices,omitempty"`
+	FixedFeeMonthly utils.Money       `json:"fixedFeeMonthly"`
+	UnitsSoFar      float64           `json:"unitsSoFar"`
+	ProjectedUnits  float64           `json:"projectedUnits"`
+	CostSoFar       utils.Money       `json:"costSoFar"`
+	ProjectedTotal  utils.Money       `json:"projectedTotal"`
+	Subjects        []SubjectForecast `json:"subjects"`
+}
+
+// ZonePrice is the unit price a forecast charges in one tariff zone
+type ZonePrice struct {
+	Zone      string  `json:"zone"`
+	UnitPrice float64 `json:"unitPrice"`
+}
+
+// SubjectForecast is a user's or group's projected usage and share of the forecast total
+type SubjectForecast struct {
+	SubjectID      string      `json:"subjectId"`
+	SubjectType    string      `json:"subjectType"`
+	SubjectName    string      `json:"subjectName"`
+	UnitsSoFar     float64     `json:"unitsSoFar"`
+	ProjectedUnits float64     `json:"projectedUnits"`
+	PersonalAmount utils.Money `json:"personalAmount"`
+	SharedAmount   utils.Money `json:"sharedAmount"`
+	Amount         utils.Money `json:"amount"`
+}
+
+// ListTariffs returns all utility tariffs
+func (s *ForecastService) ListTariffs(ctx context.Context) ([]models.UtilityTariff, error) {
+	tariffs, err := s.tariffs.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return tariffs, nil
+}
+
+// SetTariff records the price of a utility in one tariff zone from the given day on.
+// Setting a tariff for a day that already has one replaces it.
+func (s *ForecastService) SetTariff(ctx context.Context, req SetTariffRequest) (*models.UtilityTariff, error) {
+	billType, customType, err := normalizeUtility(req.BillType, req.CustomType)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unit prices often have more than two decimals, so they are kept as exact decimal strings
+	unitPrice := strings.Replace(strings.TrimSpace(req.UnitPrice), ",", ".", 1)
+	value, ok := new(big.Rat).SetString(unitPrice)
+	if !ok || strings.ContainsAny(unitPrice, "/eE") {
+		return nil, errors.New("invalid unit price")
+	}
+	if value.Sign() <= 0 {
+		return nil, errors.New("unit price must be positive")
+	}
+
+	fixedFee := utils.NewMoney(0, 0)
+	if strings.TrimSpace(req.FixedFeeMonthly) != "" {
+		fixedFee, err = utils.ParseMoney(req.FixedFeeMonthly)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fixed fee: %w", err)
+		}
+		if fixedFee.IsNegative() {
+			return nil, errors.New("fixed fee cannot be negative")
+		}
+	}
+
+	validFrom := req.ValidFrom
+	if validFrom.IsZero() {
+		validFrom = time.Now()
+	}
+
+	tariff := &models.UtilityTariff{
+		BillType:        billType,
+		CustomType:      customType,
+		TariffZone:      normalizeTariffZone(req.TariffZone),
+		UnitPrice:       unitPrice,
+		FixedFeeMonthly: fixedFee.String(),
+		ValidFrom:       truncateToDay(validFrom),
+	}
+
+	if err := s.tariffs.Upsert(ctx, tariff); err != nil {
+		return nil, fmt.Errorf("failed to save tariff: %w", err)
+	}
+
+	tariff.CreatedAt = time.Now()
+	return tariff, nil
+}
+
+// DeleteTariff deletes a utility tariff
+func (s *ForecastService) DeleteTariff(ctx context.Context, id string) error {
+	tariff, err := s.tariffs.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if tariff == nil {
+		return ErrTariffNotFound
+	}
+
+	if err := s.tariffs.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete tariff: %w", err)
+	}
+	return nil
+}
+
+// Forecast projects the bill of a metered utility for the period containing at.
+// Each subject's usage is extrapolated from their readings in the period so far, or taken from their
+// average daily usage on earlier bills when they have not read their meters yet. Common usage (units
+// on the bill nobody read) is added in the proportion seen on earlier bills. Units are priced by the
+// configured tariff, or by a unit price and fixed fee fitted to earlier bills; personal usage is
+// charged to its subject and the rest is shared by weight, as the bill will be.
+func (s *ForecastService) Forecast(ctx context.Context, billType, customType string, at time.Time) (*BillForecast, error) {
+	billType, customType, err := normalizeUtility(billType, customType)
+	if err != nil {
+		return nil, err
+	}
+
+	allBills, err := s.bills.ListByType(ctx, billType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bills: %w", err)
+	}
+	bills := allBills[:0]
+	for _, bill := range allBills {
+		if billMatchesUtility(bill, customType) {
+			bills = append(bills, bill)
+		}
+	}
+
+	periodStart, periodEnd := forecastPeriod(bills, at)
+	periodDays := daysInPeriod(periodStart, periodEnd)
+	daysElapsed := daysInPeriod(periodStart, at)
+
+	history := forecastHistory(bills, periodStart)
+
+	currency, err := s.currencyService.BaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := s.forecastRates(ctx, billType, customType, history, at)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := s.periodUsage(ctx, bills, periodStart, at)
+	if err != nil {
+		return nil, err
+	}
+	historyUsage, err := s.historyUsage(ctx, history)
+	if err != nil {
+		return nil, err
+	}
+
+	subjects, err := s.allocationService.buildAllocationSubjects(ctx, periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	forecast := &BillForecast{
+		BillType:        billType,
+		CustomType:      customType,
+		PeriodStart:     periodStart,
+		PeriodEnd:       periodEnd,
+		AsOf:            at,
+		DaysElapsed:     daysElapsed,
+		PeriodDays:      periodDays,
+		Currency:        currency,
+		RateSource:      rates.source,
+		UnitPrices:      rates.zonePrices(),
+		FixedFeeMonthly: rates.monthlyFee,
+		Subjects:        make([]SubjectForecast, len(subjects)),
+	}
+
+	// Personal usage of each subject, so far and projected to the end of the period
+	personalCosts := make([]utils.Money, len(subjects))
+	personalSoFar, personalProjected := 0.0, 0.0
+	personalCost, personalCostSoFar := utils.NewMoney(0, 0), utils.NewMoney(0, 0)
+	for i, subject := range subjects {
+		soFar := usage.subjects[subject.id]
+		projected := make(map[string]float64)
+		switch {
+		case soFar != nil:
+			// Extrapolate the usage up to the last reading to the whole period
+			factor := float64(periodDays) / float64(daysInPeriod(periodStart, soFar.lastReading))
+			for zone, units := range soFar.units {
+				projected[zone] = units * factor
+			}
+		case historyUsage.days > 0:
+			for zone, units := range historyUsage.subjects[subject.id] {
+				projected[zone] = units / float64(historyUsage.days) * float64(periodDays)
+			}
+		}
+
+		entry := SubjectForecast{
+			SubjectID:   subject.id,
+			SubjectType: subject.subjectType,
+			SubjectName: subject.name,
+		}
+		for _, units := range projected {
+			entry.ProjectedUnits += units
+		}
+		cost, err := rates.cost(projected)
+		if err != nil {
+			return nil, err
+		}
+		if soFar != nil {
+			for _, units := range soFar.units {
+				entry.UnitsSoFar += units
+			}
+			costSoFar, err := rates.cost(soFar.units)
+			if err != nil {
+				return nil, err
+			}
+			personalCostSoFar = personalCostSoFar.Add(costSoFar)
+		}
+		personalCosts[i] = cost
+		personalCost = personalCost.Add(cost)
+		personalSoFar += entry.UnitsSoFar
+		personalProjected += entry.ProjectedUnits
+
+		entry.UnitsSoFar = utils.RoundToThreeDecimals(entry.UnitsSoFar)
+		entry.ProjectedUnits = utils.RoundToThreeDecimals(entry.ProjectedUnits)
+		forecast.Subjects[i] = entry
+	}
+
+	share := historyUsage.personalShare()
+	projectedUnits := personalProjected / share
+	if personalProjected == 0 && historyUsage.days > 0 {
+		projectedUnits = historyUsage.totalUnits / float64(historyUsage.days) * float64(periodDays)
+	}
+	commonUnits := projectedUnits - personalProjected
+
+	// Units on the bill that no meter accounts for are priced at the average price of personal usage
+	var commonCost utils.Money
+	if personalProjected > 0 {
+		commonCost = personalCost.MulFloat(commonUnits / personalProjected)
+	} else if commonCost, err = utils.UnitCost(rates.price(DefaultTariffZone), commonUnits); err != nil {
+		return nil, err
+	}
+	sharedPool := commonCost.Add(rates.fees(periodDays))
+	sharedWeights := make([]float64, len(subjects))
+	for i, subject := range subjects {
+		sharedWeights[i] = subject.totalWeight
+	}
+	sharedShares := sharedPool.Allocate(sharedWeights)
+
+	total := utils.NewMoney(0, 0)
+	for i := range subjects {
+		entry := &forecast.Subjects[i]
+		entry.PersonalAmount = personalCosts[i]
+		entry.SharedAmount = sharedShares[i]
+		entry.Amount = personalCosts[i].Add(sharedShares[i])
+		total = total.Add(entry.Amount)
+	}
+
+	forecast.UnitsSoFar = utils.RoundToThreeDecimals(personalSoFar / share)
+	forecast.ProjectedUnits = utils.RoundToThreeDecimals(projectedUnits)
+	forecast.CostSoFar = personalCostSoFar.MulFloat(1 / share).Add(rates.fees(daysElapsed))
+	forecast.ProjectedTotal = total
+
+	return forecast, nil
+}
+
+// forecastRates are the prices a forecast charges, in the base currency. Unit prices are exact
+// decimal strings, as tariffs often have more than two decimals.
+type forecastRates struct {
+	source     string
+	zones      map[string]string // prices of zones with a tariff of their own
+	unitPrice  string            // price of units in any other zone
+	monthlyFee utils.Money
+}
+
+func (r forecastRates) price(zone string) string {
+	if price, ok := r.zones[zone]; ok {
+		return price
+	}
+	return r.unitPrice
+}
+
+// cost prices the units read in each zone
+func (r forecastRates) cost(units map[string]float64) (utils.Money, error) {
+	total := utils.NewMoney(0, 0)
+	for zone, n := range units {
+		cost, err := utils.UnitCost(r.price(zone), n)
+		if err != nil {
+			return 0, err
+		}
+		total = total.Add(cost)
+	}
+	return total, nil
+}
+
+// fees returns the fixed fees of a number of days, a month being a twelfth of a year
+func (r forecastRates) fees(days int) utils.Money {
+	return r.monthlyFee.MulRatio(int64(days)*12, 365)
+}
+
+func (r forecastRates) zonePrices() []ZonePrice {
+	if r.source == RateSourceNone {
+		return nil
+	}
+	if len(r.zones) == 0 {
+		return []ZonePrice{{Zone: DefaultTariffZone, UnitPrice: utils.DecimalStringToFloat(r.unitPrice)}}
+	}
+	prices := make([]ZonePrice, 0, len(r.zones))
+	for zone, price := range r.zones {
+		prices = append(prices, ZonePrice{Zone: zone, UnitPrice: utils.DecimalStringToFloat(price)})
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Zone < prices[j].Zone })
+	return prices
+}
+
+// forecastRates prices units with the tariffs in effect at the given time, or learns the prices from
+// earlier bills when the utility has no tariff
+func (s *ForecastService) forecastRates(ctx context.Context, billType, customType string, history []models.Bill, at time.Time) (forecastRates, error) {
+	tariffs, err := s.tariffs.ListByUtility(ctx, billType, customType)
+	if err != nil {
+		return forecastRates{}, fmt.Errorf("failed to get tariffs: %w", err)
+	}
+
+	// Tariffs come newest first, so the first one of each zone is the one in effect.
+	// Fixed fees of all zones add up.
+	rates := forecastRates{source: RateSourceConfigured, zones: make(map[string]string)}
+	for _, tariff := range tariffs {
+		if tariff.ValidFrom.After(at) {
+			continue
+		}
+		if _, ok := rates.zones[tariff.TariffZone]; ok {
+			continue
+		}
+		rates.zones[tariff.TariffZone] = tariff.UnitPrice
+		rates.monthlyFee = rates.monthlyFee.Add(utils.MoneyFromString(tariff.FixedFeeMonthly))
+	}
+
+	if len(rates.zones) > 0 {
+		// Units outside the tariff's zones cost the single-zone price, or the average of the zones
+		if price, ok := rates.zones[DefaultTariffZone]; ok {
+			rates.unitPrice = price
+		} else {
+			sum := new(big.Rat)
+			for _, price := range rates.zones {
+				value, ok := new(big.Rat).SetString(price)
+				if !ok {
+					return forecastRates{}, fmt.Errorf("invalid unit price %q", price)
+				}
+				sum.Add(sum, value)
+			}
+			rates.unitPrice = sum.Quo(sum, big.NewRat(int64(len(rates.zones)), 1)).FloatString(8)
+		}
+		return rates, nil
+	}
+
+	return s.learnedRates(ctx, history)
+}
+
+// learnedRates fits amount per day = fixed fee per day + unit price * units per day to earlier bills
+// with a least-squares line. When the bills cannot tell the two apart (too few bills, the same usage
+// every time, or a fit that makes no sense), the whole amount is put on the units instead.
+func (s *ForecastService) learnedRates(ctx context.Context, history []models.Bill) (forecastRates, error) {
+	var dailyUnits, dailyAmounts []float64
+	totalUnits, totalAmount := 0.0, 0.0
+	for _, bill := range history {
+		units := utils.DecimalStringToFloat(bill.TotalUnits)
+		if units <= 0 {
+			continue
+		}
+		amount, err := s.currencyService.Convert(ctx, utils.MoneyFromString(bill.TotalAmountPLN), bill.Currency, bill.PeriodEnd)
+		if errors.Is(err, ErrExchangeRateNotFound) {
+			continue
+		}
+		if err != nil {
+			return forecastRates{}, err
+		}
+
+		days := float64(daysInPeriod(bill.PeriodStart, bill.PeriodEnd))
+		dailyUnits = append(dailyUnits, units/days)
+		dailyAmounts = append(dailyAmounts, amount.Float64()/days)
+		totalUnits += units
+		totalAmount += amount.Float64()
+	}
+
+	if totalUnits == 0 {
+		return forecastRates{source: RateSourceNone, unitPrice: "0"}, nil
+	}
+
+	unitPrice, dailyFee := totalAmount/totalUnits, 0.0
+
+	n := float64(len(dailyUnits))
+	meanUnits, meanAmount := 0.0, 0.0
+	for i := range dailyUnits {
+		meanUnits += dailyUnits[i] / n
+		meanAmount += dailyAmounts[i] / n
+	}
+	varUnits, covariance := 0.0, 0.0
+	for i := range dailyUnits {
+		varUnits += (dailyUnits[i] - meanUnits) * (dailyUnits[i] - meanUnits)
+		covariance += (dailyUnits[i] - meanUnits) * (dailyAmounts[i] - meanAmount)
+	}
+	if len(dailyUnits) >= 2 && varUnits > 1e-9 {
+		fittedPrice := covariance / varUnits
+		fittedFee := meanAmount - fittedPrice*meanUnits
+		if fittedPrice > 0 && fittedFee >= 0 {
+			unitPrice, dailyFee = fittedPrice, fittedFee
+		}
+	}
+
+	return forecastRates{
+		source:     RateSourceLearned,
+		unitPrice:  strconv.FormatFloat(unitPrice, 'f', -1, 64),
+		monthlyFee: utils.MoneyFromFloat(dailyFee * 365 / 12),
+	}, nil
+}
+
+// subjectUsage is what a subject has read in the current period, by tariff zone
+type subjectUsage struct {
+	units       map[string]float64
+	lastReading time.Time
+}
+
+type periodUsage struct {
+	subjects map[string]*subjectUsage
+}
+
+// periodUsage sums the readings recorded on the utility's bills between the period start and the
+// given time. A reading's units cover the time since the previous reading, which for the first
+// reading of a period is normally the closing reading of the previous bill.
+func (s *ForecastService) periodUsage(ctx context.Context, bills []models.Bill, periodStart, at time.Time) (*periodUsage, error) {
+	usage := &periodUsage{subjects: make(map[string]*subjectUsage)}
+
+	billIDs := make(map[string]bool, len(bills))
+	for _, bill := range bills {
+		billIDs[bill.ID] = true
+	}
+
+	readings, err := s.consumptions.ListFiltered(ctx, nil, nil, &periodStart, &at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consumptions: %w", err)
+	}
+
+	zones := make(map[string]string)
+	for _, c := range readings {
+		if !billIDs[c.BillID] {
+			continue
+		}
+		units, zone, err := s.readingUsage(ctx, c, zones)
+		if err != nil {
+			return nil, err
+		}
+		if units <= 0 {
+			continue
+		}
+
+		subject := usage.subjects[c.SubjectID]
+		if subject == nil {
+			subject = &subjectUsage{units: make(map[string]float64)}
+			usage.subjects[c.SubjectID] = subject
+		}
+		subject.units[zone] += units
+		if c.RecordedAt.After(subject.lastReading) {
+			subject.lastReading = c.RecordedAt
+		}
+	}
+
+	return usage, nil
+}
+
+// historyUsage is what was read and billed on earlier bills of a utility
+type historyUsage struct {
+	subjects      map[string]map[string]float64 // units by subject and tariff zone
+	days          int
+	totalUnits    float64
+	personalUnits float64 // units read on bills with a known total
+	billedUnits   float64 // total units of bills with readings
+}
+
+// personalShare is the part of a bill's units read on meters, the rest being common usage
+func (h *historyUsage) personalShare() float64 {
+	if h.billedUnits <= 0 || h.personalUnits <= 0 {
+		return 1
+	}
+	share := h.personalUnits / h.billedUnits
+	if share > 1 {
+		return 1
+	}
+	return share
+}
+
+func (s *ForecastService) historyUsage(ctx context.Context, history []models.Bill) (*historyUsage, error) {
+	usage := &historyUsage{subjects: make(map[string]map[string]float64)}
+
+	zones := make(map[string]string)
+	for _, bill := range history {
+		readings, err := s.consumptions.ListByBillID(ctx, bill.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get consumptions: %w", err)
+		}
+
+		billUnits := utils.DecimalStringToFloat(bill.TotalUnits)
+		readUnits := 0.0
+		for _, c := range readings {
+			units, zone, err := s.readingUsage(ctx, c, zones)
+			if err != nil {
+				return nil, err
+			}
+			if units <= 0 {
+				continue
+			}
+			if usage.subjects[c.SubjectID] == nil {
+				usage.subjects[c.SubjectID] = make(map[string]float64)
+			}
+			usage.subjects[c.SubjectID][zone] += units
+			readUnits += units
+		}
+
+		usage.days += daysInPeriod(bill.PeriodStart, bill.PeriodEnd)
+		usage.totalUnits += billUnits
+		if billUnits > 0 && readUnits > 0 {
+			usage.personalUnits += readUnits
+			usage.billedUnits += billUnits
+		}
+	}
+
+	return usage, nil
+}
+
+// readingUsage returns the units of a reading and the tariff zone of its meter. Readings marked
+// invalid and first readings of a meter without a registered starting value count as no usage.
+func (s *ForecastService) readingUsage(ctx context.Context, c models.Consumption, zones map[string]string) (float64, string, error) {
+	if c.Source == "invalid" {
+		return 0, "", nil
+	}
+
+	units := utils.DecimalStringToFloat(c.Units)
+	if units <= 0 && c.MeterValue != nil {
+		derived, err := meterReadingUnits(ctx, s.consumptions, s.meters, c)
+		switch {
+		case err == nil:
+			units = derived
+		case errors.Is(err, ErrNoPreviousReading):
+			return 0, "", nil
+		default:
+			return 0, "", err
+		}
+	}
+
+	zone := DefaultTariffZone
+	if c.MeterID != nil {
+		if cached, ok := zones[*c.MeterID]; ok {
+			zone = cached
+		} else {
+			meter, err := s.meters.GetByID(ctx, *c.MeterID)
+			if err != nil {
+				return 0, "", fmt.Errorf("failed to fetch meter: %w", err)
+			}
+			if meter != nil {
+				zone = meter.TariffZone
+			}
+			zones[*c.MeterID] = zone
+		}
+	}
+
+	return units, zone, nil
+}
+
+// normalizeUtility checks that bills of the type are priced by consumption and returns the
+// type with the custom type that names the utility, which only "inne" bills have
+func normalizeUtility(billType, customType string) (string, string, error) {
+	billType = strings.ToLower(strings.TrimSpace(billType))
+	switch billType {
+	case "electricity", "gas":
+		return billType, "", nil
+	case "inne":
+		customType = strings.TrimSpace(customType)
+		if customType == "" {
+			return "", "", errors.New("customType is required when type is 'inne'")
+		}
+		return billType, customType, nil
+	case "internet":
+		return "", "", ErrForecastUnsupported
+	default:
+		return "", "", fmt.Errorf("invalid bill type: %q", billType)
+	}
+}
+
+func billMatchesUtility(bill models.Bill, customType string) bool {
+	if customType == "" {
+		return true
+	}
+	return bill.CustomType != nil && strings.EqualFold(strings.TrimSpace(*bill.CustomType), customType)
+}
+
+// forecastPeriod returns the bill period containing the given time: the period of a bill that covers
+// it, otherwise the periods following the latest earlier bill at the same length (in whole months for
+// bills that run from the first to the last day of a month), or the calendar month when there are no bills
+func forecastPeriod(bills []models.Bill, at time.Time) (time.Time, time.Time) {
+	day := truncateToDay(at)
+
+	var covering, previous *models.Bill
+	for i := range bills {
+		bill := &bills[i]
+		start, end := truncateToDay(bill.PeriodStart), truncateToDay(bill.PeriodEnd)
+		if !day.Before(start) && !day.After(end) {
+			if covering == nil || start.After(truncateToDay(covering.PeriodStart)) {
+				covering = bill
+			}
+		} else if end.Before(day) {
+			if previous == nil || end.After(truncateToDay(previous.PeriodEnd)) {
+				previous = bill
+			}
+		}
+	}
+
+	if covering != nil {
+		return truncateToDay(covering.PeriodStart), truncateToDay(covering.PeriodEnd)
+	}
+	if previous == nil {
+		start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, -1)
+	}
+
+	prevStart, prevEnd := truncateToDay(previous.PeriodStart), truncateToDay(previous.PeriodEnd)
+	next := prevEnd.AddDate(0, 0, 1)
+	if prevStart.Day() == 1 && next.Day() == 1 {
+		months := (next.Year()-prevStart.Year())*12 + int(next.Month()-prevStart.Month())
+		start := next
+		for !start.AddDate(0, months, 0).After(day) {
+			start = start.AddDate(0, months, 0)
+		}
+		return start, start.AddDate(0, months, -1)
+	}
+
+	length := daysInPeriod(prevStart, prevEnd)
+	start := next
+	for !start.AddDate(0, 0, length).After(day) {
+		start = start.AddDate(0, 0, length)
+	}
+	return start, start.AddDate(0, 0, length-1)
+}
+
+// forecastHistory returns the latest posted or closed bills that ended before the period, newest first
+func forecastHistory(bills []models.Bill, periodStart time.Time) []models.Bill {
+	var history []models.Bill
+	for _, bill := range bills {
+		if !isIssuedBill(bill.Status) {
+			continue
+		}
+		if !truncateToDay(bill.PeriodEnd).Before(periodStart) {
+			continue
+		}
+		history = append(history, bill)
+	}
+
+	sort.Slice(history, func(i, j int) bool { return history[i].PeriodEnd.After(history[j].PeriodEnd) })
+	if len(history) > forecastHistoryBills {
+		history = history[:forecastHistoryBills]
+	}
+	return history
+}
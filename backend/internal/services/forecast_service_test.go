@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForecastMeteredBill(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	_, allocationService := newTestBillService(repos)
+	currencyService := NewCurrencyService(repos.ExchangeRates, NewAppSettingsService(repos.AppSettings))
+	forecastService := NewForecastService(repos.UtilityTariffs, repos.Bills, repos.Consumptions, repos.Meters, allocationService, currencyService)
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+	date := func(month time.Month, d int) time.Time { return time.Date(2024, month, d, 12, 0, 0, 0, time.UTC) }
+
+	newBill := func(start, end time.Time, amount, units, status string) *models.Bill {
+		bill := &models.Bill{
+			Type: "electricity", AllocationType: stringPtr("metered"), PeriodStart: start, PeriodEnd: end,
+			TotalAmountPLN: amount, Currency: "PLN", TotalUnits: units, Status: status,
+		}
+		require.NoError(t, repos.Bills.Create(ctx, bill))
+		return bill
+	}
+	read := func(bill *models.Bill, user *models.User, units string, at time.Time) {
+		require.NoError(t, repos.Consumptions.Create(ctx, &models.Consumption{
+			BillID: bill.ID, SubjectType: "user", SubjectID: user.ID, Units: units, RecordedAt: at, Source: "user",
+		}))
+	}
+
+	// 1 zł a day in fixed charges and 0.50 zł per kWh; 80% of the units are read on personal meters
+	november := newBill(time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 11, 30, 0, 0, 0, 0, time.UTC), "180.00", "300", "closed")
+	read(november, alice, "150", time.Date(2023, 11, 30, 12, 0, 0, 0, time.UTC))
+	read(november, bob, "90", time.Date(2023, 11, 30, 12, 0, 0, 0, time.UTC))
+	december := newBill(time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), "341.00", "620", "posted")
+	read(december, alice, "300", time.Date(2023, 12, 31, 12, 0, 0, 0, time.UTC))
+	read(december, bob, "196", time.Date(2023, 12, 31, 12, 0, 0, 0, time.UTC))
+
+	// January is under way: Alice read 100 kWh in the first ten days, Bob has not read yet
+	january := newBill(date(time.January, 1), date(time.January, 31), "0.00", "", "draft")
+	read(january, alice, "100", date(time.January, 10))
+	asOf := time.Date(2024, 1, 10, 23, 59, 59, 0, time.UTC)
+
+	forecast, err := forecastService.Forecast(ctx, "electricity", "", asOf)
+	require.NoError(t, err)
+	assert.Equal(t, RateSourceLearned, forecast.RateSource)
+	assert.Equal(t, 31, forecast.PeriodDays)
+	assert.Equal(t, 10, forecast.DaysElapsed)
+	assert.InDelta(t, 0.5, forecast.UnitPrices[0].UnitPrice, 1e-9)
+	assert.Equal(t, utils.NewMoney(30, 42), forecast.FixedFeeMonthly)
+	assert.Equal(t, 125.0, forecast.UnitsSoFar)
+	assert.Equal(t, utils.NewMoney(72, 50), forecast.CostSoFar)
+
+	got := make(map[string]SubjectForecast)
+	for _, subject := range forecast.Subjects {
+		got[subject.SubjectID] = subject
+	}
+	// Alice: 100 kWh in 10 days -> 310 kWh. Bob: 286 kWh over the last 61 days -> 145.344 kWh.
+	// Common usage is a fifth of the total, 113.836 kWh at 0.50 zł, plus 31 days of fixed charges.
+	assert.Equal(t, 310.0, got[alice.ID].ProjectedUnits)
+	assert.Equal(t, utils.NewMoney(155, 0), got[alice.ID].PersonalAmount)
+	assert.Equal(t, 0.0, got[bob.ID].UnitsSoFar)
+	assert.Equal(t, 145.344, got[bob.ID].ProjectedUnits)
+	assert.Equal(t, utils.NewMoney(72, 67), got[bob.ID].PersonalAmount)
+	assert.Equal(t, utils.NewMoney(43, 96), got[bob.ID].SharedAmount)
+	assert.Equal(t, 569.18, forecast.ProjectedUnits)
+	assert.Equal(t, utils.NewMoney(315, 59), forecast.ProjectedTotal)
+
+	// A configured tariff takes precedence over the learned one once it is in effect
+	_, err = forecastService.SetTariff(ctx, SetTariffRequest{BillType: "electricity", UnitPrice: "0,80", FixedFeeMonthly: "36.50",
+		ValidFrom: date(time.January, 1)})
+	require.NoError(t, err)
+	_, err = forecastService.SetTariff(ctx, SetTariffRequest{BillType: "electricity", UnitPrice: "2.00", ValidFrom: date(time.February, 1)})
+	require.NoError(t, err)
+	_, err = forecastService.SetTariff(ctx, SetTariffRequest{BillType: "electricity", UnitPrice: "-1"})
+	assert.ErrorContains(t, err, "must be positive")
+
+	forecast, err = forecastService.Forecast(ctx, "electricity", "", asOf)
+	require.NoError(t, err)
+	assert.Equal(t, RateSourceConfigured, forecast.RateSource)
+	assert.Equal(t, []ZonePrice{{Zone: DefaultTariffZone, UnitPrice: 0.8}}, forecast.UnitPrices)
+	assert.Equal(t, utils.NewMoney(36, 50), forecast.FixedFeeMonthly)
+	for _, subject := range forecast.Subjects {
+		if subject.SubjectID == alice.ID {
+			assert.Equal(t, utils.NewMoney(248, 0), subject.PersonalAmount)
+		}
+	}
+
+	// Unit prices keep all their decimals: 310 kWh at 0.8123 zł
+	_, err = forecastService.SetTariff(ctx, SetTariffRequest{BillType: "electricity", UnitPrice: "0.8123", ValidFrom: date(time.January, 5)})
+	require.NoError(t, err)
+	forecast, err = forecastService.Forecast(ctx, "electricity", "", asOf)
+	require.NoError(t, err)
+	for _, subject := range forecast.Subjects {
+		if subject.SubjectID == alice.ID {
+			assert.Equal(t, utils.NewMoney(251, 81), subject.PersonalAmount)
+		}
+	}
+
+	_, err = forecastService.Forecast(ctx, "internet", "", asOf)
+	assert.ErrorIs(t, err, ErrForecastUnsupported)
+}
+
+func TestForecastPeriod(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	bill := func(start, end time.Time) models.Bill { return models.Bill{PeriodStart: start, PeriodEnd: end} }
+
+	tests := []struct {
+		name      string
+		bills     []models.Bill
+		at        time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{"Calendar month without bills", nil, day(2024, 2, 10), day(2024, 2, 1), day(2024, 2, 29)},
+		{"Bill covering the day", []models.Bill{bill(day(2024, 1, 15), day(2024, 2, 14))}, day(2024, 2, 1), day(2024, 1, 15), day(2024, 2, 14)},
+		{"Whole months continue as months", []models.Bill{bill(day(2023, 11, 1), day(2023, 12, 31))}, day(2024, 3, 5),
+			day(2024, 3, 1), day(2024, 4, 30)},
+		{"Other periods keep their length", []models.Bill{bill(day(2023, 12, 15), day(2024, 1, 14))}, day(2024, 3, 1),
+			day(2024, 2, 15), day(2024, 3, 16)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := forecastPeriod(tt.bills, tt.at)
+			assert.Equal(t, tt.wantStart, start)
+			assert.Equal(t, tt.wantEnd, end)
+		})
+	}
+}
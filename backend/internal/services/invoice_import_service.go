@@ -0,0 +1,148 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidInvoice is returned when no text can be read from an uploaded invoice
+var ErrInvalidInvoice = errors.New("invalid invoice")
+
+// InvoiceImportService turns invoice PDFs into bill drafts. Each invoice is read by the first
+// extractor that recognizes its provider; values it cannot find are taken from the generic
+// extractor.
+type InvoiceImportService struct {
+	extractors []InvoiceExtractor
+}
+
+func NewInvoiceImportService() *InvoiceImportService {
+	return &InvoiceImportService{extractors: DefaultInvoiceExtractors()}
+}
+
+// RegisterExtractor adds an extractor, checked before the ones registered earlier
+func (s *InvoiceImportService) RegisterExtractor(extractor InvoiceExtractor) {
+	s.extractors = append([]InvoiceExtractor{extractor}, s.extractors...)
+}
+
+// InvoiceDraft is a bill read from an invoice, to be reviewed before it is created
+type InvoiceDraft struct {
+	Provider      string            `json:"provider"` // "generic" when no provider template matched
+	InvoiceNumber string            `json:"invoiceNumber,omitempty"`
+	Bill          CreateBillRequest `json:"bill"`
+	Missing       []string          `json:"missing"` // bill fields that could not be read
+	Text          string            `json:"text"`    // text the values were read from
+}
+
+// ParseInvoice reads a text-based PDF or plain text invoice and returns a bill draft
+func (s *InvoiceImportService) ParseInvoice(data []byte) (*InvoiceDraft, error) {
+	text, err := invoiceText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, fields := s.extract(text)
+	return buildInvoiceDraft(provider, fields, text), nil
+}
+
+func invoiceText(data []byte) (string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var text string
+	if bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF-")) {
+		var err error
+		text, err = ExtractPDFText(data)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidInvoice, err)
+		}
+		if strings.TrimSpace(text) == "" {
+			return "", fmt.Errorf("%w: the PDF has no text layer, scanned invoices are not supported", ErrInvalidInvoice)
+		}
+	} else {
+		if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+			return "", fmt.Errorf("%w: upload a PDF or a text file", ErrInvalidInvoice)
+		}
+		text = string(data)
+	}
+	return text, nil
+}
+
+// extract runs the extractor pipeline over the invoice text
+func (s *InvoiceImportService) extract(text string) (string, InvoiceFields) {
+	generic := genericInvoiceExtractor.Extract(text)
+	for _, extractor := range s.extractors {
+		if !extractor.Matches(text) {
+			continue
+		}
+		return extractor.Name(), mergeInvoiceFields(extractor.Extract(text), generic)
+	}
+	return genericInvoiceExtractor.Name(), generic
+}
+
+// mergeInvoiceFields fills the fields a provider extractor left empty
+func mergeInvoiceFields(fields, fallback InvoiceFields) InvoiceFields {
+	if fields.BillType == "" {
+		fields.BillType = fallback.BillType
+	}
+	if fields.InvoiceNumber == "" {
+		fields.InvoiceNumber = fallback.InvoiceNumber
+	}
+	if fields.PeriodStart == nil || fields.PeriodEnd == nil {
+		fields.PeriodStart, fields.PeriodEnd = fallback.PeriodStart, fallback.PeriodEnd
+	}
+	if fields.PaymentDeadline == nil {
+		fields.PaymentDeadline = fallback.PaymentDeadline
+	}
+	if fields.Total == nil {
+		fields.Total = fallback.Total
+	}
+	if fields.Units == nil {
+		fields.Units = fallback.Units
+	}
+	return fields
+}
+
+func buildInvoiceDraft(provider string, fields InvoiceFields, text string) *InvoiceDraft {
+	draft := &InvoiceDraft{
+		Provider:      provider,
+		InvoiceNumber: fields.InvoiceNumber,
+		Missing:       []string{},
+		Text:          text,
+	}
+	bill := &draft.Bill
+
+	bill.Type = fields.BillType
+	if bill.Type == "" {
+		draft.Missing = append(draft.Missing, "type")
+	}
+	if fields.PeriodStart != nil && fields.PeriodEnd != nil {
+		bill.PeriodStart, bill.PeriodEnd = *fields.PeriodStart, *fields.PeriodEnd
+	} else {
+		draft.Missing = append(draft.Missing, "periodStart", "periodEnd")
+	}
+	if fields.Total != nil {
+		bill.TotalAmountPLN = *fields.Total
+	} else {
+		draft.Missing = append(draft.Missing, "totalAmountPLN")
+	}
+	bill.PaymentDeadline = fields.PaymentDeadline
+	if bill.PaymentDeadline == nil {
+		draft.Missing = append(draft.Missing, "paymentDeadline")
+	}
+	bill.TotalUnits = fields.Units
+	// Metered bills are split by consumption and need the invoiced units
+	if bill.TotalUnits == nil && bill.Type == "electricity" {
+		draft.Missing = append(draft.Missing, "totalUnits")
+	}
+
+	if fields.InvoiceNumber != "" {
+		note := "Faktura " + fields.InvoiceNumber
+		if provider != genericInvoiceExtractor.Name() {
+			note += ", " + provider
+		}
+		bill.Notes = &note
+	}
+	return draft
+}
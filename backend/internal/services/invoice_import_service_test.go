@@ -0,0 +1,118 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildTestPDF writes a one-page PDF with a compressed content stream, a simple font using
+// encoding differences for Polish letters and a composite font with a ToUnicode map
+func buildTestPDF(t *testing.T, content string) []byte {
+	t.Helper()
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, err := zw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	cmap := "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n" +
+		"1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+		"1 beginbfrange <0020> <007E> <0020> endbfrange\n" +
+		"1 beginbfchar <0100> <0142> endbfchar\n" +
+		"endcmap end end\n"
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	pdf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pdf.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>\nendobj\n")
+	pdf.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Contents 4 0 R >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+	pdf.Write(compressed.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n")
+	pdf.WriteString("5 0 obj\n<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding << /BaseEncoding /WinAnsiEncoding /Differences [156 /sacute 179 /lslash 191 /zdotaccent] >> >>\nendobj\n")
+	pdf.WriteString("6 0 obj\n<< /Type /Font /Subtype /Type0 /BaseFont /Arial /Encoding /Identity-H /DescendantFonts [8 0 R] /ToUnicode 7 0 R >>\nendobj\n")
+	fmt.Fprintf(&pdf, "7 0 obj\n<< /Length %d >>\nstream\n%sendstream\nendobj\n", len(cmap), cmap)
+	pdf.WriteString("8 0 obj\n<< /Type /Font /Subtype /CIDFontType2 /BaseFont /Arial /DW 500 >>\nendobj\n")
+	pdf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestParseInvoicePDF(t *testing.T) {
+	content := "BT /F1 10 Tf 14 TL 50 800 Td (PGE Obr\\363t S.A.) Tj\n" +
+		"T* (Faktura VAT nr FE/2024/03/0001) Tj\n" +
+		"T* (Okres rozliczeniowy: 01.02.2024 - 29.02.2024) Tj\n" +
+		"T* [(Zu) -10 (\\277ycie energii:)] TJ 200 0 Td (245 kWh) Tj\n" +
+		"-200 -14 Td /F2 10 Tf <0044006F0020007A006100700100006100740079003A> Tj\n" +
+		"/F1 10 Tf 200 0 Td (412,37 z\\263) Tj\n" +
+		"-200 -14 Td (Termin p\\263atno\\234ci: 15.03.2024) Tj ET"
+	pdf := buildTestPDF(t, content)
+
+	text, err := ExtractPDFText(pdf)
+	require.NoError(t, err)
+	assert.Equal(t, "PGE Obrót S.A.\n"+
+		"Faktura VAT nr FE/2024/03/0001\n"+
+		"Okres rozliczeniowy: 01.02.2024 - 29.02.2024\n"+
+		"Zużycie energii: 245 kWh\n"+
+		"Do zapłaty: 412,37 zł\n"+
+		"Termin płatności: 15.03.2024", text)
+
+	draft, err := NewInvoiceImportService().ParseInvoice(pdf)
+	require.NoError(t, err)
+	assert.Equal(t, "PGE Obrót", draft.Provider)
+	assert.Equal(t, "FE/2024/03/0001", draft.InvoiceNumber)
+	assert.Empty(t, draft.Missing)
+
+	bill := draft.Bill
+	assert.Equal(t, "electricity", bill.Type)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), bill.PeriodStart)
+	assert.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), bill.PeriodEnd)
+	assert.Equal(t, utils.NewMoney(412, 37), bill.TotalAmountPLN)
+	require.NotNil(t, bill.PaymentDeadline)
+	assert.Equal(t, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), *bill.PaymentDeadline)
+	require.NotNil(t, bill.TotalUnits)
+	assert.Equal(t, 245.0, *bill.TotalUnits)
+	require.NotNil(t, bill.Notes)
+	assert.Equal(t, "Faktura FE/2024/03/0001, PGE Obrót", *bill.Notes)
+}
+
+func TestParseInvoiceText(t *testing.T) {
+	service := NewInvoiceImportService()
+
+	// Unknown providers are read with the generic labels and the type is guessed
+	draft, err := service.ParseInvoice([]byte("Sprzedaż paliwa gazowego\nOkres: 2024-01-01 do 2024-02-29\nNależność ogółem 1 234,56 zł\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "generic", draft.Provider)
+	assert.Equal(t, "gas", draft.Bill.Type)
+	assert.Equal(t, utils.NewMoney(1234, 56), draft.Bill.TotalAmountPLN)
+	assert.Equal(t, []string{"paymentDeadline"}, draft.Missing)
+	assert.Nil(t, draft.Bill.Notes)
+
+	// Registered extractors take precedence and fall back to the generic labels
+	service.RegisterExtractor(&TemplateInvoiceExtractor{
+		Provider: "Światłowód Osiedlowy",
+		BillType: "internet",
+		Detect:   regexp.MustCompile(`Światłowód Osiedlowy`),
+		Total:    regexp.MustCompile(`Abonament miesięczny\s+(\d+,\d{2})`),
+	})
+	draft, err = service.ParseInvoice([]byte("Światłowód Osiedlowy\nAbonament miesięczny 79,99\nZapłać do: 10.04.2024\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "Światłowód Osiedlowy", draft.Provider)
+	assert.Equal(t, "internet", draft.Bill.Type)
+	assert.Equal(t, utils.NewMoney(79, 99), draft.Bill.TotalAmountPLN)
+	require.NotNil(t, draft.Bill.PaymentDeadline)
+	assert.Equal(t, []string{"periodStart", "periodEnd"}, draft.Missing)
+
+	_, err = service.ParseInvoice([]byte{0x89, 'P', 'N', 'G', 0, 0})
+	assert.ErrorIs(t, err, ErrInvalidInvoice)
+	_, err = service.ParseInvoice(buildTestPDF(t, "0 0 100 100 re f"))
+	assert.ErrorIs(t, err, ErrInvalidInvoice, "scanned invoices have no text")
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+// InvoiceFields are the values read from an invoice. Fields that were not found are left empty.
+type InvoiceFields struct {
+	BillType        string // electricity, gas, internet; empty when unknown
+	InvoiceNumber   string
+	PeriodStart     *time.Time
+	PeriodEnd       *time.Time
+	PaymentDeadline *time.Time
+	Total           *utils.Money // amount to pay
+	Units           *float64     // consumption in kWh or m³
+}
+
+// InvoiceExtractor reads the invoices of one provider
+type InvoiceExtractor interface {
+	// Name identifies the provider
+	Name() string
+	// Matches reports whether the text is an invoice of this provider
+	Matches(text string) bool
+	// Extract reads what it can from the invoice text
+	Extract(text string) InvoiceFields
+}
+
+// Building blocks of the invoice patterns
+const (
+	invoiceDate   = `(\d{2}[.\-/]\d{2}[.\-/]\d{4}|\d{4}-\d{2}-\d{2})`
+	invoiceAmount = `(-?\d[\d\x{00a0} .]*,\d{2}|-?\d+\.\d{2})`
+)
+
+// Patterns for the labels Polish invoices use, shared by all templates unless overridden
+var (
+	invoicePeriodPattern = regexp.MustCompile(`(?i)okres(?:\s+rozliczeniowy|\s+rozliczenia|\s+obj[eę]ty\s+faktur[ąa]|\s+us[łl]ugi)?\s*:?\s*(?:od\s+)?` +
+		invoiceDate + `\s*(?:r\.)?\s*(?:-|–|do)\s*` + invoiceDate)
+	invoiceTotalPattern = regexp.MustCompile(`(?i)(?:do\s+zap[łl]aty|nale[żz]no[śs][ćc]\s+og[óo][łl]em|razem\s+brutto)\s*:?\s*(?:PLN|z[łl])?\s*` +
+		invoiceAmount)
+	invoiceDeadlinePattern = regexp.MustCompile(`(?i)(?:termin\s+p[łl]atno[śs]ci|termin\s+zap[łl]aty|zap[łl]a[ćc]\s+do|p[łl]atne\s+do)\s*:?\s*` +
+		invoiceDate)
+	invoiceUnitsPattern  = regexp.MustCompile(`(?i)zu[żz]ycie[^\d\n]{0,60}?(\d[\d\x{00a0} ]*(?:[.,]\d+)?)\s*(?:kwh|m3|m³)`)
+	invoiceNumberPattern = regexp.MustCompile(`(?i)faktura(?:\s+vat)?\s+(?:nr|numer)\.?\s*:?\s*([A-Z0-9][A-Z0-9/\-_.]*[A-Z0-9])`)
+
+	// Bill type guesses for invoices of unknown providers, checked in order
+	invoiceTypeKeywords = []struct {
+		billType string
+		pattern  *regexp.Regexp
+	}{
+		{"gas", regexp.MustCompile(`(?i)paliw[ao]\s+gazow|gaz\s+ziemn`)},
+		{"electricity", regexp.MustCompile(`(?i)energi[aięy]\s+elektryczn`)},
+		{"internet", regexp.MustCompile(`(?i)internet|[śs]wiat[łl]ow[óo]d`)},
+	}
+)
+
+// TemplateInvoiceExtractor reads invoices with regular expressions. Patterns left nil use
+// the labels common to Polish invoices; a template only needs its own pattern for a field
+// its provider labels differently.
+type TemplateInvoiceExtractor struct {
+	Provider string
+	BillType string         // empty to guess from keywords
+	Detect   *regexp.Regexp // identifies the provider's invoices, nil matches any invoice
+	Period   *regexp.Regexp // two groups: start and end date
+	Total    *regexp.Regexp // one group: amount
+	Deadline *regexp.Regexp // one group: date
+	Units    *regexp.Regexp // one group: consumption
+	Number   *regexp.Regexp // one group: invoice number
+}
+
+func (t *TemplateInvoiceExtractor) Name() string {
+	return t.Provider
+}
+
+func (t *TemplateInvoiceExtractor) Matches(text string) bool {
+	return t.Detect == nil || t.Detect.MatchString(text)
+}
+
+func (t *TemplateInvoiceExtractor) Extract(text string) InvoiceFields {
+	fields := InvoiceFields{BillType: t.BillType}
+	if fields.BillType == "" {
+		for _, keyword := range invoiceTypeKeywords {
+			if keyword.pattern.MatchString(text) {
+				fields.BillType = keyword.billType
+				break
+			}
+		}
+	}
+
+	if m := orPattern(t.Period, invoicePeriodPattern).FindStringSubmatch(text); m != nil {
+		start, err1 := parseStatementDate(m[1])
+		end, err2 := parseStatementDate(m[2])
+		if err1 == nil && err2 == nil {
+			fields.PeriodStart, fields.PeriodEnd = &start, &end
+		}
+	}
+	if m := orPattern(t.Total, invoiceTotalPattern).FindStringSubmatch(text); m != nil {
+		if amount, _, err := parseStatementAmount(m[1]); err == nil {
+			fields.Total = &amount
+		}
+	}
+	if m := orPattern(t.Deadline, invoiceDeadlinePattern).FindStringSubmatch(text); m != nil {
+		if deadline, err := parseStatementDate(m[1]); err == nil {
+			fields.PaymentDeadline = &deadline
+		}
+	}
+	if m := orPattern(t.Units, invoiceUnitsPattern).FindStringSubmatch(text); m != nil {
+		value := strings.NewReplacer(" ", "", "\u00a0", "", ",", ".").Replace(m[1])
+		if units, err := strconv.ParseFloat(value, 64); err == nil {
+			fields.Units = &units
+		}
+	}
+	if m := orPattern(t.Number, invoiceNumberPattern).FindStringSubmatch(text); m != nil {
+		fields.InvoiceNumber = m[1]
+	}
+	return fields
+}
+
+func orPattern(pattern, fallback *regexp.Regexp) *regexp.Regexp {
+	if pattern != nil {
+		return pattern
+	}
+	return fallback
+}
+
+// genericInvoiceExtractor reads invoices no provider template recognized
+var genericInvoiceExtractor = &TemplateInvoiceExtractor{Provider: "generic"}
+
+// DefaultInvoiceExtractors returns templates for the common Polish utility providers
+func DefaultInvoiceExtractors() []InvoiceExtractor {
+	provider := func(name, billType, detect string) InvoiceExtractor {
+		return &TemplateInvoiceExtractor{Provider: name, BillType: billType, Detect: regexp.MustCompile(`(?i)` + detect)}
+	}
+	return []InvoiceExtractor{
+		provider("PGE Obrót", "electricity", `PGE\s+Obr[óo]t`),
+		provider("TAURON Sprzedaż", "electricity", `TAURON\s+Sprzeda[żz]`),
+		provider("Enea", "electricity", `ENEA\s+S\.\s?A\.`),
+		provider("Energa Obrót", "electricity", `ENERGA[\s-]+OBR[ÓO]T`),
+		provider("E.ON Polska", "electricity", `E\.ON\s+Polska|innogy\s+Polska`),
+		provider("PGNiG Obrót Detaliczny", "gas", `PGNiG\s+Obr[óo]t\s+Detaliczny`),
+		provider("Orange Polska", "internet", `Orange\s+Polska`),
+		provider("Play", "internet", `P4\s+sp\.\s?z\s?o\.\s?o\.|UPC\s+Polska`),
+		provider("Netia", "internet", `Netia\s+S\.\s?A\.`),
+		provider("Vectra", "internet", `Vectra\s+S\.\s?A\.`),
+	}
+}
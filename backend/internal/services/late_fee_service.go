@@ -0,0 +1,493 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+// Late fee kinds
+const (
+	LateFeeKindFlat     = "flat"     // charged once when the grace days are over
+	LateFeeKindInterest = "interest" // a percentage of the unpaid amount, charged every period
+)
+
+// Late fee statuses
+const (
+	LateFeeStatusAccrued = "accrued"
+	LateFeeStatusWaived  = "waived"
+)
+
+// defaultLateFeePeriodDays is the interest period of policies that do not set one
+const defaultLateFeePeriodDays = 30
+
+var (
+	ErrLateFeeNotFound       = errors.New("late fee not found")
+	ErrLateFeePolicyNotFound = errors.New("late fee policy not found")
+	ErrLateFeeWaived         = errors.New("late fee is already waived")
+)
+
+type LateFeeService struct {
+	policies            repository.LateFeePolicyRepository
+	fees                repository.LateFeeRepository
+	bills               repository.BillRepository
+	loans               repository.LoanRepository
+	loanPayments        repository.LoanPaymentRepository
+	users               repository.UserRepository
+	creditService       *CreditService
+	notificationService *NotificationService
+}
+
+func NewLateFeeService(
+	policies repository.LateFeePolicyRepository,
+	fees repository.LateFeeRepository,
+	bills repository.BillRepository,
+	loans repository.LoanRepository,
+	loanPayments repository.LoanPaymentRepository,
+	users repository.UserRepository,
+	creditService *CreditService,
+	notificationService *NotificationService,
+) *LateFeeService {
+	return &LateFeeService{
+		policies:            policies,
+		fees:                fees,
+		bills:               bills,
+		loans:               loans,
+		loanPayments:        loanPayments,
+		users:               users,
+		creditService:       creditService,
+		notificationService: notificationService,
+	}
+}
+
+// SetLateFeePolicyRequest sets the late fees of a bill type or of loans
+type SetLateFeePolicyRequest struct {
+	ResourceType     string       `json:"resourceType"`         // bill, loan
+	BillType         string       `json:"billType,omitempty"`   // bills only
+	CustomType       string       `json:"customType,omitempty"` // "inne" bills only, empty for every bill of the type
+	LoanID           string       `json:"loanId,omitempty"`     // loans only, empty for the default of all loans
+	FlatFee          utils.Money  `json:"flatFee"`
+	PercentPerPeriod string       `json:"percentPerPeriod,omitempty"` // e.g. "1.5" for 1.5% of the unpaid amount
+	PeriodDays       int          `json:"periodDays,omitempty"`       // defaults to 30
+	GraceDays        int          `json:"graceDays"`
+	Cap              *utils.Money `json:"cap,omitempty"`
+}
+
+// SetPolicy creates the late-fee policy of a bill type or loan, or replaces the one it has
+func (s *LateFeeService) SetPolicy(ctx context.Context, req SetLateFeePolicyRequest) (*models.LateFeePolicy, error) {
+	policy := &models.LateFeePolicy{
+		ResourceType: req.ResourceType,
+		GraceDays:    req.GraceDays,
+		PeriodDays:   req.PeriodDays,
+	}
+
+	switch req.ResourceType {
+	case "bill":
+		billType := strings.ToLower(strings.TrimSpace(req.BillType))
+		customType := strings.TrimSpace(req.CustomType)
+		switch billType {
+		case "electricity", "gas", "internet":
+			if customType != "" {
+				return nil, errors.New("customType should only be provided when type is 'inne'")
+			}
+		case "inne":
+		default:
+			return nil, fmt.Errorf("invalid bill type: %q", req.BillType)
+		}
+		if req.LoanID != "" {
+			return nil, errors.New("loanId is only used by loan policies")
+		}
+		policy.BillType, policy.CustomType = billType, customType
+	case "loan":
+		if req.BillType != "" || req.CustomType != "" {
+			return nil, errors.New("billType is only used by bill policies")
+		}
+		if req.LoanID != "" {
+			loan, err := s.loans.GetByID(ctx, req.LoanID)
+			if err != nil || loan == nil {
+				return nil, errors.New("loan not found")
+			}
+		}
+		policy.LoanID = req.LoanID
+	default:
+		return nil, errors.New("resourceType must be 'bill' or 'loan'")
+	}
+
+	if req.FlatFee.IsNegative() {
+		return nil, errors.New("flat fee cannot be negative")
+	}
+	policy.FlatFee = req.FlatFee.String()
+
+	percent := strings.Replace(strings.TrimSpace(req.PercentPerPeriod), ",", ".", 1)
+	if percent == "" {
+		percent = "0"
+	}
+	value, ok := new(big.Rat).SetString(percent)
+	if !ok || strings.ContainsAny(percent, "/eE") {
+		return nil, errors.New("invalid percentage")
+	}
+	if value.Sign() < 0 {
+		return nil, errors.New("percentage cannot be negative")
+	}
+	policy.PercentPerPeriod = percent
+
+	if req.FlatFee.IsZero() && value.Sign() == 0 {
+		return nil, errors.New("a policy needs a flat fee or a percentage")
+	}
+	if policy.PeriodDays == 0 {
+		policy.PeriodDays = defaultLateFeePeriodDays
+	}
+	if policy.PeriodDays < 0 {
+		return nil, errors.New("period days must be positive")
+	}
+	if policy.GraceDays < 0 {
+		return nil, errors.New("grace days cannot be negative")
+	}
+	if req.Cap != nil {
+		if !req.Cap.IsPositive() {
+			return nil, errors.New("cap must be positive")
+		}
+		limit := req.Cap.String()
+		policy.Cap = &limit
+	}
+
+	if err := s.policies.Upsert(ctx, policy); err != nil {
+		return nil, fmt.Errorf("failed to save late fee policy: %w", err)
+	}
+	return policy, nil
+}
+
+// GetPolicies returns all late-fee policies
+func (s *LateFeeService) GetPolicies(ctx context.Context) ([]models.LateFeePolicy, error) {
+	return s.policies.List(ctx)
+}
+
+// DeletePolicy deletes a late-fee policy. Fees it accrued are kept.
+func (s *LateFeeService) DeletePolicy(ctx context.Context, id string) error {
+	policy, err := s.policies.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get late fee policy: %w", err)
+	}
+	if policy == nil {
+		return ErrLateFeePolicyNotFound
+	}
+	return s.policies.Delete(ctx, id)
+}
+
+// GetFees returns the fees of a bill or loan, or all fees when no resource is given
+func (s *LateFeeService) GetFees(ctx context.Context, resourceType, resourceID string) ([]models.LateFee, error) {
+	if resourceType == "" && resourceID == "" {
+		return s.fees.List(ctx)
+	}
+	if resourceType != "bill" && resourceType != "loan" {
+		return nil, errors.New("resourceType must be 'bill' or 'loan'")
+	}
+	return s.fees.ListByResource(ctx, resourceType, resourceID)
+}
+
+// WaiveFee cancels an accrued fee. The fee is kept with who waived it and why.
+func (s *LateFeeService) WaiveFee(ctx context.Context, feeID, reason, userID string) (*models.LateFee, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("a reason is required to waive a fee")
+	}
+
+	fee, err := s.fees.GetByID(ctx, feeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get late fee: %w", err)
+	}
+	if fee == nil {
+		return nil, ErrLateFeeNotFound
+	}
+	if fee.Status == LateFeeStatusWaived {
+		return nil, ErrLateFeeWaived
+	}
+
+	now := time.Now()
+	fee.Status = LateFeeStatusWaived
+	fee.WaivedBy = &userID
+	fee.WaivedAt = &now
+	fee.WaiveReason = &reason
+	if err := s.fees.Update(ctx, fee); err != nil {
+		return nil, fmt.Errorf("failed to waive late fee: %w", err)
+	}
+
+	log.Printf("[LATE FEE] Waived: ID=%s, %s %s, %s %s (by user %s)",
+		fee.ID, fee.ResourceType, fee.ResourceID, fee.Amount, fee.Currency, userID)
+	return fee, nil
+}
+
+// lateFeeDebt is an overdue amount a policy charges fees on
+type lateFeeDebt struct {
+	resourceType  string
+	resourceID    string
+	label         string
+	subjectType   string
+	subjectID     string
+	beneficiaryID *string
+	remaining     utils.Money
+	currency      string
+	dueAt         time.Time
+}
+
+// AccrueFees charges the fees that became due by now on overdue bill shares and loans. Every fee is
+// accrued once, so running it again only adds fees of the periods that passed since.
+// Returns the number of fees accrued.
+func (s *LateFeeService) AccrueFees(ctx context.Context, now time.Time) (int, error) {
+	accrued := 0
+
+	// Bill shares, disputed bills wait until the dispute is resolved
+	bills, err := listBillsByStatus(ctx, s.bills, BillStatusPosted, BillStatusAwaitingPayment)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list posted bills: %w", err)
+	}
+	for _, bill := range bills {
+		if bill.PaymentDeadline == nil || now.Before(*bill.PaymentDeadline) {
+			continue
+		}
+		policy, err := s.billPolicy(ctx, &bill)
+		if err != nil {
+			return accrued, err
+		}
+		if policy == nil {
+			continue
+		}
+		shares, err := s.creditService.GetOpenShares(ctx, bill.ID)
+		if err != nil {
+			return accrued, err
+		}
+		for _, share := range shares {
+			n, err := s.accrue(ctx, policy, lateFeeDebt{
+				resourceType: "bill",
+				resourceID:   bill.ID,
+				label:        fmt.Sprintf("rachunek '%s'", getBillTypeName(bill.Type, bill.CustomType)),
+				subjectType:  share.SubjectType,
+				subjectID:    share.SubjectID,
+				remaining:    share.Remaining,
+				currency:     bill.Currency,
+				dueAt:        *bill.PaymentDeadline,
+			}, now)
+			accrued += n
+			if err != nil {
+				return accrued, err
+			}
+		}
+	}
+
+	// Loans, charged to the borrower and owed to the lender
+	var loans []models.Loan
+	for _, status := range []string{"open", "partial"} {
+		byStatus, err := s.loans.ListByStatus(ctx, status)
+		if err != nil {
+			return accrued, fmt.Errorf("failed to list %s loans: %w", status, err)
+		}
+		loans = append(loans, byStatus...)
+	}
+	for _, loan := range loans {
+		if loan.DueDate == nil || now.Before(*loan.DueDate) {
+			continue
+		}
+		policy, err := s.loanPolicy(ctx, &loan)
+		if err != nil {
+			return accrued, err
+		}
+		if policy == nil {
+			continue
+		}
+		paid, err := s.loanPayments.SumByLoanID(ctx, loan.ID)
+		if err != nil {
+			return accrued, fmt.Errorf("failed to sum loan payments: %w", err)
+		}
+		remaining := utils.MoneyFromString(loan.AmountPLN).Sub(utils.MoneyFromString(paid))
+		if !remaining.IsPositive() {
+			continue
+		}
+		lender := loan.LenderID
+		lenderName := "kogoś"
+		if user, err := s.users.GetByID(ctx, lender); err == nil && user != nil {
+			lenderName = user.Name
+		}
+		n, err := s.accrue(ctx, policy, lateFeeDebt{
+			resourceType:  "loan",
+			resourceID:    loan.ID,
+			label:         fmt.Sprintf("pożyczka od %s", lenderName),
+			subjectType:   "user",
+			subjectID:     loan.BorrowerID,
+			beneficiaryID: &lender,
+			remaining:     remaining,
+			currency:      loan.Currency,
+			dueAt:         *loan.DueDate,
+		}, now)
+		accrued += n
+		if err != nil {
+			return accrued, err
+		}
+	}
+
+	if accrued > 0 {
+		log.Printf("[LATE FEE] Accrued %d late fees", accrued)
+	}
+	return accrued, nil
+}
+
+// accrue charges the fees of one overdue debt that are due by now and not charged yet, up to the cap
+func (s *LateFeeService) accrue(ctx context.Context, policy *models.LateFeePolicy, debt lateFeeDebt, now time.Time) (int, error) {
+	start := debt.dueAt.AddDate(0, 0, policy.GraceDays)
+	if now.Before(start) {
+		return 0, nil
+	}
+
+	existing, err := s.fees.ListByResource(ctx, debt.resourceType, debt.resourceID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get late fees: %w", err)
+	}
+	charged := make(map[string]bool)
+	var total utils.Money
+	for _, fee := range existing {
+		if fee.SubjectType != debt.subjectType || fee.SubjectID != debt.subjectID {
+			continue
+		}
+		charged[fmt.Sprintf("%s:%d", fee.Kind, fee.Period)] = true
+		if fee.Status == LateFeeStatusAccrued {
+			total = total.Add(utils.MoneyFromString(fee.Amount))
+		}
+	}
+
+	type due struct {
+		kind   string
+		period int
+		amount utils.Money
+		at     time.Time
+	}
+	var fees []due
+	if flat := utils.MoneyFromString(policy.FlatFee); flat.IsPositive() {
+		fees = append(fees, due{LateFeeKindFlat, 0, flat, start})
+	}
+	if policy.PeriodDays > 0 {
+		for period := 1; ; period++ {
+			at := start.AddDate(0, 0, period*policy.PeriodDays)
+			if at.After(now) {
+				break
+			}
+			amount, err := percentOf(debt.remaining, policy.PercentPerPeriod)
+			if err != nil {
+				return 0, err
+			}
+			if !amount.IsPositive() {
+				break
+			}
+			fees = append(fees, due{LateFeeKindInterest, period, amount, at})
+		}
+	}
+
+	accrued := 0
+	for _, d := range fees {
+		if charged[fmt.Sprintf("%s:%d", d.kind, d.period)] {
+			continue
+		}
+		amount := d.amount
+		if policy.Cap != nil {
+			amount = utils.MinMoney(amount, utils.MoneyFromString(*policy.Cap).Sub(total))
+		}
+		if !amount.IsPositive() {
+			break
+		}
+
+		fee := &models.LateFee{
+			PolicyID:      &policy.ID,
+			ResourceType:  debt.resourceType,
+			ResourceID:    debt.resourceID,
+			SubjectType:   debt.subjectType,
+			SubjectID:     debt.subjectID,
+			BeneficiaryID: debt.beneficiaryID,
+			Kind:          d.kind,
+			Period:        d.period,
+			Amount:        amount.String(),
+			Currency:      debt.currency,
+			AccruedAt:     d.at,
+			Status:        LateFeeStatusAccrued,
+		}
+		if err := s.fees.Create(ctx, fee); err != nil {
+			return accrued, fmt.Errorf("failed to accrue late fee: %w", err)
+		}
+		total = total.Add(amount)
+		accrued++
+
+		s.notifyFee(ctx, fee, debt.label)
+	}
+	return accrued, nil
+}
+
+// billPolicy returns the policy of a bill's custom type, or else of its type
+func (s *LateFeeService) billPolicy(ctx context.Context, bill *models.Bill) (*models.LateFeePolicy, error) {
+	if bill.Type == "inne" && bill.CustomType != nil && strings.TrimSpace(*bill.CustomType) != "" {
+		policy, err := s.policies.Find(ctx, "bill", bill.Type, strings.TrimSpace(*bill.CustomType), "")
+		if err != nil || policy != nil {
+			return policy, err
+		}
+	}
+	return s.policies.Find(ctx, "bill", bill.Type, "", "")
+}
+
+// loanPolicy returns the policy of a loan, or else the default loan policy
+func (s *LateFeeService) loanPolicy(ctx context.Context, loan *models.Loan) (*models.LateFeePolicy, error) {
+	policy, err := s.policies.Find(ctx, "loan", "", "", loan.ID)
+	if err != nil || policy != nil {
+		return policy, err
+	}
+	return s.policies.Find(ctx, "loan", "", "", "")
+}
+
+// notifyFee tells the users charged a fee about it, under the bill or loan notification preference
+func (s *LateFeeService) notifyFee(ctx context.Context, fee *models.LateFee, label string) {
+	if s.notificationService == nil {
+		return
+	}
+	userIDs := []string{fee.SubjectID}
+	if fee.SubjectType == "group" {
+		members, err := s.users.ListByGroupID(ctx, fee.SubjectID)
+		if err != nil {
+			log.Printf("[LATE FEE] Failed to get group members: %v", err)
+			return
+		}
+		userIDs = userIDs[:0]
+		for _, member := range members {
+			userIDs = append(userIDs, member.ID)
+		}
+	}
+
+	for _, userID := range userIDs {
+		userID := userID
+		now := time.Now()
+		_ = s.notificationService.CreateNotification(ctx, &models.Notification{
+			UserID:       &userID,
+			Channel:      "app",
+			TemplateID:   fee.ResourceType,
+			ScheduledFor: now,
+			SentAt:       &now,
+			Status:       "sent",
+			Title:        "Opłata za opóźnienie",
+			Body:         fmt.Sprintf("Naliczono %s opłaty za opóźnienie: %s", FormatAmount(utils.MoneyFromString(fee.Amount), fee.Currency), label),
+		})
+	}
+}
+
+// percentOf returns percent % of an amount, rounded to the grosz
+func percentOf(amount utils.Money, percent string) (utils.Money, error) {
+	value, ok := new(big.Rat).SetString(percent)
+	if !ok {
+		return 0, fmt.Errorf("invalid percentage: %q", percent)
+	}
+	if value.Sign() == 0 {
+		return 0, nil
+	}
+	return amount.MulDecimal(value.Quo(value, big.NewRat(100, 1)).FloatString(12))
+}
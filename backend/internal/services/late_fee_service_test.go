@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccrueLateFees(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	billService, paymentService, _ := newTestCreditServices(repos)
+	allocationService := NewAllocationService(repos.Users, repos.Residencies, repos.BillResidents, repos.Groups, repos.Consumptions, repos.Allocations,
+		repos.Bills, repos.BillSplits, repos.BillItems, repos.BillTariffZones, repos.Meters)
+	creditService := NewCreditService(repos.CreditEntries, repos.Payments, repos.Users, allocationService)
+	lateFeeService := NewLateFeeService(repos.LateFeePolicies, repos.LateFees, repos.Bills, repos.Loans, repos.LoanPayments,
+		repos.Users, creditService, newTestNotificationService(repos))
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+	carol := createTestUser(t, repos, "Carol")
+	now := time.Now()
+
+	_, err := lateFeeService.SetPolicy(ctx, SetLateFeePolicyRequest{ResourceType: "bill", BillType: "water", FlatFee: utils.NewMoney(10, 0)})
+	assert.Error(t, err)
+	_, err = lateFeeService.SetPolicy(ctx, SetLateFeePolicyRequest{ResourceType: "bill", BillType: "internet"})
+	assert.Error(t, err)
+	_, err = lateFeeService.SetPolicy(ctx, SetLateFeePolicyRequest{ResourceType: "bill", BillType: "internet", FlatFee: utils.NewMoney(10, 0), PercentPerPeriod: "-1"})
+	assert.Error(t, err)
+
+	// 10 zł after 7 days, then 2% of the unpaid share every 30 days, up to 10.50 zł
+	limit := utils.NewMoney(10, 50)
+	policy, err := lateFeeService.SetPolicy(ctx, SetLateFeePolicyRequest{
+		ResourceType:     "bill",
+		BillType:         "internet",
+		FlatFee:          utils.NewMoney(10, 0),
+		PercentPerPeriod: "2",
+		GraceDays:        7,
+		Cap:              &limit,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 30, policy.PeriodDays)
+	_, err = lateFeeService.SetPolicy(ctx, SetLateFeePolicyRequest{ResourceType: "loan", FlatFee: utils.NewMoney(5, 0), GraceDays: 7})
+	require.NoError(t, err)
+
+	deadline := now.AddDate(0, 0, -40)
+	bill, err := billService.CreateBill(ctx, CreateBillRequest{
+		Type:            "internet",
+		PeriodStart:     now.AddDate(0, -2, 0),
+		PeriodEnd:       now.AddDate(0, -1, 0),
+		PaymentDeadline: &deadline,
+		TotalAmountPLN:  utils.NewMoney(90, 0),
+	}, alice.ID)
+	require.NoError(t, err)
+	require.NoError(t, billService.PostBill(ctx, bill.ID))
+	_, err = paymentService.RecordPayment(ctx, RecordPaymentRequest{BillID: bill.ID, Amount: utils.NewMoney(30, 0)}, bob.ID)
+	require.NoError(t, err)
+
+	dueDate := now.AddDate(0, 0, -10)
+	loan := &models.Loan{LenderID: alice.ID, BorrowerID: bob.ID, AmountPLN: "100.00", Currency: DefaultCurrency, DueDate: &dueDate, Status: "open"}
+	require.NoError(t, repos.Loans.Create(ctx, loan))
+
+	// Bob paid his share in full, Alice and Carol each owe 30.00
+	accrued, err := lateFeeService.AccrueFees(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 5, accrued)
+
+	fees, err := lateFeeService.GetFees(ctx, "bill", bill.ID)
+	require.NoError(t, err)
+	charged := make(map[string][]string)
+	for _, fee := range fees {
+		charged[fee.SubjectID] = append(charged[fee.SubjectID], fee.Kind+" "+fee.Amount)
+	}
+	assert.Equal(t, map[string][]string{
+		alice.ID: {"flat 10.00", "interest 0.50"},
+		carol.ID: {"flat 10.00", "interest 0.50"},
+	}, charged)
+
+	loanFees, err := lateFeeService.GetFees(ctx, "loan", loan.ID)
+	require.NoError(t, err)
+	require.Len(t, loanFees, 1)
+	assert.Equal(t, bob.ID, loanFees[0].SubjectID)
+	assert.Equal(t, alice.ID, *loanFees[0].BeneficiaryID)
+	assert.Equal(t, "5.00", loanFees[0].Amount)
+
+	notifications, err := repos.Notifications.ListByUserID(ctx, carol.ID, 20)
+	require.NoError(t, err)
+	count := 0
+	for _, notification := range notifications {
+		if notification.Title == "Opłata za opóźnienie" {
+			count++
+		}
+	}
+	assert.Equal(t, 2, count)
+
+	// Fees are accrued once
+	accrued, err = lateFeeService.AccrueFees(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, accrued)
+
+	// A waived fee is kept with the reason and reversed on the statement
+	_, err = lateFeeService.WaiveFee(ctx, loanFees[0].ID, " ", alice.ID)
+	assert.Error(t, err)
+	waived, err := lateFeeService.WaiveFee(ctx, loanFees[0].ID, "Paid in cash", alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, LateFeeStatusWaived, waived.Status)
+	assert.Equal(t, "Paid in cash", *waived.WaiveReason)
+	_, err = lateFeeService.WaiveFee(ctx, loanFees[0].ID, "Again", alice.ID)
+	assert.ErrorIs(t, err, ErrLateFeeWaived)
+
+	statement, err := newTestLedgerService(repos).GetStatement(ctx, bob.ID, nil, nil)
+	require.NoError(t, err)
+	var kinds []string
+	for _, entry := range statement.Entries {
+		if entry.ReferenceID == waived.ID {
+			kinds = append(kinds, entry.Kind+" "+entry.Amount.String())
+		}
+	}
+	assert.Equal(t, []string{"late_fee -5.00", "late_fee_waived 5.00"}, kinds)
+}
@@ -0,0 +1,439 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+// Ledger entry kinds
+const (
+	LedgerKindBillAllocation     = "bill_allocation"
+	LedgerKindBillPayment        = "bill_payment"
+	LedgerKindLoanGiven          = "loan_given"
+	LedgerKindLoanReceived       = "loan_received"
+	LedgerKindLoanRepaid         = "loan_repaid"
+	LedgerKindLoanRepayment      = "loan_repayment_received"
+	LedgerKindSupplyContribution = "supply_contribution"
+	LedgerKindSupplyRefund       = "supply_refund"
+	LedgerKindLateFee            = "late_fee"
+	LedgerKindLateFeeReceived    = "late_fee_received"
+	LedgerKindLateFeeWaived      = "late_fee_waived"
+)
+
+type LedgerService struct {
+	users               repository.UserRepository
+	bills               repository.BillRepository
+	payments            repository.PaymentRepository
+	loans               repository.LoanRepository
+	loanPayments        repository.LoanPaymentRepository
+	supplyContributions repository.SupplyContributionRepository
+	supplyItemHistory   repository.SupplyItemHistoryRepository
+	lateFees            repository.LateFeeRepository
+	allocationService   *AllocationService
+	currencyService     *CurrencyService
+}
+
+func NewLedgerService(
+	users repository.UserRepository,
+	bills repository.BillRepository,
+	payments repository.PaymentRepository,
+	loans repository.LoanRepository,
+	loanPayments repository.LoanPaymentRepository,
+	supplyContributions repository.SupplyContributionRepository,
+	supplyItemHistory repository.SupplyItemHistoryRepository,
+	lateFees repository.LateFeeRepository,
+	allocationService *AllocationService,
+	currencyService *CurrencyService,
+) *LedgerService {
+	return &LedgerService{
+		users:               users,
+		bills:               bills,
+		payments:            payments,
+		loans:               loans,
+		loanPayments:        loanPayments,
+		supplyContributions: supplyContributions,
+		supplyItemHistory:   supplyItemHistory,
+		lateFees:            lateFees,
+		allocationService:   allocationService,
+		currencyService:     currencyService,
+	}
+}
+
+// LedgerEntry is one line of a user's statement. Money the user put in (bill payments, loans
+// given, repayments made, supply contributions) is a credit; money the user was charged or
+// received (bill shares, loans taken, repayments received, refunds) is a debit.
+type LedgerEntry struct {
+	Date        time.Time   `json:"date"`
+	Kind        string      `json:"kind"`
+	Description string      `json:"description"`
+	ReferenceID string      `json:"referenceId"` // bill, payment, loan, loan payment, contribution or history ID
+	Amount      utils.Money `json:"amount"`      // signed, in Currency; positive is a credit
+	Currency    string      `json:"currency"`
+	Debit       utils.Money `json:"debit"`   // in the base currency
+	Credit      utils.Money `json:"credit"`  // in the base currency
+	Balance     utils.Money `json:"balance"` // running balance in the base currency after this entry
+}
+
+// LedgerStatement is a user's chronological account statement.
+// A positive balance means the user put more into the household than they were charged.
+type LedgerStatement struct {
+	UserID         string        `json:"userId"`
+	UserName       string        `json:"userName"`
+	Currency       string        `json:"currency"` // base currency of debits, credits and balances
+	From           *time.Time    `json:"from,omitempty"`
+	To             *time.Time    `json:"to,omitempty"`
+	OpeningBalance utils.Money   `json:"openingBalance"` // balance of everything before From
+	ClosingBalance utils.Money   `json:"closingBalance"`
+	Entries        []LedgerEntry `json:"entries"`
+}
+
+// GetStatement builds a user's statement, optionally limited to entries dated within [from, to]
+func (s *LedgerService) GetStatement(ctx context.Context, userID string, from, to *time.Time) (*LedgerStatement, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	baseCurrency, err := s.currencyService.BaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.collectEntries(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date.Before(entries[j].Date)
+	})
+
+	statement := &LedgerStatement{
+		UserID:   user.ID,
+		UserName: user.Name,
+		Currency: baseCurrency,
+		From:     from,
+		To:       to,
+		Entries:  []LedgerEntry{},
+	}
+
+	var balance utils.Money
+	for _, entry := range entries {
+		if to != nil && entry.Date.After(*to) {
+			break
+		}
+
+		converted, err := s.currencyService.Convert(ctx, entry.Amount, entry.Currency, entry.Date)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %s %s: %w", entry.Kind, entry.ReferenceID, err)
+		}
+		if converted.IsNegative() {
+			entry.Debit = converted.Neg()
+		} else {
+			entry.Credit = converted
+		}
+		balance = balance.Add(converted)
+		entry.Balance = balance
+
+		if from != nil && entry.Date.Before(*from) {
+			statement.OpeningBalance = balance
+			continue
+		}
+		statement.Entries = append(statement.Entries, entry)
+	}
+	statement.ClosingBalance = balance
+
+	return statement, nil
+}
+
+// collectEntries gathers every money movement involving the user, unsorted and without balances
+func (s *LedgerService) collectEntries(ctx context.Context, user *models.User) ([]LedgerEntry, error) {
+	var entries []LedgerEntry
+
+	bills, err := s.bills.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	billByID := make(map[string]models.Bill, len(bills))
+	for _, bill := range bills {
+		billByID[bill.ID] = bill
+	}
+
+	// Shares of posted bills
+	for _, bill := range bills {
+		if !isIssuedBill(bill.Status) {
+			continue
+		}
+		share, err := s.billShare(ctx, &bill, user)
+		if err != nil {
+			return nil, err
+		}
+		if share.IsZero() {
+			continue
+		}
+		entries = append(entries, LedgerEntry{
+			Date:        bill.CreatedAt,
+			Kind:        LedgerKindBillAllocation,
+			Description: fmt.Sprintf("Udział w rachunku %s (%s – %s)", billLabel(&bill), bill.PeriodStart.Format("2006-01-02"), bill.PeriodEnd.Format("2006-01-02")),
+			ReferenceID: bill.ID,
+			Amount:      share.Neg(),
+			Currency:    bill.Currency,
+		})
+	}
+
+	// Bill payments, including settle-up corrections. Overpayments stay in full here: they
+	// raise the balance, and the later bill share they cover lowers it again.
+	payments, err := s.payments.ListByPayerID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	for _, payment := range payments {
+		bill, ok := billByID[payment.BillID]
+		if !ok {
+			continue
+		}
+		entries = append(entries, LedgerEntry{
+			Date:        payment.PaidAt,
+			Kind:        LedgerKindBillPayment,
+			Description: fmt.Sprintf("Płatność za rachunek %s", billLabel(&bill)),
+			ReferenceID: payment.ID,
+			Amount:      utils.MoneyFromString(payment.AmountPLN),
+			Currency:    bill.Currency,
+		})
+	}
+
+	// Loans and their repayments
+	userNames := make(map[string]string)
+	nameOf := func(userID string) string {
+		if name, ok := userNames[userID]; ok {
+			return name
+		}
+		name := "Nieznany użytkownik"
+		if u, err := s.users.GetByID(ctx, userID); err == nil && u != nil {
+			name = u.Name
+		}
+		userNames[userID] = name
+		return name
+	}
+
+	lent, err := s.loans.ListByLenderID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	borrowed, err := s.loans.ListByBorrowerID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	for _, loan := range append(lent, borrowed...) {
+		isLender := loan.LenderID == user.ID
+		amount := utils.MoneyFromString(loan.AmountPLN)
+
+		entry := LedgerEntry{
+			Date:        loan.CreatedAt,
+			ReferenceID: loan.ID,
+			Currency:    loan.Currency,
+		}
+		if isLender {
+			entry.Kind = LedgerKindLoanGiven
+			entry.Description = fmt.Sprintf("Pożyczka dla %s", nameOf(loan.BorrowerID))
+			entry.Amount = amount
+		} else {
+			entry.Kind = LedgerKindLoanReceived
+			entry.Description = fmt.Sprintf("Pożyczka od %s", nameOf(loan.LenderID))
+			entry.Amount = amount.Neg()
+		}
+		entries = append(entries, entry)
+
+		loanPayments, err := s.loanPayments.ListByLoanID(ctx, loan.ID)
+		if err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		for _, payment := range loanPayments {
+			paid := utils.MoneyFromString(payment.AmountPLN)
+			entry := LedgerEntry{
+				Date:        payment.PaidAt,
+				ReferenceID: payment.ID,
+				Currency:    loan.Currency,
+			}
+			if isLender {
+				entry.Kind = LedgerKindLoanRepayment
+				entry.Description = fmt.Sprintf("Spłata od %s", nameOf(loan.BorrowerID))
+				entry.Amount = paid.Neg()
+			} else {
+				entry.Kind = LedgerKindLoanRepaid
+				entry.Description = fmt.Sprintf("Spłata dla %s", nameOf(loan.LenderID))
+				entry.Amount = paid
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	// Supply budget, which is kept in the base currency
+	baseCurrency, err := s.currencyService.BaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	contributions, err := s.supplyContributions.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	for _, contribution := range contributions {
+		entries = append(entries, LedgerEntry{
+			Date:        contribution.CreatedAt,
+			Kind:        LedgerKindSupplyContribution,
+			Description: fmt.Sprintf("Wpłata na zakupy (%s)", contribution.Type),
+			ReferenceID: contribution.ID,
+			Amount:      utils.MoneyFromString(contribution.AmountPLN),
+			Currency:    baseCurrency,
+		})
+	}
+
+	history, err := s.supplyItemHistory.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	for _, h := range history {
+		if h.Action != "refund" || h.CostPLN == nil {
+			continue
+		}
+		entries = append(entries, LedgerEntry{
+			Date:        h.CreatedAt,
+			Kind:        LedgerKindSupplyRefund,
+			Description: "Zwrot za zakup",
+			ReferenceID: h.ID,
+			Amount:      utils.MoneyFromString(*h.CostPLN).Neg(),
+			Currency:    baseCurrency,
+		})
+	}
+
+	// Late fees the user was charged or is owed. A waived fee stays on the statement and is
+	// reversed on the day it was waived.
+	charged, err := s.lateFees.ListBySubject(ctx, "user", user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if user.GroupID != nil {
+		groupFees, err := s.lateFees.ListBySubject(ctx, "group", *user.GroupID)
+		if err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		charged = append(charged, groupFees...)
+	}
+	received, err := s.lateFees.ListByBeneficiaryID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	for _, fee := range append(charged, received...) {
+		var amount utils.Money
+		var kind, description string
+		if fee.BeneficiaryID != nil && *fee.BeneficiaryID == user.ID {
+			amount = utils.MoneyFromString(fee.Amount)
+			kind = LedgerKindLateFeeReceived
+			description = fmt.Sprintf("Opłata za opóźnienie od %s", nameOf(fee.SubjectID))
+		} else {
+			share, err := s.lateFeeShare(ctx, &fee, user)
+			if err != nil {
+				return nil, err
+			}
+			amount = share.Neg()
+			kind = LedgerKindLateFee
+			description = "Opłata za opóźnienie w spłacie pożyczki"
+			if fee.ResourceType == "bill" {
+				description = "Opłata za opóźnienie w zapłacie rachunku"
+				if bill, ok := billByID[fee.ResourceID]; ok {
+					description = fmt.Sprintf("Opłata za opóźnienie w zapłacie rachunku %s", billLabel(&bill))
+				}
+			}
+		}
+		if amount.IsZero() {
+			continue
+		}
+		entries = append(entries, LedgerEntry{
+			Date:        fee.AccruedAt,
+			Kind:        kind,
+			Description: description,
+			ReferenceID: fee.ID,
+			Amount:      amount,
+			Currency:    fee.Currency,
+		})
+		if fee.Status == LateFeeStatusWaived && fee.WaivedAt != nil {
+			entries = append(entries, LedgerEntry{
+				Date:        *fee.WaivedAt,
+				Kind:        LedgerKindLateFeeWaived,
+				Description: "Umorzono: " + description,
+				ReferenceID: fee.ID,
+				Amount:      amount.Neg(),
+				Currency:    fee.Currency,
+			})
+		}
+	}
+
+	return entries, nil
+}
+
+// lateFeeShare returns the user's part of a late fee, divided like a group's bill share
+func (s *LedgerService) lateFeeShare(ctx context.Context, fee *models.LateFee, user *models.User) (utils.Money, error) {
+	amount := utils.MoneyFromString(fee.Amount)
+	if fee.SubjectType != "group" {
+		return amount, nil
+	}
+	members, err := s.users.ListByGroupID(ctx, fee.SubjectID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get group members: %w", err)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].ID < members[j].ID
+	})
+	parts := amount.Split(len(members))
+	for i, member := range members {
+		if member.ID == user.ID {
+			return parts[i], nil
+		}
+	}
+	return 0, nil
+}
+
+// billShare returns the user's part of a bill. A group's share is divided evenly among its
+// current members, the first members by ID taking the leftover grosze.
+func (s *LedgerService) billShare(ctx context.Context, bill *models.Bill, user *models.User) (utils.Money, error) {
+	breakdown, err := s.allocationService.GetAllocationBreakdown(ctx, bill.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get allocation of bill %s: %w", bill.ID, err)
+	}
+
+	for _, entry := range breakdown {
+		if entry.SubjectType == "user" && entry.SubjectID == user.ID {
+			return entry.Amount, nil
+		}
+		if entry.SubjectType == "group" && user.GroupID != nil && entry.SubjectID == *user.GroupID {
+			members, err := s.users.ListByGroupID(ctx, entry.SubjectID)
+			if err != nil {
+				return 0, fmt.Errorf("failed to get group members: %w", err)
+			}
+			sort.Slice(members, func(i, j int) bool {
+				return members[i].ID < members[j].ID
+			})
+			parts := entry.Amount.Split(len(members))
+			for i, member := range members {
+				if member.ID == user.ID {
+					return parts[i], nil
+				}
+			}
+		}
+	}
+	return 0, nil
+}
+
+// billLabel returns the name a bill is shown under
+func billLabel(bill *models.Bill) string {
+	if bill.Type == "inne" && bill.CustomType != nil && *bill.CustomType != "" {
+		return *bill.CustomType
+	}
+	return bill.Type
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestLedgerService wires a ledger service against the test database
+func newTestLedgerService(repos *repository.Repositories) *LedgerService {
+	currencyService := NewCurrencyService(repos.ExchangeRates, NewAppSettingsService(repos.AppSettings))
+	allocationService := NewAllocationService(repos.Users, repos.Residencies, repos.BillResidents, repos.Groups, repos.Consumptions, repos.Allocations,
+		repos.Bills, repos.BillSplits, repos.BillItems, repos.BillTariffZones, repos.Meters)
+	return NewLedgerService(repos.Users, repos.Bills, repos.Payments, repos.Loans, repos.LoanPayments,
+		repos.SupplyContributions, repos.SupplyItemHistory, repos.LateFees, allocationService, currencyService)
+}
+
+func TestLedgerStatement(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	ledgerService := newTestLedgerService(repos)
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+	now := time.Now()
+
+	// Alice's share of the internet bill is 50.00 and she pays 60.00 of it
+	bill := &models.Bill{
+		Type:           "internet",
+		PeriodStart:    now.AddDate(0, -1, 0),
+		PeriodEnd:      now,
+		TotalAmountPLN: "100.00",
+		Currency:       DefaultCurrency,
+		Status:         "posted",
+	}
+	require.NoError(t, repos.Bills.Create(ctx, bill))
+	require.NoError(t, repos.Bills.Create(ctx, &models.Bill{
+		Type:           "gas",
+		PeriodStart:    now.AddDate(0, -1, 0),
+		PeriodEnd:      now,
+		TotalAmountPLN: "80.00",
+		Currency:       DefaultCurrency,
+		Status:         "draft", // not charged yet
+	}))
+	require.NoError(t, repos.Payments.Create(ctx, &models.Payment{
+		BillID: bill.ID, PayerUserID: alice.ID, AmountPLN: "60.00", PaidAt: now,
+	}))
+
+	// Alice lends Bob 40.00 and gets 10.00 back
+	loan := &models.Loan{LenderID: alice.ID, BorrowerID: bob.ID, AmountPLN: "40.00", Currency: DefaultCurrency, Status: "partial"}
+	require.NoError(t, repos.Loans.Create(ctx, loan))
+	loans, err := repos.Loans.ListByLenderID(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, loans, 1)
+	require.NoError(t, repos.LoanPayments.Create(ctx, &models.LoanPayment{
+		LoanID: loans[0].ID, AmountPLN: "10.00", PaidAt: now.Add(time.Minute),
+	}))
+
+	// She puts 5.00 into the supply budget and is refunded 20.00 for a purchase
+	require.NoError(t, repos.SupplyContributions.Create(ctx, &models.SupplyContribution{
+		UserID: alice.ID, AmountPLN: "5.00", PeriodStart: now, PeriodEnd: now, Type: "manual",
+	}))
+	cost := "20.00"
+	require.NoError(t, repos.SupplyItems.Create(ctx, &models.SupplyItem{
+		ID: "item-1", Name: "Soap", Category: "cleaning", Unit: "pcs", Priority: 1, AddedByUserID: alice.ID, AddedAt: now,
+	}))
+	require.NoError(t, repos.SupplyItemHistory.Create(ctx, &models.SupplyItemHistory{
+		SupplyItemID: "item-1", UserID: alice.ID, Action: "refund", CostPLN: &cost,
+	}))
+
+	statement, err := ledgerService.GetStatement(ctx, alice.ID, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, statement.Entries, 6)
+
+	kinds := make(map[string]utils.Money)
+	descriptions := make(map[string]string)
+	var debits, credits utils.Money
+	for _, entry := range statement.Entries {
+		kinds[entry.Kind] = entry.Amount
+		descriptions[entry.Kind] = entry.Description
+		debits = debits.Add(entry.Debit)
+		credits = credits.Add(entry.Credit)
+	}
+	assert.Equal(t, utils.Money(-5000), kinds[LedgerKindBillAllocation])
+	assert.Equal(t, utils.Money(6000), kinds[LedgerKindBillPayment])
+	assert.Equal(t, utils.Money(4000), kinds[LedgerKindLoanGiven])
+	assert.Equal(t, utils.Money(-1000), kinds[LedgerKindLoanRepayment])
+	assert.Equal(t, utils.Money(500), kinds[LedgerKindSupplyContribution])
+	assert.Equal(t, utils.Money(-2000), kinds[LedgerKindSupplyRefund])
+	assert.Equal(t, "Pożyczka dla Bob", descriptions[LedgerKindLoanGiven])
+
+	// 60 + 40 + 5 - 50 - 10 - 20
+	assert.Equal(t, utils.Money(2500), statement.ClosingBalance)
+	assert.Equal(t, statement.ClosingBalance, credits.Sub(debits))
+	assert.Equal(t, statement.ClosingBalance, statement.Entries[len(statement.Entries)-1].Balance)
+	assert.True(t, statement.OpeningBalance.IsZero())
+
+	bobStatement, err := ledgerService.GetStatement(ctx, bob.ID, nil, nil)
+	require.NoError(t, err)
+	// Bob's unpaid share, the loan and his repayment
+	assert.Equal(t, utils.Money(-5000-4000+1000), bobStatement.ClosingBalance)
+
+	t.Run("Entries before the range go into the opening balance", func(t *testing.T) {
+		from := now.Add(time.Hour)
+		filtered, err := ledgerService.GetStatement(ctx, alice.ID, &from, nil)
+		require.NoError(t, err)
+		assert.Empty(t, filtered.Entries)
+		assert.Equal(t, utils.Money(2500), filtered.OpeningBalance)
+		assert.Equal(t, utils.Money(2500), filtered.ClosingBalance)
+	})
+
+	t.Run("Entries after the range are left out", func(t *testing.T) {
+		to := now.Add(-time.Hour)
+		filtered, err := ledgerService.GetStatement(ctx, alice.ID, nil, &to)
+		require.NoError(t, err)
+		assert.Empty(t, filtered.Entries)
+		assert.True(t, filtered.ClosingBalance.IsZero())
+	})
+}
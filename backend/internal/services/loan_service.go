@@ -19,7 +19,9 @@ type LoanService struct {
 	loanPayments        repository.LoanPaymentRepository
 	users               repository.UserRepository
 	groups              repository.GroupRepository
+	txManager           repository.TxManager
 	notificationService *NotificationService
+	currencyService     *CurrencyService
 }
 
 func NewLoanService(
@@ -27,41 +29,46 @@ func NewLoanService(
 	loanPayments repository.LoanPaymentRepository,
 	users repository.UserRepository,
 	groups repository.GroupRepository,
+	txManager repository.TxManager,
 	notificationService *NotificationService,
+	currencyService *CurrencyService,
 ) *LoanService {
 	return &LoanService{
 		loans:               loans,
 		loanPayments:        loanPayments,
 		users:               users,
 		groups:              groups,
+		txManager:           txManager,
 		notificationService: notificationService,
+		currencyService:     currencyService,
 	}
 }
 
 type CreateLoanRequest struct {
-	LenderID   string     `json:"lenderId"`
-	BorrowerID string     `json:"borrowerId"`
-	AmountPLN  float64    `json:"amountPLN"`
-	Note       *string    `json:"note,omitempty"`
-	DueDate    *time.Time `json:"dueDate,omitempty"`
+	LenderID   string      `json:"lenderId"`
+	BorrowerID string      `json:"borrowerId"`
+	AmountPLN  utils.Money `json:"amountPLN"`          // amount in Currency
+	Currency   string      `json:"currency,omitempty"` // defaults to the household base currency
+	Note       *string     `json:"note,omitempty"`
+	DueDate    *time.Time  `json:"dueDate,omitempty"`
 }
 
 type CreateLoanPaymentRequest struct {
-	LoanID    string    `json:"loanId"`
-	AmountPLN float64   `json:"amountPLN"`
-	PaidAt    time.Time `json:"paidAt"`
-	Note      *string   `json:"note,omitempty"`
+	LoanID    string      `json:"loanId"`
+	AmountPLN utils.Money `json:"amountPLN"` // in the currency of the loan
+	PaidAt    time.Time   `json:"paidAt"`
+	Note      *string     `json:"note,omitempty"`
 }
 
 type CompensationResult struct {
-	CompensationsPerformed int     `json:"compensationsPerformed"`
-	TotalAmountCompensated float64 `json:"totalAmountCompensated"`
+	CompensationsPerformed int         `json:"compensationsPerformed"`
+	TotalAmountCompensated utils.Money `json:"totalAmountCompensated"`
 }
 
 type Balance struct {
-	UserID string  `json:"userId"`
-	Owed   float64 `json:"owed"`  // Money this user owes to others
-	Owing  float64 `json:"owing"` // Money others owe to this user
+	UserID string      `json:"userId"`
+	Owed   utils.Money `json:"owed"`  // Money this user owes to others
+	Owing  utils.Money `json:"owing"` // Money others owe to this user
 }
 
 type PairwiseBalance struct {
@@ -74,6 +81,7 @@ type PairwiseBalance struct {
 	ToUserGroupID     *string `json:"toUserGroupId,omitempty"`
 	ToUserGroupName   *string `json:"toUserGroupName,omitempty"`
 	NetAmount         string  `json:"netAmount"`
+	Currency          string  `json:"currency"` // household base currency NetAmount is expressed in
 }
 
 // CreateLoan creates a new loan with automatic debt offsetting
@@ -82,10 +90,19 @@ func (s *LoanService) CreateLoan(ctx context.Context, req CreateLoanRequest) (*m
 		return nil, errors.New("lender and borrower cannot be the same user")
 	}
 
-	if req.AmountPLN <= 0 {
+	if !req.AmountPLN.IsPositive() {
 		return nil, errors.New("loan amount must be positive")
 	}
 
+	currency, err := s.currencyService.ResolveCurrency(ctx, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	// Balances are netted in the base currency, so foreign loans need a rate
+	if err := s.currencyService.EnsureConvertible(ctx, currency, time.Now()); err != nil {
+		return nil, err
+	}
+
 	// Get user names for logging
 	lender, _ := s.users.GetByID(ctx, req.LenderID)
 	borrower, _ := s.users.GetByID(ctx, req.BorrowerID)
@@ -102,7 +119,7 @@ func (s *LoanService) CreateLoan(ctx context.Context, req CreateLoanRequest) (*m
 	if req.Note != nil {
 		noteStr = *req.Note
 	}
-	log.Printf("[LOAN] Creating loan: %s → %s, amount: %.2f PLN, note: %q", lenderName, borrowerName, req.AmountPLN, noteStr)
+	log.Printf("[LOAN] Creating loan: %s → %s, amount: %s %s, note: %q", lenderName, borrowerName, req.AmountPLN, currency, noteStr)
 
 	// Verify users exist
 	for _, userID := range []string{req.LenderID, req.BorrowerID} {
@@ -112,21 +129,61 @@ func (s *LoanService) CreateLoan(ctx context.Context, req CreateLoanRequest) (*m
 		}
 	}
 
+	// Compensation, offsets and the new loan are committed together,
+	// so a failure part way does not leave offset payments behind
+	var loan *models.Loan
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		loan, err = s.offsetAndCreateLoan(ctx, req, currency, lenderName, borrowerName)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Notify borrower about new loan once it is committed (fully offset loans are already settled)
+	if loan.Status == "open" && s.notificationService != nil {
+		borrowerID := req.BorrowerID
+		_ = s.notificationService.CreateNotification(ctx, &models.Notification{
+			UserID:     &borrowerID,
+			TemplateID: "loan_created",
+			Title:      "Nowa pożyczka",
+			Body:       fmt.Sprintf("%s pożyczył/a Ci %s", lenderName, FormatAmount(utils.MoneyFromString(loan.AmountPLN), currency)),
+		})
+	}
+
+	return loan, nil
+}
+
+// offsetAndCreateLoan does the writes of CreateLoan; it must run inside a transaction
+func (s *LoanService) offsetAndCreateLoan(ctx context.Context, req CreateLoanRequest, currency, lenderName, borrowerName string) (*models.Loan, error) {
+	noteStr := ""
+	if req.Note != nil {
+		noteStr = *req.Note
+	}
+
 	// Perform group compensation on existing loans first
 	compResult, err := s.PerformGroupCompensation(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("group compensation failed: %w", err)
 	}
 	if compResult.CompensationsPerformed > 0 {
-		log.Printf("[LOAN] Group compensation performed: %d compensations, total %.2f PLN", compResult.CompensationsPerformed, compResult.TotalAmountCompensated)
+		log.Printf("[LOAN] Group compensation performed: %d compensations, total %s", compResult.CompensationsPerformed, compResult.TotalAmountCompensated)
 	}
 
 	// Check for reverse debt (borrower owes lender)
-	// Find open/partial loans where new borrower is the lender and new lender is the borrower
-	reverseLoans, err := s.loans.ListOpenBetweenUsers(ctx, req.BorrowerID, req.LenderID)
+	// Find open/partial loans where new borrower is the lender and new lender is the borrower.
+	// Only loans in the same currency are offset, since the offset is recorded as a repayment of the reverse loan.
+	openReverseLoans, err := s.loans.ListOpenBetweenUsers(ctx, req.BorrowerID, req.LenderID)
 	if err != nil {
 		return nil, fmt.Errorf("database error: %w", err)
 	}
+	reverseLoans := make([]models.Loan, 0, len(openReverseLoans))
+	for _, reverseLoan := range openReverseLoans {
+		if reverseLoan.Currency == currency {
+			reverseLoans = append(reverseLoans, reverseLoan)
+		}
+	}
 
 	if len(reverseLoans) > 0 {
 		log.Printf("[LOAN] Found %d reverse loans to offset (where %s lent to %s)", len(reverseLoans), borrowerName, lenderName)
@@ -135,40 +192,37 @@ func (s *LoanService) CreateLoan(ctx context.Context, req CreateLoanRequest) (*m
 	// If there are reverse debts, offset them
 	remainingAmount := req.AmountPLN
 	for _, reverseLoan := range reverseLoans {
-		if remainingAmount <= 0 {
+		if !remainingAmount.IsPositive() {
 			break
 		}
 
 		// Calculate how much is remaining on the reverse loan
-		reverseLoanAmount := utils.DecimalStringToFloat(reverseLoan.AmountPLN)
+		reverseLoanAmount := utils.MoneyFromString(reverseLoan.AmountPLN)
 		totalPaid, err := s.getTotalPaidForLoan(ctx, reverseLoan.ID)
 		if err != nil {
 			return nil, err
 		}
-		reverseRemaining := reverseLoanAmount - totalPaid
+		reverseRemaining := reverseLoanAmount.Sub(totalPaid)
 
-		if reverseRemaining <= 0 {
+		if !reverseRemaining.IsPositive() {
 			continue
 		}
 
 		// Offset amount is the minimum of remaining on both sides
-		offsetAmount := remainingAmount
-		if reverseRemaining < offsetAmount {
-			offsetAmount = reverseRemaining
-		}
+		offsetAmount := utils.MinMoney(remainingAmount, reverseRemaining)
 
 		reverseLoanNote := ""
 		if reverseLoan.Note != nil {
 			reverseLoanNote = *reverseLoan.Note
 		}
-		log.Printf("[LOAN] Offsetting %.2f PLN against reverse loan %q (original: %.2f PLN, remaining before: %.2f PLN)",
-			offsetAmount, reverseLoanNote, reverseLoanAmount, reverseRemaining)
+		log.Printf("[LOAN] Offsetting %s %s against reverse loan %q (original: %s, remaining before: %s)",
+			offsetAmount, currency, reverseLoanNote, reverseLoanAmount, reverseRemaining)
 
 		// Create a payment to offset the reverse loan
 		payment := models.LoanPayment{
 			ID:        uuid.New().String(),
 			LoanID:    reverseLoan.ID,
-			AmountPLN: utils.FloatToDecimalString(offsetAmount),
+			AmountPLN: offsetAmount.String(),
 			PaidAt:    time.Now(),
 			Note:      getStringPtr("Automatyczne rozliczenie długów"),
 		}
@@ -178,14 +232,14 @@ func (s *LoanService) CreateLoan(ctx context.Context, req CreateLoanRequest) (*m
 		}
 
 		// Update reverse loan status
-		newTotalPaid := totalPaid + offsetAmount
+		newTotalPaid := totalPaid.Add(offsetAmount)
 		var newStatus string
 		if newTotalPaid >= reverseLoanAmount {
 			newStatus = "settled"
 			log.Printf("[LOAN] Reverse loan %q is now fully settled", reverseLoanNote)
 		} else {
 			newStatus = "partial"
-			log.Printf("[LOAN] Reverse loan %q is now partial (remaining: %.2f PLN)", reverseLoanNote, reverseLoanAmount-newTotalPaid)
+			log.Printf("[LOAN] Reverse loan %q is now partial (remaining: %s %s)", reverseLoanNote, reverseLoanAmount.Sub(newTotalPaid), currency)
 		}
 
 		reverseLoan.Status = newStatus
@@ -193,21 +247,22 @@ func (s *LoanService) CreateLoan(ctx context.Context, req CreateLoanRequest) (*m
 			return nil, fmt.Errorf("failed to update reverse loan status: %w", err)
 		}
 
-		remainingAmount -= offsetAmount
+		remainingAmount = remainingAmount.Sub(offsetAmount)
 	}
 
 	// If there's still remaining amount, create the new loan
-	if remainingAmount > 0 {
+	if remainingAmount.IsPositive() {
 		if remainingAmount < req.AmountPLN {
-			log.Printf("[LOAN] After offsetting, creating loan for reduced amount: %.2f PLN (original: %.2f PLN, offset: %.2f PLN)",
-				remainingAmount, req.AmountPLN, req.AmountPLN-remainingAmount)
+			log.Printf("[LOAN] After offsetting, creating loan for reduced amount: %s %s (original: %s, offset: %s)",
+				remainingAmount, currency, req.AmountPLN, req.AmountPLN.Sub(remainingAmount))
 		}
 
 		loan := models.Loan{
 			ID:         uuid.New().String(),
 			LenderID:   req.LenderID,
 			BorrowerID: req.BorrowerID,
-			AmountPLN:  utils.FloatToDecimalString(remainingAmount),
+			AmountPLN:  remainingAmount.String(),
+			Currency:   currency,
 			Note:       req.Note,
 			DueDate:    req.DueDate,
 			Status:     "open",
@@ -218,24 +273,13 @@ func (s *LoanService) CreateLoan(ctx context.Context, req CreateLoanRequest) (*m
 			return nil, fmt.Errorf("failed to create loan: %w", err)
 		}
 
-		log.Printf("[LOAN] Created loan: %s → %s, %.2f PLN, note: %q", lenderName, borrowerName, remainingAmount, noteStr)
-
-		// Notify borrower about new loan
-		if s.notificationService != nil {
-			borrowerID := req.BorrowerID
-			_ = s.notificationService.CreateNotification(ctx, &models.Notification{
-				UserID:     &borrowerID,
-				TemplateID: "loan_created",
-				Title:      "Nowa pożyczka",
-				Body:       fmt.Sprintf("%s pożyczył/a Ci %.2f zł", lenderName, remainingAmount),
-			})
-		}
+		log.Printf("[LOAN] Created loan: %s → %s, %s %s, note: %q", lenderName, borrowerName, remainingAmount, currency, noteStr)
 
 		return &loan, nil
 	}
 
 	// All debt was offset, save settled loan to database
-	log.Printf("[LOAN] Entire loan amount (%.2f PLN) was offset against reverse debts - creating as settled", req.AmountPLN)
+	log.Printf("[LOAN] Entire loan amount (%s %s) was offset against reverse debts - creating as settled", req.AmountPLN, currency)
 
 	// Append offset message to user's note if they provided one
 	var settledNote *string
@@ -250,7 +294,8 @@ func (s *LoanService) CreateLoan(ctx context.Context, req CreateLoanRequest) (*m
 		ID:         uuid.New().String(),
 		LenderID:   req.LenderID,
 		BorrowerID: req.BorrowerID,
-		AmountPLN:  utils.FloatToDecimalString(req.AmountPLN),
+		AmountPLN:  req.AmountPLN.String(),
+		Currency:   currency,
 		Note:       settledNote,
 		DueDate:    req.DueDate,
 		Status:     "settled",
@@ -261,7 +306,7 @@ func (s *LoanService) CreateLoan(ctx context.Context, req CreateLoanRequest) (*m
 		return nil, fmt.Errorf("failed to create settled loan: %w", err)
 	}
 
-	log.Printf("[LOAN] Created settled loan (fully offset): %s → %s, %.2f PLN, note: %q", lenderName, borrowerName, req.AmountPLN, noteStr)
+	log.Printf("[LOAN] Created settled loan (fully offset): %s → %s, %s %s, note: %q", lenderName, borrowerName, req.AmountPLN, currency, noteStr)
 
 	return &settledLoan, nil
 }
@@ -272,8 +317,23 @@ func getStringPtr(s string) *string {
 
 // PerformGroupCompensation performs debt compensation for group members
 // When GroupMember1 owes External and External owes GroupMember2 (same group),
-// the debts are offset without creating internal group debt
+// the debts are offset without creating internal group debt.
+// Only loans in the same currency compensate each other.
+// All compensating payments are committed together.
 func (s *LoanService) PerformGroupCompensation(ctx context.Context) (*CompensationResult, error) {
+	var result *CompensationResult
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = s.performGroupCompensation(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *LoanService) performGroupCompensation(ctx context.Context) (*CompensationResult, error) {
 	// Get all users with their group memberships
 	users, err := s.users.List(ctx)
 	if err != nil {
@@ -305,18 +365,18 @@ func (s *LoanService) PerformGroupCompensation(ctx context.Context) (*Compensati
 	// Calculate remaining amounts for each loan
 	type loanWithRemaining struct {
 		loan      models.Loan
-		remaining float64
+		remaining utils.Money
 	}
 
 	loansWithRemaining := []loanWithRemaining{}
 	for _, loan := range loans {
-		loanAmount := utils.DecimalStringToFloat(loan.AmountPLN)
+		loanAmount := utils.MoneyFromString(loan.AmountPLN)
 		totalPaid, err := s.getTotalPaidForLoan(ctx, loan.ID)
 		if err != nil {
 			return nil, err
 		}
-		remaining := loanAmount - totalPaid
-		if remaining > 0 {
+		remaining := loanAmount.Sub(totalPaid)
+		if remaining.IsPositive() {
 			loansWithRemaining = append(loansWithRemaining, loanWithRemaining{
 				loan:      loan,
 				remaining: remaining,
@@ -325,14 +385,14 @@ func (s *LoanService) PerformGroupCompensation(ctx context.Context) (*Compensati
 	}
 
 	compensationsPerformed := 0
-	totalAmountCompensated := 0.0
+	var totalAmountCompensated utils.Money
 
 	// Find compensation opportunities
 	// Pattern: GroupMemberA owes External, External owes GroupMemberB (same group)
 	// Loan1: Lender=External, Borrower=GroupMemberA
 	// Loan2: Lender=GroupMemberB, Borrower=External
 	for i := range loansWithRemaining {
-		if loansWithRemaining[i].remaining <= 0 {
+		if !loansWithRemaining[i].remaining.IsPositive() {
 			continue
 		}
 
@@ -354,7 +414,7 @@ func (s *LoanService) PerformGroupCompensation(ctx context.Context) (*Compensati
 
 		// Find loans where External is borrower and lender is in same group as GroupMemberA
 		for j := range loansWithRemaining {
-			if i == j || loansWithRemaining[j].remaining <= 0 {
+			if i == j || !loansWithRemaining[j].remaining.IsPositive() {
 				continue
 			}
 
@@ -365,6 +425,10 @@ func (s *LoanService) PerformGroupCompensation(ctx context.Context) (*Compensati
 				continue
 			}
 
+			if loan2.Currency != loan1.Currency {
+				continue
+			}
+
 			groupMemberB := loan2.LenderID
 			groupMemberBGroupID := userGroupMap[groupMemberB]
 
@@ -374,10 +438,7 @@ func (s *LoanService) PerformGroupCompensation(ctx context.Context) (*Compensati
 			}
 
 			// Found a compensation opportunity!
-			compensationAmount := loansWithRemaining[i].remaining
-			if loansWithRemaining[j].remaining < compensationAmount {
-				compensationAmount = loansWithRemaining[j].remaining
-			}
+			compensationAmount := utils.MinMoney(loansWithRemaining[i].remaining, loansWithRemaining[j].remaining)
 
 			// Get names for logging
 			externalUser, _ := s.users.GetByID(ctx, external)
@@ -405,9 +466,9 @@ func (s *LoanService) PerformGroupCompensation(ctx context.Context) (*Compensati
 				loan2Note = *loan2.Note
 			}
 
-			log.Printf("[GROUP COMPENSATION] Found opportunity: %.2f PLN", compensationAmount)
-			log.Printf("[GROUP COMPENSATION]   Loan1: %s owes %s %.2f PLN (%q)", groupMemberAName, externalName, loansWithRemaining[i].remaining, loan1Note)
-			log.Printf("[GROUP COMPENSATION]   Loan2: %s owes %s %.2f PLN (%q)", externalName, groupMemberBName, loansWithRemaining[j].remaining, loan2Note)
+			log.Printf("[GROUP COMPENSATION] Found opportunity: %s %s", compensationAmount, loan1.Currency)
+			log.Printf("[GROUP COMPENSATION]   Loan1: %s owes %s %s (%q)", groupMemberAName, externalName, loansWithRemaining[i].remaining, loan1Note)
+			log.Printf("[GROUP COMPENSATION]   Loan2: %s owes %s %s (%q)", externalName, groupMemberBName, loansWithRemaining[j].remaining, loan2Note)
 
 			// Create payments with compensation note
 			compensationNote := getStringPtr("Kompensacja grupowa")
@@ -416,7 +477,7 @@ func (s *LoanService) PerformGroupCompensation(ctx context.Context) (*Compensati
 			payment1 := models.LoanPayment{
 				ID:        uuid.New().String(),
 				LoanID:    loan1.ID,
-				AmountPLN: utils.FloatToDecimalString(compensationAmount),
+				AmountPLN: compensationAmount.String(),
 				PaidAt:    time.Now(),
 				Note:      compensationNote,
 			}
@@ -427,14 +488,14 @@ func (s *LoanService) PerformGroupCompensation(ctx context.Context) (*Compensati
 
 			// Update loan1 status
 			newTotalPaid1, _ := s.getTotalPaidForLoan(ctx, loan1.ID)
-			loanAmount1 := utils.DecimalStringToFloat(loan1.AmountPLN)
+			loanAmount1 := utils.MoneyFromString(loan1.AmountPLN)
 			var newStatus1 string
 			if newTotalPaid1 >= loanAmount1 {
 				newStatus1 = "settled"
 				log.Printf("[GROUP COMPENSATION]   Loan1 %q is now settled", loan1Note)
 			} else {
 				newStatus1 = "partial"
-				log.Printf("[GROUP COMPENSATION]   Loan1 %q is now partial (remaining: %.2f PLN)", loan1Note, loanAmount1-newTotalPaid1)
+				log.Printf("[GROUP COMPENSATION]   Loan1 %q is now partial (remaining: %s)", loan1Note, loanAmount1.Sub(newTotalPaid1))
 			}
 
 			loan1.Status = newStatus1
@@ -446,7 +507,7 @@ func (s *LoanService) PerformGroupCompensation(ctx context.Context) (*Compensati
 			payment2 := models.LoanPayment{
 				ID:        uuid.New().String(),
 				LoanID:    loan2.ID,
-				AmountPLN: utils.FloatToDecimalString(compensationAmount),
+				AmountPLN: compensationAmount.String(),
 				PaidAt:    time.Now(),
 				Note:      compensationNote,
 			}
@@ -457,14 +518,14 @@ func (s *LoanService) PerformGroupCompensation(ctx context.Context) (*Compensati
 
 			// Update loan2 status
 			newTotalPaid2, _ := s.getTotalPaidForLoan(ctx, loan2.ID)
-			loanAmount2 := utils.DecimalStringToFloat(loan2.AmountPLN)
+			loanAmount2 := utils.MoneyFromString(loan2.AmountPLN)
 			var newStatus2 string
 			if newTotalPaid2 >= loanAmount2 {
 				newStatus2 = "settled"
 				log.Printf("[GROUP COMPENSATION]   Loan2 %q is now settled", loan2Note)
 			} else {
 				newStatus2 = "partial"
-				log.Printf("[GROUP COMPENSATION]   Loan2 %q is now partial (remaining: %.2f PLN)", loan2Note, loanAmount2-newTotalPaid2)
+				log.Printf("[GROUP COMPENSATION]   Loan2 %q is now partial (remaining: %s)", loan2Note, loanAmount2.Sub(newTotalPaid2))
 			}
 
 			loan2.Status = newStatus2
@@ -473,14 +534,14 @@ func (s *LoanService) PerformGroupCompensation(ctx context.Context) (*Compensati
 			}
 
 			// Update remaining amounts
-			loansWithRemaining[i].remaining -= compensationAmount
-			loansWithRemaining[j].remaining -= compensationAmount
+			loansWithRemaining[i].remaining = loansWithRemaining[i].remaining.Sub(compensationAmount)
+			loansWithRemaining[j].remaining = loansWithRemaining[j].remaining.Sub(compensationAmount)
 
 			compensationsPerformed++
-			totalAmountCompensated += compensationAmount
+			totalAmountCompensated = totalAmountCompensated.Add(compensationAmount)
 
 			// If loan1 is fully settled, break to outer loop
-			if loansWithRemaining[i].remaining <= 0 {
+			if !loansWithRemaining[i].remaining.IsPositive() {
 				break
 			}
 		}
@@ -504,7 +565,7 @@ func (s *LoanService) CreateLoanPayment(ctx context.Context, req CreateLoanPayme
 		return nil, errors.New("loan is already settled")
 	}
 
-	if req.AmountPLN <= 0 {
+	if !req.AmountPLN.IsPositive() {
 		return nil, errors.New("payment amount must be positive")
 	}
 
@@ -514,17 +575,17 @@ func (s *LoanService) CreateLoanPayment(ctx context.Context, req CreateLoanPayme
 		return nil, err
 	}
 
-	loanAmount := utils.DecimalStringToFloat(loan.AmountPLN)
-	remaining := loanAmount - totalPaid
+	loanAmount := utils.MoneyFromString(loan.AmountPLN)
+	remaining := loanAmount.Sub(totalPaid)
 
 	if req.AmountPLN > remaining {
-		return nil, fmt.Errorf("payment amount (%.2f) exceeds remaining balance (%.2f)", req.AmountPLN, remaining)
+		return nil, fmt.Errorf("payment amount (%s) exceeds remaining balance (%s)", req.AmountPLN, remaining)
 	}
 
 	payment := models.LoanPayment{
 		ID:        uuid.New().String(),
 		LoanID:    req.LoanID,
-		AmountPLN: utils.FloatToDecimalString(req.AmountPLN),
+		AmountPLN: req.AmountPLN.String(),
 		PaidAt:    req.PaidAt,
 		Note:      req.Note,
 	}
@@ -534,7 +595,7 @@ func (s *LoanService) CreateLoanPayment(ctx context.Context, req CreateLoanPayme
 	}
 
 	// Update loan status
-	newTotalPaid := totalPaid + req.AmountPLN
+	newTotalPaid := totalPaid.Add(req.AmountPLN)
 	var newStatus string
 	if newTotalPaid >= loanAmount {
 		newStatus = "settled"
@@ -559,14 +620,16 @@ func (s *LoanService) CreateLoanPayment(ctx context.Context, req CreateLoanPayme
 			UserID:     &lenderID,
 			TemplateID: "loan_payment_received",
 			Title:      "Otrzymano spłatę pożyczki",
-			Body:       fmt.Sprintf("%s spłacił/a %.2f zł", borrowerName, req.AmountPLN),
+			Body:       fmt.Sprintf("%s spłacił/a %s", borrowerName, FormatAmount(req.AmountPLN, loan.Currency)),
 		})
 	}
 
 	return &payment, nil
 }
 
-// GetBalances calculates pairwise balances for all users
+// GetBalances calculates pairwise balances for all users.
+// Remaining amounts are converted to the base currency at today's rate before netting,
+// so a EUR debt one way and a PLN debt the other way cancel out.
 func (s *LoanService) GetBalances(ctx context.Context) ([]PairwiseBalance, error) {
 	// Get all loans
 	loans, err := s.loans.List(ctx)
@@ -574,25 +637,36 @@ func (s *LoanService) GetBalances(ctx context.Context) ([]PairwiseBalance, error
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 
+	baseCurrency, err := s.currencyService.BaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
 	// Calculate net balances
-	balances := make(map[string]float64) // key: "lenderID-borrowerID"
+	balances := make(map[string]utils.Money) // key: "borrowerID-lenderID"
 
 	for _, loan := range loans {
 		if loan.Status == "settled" {
 			continue
 		}
 
-		loanAmount := utils.DecimalStringToFloat(loan.AmountPLN)
+		loanAmount := utils.MoneyFromString(loan.AmountPLN)
 		totalPaid, err := s.getTotalPaidForLoan(ctx, loan.ID)
 		if err != nil {
 			return nil, err
 		}
 
-		remaining := loanAmount - totalPaid
-		if remaining <= 0 {
+		remaining := loanAmount.Sub(totalPaid)
+		if !remaining.IsPositive() {
 			continue
 		}
 
+		remaining, err = s.currencyService.Convert(ctx, remaining, loan.Currency, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert loan %s: %w", loan.ID, err)
+		}
+
 		key := fmt.Sprintf("%s-%s", loan.BorrowerID, loan.LenderID)
 		reverseKey := fmt.Sprintf("%s-%s", loan.LenderID, loan.BorrowerID)
 
@@ -649,7 +723,8 @@ func (s *LoanService) GetBalances(ctx context.Context) ([]PairwiseBalance, error
 				ToUserId:     toID,
 				FromUserName: userMap[fromID],
 				ToUserName:   userMap[toID],
-				NetAmount:    utils.FloatToDecimalString(amount),
+				NetAmount:    amount.String(),
+				Currency:     baseCurrency,
 			}
 
 			// Add group information if user belongs to a group
@@ -734,18 +809,18 @@ func (s *LoanService) GetLoansWithOptions(ctx context.Context, opts GetLoansOpti
 	result := make([]LoanWithNames, len(loans))
 	for i, loan := range loans {
 		// Calculate remaining amount
-		loanAmount := utils.DecimalStringToFloat(loan.AmountPLN)
+		loanAmount := utils.MoneyFromString(loan.AmountPLN)
 		totalPaid, err := s.getTotalPaidForLoan(ctx, loan.ID)
 		if err != nil {
 			totalPaid = 0
 		}
-		remaining := loanAmount - totalPaid
+		remaining := loanAmount.Sub(totalPaid)
 
 		loanWithNames := LoanWithNames{
 			Loan:         loan,
 			FromUserName: userMap[loan.LenderID],
 			ToUserName:   userMap[loan.BorrowerID],
-			RemainingPLN: utils.FloatToDecimalString(remaining),
+			RemainingPLN: remaining.String(),
 		}
 
 		// Add group information if user belongs to a group
@@ -772,8 +847,8 @@ func (s *LoanService) GetLoansWithOptions(ctx context.Context, opts GetLoansOpti
 	switch opts.SortBy {
 	case "amountPLN":
 		sort.Slice(result, func(i, j int) bool {
-			amtI := utils.DecimalStringToFloat(result[i].AmountPLN)
-			amtJ := utils.DecimalStringToFloat(result[j].AmountPLN)
+			amtI := utils.MoneyFromString(result[i].AmountPLN)
+			amtJ := utils.MoneyFromString(result[j].AmountPLN)
 			if sortOrder == 1 {
 				return amtI < amtJ
 			}
@@ -788,8 +863,8 @@ func (s *LoanService) GetLoansWithOptions(ctx context.Context, opts GetLoansOpti
 		})
 	case "remainingPLN":
 		sort.Slice(result, func(i, j int) bool {
-			remI := utils.DecimalStringToFloat(result[i].RemainingPLN)
-			remJ := utils.DecimalStringToFloat(result[j].RemainingPLN)
+			remI := utils.MoneyFromString(result[i].RemainingPLN)
+			remJ := utils.MoneyFromString(result[j].RemainingPLN)
 			if sortOrder == 1 {
 				return remI < remJ
 			}
@@ -867,12 +942,12 @@ func (s *LoanService) DeleteLoan(ctx context.Context, loanID string) error {
 }
 
 // Helper functions
-func (s *LoanService) getTotalPaidForLoan(ctx context.Context, loanID string) (float64, error) {
+func (s *LoanService) getTotalPaidForLoan(ctx context.Context, loanID string) (utils.Money, error) {
 	sumStr, err := s.loanPayments.SumByLoanID(ctx, loanID)
 	if err != nil {
 		return 0, fmt.Errorf("database error: %w", err)
 	}
-	return utils.DecimalStringToFloat(sumStr), nil
+	return utils.MoneyFromString(sumStr), nil
 }
 
 // GetLoanPayments retrieves all payments for a specific loan
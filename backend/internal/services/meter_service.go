@@ -0,0 +1,470 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+// DefaultTariffZone is the zone of meters on a single-rate tariff
+const DefaultTariffZone = "single"
+
+// meterUnits are the units meters of each type are read in unless set otherwise
+var meterUnits = map[string]string{
+	"electricity": "kWh",
+	"gas":         "m3",
+	"water":       "m3",
+	"heat":        "GJ",
+}
+
+var (
+	// ErrMeterNotFound is returned when a meter does not exist
+	ErrMeterNotFound = errors.New("meter not found")
+	// ErrMeterReplaced is returned when a replaced meter is replaced again or read after the replacement
+	ErrMeterReplaced = errors.New("meter has been replaced")
+	// ErrMeterInUse is returned when deleting a meter that has readings or a replacement
+	ErrMeterInUse = errors.New("meter has readings or a replacement and cannot be deleted")
+	// ErrMeterWentBackwards is returned when a meter value is lower than the reading before it
+	ErrMeterWentBackwards = errors.New("meter reading cannot be lower than previous reading")
+)
+
+type MeterService struct {
+	meters       repository.MeterRepository
+	consumptions repository.ConsumptionRepository
+	users        repository.UserRepository
+	groups       repository.GroupRepository
+	txManager    repository.TxManager
+}
+
+func NewMeterService(
+	meters repository.MeterRepository,
+	consumptions repository.ConsumptionRepository,
+	users repository.UserRepository,
+	groups repository.GroupRepository,
+	txManager repository.TxManager,
+) *MeterService {
+	return &MeterService{
+		meters:       meters,
+		consumptions: consumptions,
+		users:        users,
+		groups:       groups,
+		txManager:    txManager,
+	}
+}
+
+type CreateMeterRequest struct {
+	Type         string    `json:"type"`                 // electricity, gas, water, heat
+	Unit         string    `json:"unit,omitempty"`       // defaults to the usual unit of the type
+	SubjectType  string    `json:"subjectType"`          // "user" or "group"
+	SubjectID    string    `json:"subjectId"`            // user ID or group ID
+	TariffZone   string    `json:"tariffZone,omitempty"` // defaults to "single"
+	SerialNumber string    `json:"serialNumber"`
+	InstalledAt  time.Time `json:"installedAt"`
+	InitialValue float64   `json:"initialValue"`
+}
+
+// CreateMeter registers a meter of a user or group
+func (s *MeterService) CreateMeter(ctx context.Context, req CreateMeterRequest) (*models.Meter, error) {
+	defaultUnit, ok := meterUnits[req.Type]
+	if !ok {
+		return nil, errors.New("meter type must be electricity, gas, water or heat")
+	}
+	if err := s.checkSubject(ctx, req.SubjectType, req.SubjectID); err != nil {
+		return nil, err
+	}
+	if req.InitialValue < 0 {
+		return nil, errors.New("initial value cannot be negative")
+	}
+	if req.InstalledAt.IsZero() {
+		req.InstalledAt = time.Now()
+	}
+
+	unit := strings.TrimSpace(req.Unit)
+	if unit == "" {
+		unit = defaultUnit
+	}
+
+	meter := &models.Meter{
+		Type:         req.Type,
+		Unit:         unit,
+		SubjectType:  req.SubjectType,
+		SubjectID:    req.SubjectID,
+		TariffZone:   normalizeTariffZone(req.TariffZone),
+		SerialNumber: strings.TrimSpace(req.SerialNumber),
+		InstalledAt:  req.InstalledAt,
+		InitialValue: utils.FloatToDecimalString(req.InitialValue),
+	}
+	if err := s.meters.Create(ctx, meter); err != nil {
+		return nil, fmt.Errorf("failed to create meter: %w", err)
+	}
+
+	log.Printf("[METER] Created: type=%s, zone=%s, serial=%q for %s %s (ID: %s)",
+		meter.Type, meter.TariffZone, meter.SerialNumber, meter.SubjectType, meter.SubjectID, meter.ID)
+	return meter, nil
+}
+
+// GetMeter returns a meter by ID
+func (s *MeterService) GetMeter(ctx context.Context, meterID string) (*models.Meter, error) {
+	meter, err := s.meters.GetByID(ctx, meterID)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if meter == nil {
+		return nil, ErrMeterNotFound
+	}
+	return meter, nil
+}
+
+// ListMeters returns all meters, or the meters of one user or group
+func (s *MeterService) ListMeters(ctx context.Context, subjectType, subjectID *string) ([]models.Meter, error) {
+	if subjectType != nil && subjectID != nil {
+		return s.meters.ListBySubject(ctx, *subjectType, *subjectID)
+	}
+	return s.meters.List(ctx)
+}
+
+// ListUserMeters returns the meters of a user, or of their group when they belong to one
+func (s *MeterService) ListUserMeters(ctx context.Context, userID string) ([]models.Meter, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, errors.New("user not found")
+	}
+	subjectType, subjectID := "user", userID
+	if user.GroupID != nil {
+		subjectType, subjectID = "group", *user.GroupID
+	}
+	return s.meters.ListBySubject(ctx, subjectType, subjectID)
+}
+
+type UpdateMeterRequest struct {
+	Unit         *string `json:"unit,omitempty"`
+	TariffZone   *string `json:"tariffZone,omitempty"`
+	SerialNumber *string `json:"serialNumber,omitempty"`
+}
+
+// UpdateMeter corrects the description of a meter. Readings and the delta chain are not affected.
+func (s *MeterService) UpdateMeter(ctx context.Context, meterID string, req UpdateMeterRequest) (*models.Meter, error) {
+	meter, err := s.GetMeter(ctx, meterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Unit != nil {
+		unit := strings.TrimSpace(*req.Unit)
+		if unit == "" {
+			return nil, errors.New("unit cannot be empty")
+		}
+		meter.Unit = unit
+	}
+	if req.TariffZone != nil {
+		meter.TariffZone = normalizeTariffZone(*req.TariffZone)
+	}
+	if req.SerialNumber != nil {
+		meter.SerialNumber = strings.TrimSpace(*req.SerialNumber)
+	}
+
+	if err := s.meters.Update(ctx, meter); err != nil {
+		return nil, fmt.Errorf("failed to update meter: %w", err)
+	}
+	return meter, nil
+}
+
+type ReplaceMeterRequest struct {
+	ReplacedAt   time.Time `json:"replacedAt"`
+	FinalValue   float64   `json:"finalValue"` // last value shown by the old meter
+	SerialNumber string    `json:"serialNumber"`
+	InitialValue float64   `json:"initialValue"` // value shown by the new meter when installed
+}
+
+// ReplaceMeter records the swap of a meter. The old meter keeps its final value and the new one
+// takes over its type, unit, subject and tariff zone. The first reading of the new meter is
+// counted from its initial value plus what the old meter counted after its last reading.
+func (s *MeterService) ReplaceMeter(ctx context.Context, meterID string, req ReplaceMeterRequest) (*models.Meter, error) {
+	if req.ReplacedAt.IsZero() {
+		req.ReplacedAt = time.Now()
+	}
+	if req.InitialValue < 0 {
+		return nil, errors.New("initial value cannot be negative")
+	}
+
+	var replacement *models.Meter
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		meter, err := s.GetMeter(ctx, meterID)
+		if err != nil {
+			return err
+		}
+		if meter.ReplacedAt != nil {
+			return ErrMeterReplaced
+		}
+		if req.ReplacedAt.Before(meter.InstalledAt) {
+			return errors.New("replacement date cannot be before the meter was installed")
+		}
+
+		// The final value closes the old meter's delta chain and cannot undo readings already counted
+		last := utils.DecimalStringToFloat(meter.InitialValue)
+		readings, err := s.consumptions.ListByMeterID(ctx, meter.ID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch readings: %w", err)
+		}
+		for _, reading := range readings {
+			if reading.MeterValue == nil {
+				continue
+			}
+			if reading.RecordedAt.After(req.ReplacedAt) {
+				return errors.New("meter has readings after the replacement date")
+			}
+			if value := utils.DecimalStringToFloat(*reading.MeterValue); value > last {
+				last = value
+			}
+		}
+		if req.FinalValue < last {
+			return errors.New("final value cannot be lower than the last reading")
+		}
+
+		finalValue := utils.FloatToDecimalString(req.FinalValue)
+		meter.ReplacedAt = &req.ReplacedAt
+		meter.FinalValue = &finalValue
+		if err := s.meters.Update(ctx, meter); err != nil {
+			return fmt.Errorf("failed to update meter: %w", err)
+		}
+
+		replacement = &models.Meter{
+			Type:            meter.Type,
+			Unit:            meter.Unit,
+			SubjectType:     meter.SubjectType,
+			SubjectID:       meter.SubjectID,
+			TariffZone:      meter.TariffZone,
+			SerialNumber:    strings.TrimSpace(req.SerialNumber),
+			InstalledAt:     req.ReplacedAt,
+			InitialValue:    utils.FloatToDecimalString(req.InitialValue),
+			ReplacesMeterID: &meter.ID,
+		}
+		if err := s.meters.Create(ctx, replacement); err != nil {
+			return fmt.Errorf("failed to create meter: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[METER] Replaced: %s by %s (serial=%q) at %s", meterID, replacement.ID, replacement.SerialNumber,
+		req.ReplacedAt.Format("2006-01-02"))
+	return replacement, nil
+}
+
+// DeleteMeter removes a meter registered by mistake. Meters with readings are kept for the delta chain.
+func (s *MeterService) DeleteMeter(ctx context.Context, meterID string) error {
+	meter, err := s.GetMeter(ctx, meterID)
+	if err != nil {
+		return err
+	}
+
+	readings, err := s.consumptions.ListByMeterID(ctx, meter.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch readings: %w", err)
+	}
+	successor, err := s.meters.GetByReplacesMeterID(ctx, meter.ID)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if len(readings) > 0 || successor != nil {
+		return ErrMeterInUse
+	}
+
+	return s.meters.Delete(ctx, meter.ID)
+}
+
+func (s *MeterService) checkSubject(ctx context.Context, subjectType, subjectID string) error {
+	switch subjectType {
+	case "user":
+		user, err := s.users.GetByID(ctx, subjectID)
+		if err != nil || user == nil {
+			return errors.New("user not found")
+		}
+	case "group":
+		group, err := s.groups.GetByID(ctx, subjectID)
+		if err != nil || group == nil {
+			return errors.New("group not found")
+		}
+	default:
+		return errors.New("subject type must be 'user' or 'group'")
+	}
+	return nil
+}
+
+func normalizeTariffZone(zone string) string {
+	zone = strings.ToLower(strings.TrimSpace(zone))
+	if zone == "" {
+		return DefaultTariffZone
+	}
+	return zone
+}
+
+// meterReadingUnits derives the usage of a reading from the reading before it.
+// Readings of a registered meter continue from that meter's previous reading, or for the first
+// reading from its initial value plus what the meter it replaced counted after its last reading.
+// Readings without a meter continue from the previous such reading of their subject and return
+// ErrNoPreviousReading when there is none.
+func meterReadingUnits(ctx context.Context, consumptions repository.ConsumptionRepository, meters repository.MeterRepository, reading models.Consumption) (float64, error) {
+	if reading.MeterValue == nil {
+		return 0, nil
+	}
+	current := utils.DecimalStringToFloat(*reading.MeterValue)
+
+	if reading.MeterID == nil {
+		readings, err := consumptions.ListBySubject(ctx, reading.SubjectType, reading.SubjectID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to fetch consumptions: %w", err)
+		}
+		unregistered := readings[:0]
+		for _, c := range readings {
+			if c.MeterID == nil {
+				unregistered = append(unregistered, c)
+			}
+		}
+		previous := latestReadingBefore(unregistered, reading.RecordedAt)
+		if previous == nil {
+			return 0, ErrNoPreviousReading
+		}
+		return meterDelta(current, utils.DecimalStringToFloat(*previous.MeterValue))
+	}
+
+	meter, err := meters.GetByID(ctx, *reading.MeterID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch meter: %w", err)
+	}
+	if meter == nil {
+		return 0, ErrMeterNotFound
+	}
+
+	readings, err := consumptions.ListByMeterID(ctx, meter.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch consumptions: %w", err)
+	}
+	if previous := latestReadingBefore(readings, reading.RecordedAt); previous != nil {
+		units, err := meterDelta(current, utils.DecimalStringToFloat(*previous.MeterValue))
+		if errors.Is(err, ErrMeterWentBackwards) && previous.Source == "estimated" {
+			return 0, nil // the estimate overshot; the usage up to it has been billed already
+		}
+		return units, err
+	}
+
+	units, err := meterDelta(current, utils.DecimalStringToFloat(meter.InitialValue))
+	if err != nil {
+		return 0, err
+	}
+	carried, err := replacedMeterUnits(ctx, consumptions, meters, meter)
+	if err != nil {
+		return 0, err
+	}
+	return units + carried, nil
+}
+
+// replacedMeterUnits returns what the meter replaced by the given one counted between its last
+// reading and its final value. That usage belongs to the first reading of the replacement.
+func replacedMeterUnits(ctx context.Context, consumptions repository.ConsumptionRepository, meters repository.MeterRepository, meter *models.Meter) (float64, error) {
+	if meter.ReplacesMeterID == nil {
+		return 0, nil
+	}
+	old, err := meters.GetByID(ctx, *meter.ReplacesMeterID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch meter: %w", err)
+	}
+	if old == nil || old.FinalValue == nil {
+		return 0, nil
+	}
+
+	readings, err := consumptions.ListByMeterID(ctx, old.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch consumptions: %w", err)
+	}
+	// Readings of a replaced meter cannot be later than the replacement, so the latest one is its last
+	last := utils.DecimalStringToFloat(old.InitialValue)
+	var lastAt time.Time
+	for _, c := range readings {
+		if c.MeterValue != nil && !c.RecordedAt.Before(lastAt) {
+			last, lastAt = utils.DecimalStringToFloat(*c.MeterValue), c.RecordedAt
+		}
+	}
+
+	carried := utils.DecimalStringToFloat(*old.FinalValue) - last
+	if carried < 0 {
+		return 0, nil
+	}
+	return carried, nil
+}
+
+// latestReadingBefore returns the most recent reading with a meter value taken before the given time
+func latestReadingBefore(readings []models.Consumption, before time.Time) *models.Consumption {
+	var previous *models.Consumption
+	for i := range readings {
+		c := &readings[i]
+		if c.MeterValue == nil || !c.RecordedAt.Before(before) {
+			continue
+		}
+		if previous == nil || c.RecordedAt.After(previous.RecordedAt) {
+			previous = c
+		}
+	}
+	return previous
+}
+
+func meterDelta(current, previous float64) (float64, error) {
+	units := current - previous
+	if units < 0 {
+		return 0, ErrMeterWentBackwards
+	}
+	return units, nil
+}
+
+// rederiveNextReading recomputes the units of the first reading of a meter taken after the given time,
+// once the reading before it has changed. Readings on closed bills are left as they are, and so are
+// readings that would go backwards, which the anomaly check flags.
+func rederiveNextReading(ctx context.Context, consumptions repository.ConsumptionRepository, meters repository.MeterRepository, bills repository.BillRepository, meterID string, after time.Time) error {
+	readings, err := consumptions.ListByMeterID(ctx, meterID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch consumptions: %w", err)
+	}
+	var next *models.Consumption
+	for i := range readings {
+		c := &readings[i]
+		if c.MeterValue == nil || c.Source == "invalid" || !c.RecordedAt.After(after) {
+			continue
+		}
+		if next == nil || c.RecordedAt.Before(next.RecordedAt) {
+			next = c
+		}
+	}
+	if next == nil {
+		return nil
+	}
+
+	bill, err := bills.GetByID(ctx, next.BillID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch bill: %w", err)
+	}
+	if bill == nil || bill.Status == "closed" {
+		return nil
+	}
+
+	units, err := meterReadingUnits(ctx, consumptions, meters, *next)
+	if errors.Is(err, ErrMeterWentBackwards) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	next.Units = utils.FloatToDecimalString(units)
+	if err := consumptions.Update(ctx, next); err != nil {
+		return fmt.Errorf("failed to update consumption: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeterReplacementAndZonedAllocation(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	billService, allocationService := newTestBillService(repos)
+	consumptionService := NewConsumptionService(repos.Consumptions, repos.ConsumptionAnomalies, newTestAttachmentService(t, repos), repos.Bills, repos.Users, repos.Meters, repos.BillTariffZones, repos.TxManager)
+	meterService := NewMeterService(repos.Meters, repos.Consumptions, repos.Users, repos.Groups, repos.TxManager)
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC) }
+	installed := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newMeter := func(user *models.User, meterType, zone string, initial float64) *models.Meter {
+		meter, err := meterService.CreateMeter(ctx, CreateMeterRequest{
+			Type: meterType, SubjectType: "user", SubjectID: user.ID, TariffZone: zone, InstalledAt: installed, InitialValue: initial,
+		})
+		require.NoError(t, err)
+		return meter
+	}
+	aliceDay := newMeter(alice, "electricity", "Day", 1000)
+	aliceNight := newMeter(alice, "electricity", "night", 500)
+	bobDay := newMeter(bob, "electricity", "day", 0)
+	aliceHeat := newMeter(alice, "heat", "", 0)
+	assert.Equal(t, "day", aliceDay.TariffZone)
+	assert.Equal(t, "kWh", aliceDay.Unit)
+	assert.Equal(t, DefaultTariffZone, aliceHeat.TariffZone)
+	assert.Equal(t, "GJ", aliceHeat.Unit)
+
+	// 200 kWh day and 100 kWh night, the remaining 100 zł are fixed charges
+	bill, err := billService.CreateBill(ctx, CreateBillRequest{
+		Type:           "electricity",
+		PeriodStart:    day(1),
+		PeriodEnd:      day(31),
+		TotalAmountPLN: utils.NewMoney(300, 0),
+		TariffZones: []models.BillTariffZone{
+			{Zone: "day", Units: "200", Amount: "160"},
+			{Zone: "night", Units: "100", Amount: "40.00"},
+		},
+	}, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "300.00", bill.TotalUnits)
+	stored, err := billService.GetBill(ctx, bill.ID)
+	require.NoError(t, err)
+	require.Len(t, stored.TariffZones, 2)
+	assert.Equal(t, "160.00", stored.TariffZones[0].Amount)
+
+	read := func(user *models.User, meter *models.Meter, value float64, at time.Time) (*models.Consumption, error) {
+		return consumptionService.CreateConsumption(ctx, CreateConsumptionRequest{
+			BillID: bill.ID, UserID: user.ID, MeterID: &meter.ID, MeterValue: &value, RecordedAt: at,
+		}, "user")
+	}
+
+	reading, err := read(alice, aliceDay, 1100, day(31))
+	require.NoError(t, err)
+	assert.Equal(t, "100.00", reading.Units)
+	_, err = read(alice, aliceNight, 560, day(31))
+	require.NoError(t, err)
+
+	_, err = read(bob, aliceDay, 1200, day(31))
+	assert.ErrorContains(t, err, "your own meters")
+	_, err = read(alice, aliceHeat, 3, day(31))
+	assert.ErrorContains(t, err, "heat meters cannot be read for electricity bills")
+
+	// Bob's meter is swapped mid-month: 30 kWh between his last reading and the swap carry over
+	reading, err = read(bob, bobDay, 50, day(10))
+	require.NoError(t, err)
+	assert.Equal(t, "50.00", reading.Units)
+
+	_, err = meterService.ReplaceMeter(ctx, bobDay.ID, ReplaceMeterRequest{ReplacedAt: day(15), FinalValue: 40})
+	assert.ErrorContains(t, err, "lower than the last reading")
+	replacement, err := meterService.ReplaceMeter(ctx, bobDay.ID, ReplaceMeterRequest{ReplacedAt: day(15), FinalValue: 80, SerialNumber: "NEW-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "day", replacement.TariffZone)
+	require.NotNil(t, replacement.ReplacesMeterID)
+	_, err = meterService.ReplaceMeter(ctx, bobDay.ID, ReplaceMeterRequest{ReplacedAt: day(20), FinalValue: 90})
+	assert.ErrorIs(t, err, ErrMeterReplaced)
+
+	_, err = read(bob, bobDay, 90, day(31))
+	assert.ErrorIs(t, err, ErrMeterReplaced)
+	reading, err = read(bob, replacement, 20, day(31))
+	require.NoError(t, err)
+	assert.Equal(t, "50.00", reading.Units)
+
+	assert.ErrorIs(t, meterService.DeleteMeter(ctx, bobDay.ID), ErrMeterInUse)
+	require.NoError(t, meterService.DeleteMeter(ctx, aliceHeat.ID))
+
+	// Day: 200 of 200 kWh read, 160 zł split 80/80. Night: 60 of 100 kWh read, 24 zł to Alice.
+	// Shared: 300 - 160 - 24 = 116 zł split by weight.
+	breakdown, err := allocationService.CalculateAllocation(ctx, stored)
+	require.NoError(t, err)
+	got := make(map[string]AllocationBreakdown)
+	for _, entry := range breakdown {
+		got[entry.SubjectID] = entry
+	}
+	assert.Equal(t, utils.NewMoney(162, 0), got[alice.ID].Amount)
+	assert.Equal(t, utils.NewMoney(138, 0), got[bob.ID].Amount)
+	assert.Equal(t, utils.NewMoney(58, 0), *got[bob.ID].SharedAmount)
+	assert.Equal(t, []ZoneUsage{
+		{Zone: "day", Units: 100, Amount: utils.NewMoney(80, 0)},
+		{Zone: "night", Units: 60, Amount: utils.NewMoney(24, 0)},
+	}, got[alice.ID].Zones)
+	assert.Equal(t, 160.0, *got[alice.ID].Units)
+
+	// Zones must fit in the bill and belong to a metered bill
+	_, err = billService.CreateBill(ctx, CreateBillRequest{
+		Type: "electricity", PeriodStart: day(1), PeriodEnd: day(31), TotalAmountPLN: utils.NewMoney(100, 0),
+		TariffZones: []models.BillTariffZone{{Zone: "day", Units: "10", Amount: "150"}},
+	}, alice.ID)
+	assert.ErrorContains(t, err, "more than the bill total")
+	_, err = billService.CreateBill(ctx, CreateBillRequest{
+		Type: "gas", PeriodStart: day(1), PeriodEnd: day(31), TotalAmountPLN: utils.NewMoney(100, 0),
+		TariffZones: []models.BillTariffZone{{Zone: "day", Units: "10", Amount: "50"}},
+	}, alice.ID)
+	assert.ErrorContains(t, err, "metered allocation")
+}
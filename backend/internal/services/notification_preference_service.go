@@ -50,10 +50,11 @@ func (s *NotificationPreferenceService) createDefaultPreferences(ctx context.Con
 		ID:     uuid.New().String(),
 		UserID: userID,
 		Preferences: map[string]bool{
-			"bill":   true,
-			"chore":  true,
-			"supply": true,
-			"loan":   true,
+			"bill":                true,
+			"chore":               true,
+			"supply":              true,
+			"loan":                true,
+			"consumption_anomaly": true,
 		},
 		AllEnabled: true,
 		UpdatedAt:  time.Now(),
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/boombuler/barcode/qr"
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+// QR code image formats
+const (
+	PaymentQRFormatPNG = "png"
+	PaymentQRFormatSVG = "svg"
+)
+
+const (
+	// zbpMaxAmount is the largest amount the ZBP amount field (six digits of grosze) can hold
+	zbpMaxAmount = utils.Money(999999)
+	// zbpMaxNameLength and zbpMaxTitleLength are the field limits of the ZBP recommendation
+	zbpMaxNameLength  = 20
+	zbpMaxTitleLength = 32
+	// qrQuietZone is the blank border around the code, in modules
+	qrQuietZone = 4
+)
+
+// ErrPaymentRequestUnavailable is returned when a bill's shares cannot be requested by transfer
+var ErrPaymentRequestUnavailable = errors.New("payment request unavailable")
+
+type PaymentRequestService struct {
+	bills         repository.BillRepository
+	bankAccounts  repository.BankAccountRepository
+	creditService *CreditService
+}
+
+func NewPaymentRequestService(bills repository.BillRepository, bankAccounts repository.BankAccountRepository, creditService *CreditService) *PaymentRequestService {
+	return &PaymentRequestService{
+		bills:         bills,
+		bankAccounts:  bankAccounts,
+		creditService: creditService,
+	}
+}
+
+// PaymentRequest is a ready-to-send transfer for the unpaid part of one allocation
+type PaymentRequest struct {
+	BillID        string      `json:"billId"`
+	SubjectID     string      `json:"subjectId"`
+	SubjectType   string      `json:"subjectType"` // "user" or "group"
+	SubjectName   string      `json:"subjectName"`
+	Amount        utils.Money `json:"amount"`
+	Currency      string      `json:"currency"`
+	RecipientName string      `json:"recipientName"`
+	AccountNumber string      `json:"accountNumber"`
+	Reference     string      `json:"reference"` // identifies the bill and the allocation, part of Title
+	Title         string      `json:"title"`
+	QRPayload     string      `json:"qrPayload"` // ZBP transfer QR content
+}
+
+// PaymentReference builds the structured transfer reference of an allocation: the first block of
+// the bill ID, which bank statement matching recognizes in transfer titles, and a short subject tag
+func PaymentReference(billID, subjectID string) string {
+	bill, _, _ := strings.Cut(billID, "-")
+	subject := truncateRunes(subjectID, 4)
+	return strings.ToUpper("HH-" + bill + "-" + subject)
+}
+
+// GetBillPaymentRequests returns a payment request for every allocation of a posted bill
+// that is not fully paid
+func (s *PaymentRequestService) GetBillPaymentRequests(ctx context.Context, billID string) ([]PaymentRequest, error) {
+	bill, account, err := s.requestableBill(ctx, billID)
+	if err != nil {
+		return nil, err
+	}
+
+	shares, err := s.creditService.GetOpenShares(ctx, billID)
+	if err != nil {
+		return nil, err
+	}
+
+	requests := make([]PaymentRequest, 0, len(shares))
+	for _, share := range shares {
+		requests = append(requests, buildPaymentRequest(bill, account, &share))
+	}
+	return requests, nil
+}
+
+// GetPaymentRequest returns the payment request for one allocation of a bill
+func (s *PaymentRequestService) GetPaymentRequest(ctx context.Context, billID, subjectID string) (*PaymentRequest, error) {
+	requests, err := s.GetBillPaymentRequests(ctx, billID)
+	if err != nil {
+		return nil, err
+	}
+	for _, req := range requests {
+		if req.SubjectID == subjectID {
+			return &req, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: nothing left to pay for this allocation", ErrPaymentRequestUnavailable)
+}
+
+// requestableBill loads a bill and the account its shares are paid to. Only posted PLN bills
+// with a recipient account can be paid with a domestic transfer QR code.
+func (s *PaymentRequestService) requestableBill(ctx context.Context, billID string) (*models.Bill, *models.BankAccount, error) {
+	bill, err := s.bills.GetByID(ctx, billID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bill == nil {
+		return nil, nil, errors.New("bill not found")
+	}
+	if bill.Status != BillStatusPosted && bill.Status != BillStatusAwaitingPayment {
+		return nil, nil, fmt.Errorf("%w: bill is not awaiting payment", ErrPaymentRequestUnavailable)
+	}
+	if bill.Currency != DefaultCurrency {
+		return nil, nil, fmt.Errorf("%w: transfer QR codes only support %s", ErrPaymentRequestUnavailable, DefaultCurrency)
+	}
+	if bill.RecipientAccountID == nil {
+		return nil, nil, fmt.Errorf("%w: bill has no recipient bank account", ErrPaymentRequestUnavailable)
+	}
+
+	account, err := s.bankAccounts.GetByID(ctx, *bill.RecipientAccountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if account == nil {
+		return nil, nil, fmt.Errorf("%w: recipient bank account was removed", ErrPaymentRequestUnavailable)
+	}
+	return bill, account, nil
+}
+
+func buildPaymentRequest(bill *models.Bill, account *models.BankAccount, share *OpenBillShare) PaymentRequest {
+	reference := PaymentReference(bill.ID, share.SubjectID)
+
+	name := bill.Type
+	if bill.CustomType != nil && *bill.CustomType != "" {
+		name = *bill.CustomType
+	}
+	title := truncateRunes(fmt.Sprintf("%s %s %s", reference, name, bill.PeriodStart.Format("01/06")), zbpMaxTitleLength)
+
+	return PaymentRequest{
+		BillID:        bill.ID,
+		SubjectID:     share.SubjectID,
+		SubjectType:   share.SubjectType,
+		SubjectName:   share.SubjectName,
+		Amount:        share.Remaining,
+		Currency:      bill.Currency,
+		RecipientName: account.HolderName,
+		AccountNumber: account.AccountNumber,
+		Reference:     reference,
+		Title:         title,
+		QRPayload:     zbpPayload(account.AccountNumber, account.HolderName, title, share.Remaining),
+	}
+}
+
+// zbpPayload builds the content of a Polish transfer QR code (Związek Banków Polskich
+// recommendation): NIP|country|account|amount in grosze|recipient|title|||.
+// Amounts too large for the six digit field are left for the payer to type in.
+func zbpPayload(accountNumber, recipient, title string, amount utils.Money) string {
+	amountField := ""
+	if amount.IsPositive() && amount <= zbpMaxAmount {
+		amountField = fmt.Sprintf("%06d", int64(amount))
+	}
+
+	fields := []string{
+		"", // NIP, only used for tax payments
+		"PL",
+		accountNumber,
+		amountField,
+		truncateRunes(zbpField(recipient), zbpMaxNameLength),
+		truncateRunes(zbpField(title), zbpMaxTitleLength),
+		"", "", "", // reserved
+	}
+	return strings.Join(fields, "|")
+}
+
+// zbpField removes the field separator from free text
+func zbpField(s string) string {
+	return strings.TrimSpace(strings.ReplaceAll(s, "|", " "))
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:max]))
+}
+
+// RenderPaymentQR draws a payment request's QR code as PNG or SVG, roughly size pixels wide.
+// Returns the image and its content type.
+func RenderPaymentQR(payload, format string, size int) ([]byte, string, error) {
+	code, err := qr.Encode(payload, qr.M, qr.Unicode)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode QR code: %w", err)
+	}
+
+	modules := code.Bounds().Dx()
+	dark := func(x, y int) bool {
+		return color.GrayModel.Convert(code.At(x, y)).(color.Gray).Y < 128
+	}
+
+	switch format {
+	case "", PaymentQRFormatPNG:
+		scale := size / (modules + 2*qrQuietZone)
+		if scale < 1 {
+			scale = 1
+		}
+		width := (modules + 2*qrQuietZone) * scale
+		img := image.NewGray(image.Rect(0, 0, width, width))
+		for i := range img.Pix {
+			img.Pix[i] = 0xff
+		}
+		for y := 0; y < modules; y++ {
+			for x := 0; x < modules; x++ {
+				if !dark(x, y) {
+					continue
+				}
+				for dy := 0; dy < scale; dy++ {
+					for dx := 0; dx < scale; dx++ {
+						img.SetGray((x+qrQuietZone)*scale+dx, (y+qrQuietZone)*scale+dy, color.Gray{Y: 0})
+					}
+				}
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", fmt.Errorf("failed to encode PNG: %w", err)
+		}
+		return buf.Bytes(), "image/png", nil
+
+	case PaymentQRFormatSVG:
+		total := modules + 2*qrQuietZone
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" shape-rendering="crispEdges">`,
+			total, total, size, size)
+		fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, total, total)
+		for y := 0; y < modules; y++ {
+			for x := 0; x < modules; x++ {
+				if dark(x, y) {
+					fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x+qrQuietZone, y+qrQuietZone)
+				}
+			}
+		}
+		buf.WriteString(`"/></svg>`)
+		return buf.Bytes(), "image/svg+xml", nil
+
+	default:
+		return nil, "", fmt.Errorf("unsupported QR code format: %s", format)
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeNRB(t *testing.T) {
+	nrb, err := NormalizeNRB("PL61 1090 1014 0000 0712 1981 2874")
+	require.NoError(t, err)
+	assert.Equal(t, "61109010140000071219812874", nrb)
+
+	_, err = NormalizeNRB("62 1090 1014 0000 0712 1981 2874")
+	assert.ErrorIs(t, err, ErrInvalidAccountNumber, "wrong check digits")
+	_, err = NormalizeNRB("1090 1014")
+	assert.ErrorIs(t, err, ErrInvalidAccountNumber)
+}
+
+func TestZBPPayload(t *testing.T) {
+	payload := zbpPayload("61109010140000071219812874", "Wspólnota Mieszkaniowa Słoneczna", "HH-AB|CD internet", utils.NewMoney(123, 45))
+	assert.Equal(t, "|PL|61109010140000071219812874|012345|Wspólnota Mieszkanio|HH-AB CD internet|||", payload)
+
+	// Amounts above 9999.99 do not fit the field and are typed in by the payer
+	payload = zbpPayload("61109010140000071219812874", "Jan", "Czynsz", utils.NewMoney(12000, 0))
+	assert.Equal(t, "|PL|61109010140000071219812874||Jan|Czynsz|||", payload)
+}
+
+func TestBillPaymentRequests(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	billService, paymentService, _ := newTestCreditServices(repos)
+	allocationService := NewAllocationService(repos.Users, repos.Residencies, repos.BillResidents, repos.Groups, repos.Consumptions, repos.Allocations,
+		repos.Bills, repos.BillSplits, repos.BillItems, repos.BillTariffZones, repos.Meters)
+	creditService := NewCreditService(repos.CreditEntries, repos.Payments, repos.Users, allocationService)
+	bankAccountService := NewBankAccountService(repos.BankAccounts, repos.TxManager)
+	paymentRequestService := NewPaymentRequestService(repos.Bills, repos.BankAccounts, creditService)
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+
+	account, err := bankAccountService.CreateAccount(ctx, alice.ID, BankAccountRequest{
+		HolderName:    "Alice Kowalska",
+		AccountNumber: "PL61 1090 1014 0000 0712 1981 2874",
+	})
+	require.NoError(t, err)
+	assert.True(t, account.IsDefault, "the first account becomes the default")
+
+	bill, err := billService.CreateBill(ctx, CreateBillRequest{
+		Type:               "internet",
+		PeriodStart:        time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:          time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+		TotalAmountPLN:     utils.NewMoney(100, 0),
+		RecipientAccountID: &account.ID,
+	}, alice.ID)
+	require.NoError(t, err)
+
+	_, err = paymentRequestService.GetBillPaymentRequests(ctx, bill.ID)
+	assert.ErrorIs(t, err, ErrPaymentRequestUnavailable, "draft bills can still change")
+
+	require.NoError(t, billService.PostBill(ctx, bill.ID))
+	_, err = paymentService.RecordPayment(ctx, RecordPaymentRequest{BillID: bill.ID, Amount: utils.NewMoney(20, 0)}, bob.ID)
+	require.NoError(t, err)
+
+	requests, err := paymentRequestService.GetBillPaymentRequests(ctx, bill.ID)
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+
+	req, err := paymentRequestService.GetPaymentRequest(ctx, bill.ID, bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, utils.NewMoney(30, 0), req.Amount, "payments already made are taken off")
+	assert.Equal(t, "61109010140000071219812874", req.AccountNumber)
+	assert.Equal(t, PaymentReference(bill.ID, bob.ID)+" internet 03/24", req.Title)
+	assert.Equal(t, "|PL|61109010140000071219812874|003000|Alice Kowalska|"+req.Title+"|||", req.QRPayload)
+
+	// Bank statement matching recognizes the bill in the transfer title
+	assert.True(t, referenceMentioned(foldForMatching(req.Title), bill.ID))
+
+	image, contentType, err := RenderPaymentQR(req.QRPayload, PaymentQRFormatPNG, 300)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+	decoded, err := png.Decode(bytes.NewReader(image))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, decoded.Bounds().Dx(), 300)
+
+	image, contentType, err = RenderPaymentQR(req.QRPayload, PaymentQRFormatSVG, 300)
+	require.NoError(t, err)
+	assert.Equal(t, "image/svg+xml", contentType)
+	assert.True(t, strings.HasPrefix(string(image), "<svg"))
+}
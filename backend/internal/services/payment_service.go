@@ -15,31 +15,40 @@ import (
 type PaymentService struct {
 	payments             repository.PaymentRepository
 	bills                repository.BillRepository
+	txManager            repository.TxManager
+	creditService        *CreditService
 	recurringBillService *RecurringBillService
 }
 
 func NewPaymentService(
 	payments repository.PaymentRepository,
 	bills repository.BillRepository,
+	txManager repository.TxManager,
+	creditService *CreditService,
 	recurringBillService *RecurringBillService,
 ) *PaymentService {
 	return &PaymentService{
 		payments:             payments,
 		bills:                bills,
+		txManager:            txManager,
+		creditService:        creditService,
 		recurringBillService: recurringBillService,
 	}
 }
 
 type RecordPaymentRequest struct {
-	BillID string  `json:"billId"`
-	Amount float64 `json:"amount"`
-	Method *string `json:"method,omitempty"`
+	BillID    string      `json:"billId"`
+	Amount    utils.Money `json:"amount"`
+	Method    *string     `json:"method,omitempty"`
+	Reference *string     `json:"reference,omitempty"`
+	PaidAt    *time.Time  `json:"paidAt,omitempty"` // defaults to now
 }
 
-// RecordPayment records a payment made by a user for a bill
+// RecordPayment records a payment made by a user for a bill.
+// Whatever exceeds the bill total once everyone's payments are counted becomes the payer's credit.
 func (s *PaymentService) RecordPayment(ctx context.Context, req RecordPaymentRequest, userID string) (*models.Payment, error) {
 	// Validate amount is positive
-	if req.Amount <= 0 {
+	if !req.Amount.IsPositive() {
 		return nil, fmt.Errorf("payment amount must be positive")
 	}
 
@@ -52,8 +61,13 @@ func (s *PaymentService) RecordPayment(ctx context.Context, req RecordPaymentReq
 		return nil, fmt.Errorf("bill %s does not exist", req.BillID)
 	}
 
-	if bill.Status != "posted" && bill.Status != "closed" {
-		return nil, fmt.Errorf("can only record payments for posted or closed bills (current status: %s)", bill.Status)
+	if !isIssuedBill(bill.Status) {
+		return nil, fmt.Errorf("can only record payments for posted bills (current status: %s)", bill.Status)
+	}
+
+	paidAt := time.Now()
+	if req.PaidAt != nil {
+		paidAt = *req.PaidAt
 	}
 
 	// Create payment record
@@ -61,16 +75,24 @@ func (s *PaymentService) RecordPayment(ctx context.Context, req RecordPaymentReq
 		ID:          uuid.New().String(),
 		BillID:      req.BillID,
 		PayerUserID: userID,
-		AmountPLN:   utils.FloatToDecimalString(req.Amount),
-		PaidAt:      time.Now(),
+		AmountPLN:   req.Amount.String(),
+		PaidAt:      paidAt,
 		Method:      req.Method,
+		Reference:   req.Reference,
 	}
 
-	if err := s.payments.Create(ctx, payment); err != nil {
-		return nil, fmt.Errorf("failed to record payment: %w", err)
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.payments.Create(ctx, payment); err != nil {
+			return fmt.Errorf("failed to record payment: %w", err)
+		}
+		_, err := s.creditService.RecordOverpayment(ctx, bill, payment)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	log.Printf("[PAYMENT] Recorded: %.2f PLN for bill %s by user %s (payment ID: %s)", req.Amount, req.BillID, userID, payment.ID)
+	log.Printf("[PAYMENT] Recorded: %s PLN for bill %s by user %s (payment ID: %s)", req.Amount, req.BillID, userID, payment.ID)
 
 	// Check if this payment completes a recurring bill and generate next bill if needed
 	if s.recurringBillService != nil {
@@ -90,3 +112,8 @@ func (s *PaymentService) GetBillPayments(ctx context.Context, billID string) ([]
 func (s *PaymentService) GetUserPayments(ctx context.Context, userID string) ([]models.Payment, error) {
 	return s.payments.ListByPayerID(ctx, userID)
 }
+
+// GetUserCreditStatement returns a user's bill credit movements with a running balance
+func (s *PaymentService) GetUserCreditStatement(ctx context.Context, userID string) (*CreditStatement, error) {
+	return s.creditService.GetStatement(ctx, userID)
+}
@@ -0,0 +1,1072 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrInvalidPDF is returned for files that are not readable PDF documents
+var ErrInvalidPDF = errors.New("invalid PDF")
+
+// PDF extraction limits, protecting against malformed or hostile files
+const (
+	pdfMaxDepth      = 32       // nested arrays, dictionaries, references and form XObjects
+	pdfMaxStreamSize = 50 << 20 // decompressed size of a single stream
+	pdfMaxCMapRange  = 1 << 16  // codes expanded from one bfrange entry
+)
+
+// ExtractPDFText returns the text layer of a PDF, one line per text line, pages separated by
+// blank lines. Only text-based PDFs are supported: scanned pages have no text to extract.
+//
+// This is deliberately a small reader for the documents utilities send out, not a full PDF
+// implementation: it understands Flate, ASCIIHex and ASCII85 streams, object streams,
+// ToUnicode maps, WinAnsi encoded simple fonts and text drawn in form XObjects.
+func ExtractPDFText(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF-")) {
+		return "", fmt.Errorf("%w: missing PDF header", ErrInvalidPDF)
+	}
+
+	doc := parsePDFDocument(data)
+	pages := doc.pages()
+	if len(pages) == 0 {
+		return "", fmt.Errorf("%w: no pages found", ErrInvalidPDF)
+	}
+
+	var out []string
+	for _, page := range pages {
+		w := &pdfTextWriter{}
+		doc.writePageText(w, page.dict, page.resources, 0)
+		if text := w.String(); text != "" {
+			out = append(out, text)
+		}
+	}
+	return strings.Join(out, "\n\n"), nil
+}
+
+// PDF object model
+
+type pdfName string
+type pdfString []byte
+type pdfKeyword string
+type pdfArray []any
+type pdfDict map[pdfName]any
+
+type pdfRef struct {
+	num int
+}
+
+type pdfStream struct {
+	dict pdfDict
+	raw  []byte
+}
+
+// pdfLexer reads PDF tokens and objects from file or content stream data
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) {
+			l.pos++
+		} else if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		} else {
+			return
+		}
+	}
+}
+
+// token returns the next token: a number, name, string, keyword or delimiter keyword
+// ("[", "]", "<<", ">>"). Returns io.EOF at the end of the data.
+func (l *pdfLexer) token() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+			l.pos++
+		}
+		return pdfName(decodePDFName(l.data[start:l.pos])), nil
+	case c == '(':
+		return l.literalString()
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return pdfKeyword("<<"), nil
+		}
+		return l.hexString()
+	case c == '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return pdfKeyword(">>"), nil
+		}
+		l.pos++
+		return pdfKeyword(">"), nil
+	case c == '[' || c == ']' || c == '{' || c == '}' || c == ')':
+		l.pos++
+		return pdfKeyword(string(c)), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.ParseFloat(word, 64); err == nil && (word[0] == '-' || word[0] == '+' || word[0] == '.' || (word[0] >= '0' && word[0] <= '9')) {
+		return n, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+func decodePDFName(raw []byte) string {
+	if bytes.IndexByte(raw, '#') < 0 {
+		return string(raw)
+	}
+	var b []byte
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			if v, err := strconv.ParseUint(string(raw[i+1:i+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				i += 2
+				continue
+			}
+		}
+		b = append(b, raw[i])
+	}
+	return string(b)
+}
+
+func (l *pdfLexer) literalString() (any, error) {
+	l.pos++ // (
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfString(b), nil
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				continue
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return pdfString(b), nil
+}
+
+func (l *pdfLexer) hexString() (any, error) {
+	l.pos++ // <
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b := make([]byte, len(digits)/2)
+	if _, err := hex.Decode(b, digits); err != nil {
+		return nil, fmt.Errorf("invalid hex string: %w", err)
+	}
+	return pdfString(b), nil
+}
+
+// object reads a complete object. References ("12 0 R") are recognized when refs is set;
+// content streams have no references.
+func (l *pdfLexer) object(refs bool, depth int) (any, error) {
+	tok, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+	return l.objectFrom(tok, refs, depth)
+}
+
+func (l *pdfLexer) objectFrom(tok any, refs bool, depth int) (any, error) {
+	if depth > pdfMaxDepth {
+		return nil, errors.New("objects nested too deeply")
+	}
+	switch t := tok.(type) {
+	case float64:
+		if !refs || t != math.Trunc(t) || t < 0 {
+			return t, nil
+		}
+		// Look ahead for "<num> <gen> R"
+		save := l.pos
+		if gen, err := l.token(); err == nil {
+			if g, ok := gen.(float64); ok && g == math.Trunc(g) {
+				if r, err := l.token(); err == nil && r == pdfKeyword("R") {
+					return pdfRef{num: int(t)}, nil
+				}
+			}
+		}
+		l.pos = save
+		return t, nil
+
+	case pdfKeyword:
+		switch t {
+		case "[":
+			var arr pdfArray
+			for {
+				tok, err := l.token()
+				if err != nil {
+					return arr, err
+				}
+				if tok == pdfKeyword("]") {
+					return arr, nil
+				}
+				v, err := l.objectFrom(tok, refs, depth+1)
+				if err != nil {
+					return arr, err
+				}
+				arr = append(arr, v)
+			}
+		case "<<":
+			dict := pdfDict{}
+			for {
+				tok, err := l.token()
+				if err != nil {
+					return dict, err
+				}
+				if tok == pdfKeyword(">>") {
+					return dict, nil
+				}
+				key, ok := tok.(pdfName)
+				if !ok {
+					continue
+				}
+				v, err := l.object(refs, depth+1)
+				if err != nil {
+					return dict, err
+				}
+				dict[key] = v
+			}
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	}
+	return tok, nil
+}
+
+// Document structure
+
+type pdfDocument struct {
+	objects map[int]any
+	roots   []pdfRef // catalog references from trailers, last one first
+}
+
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+var (
+	pdfObjectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	pdfRootRef      = regexp.MustCompile(`/Root\s+(\d+)\s+\d+\s+R`)
+)
+
+// parsePDFDocument reads every indirect object by scanning the file rather than trusting the
+// cross-reference table, which also copes with damaged files. Later definitions win, matching
+// incremental updates.
+func parsePDFDocument(data []byte) *pdfDocument {
+	doc := &pdfDocument{objects: map[int]any{}}
+	for _, m := range pdfRootRef.FindAllSubmatch(data, -1) {
+		if num, err := strconv.Atoi(string(m[1])); err == nil {
+			doc.roots = append([]pdfRef{{num: num}}, doc.roots...)
+		}
+	}
+
+	skipUntil := 0
+	for _, m := range pdfObjectHeader.FindAllSubmatchIndex(data, -1) {
+		if m[0] < skipUntil {
+			continue // "obj" inside stream data
+		}
+		num, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+
+		l := &pdfLexer{data: data, pos: m[1]}
+		value, err := l.object(true, 0)
+		if err != nil {
+			continue
+		}
+
+		l.skipSpace()
+		if dict, ok := value.(pdfDict); ok && bytes.HasPrefix(data[l.pos:], []byte("stream")) {
+			start := l.pos + len("stream")
+			if start < len(data) && data[start] == '\r' {
+				start++
+			}
+			if start < len(data) && data[start] == '\n' {
+				start++
+			}
+			end := pdfStreamEnd(data, start, dict)
+			value = &pdfStream{dict: dict, raw: data[start:end]}
+			skipUntil = end
+		}
+		doc.objects[num] = value
+	}
+
+	// Objects compressed into object streams; directly defined objects take precedence
+	for _, value := range doc.objects {
+		stream, ok := value.(*pdfStream)
+		if !ok || stream.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		doc.loadObjectStream(stream)
+	}
+	return doc
+}
+
+// pdfStreamEnd finds where stream data ends, preferring the declared length
+func pdfStreamEnd(data []byte, start int, dict pdfDict) int {
+	if length, ok := dict["Length"].(float64); ok {
+		end := start + int(length)
+		if end >= start && end <= len(data) {
+			rest := bytes.TrimLeft(data[end:], "\r\n ")
+			if bytes.HasPrefix(rest, []byte("endstream")) {
+				return end
+			}
+		}
+	}
+	idx := bytes.Index(data[start:], []byte("endstream"))
+	if idx < 0 {
+		return len(data)
+	}
+	end := start + idx
+	for end > start && (data[end-1] == '\n' || data[end-1] == '\r') {
+		end--
+	}
+	return end
+}
+
+func (d *pdfDocument) loadObjectStream(stream *pdfStream) {
+	data, err := d.decodeStream(stream)
+	if err != nil {
+		return
+	}
+	n, _ := stream.dict["N"].(float64)
+	first, _ := stream.dict["First"].(float64)
+
+	header := &pdfLexer{data: data}
+	for i := 0; i < int(n); i++ {
+		numTok, err1 := header.token()
+		offTok, err2 := header.token()
+		num, ok1 := numTok.(float64)
+		off, ok2 := offTok.(float64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			return
+		}
+		if _, exists := d.objects[int(num)]; exists {
+			continue
+		}
+		pos := int(first) + int(off)
+		if pos < 0 || pos >= len(data) {
+			continue
+		}
+		l := &pdfLexer{data: data, pos: pos}
+		if value, err := l.object(true, 0); err == nil {
+			d.objects[int(num)] = value
+		}
+	}
+}
+
+// resolve follows references to the object they point to
+func (d *pdfDocument) resolve(v any) any {
+	for i := 0; i < pdfMaxDepth; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.objects[ref.num]
+	}
+	return nil
+}
+
+func (d *pdfDocument) dict(v any) pdfDict {
+	switch t := d.resolve(v).(type) {
+	case pdfDict:
+		return t
+	case *pdfStream:
+		return t.dict
+	}
+	return nil
+}
+
+// decodeStream applies the stream's filters
+func (d *pdfDocument) decodeStream(stream *pdfStream) ([]byte, error) {
+	var filters []any
+	switch f := d.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{f}
+	case pdfArray:
+		filters = f
+	}
+
+	data := stream.raw
+	for _, f := range filters {
+		var err error
+		switch d.resolve(f) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			var r io.ReadCloser
+			r, err = zlib.NewReader(bytes.NewReader(data))
+			if err == nil {
+				// Keep what was inflated before a corrupt tail
+				data, err = io.ReadAll(io.LimitReader(r, pdfMaxStreamSize))
+				if len(data) > 0 {
+					err = nil
+				}
+			}
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			hexData := bytes.TrimSuffix(bytes.TrimSpace(data), []byte(">"))
+			l := &pdfLexer{data: append(append([]byte("<"), hexData...), '>')}
+			var s any
+			if s, err = l.hexString(); err == nil {
+				data = s.(pdfString)
+			}
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			src := bytes.TrimSuffix(bytes.TrimSpace(data), []byte("~>"))
+			src = bytes.TrimPrefix(src, []byte("<~"))
+			dst := make([]byte, 4*len(src)/5+4)
+			var n int
+			n, _, err = ascii85.Decode(dst, src, true)
+			data = dst[:n]
+		default:
+			return nil, fmt.Errorf("unsupported stream filter %v", f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// pages returns the pages in document order with their inherited resources
+func (d *pdfDocument) pages() []pdfPage {
+	var pages []pdfPage
+	visited := map[int]bool{}
+
+	var walk func(node any, resources pdfDict, depth int)
+	walk = func(node any, resources pdfDict, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref.num] {
+				return
+			}
+			visited[ref.num] = true
+		}
+		dict := d.dict(node)
+		if dict == nil || depth > pdfMaxDepth {
+			return
+		}
+		if r := d.dict(dict["Resources"]); r != nil {
+			resources = r
+		}
+		if dict["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: dict, resources: resources})
+			return
+		}
+		if kids, ok := d.resolve(dict["Kids"]).(pdfArray); ok {
+			for _, kid := range kids {
+				walk(kid, resources, depth+1)
+			}
+		}
+	}
+
+	for _, root := range d.roots {
+		if catalog := d.dict(root); catalog != nil {
+			walk(catalog["Pages"], nil, 0)
+		}
+		if len(pages) > 0 {
+			return pages
+		}
+	}
+
+	// No usable catalog: take every page object in object number order
+	var nums []int
+	for num, value := range d.objects {
+		if dict, ok := value.(pdfDict); ok && dict["Type"] == pdfName("Page") {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		dict := d.objects[num].(pdfDict)
+		pages = append(pages, pdfPage{dict: dict, resources: d.dict(dict["Resources"])})
+	}
+	return pages
+}
+
+// Text extraction
+
+// pdfTextWriter assembles shown text into lines, using text positions to tell
+// line breaks from gaps between words
+type pdfTextWriter struct {
+	lines    []string
+	line     strings.Builder
+	hasText  bool
+	lastY    float64
+	lastEndX float64
+}
+
+// show adds text drawn at x, y that is advance wide in a font of the given size
+func (w *pdfTextWriter) show(text string, x, y, advance, size float64) {
+	if text == "" {
+		return
+	}
+	if w.hasText {
+		switch {
+		case math.Abs(y-w.lastY) > math.Max(size, 1)/2:
+			w.newline()
+		case x > w.lastEndX+size*0.15 || x < w.lastEndX-size:
+			// A gap wider than letter spacing separates words; jumping back starts a new column
+			w.space()
+		}
+	}
+	w.line.WriteString(text)
+	w.hasText = true
+	w.lastY = y
+	w.lastEndX = x + advance
+}
+
+func (w *pdfTextWriter) space() {
+	if s := w.line.String(); s != "" && !strings.HasSuffix(s, " ") {
+		w.line.WriteByte(' ')
+	}
+}
+
+func (w *pdfTextWriter) newline() {
+	w.lines = append(w.lines, w.line.String())
+	w.line.Reset()
+}
+
+func (w *pdfTextWriter) String() string {
+	lines := append(w.lines, w.line.String())
+	var out []string
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// pdfTextState is the part of the graphics state that positions text. Rotation and the
+// current transformation matrix are ignored; invoices are laid out horizontally.
+type pdfTextState struct {
+	font     *pdfFont
+	fontSize float64
+	leading  float64
+	scaleX   float64
+	scaleY   float64
+	x, y     float64 // current position
+	lineX    float64 // start of the current line
+	lineY    float64
+}
+
+func (s *pdfTextState) moveLine(tx, ty float64) {
+	s.lineX += tx * s.scaleX
+	s.lineY += ty * s.scaleY
+	s.x, s.y = s.lineX, s.lineY
+}
+
+func (d *pdfDocument) writePageText(w *pdfTextWriter, page pdfDict, resources pdfDict, depth int) {
+	var content []byte
+	switch c := d.resolve(page["Contents"]).(type) {
+	case *pdfStream:
+		content, _ = d.decodeStream(c)
+	case pdfArray:
+		for _, part := range c {
+			if stream, ok := d.resolve(part).(*pdfStream); ok {
+				data, _ := d.decodeStream(stream)
+				content = append(append(content, data...), '\n')
+			}
+		}
+	}
+	d.writeContentText(w, content, resources, depth)
+}
+
+func (d *pdfDocument) writeContentText(w *pdfTextWriter, content []byte, resources pdfDict, depth int) {
+	if depth > pdfMaxDepth || len(content) == 0 {
+		return
+	}
+	fonts := map[pdfName]*pdfFont{}
+	state := pdfTextState{scaleX: 1, scaleY: 1}
+
+	showString := func(s pdfString) {
+		size := state.fontSize * math.Abs(state.scaleX)
+		text, advance := state.font.decode(s, size)
+		w.show(text, state.x, state.y, advance, size)
+		state.x += advance
+	}
+
+	l := &pdfLexer{data: content}
+	var operands []any
+	for {
+		tok, err := l.token()
+		if err != nil {
+			return
+		}
+		op, isOp := tok.(pdfKeyword)
+		if !isOp || op == "[" || op == "<<" {
+			v, err := l.objectFrom(tok, false, 0)
+			if err != nil {
+				return
+			}
+			operands = append(operands, v)
+			continue
+		}
+
+		switch op {
+		case "BI":
+			// Inline image data is binary; skip to the end of the image
+			idx := bytes.Index(l.data[l.pos:], []byte("EI"))
+			for idx >= 0 && l.pos+idx+2 < len(l.data) && !isPDFSpace(l.data[l.pos+idx+2]) {
+				next := bytes.Index(l.data[l.pos+idx+2:], []byte("EI"))
+				if next < 0 {
+					idx = -1
+					break
+				}
+				idx += next + 2
+			}
+			if idx < 0 {
+				return
+			}
+			l.pos += idx + 2
+		case "BT":
+			state.scaleX, state.scaleY = 1, 1
+			state.x, state.y, state.lineX, state.lineY = 0, 0, 0, 0
+		case "Tf":
+			if len(operands) >= 2 {
+				name, _ := operands[len(operands)-2].(pdfName)
+				state.fontSize, _ = operands[len(operands)-1].(float64)
+				if _, ok := fonts[name]; !ok {
+					fonts[name] = d.loadFont(d.dict(resources["Font"])[name])
+				}
+				state.font = fonts[name]
+			}
+		case "TL":
+			if n := pdfNumbers(operands, 1); n != nil {
+				state.leading = n[0]
+			}
+		case "Td":
+			if n := pdfNumbers(operands, 2); n != nil {
+				state.moveLine(n[0], n[1])
+			}
+		case "TD":
+			if n := pdfNumbers(operands, 2); n != nil {
+				state.leading = -n[1]
+				state.moveLine(n[0], n[1])
+			}
+		case "Tm":
+			if n := pdfNumbers(operands, 6); n != nil {
+				state.scaleX, state.scaleY = n[0], n[3]
+				if state.scaleX == 0 {
+					state.scaleX = 1
+				}
+				if state.scaleY == 0 {
+					state.scaleY = 1
+				}
+				state.lineX, state.lineY = n[4], n[5]
+				state.x, state.y = n[4], n[5]
+			}
+		case "T*":
+			state.moveLine(0, -state.leading)
+		case "Tj":
+			if len(operands) > 0 {
+				if s, ok := operands[len(operands)-1].(pdfString); ok {
+					showString(s)
+				}
+			}
+		case "'", "\"":
+			state.moveLine(0, -state.leading)
+			if len(operands) > 0 {
+				if s, ok := operands[len(operands)-1].(pdfString); ok {
+					showString(s)
+				}
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				arr, _ := operands[len(operands)-1].(pdfArray)
+				for _, part := range arr {
+					switch p := part.(type) {
+					case pdfString:
+						showString(p)
+					case float64:
+						// Adjustments are in thousandths of the font size; large negative ones separate words
+						state.x -= p / 1000 * state.fontSize * math.Abs(state.scaleX)
+						if p < -250 {
+							w.space()
+						}
+					}
+				}
+			}
+		case "Do":
+			if len(operands) > 0 {
+				name, _ := operands[len(operands)-1].(pdfName)
+				xobject, ok := d.resolve(d.dict(resources["XObject"])[name]).(*pdfStream)
+				if ok && xobject.dict["Subtype"] == pdfName("Form") {
+					formResources := d.dict(xobject.dict["Resources"])
+					if formResources == nil {
+						formResources = resources
+					}
+					data, _ := d.decodeStream(xobject)
+					d.writeContentText(w, data, formResources, depth+1)
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func pdfNumbers(operands []any, n int) []float64 {
+	if len(operands) < n {
+		return nil
+	}
+	nums := make([]float64, n)
+	for i, v := range operands[len(operands)-n:] {
+		f, ok := v.(float64)
+		if !ok {
+			return nil
+		}
+		nums[i] = f
+	}
+	return nums
+}
+
+// Fonts
+
+// pdfFont maps character codes of shown strings to text
+type pdfFont struct {
+	toUnicode map[uint32]string  // from the font's ToUnicode map
+	codeBytes int                // bytes per character code
+	encoding  [256]rune          // simple fonts without a ToUnicode entry for a code
+	composite bool               // Type0 font; codes without a ToUnicode entry are glyph IDs
+	widths    map[uint32]float64 // glyph widths in thousandths of the font size
+	dw        float64            // width of glyphs missing from widths
+}
+
+func (d *pdfDocument) loadFont(ref any) *pdfFont {
+	font := &pdfFont{codeBytes: 1, encoding: winAnsiEncoding(), widths: map[uint32]float64{}, dw: 500}
+	dict := d.dict(ref)
+	if dict == nil {
+		return font
+	}
+
+	if dict["Subtype"] == pdfName("Type0") {
+		font.composite = true
+		font.codeBytes = 2
+		font.dw = 1000
+		if descendants, ok := d.resolve(dict["DescendantFonts"]).(pdfArray); ok && len(descendants) > 0 {
+			d.loadCIDWidths(font, d.dict(descendants[0]))
+		}
+	} else if widths, ok := d.resolve(dict["Widths"]).(pdfArray); ok {
+		first, _ := d.resolve(dict["FirstChar"]).(float64)
+		for i, v := range widths {
+			if w, ok := d.resolve(v).(float64); ok {
+				font.widths[uint32(first)+uint32(i)] = w
+			}
+		}
+	}
+
+	switch enc := d.resolve(dict["Encoding"]).(type) {
+	case pdfDict:
+		if diffs, ok := d.resolve(enc["Differences"]).(pdfArray); ok {
+			code := 0
+			for _, v := range diffs {
+				switch t := d.resolve(v).(type) {
+				case float64:
+					code = int(t)
+				case pdfName:
+					if code >= 0 && code < 256 {
+						if r, ok := glyphNameRune(string(t)); ok {
+							font.encoding[code] = r
+						}
+					}
+					code++
+				}
+			}
+		}
+	}
+
+	if stream, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.decodeStream(stream); err == nil {
+			font.toUnicode, font.codeBytes = parseToUnicodeCMap(data, font.codeBytes)
+		}
+	}
+	return font
+}
+
+// loadCIDWidths reads the W array of a CID font: "c [w1 w2 ...]" lists widths from code c,
+// "cfirst clast w" gives a range the same width
+func (d *pdfDocument) loadCIDWidths(font *pdfFont, cidFont pdfDict) {
+	if cidFont == nil {
+		return
+	}
+	if dw, ok := d.resolve(cidFont["DW"]).(float64); ok {
+		font.dw = dw
+	}
+	w, _ := d.resolve(cidFont["W"]).(pdfArray)
+	for i := 0; i+1 < len(w); {
+		first, ok := d.resolve(w[i]).(float64)
+		if !ok {
+			return
+		}
+		switch next := d.resolve(w[i+1]).(type) {
+		case pdfArray:
+			for j, v := range next {
+				if width, ok := d.resolve(v).(float64); ok {
+					font.widths[uint32(first)+uint32(j)] = width
+				}
+			}
+			i += 2
+		case float64:
+			if i+2 >= len(w) || next < first || next-first > pdfMaxCMapRange {
+				return
+			}
+			width, _ := d.resolve(w[i+2]).(float64)
+			for c := uint32(first); c <= uint32(next); c++ {
+				font.widths[c] = width
+			}
+			i += 3
+		default:
+			return
+		}
+	}
+}
+
+// decode converts a shown string to text and returns its width at the given font size
+func (f *pdfFont) decode(s pdfString, size float64) (string, float64) {
+	if f == nil {
+		f = &pdfFont{codeBytes: 1, encoding: winAnsiEncoding(), dw: 500}
+	}
+	var b strings.Builder
+	width := 0.0
+	for i := 0; i+f.codeBytes <= len(s); i += f.codeBytes {
+		var code uint32
+		for _, c := range s[i : i+f.codeBytes] {
+			code = code<<8 | uint32(c)
+		}
+		if w, ok := f.widths[code]; ok {
+			width += w
+		} else {
+			width += f.dw
+		}
+		if text, ok := f.toUnicode[code]; ok {
+			b.WriteString(text)
+		} else if !f.composite && code < 256 && f.encoding[code] != 0 {
+			b.WriteRune(f.encoding[code])
+		}
+	}
+	return b.String(), width / 1000 * size
+}
+
+// parseToUnicodeCMap reads bfchar and bfrange mappings of a ToUnicode CMap.
+// The code length comes from the codespace range.
+func parseToUnicodeCMap(data []byte, codeBytes int) (map[uint32]string, int) {
+	mapping := map[uint32]string{}
+	l := &pdfLexer{data: data}
+	var operands []any
+
+	for {
+		tok, err := l.token()
+		if err != nil {
+			return mapping, codeBytes
+		}
+		op, isOp := tok.(pdfKeyword)
+		if !isOp || op == "[" || op == "<<" {
+			if v, err := l.objectFrom(tok, false, 0); err == nil {
+				operands = append(operands, v)
+			}
+			continue
+		}
+
+		switch op {
+		case "endcodespacerange":
+			if len(operands) > 0 {
+				if lo, ok := operands[0].(pdfString); ok && len(lo) > 0 {
+					codeBytes = len(lo)
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					mapping[cmapCode(src)] = utf16BEString(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 {
+					continue
+				}
+				start, end := cmapCode(lo), cmapCode(hi)
+				if end < start || end-start > pdfMaxCMapRange {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					units := utf16BE(dst)
+					for code := start; code <= end; code++ {
+						next := append([]uint16(nil), units...)
+						if len(next) > 0 {
+							next[len(next)-1] += uint16(code - start)
+						}
+						mapping[code] = string(utf16.Decode(next))
+					}
+				case pdfArray:
+					for j, v := range dst {
+						if s, ok := v.(pdfString); ok && start+uint32(j) <= end {
+							mapping[start+uint32(j)] = utf16BEString(s)
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func cmapCode(b []byte) uint32 {
+	var code uint32
+	for _, c := range b {
+		code = code<<8 | uint32(c)
+	}
+	return code
+}
+
+func utf16BE(b []byte) []uint16 {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return units
+}
+
+func utf16BEString(b []byte) string {
+	return string(utf16.Decode(utf16BE(b)))
+}
+
+// winAnsiEncoding is the Windows-1252 code page most simple fonts use
+func winAnsiEncoding() [256]rune {
+	var enc [256]rune
+	for i := 32; i < 256; i++ {
+		enc[i] = rune(i)
+	}
+	enc[127] = 0
+	for i, r := range []rune("€\x00‚ƒ„…†‡ˆ‰Š‹Œ\x00Ž\x00\x00‘’“”•–—˜™š›œ\x00žŸ") {
+		enc[0x80+i] = r
+	}
+	enc[0xa0] = ' '
+	return enc
+}
+
+// glyphNames covers the Adobe glyph names used in font encoding differences for
+// Polish text and common punctuation; single letters and digits are handled directly
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$', "percent": '%',
+	"ampersand": '&', "quotesingle": '\'', "parenleft": '(', "parenright": ')', "asterisk": '*',
+	"plus": '+', "comma": ',', "hyphen": '-', "period": '.', "slash": '/', "colon": ':',
+	"semicolon": ';', "less": '<', "equal": '=', "greater": '>', "question": '?', "at": '@',
+	"bracketleft": '[', "backslash": '\\', "bracketright": ']', "underscore": '_',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4', "five": '5', "six": '6',
+	"seven": '7', "eight": '8', "nine": '9', "endash": '–', "emdash": '—', "quoteright": '’',
+	"quotedblbase": '„', "quotedblright": '”', "degree": '°', "section": '§', "Euro": '€',
+	"aogonek": 'ą', "Aogonek": 'Ą', "cacute": 'ć', "Cacute": 'Ć', "eogonek": 'ę', "Eogonek": 'Ę',
+	"lslash": 'ł', "Lslash": 'Ł', "nacute": 'ń', "Nacute": 'Ń', "oacute": 'ó', "Oacute": 'Ó',
+	"sacute": 'ś', "Sacute": 'Ś', "zacute": 'ź', "Zacute": 'Ź', "zdotaccent": 'ż', "Zdotaccent": 'Ż',
+	"threesuperior": '³', "twosuperior": '²', "nbspace": ' ',
+}
+
+func glyphNameRune(name string) (rune, bool) {
+	if r, ok := glyphNames[name]; ok {
+		return r, true
+	}
+	if len(name) == 1 {
+		return rune(name[0]), true
+	}
+	if strings.HasPrefix(name, "uni") && len(name) == 7 {
+		if v, err := strconv.ParseUint(name[3:], 16, 32); err == nil {
+			return rune(v), true
+		}
+	}
+	return 0, false
+}
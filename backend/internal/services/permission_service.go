@@ -41,6 +41,7 @@ func (s *PermissionService) InitializeDefaultPermissions(ctx context.Context) er
 		{ID: uuid.New().String(), Name: "bills.delete", Description: "Usuń rachunki", Category: "bills"},
 		{ID: uuid.New().String(), Name: "bills.post", Description: "Opublikuj rachunki", Category: "bills"},
 		{ID: uuid.New().String(), Name: "bills.close", Description: "Zamknij rachunki", Category: "bills"},
+		{ID: uuid.New().String(), Name: "bills.disputes.resolve", Description: "Rozstrzygaj spory dotyczące rachunków", Category: "bills"},
 
 		// Chore management
 		{ID: uuid.New().String(), Name: "chores.create", Description: "Twórz nowe obowiązki", Category: "chores"},
@@ -77,8 +78,18 @@ func (s *PermissionService) InitializeDefaultPermissions(ctx context.Context) er
 		{ID: uuid.New().String(), Name: "loan-payments.update", Description: "Edytuj spłaty pożyczek", Category: "loans"},
 		{ID: uuid.New().String(), Name: "loan-payments.delete", Description: "Usuń spłaty pożyczek", Category: "loans"},
 
+		// Bank statement import
+		{ID: uuid.New().String(), Name: "payments.import", Description: "Importuj wyciągi bankowe i dopasowuj wpłaty", Category: "payments"},
+
+		// Late fees
+		{ID: uuid.New().String(), Name: "late-fees.manage", Description: "Ustalaj i umarzaj opłaty za opóźnienie", Category: "payments"},
+
+		// Budgets
+		{ID: uuid.New().String(), Name: "budgets.manage", Description: "Ustalaj miesięczne budżety domowe", Category: "budgets"},
+
 		// Reading management
 		{ID: uuid.New().String(), Name: "readings.delete", Description: "Usuń odczyty liczników", Category: "readings"},
+		{ID: uuid.New().String(), Name: "meters.manage", Description: "Zarządzaj licznikami i ich wymianą", Category: "readings"},
 
 		// Backup management
 		{ID: uuid.New().String(), Name: "backup.export", Description: "Eksportuj kopię zapasową", Category: "backup"},
@@ -147,6 +158,7 @@ func (s *RoleService) InitializeDefaultRoles(ctx context.Context) error {
 		"users.create", "users.read", "users.update", "users.delete",
 		"groups.create", "groups.read", "groups.update", "groups.delete",
 		"bills.create", "bills.read", "bills.update", "bills.delete", "bills.post", "bills.close",
+		"bills.disputes.resolve",
 		"chores.create", "chores.read", "chores.update", "chores.delete", "chores.assign",
 		"supplies.create", "supplies.read", "supplies.update", "supplies.delete",
 		"roles.create", "roles.read", "roles.update", "roles.delete",
@@ -154,7 +166,11 @@ func (s *RoleService) InitializeDefaultRoles(ctx context.Context) error {
 		"audit.read",
 		"loans.create", "loans.read", "loans.update", "loans.delete",
 		"loan-payments.create", "loan-payments.read", "loan-payments.update", "loan-payments.delete",
+		"payments.import",
+		"late-fees.manage",
+		"budgets.manage",
 		"readings.delete",
+		"meters.manage",
 		"backup.export", "backup.import",
 		"settings.app.update",
 		"reminders.send",
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+// estimateHistoryBills is how many earlier bills of a utility a subject's usual usage is taken from
+const estimateHistoryBills = 3
+
+// meterPoint is a known value of a meter at a point in time
+type meterPoint struct {
+	at    time.Time
+	value float64
+}
+
+// StoreEstimatedReadings records estimates for the readings still missing on a metered bill, so they
+// are listed among the bill's readings with source "estimated". A real reading of the subject that
+// arrives before the bill is closed replaces them.
+func (s *AllocationService) StoreEstimatedReadings(ctx context.Context, bill *models.Bill) (int, error) {
+	if bill.AllocationType == nil || *bill.AllocationType != "metered" {
+		return 0, nil
+	}
+
+	pools, zoned, err := s.meteredPools(ctx, bill)
+	if err != nil {
+		return 0, err
+	}
+	consumptions, err := s.consumptions.ListByBillID(ctx, bill.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get consumptions: %w", err)
+	}
+	subjects, err := s.billSubjects(ctx, bill)
+	if err != nil {
+		return 0, err
+	}
+
+	estimates, err := s.estimateMissingReadings(ctx, bill, consumptions, subjects, pools, zoned)
+	if err != nil {
+		return 0, err
+	}
+	for i := range estimates {
+		estimate := &estimates[i]
+		if err := s.consumptions.Create(ctx, estimate); err != nil {
+			return 0, fmt.Errorf("failed to store estimated reading: %w", err)
+		}
+		// A reading already taken after an interpolated estimate now continues from it
+		if estimate.MeterID != nil {
+			if err := rederiveNextReading(ctx, s.consumptions, s.meters, s.bills, *estimate.MeterID, estimate.RecordedAt); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(estimates), nil
+}
+
+// estimateMissingReadings estimates the usage of subjects whose readings are missing on a metered bill.
+// Every meter of the bill's utility that was not read gets an estimated value at the end of the period;
+// subjects without such meters are charged their usual usage per day on their last bills of the utility.
+// Bills with tariff zones only price meter readings, so only meters in the bill's zones are estimated.
+func (s *AllocationService) estimateMissingReadings(ctx context.Context, bill *models.Bill, consumptions []models.Consumption, subjects []allocationSubject, pools []meteredPool, zoned bool) ([]models.Consumption, error) {
+	readSubjects := make(map[string]bool)
+	manualSubjects := make(map[string]bool) // subjects with readings not taken from a registered meter
+	readMeters := make(map[string]bool)
+	for _, c := range consumptions {
+		if c.Source == "invalid" {
+			continue
+		}
+		readSubjects[c.SubjectID] = true
+		if c.MeterID == nil {
+			manualSubjects[c.SubjectID] = true
+		} else {
+			readMeters[*c.MeterID] = true
+		}
+	}
+
+	var zones map[string]bool
+	if zoned {
+		zones = make(map[string]bool, len(pools))
+		for _, pool := range pools {
+			zones[pool.zone] = true
+		}
+	}
+
+	var estimates []models.Consumption
+	for _, subject := range subjects {
+		if manualSubjects[subject.id] {
+			continue
+		}
+
+		meterEstimates, err := s.estimateMeterReadings(ctx, bill, subject, readMeters, zones)
+		if err != nil {
+			return nil, err
+		}
+		if len(meterEstimates) > 0 || readSubjects[subject.id] || zoned {
+			estimates = append(estimates, meterEstimates...)
+			continue
+		}
+
+		estimate, err := s.estimateFromHistory(ctx, bill, subject)
+		if err != nil {
+			return nil, err
+		}
+		if estimate != nil {
+			estimates = append(estimates, *estimate)
+		}
+	}
+	return estimates, nil
+}
+
+// estimateMeterReadings estimates a reading at the end of the bill period for each of the subject's
+// meters of the bill's utility that was in use then and has not been read for the bill
+func (s *AllocationService) estimateMeterReadings(ctx context.Context, bill *models.Bill, subject allocationSubject, readMeters, zones map[string]bool) ([]models.Consumption, error) {
+	// Only electricity and gas bills tell which meters they are read from
+	if bill.Type != "electricity" && bill.Type != "gas" {
+		return nil, nil
+	}
+
+	meters, err := s.meters.ListBySubject(ctx, subject.subjectType, subject.id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get meters: %w", err)
+	}
+
+	var estimates []models.Consumption
+	for _, meter := range meters {
+		if meter.Type != bill.Type || readMeters[meter.ID] || meter.InstalledAt.After(bill.PeriodEnd) {
+			continue
+		}
+		if meter.ReplacedAt != nil && !meter.ReplacedAt.After(bill.PeriodEnd) {
+			continue
+		}
+		if zones != nil && !zones[meter.TariffZone] {
+			continue
+		}
+
+		value, ok, err := s.estimateMeterValue(ctx, meter, bill.PeriodEnd)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		meterID := meter.ID
+		meterValue := utils.FloatToDecimalString(value)
+		estimate := models.Consumption{
+			BillID:      bill.ID,
+			SubjectType: subject.subjectType,
+			SubjectID:   subject.id,
+			MeterID:     &meterID,
+			MeterValue:  &meterValue,
+			RecordedAt:  bill.PeriodEnd,
+			Source:      "estimated",
+		}
+		units, err := meterReadingUnits(ctx, s.consumptions, s.meters, estimate)
+		if errors.Is(err, ErrMeterWentBackwards) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if units <= 0 {
+			continue
+		}
+		estimate.Units = utils.FloatToDecimalString(units)
+		estimates = append(estimates, estimate)
+	}
+	return estimates, nil
+}
+
+// estimateMeterValue estimates what a meter showed at the given time from its real readings: interpolated
+// between the readings before and after that time, or extrapolated from the usage per day over the year
+// up to the last reading. Reports false when the meter has not been read enough to tell.
+func (s *AllocationService) estimateMeterValue(ctx context.Context, meter models.Meter, at time.Time) (float64, bool, error) {
+	readings, err := s.consumptions.ListByMeterID(ctx, meter.ID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to fetch consumptions: %w", err)
+	}
+
+	points := []meterPoint{{at: meter.InstalledAt, value: utils.DecimalStringToFloat(meter.InitialValue)}}
+	for _, c := range readings {
+		if c.MeterValue == nil || c.Source == "invalid" || c.Source == "estimated" {
+			continue
+		}
+		points = append(points, meterPoint{at: c.RecordedAt, value: utils.DecimalStringToFloat(*c.MeterValue)})
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].at.Before(points[j].at) })
+
+	var previous, next *meterPoint
+	for i := range points {
+		if points[i].at.After(at) {
+			next = &points[i]
+			break
+		}
+		previous = &points[i]
+	}
+	if previous == nil {
+		return 0, false, nil
+	}
+
+	if next != nil {
+		span := next.at.Sub(previous.at).Hours()
+		return previous.value + (next.value-previous.value)*at.Sub(previous.at).Hours()/span, true, nil
+	}
+
+	yearBefore := previous.at.AddDate(-1, 0, 0)
+	first := previous
+	for i := range points {
+		if !points[i].at.Before(yearBefore) {
+			first = &points[i]
+			break
+		}
+	}
+	days := previous.at.Sub(first.at).Hours() / 24
+	if days < 1 || previous.value < first.value {
+		return 0, false, nil
+	}
+	rate := (previous.value - first.value) / days
+	return previous.value + rate*at.Sub(previous.at).Hours()/24, true, nil
+}
+
+// estimateFromHistory estimates a subject's usage over the bill period from its usage per day on its last
+// few earlier bills of the same utility, counting the days someone of the subject lived in the household.
+// Returns nil when the subject has no such history.
+func (s *AllocationService) estimateFromHistory(ctx context.Context, bill *models.Bill, subject allocationSubject) (*models.Consumption, error) {
+	readings, err := s.consumptions.ListBySubject(ctx, subject.subjectType, subject.id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch consumptions: %w", err)
+	}
+
+	billUnits := make(map[string]float64)
+	for _, c := range readings {
+		if c.BillID == bill.ID || c.Source == "invalid" || c.Source == "estimated" {
+			continue
+		}
+		billUnits[c.BillID] += utils.DecimalStringToFloat(c.Units)
+	}
+
+	customType := ""
+	if bill.CustomType != nil {
+		customType = strings.TrimSpace(*bill.CustomType)
+	}
+	var history []models.Bill
+	for billID := range billUnits {
+		earlier, err := s.bills.GetByID(ctx, billID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get bill: %w", err)
+		}
+		if earlier == nil || earlier.Type != bill.Type || !billMatchesUtility(*earlier, customType) || !earlier.PeriodEnd.Before(bill.PeriodStart) {
+			continue
+		}
+		history = append(history, *earlier)
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].PeriodEnd.After(history[j].PeriodEnd) })
+	if len(history) > estimateHistoryBills {
+		history = history[:estimateHistoryBills]
+	}
+
+	totalUnits, days := 0.0, 0
+	for _, earlier := range history {
+		totalUnits += billUnits[earlier.ID]
+		days += daysInPeriod(earlier.PeriodStart, earlier.PeriodEnd)
+	}
+	if totalUnits <= 0 || days == 0 {
+		return nil, nil
+	}
+
+	daysPresent := 0
+	for _, p := range subject.presence {
+		if p.DaysPresent > daysPresent {
+			daysPresent = p.DaysPresent
+		}
+	}
+
+	return &models.Consumption{
+		BillID:      bill.ID,
+		SubjectType: subject.subjectType,
+		SubjectID:   subject.id,
+		Units:       utils.FloatToDecimalString(totalUnits / float64(days) * float64(daysPresent)),
+		RecordedAt:  bill.PeriodEnd,
+		Source:      "estimated",
+	}, nil
+}
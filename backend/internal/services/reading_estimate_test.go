@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateMissingReadings(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	billService, allocationService := newTestBillService(repos)
+	consumptionService := NewConsumptionService(repos.Consumptions, repos.ConsumptionAnomalies, newTestAttachmentService(t, repos), repos.Bills, repos.Users,
+		repos.Meters, repos.BillTariffZones, repos.TxManager)
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+	carol := createTestUser(t, repos, "Carol")
+	noon := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	newBill := func(start, end time.Time, status string) *models.Bill {
+		bill := &models.Bill{
+			Type: "electricity", AllocationType: stringPtr("metered"), PeriodStart: start, PeriodEnd: end,
+			TotalAmountPLN: "1000.00", Currency: "PLN", TotalUnits: "500", Status: status,
+		}
+		require.NoError(t, repos.Bills.Create(ctx, bill))
+		return bill
+	}
+	newMeter := func(user *models.User) *models.Meter {
+		meter := &models.Meter{Type: "electricity", Unit: "kWh", SubjectType: "user", SubjectID: user.ID, TariffZone: DefaultTariffZone,
+			InstalledAt: noon(2022, time.June, 1), InitialValue: "0"}
+		require.NoError(t, repos.Meters.Create(ctx, meter))
+		return meter
+	}
+	read := func(bill *models.Bill, user *models.User, meter *models.Meter, value, units string, at time.Time) *models.Consumption {
+		c := &models.Consumption{BillID: bill.ID, SubjectType: "user", SubjectID: user.ID, Units: units, RecordedAt: at, Source: "user"}
+		if meter != nil {
+			c.MeterID = &meter.ID
+			c.MeterValue = &value
+		}
+		require.NoError(t, repos.Consumptions.Create(ctx, c))
+		return c
+	}
+
+	november := newBill(noon(2023, time.November, 1), noon(2023, time.November, 30), "closed")
+	december := newBill(noon(2023, time.December, 1), noon(2023, time.December, 31), "closed")
+	january := newBill(noon(2024, time.January, 1), noon(2024, time.January, 31), "draft")
+	february := newBill(noon(2024, time.February, 1), noon(2024, time.February, 29), "draft")
+
+	// Alice uses 10 kWh a day and has not read January yet
+	aliceMeter := newMeter(alice)
+	read(december, alice, aliceMeter, "100", "100", noon(2023, time.December, 1))
+	read(december, alice, aliceMeter, "400", "300", noon(2023, time.December, 31))
+
+	// Bob skipped January but has already read his meter for February
+	bobMeter := newMeter(bob)
+	read(december, bob, bobMeter, "200", "200", noon(2023, time.December, 31))
+	bobFebruary := read(february, bob, bobMeter, "320", "120", noon(2024, time.February, 29))
+
+	// Carol has no meter and reports 2 kWh a day
+	read(november, carol, nil, "", "60", noon(2023, time.November, 30))
+	read(december, carol, nil, "", "62", noon(2023, time.December, 31))
+
+	// Alice: 400 kWh + 31 days at 10 kWh. Bob: 31 of the 60 days between his readings. Carol: 31 days at 2 kWh.
+	// 434 of 500 kWh are personal usage, 868 zł split by units; the other 132 zł are shared.
+	breakdown, err := allocationService.CalculateAllocation(ctx, january)
+	require.NoError(t, err)
+	got := make(map[string]AllocationBreakdown)
+	for _, entry := range breakdown {
+		got[entry.SubjectID] = entry
+	}
+	assert.Equal(t, 310.0, *got[alice.ID].EstimatedUnits)
+	assert.Equal(t, 62.0, *got[bob.ID].EstimatedUnits)
+	assert.Equal(t, 62.0, *got[carol.ID].EstimatedUnits)
+	assert.Equal(t, utils.NewMoney(664, 0), got[alice.ID].Amount)
+	assert.Equal(t, utils.NewMoney(168, 0), got[bob.ID].Amount)
+	assert.Equal(t, utils.NewMoney(168, 0), got[carol.ID].Amount)
+
+	// Posting stores the estimates; Bob's February reading continues from his estimate
+	require.NoError(t, billService.PostBill(ctx, january.ID))
+	readings, err := consumptionService.GetConsumptions(ctx, &january.ID)
+	require.NoError(t, err)
+	require.Len(t, readings, 3)
+	for _, reading := range readings {
+		assert.Equal(t, "estimated", reading.Source)
+		if reading.SubjectID == bob.ID {
+			assert.Equal(t, "262.00", *reading.MeterValue)
+		}
+	}
+	stored, err := repos.Consumptions.GetByID(ctx, bobFebruary.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "58.00", stored.Units)
+
+	breakdown, err = allocationService.CalculateAllocation(ctx, january)
+	require.NoError(t, err)
+	assert.Len(t, breakdown, 3)
+	for _, entry := range breakdown {
+		assert.NotNil(t, entry.EstimatedUnits)
+	}
+
+	// A rejected reading leaves the estimate it would have replaced
+	_, err = consumptionService.CreateConsumption(ctx, CreateConsumptionRequest{
+		BillID: january.ID, UserID: alice.ID, RecordedAt: noon(2024, time.January, 31),
+	}, "user")
+	require.Error(t, err)
+	aliceValue := 350.0
+	_, err = consumptionService.CreateConsumption(ctx, CreateConsumptionRequest{
+		BillID: january.ID, UserID: alice.ID, MeterID: &aliceMeter.ID, MeterValue: &aliceValue, RecordedAt: noon(2024, time.January, 31),
+	}, "user")
+	require.ErrorIs(t, err, ErrMeterWentBackwards)
+	_, err = consumptionService.CreateConsumption(ctx, CreateConsumptionRequest{
+		BillID: january.ID, UserID: carol.ID, Units: 40, RecordedAt: noon(2024, time.January, 31),
+		Photo: &ReadingPhoto{FileName: "meter.txt", Data: []byte("not a photo")},
+	}, "user")
+	require.Error(t, err)
+	readings, err = repos.Consumptions.ListByBillID(ctx, january.ID)
+	require.NoError(t, err)
+	assert.Len(t, readings, 3)
+
+	// Real readings replace the estimates while the bill is open
+	bobValue := 270.0
+	_, err = consumptionService.CreateConsumption(ctx, CreateConsumptionRequest{
+		BillID: january.ID, UserID: bob.ID, MeterID: &bobMeter.ID, MeterValue: &bobValue, RecordedAt: noon(2024, time.January, 31),
+	}, "user")
+	require.NoError(t, err)
+	_, err = consumptionService.CreateConsumption(ctx, CreateConsumptionRequest{
+		BillID: january.ID, UserID: carol.ID, Units: 40, RecordedAt: noon(2024, time.January, 31),
+	}, "user")
+	require.NoError(t, err)
+
+	readings, err = repos.Consumptions.ListByBillID(ctx, january.ID)
+	require.NoError(t, err)
+	sources := make(map[string]string)
+	for _, reading := range readings {
+		sources[reading.SubjectID] = reading.Source
+	}
+	assert.Equal(t, map[string]string{alice.ID: "estimated", bob.ID: "user", carol.ID: "user"}, sources)
+	stored, err = repos.Consumptions.GetByID(ctx, bobFebruary.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "50.00", stored.Units)
+
+	breakdown, err = allocationService.CalculateAllocation(ctx, january)
+	require.NoError(t, err)
+	for _, entry := range breakdown {
+		if entry.SubjectID == alice.ID {
+			assert.Equal(t, 310.0, *entry.EstimatedUnits)
+		} else {
+			assert.Nil(t, entry.EstimatedUnits)
+			assert.NotNil(t, entry.Units)
+		}
+	}
+}
@@ -20,8 +20,9 @@ type RecurringBillService struct {
 	templateAllocations repository.RecurringBillAllocationRepository
 	bills               repository.BillRepository
 	allocations         repository.AllocationRepository
-	payments            repository.PaymentRepository
 	users               repository.UserRepository
+	creditService       *CreditService
+	currencyService     *CurrencyService
 	cfg                 *config.Config
 }
 
@@ -30,8 +31,9 @@ func NewRecurringBillService(
 	templateAllocations repository.RecurringBillAllocationRepository,
 	bills repository.BillRepository,
 	allocations repository.AllocationRepository,
-	payments repository.PaymentRepository,
 	users repository.UserRepository,
+	creditService *CreditService,
+	currencyService *CurrencyService,
 	cfg *config.Config,
 ) *RecurringBillService {
 	return &RecurringBillService{
@@ -39,8 +41,9 @@ func NewRecurringBillService(
 		templateAllocations: templateAllocations,
 		bills:               bills,
 		allocations:         allocations,
-		payments:            payments,
 		users:               users,
+		creditService:       creditService,
+		currencyService:     currencyService,
 		cfg:                 cfg,
 	}
 }
@@ -52,6 +55,17 @@ func (s *RecurringBillService) CreateTemplate(ctx context.Context, template *mod
 		return err
 	}
 
+	amount, err := utils.ParseMoney(template.Amount)
+	if err != nil || !amount.IsPositive() {
+		return errors.New("amount must be a positive decimal number")
+	}
+	template.Amount = amount.String()
+
+	template.Currency, err = s.currencyService.ResolveCurrency(ctx, template.Currency)
+	if err != nil {
+		return err
+	}
+
 	// Set timestamps
 	now := time.Now()
 	template.ID = uuid.New().String()
@@ -95,8 +109,8 @@ func (s *RecurringBillService) CreateTemplate(ctx context.Context, template *mod
 		return fmt.Errorf("failed to generate first bill: %w", err)
 	}
 
-	log.Printf("[RECURRING BILL] Template created: %q (ID: %s, frequency: %s, amount: %s PLN, next due: %s)",
-		template.CustomType, template.ID, template.Frequency, template.Amount, template.NextDueDate.Format("2006-01-02"))
+	log.Printf("[RECURRING BILL] Template created: %q (ID: %s, frequency: %s, amount: %s %s, next due: %s)",
+		template.CustomType, template.ID, template.Frequency, template.Amount, template.Currency, template.NextDueDate.Format("2006-01-02"))
 
 	return nil
 }
@@ -165,8 +179,19 @@ func (s *RecurringBillService) UpdateTemplate(ctx context.Context, id string, up
 	if frequency, ok := updates["frequency"].(string); ok {
 		template.Frequency = frequency
 	}
-	if amount, ok := updates["amount"].(string); ok {
-		template.Amount = amount
+	if amountStr, ok := updates["amount"].(string); ok {
+		amount, err := utils.ParseMoney(amountStr)
+		if err != nil || !amount.IsPositive() {
+			return errors.New("amount must be a positive decimal number")
+		}
+		template.Amount = amount.String()
+	}
+	if currency, ok := updates["currency"].(string); ok {
+		normalized, err := NormalizeCurrency(currency)
+		if err != nil {
+			return err
+		}
+		template.Currency = normalized
 	}
 	if dayOfMonth, ok := updates["dayOfMonth"]; ok {
 		switch v := dayOfMonth.(type) {
@@ -315,6 +340,7 @@ func (s *RecurringBillService) generateBillFromTemplate(ctx context.Context, tem
 		PeriodEnd:           periodEnd,
 		PaymentDeadline:     &template.NextDueDate,
 		TotalAmountPLN:      template.Amount,
+		Currency:            template.Currency,
 		Notes:               template.Notes,
 		Status:              "draft", // Start as draft so it's modifiable
 		RecurringTemplateID: &template.ID,
@@ -327,38 +353,24 @@ func (s *RecurringBillService) generateBillFromTemplate(ctx context.Context, tem
 	}
 
 	// Create allocations based on template
-	for _, allocTemplate := range template.Allocations {
-		var allocatedAmount string
-
-		// Debug logging to track allocation creation
-		log.Printf("[RECURRING BILL] Creating allocation - Type: %s, SubjectType: %s", allocTemplate.AllocationType, allocTemplate.SubjectType)
-
-		switch allocTemplate.AllocationType {
-		case "fixed":
-			allocatedAmount = *allocTemplate.FixedAmount
-			amountFloat := utils.DecimalStringToFloat(allocatedAmount)
-			log.Printf("[RECURRING BILL] Fixed allocation: %.2f PLN", amountFloat)
-		case "percentage":
-			amountFloat := utils.DecimalStringToFloat(template.Amount)
-			percentage := *allocTemplate.Percentage / 100.0
-			allocatedFloat := amountFloat * percentage
-			roundedAmount := utils.RoundPLN(allocatedFloat)
-			allocatedAmount = utils.FloatToDecimalString(roundedAmount)
-			log.Printf("[RECURRING BILL] Percentage allocation: %.2f%% of %.2f = %.2f PLN", *allocTemplate.Percentage, amountFloat, roundedAmount)
-		case "fraction":
-			amountFloat := utils.DecimalStringToFloat(template.Amount)
-			fraction := float64(*allocTemplate.FractionNum) / float64(*allocTemplate.FractionDenom)
-			allocatedFloat := amountFloat * fraction
-			roundedAmount := utils.RoundPLN(allocatedFloat)
-			allocatedAmount = utils.FloatToDecimalString(roundedAmount)
-			log.Printf("[RECURRING BILL] Fraction allocation: %d/%d of %.2f = %.2f PLN", *allocTemplate.FractionNum, *allocTemplate.FractionDenom, amountFloat, roundedAmount)
-		}
+	amounts, err := s.calculateTemplateAllocationAmounts(template)
+	if err != nil {
+		return err
+	}
+	for i, allocTemplate := range template.Allocations {
+		log.Printf("[RECURRING BILL] Creating allocation - Type: %s, SubjectType: %s, Amount: %s %s",
+			allocTemplate.AllocationType, allocTemplate.SubjectType, amounts[i], template.Currency)
 
-		if err := s.allocations.Create(ctx, billID, allocTemplate.SubjectType, allocTemplate.SubjectID, allocatedAmount); err != nil {
+		if err := s.allocations.Create(ctx, billID, allocTemplate.SubjectType, allocTemplate.SubjectID, amounts[i].String()); err != nil {
 			return fmt.Errorf("failed to create allocation: %w", err)
 		}
 	}
 
+	// Allocations are fixed from the start, so leftover credit can be used right away
+	if err := s.creditService.ApplyCredit(ctx, bill); err != nil {
+		return err
+	}
+
 	// Update template's next due date, current bill ID, and last generated timestamp
 	nextDueDate := s.calculateNextDueDate(template.NextDueDate, template.DayOfMonth, template.Frequency)
 	template.CurrentBillID = &billID
@@ -370,12 +382,58 @@ func (s *RecurringBillService) generateBillFromTemplate(ctx context.Context, tem
 		return err
 	}
 
-	log.Printf("[RECURRING BILL] Bill generated from template %q (bill ID: %s, amount: %s PLN, period: %s to %s, next due: %s)",
-		template.CustomType, billID, template.Amount, periodStart.Format("2006-01-02"), periodEnd.Format("2006-01-02"), nextDueDate.Format("2006-01-02"))
+	log.Printf("[RECURRING BILL] Bill generated from template %q (bill ID: %s, amount: %s %s, period: %s to %s, next due: %s)",
+		template.CustomType, billID, template.Amount, template.Currency, periodStart.Format("2006-01-02"), periodEnd.Format("2006-01-02"), nextDueDate.Format("2006-01-02"))
 
 	return nil
 }
 
+// calculateTemplateAllocationAmounts converts template allocations into exact amounts.
+// Fixed amounts are taken as-is; percentage and fraction shares split the template amount
+// with remainder distribution, so a 1/3 + 1/3 + 1/3 split of 100.00 gives 33.34 + 33.33 + 33.33.
+func (s *RecurringBillService) calculateTemplateAllocationAmounts(template *models.RecurringBillTemplate) ([]utils.Money, error) {
+	total, err := utils.ParseMoney(template.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template amount %q: %w", template.Amount, err)
+	}
+
+	amounts := make([]utils.Money, len(template.Allocations))
+	weights := make([]float64, len(template.Allocations))
+	hasProportional := false
+
+	for i, alloc := range template.Allocations {
+		switch alloc.AllocationType {
+		case "fixed":
+			if alloc.FixedAmount == nil {
+				return nil, fmt.Errorf("allocation %d: fixed amount is required for fixed type", i+1)
+			}
+			amount, err := utils.ParseMoney(*alloc.FixedAmount)
+			if err != nil {
+				return nil, fmt.Errorf("allocation %d: invalid fixed amount %q", i+1, *alloc.FixedAmount)
+			}
+			amounts[i] = amount
+		case "percentage":
+			weights[i] = *alloc.Percentage / 100.0
+			hasProportional = true
+		case "fraction":
+			weights[i] = float64(*alloc.FractionNum) / float64(*alloc.FractionDenom)
+			hasProportional = true
+		}
+	}
+
+	if hasProportional {
+		// validateAllocations guarantees percentages/fractions cover the whole amount
+		shares := total.Allocate(weights)
+		for i, alloc := range template.Allocations {
+			if alloc.AllocationType != "fixed" {
+				amounts[i] = shares[i]
+			}
+		}
+	}
+
+	return amounts, nil
+}
+
 // calculateNextDueDate calculates the next due date based on frequency
 func (s *RecurringBillService) calculateNextDueDate(from time.Time, dayOfMonth int, frequency string) time.Time {
 	var next time.Time
@@ -447,8 +505,12 @@ func (s *RecurringBillService) CheckAndGenerateNextBill(ctx context.Context, bil
 		return nil
 	}
 
-	// Only generate next bill if the current bill is posted (not draft)
-	if bill.Status != "posted" {
+	// Only generate next bill once the current bill is posted and not disputed
+	if !isIssuedBill(bill.Status) || bill.Status == BillStatusDisputed {
+		return nil
+	}
+	// Only the template's latest bill moves it on, so a bill is never followed twice
+	if template.CurrentBillID == nil || *template.CurrentBillID != bill.ID {
 		return nil
 	}
 
@@ -469,27 +531,21 @@ func (s *RecurringBillService) CheckAndGenerateNextBill(ctx context.Context, bil
 		return err
 	}
 
-	// Get all payments for this bill
-	payments, err := s.payments.ListByBillID(ctx, billID)
+	// Get the amount covered by each user, credit included
+	coverage, err := s.creditService.GetBillCoverage(ctx, billID)
 	if err != nil {
 		return err
 	}
+	paymentMap := coverage.Paid
 
-	// Build a map of total amount paid by each user
-	paymentMap := make(map[string]float64)
-	for _, payment := range payments {
-		paidFloat := utils.DecimalStringToFloat(payment.AmountPLN)
-		paymentMap[payment.PayerUserID] += paidFloat
-	}
-
-	// Check if all users with allocations have paid their full amount
+	// Check if all users with allocations have paid their full amount.
+	// Amounts are exact, so no rounding tolerance is needed.
 	allPaid := true
 	for _, alloc := range storedAllocations {
-		allocFloat := utils.DecimalStringToFloat(alloc.AllocatedPLN)
+		allocated := utils.MoneyFromString(alloc.AllocatedPLN)
 
 		if alloc.SubjectType == "user" {
-			paidFloat := paymentMap[alloc.SubjectID]
-			if paidFloat < allocFloat-0.01 { // Allow 1 cent tolerance for rounding
+			if paymentMap[alloc.SubjectID] < allocated {
 				allPaid = false
 				break
 			}
@@ -501,12 +557,12 @@ func (s *RecurringBillService) CheckAndGenerateNextBill(ctx context.Context, bil
 			}
 
 			// Calculate total paid by all group members
-			totalPaidFloat := 0.0
+			var totalPaid utils.Money
 			for _, user := range groupUsers {
-				totalPaidFloat += paymentMap[user.ID]
+				totalPaid += paymentMap[user.ID]
 			}
 
-			if totalPaidFloat < allocFloat-0.01 { // Allow 1 cent tolerance for rounding
+			if totalPaid < allocated {
 				allPaid = false
 				break
 			}
@@ -521,6 +577,11 @@ func (s *RecurringBillService) CheckAndGenerateNextBill(ctx context.Context, bil
 	return nil
 }
 
+// OnBillTransition generates the next bill of a template once a transition leaves its current bill paid
+func (s *RecurringBillService) OnBillTransition(ctx context.Context, event BillTransitionEvent) error {
+	return s.CheckAndGenerateNextBill(ctx, event.Bill.ID)
+}
+
 // validateAllocations validates that allocations are properly configured
 func (s *RecurringBillService) validateAllocations(allocations []models.RecurringBillAllocation) error {
 	if len(allocations) == 0 {
@@ -558,6 +619,9 @@ func (s *RecurringBillService) validateAllocations(allocations []models.Recurrin
 			if alloc.FixedAmount == nil {
 				return fmt.Errorf("allocation %d: fixed amount is required for fixed type", i+1)
 			}
+			if _, err := utils.ParseMoney(*alloc.FixedAmount); err != nil {
+				return fmt.Errorf("allocation %d: invalid fixed amount %q", i+1, *alloc.FixedAmount)
+			}
 		default:
 			return fmt.Errorf("allocation %d: invalid allocation type '%s'", i+1, alloc.AllocationType)
 		}
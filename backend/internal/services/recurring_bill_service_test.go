@@ -246,6 +246,61 @@ func TestGenerateBillFromTemplateRequiresAllocations(t *testing.T) {
 	}
 }
 
+// TestCalculateTemplateAllocationAmounts tests that proportional template shares sum to the bill amount exactly
+func TestCalculateTemplateAllocationAmounts(t *testing.T) {
+	service := &RecurringBillService{}
+
+	tests := []struct {
+		name        string
+		amount      string
+		allocations []models.RecurringBillAllocation
+		want        []string
+	}{
+		{
+			name:   "Thirds of 100.00 keep the leftover grosz",
+			amount: "100.00",
+			allocations: []models.RecurringBillAllocation{
+				{SubjectType: "user", SubjectID: "user-1", AllocationType: "fraction", FractionNum: intPtr(1), FractionDenom: intPtr(3)},
+				{SubjectType: "user", SubjectID: "user-2", AllocationType: "fraction", FractionNum: intPtr(1), FractionDenom: intPtr(3)},
+				{SubjectType: "user", SubjectID: "user-3", AllocationType: "fraction", FractionNum: intPtr(1), FractionDenom: intPtr(3)},
+			},
+			want: []string{"33.34", "33.33", "33.33"},
+		},
+		{
+			name:   "Percentages of an odd amount",
+			amount: "99.99",
+			allocations: []models.RecurringBillAllocation{
+				{SubjectType: "group", SubjectID: "group-1", AllocationType: "percentage", Percentage: floatPtr(50)},
+				{SubjectType: "user", SubjectID: "user-1", AllocationType: "percentage", Percentage: floatPtr(50)},
+			},
+			want: []string{"50.00", "49.99"},
+		},
+		{
+			name:   "Fixed amounts are kept as entered",
+			amount: "3780.51",
+			allocations: []models.RecurringBillAllocation{
+				{SubjectType: "group", SubjectID: "group-1", AllocationType: "fixed", FixedAmount: stringPtr("2380.51")},
+				{SubjectType: "user", SubjectID: "user-1", AllocationType: "fixed", FixedAmount: stringPtr("1400")},
+			},
+			want: []string{"2380.51", "1400.00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := &models.RecurringBillTemplate{Amount: tt.amount, Allocations: tt.allocations}
+			amounts, err := service.calculateTemplateAllocationAmounts(template)
+			assert.NoError(t, err)
+
+			got := make([]string, len(amounts))
+			for i, amount := range amounts {
+				got[i] = amount.String()
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func intPtr(i int) *int {
 	return &i
 }
@@ -21,6 +21,7 @@ type ReminderService struct {
 	chores              repository.ChoreRepository
 	supplyItems         repository.SupplyItemRepository
 	notificationService *NotificationService
+	currencyService     *CurrencyService
 }
 
 func NewReminderService(
@@ -33,6 +34,7 @@ func NewReminderService(
 	chores repository.ChoreRepository,
 	supplyItems repository.SupplyItemRepository,
 	notificationService *NotificationService,
+	currencyService *CurrencyService,
 ) *ReminderService {
 	return &ReminderService{
 		sentReminders:       sentReminders,
@@ -44,6 +46,7 @@ func NewReminderService(
 		chores:              chores,
 		supplyItems:         supplyItems,
 		notificationService: notificationService,
+		currencyService:     currencyService,
 	}
 }
 
@@ -72,7 +75,12 @@ func (s *ReminderService) SendDebtReminder(ctx context.Context, targetUserID, se
 		return fmt.Errorf("failed to calculate debt: %w", err)
 	}
 
-	if debt <= 0 {
+	baseCurrency, err := s.currencyService.BaseCurrency(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !debt.IsPositive() {
 		return errors.New("user has no debt to you")
 	}
 
@@ -91,7 +99,7 @@ func (s *ReminderService) SendDebtReminder(ctx context.Context, targetUserID, se
 			UserID:     &targetUserID,
 			TemplateID: "debt_reminder",
 			Title:      "Przypomnienie o zadłużeniu",
-			Body:       fmt.Sprintf("%s przypomina o spłacie %.2f zł", sender.Name, debt),
+			Body:       fmt.Sprintf("%s przypomina o spłacie %s", sender.Name, FormatAmount(debt, baseCurrency)),
 		})
 	}
 
@@ -250,14 +258,14 @@ func (s *ReminderService) SendLowSuppliesReminder(ctx context.Context, senderUse
 	return notifiedCount, nil
 }
 
-// calculateDebt calculates how much borrowerID owes to lenderID
-func (s *ReminderService) calculateDebt(ctx context.Context, borrowerID, lenderID string) (float64, error) {
+// calculateDebt calculates how much borrowerID owes to lenderID, in the base currency
+func (s *ReminderService) calculateDebt(ctx context.Context, borrowerID, lenderID string) (utils.Money, error) {
 	loans, err := s.loans.ListByBorrowerID(ctx, borrowerID)
 	if err != nil {
 		return 0, err
 	}
 
-	totalDebt := 0.0
+	var totalDebt utils.Money
 	for _, loan := range loans {
 		if loan.LenderID != lenderID {
 			continue
@@ -266,16 +274,22 @@ func (s *ReminderService) calculateDebt(ctx context.Context, borrowerID, lenderI
 			continue
 		}
 
-		loanAmount := utils.DecimalStringToFloat(loan.AmountPLN)
+		loanAmount := utils.MoneyFromString(loan.AmountPLN)
 		sumStr, err := s.loanPayments.SumByLoanID(ctx, loan.ID)
 		if err != nil {
 			continue
 		}
-		totalPaid := utils.DecimalStringToFloat(sumStr)
-		remaining := loanAmount - totalPaid
-		if remaining > 0 {
-			totalDebt += remaining
+		remaining := loanAmount.Sub(utils.MoneyFromString(sumStr))
+		if !remaining.IsPositive() {
+			continue
+		}
+
+		// Loans may be in different currencies, so the total is expressed in the base currency
+		converted, err := s.currencyService.Convert(ctx, remaining, loan.Currency, time.Now())
+		if err != nil {
+			return 0, err
 		}
+		totalDebt = totalDebt.Add(converted)
 	}
 
 	return totalDebt, nil
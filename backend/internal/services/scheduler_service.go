@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
 	"github.com/sainaif/holy-home/internal/models"
@@ -12,15 +13,20 @@ import (
 )
 
 type SchedulerService struct {
-	sentReminders       repository.SentReminderRepository
-	users               repository.UserRepository
-	bills               repository.BillRepository
-	loans               repository.LoanRepository
-	loanPayments        repository.LoanPaymentRepository
-	choreAssignments    repository.ChoreAssignmentRepository
-	chores              repository.ChoreRepository
-	supplyItems         repository.SupplyItemRepository
-	notificationService *NotificationService
+	sentReminders        repository.SentReminderRepository
+	users                repository.UserRepository
+	bills                repository.BillRepository
+	loans                repository.LoanRepository
+	loanPayments         repository.LoanPaymentRepository
+	choreAssignments     repository.ChoreAssignmentRepository
+	chores               repository.ChoreRepository
+	supplyItems          repository.SupplyItemRepository
+	consumptions         repository.ConsumptionRepository
+	consumptionAnomalies repository.ConsumptionAnomalyRepository
+	meters               repository.MeterRepository
+	notificationService  *NotificationService
+	lateFeeService       *LateFeeService
+	budgetService        *BudgetService
 }
 
 func NewSchedulerService(
@@ -32,18 +38,28 @@ func NewSchedulerService(
 	choreAssignments repository.ChoreAssignmentRepository,
 	chores repository.ChoreRepository,
 	supplyItems repository.SupplyItemRepository,
+	consumptions repository.ConsumptionRepository,
+	consumptionAnomalies repository.ConsumptionAnomalyRepository,
+	meters repository.MeterRepository,
 	notificationService *NotificationService,
+	lateFeeService *LateFeeService,
+	budgetService *BudgetService,
 ) *SchedulerService {
 	return &SchedulerService{
-		sentReminders:       sentReminders,
-		users:               users,
-		bills:               bills,
-		loans:               loans,
-		loanPayments:        loanPayments,
-		choreAssignments:    choreAssignments,
-		chores:              chores,
-		supplyItems:         supplyItems,
-		notificationService: notificationService,
+		sentReminders:        sentReminders,
+		users:                users,
+		bills:                bills,
+		loans:                loans,
+		loanPayments:         loanPayments,
+		choreAssignments:     choreAssignments,
+		chores:               chores,
+		supplyItems:          supplyItems,
+		consumptions:         consumptions,
+		consumptionAnomalies: consumptionAnomalies,
+		meters:               meters,
+		notificationService:  notificationService,
+		lateFeeService:       lateFeeService,
+		budgetService:        budgetService,
 	}
 }
 
@@ -67,9 +83,39 @@ func (s *SchedulerService) RunAllChecks(ctx context.Context) {
 		log.Printf("Error checking low supply reminders: %v", err)
 	}
 
+	if err := s.CheckConsumptionAnomalies(ctx); err != nil {
+		log.Printf("Error checking consumption anomalies: %v", err)
+	}
+
+	if err := s.CheckLateFees(ctx); err != nil {
+		log.Printf("Error checking late fees: %v", err)
+	}
+
+	if err := s.CheckBudgetAlerts(ctx); err != nil {
+		log.Printf("Error checking budget alerts: %v", err)
+	}
+
 	log.Println("Scheduled reminder checks completed")
 }
 
+// CheckLateFees accrues the late fees of overdue bills and loans
+func (s *SchedulerService) CheckLateFees(ctx context.Context) error {
+	if s.lateFeeService == nil {
+		return nil
+	}
+	_, err := s.lateFeeService.AccrueFees(ctx, time.Now())
+	return err
+}
+
+// CheckBudgetAlerts alerts the household about budgets that reached a threshold
+func (s *SchedulerService) CheckBudgetAlerts(ctx context.Context) error {
+	if s.budgetService == nil {
+		return nil
+	}
+	_, err := s.budgetService.CheckAlerts(ctx, time.Now())
+	return err
+}
+
 // CheckChoreReminders sends reminders for chores due soon
 func (s *SchedulerService) CheckChoreReminders(ctx context.Context) error {
 	// Get all pending chore assignments
@@ -149,8 +195,8 @@ func (s *SchedulerService) CheckChoreReminders(ctx context.Context) error {
 
 // CheckBillReminders sends reminders for bills with upcoming payment deadlines
 func (s *SchedulerService) CheckBillReminders(ctx context.Context) error {
-	// Get all posted bills
-	bills, err := s.bills.ListByStatus(ctx, "posted")
+	// Get all bills that are due, disputed ones wait until the dispute is resolved
+	bills, err := listBillsByStatus(ctx, s.bills, BillStatusPosted, BillStatusAwaitingPayment)
 	if err != nil {
 		return fmt.Errorf("failed to list posted bills: %w", err)
 	}
@@ -281,16 +327,14 @@ func (s *SchedulerService) CheckLoanReminders(ctx context.Context) error {
 
 		// Calculate remaining amount
 		totalPaidStr, _ := s.loanPayments.SumByLoanID(ctx, loan.ID)
-		totalPaid := utils.DecimalStringToFloat(totalPaidStr)
-		loanAmount := utils.DecimalStringToFloat(loan.AmountPLN)
-		remaining := loanAmount - totalPaid
+		remaining := utils.MoneyFromString(loan.AmountPLN).Sub(utils.MoneyFromString(totalPaidStr))
 
 		// Create notification
 		if s.notificationService != nil {
 			daysLeft := int(time.Until(*loan.DueDate).Hours() / 24)
-			body := fmt.Sprintf("Pożyczka od %s (%.2f zł) - termin za %d dni", lenderName, remaining, daysLeft)
+			body := fmt.Sprintf("Pożyczka od %s (%s) - termin za %d dni", lenderName, FormatAmount(remaining, loan.Currency), daysLeft)
 			if daysLeft <= 0 {
-				body = fmt.Sprintf("Pożyczka od %s (%.2f zł) - termin minął!", lenderName, remaining)
+				body = fmt.Sprintf("Pożyczka od %s (%s) - termin minął!", lenderName, FormatAmount(remaining, loan.Currency))
 			}
 
 			_ = s.notificationService.CreateNotification(ctx, &models.Notification{
@@ -398,6 +442,303 @@ func (s *SchedulerService) CheckLowSupplyReminders(ctx context.Context) error {
 	return nil
 }
 
+// Kinds of consumption anomalies
+const (
+	AnomalySpike            = "spike"             // usage well above the subject's usual daily rate
+	AnomalyImplausibleDelta = "implausible_delta" // usage so far above the usual rate that it is more likely a typo
+	AnomalyNegativeDelta    = "negative_delta"    // meter value lower than an earlier reading of the same meter
+	AnomalyStaleMeter       = "stale_meter"       // meter nobody has read for a long time
+)
+
+const (
+	anomalySpikeFactor       = 3.0  // daily usage this many times the usual rate is a spike
+	anomalyImplausibleFactor = 10.0 // and this many times the usual rate is implausible
+	anomalyMinHistoryDays    = 14   // the usual rate needs readings spanning at least this many days
+	staleMeterDays           = 45   // meters without a reading for this long are stale
+)
+
+// anomalyFlag is an anomaly together with the notification text sent about it
+type anomalyFlag struct {
+	anomaly models.ConsumptionAnomaly
+	title   string
+	body    string
+}
+
+// CheckConsumptionAnomalies checks every reading recorded since the last run against the readings
+// before it, then flags active meters that have not been read for a while. The subject's residents
+// are notified about every flag.
+func (s *SchedulerService) CheckConsumptionAnomalies(ctx context.Context) error {
+	readings, err := s.consumptions.ListUnchecked(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list unchecked consumptions: %w", err)
+	}
+
+	now := time.Now()
+	anomaliesFound := 0
+
+	pending := make(map[string]bool, len(readings))
+	for _, reading := range readings {
+		pending[reading.ID] = true
+	}
+
+	for _, reading := range readings {
+		if reading.Source != "invalid" && reading.Source != "estimated" {
+			series, err := s.readingSeries(ctx, reading)
+			if err != nil {
+				return err
+			}
+			for _, flag := range detectReadingAnomalies(reading, series, pending) {
+				if err := s.raiseAnomaly(ctx, flag, now); err != nil {
+					return err
+				}
+				anomaliesFound++
+			}
+		}
+
+		if err := s.consumptions.MarkChecked(ctx, reading.ID, now); err != nil {
+			return fmt.Errorf("failed to mark consumption checked: %w", err)
+		}
+	}
+
+	meters, err := s.meters.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list meters: %w", err)
+	}
+	for _, meter := range meters {
+		flag, err := s.staleMeterAnomaly(ctx, meter, now)
+		if err != nil {
+			return err
+		}
+		if flag == nil {
+			continue
+		}
+		if err := s.raiseAnomaly(ctx, *flag, now); err != nil {
+			return err
+		}
+		anomaliesFound++
+	}
+
+	if anomaliesFound > 0 {
+		log.Printf("Flagged %d consumption anomalies", anomaliesFound)
+	}
+	return nil
+}
+
+// readingSeries returns the other valid readings of the same meter, or for readings without a
+// registered meter the subject's other readings without one, oldest first
+func (s *SchedulerService) readingSeries(ctx context.Context, reading models.Consumption) ([]models.Consumption, error) {
+	var readings []models.Consumption
+	var err error
+	if reading.MeterID != nil {
+		readings, err = s.consumptions.ListByMeterID(ctx, *reading.MeterID)
+	} else {
+		readings, err = s.consumptions.ListBySubject(ctx, reading.SubjectType, reading.SubjectID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch consumptions: %w", err)
+	}
+
+	series := make([]models.Consumption, 0, len(readings))
+	for _, c := range readings {
+		if c.ID == reading.ID || c.Source == "invalid" || c.Source == "estimated" || (reading.MeterID == nil && c.MeterID != nil) {
+			continue
+		}
+		series = append(series, c)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].RecordedAt.Before(series[j].RecordedAt) })
+	return series, nil
+}
+
+// detectReadingAnomalies compares a reading with the rest of its series. A meter value that goes
+// backwards is flagged on its own; otherwise the usage per day since the previous reading is compared
+// with the usual usage per day over the earlier readings.
+// A reading higher than a later one is only flagged when the later one was checked in an earlier run,
+// i.e. the reading was backdated; otherwise the later reading is the one flagged.
+func detectReadingAnomalies(reading models.Consumption, series []models.Consumption, pending map[string]bool) []anomalyFlag {
+	var previous, next *models.Consumption
+	for i := range series {
+		c := &series[i]
+		if c.RecordedAt.Before(reading.RecordedAt) {
+			previous = c
+		} else if next == nil && c.RecordedAt.After(reading.RecordedAt) {
+			next = c
+		}
+	}
+
+	flag := func(kind, message, title, body string) anomalyFlag {
+		consumptionID := reading.ID
+		return anomalyFlag{
+			anomaly: models.ConsumptionAnomaly{
+				ConsumptionID: &consumptionID,
+				MeterID:       reading.MeterID,
+				SubjectType:   reading.SubjectType,
+				SubjectID:     reading.SubjectID,
+				Kind:          kind,
+				Message:       message,
+			},
+			title: title,
+			body:  body,
+		}
+	}
+	readDate := reading.RecordedAt.Format("2006-01-02")
+
+	if reading.MeterValue != nil {
+		value := utils.DecimalStringToFloat(*reading.MeterValue)
+		if previous != nil && previous.MeterValue != nil && value < utils.DecimalStringToFloat(*previous.MeterValue) {
+			return []anomalyFlag{flag(AnomalyNegativeDelta,
+				fmt.Sprintf("Meter value %s is lower than %s read on %s", *reading.MeterValue, *previous.MeterValue, previous.RecordedAt.Format("2006-01-02")),
+				"Nieprawidłowy odczyt licznika",
+				fmt.Sprintf("Odczyt z %s jest niższy niż poprzedni - sprawdź stan licznika", readDate))}
+		}
+		if next != nil && !pending[next.ID] && next.MeterValue != nil && value > utils.DecimalStringToFloat(*next.MeterValue) {
+			return []anomalyFlag{flag(AnomalyNegativeDelta,
+				fmt.Sprintf("Meter value %s is higher than %s read later on %s", *reading.MeterValue, *next.MeterValue, next.RecordedAt.Format("2006-01-02")),
+				"Nieprawidłowy odczyt licznika",
+				fmt.Sprintf("Odczyt z %s jest wyższy niż późniejszy - sprawdź stan licznika", readDate))}
+		}
+	}
+
+	if previous == nil {
+		return nil
+	}
+
+	// The usual rate runs from the first reading of the series to the previous one
+	earliest := &series[0]
+	historyDays := previous.RecordedAt.Sub(earliest.RecordedAt).Hours() / 24
+	if historyDays < anomalyMinHistoryDays {
+		return nil
+	}
+	historyUnits := 0.0
+	for _, c := range series {
+		if c.RecordedAt.After(earliest.RecordedAt) && !c.RecordedAt.After(previous.RecordedAt) {
+			historyUnits += utils.DecimalStringToFloat(c.Units)
+		}
+	}
+	if historyUnits <= 0 {
+		return nil
+	}
+	expected := historyUnits / historyDays
+
+	days := reading.RecordedAt.Sub(previous.RecordedAt).Hours() / 24
+	if days < 1 {
+		days = 1
+	}
+	daily := utils.DecimalStringToFloat(reading.Units) / days
+	ratio := daily / expected
+
+	var result anomalyFlag
+	switch {
+	case ratio >= anomalyImplausibleFactor:
+		result = flag(AnomalyImplausibleDelta,
+			fmt.Sprintf("%.2f units a day since %s is %.0f times the usual %.2f", daily, previous.RecordedAt.Format("2006-01-02"), ratio, expected),
+			"Nieprawidłowy odczyt licznika",
+			fmt.Sprintf("Odczyt z %s oznacza zużycie %.0f razy wyższe niż zwykle - sprawdź, czy nie ma pomyłki", readDate, ratio))
+	case ratio >= anomalySpikeFactor:
+		result = flag(AnomalySpike,
+			fmt.Sprintf("%.2f units a day since %s is %.1f times the usual %.2f", daily, previous.RecordedAt.Format("2006-01-02"), ratio, expected),
+			"Wysokie zużycie",
+			fmt.Sprintf("Odczyt z %s oznacza zużycie %.1f razy wyższe niż zwykle", readDate, ratio))
+	default:
+		return nil
+	}
+
+	dailyUnits := utils.FloatToDecimalString(daily)
+	expectedUnits := utils.FloatToDecimalString(expected)
+	result.anomaly.DailyUnits = &dailyUnits
+	result.anomaly.ExpectedDailyUnits = &expectedUnits
+	return []anomalyFlag{result}
+}
+
+// staleMeterAnomaly flags an active meter without a reading for staleMeterDays, once per gap in readings
+func (s *SchedulerService) staleMeterAnomaly(ctx context.Context, meter models.Meter, now time.Time) (*anomalyFlag, error) {
+	if meter.ReplacedAt != nil {
+		return nil, nil
+	}
+
+	readings, err := s.consumptions.ListByMeterID(ctx, meter.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch consumptions: %w", err)
+	}
+	lastReading := meter.InstalledAt
+	for _, c := range readings {
+		if c.Source != "invalid" && c.Source != "estimated" && c.RecordedAt.After(lastReading) {
+			lastReading = c.RecordedAt
+		}
+	}
+
+	days := int(now.Sub(lastReading).Hours() / 24)
+	if days < staleMeterDays {
+		return nil, nil
+	}
+
+	latest, err := s.consumptionAnomalies.GetLatestByMeterID(ctx, meter.ID, AnomalyStaleMeter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch anomalies: %w", err)
+	}
+	if latest != nil && latest.DetectedAt.After(lastReading) {
+		return nil, nil
+	}
+
+	name := meter.SerialNumber
+	if name == "" {
+		name = meter.Type
+	}
+	meterID := meter.ID
+	return &anomalyFlag{
+		anomaly: models.ConsumptionAnomaly{
+			MeterID:     &meterID,
+			SubjectType: meter.SubjectType,
+			SubjectID:   meter.SubjectID,
+			Kind:        AnomalyStaleMeter,
+			Message:     fmt.Sprintf("No reading since %s (%d days)", lastReading.Format("2006-01-02"), days),
+		},
+		title: "Brak odczytu licznika",
+		body:  fmt.Sprintf("Licznik %s nie ma odczytu od %d dni", name, days),
+	}, nil
+}
+
+// raiseAnomaly stores an anomaly and notifies the residents of its subject
+func (s *SchedulerService) raiseAnomaly(ctx context.Context, flag anomalyFlag, now time.Time) error {
+	flag.anomaly.DetectedAt = now
+	if err := s.consumptionAnomalies.Create(ctx, &flag.anomaly); err != nil {
+		return fmt.Errorf("failed to save anomaly: %w", err)
+	}
+
+	if s.notificationService == nil {
+		return nil
+	}
+
+	var recipients []models.User
+	if flag.anomaly.SubjectType == "group" {
+		members, err := s.users.ListByGroupID(ctx, flag.anomaly.SubjectID)
+		if err != nil {
+			return fmt.Errorf("failed to list group members: %w", err)
+		}
+		recipients = members
+	} else {
+		user, err := s.users.GetByID(ctx, flag.anomaly.SubjectID)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+		if user != nil {
+			recipients = append(recipients, *user)
+		}
+	}
+
+	for _, user := range recipients {
+		if !user.IsActive {
+			continue
+		}
+		_ = s.notificationService.CreateNotification(ctx, &models.Notification{
+			UserID:     &user.ID,
+			TemplateID: "consumption_anomaly",
+			Title:      flag.title,
+			Body:       flag.body,
+		})
+	}
+	return nil
+}
+
 // getBillTypeName returns the display name for a bill type
 func getBillTypeName(billType string, customType *string) string {
 	switch billType {
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckConsumptionAnomalies(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	scheduler := NewSchedulerService(repos.SentReminders, repos.Users, repos.Bills, repos.Loans, repos.LoanPayments,
+		repos.ChoreAssignments, repos.Chores, repos.SupplyItems, repos.Consumptions, repos.ConsumptionAnomalies, repos.Meters,
+		newTestNotificationService(repos), nil, nil)
+	consumptionService := NewConsumptionService(repos.Consumptions, repos.ConsumptionAnomalies, newTestAttachmentService(t, repos), repos.Bills, repos.Users,
+		repos.Meters, repos.BillTariffZones, repos.TxManager)
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+	carol := createTestUser(t, repos, "Carol")
+	dan := createTestUser(t, repos, "Dan")
+	base := time.Now().AddDate(0, 0, -100).Truncate(time.Second)
+	day := func(n int) time.Time { return base.AddDate(0, 0, n) }
+
+	bill := &models.Bill{Type: "electricity", PeriodStart: day(0), PeriodEnd: day(100), TotalAmountPLN: "100.00", Currency: "PLN", Status: "draft"}
+	require.NoError(t, repos.Bills.Create(ctx, bill))
+
+	newMeter := func(user *models.User) *models.Meter {
+		meter := &models.Meter{Type: "electricity", Unit: "kWh", SubjectType: "user", SubjectID: user.ID, TariffZone: DefaultTariffZone,
+			InstalledAt: day(-10), InitialValue: "0"}
+		require.NoError(t, repos.Meters.Create(ctx, meter))
+		return meter
+	}
+	read := func(user *models.User, meter *models.Meter, n int, value, units float64) *models.Consumption {
+		meterValue := utils.FloatToDecimalString(value)
+		c := &models.Consumption{BillID: bill.ID, SubjectType: "user", SubjectID: user.ID, Units: utils.FloatToDecimalString(units),
+			MeterValue: &meterValue, RecordedAt: day(n), Source: "user"}
+		if meter != nil {
+			c.MeterID = &meter.ID
+		}
+		require.NoError(t, repos.Consumptions.Create(ctx, c))
+		return c
+	}
+
+	// Alice uses about 3 kWh a day, then 150 kWh in a week
+	aliceMeter := newMeter(alice)
+	read(alice, aliceMeter, 0, 100, 100)
+	read(alice, aliceMeter, 30, 190, 90)
+	read(alice, aliceMeter, 60, 290, 100)
+	spike := read(alice, aliceMeter, 67, 440, 150)
+
+	// Bob uses 2 kWh a day, then an extra digit slips into the meter value
+	bobMeter := newMeter(bob)
+	read(bob, bobMeter, 0, 50, 50)
+	read(bob, bobMeter, 30, 110, 60)
+	read(bob, bobMeter, 60, 170, 60)
+	typo := read(bob, bobMeter, 90, 1900, 1730)
+
+	// Carol's meter goes backwards; only the newer reading is flagged
+	read(carol, nil, 10, 500, 500)
+	earlier := read(carol, nil, 40, 520, 20)
+	backwards := read(carol, nil, 50, 510, 0)
+
+	// Dan's meter was installed 100 days ago and never read
+	danMeter := &models.Meter{Type: "gas", Unit: "m3", SubjectType: "user", SubjectID: dan.ID, TariffZone: DefaultTariffZone,
+		SerialNumber: "GAS-7", InstalledAt: day(0), InitialValue: "0"}
+	require.NoError(t, repos.Meters.Create(ctx, danMeter))
+
+	require.NoError(t, scheduler.CheckConsumptionAnomalies(ctx))
+
+	anomalies, err := repos.ConsumptionAnomalies.List(ctx)
+	require.NoError(t, err)
+	kinds := make(map[string]string)
+	for _, anomaly := range anomalies {
+		if anomaly.ConsumptionID != nil {
+			kinds[*anomaly.ConsumptionID] = anomaly.Kind
+		} else {
+			kinds[*anomaly.MeterID] = anomaly.Kind
+		}
+	}
+	assert.Equal(t, map[string]string{
+		spike.ID:     AnomalySpike,
+		typo.ID:      AnomalyImplausibleDelta,
+		backwards.ID: AnomalyNegativeDelta,
+		danMeter.ID:  AnomalyStaleMeter,
+	}, kinds)
+	assert.NotContains(t, kinds, earlier.ID)
+
+	// Flags show up on the readings list
+	readings, err := consumptionService.GetConsumptions(ctx, &bill.ID)
+	require.NoError(t, err)
+	for _, reading := range readings {
+		if reading.ID == spike.ID {
+			require.Len(t, reading.Anomalies, 1)
+			assert.Equal(t, "21.43", *reading.Anomalies[0].DailyUnits)
+			assert.Equal(t, "3.17", *reading.Anomalies[0].ExpectedDailyUnits)
+		}
+	}
+
+	notifications, err := repos.Notifications.ListByUserID(ctx, dan.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "consumption_anomaly", notifications[0].TemplateID)
+	assert.Contains(t, notifications[0].Body, "GAS-7")
+
+	// Checked readings and already flagged stale meters are not flagged again
+	require.NoError(t, scheduler.CheckConsumptionAnomalies(ctx))
+	anomalies, err = repos.ConsumptionAnomalies.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, anomalies, 4)
+
+	// A backdated reading above a later, already checked one is flagged itself
+	backdated := read(bob, bobMeter, 45, 200, 90)
+	require.NoError(t, scheduler.CheckConsumptionAnomalies(ctx))
+	flags, err := repos.ConsumptionAnomalies.ListByConsumptionID(ctx, backdated.ID)
+	require.NoError(t, err)
+	require.Len(t, flags, 1)
+	assert.Equal(t, AnomalyNegativeDelta, flags[0].Kind)
+}
@@ -0,0 +1,627 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+)
+
+// SettleUpPaymentMethod marks bill payments recorded by a settle-up.
+// A flatmate who covered someone else's share gets a negative payment and the
+// one who owed it a positive payment, so the bill shows who really paid what.
+const SettleUpPaymentMethod = "settle_up"
+
+// ErrNothingToSettle is returned when applying a settle-up with no open debts
+var ErrNothingToSettle = errors.New("nothing to settle")
+
+type SettleUpService struct {
+	loans           repository.LoanRepository
+	loanPayments    repository.LoanPaymentRepository
+	transfers       repository.SettlementTransferRepository
+	bills           repository.BillRepository
+	allocations     repository.AllocationRepository
+	payments        repository.PaymentRepository
+	users           repository.UserRepository
+	groups          repository.GroupRepository
+	txManager       repository.TxManager
+	currencyService *CurrencyService
+	creditService   *CreditService
+}
+
+func NewSettleUpService(
+	loans repository.LoanRepository,
+	loanPayments repository.LoanPaymentRepository,
+	transfers repository.SettlementTransferRepository,
+	bills repository.BillRepository,
+	allocations repository.AllocationRepository,
+	payments repository.PaymentRepository,
+	users repository.UserRepository,
+	groups repository.GroupRepository,
+	txManager repository.TxManager,
+	currencyService *CurrencyService,
+	creditService *CreditService,
+) *SettleUpService {
+	return &SettleUpService{
+		loans:           loans,
+		loanPayments:    loanPayments,
+		transfers:       transfers,
+		bills:           bills,
+		allocations:     allocations,
+		payments:        payments,
+		users:           users,
+		groups:          groups,
+		txManager:       txManager,
+		currencyService: currencyService,
+		creditService:   creditService,
+	}
+}
+
+// SettleUpParty is a user, or a whole group whose members settle as a unit
+type SettleUpParty struct {
+	Type    string   `json:"type"` // "user" or "group"
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Members []string `json:"members,omitempty"` // member names for groups
+}
+
+// SettleUpBalance is the net position of a party; positive means it should receive money
+type SettleUpBalance struct {
+	Party SettleUpParty `json:"party"`
+	Net   utils.Money   `json:"net"`
+}
+
+// SettleUpTransfer is a single payment that should be made to settle up
+type SettleUpTransfer struct {
+	ID     string        `json:"id,omitempty"` // recorded transfer, once applied
+	From   SettleUpParty `json:"from"`
+	To     SettleUpParty `json:"to"`
+	Amount utils.Money   `json:"amount"`
+}
+
+// SettleUpPlan is the result of planning (or applying) a settle-up.
+// Amounts are in the household base currency.
+type SettleUpPlan struct {
+	Currency      string             `json:"currency"`
+	Balances      []SettleUpBalance  `json:"balances"`
+	Transfers     []SettleUpTransfer `json:"transfers"`
+	PairwiseDebts int                `json:"pairwiseDebts"` // transfers needed without simplification
+	LoansSettled  int                `json:"loansSettled"`
+	BillsSettled  int                `json:"billsSettled"`
+	Applied       bool               `json:"applied"`
+	SettlementID  string             `json:"settlementId,omitempty"` // stored on the recorded transfers, loan payments and bill payments
+}
+
+// settleUpLoan is an open loan between two different parties
+type settleUpLoan struct {
+	loan      models.Loan
+	remaining utils.Money // in the loan currency
+}
+
+// settleUpBillShare is part of a bill one party paid on behalf of another
+type settleUpBillShare struct {
+	bill       models.Bill
+	debtorID   string // user recorded as paying the share
+	creditorID string // user recorded as being reimbursed
+	amount     utils.Money
+}
+
+// settleUpLedger holds every open debt between parties, netted per party
+type settleUpLedger struct {
+	currency   string
+	parties    map[string]SettleUpParty
+	net        map[string]utils.Money
+	pairs      map[[2]string]bool
+	loans      []settleUpLoan
+	billShares []settleUpBillShare
+	bills      map[string]bool
+}
+
+// settleUpDirectory maps users to the party they settle as
+type settleUpDirectory struct {
+	parties     map[string]SettleUpParty
+	userParty   map[string]string   // userID -> party key
+	partyUsers  map[string][]string // party key -> user IDs
+	userByParty map[string]string   // party key -> default user recorded for the party
+}
+
+func partyKey(partyType, id string) string {
+	return partyType + ":" + id
+}
+
+// PreviewSettleUp computes the transfers needed to settle all open debts without recording anything
+func (s *SettleUpService) PreviewSettleUp(ctx context.Context) (*SettleUpPlan, error) {
+	ledger, err := s.buildLedger(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ledger.plan(), nil
+}
+
+// ApplySettleUp records the settle-up: the planned transfers are stored, every open loan between
+// different parties is repaid in full and bill shares covered by someone else are moved to the
+// person who owed them, all under one settlement ID. Everything is recorded in one transaction.
+func (s *SettleUpService) ApplySettleUp(ctx context.Context) (*SettleUpPlan, error) {
+	var plan *SettleUpPlan
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Rebuild inside the transaction so the recorded payments match the data they settle
+		ledger, err := s.buildLedger(ctx)
+		if err != nil {
+			return err
+		}
+		if len(ledger.loans) == 0 && len(ledger.billShares) == 0 {
+			return ErrNothingToSettle
+		}
+
+		plan = ledger.plan()
+		plan.SettlementID = uuid.New().String()
+		now := time.Now()
+
+		// The transfers are the money that actually moves; loans and bill shares are closed against them
+		for i, transfer := range plan.Transfers {
+			record := models.SettlementTransfer{
+				SettlementID: plan.SettlementID,
+				FromType:     transfer.From.Type,
+				FromID:       transfer.From.ID,
+				ToType:       transfer.To.Type,
+				ToID:         transfer.To.ID,
+				Amount:       transfer.Amount.String(),
+				Currency:     plan.Currency,
+				CreatedAt:    now,
+			}
+			if err := s.transfers.Create(ctx, &record); err != nil {
+				return fmt.Errorf("failed to record transfer: %w", err)
+			}
+			plan.Transfers[i].ID = record.ID
+		}
+
+		for _, debt := range ledger.loans {
+			payment := models.LoanPayment{
+				LoanID:       debt.loan.ID,
+				AmountPLN:    debt.remaining.String(),
+				PaidAt:       now,
+				Note:         getStringPtr("Rozliczenie zbiorcze"),
+				SettlementID: &plan.SettlementID,
+			}
+			if err := s.loanPayments.Create(ctx, &payment); err != nil {
+				return fmt.Errorf("failed to create loan payment: %w", err)
+			}
+
+			loan := debt.loan
+			loan.Status = "settled"
+			if err := s.loans.Update(ctx, &loan); err != nil {
+				return fmt.Errorf("failed to update loan status: %w", err)
+			}
+		}
+
+		method := SettleUpPaymentMethod
+		for _, share := range ledger.billShares {
+			entries := []struct {
+				userID string
+				amount utils.Money
+			}{
+				{share.debtorID, share.amount},
+				{share.creditorID, share.amount.Neg()},
+			}
+			for _, entry := range entries {
+				payment := models.Payment{
+					ID:          uuid.New().String(),
+					BillID:      share.bill.ID,
+					PayerUserID: entry.userID,
+					AmountPLN:   entry.amount.String(),
+					PaidAt:      now,
+					Method:      &method,
+					Reference:   &plan.SettlementID,
+				}
+				if err := s.payments.Create(ctx, &payment); err != nil {
+					return fmt.Errorf("failed to record bill payment: %w", err)
+				}
+			}
+		}
+
+		plan.Applied = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[SETTLE-UP] Applied %s: %d transfers, %d loans settled, %d bills", plan.SettlementID, len(plan.Transfers), plan.LoansSettled, plan.BillsSettled)
+	return plan, nil
+}
+
+// GetTransfers returns the transfers recorded by settle-ups, newest first, or those of one
+// settlement when settlementID is set
+func (s *SettleUpService) GetTransfers(ctx context.Context, settlementID string) ([]models.SettlementTransfer, error) {
+	var transfers []models.SettlementTransfer
+	var err error
+	if settlementID != "" {
+		transfers, err = s.transfers.ListBySettlementID(ctx, settlementID)
+	} else {
+		transfers, err = s.transfers.List(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transfers: %w", err)
+	}
+	return transfers, nil
+}
+
+// buildLedger collects open loans and bill shares covered by other flatmates, netted per party
+func (s *SettleUpService) buildLedger(ctx context.Context) (*settleUpLedger, error) {
+	currency, err := s.currencyService.BaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := s.buildDirectory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger := &settleUpLedger{
+		currency: currency,
+		parties:  dir.parties,
+		net:      make(map[string]utils.Money),
+		pairs:    make(map[[2]string]bool),
+		bills:    make(map[string]bool),
+	}
+	now := time.Now()
+
+	if err := s.addLoans(ctx, ledger, dir, now); err != nil {
+		return nil, err
+	}
+	if err := s.addBills(ctx, ledger, dir, now); err != nil {
+		return nil, err
+	}
+
+	return ledger, nil
+}
+
+func (s *SettleUpService) buildDirectory(ctx context.Context) (*settleUpDirectory, error) {
+	users, err := s.users.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users: %w", err)
+	}
+	groups, err := s.groups.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch groups: %w", err)
+	}
+
+	groupNames := make(map[string]string)
+	for _, g := range groups {
+		groupNames[g.ID] = g.Name
+	}
+
+	dir := &settleUpDirectory{
+		parties:     make(map[string]SettleUpParty),
+		userParty:   make(map[string]string),
+		partyUsers:  make(map[string][]string),
+		userByParty: make(map[string]string),
+	}
+
+	for _, u := range users {
+		key := partyKey("user", u.ID)
+		party := SettleUpParty{Type: "user", ID: u.ID, Name: u.Name}
+		if u.GroupID != nil {
+			key = partyKey("group", *u.GroupID)
+			party = dir.parties[key]
+			party.Type = "group"
+			party.ID = *u.GroupID
+			party.Name = groupNames[*u.GroupID]
+			party.Members = append(party.Members, u.Name)
+		}
+		dir.parties[key] = party
+		dir.userParty[u.ID] = key
+		dir.partyUsers[key] = append(dir.partyUsers[key], u.ID)
+		if _, ok := dir.userByParty[key]; !ok {
+			dir.userByParty[key] = u.ID
+		}
+	}
+
+	return dir, nil
+}
+
+// addLoans adds open loans between different parties; loans inside a group are left to group compensation
+func (s *SettleUpService) addLoans(ctx context.Context, ledger *settleUpLedger, dir *settleUpDirectory, now time.Time) error {
+	var loans []models.Loan
+	for _, status := range []string{"open", "partial"} {
+		byStatus, err := s.loans.ListByStatus(ctx, status)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+		loans = append(loans, byStatus...)
+	}
+	sort.Slice(loans, func(i, j int) bool {
+		return loans[i].CreatedAt.Before(loans[j].CreatedAt)
+	})
+
+	for _, loan := range loans {
+		from, to := dir.userParty[loan.BorrowerID], dir.userParty[loan.LenderID]
+		if from == "" || to == "" || from == to {
+			continue
+		}
+
+		paid, err := s.loanPayments.SumByLoanID(ctx, loan.ID)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+		remaining := utils.MoneyFromString(loan.AmountPLN).Sub(utils.MoneyFromString(paid))
+		if !remaining.IsPositive() {
+			continue
+		}
+
+		converted, err := s.currencyService.Convert(ctx, remaining, loan.Currency, now)
+		if err != nil {
+			return fmt.Errorf("failed to convert loan %s: %w", loan.ID, err)
+		}
+
+		ledger.addDebt(from, to, converted)
+		ledger.loans = append(ledger.loans, settleUpLoan{loan: loan, remaining: remaining})
+	}
+
+	return nil
+}
+
+// addBills adds bill shares one party paid for another. On each posted bill, parties that paid
+// more than their allocation are owed by parties that paid less; anything still unpaid beyond
+// that is owed to the provider, not to flatmates, and is left out.
+func (s *SettleUpService) addBills(ctx context.Context, ledger *settleUpLedger, dir *settleUpDirectory, now time.Time) error {
+	bills, err := listBillsByStatus(ctx, s.bills, IssuedBillStatuses...)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	sort.Slice(bills, func(i, j int) bool {
+		return bills[i].CreatedAt.Before(bills[j].CreatedAt)
+	})
+
+	for _, bill := range bills {
+		allocations, err := s.allocations.GetByBillID(ctx, bill.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get allocations: %w", err)
+		}
+		// Overpayments moved to credit no longer cover the bill, applied credit does
+		coverage, err := s.creditService.GetBillCoverage(ctx, bill.ID)
+		if err != nil {
+			return err
+		}
+
+		balance := make(map[string]utils.Money)
+		paidByUser := make(map[string]utils.Money)
+		for _, alloc := range allocations {
+			key := partyKey(alloc.SubjectType, alloc.SubjectID)
+			if alloc.SubjectType == "user" && dir.userParty[alloc.SubjectID] != "" {
+				key = dir.userParty[alloc.SubjectID]
+			}
+			balance[key] = balance[key].Sub(utils.MoneyFromString(alloc.AllocatedPLN))
+		}
+		for userID, amount := range coverage.Paid {
+			key := dir.userParty[userID]
+			if key == "" {
+				continue
+			}
+			balance[key] = balance[key].Add(amount)
+			paidByUser[userID] = paidByUser[userID].Add(amount)
+		}
+
+		debtors, creditors := splitBalances(balance)
+		for _, pair := range pairBillShares(debtors, creditors) {
+			// Allocations of parties that no longer exist cannot be settled between flatmates
+			if _, ok := dir.parties[pair.from]; !ok {
+				continue
+			}
+
+			converted, err := s.currencyService.Convert(ctx, pair.amount, bill.Currency, now)
+			if err != nil {
+				return fmt.Errorf("failed to convert bill %s: %w", bill.ID, err)
+			}
+
+			ledger.addDebt(pair.from, pair.to, converted)
+			ledger.billShares = append(ledger.billShares, settleUpBillShare{
+				bill:       bill,
+				debtorID:   dir.recordedUser(pair.from, paidByUser),
+				creditorID: dir.recordedUser(pair.to, paidByUser),
+				amount:     pair.amount,
+			})
+			ledger.bills[bill.ID] = true
+		}
+	}
+
+	return nil
+}
+
+// recordedUser picks the user a party's bill payment is recorded for:
+// the member who paid the most on the bill, or the first member
+func (d *settleUpDirectory) recordedUser(key string, paidByUser map[string]utils.Money) string {
+	best := d.userByParty[key]
+	for _, userID := range d.partyUsers[key] {
+		if paidByUser[userID] > paidByUser[best] {
+			best = userID
+		}
+	}
+	return best
+}
+
+func (l *settleUpLedger) addDebt(from, to string, amount utils.Money) {
+	l.net[from] = l.net[from].Sub(amount)
+	l.net[to] = l.net[to].Add(amount)
+	l.pairs[[2]string{from, to}] = true
+}
+
+func (l *settleUpLedger) plan() *SettleUpPlan {
+	plan := &SettleUpPlan{
+		Currency:     l.currency,
+		Balances:     []SettleUpBalance{},
+		Transfers:    []SettleUpTransfer{},
+		LoansSettled: len(l.loans),
+		BillsSettled: len(l.bills),
+	}
+
+	// Debts in both directions between two parties cancel out, so count unordered pairs
+	seen := make(map[[2]string]bool)
+	for pair := range l.pairs {
+		a, b := pair[0], pair[1]
+		if a > b {
+			a, b = b, a
+		}
+		seen[[2]string{a, b}] = true
+	}
+	plan.PairwiseDebts = len(seen)
+
+	keys := make([]string, 0, len(l.net))
+	for key, net := range l.net {
+		if !net.IsZero() {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		plan.Balances = append(plan.Balances, SettleUpBalance{Party: l.parties[key], Net: l.net[key]})
+	}
+
+	for _, t := range simplifyDebts(l.net) {
+		plan.Transfers = append(plan.Transfers, SettleUpTransfer{
+			From:   l.parties[t.from],
+			To:     l.parties[t.to],
+			Amount: t.amount,
+		})
+	}
+
+	return plan
+}
+
+// debtTransfer is a payment between two party keys
+type debtTransfer struct {
+	from   string
+	to     string
+	amount utils.Money
+}
+
+// partyAmount is a party key with a positive amount owed or receivable
+type partyAmount struct {
+	key    string
+	amount utils.Money
+}
+
+// splitBalances separates negative balances (debtors) from positive ones (creditors), sorted by key
+func splitBalances(balances map[string]utils.Money) (debtors, creditors []partyAmount) {
+	for key, amount := range balances {
+		switch {
+		case amount.IsPositive():
+			creditors = append(creditors, partyAmount{key, amount})
+		case amount.IsNegative():
+			debtors = append(debtors, partyAmount{key, amount.Neg()})
+		}
+	}
+	byKey := func(list []partyAmount) {
+		sort.Slice(list, func(i, j int) bool { return list[i].key < list[j].key })
+	}
+	byKey(debtors)
+	byKey(creditors)
+	return debtors, creditors
+}
+
+// pairBillShares matches underpayments on a bill with overpayments. When more is missing than
+// was overpaid, every debtor is charged in proportion to what they still owe.
+func pairBillShares(debtors, creditors []partyAmount) []debtTransfer {
+	var owed, covered utils.Money
+	for _, d := range debtors {
+		owed = owed.Add(d.amount)
+	}
+	for _, c := range creditors {
+		covered = covered.Add(c.amount)
+	}
+	settled := utils.MinMoney(owed, covered)
+	if !settled.IsPositive() {
+		return nil
+	}
+
+	scale := func(list []partyAmount) []partyAmount {
+		weights := make([]float64, len(list))
+		for i, p := range list {
+			weights[i] = float64(p.amount)
+		}
+		shares := settled.Allocate(weights)
+		scaled := make([]partyAmount, 0, len(list))
+		for i, p := range list {
+			if shares[i].IsPositive() {
+				scaled = append(scaled, partyAmount{p.key, shares[i]})
+			}
+		}
+		return scaled
+	}
+
+	return matchAmounts(scale(debtors), scale(creditors))
+}
+
+// matchAmounts pairs debtors with creditors in order until both lists (with equal totals) are used up
+func matchAmounts(debtors, creditors []partyAmount) []debtTransfer {
+	var transfers []debtTransfer
+	i, j := 0, 0
+	for i < len(debtors) && j < len(creditors) {
+		amount := utils.MinMoney(debtors[i].amount, creditors[j].amount)
+		if amount.IsPositive() {
+			transfers = append(transfers, debtTransfer{from: debtors[i].key, to: creditors[j].key, amount: amount})
+		}
+		debtors[i].amount = debtors[i].amount.Sub(amount)
+		creditors[j].amount = creditors[j].amount.Sub(amount)
+		if !debtors[i].amount.IsPositive() {
+			i++
+		}
+		if !creditors[j].amount.IsPositive() {
+			j++
+		}
+	}
+	return transfers
+}
+
+// simplifyDebts turns net balances into a small set of transfers. Parties whose balances cancel
+// exactly are paired first, then the largest debtor always pays the largest creditor, which
+// needs at most one transfer fewer than the number of parties with a balance.
+func simplifyDebts(net map[string]utils.Money) []debtTransfer {
+	debtors, creditors := splitBalances(net)
+	var transfers []debtTransfer
+
+	// Exact matches settle two parties with one transfer
+	for i := range debtors {
+		for j := range creditors {
+			if creditors[j].amount.IsPositive() && debtors[i].amount == creditors[j].amount {
+				transfers = append(transfers, debtTransfer{from: debtors[i].key, to: creditors[j].key, amount: debtors[i].amount})
+				debtors[i].amount = 0
+				creditors[j].amount = 0
+				break
+			}
+		}
+	}
+
+	for {
+		d := largestAmount(debtors)
+		c := largestAmount(creditors)
+		if d < 0 || c < 0 {
+			break
+		}
+		amount := utils.MinMoney(debtors[d].amount, creditors[c].amount)
+		transfers = append(transfers, debtTransfer{from: debtors[d].key, to: creditors[c].key, amount: amount})
+		debtors[d].amount = debtors[d].amount.Sub(amount)
+		creditors[c].amount = creditors[c].amount.Sub(amount)
+	}
+
+	return transfers
+}
+
+// largestAmount returns the index of the largest positive amount, or -1 if none is left
+func largestAmount(list []partyAmount) int {
+	best := -1
+	for i, p := range list {
+		if p.amount.IsPositive() && (best < 0 || p.amount > list[best].amount) {
+			best = i
+		}
+	}
+	return best
+}
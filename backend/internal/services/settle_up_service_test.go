@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimplifyDebts(t *testing.T) {
+	tests := []struct {
+		name          string
+		net           map[string]utils.Money
+		wantTransfers int
+	}{
+		{
+			name:          "Chain collapses to one transfer",
+			net:           map[string]utils.Money{"a": 10000, "b": 0, "c": -10000},
+			wantTransfers: 1,
+		},
+		{
+			name:          "Exact matches are paired first",
+			net:           map[string]utils.Money{"a": 5000, "b": 3000, "c": -3000, "d": -5000},
+			wantTransfers: 2,
+		},
+		{
+			name: "Five balances need at most four transfers",
+			net: map[string]utils.Money{
+				"a": 12000, "b": 4550, "c": -3000, "d": -7525, "e": -6025, "f": 0,
+			},
+			wantTransfers: 4,
+		},
+		{
+			name:          "Nothing owed",
+			net:           map[string]utils.Money{"a": 0},
+			wantTransfers: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transfers := simplifyDebts(tt.net)
+			assert.Len(t, transfers, tt.wantTransfers)
+
+			// Applying the transfers must settle every balance exactly
+			remaining := make(map[string]utils.Money)
+			for key, amount := range tt.net {
+				remaining[key] = amount
+			}
+			for _, transfer := range transfers {
+				assert.True(t, transfer.amount.IsPositive())
+				remaining[transfer.from] = remaining[transfer.from].Add(transfer.amount)
+				remaining[transfer.to] = remaining[transfer.to].Sub(transfer.amount)
+			}
+			for key, amount := range remaining {
+				assert.True(t, amount.IsZero(), "%s still has %s", key, amount)
+			}
+		})
+	}
+}
+
+func TestPairBillShares(t *testing.T) {
+	// 60.00 missing but only 30.00 covered by a flatmate: debtors are charged in proportion
+	debtors := []partyAmount{{"b", 4000}, {"c", 2000}}
+	creditors := []partyAmount{{"a", 3000}}
+
+	transfers := pairBillShares(debtors, creditors)
+	require.Len(t, transfers, 2)
+	assert.Equal(t, debtTransfer{from: "b", to: "a", amount: 2000}, transfers[0])
+	assert.Equal(t, debtTransfer{from: "c", to: "a", amount: 1000}, transfers[1])
+
+	assert.Empty(t, pairBillShares(debtors, nil), "nothing is owed to flatmates when nobody overpaid")
+}
+
+// newTestSettleUpService wires a settle-up service against the test database
+func newTestSettleUpService(repos *repository.Repositories, loans repository.LoanRepository) *SettleUpService {
+	currencyService := NewCurrencyService(repos.ExchangeRates, NewAppSettingsService(repos.AppSettings))
+	allocationService := NewAllocationService(repos.Users, repos.Residencies, repos.BillResidents, repos.Groups, repos.Consumptions, repos.Allocations,
+		repos.Bills, repos.BillSplits, repos.BillItems, repos.BillTariffZones, repos.Meters)
+	creditService := NewCreditService(repos.CreditEntries, repos.Payments, repos.Users, allocationService)
+	return NewSettleUpService(loans, repos.LoanPayments, repos.SettlementTransfers, repos.Bills, repos.Allocations, repos.Payments,
+		repos.Users, repos.Groups, repos.TxManager, currencyService, creditService)
+}
+
+// seedSettleUpData creates loans Alice -> Bob -> Carol and a bill Alice paid for everyone
+func seedSettleUpData(t *testing.T, repos *repository.Repositories) (alice, bob, carol *models.User) {
+	t.Helper()
+	ctx := context.Background()
+
+	alice = createTestUser(t, repos, "Alice")
+	bob = createTestUser(t, repos, "Bob")
+	carol = createTestUser(t, repos, "Carol")
+
+	for _, loan := range []models.Loan{
+		{LenderID: alice.ID, BorrowerID: bob.ID, AmountPLN: "100.00", Status: "open"},
+		{LenderID: bob.ID, BorrowerID: carol.ID, AmountPLN: "100.00", Status: "open"},
+	} {
+		require.NoError(t, repos.Loans.Create(ctx, &loan))
+	}
+
+	bill := &models.Bill{
+		Type:           "internet",
+		PeriodStart:    time.Now().AddDate(0, -1, 0),
+		PeriodEnd:      time.Now(),
+		TotalAmountPLN: "90.00",
+		Status:         "posted",
+	}
+	require.NoError(t, repos.Bills.Create(ctx, bill))
+	for _, user := range []*models.User{alice, bob, carol} {
+		require.NoError(t, repos.Allocations.Create(ctx, bill.ID, "user", user.ID, "30.00"))
+	}
+	require.NoError(t, repos.Payments.Create(ctx, &models.Payment{
+		BillID:      bill.ID,
+		PayerUserID: alice.ID,
+		AmountPLN:   "90.00",
+		PaidAt:      time.Now(),
+	}))
+
+	return alice, bob, carol
+}
+
+func TestSettleUpPreviewAndApply(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	alice, bob, carol := seedSettleUpData(t, repos)
+	settleUpService := newTestSettleUpService(repos, repos.Loans)
+
+	plan, err := settleUpService.PreviewSettleUp(ctx)
+	require.NoError(t, err)
+	assert.False(t, plan.Applied)
+	assert.Equal(t, 3, plan.PairwiseDebts)
+	assert.Equal(t, 2, plan.LoansSettled)
+	assert.Equal(t, 1, plan.BillsSettled)
+
+	// Net balances: Alice +160, Bob -30 (owes 130, is owed 100), Carol -130
+	require.Len(t, plan.Transfers, 2)
+	assert.Equal(t, carol.ID, plan.Transfers[0].From.ID)
+	assert.Equal(t, alice.ID, plan.Transfers[0].To.ID)
+	assert.Equal(t, utils.Money(13000), plan.Transfers[0].Amount)
+	assert.Equal(t, bob.ID, plan.Transfers[1].From.ID)
+	assert.Equal(t, utils.Money(3000), plan.Transfers[1].Amount)
+
+	applied, err := settleUpService.ApplySettleUp(ctx)
+	require.NoError(t, err)
+	assert.True(t, applied.Applied)
+	require.Len(t, applied.Transfers, 2)
+
+	// Every planned transfer is recorded, and the loan payments point at the same settlement
+	transfers, err := settleUpService.GetTransfers(ctx, applied.SettlementID)
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+	for i, transfer := range transfers {
+		assert.Equal(t, applied.Transfers[i].ID, transfer.ID)
+		assert.Equal(t, plan.Transfers[i].From.ID, transfer.FromID)
+		assert.Equal(t, plan.Transfers[i].To.ID, transfer.ToID)
+		assert.Equal(t, plan.Transfers[i].Amount.String(), transfer.Amount)
+	}
+
+	settled, err := repos.Loans.ListByStatus(ctx, "settled")
+	require.NoError(t, err)
+	assert.Len(t, settled, 2)
+	loanPayments, err := repos.LoanPayments.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, loanPayments, 2)
+	for _, payment := range loanPayments {
+		require.NotNil(t, payment.SettlementID)
+		assert.Equal(t, applied.SettlementID, *payment.SettlementID)
+	}
+
+	payments, err := repos.Payments.ListByPayerID(ctx, alice.ID)
+	require.NoError(t, err)
+	var alicePaid utils.Money
+	for _, p := range payments {
+		alicePaid = alicePaid.Add(utils.MoneyFromString(p.AmountPLN))
+	}
+	assert.Equal(t, utils.Money(3000), alicePaid, "Alice should only be left with her own share")
+
+	after, err := settleUpService.PreviewSettleUp(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, after.Transfers)
+
+	_, err = settleUpService.ApplySettleUp(ctx)
+	assert.ErrorIs(t, err, ErrNothingToSettle)
+}
+
+func TestSettleUpTreatsGroupAsOneParty(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+	carol := createTestUser(t, repos, "Carol")
+
+	require.NoError(t, repos.Groups.Create(ctx, &models.Group{Name: "Couple", Weight: 2}))
+	groups, err := repos.Groups.List(ctx)
+	require.NoError(t, err)
+	for _, user := range []*models.User{bob, carol} {
+		user.GroupID = &groups[0].ID
+		require.NoError(t, repos.Users.Update(ctx, user))
+	}
+
+	for _, loan := range []models.Loan{
+		{LenderID: bob.ID, BorrowerID: carol.ID, AmountPLN: "500.00", Status: "open"}, // inside the group
+		{LenderID: alice.ID, BorrowerID: bob.ID, AmountPLN: "40.00", Status: "open"},
+		{LenderID: carol.ID, BorrowerID: alice.ID, AmountPLN: "10.00", Status: "open"},
+	} {
+		require.NoError(t, repos.Loans.Create(ctx, &loan))
+	}
+
+	plan, err := newTestSettleUpService(repos, repos.Loans).PreviewSettleUp(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, plan.LoansSettled, "loans inside a group are left to group compensation")
+	require.Len(t, plan.Transfers, 1)
+	assert.Equal(t, "group", plan.Transfers[0].From.Type)
+	assert.ElementsMatch(t, []string{"Bob", "Carol"}, plan.Transfers[0].From.Members)
+	assert.Equal(t, alice.ID, plan.Transfers[0].To.ID)
+	assert.Equal(t, utils.Money(3000), plan.Transfers[0].Amount)
+}
+
+func TestApplySettleUpIsAtomic(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	alice, _, _ := seedSettleUpData(t, repos)
+
+	_, err := newTestSettleUpService(repos, &failingLoanRepository{repos.Loans}).ApplySettleUp(ctx)
+	require.ErrorIs(t, err, errInjected)
+
+	loanPayments, err := repos.LoanPayments.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, loanPayments)
+	transfers, err := repos.SettlementTransfers.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, transfers, "no transfers should be recorded")
+
+	payments, err := repos.Payments.ListByPayerID(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Len(t, payments, 1, "no settle-up bill payments should be recorded")
+}
@@ -16,23 +16,32 @@ type SupplyService struct {
 	supplySettings      repository.SupplySettingsRepository
 	supplyItems         repository.SupplyItemRepository
 	supplyContributions repository.SupplyContributionRepository
+	supplyItemHistory   repository.SupplyItemHistoryRepository
 	users               repository.UserRepository
+	txManager           repository.TxManager
 	notificationService *NotificationService
+	currencyService     *CurrencyService
 }
 
 func NewSupplyService(
 	supplySettings repository.SupplySettingsRepository,
 	supplyItems repository.SupplyItemRepository,
 	supplyContributions repository.SupplyContributionRepository,
+	supplyItemHistory repository.SupplyItemHistoryRepository,
 	users repository.UserRepository,
+	txManager repository.TxManager,
 	notificationService *NotificationService,
+	currencyService *CurrencyService,
 ) *SupplyService {
 	return &SupplyService{
 		supplySettings:      supplySettings,
 		supplyItems:         supplyItems,
 		supplyContributions: supplyContributions,
+		supplyItemHistory:   supplyItemHistory,
 		users:               users,
+		txManager:           txManager,
 		notificationService: notificationService,
+		currencyService:     currencyService,
 	}
 }
 
@@ -45,9 +54,9 @@ func (s *SupplyService) GetSettings(ctx context.Context) (*models.SupplySettings
 		// Create default settings
 		settings = &models.SupplySettings{
 			ID:                    "singleton",
-			WeeklyContributionPLN: utils.FloatToDecimalString(10.0), // 10 PLN per person per week
+			WeeklyContributionPLN: utils.NewMoney(10, 0).String(), // 10 PLN per person per week
 			ContributionDay:       "monday",
-			CurrentBudgetPLN:      utils.FloatToDecimalString(0.0),
+			CurrentBudgetPLN:      utils.Money(0).String(),
 			LastContributionAt:    time.Now(),
 			IsActive:              true,
 			CreatedAt:             time.Now(),
@@ -65,8 +74,8 @@ func (s *SupplyService) GetSettings(ctx context.Context) (*models.SupplySettings
 }
 
 // UpdateSettings updates supply settings (ADMIN only)
-func (s *SupplyService) UpdateSettings(ctx context.Context, weeklyContribution float64, contributionDay string) error {
-	if weeklyContribution <= 0 {
+func (s *SupplyService) UpdateSettings(ctx context.Context, weeklyContribution utils.Money, contributionDay string) error {
+	if !weeklyContribution.IsPositive() {
 		return errors.New("weekly contribution must be positive")
 	}
 
@@ -83,7 +92,7 @@ func (s *SupplyService) UpdateSettings(ctx context.Context, weeklyContribution f
 		return err
 	}
 
-	settings.WeeklyContributionPLN = utils.FloatToDecimalString(weeklyContribution)
+	settings.WeeklyContributionPLN = weeklyContribution.String()
 	settings.ContributionDay = contributionDay
 	settings.UpdatedAt = time.Now()
 
@@ -95,15 +104,14 @@ func (s *SupplyService) UpdateSettings(ctx context.Context, weeklyContribution f
 }
 
 // AdjustBudget manually adjusts the budget (ADMIN only)
-func (s *SupplyService) AdjustBudget(ctx context.Context, adjustment float64, notes string) error {
+func (s *SupplyService) AdjustBudget(ctx context.Context, adjustment utils.Money, notes string) error {
 	settings, err := s.GetSettings(ctx)
 	if err != nil {
 		return err
 	}
 
-	currentBudget := utils.DecimalStringToFloat(settings.CurrentBudgetPLN)
-	newBudget := currentBudget + adjustment
-	settings.CurrentBudgetPLN = utils.FloatToDecimalString(newBudget)
+	currentBudget := utils.MoneyFromString(settings.CurrentBudgetPLN)
+	settings.CurrentBudgetPLN = currentBudget.Add(adjustment).String()
 	settings.UpdatedAt = time.Now()
 
 	if err := s.supplySettings.Upsert(ctx, settings); err != nil {
@@ -287,36 +295,93 @@ func (s *SupplyService) UpdateItem(ctx context.Context, itemID string, name *str
 	return nil
 }
 
-// RestockItem increases quantity and optionally records amount spent for refund
-func (s *SupplyService) RestockItem(ctx context.Context, itemID, userID string, quantityToAdd int, amountPLN *float64, needsRefund bool) error {
+// RestockItem increases quantity and optionally records amount spent for refund.
+// The amount may be in any currency with a known exchange rate; an empty currency means the base currency.
+// Returns the restock's history entry, which its receipts are attached to.
+func (s *SupplyService) RestockItem(ctx context.Context, itemID, userID string, quantityToAdd int, amountPLN *utils.Money, currency string, needsRefund bool) (*models.SupplyItemHistory, error) {
 	if quantityToAdd <= 0 {
-		return errors.New("quantity to add must be positive")
+		return nil, errors.New("quantity to add must be positive")
 	}
 
 	item, err := s.supplyItems.GetByID(ctx, itemID)
-	if err != nil {
-		return errors.New("item not found")
+	if err != nil || item == nil {
+		return nil, errors.New("item not found")
 	}
 
 	now := time.Now()
+	oldQuantity := item.CurrentQuantity
 	item.CurrentQuantity += quantityToAdd
 	item.LastRestockedAt = &now
 	item.LastRestockedByUserID = &userID
 	item.NeedsRefund = needsRefund
 
 	if amountPLN != nil {
-		if *amountPLN < 0 {
-			return errors.New("amount cannot be negative")
+		if amountPLN.IsNegative() {
+			return nil, errors.New("amount cannot be negative")
+		}
+		restockCurrency, err := s.currencyService.ResolveCurrency(ctx, currency)
+		if err != nil {
+			return nil, err
 		}
-		amountStr := utils.FloatToDecimalString(*amountPLN)
+		// Refunds come out of the budget, which is kept in the base currency
+		if err := s.currencyService.EnsureConvertible(ctx, restockCurrency, now); err != nil {
+			return nil, err
+		}
+		amountStr := amountPLN.String()
 		item.LastRestockAmountPLN = &amountStr
+		item.LastRestockCurrency = &restockCurrency
 	}
 
-	if err := s.supplyItems.Update(ctx, item); err != nil {
-		return fmt.Errorf("failed to restock item: %w", err)
+	// The restock is kept in the history with its cost in the base currency, which budgets count as spending
+	history := &models.SupplyItemHistory{
+		SupplyItemID:  item.ID,
+		UserID:        userID,
+		Action:        "restock",
+		QuantityDelta: quantityToAdd,
+		OldQuantity:   oldQuantity,
+		NewQuantity:   item.CurrentQuantity,
+	}
+	if amountPLN != nil {
+		cost, err := s.restockAmountInBaseCurrency(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+		costStr := cost.String()
+		history.CostPLN = &costStr
 	}
 
-	return nil
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.supplyItems.Update(ctx, item); err != nil {
+			return fmt.Errorf("failed to restock item: %w", err)
+		}
+		if err := s.supplyItemHistory.Create(ctx, history); err != nil {
+			return fmt.Errorf("failed to record restock: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// GetRestocks returns the restocks of a supply item, newest first
+func (s *SupplyService) GetRestocks(ctx context.Context, itemID string) ([]models.SupplyItemHistory, error) {
+	item, err := s.supplyItems.GetByID(ctx, itemID)
+	if err != nil || item == nil {
+		return nil, errors.New("item not found")
+	}
+	history, err := s.supplyItemHistory.ListBySupplyItemID(ctx, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item history: %w", err)
+	}
+	restocks := []models.SupplyItemHistory{}
+	for _, h := range history {
+		if h.Action == "restock" {
+			restocks = append(restocks, h)
+		}
+	}
+	return restocks, nil
 }
 
 // ConsumeItem reduces quantity (for use/consumption)
@@ -410,27 +475,63 @@ func (s *SupplyService) MarkAsRefunded(ctx context.Context, itemID string) error
 		return err
 	}
 
-	amountToRefund := utils.DecimalStringToFloat(*item.LastRestockAmountPLN)
-	currentBudget := utils.DecimalStringToFloat(settings.CurrentBudgetPLN)
+	amountToRefund, err := s.restockAmountInBaseCurrency(ctx, item)
+	if err != nil {
+		return err
+	}
+	currentBudget := utils.MoneyFromString(settings.CurrentBudgetPLN)
 
 	if currentBudget < amountToRefund {
-		return fmt.Errorf("insufficient budget: have %.2f PLN, need %.2f PLN", currentBudget, amountToRefund)
+		return fmt.Errorf("insufficient budget: have %s, need %s", currentBudget, amountToRefund)
 	}
 
-	// Update item
-	item.NeedsRefund = false
-	if err := s.supplyItems.Update(ctx, item); err != nil {
-		return fmt.Errorf("failed to update item: %w", err)
-	}
+	// Item, refund record and budget change together
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Update item
+		item.NeedsRefund = false
+		if err := s.supplyItems.Update(ctx, item); err != nil {
+			return fmt.Errorf("failed to update item: %w", err)
+		}
 
-	// Update budget
-	settings.CurrentBudgetPLN = utils.FloatToDecimalString(currentBudget - amountToRefund)
-	settings.UpdatedAt = time.Now()
-	if err := s.supplySettings.Upsert(ctx, settings); err != nil {
-		return fmt.Errorf("failed to update budget: %w", err)
+		// Record who got the money back, so it shows up on their statement
+		if item.LastRestockedByUserID != nil {
+			cost := amountToRefund.String()
+			if err := s.supplyItemHistory.Create(ctx, &models.SupplyItemHistory{
+				SupplyItemID: item.ID,
+				UserID:       *item.LastRestockedByUserID,
+				Action:       "refund",
+				OldQuantity:  item.CurrentQuantity,
+				NewQuantity:  item.CurrentQuantity,
+				CostPLN:      &cost,
+			}); err != nil {
+				return fmt.Errorf("failed to record refund: %w", err)
+			}
+		}
+
+		// Update budget
+		settings.CurrentBudgetPLN = currentBudget.Sub(amountToRefund).String()
+		settings.UpdatedAt = time.Now()
+		if err := s.supplySettings.Upsert(ctx, settings); err != nil {
+			return fmt.Errorf("failed to update budget: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// restockAmountInBaseCurrency converts the last restock amount of an item to the base currency
+// using the rate valid when the item was restocked
+func (s *SupplyService) restockAmountInBaseCurrency(ctx context.Context, item *models.SupplyItem) (utils.Money, error) {
+	amount := utils.MoneyFromString(*item.LastRestockAmountPLN)
+	if item.LastRestockCurrency == nil {
+		return amount, nil
 	}
 
-	return nil
+	at := time.Now()
+	if item.LastRestockedAt != nil {
+		at = *item.LastRestockedAt
+	}
+	return s.currencyService.Convert(ctx, amount, *item.LastRestockCurrency, at)
 }
 
 // DeleteItem deletes an item (ADMIN or creator only - enforced at handler level)
@@ -507,8 +608,8 @@ func (s *SupplyService) getAllContributions(ctx context.Context) ([]models.Suppl
 }
 
 // CreateManualContribution adds a manual contribution
-func (s *SupplyService) CreateManualContribution(ctx context.Context, userID string, amountPLN float64, notes *string) error {
-	if amountPLN <= 0 {
+func (s *SupplyService) CreateManualContribution(ctx context.Context, userID string, amountPLN utils.Money, notes *string) error {
+	if !amountPLN.IsPositive() {
 		return errors.New("amount must be positive")
 	}
 
@@ -516,7 +617,7 @@ func (s *SupplyService) CreateManualContribution(ctx context.Context, userID str
 	contribution := models.SupplyContribution{
 		ID:          uuid.New().String(),
 		UserID:      userID,
-		AmountPLN:   utils.FloatToDecimalString(amountPLN),
+		AmountPLN:   amountPLN.String(),
 		PeriodStart: now,
 		PeriodEnd:   now,
 		Type:        "manual",
@@ -534,8 +635,8 @@ func (s *SupplyService) CreateManualContribution(ctx context.Context, userID str
 		return err
 	}
 
-	currentBudget := utils.DecimalStringToFloat(settings.CurrentBudgetPLN)
-	settings.CurrentBudgetPLN = utils.FloatToDecimalString(currentBudget + amountPLN)
+	currentBudget := utils.MoneyFromString(settings.CurrentBudgetPLN)
+	settings.CurrentBudgetPLN = currentBudget.Add(amountPLN).String()
 	settings.UpdatedAt = time.Now()
 
 	if err := s.supplySettings.Upsert(ctx, settings); err != nil {
@@ -570,39 +671,42 @@ func (s *SupplyService) ProcessWeeklyContributions(ctx context.Context) error {
 	weekStart := now.AddDate(0, 0, -int(now.Weekday()))
 	weekEnd := weekStart.AddDate(0, 0, 6)
 
-	totalContributed := 0.0
-	weeklyContribution := utils.DecimalStringToFloat(settings.WeeklyContributionPLN)
+	var totalContributed utils.Money
+	weeklyContribution := utils.MoneyFromString(settings.WeeklyContributionPLN)
+
+	// Contributions and the budget update are committed together, so a failed run changes neither
+	return s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Create contribution for each active user
+		for _, user := range users {
+			contribution := models.SupplyContribution{
+				ID:          uuid.New().String(),
+				UserID:      user.ID,
+				AmountPLN:   weeklyContribution.String(),
+				PeriodStart: weekStart,
+				PeriodEnd:   weekEnd,
+				Type:        "weekly_auto",
+				CreatedAt:   now,
+			}
 
-	// Create contribution for each active user
-	for _, user := range users {
-		contribution := models.SupplyContribution{
-			ID:          uuid.New().String(),
-			UserID:      user.ID,
-			AmountPLN:   settings.WeeklyContributionPLN,
-			PeriodStart: weekStart,
-			PeriodEnd:   weekEnd,
-			Type:        "weekly_auto",
-			CreatedAt:   now,
-		}
+			if err := s.supplyContributions.Create(ctx, &contribution); err != nil {
+				return fmt.Errorf("failed to create contribution for user %s: %w", user.Email, err)
+			}
 
-		if err := s.supplyContributions.Create(ctx, &contribution); err != nil {
-			return fmt.Errorf("failed to create contribution for user %s: %w", user.Email, err)
+			totalContributed = totalContributed.Add(weeklyContribution)
 		}
 
-		totalContributed += weeklyContribution
-	}
-
-	// Update budget
-	currentBudget := utils.DecimalStringToFloat(settings.CurrentBudgetPLN)
-	settings.CurrentBudgetPLN = utils.FloatToDecimalString(currentBudget + totalContributed)
-	settings.LastContributionAt = now
-	settings.UpdatedAt = now
+		// Update budget
+		currentBudget := utils.MoneyFromString(settings.CurrentBudgetPLN)
+		settings.CurrentBudgetPLN = currentBudget.Add(totalContributed).String()
+		settings.LastContributionAt = now
+		settings.UpdatedAt = now
 
-	if err := s.supplySettings.Upsert(ctx, settings); err != nil {
-		return fmt.Errorf("failed to update budget: %w", err)
-	}
+		if err := s.supplySettings.Upsert(ctx, settings); err != nil {
+			return fmt.Errorf("failed to update budget: %w", err)
+		}
 
-	return nil
+		return nil
+	})
 }
 
 // GetStats returns spending statistics
@@ -620,12 +724,15 @@ func (s *SupplyService) GetStats(ctx context.Context) (map[string]interface{}, e
 			if _, exists := categoryStats[item.Category]; !exists {
 				categoryStats[item.Category] = map[string]interface{}{
 					"_id":        item.Category,
-					"totalSpent": 0.0,
+					"totalSpent": utils.Money(0),
 					"count":      0,
 				}
 			}
-			amount := utils.DecimalStringToFloat(*item.LastRestockAmountPLN)
-			categoryStats[item.Category]["totalSpent"] = categoryStats[item.Category]["totalSpent"].(float64) + amount
+			amount, err := s.restockAmountInBaseCurrency(ctx, &item)
+			if err != nil {
+				return nil, err
+			}
+			categoryStats[item.Category]["totalSpent"] = categoryStats[item.Category]["totalSpent"].(utils.Money).Add(amount)
 			categoryStats[item.Category]["count"] = categoryStats[item.Category]["count"].(int) + 1
 		}
 	}
@@ -643,12 +750,15 @@ func (s *SupplyService) GetStats(ctx context.Context) (map[string]interface{}, e
 			if _, exists := userStats[userID]; !exists {
 				userStats[userID] = map[string]interface{}{
 					"_id":        userID,
-					"totalSpent": 0.0,
+					"totalSpent": utils.Money(0),
 					"count":      0,
 				}
 			}
-			amount := utils.DecimalStringToFloat(*item.LastRestockAmountPLN)
-			userStats[userID]["totalSpent"] = userStats[userID]["totalSpent"].(float64) + amount
+			amount, err := s.restockAmountInBaseCurrency(ctx, &item)
+			if err != nil {
+				return nil, err
+			}
+			userStats[userID]["totalSpent"] = userStats[userID]["totalSpent"].(utils.Money).Add(amount)
 			userStats[userID]["count"] = userStats[userID]["count"].(int) + 1
 		}
 	}
@@ -669,7 +779,13 @@ func (s *SupplyService) GetStats(ctx context.Context) (map[string]interface{}, e
 		recentContributions = recentContributions[:10]
 	}
 
+	baseCurrency, err := s.currencyService.BaseCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	return map[string]interface{}{
+		"currency":            baseCurrency, // totalSpent is converted to the base currency
 		"byCategory":          categoryStatsSlice,
 		"byUser":              userStatsSlice,
 		"recentContributions": recentContributions,
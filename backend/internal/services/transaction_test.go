@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sainaif/holy-home/internal/config"
+	"github.com/sainaif/holy-home/internal/database"
+	"github.com/sainaif/holy-home/internal/models"
+	"github.com/sainaif/holy-home/internal/repository"
+	"github.com/sainaif/holy-home/internal/repository/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errInjected = errors.New("injected failure")
+
+// newTestRepositories opens a fresh SQLite database with the full schema
+func newTestRepositories(t *testing.T) *repository.Repositories {
+	t.Helper()
+
+	db, err := database.NewSQLiteDB(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return sqlite.NewRepositories(db.DB)
+}
+
+// createTestUser stores a user and returns it with the ID assigned by the repository
+func createTestUser(t *testing.T, repos *repository.Repositories, name string) *models.User {
+	t.Helper()
+	ctx := context.Background()
+
+	email := strings.ToLower(name) + "@example.com"
+	require.NoError(t, repos.Users.Create(ctx, &models.User{
+		Email:        email,
+		Name:         name,
+		PasswordHash: "hash",
+		Role:         "RESIDENT",
+		IsActive:     true,
+	}))
+
+	user, err := repos.Users.GetByEmail(ctx, email)
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	return user
+}
+
+// newTestNotificationService builds a notification service backed by the test database
+func newTestNotificationService(repos *repository.Repositories) *NotificationService {
+	return NewNotificationService(repos.Notifications, NewEventService(), NewWebPushService(repos.WebPushSubscriptions),
+		NewNotificationPreferenceService(repos.NotificationPreferences), &config.Config{})
+}
+
+// failingLoanRepository fails every loan update, after offset payments have been written
+type failingLoanRepository struct {
+	repository.LoanRepository
+}
+
+func (r *failingLoanRepository) Update(ctx context.Context, loan *models.Loan) error {
+	return errInjected
+}
+
+// failingChoreRepository fails deleting the chore, after its assignments have been deleted
+type failingChoreRepository struct {
+	repository.ChoreRepository
+}
+
+func (r *failingChoreRepository) Delete(ctx context.Context, id string) error {
+	return errInjected
+}
+
+// failingSupplySettingsRepository fails the budget update once failUpsert is set
+type failingSupplySettingsRepository struct {
+	repository.SupplySettingsRepository
+	failUpsert bool
+}
+
+func (r *failingSupplySettingsRepository) Upsert(ctx context.Context, settings *models.SupplySettings) error {
+	if r.failUpsert {
+		return errInjected
+	}
+	return r.SupplySettingsRepository.Upsert(ctx, settings)
+}
+
+func TestTxManagerWithinTx(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+
+	t.Run("Commits on success", func(t *testing.T) {
+		err := repos.TxManager.WithinTx(ctx, func(ctx context.Context) error {
+			return repos.Users.Create(ctx, &models.User{
+				Email: "committed@example.com", Name: "Committed", PasswordHash: "hash", Role: "RESIDENT", IsActive: true,
+			})
+		})
+		require.NoError(t, err)
+
+		user, err := repos.Users.GetByEmail(ctx, "committed@example.com")
+		require.NoError(t, err)
+		assert.NotNil(t, user)
+	})
+
+	t.Run("Rolls back on error, including nested calls", func(t *testing.T) {
+		err := repos.TxManager.WithinTx(ctx, func(ctx context.Context) error {
+			return repos.TxManager.WithinTx(ctx, func(ctx context.Context) error {
+				require.NoError(t, repos.Users.Create(ctx, &models.User{
+					Email: "rolledback@example.com", Name: "Rolled Back", PasswordHash: "hash", Role: "RESIDENT", IsActive: true,
+				}))
+				return errInjected
+			})
+		})
+		assert.ErrorIs(t, err, errInjected)
+
+		user, err := repos.Users.GetByEmail(ctx, "rolledback@example.com")
+		require.NoError(t, err)
+		assert.Nil(t, user)
+	})
+
+	t.Run("Runs after commit callbacks once the outermost transaction commits", func(t *testing.T) {
+		var ran []string
+		err := repos.TxManager.WithinTx(ctx, func(ctx context.Context) error {
+			err := repos.TxManager.WithinTx(ctx, func(ctx context.Context) error {
+				repos.TxManager.AfterCommit(ctx, func(context.Context) { ran = append(ran, "inner") })
+				return nil
+			})
+			assert.Empty(t, ran)
+			return err
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"inner"}, ran)
+
+		ran = nil
+		err = repos.TxManager.WithinTx(ctx, func(ctx context.Context) error {
+			repos.TxManager.AfterCommit(ctx, func(context.Context) { ran = append(ran, "rolled back") })
+			return errInjected
+		})
+		assert.ErrorIs(t, err, errInjected)
+		assert.Empty(t, ran)
+
+		repos.TxManager.AfterCommit(ctx, func(context.Context) { ran = append(ran, "no transaction") })
+		assert.Equal(t, []string{"no transaction"}, ran)
+	})
+}
+
+func TestCreateLoanRollsBackOffsetsOnFailure(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+
+	alice := createTestUser(t, repos, "Alice")
+	bob := createTestUser(t, repos, "Bob")
+
+	// Bob already lent Alice 100, so a new loan from Alice to Bob is offset against it
+	require.NoError(t, repos.Loans.Create(ctx, &models.Loan{
+		LenderID:   bob.ID,
+		BorrowerID: alice.ID,
+		AmountPLN:  "100.00",
+		Status:     "open",
+	}))
+
+	currencyService := NewCurrencyService(repos.ExchangeRates, NewAppSettingsService(repos.AppSettings))
+	loanService := NewLoanService(&failingLoanRepository{repos.Loans}, repos.LoanPayments, repos.Users, repos.Groups,
+		repos.TxManager, nil, currencyService)
+
+	_, err := loanService.CreateLoan(ctx, CreateLoanRequest{
+		LenderID:   alice.ID,
+		BorrowerID: bob.ID,
+		AmountPLN:  3000,
+	})
+	require.ErrorIs(t, err, errInjected)
+
+	reverseLoans, err := repos.Loans.ListOpenBetweenUsers(ctx, bob.ID, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, reverseLoans, 1)
+	assert.Equal(t, "open", reverseLoans[0].Status)
+
+	payments, err := repos.LoanPayments.ListByLoanID(ctx, reverseLoans[0].ID)
+	require.NoError(t, err)
+	assert.Empty(t, payments, "offset payment should have been rolled back")
+
+	newLoans, err := repos.Loans.ListOpenBetweenUsers(ctx, alice.ID, bob.ID)
+	require.NoError(t, err)
+	assert.Empty(t, newLoans)
+}
+
+func TestDeleteChore(t *testing.T) {
+	tests := []struct {
+		name            string
+		failChoreDelete bool
+		wantErr         bool
+		wantChore       bool
+		wantAssignments int
+	}{
+		{"Deletes chore and assignments", false, false, false, 0},
+		{"Keeps assignments when chore delete fails", true, true, true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos := newTestRepositories(t)
+			ctx := context.Background()
+			user := createTestUser(t, repos, "Alice")
+
+			chore := &models.Chore{
+				ID:             "chore-1",
+				Name:           "Dishes",
+				Frequency:      "daily",
+				Difficulty:     1,
+				Priority:       1,
+				AssignmentMode: "manual",
+				IsActive:       true,
+			}
+			require.NoError(t, repos.Chores.Create(ctx, chore))
+			for _, id := range []string{"assignment-1", "assignment-2"} {
+				require.NoError(t, repos.ChoreAssignments.Create(ctx, &models.ChoreAssignment{
+					ID:             id,
+					ChoreID:        chore.ID,
+					AssigneeUserID: user.ID,
+					DueDate:        time.Now(),
+					Status:         "pending",
+				}))
+			}
+
+			var chores repository.ChoreRepository = repos.Chores
+			if tt.failChoreDelete {
+				chores = &failingChoreRepository{repos.Chores}
+			}
+			choreService := NewChoreService(chores, repos.ChoreAssignments, repos.Users, repos.TxManager, nil)
+
+			err := choreService.DeleteChore(ctx, chore.ID)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errInjected)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			stored, err := repos.Chores.GetByID(ctx, chore.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantChore, stored != nil)
+
+			assignments, err := repos.ChoreAssignments.ListByChoreID(ctx, chore.ID)
+			require.NoError(t, err)
+			assert.Len(t, assignments, tt.wantAssignments)
+		})
+	}
+}
+
+func TestProcessWeeklyContributionsRollsBackOnFailure(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	createTestUser(t, repos, "Alice")
+	createTestUser(t, repos, "Bob")
+
+	settingsRepo := &failingSupplySettingsRepository{SupplySettingsRepository: repos.SupplySettings}
+	require.NoError(t, settingsRepo.Upsert(ctx, &models.SupplySettings{
+		ID:                    "singleton",
+		WeeklyContributionPLN: "10.00",
+		ContributionDay:       time.Now().Weekday().String(),
+		CurrentBudgetPLN:      "50.00",
+		IsActive:              true,
+	}))
+	settingsRepo.failUpsert = true
+
+	supplyService := NewSupplyService(settingsRepo, repos.SupplyItems, repos.SupplyContributions, repos.SupplyItemHistory,
+		repos.Users, repos.TxManager, nil, nil)
+
+	err := supplyService.ProcessWeeklyContributions(ctx)
+	require.ErrorIs(t, err, errInjected)
+
+	contributions, err := repos.SupplyContributions.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, contributions, "contributions should have been rolled back")
+
+	settings, err := repos.SupplySettings.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "50.00", settings.CurrentBudgetPLN)
+}
@@ -17,6 +17,7 @@ import (
 
 type UserService struct {
 	users               repository.UserRepository
+	residencies         repository.ResidencyRepository
 	groups              repository.GroupRepository
 	roles               repository.RoleRepository
 	passwordResetTokens repository.PasswordResetTokenRepository
@@ -25,6 +26,7 @@ type UserService struct {
 
 func NewUserService(
 	users repository.UserRepository,
+	residencies repository.ResidencyRepository,
 	groups repository.GroupRepository,
 	roles repository.RoleRepository,
 	passwordResetTokens repository.PasswordResetTokenRepository,
@@ -32,6 +34,7 @@ func NewUserService(
 ) *UserService {
 	return &UserService{
 		users:               users,
+		residencies:         residencies,
 		groups:              groups,
 		roles:               roles,
 		passwordResetTokens: passwordResetTokens,
@@ -57,6 +60,12 @@ type UpdateUserRequest struct {
 	IsActive *bool   `json:"isActive,omitempty"`
 }
 
+// ResidencyRequest describes when a user lived in the household (both dates inclusive)
+type ResidencyRequest struct {
+	MovedInAt  time.Time  `json:"movedInAt"`
+	MovedOutAt *time.Time `json:"movedOutAt,omitempty"` // empty while the user still lives there
+}
+
 // CreateUser creates a new user (ADMIN only)
 func (s *UserService) CreateUser(ctx context.Context, req CreateUserRequest) (*models.User, error) {
 	email := strings.ToLower(strings.TrimSpace(req.Email))
@@ -334,6 +343,106 @@ func (s *UserService) GetUserIDsByRole(ctx context.Context, roleName string) ([]
 	return userIDs, nil
 }
 
+// GetResidencies returns the move-in/move-out intervals of a user
+func (s *UserService) GetResidencies(ctx context.Context, userID string) ([]models.Residency, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	return s.residencies.ListByUserID(ctx, userID)
+}
+
+// AddResidency records a period during which the user lived in the household
+func (s *UserService) AddResidency(ctx context.Context, userID string, req ResidencyRequest) (*models.Residency, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	residency := &models.Residency{
+		UserID:     userID,
+		MovedInAt:  req.MovedInAt,
+		MovedOutAt: req.MovedOutAt,
+	}
+	if err := s.validateResidency(ctx, residency); err != nil {
+		return nil, err
+	}
+
+	if err := s.residencies.Create(ctx, residency); err != nil {
+		return nil, fmt.Errorf("failed to create residency: %w", err)
+	}
+
+	log.Printf("[USER] Residency added for %q: moved in %s (ID: %s)", user.Name, residency.MovedInAt.Format("2006-01-02"), residency.ID)
+
+	return residency, nil
+}
+
+// UpdateResidency changes the dates of a residency interval, e.g. to record a move-out
+func (s *UserService) UpdateResidency(ctx context.Context, userID, residencyID string, req ResidencyRequest) (*models.Residency, error) {
+	residency, err := s.residencies.GetByID(ctx, residencyID)
+	if err != nil || residency == nil || residency.UserID != userID {
+		return nil, errors.New("residency not found")
+	}
+
+	residency.MovedInAt = req.MovedInAt
+	residency.MovedOutAt = req.MovedOutAt
+	if err := s.validateResidency(ctx, residency); err != nil {
+		return nil, err
+	}
+
+	if err := s.residencies.Update(ctx, residency); err != nil {
+		return nil, fmt.Errorf("failed to update residency: %w", err)
+	}
+
+	log.Printf("[USER] Residency updated: ID=%s (user %s)", residencyID, userID)
+
+	return residency, nil
+}
+
+// DeleteResidency removes a residency interval
+func (s *UserService) DeleteResidency(ctx context.Context, userID, residencyID string) error {
+	residency, err := s.residencies.GetByID(ctx, residencyID)
+	if err != nil || residency == nil || residency.UserID != userID {
+		return errors.New("residency not found")
+	}
+
+	if err := s.residencies.Delete(ctx, residencyID); err != nil {
+		return fmt.Errorf("failed to delete residency: %w", err)
+	}
+
+	log.Printf("[USER] Residency deleted: ID=%s (user %s)", residencyID, userID)
+
+	return nil
+}
+
+// validateResidency checks the dates and that the interval does not overlap the user's other intervals
+func (s *UserService) validateResidency(ctx context.Context, residency *models.Residency) error {
+	if residency.MovedInAt.IsZero() {
+		return errors.New("movedInAt is required")
+	}
+	if residency.MovedOutAt != nil && residency.MovedOutAt.Before(residency.MovedInAt) {
+		return errors.New("movedOutAt cannot be before movedInAt")
+	}
+
+	existing, err := s.residencies.ListByUserID(ctx, residency.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get residencies: %w", err)
+	}
+	for _, other := range existing {
+		if other.ID == residency.ID {
+			continue
+		}
+		startsBeforeOtherEnds := other.MovedOutAt == nil || !residency.MovedInAt.After(*other.MovedOutAt)
+		endsAfterOtherStarts := residency.MovedOutAt == nil || !residency.MovedOutAt.Before(other.MovedInAt)
+		if startsBeforeOtherEnds && endsAfterOtherStarts {
+			return fmt.Errorf("residency overlaps an existing one starting %s", other.MovedInAt.Format("2006-01-02"))
+		}
+	}
+
+	return nil
+}
+
 // DeleteUser deletes a user from the system
 func (s *UserService) DeleteUser(ctx context.Context, userID string) error {
 	// Check if user is active
@@ -1,9 +1,12 @@
 package services
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestEmailValidation tests email validation logic
@@ -117,3 +120,48 @@ func TestUserRoleValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestAddResidencyRejectsOverlaps(t *testing.T) {
+	repos := newTestRepositories(t)
+	ctx := context.Background()
+	userService := NewUserService(repos.Users, repos.Residencies, repos.Groups, repos.Roles, repos.PasswordResetTokens, nil)
+	alice := createTestUser(t, repos, "Alice")
+
+	date := func(month time.Month, d int) time.Time { return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC) }
+	datePtr := func(month time.Month, d int) *time.Time { t := date(month, d); return &t }
+
+	first, err := userService.AddResidency(ctx, alice.ID, ResidencyRequest{MovedInAt: date(time.January, 1), MovedOutAt: datePtr(time.March, 31)})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		req     ResidencyRequest
+		wantErr string
+	}{
+		{"Missing move-in", ResidencyRequest{}, "movedInAt is required"},
+		{"Move-out before move-in", ResidencyRequest{MovedInAt: date(time.June, 1), MovedOutAt: datePtr(time.May, 1)}, "cannot be before"},
+		{"Overlaps the end", ResidencyRequest{MovedInAt: date(time.March, 31)}, "overlaps"},
+		{"Open interval covering it", ResidencyRequest{MovedInAt: date(time.January, 1).AddDate(-1, 0, 0)}, "overlaps"},
+		{"After moving out", ResidencyRequest{MovedInAt: date(time.April, 1)}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := userService.AddResidency(ctx, alice.ID, tt.req)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	// Moving the first interval's end into the second one is rejected as well
+	_, err = userService.UpdateResidency(ctx, alice.ID, first.ID, ResidencyRequest{MovedInAt: date(time.January, 1), MovedOutAt: datePtr(time.April, 2)})
+	assert.ErrorContains(t, err, "overlaps")
+
+	residencies, err := userService.GetResidencies(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Len(t, residencies, 2)
+}
@@ -6,12 +6,13 @@ import (
 	"strconv"
 )
 
-// FloatToDecimalString converts float to decimal string for SQLite storage
+// FloatToDecimalString converts float to decimal string for SQLite storage.
+// Use it for quantities such as units and meter values; money goes through Money.
 func FloatToDecimalString(f float64) string {
 	return fmt.Sprintf("%.2f", f)
 }
 
-// DecimalStringToFloat converts decimal string back to float for math
+// DecimalStringToFloat converts decimal string back to float for quantity math
 func DecimalStringToFloat(s string) float64 {
 	f, _ := strconv.ParseFloat(s, 64)
 	return f
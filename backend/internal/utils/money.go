@@ -0,0 +1,391 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact amount of money stored as an integer number of grosze
+// (hundredths of the currency unit). All money arithmetic should go through
+// Money instead of float64 so sums never drift by a grosz.
+type Money int64
+
+// ErrInvalidMoney is returned when a string cannot be parsed as an amount
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+// ParseMoney parses a decimal string such as "12.34", "-5", "0,5" into Money.
+// More than two fractional digits are rounded half away from zero.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidMoney
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	s = strings.Replace(s, ",", ".", 1)
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return 0, ErrInvalidMoney
+	}
+	if !isDigits(intPart) || !isDigits(fracPart) {
+		// Fall back for values like "1e3" that SQLite may produce from REAL columns
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, ErrInvalidMoney
+		}
+		m := MoneyFromFloat(f)
+		if negative {
+			m = -m
+		}
+		return m, nil
+	}
+
+	var units int64
+	if intPart != "" {
+		v, err := strconv.ParseInt(intPart, 10, 64)
+		if err != nil || v > math.MaxInt64/100 {
+			return 0, ErrInvalidMoney
+		}
+		units = v
+	}
+
+	var grosze int64
+	roundUp := false
+	if len(fracPart) > 0 {
+		padded := fracPart + "00"
+		grosze, _ = strconv.ParseInt(padded[:2], 10, 64)
+		if len(fracPart) > 2 && fracPart[2] >= '5' {
+			roundUp = true
+		}
+	}
+
+	total := units*100 + grosze
+	if roundUp {
+		total++
+	}
+	if negative {
+		total = -total
+	}
+	return Money(total), nil
+}
+
+// MoneyFromString parses a stored decimal string, returning zero for invalid input
+func MoneyFromString(s string) Money {
+	m, _ := ParseMoney(s)
+	return m
+}
+
+// MoneyFromFloat converts a float amount to Money, rounding to the nearest grosz
+func MoneyFromFloat(f float64) Money {
+	// Go through the shortest decimal representation so 1.005 stays 1.005
+	// instead of 1.00499999... before rounding
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if strings.ContainsAny(s, "eE") {
+		return Money(math.Round(f * 100))
+	}
+	m, err := ParseMoney(s)
+	if err != nil {
+		return Money(math.Round(f * 100))
+	}
+	return m
+}
+
+// NewMoney builds Money from whole units and grosze, e.g. NewMoney(12, 34) is 12.34
+func NewMoney(units, grosze int64) Money {
+	if units < 0 {
+		return Money(units*100 - grosze)
+	}
+	return Money(units*100 + grosze)
+}
+
+// Grosze returns the amount as an integer number of grosze
+func (m Money) Grosze() int64 {
+	return int64(m)
+}
+
+// String formats the amount with exactly two decimals, e.g. "12.30"
+func (m Money) String() string {
+	v := int64(m)
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+// Float64 returns the amount as a float, for display and ratios only
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// Add returns m + o
+func (m Money) Add(o Money) Money {
+	return m + o
+}
+
+// Sub returns m - o
+func (m Money) Sub(o Money) Money {
+	return m - o
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+	return -m
+}
+
+// Abs returns the absolute value of m
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// IsZero reports whether m is exactly zero
+func (m Money) IsZero() bool {
+	return m == 0
+}
+
+// IsPositive reports whether m is greater than zero
+func (m Money) IsPositive() bool {
+	return m > 0
+}
+
+// IsNegative reports whether m is less than zero
+func (m Money) IsNegative() bool {
+	return m < 0
+}
+
+// MinMoney returns the smaller of two amounts
+func MinMoney(a, b Money) Money {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// SumMoney adds up a list of amounts
+func SumMoney(amounts ...Money) Money {
+	var total Money
+	for _, a := range amounts {
+		total += a
+	}
+	return total
+}
+
+// MulFloat multiplies by a factor (e.g. a percentage or a unit count) and
+// rounds the result half away from zero to the nearest grosz
+func (m Money) MulFloat(f float64) Money {
+	r := new(big.Rat).SetInt64(int64(m))
+	factor := new(big.Rat)
+	if factor.SetFloat64(f) == nil {
+		return 0
+	}
+	r.Mul(r, factor)
+	return ratToMoney(r)
+}
+
+// MulRatio returns m * num / den rounded half away from zero
+func (m Money) MulRatio(num, den int64) Money {
+	if den == 0 {
+		return 0
+	}
+	r := new(big.Rat).SetInt64(int64(m))
+	r.Mul(r, big.NewRat(num, den))
+	return ratToMoney(r)
+}
+
+// MulDecimal multiplies by a factor given as a decimal string (e.g. an exchange
+// rate "4.3125") without going through float64, rounding half away from zero
+func (m Money) MulDecimal(factor string) (Money, error) {
+	f, ok := new(big.Rat).SetString(strings.Replace(strings.TrimSpace(factor), ",", ".", 1))
+	if !ok {
+		return 0, fmt.Errorf("invalid decimal factor: %q", factor)
+	}
+	r := new(big.Rat).SetInt64(int64(m))
+	r.Mul(r, f)
+	return ratToMoney(r), nil
+}
+
+// UnitCost returns the cost of a number of units at a price per unit given as a
+// decimal string (e.g. a tariff's "0.8123"). The price is not rounded to grosze
+// first; only the cost is, half away from zero.
+func UnitCost(unitPrice string, units float64) (Money, error) {
+	price, ok := new(big.Rat).SetString(strings.Replace(strings.TrimSpace(unitPrice), ",", ".", 1))
+	if !ok {
+		return 0, fmt.Errorf("invalid unit price: %q", unitPrice)
+	}
+	r := new(big.Rat)
+	if r.SetFloat64(units) == nil {
+		return 0, fmt.Errorf("invalid number of units: %v", units)
+	}
+	r.Mul(r, price)
+	r.Mul(r, big.NewRat(100, 1))
+	return ratToMoney(r), nil
+}
+
+// Allocate splits m proportionally to the given weights using the largest
+// remainder method: every share is rounded down to a whole grosz, and the
+// grosze left over go one by one to the shares with the largest fractional
+// parts (earlier entries win ties). The shares always sum to m exactly.
+// Negative weights count as zero; if all weights are zero, m is split evenly.
+func (m Money) Allocate(weights []float64) []Money {
+	shares := make([]Money, len(weights))
+	if len(weights) == 0 {
+		return shares
+	}
+
+	rats := make([]*big.Rat, len(weights))
+	total := new(big.Rat)
+	for i, w := range weights {
+		rats[i] = new(big.Rat)
+		if w > 0 && !math.IsInf(w, 0) {
+			rats[i].SetFloat64(w)
+		}
+		total.Add(total, rats[i])
+	}
+	if total.Sign() == 0 {
+		for i := range rats {
+			rats[i].SetInt64(1)
+		}
+		total.SetInt64(int64(len(weights)))
+	}
+
+	abs := m.Abs()
+	amount := new(big.Rat).SetInt64(int64(abs))
+
+	type remainder struct {
+		index int
+		frac  *big.Rat
+	}
+	remainders := make([]remainder, len(weights))
+	allocated := Money(0)
+
+	for i, w := range rats {
+		exact := new(big.Rat).Mul(amount, w)
+		exact.Quo(exact, total)
+		floor := new(big.Int).Quo(exact.Num(), exact.Denom())
+		shares[i] = Money(floor.Int64())
+		allocated += shares[i]
+		remainders[i] = remainder{
+			index: i,
+			frac:  new(big.Rat).Sub(exact, new(big.Rat).SetInt(floor)),
+		}
+	}
+
+	sort.SliceStable(remainders, func(a, b int) bool {
+		return remainders[a].frac.Cmp(remainders[b].frac) > 0
+	})
+	for left, k := abs-allocated, 0; left > 0; left, k = left-1, k+1 {
+		shares[remainders[k%len(remainders)].index]++
+	}
+
+	if m < 0 {
+		for i := range shares {
+			shares[i] = -shares[i]
+		}
+	}
+	return shares
+}
+
+// Split divides m into n equal shares that sum to m exactly
+func (m Money) Split(n int) []Money {
+	if n <= 0 {
+		return nil
+	}
+	return m.Allocate(make([]float64, n))
+}
+
+// MarshalJSON encodes Money as a JSON number with two decimals, e.g. 12.30
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number, a numeric string or null
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		*m = 0
+		return nil
+	}
+	s = strings.Trim(s, `"`)
+	if s == "" {
+		*m = 0
+		return nil
+	}
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores Money as a decimal string, matching the TEXT money columns
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads Money from a TEXT, REAL or INTEGER column
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case string:
+		parsed, err := ParseMoney(v)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	case []byte:
+		parsed, err := ParseMoney(string(v))
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	case float64:
+		*m = MoneyFromFloat(v)
+	case int64:
+		*m = Money(v * 100)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ratToMoney rounds a rational number of grosze half away from zero
+func ratToMoney(r *big.Rat) Money {
+	negative := r.Sign() < 0
+	abs := new(big.Rat).Abs(r)
+	abs.Add(abs, big.NewRat(1, 2))
+	floor := new(big.Int).Quo(abs.Num(), abs.Denom())
+	v := floor.Int64()
+	if negative {
+		v = -v
+	}
+	return Money(v)
+}
@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{"Two decimals", "123.45", 12345, false},
+		{"One decimal", "10.5", 1050, false},
+		{"Integer", "7", 700, false},
+		{"Negative", "-0.05", -5, false},
+		{"Comma separator", "12,30", 1230, false},
+		{"Round half up", "1.005", 101, false},
+		{"Round down", "1.004", 100, false},
+		{"Leading dot", ".5", 50, false},
+		{"Empty", "", 0, true},
+		{"Garbage", "abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMoney(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMoney(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMoney(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{-5, "-0.05"},
+		{12345, "123.45"},
+		{-100000, "-1000.00"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", tt.money, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyFromFloat(t *testing.T) {
+	if got := MoneyFromFloat(0.1 + 0.2); got != 30 {
+		t.Errorf("MoneyFromFloat(0.1+0.2) = %d, want 30", got)
+	}
+	if got := MoneyFromFloat(1.005); got != 101 {
+		t.Errorf("MoneyFromFloat(1.005) = %d, want 101", got)
+	}
+}
+
+func TestMoneyAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  Money
+		weights []float64
+		want    []Money
+	}{
+		{"Even thirds", 10000, []float64{1, 1, 1}, []Money{3334, 3333, 3333}},
+		{"Weighted", 10000, []float64{2, 1}, []Money{6667, 3333}},
+		{"Largest remainder wins", 100, []float64{0.15, 0.15, 0.7}, []Money{15, 15, 70}},
+		{"Zero weight gets nothing", 1001, []float64{1, 0, 1}, []Money{501, 0, 500}},
+		{"All zero splits evenly", 5, []float64{0, 0}, []Money{3, 2}},
+		{"Negative amount", -10000, []float64{1, 1, 1}, []Money{-3334, -3333, -3333}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.amount.Allocate(tt.weights)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Allocate() returned %d shares, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Allocate() share %d = %d, want %d", i, got[i], tt.want[i])
+				}
+			}
+			if SumMoney(got...) != tt.amount {
+				t.Errorf("Allocate() shares sum to %d, want %d", SumMoney(got...), tt.amount)
+			}
+		})
+	}
+}
+
+func TestMoneyAllocateAlwaysSumsToTotal(t *testing.T) {
+	weights := []float64{1.5, 1, 1, 0.75, 2.25, 1}
+	for amount := Money(1); amount < 5000; amount += 37 {
+		shares := amount.Allocate(weights)
+		if SumMoney(shares...) != amount {
+			t.Fatalf("Allocate(%d) shares sum to %d", amount, SumMoney(shares...))
+		}
+	}
+}
+
+func TestMoneyMulFloat(t *testing.T) {
+	if got := Money(10000).MulFloat(0.333); got != 3330 {
+		t.Errorf("MulFloat(0.333) = %d, want 3330", got)
+	}
+	if got := Money(1).MulFloat(0.5); got != 1 {
+		t.Errorf("MulFloat(0.5) = %d, want 1 (half away from zero)", got)
+	}
+	if got := Money(-1).MulFloat(0.5); got != -1 {
+		t.Errorf("MulFloat(0.5) = %d, want -1 (half away from zero)", got)
+	}
+}
+
+func TestMoneyMulDecimal(t *testing.T) {
+	tests := []struct {
+		money   Money
+		factor  string
+		want    Money
+		wantErr bool
+	}{
+		{10000, "4.3125", 43125, false},
+		{1999, "4.2871", 8570, false},
+		{-1999, "4.2871", -8570, false},
+		{5, "0,5", 3, false},
+		{100, "rate", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.money.MulDecimal(tt.factor)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("MulDecimal(%q) error = %v, wantErr %v", tt.factor, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("Money(%d).MulDecimal(%q) = %d, want %d", tt.money, tt.factor, got, tt.want)
+		}
+	}
+}
+
+func TestUnitCost(t *testing.T) {
+	tests := []struct {
+		price   string
+		units   float64
+		want    Money
+		wantErr bool
+	}{
+		{"0.8123", 7, 569, false},
+		{"0,8123", 1.5, 122, false},
+		{"0.8123", 1000, 81230, false},
+		{"0.80", 0, 0, false},
+		{"price", 1, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := UnitCost(tt.price, tt.units)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("UnitCost(%q, %v) error = %v, wantErr %v", tt.price, tt.units, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("UnitCost(%q, %v) = %d, want %d", tt.price, tt.units, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var payload struct {
+		Number Money `json:"number"`
+		Text   Money `json:"text"`
+	}
+	if err := json.Unmarshal([]byte(`{"number": 250.5, "text": "99.99"}`), &payload); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if payload.Number != 25050 || payload.Text != 9999 {
+		t.Errorf("Unmarshal() = %d, %d, want 25050, 9999", payload.Number, payload.Text)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(data) != `{"number":250.50,"text":99.99}` {
+		t.Errorf("Marshal() = %s", data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"number": "ten"}`), &payload); err == nil {
+		t.Error("Unmarshal() expected error for invalid amount")
+	}
+}